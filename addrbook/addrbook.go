@@ -0,0 +1,491 @@
+package addrbook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// LabelLimit is the length limit we impose on labels, names and notes.
+	// It matches the limit imposed on transaction labels.
+	LabelLimit = 500
+
+	// version is the current version of the address book namespace.
+	version = 1
+)
+
+var (
+	// ErrEmptyLabel is returned when an attempt is made to write an empty
+	// label or contact name.
+	ErrEmptyLabel = errors.New("empty label not allowed")
+
+	// ErrLabelTooLong is returned when an attempt is made to write a label,
+	// contact name or note that exceeds LabelLimit.
+	ErrLabelTooLong = errors.New("label exceeds limit")
+
+	// ErrNotFound is returned when the requested contact or label does not
+	// exist.
+	ErrNotFound = errors.New("address book entry not found")
+
+	// ErrUnknownContact is returned when an address is associated with a
+	// contact that does not exist.
+	ErrUnknownContact = errors.New("unknown contact")
+)
+
+// Key names for the buckets and values of the address book namespace.
+var (
+	versionKey = []byte("version")
+
+	bucketContacts  = []byte("c")
+	bucketAddresses = []byte("a")
+	bucketXPubs     = []byte("x")
+	bucketOutputs   = []byte("o")
+)
+
+// Contact is a named counterparty that labeled addresses may be associated
+// with.
+type Contact struct {
+	Name string
+	Note string
+}
+
+// AddressLabel is the label of an address.  The address is stored in its
+// string encoding so that addresses of any network and type may be labeled.
+type AddressLabel struct {
+	Address string
+	Label   string
+
+	// Contact is the name of the contact the address belongs to, or empty
+	// if the address is not associated with a contact.
+	Contact string
+}
+
+// OutputLabel is the label of a transaction output.
+type OutputLabel struct {
+	OutPoint wire.OutPoint
+	Label    string
+
+	// Spendable, when set, records whether the output may be spent by
+	// the wallet, as exchanged in BIP-0329 output records.  It is not
+	// enforced by the address book.
+	Spendable *bool
+}
+
+// Create creates the buckets of the address book in the namespace bucket.
+func Create(ns walletdb.ReadWriteBucket) error {
+	for _, name := range [][]byte{
+		bucketContacts, bucketAddresses, bucketXPubs, bucketOutputs,
+	} {
+		if _, err := ns.CreateBucketIfNotExists(name); err != nil {
+			return fmt.Errorf("unable to create bucket %q: %w",
+				name, err)
+		}
+	}
+
+	var v [4]byte
+	binary.BigEndian.PutUint32(v[:], version)
+	return ns.Put(versionKey, v[:])
+}
+
+// checkLabel validates a label, contact name or note.  Empty strings are only
+// allowed when allowEmpty is set.
+func checkLabel(s string, allowEmpty bool) error {
+	if s == "" && !allowEmpty {
+		return ErrEmptyLabel
+	}
+	if len(s) > LabelLimit {
+		return ErrLabelTooLong
+	}
+	return nil
+}
+
+// serializeStrings length-value encodes the strings provided.  The strings are
+// expected to have been checked against LabelLimit, so their length is stored
+// as a uint16.
+//
+// [0:2]      Length of the first string
+// [2:+len]   First string
+// ...
+func serializeStrings(strs ...string) []byte {
+	var buf bytes.Buffer
+	var b [2]byte
+	for _, s := range strs {
+		binary.BigEndian.PutUint16(b[:], uint16(len(s)))
+		buf.Write(b[:])
+		buf.WriteString(s)
+	}
+	return buf.Bytes()
+}
+
+// deserializeStrings reads n length-value encoded strings from v.
+func deserializeStrings(v []byte, n int) ([]string, error) {
+	strs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if len(v) < 2 {
+			return nil, errors.New("short address book value")
+		}
+		l := int(binary.BigEndian.Uint16(v[0:2]))
+		v = v[2:]
+		if len(v) < l {
+			return nil, errors.New("short address book value")
+		}
+		strs = append(strs, string(v[:l]))
+		v = v[l:]
+	}
+	return strs, nil
+}
+
+// nestedBucket returns the nested bucket of the namespace, or an error if the
+// address book was not created.
+func nestedBucket(ns walletdb.ReadBucket, name []byte) (walletdb.ReadBucket,
+	error) {
+
+	b := ns.NestedReadBucket(name)
+	if b == nil {
+		return nil, fmt.Errorf("missing address book bucket %q", name)
+	}
+	return b, nil
+}
+
+// nestedReadWriteBucket is the read-write version of nestedBucket.
+func nestedReadWriteBucket(ns walletdb.ReadWriteBucket,
+	name []byte) (walletdb.ReadWriteBucket, error) {
+
+	b := ns.NestedReadWriteBucket(name)
+	if b == nil {
+		return nil, fmt.Errorf("missing address book bucket %q", name)
+	}
+	return b, nil
+}
+
+// PutContact adds a contact, or replaces the note of an existing contact.
+func PutContact(ns walletdb.ReadWriteBucket, c *Contact) error {
+	if err := checkLabel(c.Name, false); err != nil {
+		return err
+	}
+	if err := checkLabel(c.Note, true); err != nil {
+		return err
+	}
+
+	b, err := nestedReadWriteBucket(ns, bucketContacts)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(c.Name), serializeStrings(c.Note))
+}
+
+// FetchContact returns the contact with the name provided.
+func FetchContact(ns walletdb.ReadBucket, name string) (*Contact, error) {
+	b, err := nestedBucket(ns, bucketContacts)
+	if err != nil {
+		return nil, err
+	}
+
+	v := b.Get([]byte(name))
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	strs, err := deserializeStrings(v, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &Contact{Name: name, Note: strs[0]}, nil
+}
+
+// DeleteContact removes a contact.  Addresses associated with the contact keep
+// their labels but are no longer associated with any contact.
+func DeleteContact(ns walletdb.ReadWriteBucket, name string) error {
+	b, err := nestedReadWriteBucket(ns, bucketContacts)
+	if err != nil {
+		return err
+	}
+	if b.Get([]byte(name)) == nil {
+		return ErrNotFound
+	}
+
+	var orphaned []*AddressLabel
+	err = ForEachAddressLabel(ns, func(a *AddressLabel) error {
+		if a.Contact == name {
+			orphaned = append(orphaned, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, a := range orphaned {
+		a.Contact = ""
+		if err := PutAddressLabel(ns, a); err != nil {
+			return err
+		}
+	}
+
+	return b.Delete([]byte(name))
+}
+
+// ForEachContact calls f for each contact, in order of their names.
+func ForEachContact(ns walletdb.ReadBucket, f func(*Contact) error) error {
+	b, err := nestedBucket(ns, bucketContacts)
+	if err != nil {
+		return err
+	}
+	return b.ForEach(func(k, v []byte) error {
+		strs, err := deserializeStrings(v, 1)
+		if err != nil {
+			return err
+		}
+		return f(&Contact{Name: string(k), Note: strs[0]})
+	})
+}
+
+// PutAddressLabel writes the label of an address, replacing any existing
+// label.  If a contact is set, it must already exist.
+func PutAddressLabel(ns walletdb.ReadWriteBucket, a *AddressLabel) error {
+	if a.Address == "" {
+		return errors.New("empty address")
+	}
+	if err := checkLabel(a.Label, false); err != nil {
+		return err
+	}
+	if a.Contact != "" {
+		_, err := FetchContact(ns, a.Contact)
+		if errors.Is(err, ErrNotFound) {
+			return ErrUnknownContact
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	b, err := nestedReadWriteBucket(ns, bucketAddresses)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(a.Address), serializeStrings(a.Label, a.Contact))
+}
+
+// FetchAddressLabel returns the label of the address with the string encoding
+// provided.
+func FetchAddressLabel(ns walletdb.ReadBucket, addr string) (*AddressLabel,
+	error) {
+
+	b, err := nestedBucket(ns, bucketAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	v := b.Get([]byte(addr))
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	return deserializeAddressLabel([]byte(addr), v)
+}
+
+// DeleteAddressLabel removes the label of an address.
+func DeleteAddressLabel(ns walletdb.ReadWriteBucket, addr string) error {
+	b, err := nestedReadWriteBucket(ns, bucketAddresses)
+	if err != nil {
+		return err
+	}
+	if b.Get([]byte(addr)) == nil {
+		return ErrNotFound
+	}
+	return b.Delete([]byte(addr))
+}
+
+// ForEachAddressLabel calls f for each labeled address.
+func ForEachAddressLabel(ns walletdb.ReadBucket,
+	f func(*AddressLabel) error) error {
+
+	b, err := nestedBucket(ns, bucketAddresses)
+	if err != nil {
+		return err
+	}
+	return b.ForEach(func(k, v []byte) error {
+		a, err := deserializeAddressLabel(k, v)
+		if err != nil {
+			return err
+		}
+		return f(a)
+	})
+}
+
+func deserializeAddressLabel(k, v []byte) (*AddressLabel, error) {
+	strs, err := deserializeStrings(v, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &AddressLabel{
+		Address: string(k),
+		Label:   strs[0],
+		Contact: strs[1],
+	}, nil
+}
+
+// PutXPubLabel writes the label of an extended public key in its string
+// encoding, replacing any existing label.
+func PutXPubLabel(ns walletdb.ReadWriteBucket, xpub, label string) error {
+	if xpub == "" {
+		return errors.New("empty extended key")
+	}
+	if err := checkLabel(label, false); err != nil {
+		return err
+	}
+
+	b, err := nestedReadWriteBucket(ns, bucketXPubs)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(xpub), serializeStrings(label))
+}
+
+// FetchXPubLabel returns the label of an extended public key.
+func FetchXPubLabel(ns walletdb.ReadBucket, xpub string) (string, error) {
+	b, err := nestedBucket(ns, bucketXPubs)
+	if err != nil {
+		return "", err
+	}
+
+	v := b.Get([]byte(xpub))
+	if v == nil {
+		return "", ErrNotFound
+	}
+	strs, err := deserializeStrings(v, 1)
+	if err != nil {
+		return "", err
+	}
+	return strs[0], nil
+}
+
+// ForEachXPubLabel calls f for each labeled extended public key.
+func ForEachXPubLabel(ns walletdb.ReadBucket,
+	f func(xpub, label string) error) error {
+
+	b, err := nestedBucket(ns, bucketXPubs)
+	if err != nil {
+		return err
+	}
+	return b.ForEach(func(k, v []byte) error {
+		strs, err := deserializeStrings(v, 1)
+		if err != nil {
+			return err
+		}
+		return f(string(k), strs[0])
+	})
+}
+
+// Flags of serialized output labels.
+const (
+	outputFlagHasSpendable = 1 << iota
+	outputFlagSpendable
+)
+
+// outPointKey returns the key of an output label, which is the transaction
+// hash followed by the big endian output index.
+func outPointKey(op *wire.OutPoint) []byte {
+	k := make([]byte, chainhash.HashSize+4)
+	copy(k, op.Hash[:])
+	binary.BigEndian.PutUint32(k[chainhash.HashSize:], op.Index)
+	return k
+}
+
+// PutOutputLabel writes the label of a transaction output, replacing any
+// existing label.
+//
+// The value of an output label is serialized as:
+//
+// [0]        Flags
+// [1:3]      Label length
+// [3:+len]   Label
+func PutOutputLabel(ns walletdb.ReadWriteBucket, o *OutputLabel) error {
+	if err := checkLabel(o.Label, false); err != nil {
+		return err
+	}
+
+	b, err := nestedReadWriteBucket(ns, bucketOutputs)
+	if err != nil {
+		return err
+	}
+
+	var flags byte
+	if o.Spendable != nil {
+		flags |= outputFlagHasSpendable
+		if *o.Spendable {
+			flags |= outputFlagSpendable
+		}
+	}
+	v := append([]byte{flags}, serializeStrings(o.Label)...)
+	return b.Put(outPointKey(&o.OutPoint), v)
+}
+
+// FetchOutputLabel returns the label of a transaction output.
+func FetchOutputLabel(ns walletdb.ReadBucket, op wire.OutPoint) (*OutputLabel,
+	error) {
+
+	b, err := nestedBucket(ns, bucketOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	k := outPointKey(&op)
+	v := b.Get(k)
+	if v == nil {
+		return nil, ErrNotFound
+	}
+	return deserializeOutputLabel(k, v)
+}
+
+// DeleteOutputLabel removes the label of a transaction output.
+func DeleteOutputLabel(ns walletdb.ReadWriteBucket, op wire.OutPoint) error {
+	b, err := nestedReadWriteBucket(ns, bucketOutputs)
+	if err != nil {
+		return err
+	}
+	k := outPointKey(&op)
+	if b.Get(k) == nil {
+		return ErrNotFound
+	}
+	return b.Delete(k)
+}
+
+// ForEachOutputLabel calls f for each labeled transaction output.
+func ForEachOutputLabel(ns walletdb.ReadBucket,
+	f func(*OutputLabel) error) error {
+
+	b, err := nestedBucket(ns, bucketOutputs)
+	if err != nil {
+		return err
+	}
+	return b.ForEach(func(k, v []byte) error {
+		o, err := deserializeOutputLabel(k, v)
+		if err != nil {
+			return err
+		}
+		return f(o)
+	})
+}
+
+func deserializeOutputLabel(k, v []byte) (*OutputLabel, error) {
+	if len(k) != chainhash.HashSize+4 || len(v) < 1 {
+		return nil, errors.New("malformed output label")
+	}
+	strs, err := deserializeStrings(v[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OutputLabel{Label: strs[0]}
+	copy(o.OutPoint.Hash[:], k[:chainhash.HashSize])
+	o.OutPoint.Index = binary.BigEndian.Uint32(k[chainhash.HashSize:])
+	if v[0]&outputFlagHasSpendable != 0 {
+		spendable := v[0]&outputFlagSpendable != 0
+		o.Spendable = &spendable
+	}
+	return o, nil
+}
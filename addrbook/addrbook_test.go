@@ -0,0 +1,186 @@
+package addrbook
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/bdb"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+var namespaceKey = []byte("addrbook")
+
+// testDB creates a database with a created address book namespace.
+func testDB(t *testing.T) walletdb.DB {
+	t.Helper()
+
+	db, err := walletdb.Create(
+		"bdb", filepath.Join(t.TempDir(), "db"), true, time.Second*10,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns, err := tx.CreateTopLevelBucket(namespaceKey)
+		if err != nil {
+			return err
+		}
+		return Create(ns)
+	})
+	require.NoError(t, err)
+
+	return db
+}
+
+func update(t *testing.T, db walletdb.DB,
+	f func(ns walletdb.ReadWriteBucket) error) error {
+
+	t.Helper()
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return f(tx.ReadWriteBucket(namespaceKey))
+	})
+}
+
+// TestContacts tests that contacts are stored, and that deleting a contact
+// keeps the labels of its addresses.
+func TestContacts(t *testing.T) {
+	t.Parallel()
+
+	db := testDB(t)
+
+	err := update(t, db, func(ns walletdb.ReadWriteBucket) error {
+		require.ErrorIs(t, PutContact(ns, &Contact{}), ErrEmptyLabel)
+		require.ErrorIs(t, PutContact(ns, &Contact{
+			Name: strings.Repeat("a", LabelLimit+1),
+		}), ErrLabelTooLong)
+
+		require.NoError(t, PutContact(ns, &Contact{Name: "bob"}))
+		require.NoError(t, PutContact(ns, &Contact{
+			Name: "alice", Note: "exchange",
+		}))
+
+		c, err := FetchContact(ns, "alice")
+		require.NoError(t, err)
+		require.Equal(t, &Contact{Name: "alice", Note: "exchange"}, c)
+
+		_, err = FetchContact(ns, "carol")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		var names []string
+		err = ForEachContact(ns, func(c *Contact) error {
+			names = append(names, c.Name)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"alice", "bob"}, names)
+
+		// Addresses may only be associated with known contacts.
+		err = PutAddressLabel(ns, &AddressLabel{
+			Address: "addr1", Label: "deposit", Contact: "carol",
+		})
+		require.ErrorIs(t, err, ErrUnknownContact)
+
+		require.NoError(t, PutAddressLabel(ns, &AddressLabel{
+			Address: "addr1", Label: "deposit", Contact: "alice",
+		}))
+		require.NoError(t, PutAddressLabel(ns, &AddressLabel{
+			Address: "addr2", Label: "withdrawal",
+		}))
+
+		require.NoError(t, DeleteContact(ns, "alice"))
+		require.ErrorIs(t, DeleteContact(ns, "alice"), ErrNotFound)
+
+		a, err := FetchAddressLabel(ns, "addr1")
+		require.NoError(t, err)
+		require.Equal(t, &AddressLabel{
+			Address: "addr1", Label: "deposit",
+		}, a)
+
+		require.NoError(t, DeleteAddressLabel(ns, "addr2"))
+		_, err = FetchAddressLabel(ns, "addr2")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestXPubAndOutputLabels tests that extended key and output labels are
+// stored, including the spendable flag of outputs.
+func TestXPubAndOutputLabels(t *testing.T) {
+	t.Parallel()
+
+	db := testDB(t)
+
+	notSpendable := false
+	labels := []*OutputLabel{{
+		OutPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0},
+		Label:    "cold",
+	}, {
+		OutPoint:  wire.OutPoint{Hash: chainhash.Hash{1}, Index: 7},
+		Label:     "dust",
+		Spendable: &notSpendable,
+	}}
+
+	err := update(t, db, func(ns walletdb.ReadWriteBucket) error {
+		require.ErrorIs(t, PutXPubLabel(ns, "xpub1", ""), ErrEmptyLabel)
+		require.NoError(t, PutXPubLabel(ns, "xpub1", "savings"))
+
+		label, err := FetchXPubLabel(ns, "xpub1")
+		require.NoError(t, err)
+		require.Equal(t, "savings", label)
+
+		for _, o := range labels {
+			require.NoError(t, PutOutputLabel(ns, o))
+		}
+
+		o, err := FetchOutputLabel(ns, labels[1].OutPoint)
+		require.NoError(t, err)
+		require.Equal(t, labels[1], o)
+
+		var fetched []*OutputLabel
+		err = ForEachOutputLabel(ns, func(o *OutputLabel) error {
+			fetched = append(fetched, o)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, labels, fetched)
+
+		require.NoError(t, DeleteOutputLabel(ns, labels[0].OutPoint))
+		_, err = FetchOutputLabel(ns, labels[0].OutPoint)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestRecords tests reading and writing BIP-0329 records.
+func TestRecords(t *testing.T) {
+	t.Parallel()
+
+	const data = `{"type":"tx","ref":"f91d0a8a78462bc59398f2c5d7a84fcff491c26ba54c4833478b202796c8aafd","label":"Transaction","origin":"wpkh([d34db33f/84'/0'/0'])"}
+
+{"type":"addr","ref":"bc1q34aq5drpuwy3wgl9lhup9892qp6svr8ldzyy7c","label":"Address"}
+{"type":"output","ref":"f91d0a8a78462bc59398f2c5d7a84fcff491c26ba54c4833478b202796c8aafd:0","label":"Output","spendable":false}
+`
+	records, err := ReadRecords(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, RecordOutput, records[2].Type)
+	require.NotNil(t, records[2].Spendable)
+	require.False(t, *records[2].Spendable)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRecords(&buf, records))
+	require.Equal(t, strings.Replace(data, "\n\n", "\n", 1), buf.String())
+
+	_, err = ReadRecords(strings.NewReader(`{"label":"x"}`))
+	require.Error(t, err)
+}
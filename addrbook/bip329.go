@@ -0,0 +1,74 @@
+package addrbook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RecordType is the type of a BIP-0329 label record.
+type RecordType string
+
+// The BIP-0329 record types.
+const (
+	RecordTx     RecordType = "tx"
+	RecordAddr   RecordType = "addr"
+	RecordPubKey RecordType = "pubkey"
+	RecordInput  RecordType = "input"
+	RecordOutput RecordType = "output"
+	RecordXPub   RecordType = "xpub"
+)
+
+// Record is a BIP-0329 label record.  Ref is the transaction id, address,
+// public key, outpoint (txid:vout) or extended public key being labeled,
+// depending on the record type.
+type Record struct {
+	Type   RecordType `json:"type"`
+	Ref    string     `json:"ref"`
+	Label  string     `json:"label,omitempty"`
+	Origin string     `json:"origin,omitempty"`
+
+	// Spendable is only used by output records.
+	Spendable *bool `json:"spendable,omitempty"`
+}
+
+// ReadRecords reads BIP-0329 records from r, which holds one JSON record per
+// line.  Blank lines are ignored.
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		rec := new(Record)
+		if err := json.Unmarshal(b, rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rec.Type == "" || rec.Ref == "" {
+			return nil, fmt.Errorf("line %d: record type and "+
+				"reference are required", line)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// WriteRecords writes BIP-0329 records to w, one JSON record per line.
+func WriteRecords(w io.Writer, records []*Record) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
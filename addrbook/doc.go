@@ -0,0 +1,11 @@
+// Package addrbook provides persistent storage of the wallet's address book.
+//
+// The address book records contacts and labels for addresses, extended public
+// keys and transaction outputs.  Labeled addresses may belong to the wallet or
+// to third parties, and may optionally be associated with a contact.  Labels
+// can be exchanged with other wallets in the BIP-0329 format using ReadRecords
+// and WriteRecords.
+//
+// Like the wtxmgr and waddrmgr packages, addrbook operates on a walletdb
+// namespace bucket which must be created with Create before use.
+package addrbook
@@ -4,25 +4,22 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/bisoncraft/utxowallet/bisonwire"
 	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
 
-func NetParams(chain, net string) (p *netparams.ChainParams, _ error) {
-	switch chain {
-	case "btc":
-		p = BTCParams[net]
-	case "ltc":
-		p = LTCParams[net]
+// NetParams looks up the parameters for a network of a registered chain.
+func NetParams(chain, net string) (*netparams.ChainParams, error) {
+	def, found := Lookup(bisonwire.Chain(chain))
+	if !found {
+		return nil, fmt.Errorf("unknown chain %s", chain)
 	}
+	p := def.Params[net]
 	if p == nil {
 		return nil, fmt.Errorf("no net params for chain %s, network %s", chain, net)
 	}
-	return
-}
-
-type Chain struct {
-	Params map[string]*netparams.ChainParams
+	return p, nil
 }
 
 var bigOne = big.NewInt(1)
@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/bisoncraft/utxowallet/bisonwire"
 	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -22,9 +23,10 @@ var genesisMerkleRoot = chainhash.Hash([chainhash.HashSize]byte{ // Make go vet
 
 var BTCParams = map[string]*netparams.ChainParams{
 	"mainnet": {
-		Name:        "mainnet",
-		Net:         wire.MainNet,
-		DefaultPort: "8333",
+		Name:          "mainnet",
+		Net:           wire.MainNet,
+		DefaultPort:   "8333",
+		RPCServerPort: "8332",
 		DNSSeeds: []chaincfg.DNSSeed{
 			{"seed.bitcoin.sipa.be", true},
 			{"dnsseed.bluematt.me", true},
@@ -144,17 +146,31 @@ var BTCParams = map[string]*netparams.ChainParams{
 		WitnessPubKeyHashAddrID: 0x06, // starts with p2
 		WitnessScriptHashAddrID: 0x0A, // starts with 7Xh
 		// BIP32 hierarchical deterministic extended key magics
-		HDPrivateKeyID: [4]byte{0x04, 0x88, 0xad, 0xe4}, // starts with xprv
-		HDPublicKeyID:  [4]byte{0x04, 0x88, 0xb2, 0x1e}, // starts with xpub
-		HDCoinType:     0,
-		BIP0034Height:  227931, // 000000000000024b89b42a942fe0d9fea3bb44ab7bd1b19115dd6a759c0808b8
-		BIP0065Height:  388381, // 000000000000000004c2b624ed5d7756c508d90fd0da2c7c679febfa6c4735f0
-		BIP0066Height:  363725, // 00000000000000000379eaa19dce8c9b722d46ae6a57c2f1a988119488b50931
+		HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4}, // starts with xprv
+		HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e}, // starts with xpub
+		HDCoinType:       0,
+		BIP0034Height:    227931, // 000000000000024b89b42a942fe0d9fea3bb44ab7bd1b19115dd6a759c0808b8
+		BIP0065Height:    388381, // 000000000000000004c2b624ed5d7756c508d90fd0da2c7c679febfa6c4735f0
+		BIP0066Height:    363725, // 00000000000000000379eaa19dce8c9b722d46ae6a57c2f1a988119488b50931
+		CoinbaseMaturity: 100,
+		MaxSatoshi:       btcutil.MaxSatoshi,
+		FilterHeaderCheckpoints: map[wire.FilterType]map[uint32]*chainhash.Hash{
+			wire.GCSFilterRegular: {
+				100000: newHashFromStr("f28cbc1ab369eb01b7b5fe8bf59763abb73a31471fe404a26a06be4153aa7fa5"),
+				200000: newHashFromStr("e5031471732f4fbfe7a25f6a03acc1413300d5c56ae8e06b95046b8e4c0f32b3"),
+				300000: newHashFromStr("1bd50220fcdde929ca3143c91d2dd9a9bfedb38c452ba98dbb51e719bff8aa5b"),
+				400000: newHashFromStr("5d973ab1f1c569c70deec1c1a8fb2e317a260f1656edb3b262c65f78ef192e3a"),
+				500000: newHashFromStr("5d16ca293c9bdc0a9bc279b63f99fb661be38b095a59a44200a807caaa631a3c"),
+				600000: newHashFromStr("bde0854d0b2f4386a860462547140e0c6817f5b4b2ab515ef70e204e377598f8"),
+				660000: newHashFromStr("08312375fabc082b17fa8ee88443feb350c19a34bb7483f94f7478fa4ad33032"),
+			},
+		},
 	},
 	"testnet": {
-		Name:        "testnet3",
-		Net:         wire.TestNet3,
-		DefaultPort: "18333",
+		Name:          "testnet3",
+		Net:           wire.TestNet3,
+		DefaultPort:   "18333",
+		RPCServerPort: "18332",
 		DNSSeeds: []chaincfg.DNSSeed{
 			{"testnet-seed.bitcoin.jonasschnelli.ch", true},
 			{"seed.tbtc.petertodd.net", true},
@@ -261,12 +277,29 @@ var BTCParams = map[string]*netparams.ChainParams{
 		BIP0034Height:           21111,  // 0000000023b3a96d3484e5abb3755c413e7d41500f8e2a5c3f0dd01299cd8ef8
 		BIP0065Height:           581885, // 00000000007f6655f22f98e72ed80d8b06dc761d5da09df0fa1dc4be4f861eb6
 		BIP0066Height:           330776, // 000000002104c8c45e99a8853285a3b592602a3ccde2b832481da85e9e4ba182
+		CoinbaseMaturity:        100,
+		MaxSatoshi:              btcutil.MaxSatoshi,
+		FilterHeaderCheckpoints: map[wire.FilterType]map[uint32]*chainhash.Hash{
+			wire.GCSFilterRegular: {
+				100000:  newHashFromStr("97c0633f14625627fcd133250ad8cc525937e776b5f3fd272b06d02c58b65a1c"),
+				200000:  newHashFromStr("51aa817e5abe3acdcf103616b1a5736caf84bc3773a7286e9081108ecc38cc87"),
+				400000:  newHashFromStr("4aab9b3d4312cd85cfcd48a08b36c4402bfdc1e8395dcf4236c3029dfa837c48"),
+				600000:  newHashFromStr("713d9c9198e2dba0739e85aab6875cb951c36297b95a2d51131aa6919753b55d"),
+				800000:  newHashFromStr("0dafdff27269a70293c120b14b1f5e9a72a5e8688098cfc6140b9d64f8325b99"),
+				1000000: newHashFromStr("c2043fa2f6eb5f8f8d2c5584f743187f36302ed86b62c302e31155f378da9c5f"),
+				1400000: newHashFromStr("f9ae1750483d4c8ce82512616b1ded932886af46decb8d3e575907930542d9b3"),
+				1500000: newHashFromStr("dc0cfa13daf09df9b8dbe7532f75ebdb4255860b295016b2ca4b789394bc5090"),
+				1800000: newHashFromStr("67083b2d5dfc9ca1415bffa14e43a5bbe595e2e8b7ffbcc7a4ea78fa069a9c8d"),
+				1900000: newHashFromStr("96a31467f9edcaa3297770bc6cdf66926d5d17dfad70cb0cac285bfe9075c494"),
+			},
+		},
 	},
 	"simnet": {
-		Name:        "regtest",
-		Net:         wire.TestNet,
-		DefaultPort: "18444",
-		DNSSeeds:    []chaincfg.DNSSeed{},
+		Name:          "regtest",
+		Net:           wire.TestNet,
+		DefaultPort:   "18444",
+		RPCServerPort: "18332",
+		DNSSeeds:      []chaincfg.DNSSeed{},
 		GenesisBlock: &wire.MsgBlock{
 			Header: wire.BlockHeader{
 				Version:    1,
@@ -339,6 +372,14 @@ var BTCParams = map[string]*netparams.ChainParams{
 		BIP0034Height:            100000000, // Not active - Permit ver 1 blocks
 		BIP0065Height:            1351,      // Used by regression tests
 		BIP0066Height:            1251,      // Used by regression tests
+		CoinbaseMaturity:         100,
 		MaxSatoshi:               btcutil.MaxSatoshi,
 	},
 }
+
+func init() {
+	mustRegister(bisonwire.ChainBTC, &AssetDefinition{
+		Name:   "Bitcoin",
+		Params: BTCParams,
+	})
+}
@@ -0,0 +1,315 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/bisonwire"
+	"github.com/bisoncraft/utxowallet/netparams"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// ltcMainNet is the network magic for the Litecoin main network.
+	ltcMainNet wire.BitcoinNet = 0xdbb6c0fb
+	// ltcTestNet4 is the network magic for the Litecoin test network
+	// (version 4).
+	ltcTestNet4 wire.BitcoinNet = 0xf1c8d2fd
+	// ltcRegTest is the network magic for the Litecoin regression test
+	// network. It is the same as Bitcoin's.
+	ltcRegTest wire.BitcoinNet = wire.TestNet
+
+	// ltcMaxSatoshi is the maximum transaction amount allowed in litoshi.
+	ltcMaxSatoshi = 84e6 * 1e8
+)
+
+var (
+	// ltcMainPowLimit is the highest proof of work value a Litecoin block
+	// can have for the main and test networks.
+	ltcMainPowLimit, _ = new(big.Int).SetString("0x0fffff000000000000000000000000000000000000000000000000000000", 0)
+
+	// ltcGenesisMerkleRoot is the hash of the first transaction in the genesis
+	// block for all Litecoin networks.
+	ltcGenesisMerkleRoot = chainhash.Hash([chainhash.HashSize]byte{ // Make go vet happy.
+		0xd9, 0xce, 0xd4, 0xed, 0x11, 0x30, 0xf7, 0xb7,
+		0xfa, 0xad, 0x9b, 0xe2, 0x53, 0x23, 0xff, 0xaf,
+		0xa3, 0x32, 0x32, 0xa1, 0x7c, 0x3e, 0xdf, 0x6c,
+		0xfd, 0x97, 0xbe, 0xe6, 0xba, 0xfb, 0xdd, 0x97,
+	})
+
+	// ltcGenesisCoinbaseTx is the coinbase transaction for the genesis blocks
+	// of all Litecoin networks.
+	ltcGenesisCoinbaseTx = &wire.MsgTx{
+		Version: 1,
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: wire.OutPoint{
+					Hash:  chainhash.Hash{},
+					Index: 0xffffffff,
+				},
+				SignatureScript: []byte{
+					0x04, 0xff, 0xff, 0x00, 0x1d, 0x01, 0x04, 0x40, /* |.......@| */
+					0x4e, 0x59, 0x20, 0x54, 0x69, 0x6d, 0x65, 0x73, /* |NY Times| */
+					0x20, 0x30, 0x35, 0x2f, 0x4f, 0x63, 0x74, 0x2f, /* | 05/Oct/| */
+					0x32, 0x30, 0x31, 0x31, 0x20, 0x53, 0x74, 0x65, /* |2011 Ste| */
+					0x76, 0x65, 0x20, 0x4a, 0x6f, 0x62, 0x73, 0x2c, /* |ve Jobs,| */
+					0x20, 0x41, 0x70, 0x70, 0x6c, 0x65, 0xe2, 0x80, /* | Apple..| */
+					0x99, 0x73, 0x20, 0x56, 0x69, 0x73, 0x69, 0x6f, /* |.s Visio| */
+					0x6e, 0x61, 0x72, 0x79, 0x2c, 0x20, 0x44, 0x69, /* |nary, Di| */
+					0x65, 0x73, 0x20, 0x61, 0x74, 0x20, 0x35, 0x36, /* |es at 56| */
+				},
+				Sequence: 0xffffffff,
+			},
+		},
+		TxOut: []*wire.TxOut{
+			{
+				Value: 0x12a05f200,
+				PkScript: []byte{
+					0x41, 0x04, 0x01, 0x84, 0x71, 0x0f, 0xa6, 0x89,
+					0xad, 0x50, 0x23, 0x69, 0x0c, 0x80, 0xf3, 0xa4,
+					0x9c, 0x8f, 0x13, 0xf8, 0xd4, 0x5b, 0x8c, 0x85,
+					0x7f, 0xbc, 0xbc, 0x8b, 0xc4, 0xa8, 0xe4, 0xd3,
+					0xeb, 0x4b, 0x10, 0xf4, 0xd4, 0x60, 0x4f, 0xa0,
+					0x8d, 0xce, 0x60, 0x1a, 0xaf, 0x0f, 0x47, 0x02,
+					0x16, 0xfe, 0x1b, 0x51, 0x85, 0x0b, 0x4a, 0xcf,
+					0x21, 0xb1, 0x79, 0xc4, 0x50, 0x70, 0xac, 0x7b,
+					0x03, 0xa9, 0xac,
+				},
+			},
+		},
+		LockTime: 0,
+	}
+)
+
+// ltcRegTestParams are the parameters for the Litecoin regression test
+// network. The simnet harness runs litecoind in regtest mode, so these
+// parameters are used for both "regtest" and "simnet".
+var ltcRegTestParams = &netparams.ChainParams{
+	Name:          "regtest",
+	Net:           ltcRegTest,
+	DefaultPort:   "19444",
+	RPCServerPort: "19443",
+	DNSSeeds:      []chaincfg.DNSSeed{},
+	GenesisBlock: &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			PrevBlock:  chainhash.Hash{},         // 0000000000000000000000000000000000000000000000000000000000000000
+			MerkleRoot: ltcGenesisMerkleRoot,     // 97ddfbbae6be97fd6cdf3e7ca13232a3afff2353e29badfab7f73011edd4ced9
+			Timestamp:  time.Unix(1296688602, 0), // 2011-02-02 23:16:42 +0000 UTC
+			Bits:       0x207fffff,               // 545259519 [7fffff0000000000000000000000000000000000000000000000000000000000]
+			Nonce:      0,
+		},
+		Transactions: []*wire.MsgTx{ltcGenesisCoinbaseTx},
+	},
+	GenesisHash: hashPointer([chainhash.HashSize]byte{ // Make go vet happy.
+		0xf9, 0x16, 0xc4, 0x56, 0xfc, 0x51, 0xdf, 0x62,
+		0x78, 0x85, 0xd7, 0xd6, 0x74, 0xed, 0x02, 0xdc,
+		0x88, 0xa2, 0x25, 0xad, 0xb3, 0xf0, 0x2a, 0xd1,
+		0x3e, 0xb4, 0x93, 0x8f, 0xf3, 0x27, 0x08, 0x53,
+	}),
+	PowLimit:                 new(big.Int).Sub(new(big.Int).Lsh(bigOne, 255), bigOne),
+	TargetTimespan:           (time.Hour * 24 * 3) + (time.Hour * 12), // 3.5 days
+	TargetTimePerBlock:       (time.Minute * 2) + (time.Second * 30),  // 2.5 minutes
+	RetargetAdjustmentFactor: 4,                                       // 25% less, 400% more
+	Checkpoints:              nil,
+	Bech32HRPSegwit:          "rltc",                          // always rltc for reg test net
+	PubKeyHashAddrID:         0x6f,                            // starts with m or n
+	ScriptHashAddrID:         0x3a,                            // starts with Q
+	PrivateKeyID:             0xef,                            // starts with 9 (uncompressed) or c (compressed)
+	HDPrivateKeyID:           [4]byte{0x04, 0x35, 0x83, 0x94}, // starts with tprv
+	HDPublicKeyID:            [4]byte{0x04, 0x35, 0x87, 0xcf}, // starts with tpub
+	HDCoinType:               1,
+	BIP0034Height:            100000000, // Not active - Permit ver 1 blocks
+	BIP0065Height:            1351,      // Used by regression tests
+	BIP0066Height:            1251,      // Used by regression tests
+	CoinbaseMaturity:         100,
+	CheckPoW:                 checkScryptPoW,
+	MaxSatoshi:               ltcMaxSatoshi,
+}
+
+var LTCParams = map[string]*netparams.ChainParams{
+	"mainnet": {
+		Name:          "mainnet",
+		Net:           ltcMainNet,
+		DefaultPort:   "9333",
+		RPCServerPort: "9332",
+		DNSSeeds: []chaincfg.DNSSeed{
+			{Host: "seed-a.litecoin.loshan.co.uk", HasFiltering: true},
+			{Host: "dnsseed.thrasher.io", HasFiltering: true},
+			{Host: "dnsseed.litecointools.com", HasFiltering: false},
+			{Host: "dnsseed.litecoinpool.org", HasFiltering: false},
+		},
+
+		// Chain parameters
+		GenesisBlock: &wire.MsgBlock{
+			Header: wire.BlockHeader{
+				Version:    1,
+				PrevBlock:  chainhash.Hash{},         // 0000000000000000000000000000000000000000000000000000000000000000
+				MerkleRoot: ltcGenesisMerkleRoot,     // 97ddfbbae6be97fd6cdf3e7ca13232a3afff2353e29badfab7f73011edd4ced9
+				Timestamp:  time.Unix(1317972665, 0), // 2011-10-07 07:31:05 +0000 UTC
+				Bits:       0x1e0ffff0,               // 504365040
+				Nonce:      2084524493,
+			},
+			Transactions: []*wire.MsgTx{ltcGenesisCoinbaseTx},
+		},
+		GenesisHash: hashPointer([chainhash.HashSize]byte{ // Make go vet happy.
+			0xe2, 0xbf, 0x04, 0x7e, 0x7e, 0x5a, 0x19, 0x1a,
+			0xa4, 0xef, 0x34, 0xd3, 0x14, 0x97, 0x9d, 0xc9,
+			0x98, 0x6e, 0x0f, 0x19, 0x25, 0x1e, 0xda, 0xba,
+			0x59, 0x40, 0xfd, 0x1f, 0xe3, 0x65, 0xa7, 0x12,
+		}),
+		PowLimit:                 ltcMainPowLimit,
+		TargetTimespan:           (time.Hour * 24 * 3) + (time.Hour * 12), // 3.5 days
+		TargetTimePerBlock:       (time.Minute * 2) + (time.Second * 30),  // 2.5 minutes
+		RetargetAdjustmentFactor: 4,                                       // 25% less, 400% more
+
+		// Checkpoints ordered from oldest to newest.
+		Checkpoints: []chaincfg.Checkpoint{
+			{Height: 1500, Hash: newHashFromStr("841a2965955dd288cfa707a755d05a54e45f8bd476835ec9af4402a2b59a2967")},
+			{Height: 4032, Hash: newHashFromStr("9ce90e427198fc0ef05e5905ce3503725b80e26afd35a987965fd7e3d9cf0846")},
+			{Height: 8064, Hash: newHashFromStr("eb984353fc5190f210651f150c40b8a4bab9eeeff0b729fcb3987da694430d70")},
+			{Height: 16128, Hash: newHashFromStr("602edf1859b7f9a6af809f1d9b0e6cb66fdc1d4d9dcd7a4bec03e12a1ccd153d")},
+			{Height: 23420, Hash: newHashFromStr("d80fdf9ca81afd0bd2b2a90ac3a9fe547da58f2530ec874e978fce0b5101b507")},
+			{Height: 50000, Hash: newHashFromStr("69dc37eb029b68f075a5012dcc0419c127672adb4f3a32882b2b3e71d07a20a6")},
+			{Height: 80000, Hash: newHashFromStr("4fcb7c02f676a300503f49c764a89955a8f920b46a8cbecb4867182ecdb2e90a")},
+			{Height: 120000, Hash: newHashFromStr("bd9d26924f05f6daa7f0155f32828ec89e8e29cee9e7121b026a7a3552ac6131")},
+			{Height: 161500, Hash: newHashFromStr("dbe89880474f4bb4f75c227c77ba1cdc024991123b28b8418dbbf7798471ff43")},
+			{Height: 179620, Hash: newHashFromStr("2ad9c65c990ac00426d18e446e0fd7be2ffa69e9a7dcb28358a50b2b78b9f709")},
+			{Height: 240000, Hash: newHashFromStr("7140d1c4b4c2157ca217ee7636f24c9c73db39c4590c4e6eab2e3ea1555088aa")},
+			{Height: 383640, Hash: newHashFromStr("2b6809f094a9215bafc65eb3f110a35127a34be94b7d0590a096c3f126c6f364")},
+			{Height: 409004, Hash: newHashFromStr("487518d663d9f1fa08611d9395ad74d982b667fbdc0e77e9cf39b4f1355908a3")},
+			{Height: 456000, Hash: newHashFromStr("bf34f71cc6366cd487930d06be22f897e34ca6a40501ac7d401be32456372004")},
+			{Height: 638902, Hash: newHashFromStr("15238656e8ec63d28de29a8c75fcf3a5819afc953dcd9cc45cecc53baec74f38")},
+			{Height: 721000, Hash: newHashFromStr("198a7b4de1df9478e2463bd99d75b714eab235a2e63e741641dc8a759a9840e5")},
+		},
+		Bech32HRPSegwit:  "ltc",
+		PubKeyHashAddrID: 0x30, // starts with L
+		ScriptHashAddrID: 0x32, // starts with M
+		PrivateKeyID:     0xB0, // starts with 6 (uncompressed) or T (compressed)
+		// BIP32 hierarchical deterministic extended key magics
+		HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4}, // starts with xprv
+		HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e}, // starts with xpub
+		HDCoinType:       2,
+		BIP0034Height:    710000,
+		BIP0065Height:    918684,
+		BIP0066Height:    811879,
+		CoinbaseMaturity: 100,
+		CheckPoW:         checkScryptPoW,
+		MaxSatoshi:       ltcMaxSatoshi,
+	},
+	"testnet": {
+		Name:          "testnet4",
+		Net:           ltcTestNet4,
+		DefaultPort:   "19335",
+		RPCServerPort: "19332",
+		DNSSeeds: []chaincfg.DNSSeed{
+			{Host: "testnet-seed.litecointools.com", HasFiltering: false},
+			{Host: "seed-b.litecoin.loshan.co.uk", HasFiltering: true},
+			{Host: "dnsseed-testnet.thrasher.io", HasFiltering: true},
+		},
+		GenesisBlock: &wire.MsgBlock{
+			Header: wire.BlockHeader{
+				Version:    1,
+				PrevBlock:  chainhash.Hash{},         // 0000000000000000000000000000000000000000000000000000000000000000
+				MerkleRoot: ltcGenesisMerkleRoot,     // 97ddfbbae6be97fd6cdf3e7ca13232a3afff2353e29badfab7f73011edd4ced9
+				Timestamp:  time.Unix(1486949366, 0), // 2017-02-13 01:29:26 +0000 UTC
+				Bits:       0x1e0ffff0,               // 504365040
+				Nonce:      293345,
+			},
+			Transactions: []*wire.MsgTx{ltcGenesisCoinbaseTx},
+		},
+		GenesisHash: hashPointer([chainhash.HashSize]byte{ // Make go vet happy.
+			0xa0, 0x29, 0x3e, 0x4e, 0xeb, 0x3d, 0xa6, 0xe6,
+			0xf5, 0x6f, 0x81, 0xed, 0x59, 0x5f, 0x57, 0x88,
+			0x0d, 0x1a, 0x21, 0x56, 0x9e, 0x13, 0xee, 0xfd,
+			0xd9, 0x51, 0x28, 0x4b, 0x5a, 0x62, 0x66, 0x49,
+		}),
+		PowLimit:                 ltcMainPowLimit,
+		TargetTimespan:           (time.Hour * 24 * 3) + (time.Hour * 12), // 3.5 days
+		TargetTimePerBlock:       (time.Minute * 2) + (time.Second * 30),  // 2.5 minutes
+		RetargetAdjustmentFactor: 4,                                       // 25% less, 400% more
+
+		// Checkpoints ordered from oldest to newest.
+		Checkpoints: []chaincfg.Checkpoint{
+			{Height: 26115, Hash: newHashFromStr("817d5b509e91ab5e439652eee2f59271bbc7ba85021d720cdb6da6565b43c14f")},
+			{Height: 43928, Hash: newHashFromStr("7d86614c153f5ef6ad878483118ae523e248cd0dd0345330cb148e812493cbb4")},
+			{Height: 69296, Hash: newHashFromStr("66c2f58da3cfd282093b55eb09c1f5287d7a18801a8ff441830e67e8771010df")},
+			{Height: 99949, Hash: newHashFromStr("8dd471cb5aecf5ead91e7e4b1e932c79a0763060f8d93671b6801d115bfc6cde")},
+			{Height: 159256, Hash: newHashFromStr("ab5b0b9968842f5414804591119d6db829af606864b1959a25d6f5c114afb2b7")},
+			{Height: 2394367, Hash: newHashFromStr("bc5829f4973d0797755efee11313687b3c63ee2f70b60b62eebcd10283534327")},
+		},
+		Bech32HRPSegwit: "tltc", // always tltc for test net
+
+		// Address encoding magics
+		PubKeyHashAddrID:        0x6f,                            // starts with m or n
+		ScriptHashAddrID:        0x3a,                            // starts with Q
+		WitnessPubKeyHashAddrID: 0x52,                            // starts with QW
+		WitnessScriptHashAddrID: 0x31,                            // starts with T7n
+		PrivateKeyID:            0xef,                            // starts with 9 (uncompressed) or c (compressed)
+		HDPrivateKeyID:          [4]byte{0x04, 0x35, 0x83, 0x94}, // starts with tprv
+		HDPublicKeyID:           [4]byte{0x04, 0x35, 0x87, 0xcf}, // starts with tpub
+		HDCoinType:              1,
+		BIP0034Height:           76,
+		BIP0065Height:           76,
+		BIP0066Height:           76,
+		CoinbaseMaturity:        100,
+		CheckPoW:                checkScryptPoW,
+		MaxSatoshi:              ltcMaxSatoshi,
+	},
+	"regtest": ltcRegTestParams,
+	"simnet":  ltcRegTestParams,
+}
+
+func init() {
+	// btcutil only recognizes bech32 addresses whose human-readable part
+	// belongs to a registered network. chaincfg.Register rejects duplicate
+	// network magics, and Litecoin's regtest magic is the same as Bitcoin's,
+	// so the regtest params are registered under a placeholder magic. Only
+	// the address encoding magics matter for decoding.
+	for _, p := range []*netparams.ChainParams{LTCParams["mainnet"], LTCParams["testnet"], ltcRegTestParams} {
+		btcdParams := p.BTCDParams()
+		if p.Net == ltcRegTest {
+			btcdParams.Net = 0xfabfb5da
+		}
+		if err := chaincfg.Register(btcdParams); err != nil {
+			panic(fmt.Sprintf("failed to register %s params for Litecoin: %v", p.Name, err))
+		}
+	}
+
+	mustRegister(bisonwire.ChainLTC, &AssetDefinition{
+		Name:        "Litecoin",
+		Params:      LTCParams,
+		MakeMessage: bisonwire.LTCMessage,
+	})
+}
+
+// checkScryptPoW checks that the scrypt hash of the block header satisfies the
+// target difficulty claimed in the header's bits. The target's range is checked
+// separately against the chain's PowLimit.
+func checkScryptPoW(hdr *wire.BlockHeader) error {
+	var b bytes.Buffer
+	b.Grow(wire.MaxBlockHeaderPayload)
+	if err := hdr.Serialize(&b); err != nil {
+		return err
+	}
+	powBytes, err := scrypt.Key(b.Bytes(), b.Bytes(), 1024, 1, 1, 32)
+	if err != nil {
+		return err
+	}
+	var powHash chainhash.Hash
+	copy(powHash[:], powBytes)
+
+	target := blockchain.CompactToBig(hdr.Bits)
+	if blockchain.HashToBig(&powHash).Cmp(target) > 0 {
+		str := fmt.Sprintf("block's scrypt hash %s is higher than "+
+			"expected max of %064x", powHash, target)
+		return blockchain.RuleError{ErrorCode: blockchain.ErrHighHash, Description: str}
+	}
+	return nil
+}
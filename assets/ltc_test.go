@@ -0,0 +1,27 @@
+package assets
+
+import (
+	"testing"
+)
+
+func TestLTCGenesis(t *testing.T) {
+	for net, p := range LTCParams {
+		genesis := p.GenesisBlock
+		if h := genesis.BlockHash(); h != *p.GenesisHash {
+			t.Fatalf("%s: genesis hash mismatch. expected %s, got %s", net, p.GenesisHash, h)
+		}
+		if root := genesis.Transactions[0].TxHash(); root != genesis.Header.MerkleRoot {
+			t.Fatalf("%s: genesis merkle root mismatch. expected %s, got %s", net, genesis.Header.MerkleRoot, root)
+		}
+		if err := p.CheckPoW(&genesis.Header); err != nil {
+			t.Fatalf("%s: genesis block failed proof-of-work check: %v", net, err)
+		}
+	}
+
+	// Bump the nonce and make sure the mainnet header no longer checks out.
+	hdr := LTCParams["mainnet"].GenesisBlock.Header
+	hdr.Nonce++
+	if err := checkScryptPoW(&hdr); err == nil {
+		t.Fatalf("no error for invalid proof of work")
+	}
+}
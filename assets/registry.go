@@ -0,0 +1,87 @@
+package assets
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bisoncraft/utxowallet/bisonwire"
+	"github.com/bisoncraft/utxowallet/netparams"
+)
+
+// AssetDefinition is everything needed to run a wallet for a chain.
+type AssetDefinition struct {
+	// Name is the human-readable name of the asset, e.g. "Bitcoin".
+	Name string
+	// Params are the chain parameters keyed by network name, e.g.
+	// "mainnet", "testnet", "simnet". The parameters carry the default P2P
+	// ports and the filter header checkpoints for the network.
+	Params map[string]*netparams.ChainParams
+	// MakeMessage creates empty wire messages for commands whose
+	// serialization differs from Bitcoin's. It may be nil.
+	MakeMessage bisonwire.MessageFactory
+}
+
+var (
+	registryMtx sync.RWMutex
+	registry    = make(map[bisonwire.Chain]*AssetDefinition)
+)
+
+// Register registers an asset. Register is typically called from an init
+// function of the package defining the asset. Registering a chain that is
+// already registered is an error.
+func Register(chain bisonwire.Chain, def *AssetDefinition) error {
+	if chain == "" {
+		return fmt.Errorf("no chain specified")
+	}
+	if def == nil || len(def.Params) == 0 {
+		return fmt.Errorf("no network parameters for chain %s", chain)
+	}
+	for net, p := range def.Params {
+		if p == nil || p.GenesisBlock == nil || p.GenesisHash == nil {
+			return fmt.Errorf("incomplete %s parameters for chain %s", net, chain)
+		}
+		if p.MaxSatoshi <= 0 {
+			return fmt.Errorf("no maximum amount in %s parameters for chain %s", net, chain)
+		}
+	}
+
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+	if _, exists := registry[chain]; exists {
+		return fmt.Errorf("chain %s is already registered", chain)
+	}
+	if err := bisonwire.RegisterChain(chain, def.MakeMessage); err != nil {
+		return err
+	}
+	registry[chain] = def
+	return nil
+}
+
+// mustRegister registers an asset and panics on error. It is used for the
+// built-in assets.
+func mustRegister(chain bisonwire.Chain, def *AssetDefinition) {
+	if err := Register(chain, def); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the definition for a registered asset.
+func Lookup(chain bisonwire.Chain) (*AssetDefinition, bool) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	def, found := registry[chain]
+	return def, found
+}
+
+// Chains returns the registered chains, sorted.
+func Chains() []bisonwire.Chain {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	chains := make([]bisonwire.Chain, 0, len(registry))
+	for chain := range registry {
+		chains = append(chains, chain)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i] < chains[j] })
+	return chains
+}
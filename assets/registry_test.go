@@ -0,0 +1,50 @@
+package assets
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/bisonwire"
+	"github.com/bisoncraft/utxowallet/netparams"
+)
+
+func TestRegistry(t *testing.T) {
+	chains := Chains()
+	if len(chains) != 2 || chains[0] != bisonwire.ChainBTC || chains[1] != bisonwire.ChainLTC {
+		t.Fatalf("wrong built-in chains: %v", chains)
+	}
+
+	if err := Register(bisonwire.ChainBTC, &AssetDefinition{Params: BTCParams}); err == nil {
+		t.Fatalf("no error for duplicate registration")
+	}
+	if err := Register("xyz", &AssetDefinition{}); err == nil {
+		t.Fatalf("no error for registration without params")
+	}
+
+	noMax := *BTCParams["simnet"]
+	noMax.MaxSatoshi = 0
+	if err := Register("xyz", &AssetDefinition{
+		Params: map[string]*netparams.ChainParams{"mainnet": &noMax},
+	}); err == nil {
+		t.Fatalf("no error for registration without a maximum amount")
+	}
+
+	if err := Register("xyz", &AssetDefinition{
+		Name:   "Fakecoin",
+		Params: map[string]*netparams.ChainParams{"mainnet": BTCParams["simnet"]},
+	}); err != nil {
+		t.Fatalf("error registering chain: %v", err)
+	}
+	if _, err := bisonwire.ChainFromString("xyz"); err != nil {
+		t.Fatalf("registered chain not known to bisonwire: %v", err)
+	}
+	p, err := NetParams("xyz", "mainnet")
+	if err != nil {
+		t.Fatalf("error getting params for registered chain: %v", err)
+	}
+	if p != BTCParams["simnet"] {
+		t.Fatalf("wrong params returned")
+	}
+	if _, err := NetParams("xyz", "testnet"); err == nil {
+		t.Fatalf("no error for unknown network")
+	}
+}
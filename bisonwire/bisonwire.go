@@ -2,6 +2,8 @@ package bisonwire
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/btcsuite/btcd/wire"
 )
@@ -13,24 +15,72 @@ const (
 	ChainLTC Chain = "ltc"
 )
 
-var KnownChains = []Chain{
-	ChainBTC,
-	ChainLTC,
+// MessageFactory creates an empty message for a command. A factory returns a
+// nil message for commands that use the standard btcd types.
+type MessageFactory func(command string) wire.Message
+
+var (
+	chainsMtx sync.RWMutex
+	chains    = make(map[Chain]MessageFactory)
+)
+
+// RegisterChain registers a chain and its message factory. The factory may be
+// nil if the chain uses only standard btcd messages.
+func RegisterChain(chain Chain, factory MessageFactory) error {
+	chainsMtx.Lock()
+	defer chainsMtx.Unlock()
+	if _, exists := chains[chain]; exists {
+		return fmt.Errorf("chain %s is already registered", chain)
+	}
+	chains[chain] = factory
+	return nil
+}
+
+// KnownChains returns the registered chains, sorted.
+func KnownChains() []Chain {
+	chainsMtx.RLock()
+	defer chainsMtx.RUnlock()
+	cs := make([]Chain, 0, len(chains))
+	for c := range chains {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i] < cs[j] })
+	return cs
 }
 
 func ChainFromString(s string) (Chain, error) {
 	sc := Chain(s)
-	for _, c := range KnownChains {
-		if c == sc {
-			return c, nil
-		}
+	chainsMtx.RLock()
+	defer chainsMtx.RUnlock()
+	if _, found := chains[sc]; found {
+		return sc, nil
 	}
 	return "", fmt.Errorf("no known chain identified by %q", s)
 }
 
+func messageFactory(chain Chain) MessageFactory {
+	chainsMtx.RLock()
+	defer chainsMtx.RUnlock()
+	return chains[chain]
+}
+
+// extendedMessage is a chain-specific variation of a standard message that
+// carries data the btcd types can't represent.
+type extendedMessage interface {
+	wire.Message
+	// BaseMessage returns the standard btcd message.
+	BaseMessage() wire.Message
+}
+
 // makeEmptyMessage creates a message of the appropriate concrete type based
 // on the command.
 func makeEmptyMessage(chain Chain, command string) (wire.Message, error) {
+	if factory := messageFactory(chain); factory != nil {
+		if msg := factory(command); msg != nil {
+			return msg, nil
+		}
+	}
+
 	var msg wire.Message
 	switch command {
 	// Bisonwire variations
@@ -94,6 +94,11 @@ func WriteMessageWithEncodingN(w io.Writer, msg wire.Message, pver uint32,
 // comprise the message.  This function is the same as ReadMessageN except it
 // allows the caller to specify which message encoding is to consult when
 // decoding wire messages.
+//
+// Blocks and transactions are decoded according to the chain's serialization,
+// e.g. Litecoin's MWEB extensions, but are returned as *wire.MsgBlock and
+// *wire.MsgTx. Use the chain-specific types, such as LTCMsgBlock, to decode
+// the returned payload if the extension data is needed.
 func ReadMessageWithEncodingN(
 	r io.Reader,
 	pver uint32,
@@ -179,6 +184,13 @@ func ReadMessageWithEncodingN(
 		return totalBytes, nil, nil, err
 	}
 
+	// Chain-specific variations are returned as their standard btcd types
+	// so that consumers only need to handle those. Any extension data is
+	// still present in the raw payload.
+	if ext, ok := msg.(extendedMessage); ok {
+		msg = ext.BaseMessage()
+	}
+
 	return totalBytes, msg, payload, nil
 }
 
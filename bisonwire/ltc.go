@@ -0,0 +1,683 @@
+package bisonwire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// ltcMwebFlag is the transaction flag bit that indicates the presence
+	// of MWEB data after the witnesses.
+	ltcMwebFlag = 0x08
+
+	// Limits used to reject nonsensical counts before allocating.
+	maxTxInPerMessage       = wire.MaxMessagePayload/41 + 1
+	maxTxOutPerMessage      = wire.MaxMessagePayload/9 + 1
+	maxWitnessItemsPerInput = 500000
+	maxWitnessItemSize      = 4_000_000
+	maxTxPerBlock           = wire.MaxBlockPayload/10 + 1
+	maxMwebItems            = 1 << 16
+)
+
+// LTCMessage is the MessageFactory for Litecoin. Blocks and transactions are
+// decoded with the MWEB-aware types.
+func LTCMessage(command string) wire.Message {
+	switch command {
+	case wire.CmdBlock:
+		return &LTCMsgBlock{}
+	case wire.CmdTx:
+		return &LTCMsgTx{}
+	}
+	return nil
+}
+
+// LTCMsgTx is a Litecoin transaction. Transactions that are pegging into or
+// out of the MWEB, as well as the HogEx transaction that closes every MWEB
+// block, carry data beyond the BIP144 serialization, signaled by the 0x08 flag
+// bit. The MWEB data is retained as raw bytes, so a decoded transaction can be
+// re-serialized exactly as it was received. The embedded wire.MsgTx is the
+// canonical part of the transaction, and its TxHash is the Litecoin txid.
+type LTCMsgTx struct {
+	wire.MsgTx
+	// IsHogEx is true for the HogEx (integrating) transaction, which is
+	// flagged as MWEB but carries no MWEB transaction.
+	IsHogEx bool
+	// MWEB is the raw serialized MWEB transaction, if any.
+	MWEB []byte
+}
+
+var _ wire.Message = (*LTCMsgTx)(nil)
+
+// HasMWEB is true if the transaction will be serialized with the MWEB flag.
+func (msg *LTCMsgTx) HasMWEB() bool {
+	return msg.IsHogEx || len(msg.MWEB) > 0
+}
+
+// StripMWEB removes the MWEB data from the transaction so that it is
+// serialized as a standard transaction.
+func (msg *LTCMsgTx) StripMWEB() {
+	msg.IsHogEx = false
+	msg.MWEB = nil
+}
+
+// BaseMessage returns the canonical part of the transaction.
+func (msg *LTCMsgTx) BaseMessage() wire.Message {
+	return &msg.MsgTx
+}
+
+// BtcDecode decodes r using the Litecoin protocol encoding into the receiver.
+// This is part of the wire.Message interface implementation.
+func (msg *LTCMsgTx) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	msg.IsHogEx = false
+	msg.MWEB = nil
+
+	version, err := binarySerializer.Uint32(r, littleEndian)
+	if err != nil {
+		return err
+	}
+	tx := &msg.MsgTx
+	tx.Version = int32(version)
+
+	count, err := wire.ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	// A count of zero means that the value is the flag marker, and the
+	// flag byte follows.
+	var hasWitness, hasMweb bool
+	if count == 0 && enc == wire.WitnessEncoding {
+		flag, err := binarySerializer.Uint8(r)
+		if err != nil {
+			return err
+		}
+		hasWitness = flag&byte(wire.WitnessFlag) != 0
+		hasMweb = flag&ltcMwebFlag != 0
+		if flag == 0 || flag&^(byte(wire.WitnessFlag)|ltcMwebFlag) != 0 {
+			str := fmt.Sprintf("unknown transaction flag %#02x", flag)
+			return messageError("LTCMsgTx.BtcDecode", str)
+		}
+
+		if count, err = wire.ReadVarInt(r, pver); err != nil {
+			return err
+		}
+	}
+
+	if count > maxTxInPerMessage {
+		str := fmt.Sprintf("too many input transactions to fit into "+
+			"max message size [count %d, max %d]", count,
+			maxTxInPerMessage)
+		return messageError("LTCMsgTx.BtcDecode", str)
+	}
+	tx.TxIn = make([]*wire.TxIn, count)
+	for i := range tx.TxIn {
+		txIn := new(wire.TxIn)
+		if err := readTxIn(r, pver, txIn); err != nil {
+			return err
+		}
+		tx.TxIn[i] = txIn
+	}
+
+	if count, err = wire.ReadVarInt(r, pver); err != nil {
+		return err
+	}
+	if count > maxTxOutPerMessage {
+		str := fmt.Sprintf("too many output transactions to fit into "+
+			"max message size [count %d, max %d]", count,
+			maxTxOutPerMessage)
+		return messageError("LTCMsgTx.BtcDecode", str)
+	}
+	tx.TxOut = make([]*wire.TxOut, count)
+	for i := range tx.TxOut {
+		txOut := new(wire.TxOut)
+		if err := wire.ReadTxOut(r, pver, tx.Version, txOut); err != nil {
+			return err
+		}
+		tx.TxOut[i] = txOut
+	}
+
+	if hasWitness {
+		for _, txIn := range tx.TxIn {
+			witCount, err := wire.ReadVarInt(r, pver)
+			if err != nil {
+				return err
+			}
+			if witCount > maxWitnessItemsPerInput {
+				str := fmt.Sprintf("too many witness items to fit "+
+					"into max message size [count %d, max %d]",
+					witCount, maxWitnessItemsPerInput)
+				return messageError("LTCMsgTx.BtcDecode", str)
+			}
+			txIn.Witness = make(wire.TxWitness, witCount)
+			for j := range txIn.Witness {
+				txIn.Witness[j], err = wire.ReadVarBytes(r, pver,
+					maxWitnessItemSize, "script witness item")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if hasMweb {
+		d := newMwebDecoder(r)
+		haveMwebTx, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		if haveMwebTx == 0 {
+			// The HogEx has the flag set, but no MWEB transaction.
+			if len(tx.TxOut) == 0 {
+				return messageError("LTCMsgTx.BtcDecode",
+					"no outputs on HogEx transaction")
+			}
+			msg.IsHogEx = true
+		} else {
+			d.record()
+			if err := d.readMwebTx(); err != nil {
+				return fmt.Errorf("error decoding MWEB transaction: %w", err)
+			}
+			msg.MWEB = d.recorded()
+		}
+	}
+
+	tx.LockTime, err = binarySerializer.Uint32(r, littleEndian)
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the Litecoin protocol encoding.
+// MWEB data is only included with the witness encoding.
+// This is part of the wire.Message interface implementation.
+func (msg *LTCMsgTx) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if enc != wire.WitnessEncoding || !msg.HasMWEB() {
+		return msg.MsgTx.BtcEncode(w, pver, enc)
+	}
+
+	tx := &msg.MsgTx
+	if err := binarySerializer.PutUint32(w, littleEndian, uint32(tx.Version)); err != nil {
+		return err
+	}
+
+	flag := byte(ltcMwebFlag)
+	hasWitness := tx.HasWitness()
+	if hasWitness {
+		flag |= byte(wire.WitnessFlag)
+	}
+	if _, err := w.Write([]byte{0x00, flag}); err != nil {
+		return err
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(tx.TxIn))); err != nil {
+		return err
+	}
+	for _, txIn := range tx.TxIn {
+		if err := writeTxIn(w, pver, txIn); err != nil {
+			return err
+		}
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(tx.TxOut))); err != nil {
+		return err
+	}
+	for _, txOut := range tx.TxOut {
+		if err := wire.WriteTxOut(w, pver, tx.Version, txOut); err != nil {
+			return err
+		}
+	}
+
+	if hasWitness {
+		for _, txIn := range tx.TxIn {
+			if err := wire.WriteVarInt(w, pver, uint64(len(txIn.Witness))); err != nil {
+				return err
+			}
+			for _, item := range txIn.Witness {
+				if err := wire.WriteVarBytes(w, pver, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if msg.IsHogEx {
+		if err := binarySerializer.PutUint8(w, 0x00); err != nil {
+			return err
+		}
+	} else {
+		if err := binarySerializer.PutUint8(w, 0x01); err != nil {
+			return err
+		}
+		if _, err := w.Write(msg.MWEB); err != nil {
+			return err
+		}
+	}
+
+	return binarySerializer.PutUint32(w, littleEndian, tx.LockTime)
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver. This is part of the wire.Message interface implementation.
+func (msg *LTCMsgTx) MaxPayloadLength(pver uint32) uint32 {
+	return wire.MaxMessagePayload
+}
+
+// Deserialize decodes a transaction from r into the receiver using the
+// witness encoding.
+func (msg *LTCMsgTx) Deserialize(r io.Reader) error {
+	return msg.BtcDecode(r, 0, wire.WitnessEncoding)
+}
+
+// Serialize encodes the transaction to w using the witness encoding, including
+// any MWEB data.
+func (msg *LTCMsgTx) Serialize(w io.Writer) error {
+	return msg.BtcEncode(w, 0, wire.WitnessEncoding)
+}
+
+// LTCMsgBlock is a Litecoin block. Since the MWEB activation, blocks may carry
+// a HogEx as their last transaction and an MWEB extension block after the
+// transactions. The extension block is retained as raw bytes so the block can
+// be re-serialized as received, or stripped with StripMWEB. The embedded
+// wire.MsgBlock holds the canonical transactions.
+type LTCMsgBlock struct {
+	wire.MsgBlock
+	// HogEx is true if the last transaction is the HogEx.
+	HogEx bool
+	// MWEB is the raw serialized extension block, if any.
+	MWEB []byte
+	// mwebTxs holds the raw MWEB data of any transactions other than the
+	// HogEx, indexed by their position in the block.
+	mwebTxs map[int][]byte
+}
+
+var _ wire.Message = (*LTCMsgBlock)(nil)
+
+// StripMWEB removes all MWEB data from the block so that it is serialized as a
+// standard block.
+func (msg *LTCMsgBlock) StripMWEB() {
+	msg.HogEx = false
+	msg.MWEB = nil
+	msg.mwebTxs = nil
+}
+
+// BaseMessage returns the canonical part of the block.
+func (msg *LTCMsgBlock) BaseMessage() wire.Message {
+	return &msg.MsgBlock
+}
+
+// BtcDecode decodes r using the Litecoin protocol encoding into the receiver.
+// This is part of the wire.Message interface implementation.
+func (msg *LTCMsgBlock) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding) error {
+	msg.StripMWEB()
+
+	if err := msg.Header.Deserialize(r); err != nil {
+		return err
+	}
+
+	txCount, err := wire.ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if txCount > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions to fit into a block "+
+			"[count %d, max %d]", txCount, maxTxPerBlock)
+		return messageError("LTCMsgBlock.BtcDecode", str)
+	}
+
+	msg.Transactions = make([]*wire.MsgTx, 0, txCount)
+	for i := 0; i < int(txCount); i++ {
+		var tx LTCMsgTx
+		if err := tx.BtcDecode(r, pver, enc); err != nil {
+			return err
+		}
+		msg.Transactions = append(msg.Transactions, &tx.MsgTx)
+		msg.HogEx = tx.IsHogEx
+		if len(tx.MWEB) > 0 {
+			if msg.mwebTxs == nil {
+				msg.mwebTxs = make(map[int][]byte)
+			}
+			msg.mwebTxs[i] = tx.MWEB
+		}
+	}
+
+	// The extension block follows the transactions of blocks whose last
+	// transaction is the HogEx, regardless of the header version.
+	if !msg.HogEx {
+		return nil
+	}
+	d := newMwebDecoder(r)
+	haveMweb, err := d.readByte()
+	if err != nil {
+		return fmt.Errorf("error reading MWEB option byte: %w", err)
+	}
+	if haveMweb == 0 {
+		return nil
+	}
+	d.record()
+	if err := d.readMwebBlock(); err != nil {
+		return fmt.Errorf("error decoding MWEB extension block: %w", err)
+	}
+	msg.MWEB = d.recorded()
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the Litecoin protocol encoding.
+// This is part of the wire.Message interface implementation.
+func (msg *LTCMsgBlock) BtcEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if err := msg.Header.Serialize(w); err != nil {
+		return err
+	}
+
+	if err := wire.WriteVarInt(w, pver, uint64(len(msg.Transactions))); err != nil {
+		return err
+	}
+	lastIdx := len(msg.Transactions) - 1
+	for i, tx := range msg.Transactions {
+		ltcTx := LTCMsgTx{
+			MsgTx:   *tx,
+			IsHogEx: msg.HogEx && i == lastIdx,
+			MWEB:    msg.mwebTxs[i],
+		}
+		if err := ltcTx.BtcEncode(w, pver, enc); err != nil {
+			return err
+		}
+	}
+
+	if enc != wire.WitnessEncoding || !msg.HogEx {
+		return nil
+	}
+	if len(msg.MWEB) == 0 {
+		return binarySerializer.PutUint8(w, 0x00)
+	}
+	if err := binarySerializer.PutUint8(w, 0x01); err != nil {
+		return err
+	}
+	_, err := w.Write(msg.MWEB)
+	return err
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver. The extension block is not counted against the base block's size
+// limit, so allow for the maximum message size. This is part of the
+// wire.Message interface implementation.
+func (msg *LTCMsgBlock) MaxPayloadLength(pver uint32) uint32 {
+	return wire.MaxMessagePayload
+}
+
+// Deserialize decodes a block from r into the receiver using the witness
+// encoding.
+func (msg *LTCMsgBlock) Deserialize(r io.Reader) error {
+	return msg.BtcDecode(r, 0, wire.WitnessEncoding)
+}
+
+// Serialize encodes the block to w using the witness encoding, including any
+// MWEB data.
+func (msg *LTCMsgBlock) Serialize(w io.Writer) error {
+	return msg.BtcEncode(w, 0, wire.WitnessEncoding)
+}
+
+// readTxIn reads the next sequence of bytes from r as a transaction input.
+func readTxIn(r io.Reader, pver uint32, ti *wire.TxIn) error {
+	op := &ti.PreviousOutPoint
+	if _, err := io.ReadFull(r, op.Hash[:]); err != nil {
+		return err
+	}
+	var err error
+	if op.Index, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+		return err
+	}
+	ti.SignatureScript, err = wire.ReadVarBytes(r, pver, wire.MaxMessagePayload,
+		"transaction input signature script")
+	if err != nil {
+		return err
+	}
+	ti.Sequence, err = binarySerializer.Uint32(r, littleEndian)
+	return err
+}
+
+// writeTxIn encodes ti to the protocol encoding for a transaction input to w.
+func writeTxIn(w io.Writer, pver uint32, ti *wire.TxIn) error {
+	op := &ti.PreviousOutPoint
+	if _, err := w.Write(op.Hash[:]); err != nil {
+		return err
+	}
+	if err := binarySerializer.PutUint32(w, littleEndian, op.Index); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, pver, ti.SignatureScript); err != nil {
+		return err
+	}
+	return binarySerializer.PutUint32(w, littleEndian, ti.Sequence)
+}
+
+// mwebDecoder walks MWEB structures without interpreting them. The MWEB
+// serialization is defined in litecoin's src/libmw/include/mw/models. Bytes
+// read while recording are retained so the structures can be re-serialized.
+type mwebDecoder struct {
+	r   io.Reader
+	rec *bytes.Buffer
+}
+
+func newMwebDecoder(r io.Reader) *mwebDecoder {
+	return &mwebDecoder{r: r}
+}
+
+func (d *mwebDecoder) Read(b []byte) (int, error) {
+	n, err := d.r.Read(b)
+	if d.rec != nil && n > 0 {
+		d.rec.Write(b[:n])
+	}
+	return n, err
+}
+
+// record starts recording read bytes.
+func (d *mwebDecoder) record() {
+	d.rec = new(bytes.Buffer)
+}
+
+// recorded stops recording and returns the recorded bytes.
+func (d *mwebDecoder) recorded() []byte {
+	b := d.rec.Bytes()
+	d.rec = nil
+	return b
+}
+
+func (d *mwebDecoder) readByte() (byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(d, b[:]); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *mwebDecoder) discard(n int64) error {
+	_, err := io.CopyN(io.Discard, d, n)
+	return err
+}
+
+// discardVector skips a compact-size prefixed byte vector.
+func (d *mwebDecoder) discardVector() error {
+	sz, err := wire.ReadVarInt(d, 0)
+	if err != nil {
+		return err
+	}
+	if sz > wire.MaxMessagePayload {
+		return fmt.Errorf("MWEB vector too large: %d", sz)
+	}
+	return d.discard(int64(sz))
+}
+
+// readCount reads a compact-size item count.
+func (d *mwebDecoder) readCount() (int, error) {
+	n, err := wire.ReadVarInt(d, 0)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxMwebItems {
+		return 0, fmt.Errorf("too many MWEB items: %d", n)
+	}
+	return int(n), nil
+}
+
+// readVarIntMW reads an MSB base-128 integer, which libmw uses for amounts and
+// heights.
+func (d *mwebDecoder) readVarIntMW() (uint64, error) {
+	var n uint64
+	for i := 0; ; i++ {
+		if i > 9 {
+			return 0, fmt.Errorf("MWEB varint too long")
+		}
+		b, err := d.readByte()
+		if err != nil {
+			return 0, err
+		}
+		n = (n << 7) | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+		n++
+	}
+}
+
+// readMwebTx reads a mw::Transaction: kernel offset, stealth offset and the
+// transaction body.
+func (d *mwebDecoder) readMwebTx() error {
+	if err := d.discard(32 + 32); err != nil {
+		return err
+	}
+	return d.readMwebTxBody()
+}
+
+// readMwebBlock reads a mw::Block: the MWEB header followed by the body.
+func (d *mwebDecoder) readMwebBlock() error {
+	// height
+	if _, err := d.readVarIntMW(); err != nil {
+		return err
+	}
+	// output root, kernel root, leafset root, kernel offset, stealth offset
+	if err := d.discard(32 * 5); err != nil {
+		return err
+	}
+	// output MMR size, kernel MMR size
+	for i := 0; i < 2; i++ {
+		if _, err := d.readVarIntMW(); err != nil {
+			return err
+		}
+	}
+	return d.readMwebTxBody()
+}
+
+// readMwebTxBody reads a TxBody, which is a vector each of inputs, outputs and
+// kernels.
+func (d *mwebDecoder) readMwebTxBody() error {
+	numIn, err := d.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < numIn; i++ {
+		feats, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		// output ID, commitment, output public key
+		if err := d.discard(32 + 33 + 33); err != nil {
+			return err
+		}
+		if feats&0x01 != 0 { // input public key
+			if err := d.discard(33); err != nil {
+				return err
+			}
+		}
+		if feats&0x02 != 0 { // extra data
+			if err := d.discardVector(); err != nil {
+				return err
+			}
+		}
+		if err := d.discard(64); err != nil { // signature
+			return err
+		}
+	}
+
+	numOut, err := d.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < numOut; i++ {
+		// commitment, sender public key, receiver public key
+		if err := d.discard(33 + 33 + 33); err != nil {
+			return err
+		}
+		feats, err := d.readByte() // output message features
+		if err != nil {
+			return err
+		}
+		if feats&0x01 != 0 { // key exchange pubkey, view tag, masked value, masked nonce
+			if err := d.discard(33 + 1 + 8 + 16); err != nil {
+				return err
+			}
+		}
+		if feats&0x02 != 0 { // extra data
+			if err := d.discardVector(); err != nil {
+				return err
+			}
+		}
+		if err := d.discard(675 + 64); err != nil { // range proof, signature
+			return err
+		}
+	}
+
+	numKerns, err := d.readCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < numKerns; i++ {
+		feats, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		if feats&0x01 != 0 { // fee
+			if _, err := d.readVarIntMW(); err != nil {
+				return err
+			}
+		}
+		if feats&0x02 != 0 { // peg-in amount
+			if _, err := d.readVarIntMW(); err != nil {
+				return err
+			}
+		}
+		if feats&0x04 != 0 { // peg-outs
+			numPegouts, err := d.readCount()
+			if err != nil {
+				return err
+			}
+			for j := 0; j < numPegouts; j++ {
+				if _, err := d.readVarIntMW(); err != nil {
+					return err
+				}
+				if err := d.discardVector(); err != nil { // pkScript
+					return err
+				}
+			}
+		}
+		if feats&0x08 != 0 { // lock height
+			if _, err := d.readVarIntMW(); err != nil {
+				return err
+			}
+		}
+		if feats&0x10 != 0 { // stealth excess
+			if err := d.discard(33); err != nil {
+				return err
+			}
+		}
+		if feats&0x20 != 0 { // extra data
+			if err := d.discardVector(); err != nil {
+				return err
+			}
+		}
+		if err := d.discard(33 + 64); err != nil { // excess, signature
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package bisonwire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestLTCMWEBRoundTrip(t *testing.T) {
+	coinbase := wire.NewMsgTx(2)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{0x01, 0x02},
+		Witness:          wire.TxWitness{make([]byte, 32)},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(5e8, []byte{0x00, 0x14}))
+
+	hogEx := &LTCMsgTx{MsgTx: *wire.NewMsgTx(2), IsHogEx: true}
+	hogEx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0x01}}, nil, nil))
+	hogEx.AddTxOut(wire.NewTxOut(1e8, []byte{0x51, 0x20}))
+
+	// An MWEB transaction body with no inputs or outputs and a single kernel
+	// with a fee.
+	var mwebTx bytes.Buffer
+	mwebTx.Write(make([]byte, 64))    // kernel and stealth offsets
+	mwebTx.Write([]byte{0x00, 0x00})  // no inputs or outputs
+	mwebTx.Write([]byte{0x01, 0x01})  // one kernel, fee feature
+	mwebTx.Write([]byte{0x80, 0x64})  // fee
+	mwebTx.Write(make([]byte, 33+64)) // excess, signature
+	pegIn := &LTCMsgTx{MsgTx: *wire.NewMsgTx(2), MWEB: mwebTx.Bytes()}
+	pegIn.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x02}},
+		Witness:          wire.TxWitness{{0x03}, {0x04}},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	pegIn.AddTxOut(wire.NewTxOut(2e8, []byte{0x59, 0x20}))
+
+	var txBuf bytes.Buffer
+	if err := pegIn.Serialize(&txBuf); err != nil {
+		t.Fatalf("error serializing MWEB tx: %v", err)
+	}
+	rawTx := txBuf.Bytes()
+	if err := new(wire.MsgTx).Deserialize(bytes.NewReader(rawTx)); err == nil {
+		t.Fatalf("btcd decoded an MWEB transaction")
+	}
+	var ltcTx LTCMsgTx
+	if err := ltcTx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		t.Fatalf("error decoding MWEB tx: %v", err)
+	}
+	if !bytes.Equal(ltcTx.MWEB, mwebTx.Bytes()) {
+		t.Fatalf("wrong MWEB data")
+	}
+	if ltcTx.TxHash() != pegIn.TxHash() {
+		t.Fatalf("wrong txid")
+	}
+
+	// A flag byte of zero is malformed.
+	zeroFlag := append([]byte(nil), rawTx...)
+	zeroFlag[5] = 0x00
+	if err := new(LTCMsgTx).Deserialize(bytes.NewReader(zeroFlag)); err == nil {
+		t.Fatalf("decoded a transaction with a zero flag byte")
+	}
+
+	// An extension block with an empty body. The block's version does not
+	// signal MWEB, since the extension block is only implied by the HogEx.
+	var ext bytes.Buffer
+	ext.WriteByte(0x05)           // height
+	ext.Write(make([]byte, 32*5)) // roots and offsets
+	ext.Write([]byte{0x01, 0x01}) // MMR sizes
+	ext.Write([]byte{0, 0, 0})    // empty body
+
+	block := &LTCMsgBlock{
+		MsgBlock: wire.MsgBlock{
+			Header: wire.BlockHeader{Version: 0x02},
+			Transactions: []*wire.MsgTx{
+				coinbase, &pegIn.MsgTx, &hogEx.MsgTx,
+			},
+		},
+		HogEx:   true,
+		MWEB:    ext.Bytes(),
+		mwebTxs: map[int][]byte{1: pegIn.MWEB},
+	}
+	var blockBuf bytes.Buffer
+	if err := block.Serialize(&blockBuf); err != nil {
+		t.Fatalf("error serializing block: %v", err)
+	}
+	rawBlock := blockBuf.Bytes()
+
+	// Decode through the message reader to make sure the chain is
+	// respected and that the standard type is returned.
+	if err := RegisterChain(ChainLTC, LTCMessage); err != nil {
+		t.Fatalf("error registering chain: %v", err)
+	}
+	var msgBuf bytes.Buffer
+	if _, err := WriteMessageWithEncodingN(&msgBuf, block, wire.ProtocolVersion,
+		wire.MainNet, wire.WitnessEncoding); err != nil {
+		t.Fatalf("error writing block message: %v", err)
+	}
+	_, msg, payload, err := ReadMessageWithEncodingN(&msgBuf, wire.ProtocolVersion,
+		ChainLTC, wire.MainNet, wire.WitnessEncoding)
+	if err != nil {
+		t.Fatalf("error reading block message: %v", err)
+	}
+	msgBlock, ok := msg.(*wire.MsgBlock)
+	if !ok {
+		t.Fatalf("expected a *wire.MsgBlock, got %T", msg)
+	}
+	if !bytes.Equal(payload, rawBlock) {
+		t.Fatalf("wrong payload")
+	}
+	if len(msgBlock.Transactions) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(msgBlock.Transactions))
+	}
+	for i, tx := range msgBlock.Transactions {
+		if tx.TxHash() != block.Transactions[i].TxHash() {
+			t.Fatalf("wrong txid for tx %d", i)
+		}
+	}
+
+	// Decode the payload with extension data and make sure it re-serializes
+	// identically.
+	var ltcBlock LTCMsgBlock
+	if err := ltcBlock.Deserialize(bytes.NewReader(payload)); err != nil {
+		t.Fatalf("error decoding block: %v", err)
+	}
+	if !ltcBlock.HogEx || !bytes.Equal(ltcBlock.MWEB, ext.Bytes()) {
+		t.Fatalf("extension block not decoded")
+	}
+	blockBuf.Reset()
+	if err := ltcBlock.Serialize(&blockBuf); err != nil {
+		t.Fatalf("error re-serializing block: %v", err)
+	}
+	if !bytes.Equal(blockBuf.Bytes(), rawBlock) {
+		t.Fatalf("re-serialized block doesn't match")
+	}
+
+	// A stripped block can be decoded by btcd.
+	ltcBlock.StripMWEB()
+	blockBuf.Reset()
+	if err := ltcBlock.Serialize(&blockBuf); err != nil {
+		t.Fatalf("error serializing stripped block: %v", err)
+	}
+	var btcBlock wire.MsgBlock
+	if err := btcBlock.Deserialize(&blockBuf); err != nil {
+		t.Fatalf("btcd failed to decode stripped block: %v", err)
+	}
+	if btcBlock.BlockHash() != block.BlockHash() {
+		t.Fatalf("wrong block hash for stripped block")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 // BlockFilterer is used to iteratively scan blocks for a set of addresses of
@@ -60,6 +61,9 @@ type BlockFilterer struct {
 	// that contained matches from an address in either ExReverseFilter or
 	// InReverseFilter.
 	RelevantTxns []*wire.MsgTx
+
+	// log is the logger unparsable output scripts are reported to.
+	log btclog.Logger
 }
 
 // NewBlockFilterer constructs the reverse indexes for the current set of
@@ -97,6 +101,7 @@ func NewBlockFilterer(params *chaincfg.Params,
 		FoundExternal:    foundExternal,
 		FoundInternal:    foundInternal,
 		FoundOutPoints:   foundOutPoints,
+		log:              log,
 	}
 }
 
@@ -147,8 +152,8 @@ func (bf *BlockFilterer) FilterTx(tx *wire.MsgTx) bool {
 			out.PkScript, bf.Params,
 		)
 		if err != nil {
-			log.Warnf("Could not parse output script in %s:%d: %v",
-				tx.TxHash(), i, err)
+			bf.log.Warnf("Could not parse output script in %s:%d: "+
+				"%v", tx.TxHash(), i, err)
 			continue
 		}
 
@@ -35,6 +35,7 @@ type NeutrinoChainService interface {
 	ChainParams() chaincfg.Params
 	Stop() error
 	PeerByAddr(string) *spv.ServerPeer
+	EstimateFee(uint32) (btcutil.Amount, error)
 }
 
 var _ NeutrinoChainService = (*spv.ChainService)(nil)
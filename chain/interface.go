@@ -3,6 +3,7 @@ package chain
 import (
 	"time"
 
+	"github.com/bisoncraft/utxowallet/spv"
 	"github.com/bisoncraft/utxowallet/waddrmgr"
 	"github.com/bisoncraft/utxowallet/wtxmgr"
 	"github.com/btcsuite/btcd/btcutil"
@@ -50,6 +51,24 @@ type Interface interface {
 	MapRPCErr(err error) error
 }
 
+// FeeEstimator is implemented by chain backends able to estimate the fee rate
+// required for a transaction to be mined within a number of blocks.
+type FeeEstimator interface {
+	// EstimateFee returns the estimated fee rate, in satoshis per
+	// kilobyte, for a transaction to be mined within confTarget blocks.
+	EstimateFee(confTarget uint32) (btcutil.Amount, error)
+}
+
+// SpendFinder is implemented by chain backends able to find the transaction
+// spending an output by scanning the chain.
+type SpendFinder interface {
+	// FindSpend scans the chain, starting at the height hint, for the
+	// output and the transaction spending it.  The spending transaction
+	// of the report is nil if the output is unspent.
+	FindSpend(op wire.OutPoint, pkScript []byte,
+		heightHint int32) (*spv.SpendReport, error)
+}
+
 // Notification types.  These are defined here and processed from from reading
 // a notificationChan to avoid handling these notifications directly in
 // rpcclient callbacks, which isn't very Go-like and doesn't allow
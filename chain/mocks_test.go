@@ -40,6 +40,7 @@ func newMockNeutrinoClient() *NeutrinoClient {
 
 	return &NeutrinoClient{
 		CS:        &mockChainService{},
+		log:       log,
 		newRescan: newRescanFunc,
 	}
 }
@@ -158,6 +159,10 @@ func (m *mockChainService) PeerByAddr(string) *spv.ServerPeer {
 	panic(errNotImplemented)
 }
 
+func (m *mockChainService) EstimateFee(uint32) (btcutil.Amount, error) {
+	panic(errNotImplemented)
+}
+
 // mockRPCClient mocks the rpcClient interface.
 type mockRPCClient struct {
 	mock.Mock
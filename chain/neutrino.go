@@ -19,6 +19,7 @@ import (
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 // ErrUnimplemented is returned when a certain method is not implemented for a
@@ -31,6 +32,7 @@ type NeutrinoClient struct {
 
 	chainParams *netparams.ChainParams
 	btcParams   *chaincfg.Params
+	log         btclog.Logger
 
 	// We currently support only one rescan/notification goroutine per client.
 	// Therefore there can only be one instance of the rescan object and
@@ -73,9 +75,24 @@ type NeutrinoClient struct {
 // interface.
 var _ Interface = (*NeutrinoClient)(nil)
 
+// A compile-time check to ensure that NeutrinoClient satisfies the
+// chain.FeeEstimator interface.
+var _ FeeEstimator = (*NeutrinoClient)(nil)
+
+// A compile-time check to ensure that NeutrinoClient satisfies the
+// chain.SpendFinder interface.
+var _ SpendFinder = (*NeutrinoClient)(nil)
+
 // NewNeutrinoClient creates a new NeutrinoClient struct with a backing
-// ChainService.
-func NewNeutrinoClient(chainParams *netparams.ChainParams, chainService *spv.ChainService) *NeutrinoClient {
+// ChainService. The client writes to logger, or to the package logger if it is
+// nil.
+func NewNeutrinoClient(chainParams *netparams.ChainParams,
+	chainService *spv.ChainService, logger btclog.Logger) *NeutrinoClient {
+
+	if logger == nil {
+		logger = log
+	}
+
 	chainSource := &spv.RescanChainSource{
 		ChainService: chainService,
 	}
@@ -91,6 +108,7 @@ func NewNeutrinoClient(chainParams *netparams.ChainParams, chainService *spv.Cha
 		CS:          chainService,
 		chainParams: chainParams,
 		btcParams:   chainParams.BTCDParams(),
+		log:         logger,
 		newRescan:   newRescan,
 	}
 }
@@ -166,6 +184,30 @@ func (s *NeutrinoClient) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error)
 	return block.MsgBlock(), nil
 }
 
+// EstimateFee returns the fee rate, in satoshis per kilobyte, a transaction
+// should pay to be mined within confTarget blocks, as estimated by the chain
+// service from recently mined blocks and the fee filters of its peers.
+func (s *NeutrinoClient) EstimateFee(confTarget uint32) (btcutil.Amount,
+	error) {
+
+	return s.CS.EstimateFee(confTarget)
+}
+
+// FindSpend scans the chain, starting at the height hint, for the output and
+// the transaction spending it, matching the output script against the
+// compact filters of blocks.
+func (s *NeutrinoClient) FindSpend(op wire.OutPoint, pkScript []byte,
+	heightHint int32) (*spv.SpendReport, error) {
+
+	return s.CS.GetUtxo(
+		spv.WatchInputs(spv.InputWithScript{
+			OutPoint: op,
+			PkScript: pkScript,
+		}),
+		spv.StartBlock(&headerfs.BlockStamp{Height: heightHint}),
+	)
+}
+
 // GetBlockHeight gets the height of a block by its hash. It serves as a
 // replacement for the use of GetBlockVerboseTxAsync for the wallet package
 // since we can't actually return a FutureGetBlockVerboseResult because the
@@ -237,6 +279,7 @@ func (s *NeutrinoClient) FilterBlocks(
 	req *FilterBlocksRequest) (*FilterBlocksResponse, error) {
 
 	blockFilterer := NewBlockFilterer(s.btcParams, req)
+	blockFilterer.log = s.log
 
 	// Construct the watchlist using the addresses and outpoints contained
 	// in the filter blocks request.
@@ -273,7 +316,7 @@ func (s *NeutrinoClient) FilterBlocks(
 			continue
 		}
 
-		log.Infof("Fetching block height=%d hash=%v",
+		s.log.Infof("Fetching block height=%d hash=%v",
 			blk.Height, blk.Hash)
 
 		// TODO(conner): can optimize bandwidth by only fetching
@@ -570,7 +613,7 @@ func (s *NeutrinoClient) onFilteredBlockConnected(height int32,
 		rec, err := wtxmgr.NewTxRecordFromMsgTx(tx.MsgTx(),
 			header.Timestamp)
 		if err != nil {
-			log.Errorf("Cannot create transaction record for "+
+			s.log.Errorf("Cannot create transaction record for "+
 				"relevant tx: %s", err)
 			// TODO(aakselrod): Return?
 			continue
@@ -679,7 +722,7 @@ func (s *NeutrinoClient) onBlockConnected(hash *chainhash.Hash, height int32,
 func (s *NeutrinoClient) dispatchRescanFinished() {
 	bs, err := s.CS.BestBlock()
 	if err != nil {
-		log.Errorf("Can't get chain service's best block: %s", err)
+		s.log.Errorf("Can't get chain service's best block: %s", err)
 		return
 	}
 
@@ -725,7 +768,7 @@ func (s *NeutrinoClient) dispatchRescanFinished() {
 func (s *NeutrinoClient) notificationHandler() {
 	hash, height, err := s.GetBestBlock()
 	if err != nil {
-		log.Errorf("Failed to get best block from chain service: %s",
+		s.log.Errorf("Failed to get best block from chain service: %s",
 			err)
 		s.Stop()
 		s.wg.Done()
@@ -791,7 +834,8 @@ out:
 
 		case err := <-rescanErr:
 			if err != nil {
-				log.Errorf("Neutrino rescan ended with error: %s", err)
+				s.log.Errorf("Neutrino rescan ended with "+
+					"error: %s", err)
 			}
 
 		case s.currentBlock <- bs:
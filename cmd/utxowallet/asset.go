@@ -0,0 +1,153 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bisoncraft/utxowallet/assets"
+	"github.com/bisoncraft/utxowallet/bisonwire"
+	"github.com/bisoncraft/utxowallet/chain"
+	"github.com/bisoncraft/utxowallet/netparams"
+	"github.com/bisoncraft/utxowallet/rpc/legacyrpc"
+	"github.com/bisoncraft/utxowallet/spv"
+	"github.com/bisoncraft/utxowallet/wallet"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btclog"
+)
+
+// asset is one of the chains hosted by the process.  Every asset has its own
+// data directory, wallet loader, chain service and logging subsystem.
+type asset struct {
+	chain     bisonwire.Chain
+	netDir    string
+	netParams *netparams.ChainParams
+	log       btclog.Logger
+
+	loader    *wallet.Loader
+	rpcServer *legacyrpc.Server
+}
+
+// peers returns the peer addresses that apply to the asset.  Addresses may be
+// prefixed with a registered chain and a colon to restrict them to that chain,
+// and unprefixed addresses apply to all assets.
+func (a *asset) peers(addrs []string) []string {
+	var peers []string
+	for _, addr := range addrs {
+		prefix, rest, found := strings.Cut(addr, ":")
+		if found {
+			if _, ok := assets.Lookup(bisonwire.Chain(prefix)); ok {
+				if bisonwire.Chain(prefix) == a.chain {
+					peers = append(peers, rest)
+				}
+				continue
+			}
+		}
+		peers = append(peers, addr)
+	}
+	return peers
+}
+
+// run creates and starts the asset's chain service and synchronizes the loaded
+// wallet with it, restarting both if the chain client shuts down while the
+// wallet is still running.
+func (a *asset) run() {
+	// The chain service database is opened once for all restarts.  Failing
+	// to open it is not retried, as it fails the same way again, so the
+	// process is shut down instead.
+	spvdbPath := filepath.Join(a.netDir, "spv.db")
+	err := wallet.CheckDBDriver(spvdbPath, cfg.DBDriver)
+	if err != nil {
+		a.log.Errorf("Unable to open Neutrino DB: %v", err)
+		simulateInterrupt()
+		return
+	}
+	spvdb, err := walletdb.Create(
+		cfg.DBDriver, spvdbPath, true, cfg.DBTimeout,
+	)
+	if err != nil {
+		a.log.Errorf("Unable to create Neutrino DB: %v", err)
+		simulateInterrupt()
+		return
+	}
+	defer spvdb.Close()
+
+	for {
+		var (
+			chainClient  chain.Interface
+			chainService *spv.ChainService
+			err          error
+		)
+		chainService, err = spv.NewChainService(
+			spv.Config{
+				Chain:        a.chain,
+				DataDir:      a.netDir,
+				Database:     spvdb,
+				ChainParams:  a.netParams,
+				ConnectPeers: a.peers(cfg.ConnectPeers),
+				AddPeers:     a.peers(cfg.AddPeers),
+				Logger:       a.log,
+			})
+		if err != nil {
+			a.log.Errorf("Couldn't create Neutrino ChainService: %s", err)
+			continue
+		}
+		chainClient = chain.NewNeutrinoClient(
+			a.netParams, chainService, a.log,
+		)
+		err = chainClient.Start()
+		if err != nil {
+			a.log.Errorf("Couldn't start Neutrino client: %s", err)
+		}
+
+		if a.rpcServer != nil {
+			a.rpcServer.SetChainServer(chainClient)
+		}
+
+		// Rather than inlining this logic directly into the loader
+		// callback, a function variable is used to avoid running any of
+		// this after the client disconnects by setting it to nil.  This
+		// prevents the callback from associating a wallet loaded at a
+		// later time with a client that has already disconnected.  A
+		// mutex is used to make this concurrent safe.
+		associateRPCClient := func(w *wallet.Wallet) {
+			w.Synchronize(chainClient)
+		}
+		mu := new(sync.Mutex)
+		a.loader.RunAfterLoad(func(w *wallet.Wallet) {
+			mu.Lock()
+			associate := associateRPCClient
+			mu.Unlock()
+			if associate != nil {
+				associate(w)
+			}
+		})
+
+		chainClient.WaitForShutdown()
+
+		mu.Lock()
+		associateRPCClient = nil
+		mu.Unlock()
+
+		loadedWallet, ok := a.loader.LoadedWallet()
+		if ok {
+			// Do not attempt a reconnect when the wallet was
+			// explicitly stopped.
+			if loadedWallet.ShuttingDown() {
+				return
+			}
+
+			loadedWallet.SetChainSynced(false)
+
+			// TODO: Rework the wallet so changing the RPC client
+			// does not require stopping and restarting everything.
+			loadedWallet.Stop()
+			loadedWallet.WaitForShutdown()
+			loadedWallet.Start()
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/wallet"
+)
+
+// backupDir returns the directory of the automatic backups of the asset's
+// wallet.
+func (a *asset) backupDir() string {
+	if cfg.BackupDir == "" {
+		return filepath.Join(a.netDir, "backups")
+	}
+	return filepath.Join(cfg.BackupDir, string(a.chain), a.netParams.Name)
+}
+
+// runBackups backs up the asset's wallet at the configured interval until quit
+// is closed, keeping only the configured number of backups.  Backups are
+// skipped while the wallet is not loaded.
+func (a *asset) runBackups(passphrase []byte, quit <-chan struct{}) {
+	ticker := time.NewTicker(cfg.BackupInterval)
+	defer ticker.Stop()
+
+	dir := a.backupDir()
+	for {
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+
+		_, err := a.loader.Backup(dir, passphrase)
+		if err == wallet.ErrNotLoaded {
+			continue
+		}
+		if err != nil {
+			a.log.Errorf("Unable to back up wallet: %v", err)
+			continue
+		}
+
+		if cfg.BackupKeep == 0 {
+			continue
+		}
+		if err := pruneBackups(dir, cfg.BackupKeep); err != nil {
+			a.log.Errorf("Unable to remove old backups: %v", err)
+		}
+	}
+}
+
+// pruneBackups removes all but the keep most recent wallet backups in dir.
+func pruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	// Backups are named by their time, so they sort by their age.  Any
+	// backups still being written are ignored.
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, wallet.BackupFilePrefix) ||
+			strings.Contains(name, ".tmp") {
+
+			continue
+		}
+		backups = append(backups, name)
+	}
+	if len(backups) <= keep {
+		return nil
+	}
+	sort.Strings(backups)
+
+	for _, name := range backups[:len(backups)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreBackup replaces the asset's wallet database with the backup file of
+// the --restorebackup option.  The wallet rescans the chain from the block the
+// backup was synced to when it is next opened.
+func restoreBackup(cfg *config, a *asset) error {
+	passphrase, err := readSecret(cfg.BackupPassEnv, -1)
+	if err != nil {
+		return err
+	}
+
+	loader := wallet.NewLoader(
+		a.netParams, a.netDir, true, cfg.DBTimeout, 250,
+		cfg.loaderOptions(a.log)...,
+	)
+	stamp, err := loader.RestoreBackup(
+		cleanAndExpandPath(cfg.RestoreBackup), passphrase,
+		[]byte(cfg.WalletPass),
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("The wallet has been restored at block %v (height %d), "+
+		"and rescans the chain from there when it is started.\n",
+		stamp.Hash, stamp.Height)
+	return nil
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -17,7 +18,6 @@ import (
 	"github.com/bisoncraft/utxowallet/assets"
 	"github.com/bisoncraft/utxowallet/bisonwire"
 	"github.com/bisoncraft/utxowallet/internal/cfgutil"
-	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/bisoncraft/utxowallet/spv"
 	"github.com/bisoncraft/utxowallet/wallet"
 	"github.com/btcsuite/btcd/btcutil"
@@ -25,26 +25,30 @@ import (
 )
 
 const (
-	defaultConfigFilename = "utxowallet.conf"
-	defaultLogLevel       = "info"
-	defaultLogDirname     = "logs"
-	defaultLogFilename    = "utxowallet.log"
+	defaultConfigFilename   = "utxowallet.conf"
+	defaultLogLevel         = "info"
+	defaultLogDirname       = "logs"
+	defaultLogFilename      = "utxowallet.log"
+	defaultRPCMaxClients    = 10
+	defaultRPCMaxWebsockets = 25
 )
 
 var (
-	defaultAppDataDir = btcutil.AppDataDir("utxowallet", false)
-	defaultConfigFile = filepath.Join(defaultAppDataDir, defaultConfigFilename)
+	defaultAppDataDir  = btcutil.AppDataDir("utxowallet", false)
+	defaultConfigFile  = filepath.Join(defaultAppDataDir, defaultConfigFilename)
+	defaultRPCKeyFile  = filepath.Join(defaultAppDataDir, "rpc.key")
+	defaultRPCCertFile = filepath.Join(defaultAppDataDir, "rpc.cert")
 )
 
 //nolint:lll
 type config struct {
 	// General application behavior
-	Chain         string                  `long:"chain" description:"Blockchain (btc, ltc)"`
+	Chain         string                  `long:"chain" description:"Blockchain -- A comma-separated list runs a wallet for each chain in one process"`
 	ConfigFile    *cfgutil.ExplicitString `short:"C" long:"configfile" description:"Path to configuration file"`
 	ShowVersion   bool                    `short:"V" long:"version" description:"Display version information and exit"`
 	Create        bool                    `long:"create" description:"Create the wallet if it does not exist"`
 	AppDataDir    *cfgutil.ExplicitString `short:"A" long:"appdata" description:"Application data directory for wallet config, databases and logs"`
-	Testnet       bool                    `long:"testnet" description:"Use the test Bitcoin network (version 3) (default mainnet)"`
+	Testnet       bool                    `long:"testnet" description:"Use the test network (default mainnet)"`
 	Simnet        bool                    `long:"simnet" description:"Use the simulation test network (default mainnet)"`
 	RegressionNet bool                    `long:"regtest" description:"Use the regression test network (default mainnet)"`
 	NoInitialLoad bool                    `long:"noinitialload" description:"Defer wallet creation/opening on startup and enable loading wallets over RPC"`
@@ -52,17 +56,57 @@ type config struct {
 	LogDir        string                  `long:"logdir" description:"Directory to log output."`
 	Profile       string                  `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
 	DBTimeout     time.Duration           `long:"dbtimeout" description:"The timeout value to use when opening the wallet database."`
+	DBDriver      string                  `long:"dbdriver" description:"Database driver of the wallet and SPV databases {bdb, sqlite} -- Existing databases are not converted"`
 
 	// Wallet options
 	WalletPass string `long:"walletpass" default-mask:"-" description:"The public wallet password -- Only required if the wallet was created with one"`
+	EncryptDB  bool   `long:"encrypteddb" description:"Encrypt the wallet database with the public wallet password, which may not be empty or the default one -- Convert existing wallet databases with walletdbcrypt first"`
+	KDFUpgrade string `long:"kdfupgrade" description:"Re-wrap the wallet's master keys with the default parameters of this key derivation function when the wallet is next unlocked, if they are stronger {scrypt, argon2id}"`
+
+	// External signer options
+	HWI            string `long:"hwi" description:"Command running HWI to sign with the hardware wallet of --hwifingerprint (default hwi)"`
+	HWIFingerprint string `long:"hwifingerprint" description:"Sign transactions with the hardware wallet having the master key of this hex encoded fingerprint, through HWI"`
+
+	// Non-interactive wallet creation options
+	SeedFile        string `long:"seedfile" description:"Create the wallet from the BIP-0039 mnemonic or hex-encoded seed in this file instead of prompting -- Only used with --create"`
+	XPub            string `long:"xpub" description:"Create a watching-only wallet for this account extended public key -- Only used with --create"`
+	XPubAddrType    string `long:"xpubaddrtype" description:"Address type of the --xpub account {np2wkh, p2wkh, p2tr}"`
+	XPubFingerprint string `long:"xpubfingerprint" description:"Hex encoded fingerprint of the master key of the --xpub account, which external signers need to sign for the account"`
+	MnemonicFile    string `long:"mnemonicfile" description:"Write the generated mnemonic to this new file, readable by the user only, instead of printing it -- Required to create a wallet non-interactively without --seedfile or --xpub"`
+	Birthday        string `long:"birthday" description:"Wallet birthday as a unix timestamp or YYYY-MM-DD date (default now, or the genesis block for an existing seed or xpub) -- Only used with --create"`
+	PrivPassEnv     string `long:"privpassenv" description:"Read the new private passphrase from this environment variable -- Only used with --create"`
+	PrivPassFD      int    `long:"privpassfd" default-mask:"-" description:"Read the new private passphrase from this file descriptor -- Only used with --create"`
+	PubPassEnv      string `long:"pubpassenv" description:"Read the new public passphrase from this environment variable (default --walletpass) -- Only used with --create"`
+	PubPassFD       int    `long:"pubpassfd" default-mask:"-" description:"Read the new public passphrase from this file descriptor (default --walletpass) -- Only used with --create"`
+	MnemonicPassEnv string `long:"mnemonicpassenv" description:"Read the BIP-0039 passphrase of the --seedfile mnemonic, or of the generated mnemonic, from this environment variable -- Only used with --create"`
+	MnemonicPassFD  int    `long:"mnemonicpassfd" default-mask:"-" description:"Read the BIP-0039 passphrase of the --seedfile mnemonic, or of the generated mnemonic, from this file descriptor -- Only used with --create"`
+
+	// Backup options
+	BackupInterval time.Duration `long:"backupinterval" description:"Back up the loaded wallets at this interval while they are running -- Valid time units are {s, m, h} (default 0, disabled)"`
+	BackupDir      string        `long:"backupdir" description:"Directory for the automatic wallet backups, with a subdirectory for each chain and network (default backups in the network directory of each chain)"`
+	BackupKeep     int           `long:"backupkeep" description:"Number of automatic backups to keep for each wallet, removing the oldest ones (0 keeps all)"`
+	BackupPassEnv  string        `long:"backuppassenv" description:"Encrypt the automatic backups with the passphrase in this environment variable -- Also decrypts the --restorebackup file"`
+	RestoreBackup  string        `long:"restorebackup" description:"Replace the wallet database with this backup file and exit -- The replaced database is kept next to it"`
+	DryRunUpgrade  bool          `long:"dryrunupgrade" description:"Report the changes the database upgrades would make to the wallet databases when they are next opened, without changing them, and exit"`
 
 	// SPV client options
 	UseSPV       bool          `long:"usespv" description:"Enables the experimental use of SPV rather than RPC for chain synchronization"`
-	AddPeers     []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
-	ConnectPeers []string      `long:"connect" description:"Connect only to the specified peers at startup"`
+	AddPeers     []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup -- Prefix with the chain and a colon (e.g. ltc:host:port) to only use it for one chain"`
+	ConnectPeers []string      `long:"connect" description:"Connect only to the specified peers at startup -- Prefix with the chain and a colon (e.g. ltc:host:port) to only use it for one chain"`
 	MaxPeers     int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
 	BanDuration  time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	BanThreshold uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
+
+	// RPC server options
+	RPCCert                *cfgutil.ExplicitString `long:"rpccert" description:"File containing the certificate file"`
+	RPCKey                 *cfgutil.ExplicitString `long:"rpckey" description:"File containing the certificate key"`
+	OneTimeTLSKey          bool                    `long:"onetimetlskey" description:"Generate a new TLS certpair at startup, but only write the certificate to disk"`
+	DisableServerTLS       bool                    `long:"noservertls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
+	LegacyRPCListeners     []string                `long:"rpclisten" description:"Listen for legacy RPC connections on this interface/port (default port depends on the chain and network, e.g. 8332 for btc mainnet, 9332 for ltc mainnet)"`
+	LegacyRPCMaxClients    int64                   `long:"rpcmaxclients" description:"Max number of legacy RPC clients for standard connections"`
+	LegacyRPCMaxWebsockets int64                   `long:"rpcmaxwebsockets" description:"Max number of legacy RPC websocket connections"`
+	Username               string                  `short:"u" long:"username" description:"Username for legacy RPC authentication"`
+	Password               string                  `short:"P" long:"password" default-mask:"-" description:"Password for legacy RPC authentication"`
 }
 
 // cleanAndExpandPath expands environement variables and leading ~ in the
@@ -198,6 +242,26 @@ func parseAndSetDebugLevels(debugLevel string) error {
 	return nil
 }
 
+// chainList is a comma-separated list of the registered chains.
+func chainList() string {
+	chains := assets.Chains()
+	names := make([]string, 0, len(chains))
+	for _, c := range chains {
+		names = append(names, string(c))
+	}
+	return strings.Join(names, ", ")
+}
+
+// describeChainOption lists the registered chains in the help text for the
+// chain option.
+func describeChainOption(parser *flags.Parser) {
+	if opt := parser.FindOptionByLongName("chain"); opt != nil {
+		opt.Description = fmt.Sprintf("Blockchain (%s) -- A "+
+			"comma-separated list runs a wallet for each chain in "+
+			"one process", chainList())
+	}
+}
+
 // loadConfig initializes and parses the config using a config file and command
 // line options.
 //
@@ -210,37 +274,48 @@ func parseAndSetDebugLevels(debugLevel string) error {
 // The above results in utxowallet functioning properly without any config
 // settings while still allowing the user to override settings with config files
 // and command line options.  Command line options always take precedence.
-func loadConfig() (*config, string, *netparams.ChainParams, error) {
+func loadConfig() (*config, []*asset, error) {
 	// Default config.
 	cfg := config{
-		DebugLevel:   defaultLogLevel,
-		ConfigFile:   cfgutil.NewExplicitString(defaultConfigFile),
-		AppDataDir:   cfgutil.NewExplicitString(defaultAppDataDir),
-		WalletPass:   wallet.InsecurePubPassphrase,
-		UseSPV:       false,
-		AddPeers:     []string{},
-		ConnectPeers: []string{},
-		MaxPeers:     spv.MaxPeers,
-		BanDuration:  spv.BanDuration,
-		BanThreshold: spv.BanThreshold,
-		DBTimeout:    wallet.DefaultDBTimeout,
+		DebugLevel:     defaultLogLevel,
+		ConfigFile:     cfgutil.NewExplicitString(defaultConfigFile),
+		AppDataDir:     cfgutil.NewExplicitString(defaultAppDataDir),
+		WalletPass:     wallet.InsecurePubPassphrase,
+		XPubAddrType:   "p2wkh",
+		PrivPassFD:     -1,
+		PubPassFD:      -1,
+		MnemonicPassFD: -1,
+		UseSPV:         false,
+		AddPeers:       []string{},
+		ConnectPeers:   []string{},
+		MaxPeers:       spv.MaxPeers,
+		BanDuration:    spv.BanDuration,
+		BanThreshold:   spv.BanThreshold,
+		DBTimeout:      wallet.DefaultDBTimeout,
+		DBDriver:       wallet.DefaultDBDriver,
+
+		RPCKey:                 cfgutil.NewExplicitString(defaultRPCKeyFile),
+		RPCCert:                cfgutil.NewExplicitString(defaultRPCCertFile),
+		LegacyRPCMaxClients:    defaultRPCMaxClients,
+		LegacyRPCMaxWebsockets: defaultRPCMaxWebsockets,
 	}
 
 	// Pre-parse the command line options to see if an alternative config
 	// file or the version flag was specified.
 	preCfg := cfg
 	preParser := flags.NewParser(&preCfg, flags.Default)
+	describeChainOption(preParser)
 	_, err := preParser.Parse()
 	if err != nil {
 		if e, ok := err.(*flags.Error); !ok || e.Type != flags.ErrHelp {
 			preParser.WriteHelp(os.Stderr)
 		}
-		return nil, "", nil, err
+		return nil, nil, err
 	}
 
 	// For now, the chain has to be set at the command-line.
 	if preCfg.Chain == "" {
-		return nil, "", nil, fmt.Errorf("no chain defined")
+		return nil, nil, fmt.Errorf("no chain defined")
 	}
 
 	// Show the version and exit if the version flag was specified.
@@ -254,6 +329,7 @@ func loadConfig() (*config, string, *netparams.ChainParams, error) {
 	// Load additional config from file.
 	var configFileError error
 	parser := flags.NewParser(&cfg, flags.Default)
+	describeChainOption(parser)
 	configFilePath := preCfg.ConfigFile.Value
 	if preCfg.ConfigFile.ExplicitlySet() {
 		configFilePath = cleanAndExpandPath(configFilePath)
@@ -265,7 +341,7 @@ func loadConfig() (*config, string, *netparams.ChainParams, error) {
 		if _, ok := err.(*os.PathError); !ok {
 			fmt.Fprintln(os.Stderr, err)
 			parser.WriteHelp(os.Stderr)
-			return nil, "", nil, err
+			return nil, nil, err
 		}
 		configFileError = err
 	}
@@ -276,25 +352,51 @@ func loadConfig() (*config, string, *netparams.ChainParams, error) {
 		if e, ok := err.(*flags.Error); !ok || e.Type != flags.ErrHelp {
 			parser.WriteHelp(os.Stderr)
 		}
-		return nil, "", nil, err
+		return nil, nil, err
+	}
+
+	// If an alternate data directory was specified, and paths with defaults
+	// relative to the data dir are unchanged, modify each path to be
+	// relative to the new data dir.
+	if cfg.AppDataDir.ExplicitlySet() {
+		cfg.AppDataDir.Value = cleanAndExpandPath(cfg.AppDataDir.Value)
+		if !cfg.RPCKey.ExplicitlySet() {
+			cfg.RPCKey.Value = filepath.Join(cfg.AppDataDir.Value, "rpc.key")
+		}
+		if !cfg.RPCCert.ExplicitlySet() {
+			cfg.RPCCert.Value = filepath.Join(cfg.AppDataDir.Value, "rpc.cert")
+		}
 	}
 
-	// They must choose a blockchain.
-	if _, err := bisonwire.ChainFromString(cfg.Chain); err != nil {
+	// They must choose registered blockchains.  Each one is hosted with its
+	// own wallet and chain service.
+	chains, err := parseChains(cfg.Chain)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(0)
 	}
 
-	net := "mainnet"
+	network := "mainnet"
 	switch {
 	case cfg.Simnet:
-		net = "simnet"
+		network = "simnet"
 	case cfg.Testnet:
-		net = "testnet"
+		network = "testnet"
+	case cfg.RegressionNet:
+		network = "regtest"
 	}
-	netParams, err := assets.NetParams(cfg.Chain, net)
-	if err != nil {
-		return nil, "", nil, err
+	hosted := make([]*asset, 0, len(chains))
+	for _, chain := range chains {
+		netParams, err := assets.NetParams(string(chain), network)
+		if err != nil {
+			return nil, nil, err
+		}
+		hosted = append(hosted, &asset{
+			chain:     chain,
+			netDir:    filepath.Join(cfg.AppDataDir.Value, string(chain), netParams.Name),
+			netParams: netParams,
+			log:       assetLogger(chain),
+		})
 	}
 
 	// Special show command to list supported subsystems and exit.
@@ -308,55 +410,270 @@ func loadConfig() (*config, string, *netparams.ChainParams, error) {
 		err := fmt.Errorf("%s: %w", "loadConfig", err)
 		fmt.Fprintln(os.Stderr, err)
 		parser.WriteHelp(os.Stderr)
-		return nil, "", nil, err
+		return nil, nil, err
 	}
 
-	// Ensure the wallet exists or create it when the create flag is set.
-	netDir := filepath.Join(cfg.AppDataDir.Value, cfg.Chain, netParams.Name)
-	dbPath := filepath.Join(netDir, wallet.WalletDBName)
-	cfg.LogDir = filepath.Join(netDir, "logs")
+	// A single chain keeps its logs with its data, while the logs of a
+	// multi-asset daemon are shared by all assets.
+	cfg.LogDir = filepath.Join(cfg.AppDataDir.Value, "logs")
+	if len(hosted) == 1 {
+		cfg.LogDir = filepath.Join(hosted[0].netDir, "logs")
+	}
 
 	// Initialize log rotation.  After log rotation has been initialized, the
 	// logger variables may be used.
 	initLogRotator(filepath.Join(cfg.LogDir, defaultLogFilename))
 
-	dbFileExists, err := cfgutil.FileExists(dbPath)
-	if err != nil {
+	// The non-interactive creation options only make sense when creating a
+	// new wallet.
+	if !cfg.Create && (cfg.nonInteractiveCreate() || cfg.Birthday != "") {
+		err := fmt.Errorf("the wallet creation options require the " +
+			"--create option")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.SeedFile != "" && cfg.MnemonicFile != "" {
+		err := fmt.Errorf("the --seedfile and --mnemonicfile options " +
+			"may not be used together")
 		fmt.Fprintln(os.Stderr, err)
-		return nil, "", nil, err
+		return nil, nil, err
+	}
+	if cfg.XPub == "" && cfg.XPubFingerprint != "" {
+		err := fmt.Errorf("the --xpubfingerprint option requires the " +
+			"--xpub option")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.XPub != "" && (cfg.MnemonicPassEnv != "" ||
+		cfg.MnemonicPassFD >= 0 || cfg.MnemonicFile != "") {
+
+		err := fmt.Errorf("a mnemonic passphrase may not be provided " +
+			"for a watching-only wallet")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.XPub != "" && cfg.SeedFile != "" {
+		err := fmt.Errorf("the --seedfile and --xpub options may not " +
+			"be used together")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.XPub != "" && (cfg.PrivPassEnv != "" || cfg.PrivPassFD >= 0) {
+		err := fmt.Errorf("a private passphrase may not be provided " +
+			"for a watching-only wallet")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	switch cfg.DBDriver {
+	case "bdb", "sqlite":
+	default:
+		err := fmt.Errorf("unknown database driver %q", cfg.DBDriver)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// Wallets are created with the public passphrase of the creation
+	// options, which is checked once it is known.
+	if !cfg.Create {
+		err := cfg.checkEncryptedDBPass([]byte(cfg.WalletPass))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	switch cfg.KDFUpgrade {
+	case "", "scrypt", "argon2id":
+	default:
+		err := fmt.Errorf("unknown key derivation function %q",
+			cfg.KDFUpgrade)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.HWI != "" && cfg.HWIFingerprint == "" {
+		err := fmt.Errorf("the --hwi option requires the " +
+			"--hwifingerprint option")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.HWIFingerprint != "" {
+		if _, err := parseFingerprint(cfg.HWIFingerprint); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if cfg.BackupInterval < 0 || cfg.BackupKeep < 0 {
+		err := fmt.Errorf("the --backupinterval and --backupkeep " +
+			"options may not be negative")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.BackupDir != "" {
+		cfg.BackupDir = cleanAndExpandPath(cfg.BackupDir)
+	}
+	if cfg.Create && cfg.RestoreBackup != "" {
+		err := fmt.Errorf("the --create and --restorebackup options " +
+			"may not be used together")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.DryRunUpgrade && (cfg.Create || cfg.RestoreBackup != "") {
+		err := fmt.Errorf("the --dryrunupgrade option may not be used " +
+			"with the --create or --restorebackup options")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.RestoreBackup != "" {
+		// Backups are restored one chain at a time.
+		if len(hosted) != 1 {
+			err := fmt.Errorf("the --restorebackup option requires " +
+				"a single chain")
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+
+		err := restoreBackup(&cfg, hosted[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to restore backup:", err)
+			return nil, nil, err
+		}
+
+		// Restored successfully, so exit now with success.
+		os.Exit(0)
 	}
 
 	if cfg.Create {
+		// Wallets are created one chain at a time.
+		if len(hosted) != 1 {
+			err := fmt.Errorf("the --create option requires a " +
+				"single chain")
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		a := hosted[0]
+
 		// Error if the create flag is set and the wallet already
 		// exists.
+		dbPath := filepath.Join(a.netDir, wallet.WalletDBName)
+		dbFileExists, err := cfgutil.FileExists(dbPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
 		if dbFileExists {
 			err := fmt.Errorf("the wallet database file `%v` "+
 				"already exists", dbPath)
 			fmt.Fprintln(os.Stderr, err)
-			return nil, "", nil, err
+			return nil, nil, err
 		}
 
 		// Ensure the data directory for the network exists.
-		if err := checkCreateDir(netDir); err != nil {
+		if err := checkCreateDir(a.netDir); err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			return nil, "", nil, err
+			return nil, nil, err
 		}
 
 		// Perform the initial wallet creation wizard.
-		if err := createWallet(&cfg, netDir, netParams); err != nil {
+		err = createWallet(&cfg, a.netDir, a.netParams, a.log)
+		if err != nil {
 			fmt.Fprintln(os.Stderr, "Unable to create wallet:", err)
-			return nil, "", nil, err
+			return nil, nil, err
 		}
 
 		// Created successfully, so exit now with success.
 		os.Exit(0)
-	} else if !dbFileExists && !cfg.NoInitialLoad {
-		return nil, "", nil, fmt.Errorf("the wallet does not exist, run with the --create option to initialize and create it")
+	}
+
+	// Every hosted wallet must exist unless they are loaded over RPC.
+	for _, a := range hosted {
+		dbPath := filepath.Join(a.netDir, wallet.WalletDBName)
+		dbFileExists, err := cfgutil.FileExists(dbPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+		if !dbFileExists && !cfg.NoInitialLoad {
+			return nil, nil, fmt.Errorf("the %s wallet does not "+
+				"exist, run with the --create option to initialize "+
+				"and create it", a.chain)
+		}
+	}
+
+	if cfg.DryRunUpgrade {
+		for _, a := range hosted {
+			if err := dryRunUpgrade(&cfg, a); err != nil {
+				fmt.Fprintln(os.Stderr, "Unable to check the "+
+					"database upgrades:", err)
+				return nil, nil, err
+			}
+		}
+
+		// Reported successfully, so exit now with success.
+		os.Exit(0)
 	}
 
 	spv.MaxPeers = cfg.MaxPeers
 	spv.BanDuration = cfg.BanDuration
 	spv.BanThreshold = cfg.BanThreshold
+
+	// Set the default RPC listeners when none are specified.
+	if len(cfg.LegacyRPCListeners) == 0 {
+		addrs, err := net.LookupHost("localhost")
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.LegacyRPCListeners = make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			addr = net.JoinHostPort(addr, hosted[0].netParams.RPCServerPort)
+			cfg.LegacyRPCListeners = append(cfg.LegacyRPCListeners, addr)
+		}
+	}
+
+	// Add default port to all rpc listener addresses if needed and remove
+	// duplicate addresses.
+	cfg.LegacyRPCListeners, err = cfgutil.NormalizeAddresses(
+		cfg.LegacyRPCListeners, hosted[0].netParams.RPCServerPort)
+	if err != nil {
+		fmt.Fprintf(os.Stderr,
+			"Invalid network address in legacy RPC listeners: %v\n", err)
+		return nil, nil, err
+	}
+
+	// Only allow server TLS to be disabled if the RPC server is bound to
+	// localhost addresses.
+	if cfg.DisableServerTLS {
+		localhostListeners := map[string]struct{}{
+			"localhost": {},
+			"127.0.0.1": {},
+			"::1":       {},
+		}
+		for _, addr := range cfg.LegacyRPCListeners {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				err := fmt.Errorf("loadConfig: RPC listen interface '%s' "+
+					"is invalid: %w", addr, err)
+				fmt.Fprintln(os.Stderr, err)
+				return nil, nil, err
+			}
+			if _, ok := localhostListeners[host]; !ok {
+				err := fmt.Errorf("loadConfig: the --noservertls option "+
+					"may not be used when binding RPC to non "+
+					"localhost addresses: %s", addr)
+				fmt.Fprintln(os.Stderr, err)
+				return nil, nil, err
+			}
+		}
+	}
+
+	// Expand environment variable and leading ~ for filepaths.
+	cfg.RPCCert.Value = cleanAndExpandPath(cfg.RPCCert.Value)
+	cfg.RPCKey.Value = cleanAndExpandPath(cfg.RPCKey.Value)
+
 	// Warn about missing config file after the final command line parse
 	// succeeds.  This prevents the warning on help messages and invalid
 	// options.
@@ -364,5 +681,25 @@ func loadConfig() (*config, string, *netparams.ChainParams, error) {
 		log.Warnf("%v", configFileError)
 	}
 
-	return &cfg, netDir, netParams, nil
+	return &cfg, hosted, nil
+}
+
+// parseChains parses the comma-separated list of chains to host, which must all
+// be registered.  Duplicates are removed.
+func parseChains(list string) ([]bisonwire.Chain, error) {
+	var chains []bisonwire.Chain
+	seen := make(map[bisonwire.Chain]bool)
+	for _, name := range strings.Split(list, ",") {
+		chain := bisonwire.Chain(strings.TrimSpace(name))
+		if _, found := assets.Lookup(chain); !found {
+			return nil, fmt.Errorf("unknown chain %q. known "+
+				"chains: %s", chain, chainList())
+		}
+		if seen[chain] {
+			continue
+		}
+		seen[chain] = true
+		chains = append(chains, chain)
+	}
+	return chains, nil
 }
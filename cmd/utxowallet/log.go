@@ -9,8 +9,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/bisoncraft/utxowallet/bisonwire"
 	"github.com/bisoncraft/utxowallet/chain"
+	"github.com/bisoncraft/utxowallet/rpc/legacyrpc"
 	"github.com/bisoncraft/utxowallet/spv"
 	"github.com/bisoncraft/utxowallet/wallet"
 	"github.com/bisoncraft/utxowallet/wtxmgr"
@@ -51,12 +54,13 @@ var (
 	// is written to by the Write method of the logWriter type.
 	logRotatorPipe *io.PipeWriter
 
-	log       = backendLog.Logger("UTXO")
-	walletLog = backendLog.Logger("WLLT")
-	txmgrLog  = backendLog.Logger("TMGR")
-	chainLog  = backendLog.Logger("CHNS")
-	grpcLog   = backendLog.Logger("GRPC")
-	spvLog    = backendLog.Logger("SPV")
+	log          = backendLog.Logger("UTXO")
+	walletLog    = backendLog.Logger("WLLT")
+	txmgrLog     = backendLog.Logger("TMGR")
+	chainLog     = backendLog.Logger("CHNS")
+	grpcLog      = backendLog.Logger("GRPC")
+	legacyRPCLog = backendLog.Logger("RPCS")
+	spvLog       = backendLog.Logger("SPV")
 )
 
 // Initialize package-global logger variables.
@@ -65,6 +69,7 @@ func init() {
 	wtxmgr.UseLogger(txmgrLog)
 	chain.UseLogger(chainLog)
 	rpcclient.UseLogger(chainLog)
+	legacyrpc.UseLogger(legacyRPCLog)
 	spv.UseLogger(spvLog)
 }
 
@@ -75,9 +80,23 @@ var subsystemLoggers = map[string]btclog.Logger{
 	"TMGR": txmgrLog,
 	"CHNS": chainLog,
 	"GRPC": grpcLog,
+	"RPCS": legacyRPCLog,
 	"SPV":  spvLog,
 }
 
+// assetLogger returns the logger for the subsystem of a hosted asset, which is
+// named after its chain (e.g. BTC), creating and registering it with the
+// subsystem loggers if needed.
+func assetLogger(chain bisonwire.Chain) btclog.Logger {
+	subsystemID := strings.ToUpper(string(chain))
+	if logger, ok := subsystemLoggers[subsystemID]; ok {
+		return logger
+	}
+	logger := backendLog.Logger(subsystemID)
+	subsystemLoggers[subsystemID] = logger
+	return logger
+}
+
 // initLogRotator initializes the logging rotater to write logs to logFile and
 // create roll files in the same directory.  It must be called before the
 // package-global log rotater variables are used.
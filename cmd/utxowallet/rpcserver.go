@@ -0,0 +1,294 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/rpc/legacyrpc"
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// openRPCKeyPair creates or loads the RPC TLS keypair specified by the
+// application config.  This function respects the cfg.OneTimeTLSKey setting.
+func openRPCKeyPair() (tls.Certificate, error) {
+	// Check for existence of the TLS key file.  If one time TLS keys are
+	// enabled but a key already exists, this function should error since
+	// it's possible that a persistent certificate was copied to a remote
+	// machine.  Otherwise, generate a new keypair when the key is missing.
+	// When generating new persistent keys, overwriting an existing cert is
+	// acceptable if the previous execution used a one time TLS key.
+	// Otherwise, both the cert and key should be read from disk.  If the
+	// cert is missing, the read error will occur in LoadX509KeyPair.
+	_, e := os.Stat(cfg.RPCKey.Value)
+	keyExists := !os.IsNotExist(e)
+	switch {
+	case cfg.OneTimeTLSKey && keyExists:
+		err := fmt.Errorf("one time TLS keys are enabled, but TLS key "+
+			"`%s` already exists", cfg.RPCKey.Value)
+		return tls.Certificate{}, err
+	case cfg.OneTimeTLSKey:
+		return generateRPCKeyPair(false)
+	case !keyExists:
+		return generateRPCKeyPair(true)
+	default:
+		return tls.LoadX509KeyPair(cfg.RPCCert.Value, cfg.RPCKey.Value)
+	}
+}
+
+// generateRPCKeyPair generates a new RPC TLS keypair and writes the cert and
+// possibly also the key in PEM format to the paths specified by the config.  If
+// successful, the new keypair is returned.
+func generateRPCKeyPair(writeKey bool) (tls.Certificate, error) {
+	log.Infof("Generating TLS certificates...")
+
+	// Create directories for cert and key files if they do not yet exist.
+	certDir, _ := filepath.Split(cfg.RPCCert.Value)
+	keyDir, _ := filepath.Split(cfg.RPCKey.Value)
+	err := os.MkdirAll(certDir, 0700)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	err = os.MkdirAll(keyDir, 0700)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Generate cert pair.
+	org := "utxowallet autogenerated cert"
+	validUntil := time.Now().Add(time.Hour * 24 * 365 * 10)
+	cert, key, err := btcutil.NewTLSCertPair(org, validUntil, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Write cert and (potentially) the key files.
+	err = os.WriteFile(cfg.RPCCert.Value, cert, 0600)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if writeKey {
+		err = os.WriteFile(cfg.RPCKey.Value, key, 0600)
+		if err != nil {
+			rmErr := os.Remove(cfg.RPCCert.Value)
+			if rmErr != nil {
+				log.Warnf("Cannot remove written certificates: %v",
+					rmErr)
+			}
+			return tls.Certificate{}, err
+		}
+	}
+
+	log.Info("Done generating TLS certificates")
+	return keyPair, nil
+}
+
+// rpcServer serves the legacy RPC servers of the hosted assets on the
+// configured listeners.
+type rpcServer struct {
+	httpServer http.Server
+	listeners  []net.Listener
+	wg         sync.WaitGroup
+}
+
+// startRPCServer creates a legacy RPC server for each hosted asset and starts
+// serving them.  A single asset is served at the root of the listeners, while
+// the RPC endpoints of a multi-asset daemon are namespaced by chain, e.g. /btc
+// and /btc/ws for bitcoin.  A nil server is returned when the RPC server is
+// disabled.
+func startRPCServer(hosted []*asset) (*rpcServer, error) {
+	var (
+		legacyListen = net.Listen
+		keyPair      tls.Certificate
+		err          error
+	)
+	if cfg.DisableServerTLS {
+		log.Info("Server TLS is disabled")
+	} else {
+		keyPair, err = openRPCKeyPair()
+		if err != nil {
+			return nil, err
+		}
+
+		// Change the standard net.Listen function to the tls one.
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{keyPair},
+			MinVersion:   tls.VersionTLS12,
+		}
+		legacyListen = func(net string, laddr string) (net.Listener, error) {
+			return tls.Listen(net, laddr, tlsConfig)
+		}
+	}
+
+	if cfg.Username == "" || cfg.Password == "" {
+		log.Info("Legacy RPC server disabled (requires username and password)")
+		return nil, nil
+	}
+	if len(cfg.LegacyRPCListeners) == 0 {
+		return nil, nil
+	}
+
+	listeners := makeListeners(cfg.LegacyRPCListeners, legacyListen)
+	if len(listeners) == 0 {
+		return nil, errors.New("failed to create listeners for legacy RPC server")
+	}
+	opts := legacyrpc.Options{
+		Username:            cfg.Username,
+		Password:            cfg.Password,
+		WalletPass:          cfg.WalletPass,
+		MaxPOSTClients:      cfg.LegacyRPCMaxClients,
+		MaxWebsocketClients: cfg.LegacyRPCMaxWebsockets,
+	}
+	for _, a := range hosted {
+		a.rpcServer = legacyrpc.NewServer(&opts, a.loader, nil)
+	}
+
+	var handler http.Handler
+	if len(hosted) == 1 {
+		handler = hosted[0].rpcServer.Handler()
+	} else {
+		handler = assetRPCHandler(hosted)
+	}
+
+	const rpcAuthTimeoutSeconds = 10
+	s := &rpcServer{
+		httpServer: http.Server{
+			Handler: handler,
+
+			// Timeout connections which don't complete the initial
+			// handshake within the allowed timeframe.
+			ReadTimeout: time.Second * rpcAuthTimeoutSeconds,
+		},
+		listeners: listeners,
+	}
+	for _, lis := range listeners {
+		lis := lis
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			log.Infof("RPC server listening on %s", lis.Addr())
+			err := s.httpServer.Serve(lis)
+			log.Tracef("Finished serving RPC: %v", err)
+		}()
+	}
+	return s, nil
+}
+
+// stop closes the listeners of the RPC server and waits for them to stop
+// serving.  The legacy RPC servers of the assets are stopped separately.
+func (s *rpcServer) stop() {
+	for _, lis := range s.listeners {
+		if err := lis.Close(); err != nil {
+			log.Errorf("Cannot close listener `%s`: %v",
+				lis.Addr(), err)
+		}
+	}
+	s.wg.Wait()
+}
+
+// assetRPCHandler routes requests to the legacy RPC server of the asset named
+// by the first element of the request path, with that element removed.
+func assetRPCHandler(hosted []*asset) http.Handler {
+	handlers := make(map[string]http.Handler, len(hosted))
+	for _, a := range hosted {
+		handlers[string(a.chain)] = a.rpcServer.Handler()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		h, ok := handlers[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + rest
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
+
+type listenFunc func(net string, laddr string) (net.Listener, error)
+
+// makeListeners splits the normalized listen addresses into IPv4 and IPv6
+// addresses and creates new net.Listeners for each with the passed listen func.
+// Invalid addresses are logged and skipped.
+func makeListeners(normalizedListenAddrs []string, listen listenFunc) []net.Listener {
+	ipv4Addrs := make([]string, 0, len(normalizedListenAddrs)*2)
+	ipv6Addrs := make([]string, 0, len(normalizedListenAddrs)*2)
+	for _, addr := range normalizedListenAddrs {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			// Shouldn't happen due to already being normalized.
+			log.Errorf("`%s` is not a normalized "+
+				"listener address", addr)
+			continue
+		}
+
+		// Empty host or host of * on plan9 is both IPv4 and IPv6.
+		if host == "" || (host == "*" && runtime.GOOS == "plan9") {
+			ipv4Addrs = append(ipv4Addrs, addr)
+			ipv6Addrs = append(ipv6Addrs, addr)
+			continue
+		}
+
+		// Remove the IPv6 zone from the host, if present.  The zone
+		// prevents ParseIP from correctly parsing the IP address.
+		// ResolveIPAddr is intentionally not used here due to the
+		// possibility of leaking a DNS query over Tor if the host is a
+		// hostname and not an IP address.
+		zoneIndex := strings.Index(host, "%")
+		if zoneIndex != -1 {
+			host = host[:zoneIndex]
+		}
+
+		ip := net.ParseIP(host)
+		switch {
+		case ip == nil:
+			log.Warnf("`%s` is not a valid IP address", host)
+		case ip.To4() == nil:
+			ipv6Addrs = append(ipv6Addrs, addr)
+		default:
+			ipv4Addrs = append(ipv4Addrs, addr)
+		}
+	}
+	listeners := make([]net.Listener, 0, len(ipv6Addrs)+len(ipv4Addrs))
+	for _, addr := range ipv4Addrs {
+		listener, err := listen("tcp4", addr)
+		if err != nil {
+			log.Warnf("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	for _, addr := range ipv6Addrs {
+		listener, err := listen("tcp6", addr)
+		if err != nil {
+			log.Warnf("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
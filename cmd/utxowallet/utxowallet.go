@@ -10,16 +10,9 @@ import (
 	"net/http"
 	_ "net/http/pprof" // nolint:gosec
 	"os"
-	"path/filepath"
 	"runtime"
-	"sync"
 
-	"github.com/bisoncraft/utxowallet/bisonwire"
-	"github.com/bisoncraft/utxowallet/chain"
-	"github.com/bisoncraft/utxowallet/netparams"
-	"github.com/bisoncraft/utxowallet/spv"
 	"github.com/bisoncraft/utxowallet/wallet"
-	"github.com/bisoncraft/utxowallet/walletdb"
 )
 
 var (
@@ -45,7 +38,7 @@ func main() {
 func walletMain() error {
 	// Load configuration and parse command line.  This function also
 	// initializes logging and configures it accordingly.
-	tcfg, netDir, netParams, err := loadConfig()
+	tcfg, hosted, err := loadConfig()
 	if err != nil {
 		return err
 	}
@@ -70,121 +63,108 @@ func walletMain() error {
 		}()
 	}
 
-	loader := wallet.NewLoader(
-		netParams.BTCDParams(), netDir, true, cfg.DBTimeout, 250,
-	)
-
-	// Create and start chain RPC client so it's ready to connect to
-	// the wallet when loaded later.
-	if !cfg.NoInitialLoad {
-		go run(loader, netDir, netParams)
-	}
+	for _, a := range hosted {
+		a.loader = wallet.NewLoader(
+			a.netParams, a.netDir, true, cfg.DBTimeout, 250,
+			cfg.loaderOptions(a.log)...,
+		)
 
-	if !cfg.NoInitialLoad {
-		// Load the wallet database.  It must have been created already
-		// or this will return an appropriate error.
-		_, err = loader.OpenExistingWallet([]byte(cfg.WalletPass), true)
+		// Wallets are signed for by the hardware wallet, if any, as
+		// soon as they are loaded.
+		signer, err := cfg.externalSigner(a)
 		if err != nil {
-			log.Error(err)
+			log.Errorf("Unable to create external signer: %v", err)
 			return err
 		}
-	}
-
-	// Add interrupt handlers to shutdown the various process components
-	// before exiting.  Interrupt handlers run in LIFO order, so the wallet
-	// (which should be closed last) is added first.
-	addInterruptHandler(func() {
-		err := loader.UnloadWallet()
-		if err != nil && err != wallet.ErrNotLoaded {
-			log.Errorf("Failed to close wallet: %v", err)
+		if signer != nil {
+			a.loader.RunAfterLoad(func(w *wallet.Wallet) {
+				w.SetExternalSigner(signer)
+			})
 		}
-	})
-
-	<-interruptHandlersDone
-	log.Info("Shutdown complete")
-	return nil
-}
-
-func run(loader *wallet.Loader, netDir string, netParams *netparams.ChainParams) {
+	}
 
-	for {
-		var (
-			chainClient chain.Interface
-			err         error
-		)
+	// Create and start HTTP server to serve wallet client connections.
+	// The wallets are registered with the server once they are loaded.
+	rpcServer, err := startRPCServer(hosted)
+	if err != nil {
+		log.Errorf("Unable to create RPC server: %v", err)
+		return err
+	}
 
-		var (
-			chainService *spv.ChainService
-			spvdb        walletdb.DB
-		)
-		spvdb, err = walletdb.Create(
-			"bdb", filepath.Join(netDir, "spv.db"),
-			true, cfg.DBTimeout,
-		)
+	// The automatic backups of all wallets are encrypted with the same
+	// passphrase.
+	var backupPass []byte
+	if cfg.BackupInterval > 0 {
+		backupPass, err = readSecret(cfg.BackupPassEnv, -1)
 		if err != nil {
-			log.Errorf("Unable to create Neutrino DB: %s", err)
-			continue
+			log.Errorf("Unable to read backup passphrase: %v", err)
+			return err
 		}
-		defer spvdb.Close()
-		chainService, err = spv.NewChainService(
-			spv.Config{
-				Chain:        bisonwire.Chain(cfg.Chain),
-				DataDir:      netDir,
-				Database:     spvdb,
-				ChainParams:  netParams,
-				ConnectPeers: cfg.ConnectPeers,
-				AddPeers:     cfg.AddPeers,
+	}
+
+	for _, a := range hosted {
+		a := a
+
+		// Create and start the chain service so it's ready to connect
+		// to the wallet when loaded. When the initial load is
+		// deferred, the chain service is started once the wallet is
+		// loaded over RPC.
+		if !cfg.NoInitialLoad {
+			go a.run()
+		} else {
+			a.loader.RunAfterLoad(func(*wallet.Wallet) {
+				go a.run()
 			})
-		if err != nil {
-			log.Errorf("Couldn't create Neutrino ChainService: %s", err)
-			continue
-		}
-		chainClient = chain.NewNeutrinoClient(netParams, chainService)
-		err = chainClient.Start()
-		if err != nil {
-			log.Errorf("Couldn't start Neutrino client: %s", err)
 		}
 
-		// Rather than inlining this logic directly into the loader
-		// callback, a function variable is used to avoid running any of
-		// this after the client disconnects by setting it to nil.  This
-		// prevents the callback from associating a wallet loaded at a
-		// later time with a client that has already disconnected.  A
-		// mutex is used to make this concurrent safe.
-		associateRPCClient := func(w *wallet.Wallet) {
-			w.Synchronize(chainClient)
+		if a.rpcServer != nil {
+			a.loader.RunAfterLoad(a.rpcServer.RegisterWallet)
 		}
-		mu := new(sync.Mutex)
-		loader.RunAfterLoad(func(w *wallet.Wallet) {
-			mu.Lock()
-			associate := associateRPCClient
-			mu.Unlock()
-			if associate != nil {
-				associate(w)
-			}
-		})
-
-		chainClient.WaitForShutdown()
 
-		mu.Lock()
-		associateRPCClient = nil
-		mu.Unlock()
-
-		loadedWallet, ok := loader.LoadedWallet()
-		if ok {
-			// Do not attempt a reconnect when the wallet was
-			// explicitly stopped.
-			if loadedWallet.ShuttingDown() {
-				return
+		if !cfg.NoInitialLoad {
+			// Load the wallet database.  It must have been created
+			// already or this will return an appropriate error.
+			_, err = a.loader.OpenExistingWallet(
+				[]byte(cfg.WalletPass), true,
+			)
+			if err != nil {
+				a.log.Error(err)
+				return err
 			}
+		}
 
-			loadedWallet.SetChainSynced(false)
-
-			// TODO: Rework the wallet so changing the RPC client
-			// does not require stopping and restarting everything.
-			loadedWallet.Stop()
-			loadedWallet.WaitForShutdown()
-			loadedWallet.Start()
+		// Add interrupt handlers to shutdown the various process
+		// components before exiting.  Interrupt handlers run in LIFO
+		// order, so the wallet (which should be closed last) is added
+		// first.
+		addInterruptHandler(func() {
+			err := a.loader.UnloadWallet()
+			if err != nil && err != wallet.ErrNotLoaded {
+				a.log.Errorf("Failed to close wallet: %v", err)
+			}
+		})
+		if cfg.BackupInterval > 0 {
+			quit := make(chan struct{})
+			go a.runBackups(backupPass, quit)
+			addInterruptHandler(func() { close(quit) })
 		}
+		if a.rpcServer != nil {
+			addInterruptHandler(a.rpcServer.Stop)
+			go func() {
+				<-a.rpcServer.RequestProcessShutdown()
+				simulateInterrupt()
+			}()
+		}
+	}
+	if rpcServer != nil {
+		addInterruptHandler(func() {
+			log.Warn("Stopping legacy RPC server...")
+			rpcServer.stop()
+			log.Info("Legacy RPC server shutdown")
+		})
 	}
+
+	<-interruptHandlersDone
+	log.Info("Shutdown complete")
+	return nil
 }
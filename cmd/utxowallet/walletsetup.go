@@ -6,24 +6,134 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bisoncraft/utxowallet/internal/prompt"
 	"github.com/bisoncraft/utxowallet/netparams"
+	"github.com/bisoncraft/utxowallet/waddrmgr"
 	"github.com/bisoncraft/utxowallet/wallet"
+	"github.com/bisoncraft/utxowallet/wallet/bip39"
+	"github.com/bisoncraft/utxowallet/wallet/hwi"
 	_ "github.com/bisoncraft/utxowallet/walletdb/bdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/sqlite"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btclog"
 )
 
+// nonInteractiveCreate returns whether any of the options for creating a wallet
+// without a terminal were set.
+func (cfg *config) nonInteractiveCreate() bool {
+	return cfg.SeedFile != "" || cfg.MnemonicFile != "" || cfg.XPub != "" ||
+		cfg.PrivPassEnv != "" || cfg.PrivPassFD >= 0 ||
+		cfg.PubPassEnv != "" || cfg.PubPassFD >= 0 ||
+		cfg.MnemonicPassEnv != "" || cfg.MnemonicPassFD >= 0
+}
+
+// loaderOptions returns the options of the wallet loaders of an asset, which
+// write to the asset's logger.
+func (cfg *config) loaderOptions(logger btclog.Logger) []wallet.LoaderOption {
+	opts := []wallet.LoaderOption{
+		wallet.WithLogger(logger),
+		wallet.WithDBDriver(cfg.DBDriver),
+	}
+	if cfg.EncryptDB {
+		opts = append(opts, wallet.WithEncryptedDB())
+	}
+	switch cfg.KDFUpgrade {
+	case "scrypt":
+		opts = append(opts, wallet.WithKDFUpgrade(
+			&waddrmgr.DefaultScryptOptions,
+		))
+	case "argon2id":
+		opts = append(opts, wallet.WithKDFUpgrade(
+			&waddrmgr.DefaultArgon2idOptions,
+		))
+	}
+	return opts
+}
+
+// externalSigner returns the HWI signer of the hardware wallet of the
+// --hwifingerprint option for the asset, or nil if the option is not set.
+func (cfg *config) externalSigner(a *asset) (wallet.ExternalSigner, error) {
+	if cfg.HWIFingerprint == "" {
+		return nil, nil
+	}
+	fingerprint, err := parseFingerprint(cfg.HWIFingerprint)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := hwi.NewSigner(
+		cfg.HWI, fingerprint, a.netParams.BTCDParams(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", a.chain, err)
+	}
+	return signer, nil
+}
+
+// checkEncryptedDBPass returns an error if the wallet database is encrypted
+// with the public passphrase, and the passphrase is empty or the insecure
+// default one, as the database would be encrypted with a key anyone can derive.
+func (cfg *config) checkEncryptedDBPass(pubPass []byte) error {
+	if cfg.EncryptDB && (len(pubPass) == 0 ||
+		string(pubPass) == wallet.InsecurePubPassphrase) {
+
+		return fmt.Errorf("the --encrypteddb option requires a " +
+			"non-empty public wallet passphrase other than the " +
+			"default one")
+	}
+	return nil
+}
+
+// dryRunUpgrade reports the changes the database upgrades would make to the
+// asset's wallet database when it is next opened, without changing it.
+func dryRunUpgrade(cfg *config, a *asset) error {
+	loader := wallet.NewLoader(
+		a.netParams, a.netDir, true, cfg.DBTimeout, 250,
+		cfg.loaderOptions(a.log)...,
+	)
+	changes, err := loader.DryRunUpgrade([]byte(cfg.WalletPass))
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("The %s wallet database is up to date.\n", a.chain)
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Printf("The %s %s database would be upgraded from version "+
+			"%d to %d, adding %d, modifying %d and removing %d "+
+			"keys.\n", a.chain, c.Name, c.FromVersion, c.ToVersion,
+			c.Added, c.Modified, c.Removed)
+	}
+	return nil
+}
+
 // createWallet prompts the user for information needed to generate a new wallet
 // and generates the wallet accordingly.  The new wallet will reside at the
-// provided path.
-func createWallet(cfg *config, netDir string, netParams *netparams.ChainParams) error {
+// provided path.  When any of the non-interactive creation options are set,
+// the wallet is instead created from those options without prompting.
+func createWallet(cfg *config, netDir string, netParams *netparams.ChainParams,
+	logger btclog.Logger) error {
+
 	loader := wallet.NewLoader(
-		netParams.BTCDParams(), netDir, true, cfg.DBTimeout, 250,
+		netParams, netDir, true, cfg.DBTimeout, 250,
+		cfg.loaderOptions(logger)...,
 	)
 
+	if cfg.nonInteractiveCreate() {
+		return createWalletNoPrompt(cfg, loader, netParams)
+	}
+
 	// Start by prompting for the private passphrase.  When there is an
 	// existing keystore, the user will be promped for that passphrase,
 	// otherwise they will be prompted for a new one.
@@ -41,6 +151,9 @@ func createWallet(cfg *config, netDir string, netParams *netparams.ChainParams)
 	if err != nil {
 		return err
 	}
+	if err := cfg.checkEncryptedDBPass(pubPass); err != nil {
+		return err
+	}
 
 	// Ascertain the wallet generation seed.  This will either be an
 	// automatically generated value the user has already confirmed or a
@@ -50,9 +163,124 @@ func createWallet(cfg *config, netDir string, netParams *netparams.ChainParams)
 		return err
 	}
 
+	bday := time.Now()
+	if cfg.Birthday != "" {
+		bday, err = parseBirthday(cfg.Birthday)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Creating the wallet...")
+	w, err := loader.CreateNewWallet(pubPass, privPass, seed, bday)
+	if err != nil {
+		return err
+	}
+
+	w.Manager.Close()
+	fmt.Println("The wallet has been created successfully.")
+	return nil
+}
+
+// createWalletNoPrompt creates a new wallet using only the seed, extended
+// public key, birthday and passphrase sources provided by the config.  This
+// allows wallets to be provisioned by scripts that do not have a terminal.
+func createWalletNoPrompt(cfg *config, loader *wallet.Loader,
+	netParams *netparams.ChainParams) error {
+
+	// Wallets restored from an existing seed or extended public key may
+	// have history that predates creation, so default to the genesis block
+	// for those.  New wallets can't have any.
+	bday := time.Now()
+	if cfg.SeedFile != "" || cfg.XPub != "" {
+		bday = netParams.GenesisBlock.Header.Timestamp
+	}
+	if cfg.Birthday != "" {
+		var err error
+		bday, err = parseBirthday(cfg.Birthday)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The public passphrase falls back to the configured one, which is the
+	// insecure default unless --walletpass was set.
+	pubPass, err := readSecret(cfg.PubPassEnv, cfg.PubPassFD)
+	if err != nil {
+		return fmt.Errorf("unable to read public passphrase: %w", err)
+	}
+	if pubPass == nil {
+		pubPass = []byte(cfg.WalletPass)
+	}
+	if err := cfg.checkEncryptedDBPass(pubPass); err != nil {
+		return err
+	}
+
+	if cfg.XPub != "" {
+		return createWatchingOnlyWallet(cfg, loader, pubPass, bday)
+	}
+
+	privPass, err := readSecret(cfg.PrivPassEnv, cfg.PrivPassFD)
+	if err != nil {
+		return fmt.Errorf("unable to read private passphrase: %w", err)
+	}
+	if len(privPass) == 0 {
+		return fmt.Errorf("a private passphrase must be provided with " +
+			"--privpassenv or --privpassfd")
+	}
+
+	mnemonicPass, err := readSecret(cfg.MnemonicPassEnv, cfg.MnemonicPassFD)
+	if err != nil {
+		return fmt.Errorf("unable to read mnemonic passphrase: %w", err)
+	}
+
+	// Generated mnemonics are written to the mnemonic file rather than
+	// printed, as the output of non-interactive runs is often logged.
+	var seed []byte
+	switch {
+	case cfg.SeedFile != "":
+		seed, err = readSeedFile(cfg.SeedFile, string(mnemonicPass))
+		if err != nil {
+			return err
+		}
+
+	case cfg.MnemonicFile != "":
+		entropy, err := bip39.NewEntropy(bip39.RecommendedEntropyBits)
+		if err != nil {
+			return err
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy, bip39.English)
+		if err != nil {
+			return err
+		}
+		seed, err = bip39.NewSeed(mnemonic, string(mnemonicPass))
+		if err != nil {
+			return err
+		}
+		err = writeMnemonicFile(cfg.MnemonicFile, mnemonic)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("The wallet generation mnemonic was written to %s.\n",
+			cfg.MnemonicFile)
+		fmt.Println("IMPORTANT: Keep the mnemonic in a safe place as " +
+			"you\nwill NOT be able to restore your wallet without it.")
+		if len(mnemonicPass) != 0 {
+			fmt.Println("The mnemonic passphrase is needed along with " +
+				"the mnemonic to restore your wallet.")
+		}
+
+	default:
+		return fmt.Errorf("a seed must be provided with --seedfile, or " +
+			"the generated mnemonic written with --mnemonicfile")
+	}
+
 	fmt.Println("Creating the wallet...")
-	w, err := loader.CreateNewWallet(pubPass, privPass, seed, time.Now())
+	w, err := loader.CreateNewWallet(pubPass, privPass, seed, bday)
 	if err != nil {
+		if cfg.MnemonicFile != "" {
+			os.Remove(cleanAndExpandPath(cfg.MnemonicFile))
+		}
 		return err
 	}
 
@@ -61,6 +289,162 @@ func createWallet(cfg *config, netDir string, netParams *netparams.ChainParams)
 	return nil
 }
 
+// createWatchingOnlyWallet creates a new watching-only wallet and imports the
+// configured account extended public key as its default account.
+func createWatchingOnlyWallet(cfg *config, loader *wallet.Loader,
+	pubPass []byte, bday time.Time) error {
+
+	acctKey, err := hdkeychain.NewKeyFromString(cfg.XPub)
+	if err != nil {
+		return fmt.Errorf("invalid extended public key: %w", err)
+	}
+
+	var addrType waddrmgr.AddressType
+	switch cfg.XPubAddrType {
+	case "np2wkh":
+		addrType = waddrmgr.NestedWitnessPubKey
+	case "p2wkh":
+		addrType = waddrmgr.WitnessPubKey
+	case "p2tr":
+		addrType = waddrmgr.TaprootPubKey
+	default:
+		return fmt.Errorf("unknown address type %q", cfg.XPubAddrType)
+	}
+
+	var fingerprint uint32
+	if cfg.XPubFingerprint != "" {
+		fingerprint, err = parseFingerprint(cfg.XPubFingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Creating the watching-only wallet...")
+	w, err := loader.CreateNewWatchingOnlyWallet(pubPass, bday)
+	if err != nil {
+		return err
+	}
+	defer w.Manager.Close()
+
+	_, err = w.ImportAccount("default", acctKey, fingerprint, &addrType)
+	if err != nil {
+		return fmt.Errorf("unable to import account: %w", err)
+	}
+
+	fmt.Println("The wallet has been created successfully.")
+	return nil
+}
+
+// parseFingerprint parses a master key fingerprint given as 4 hex encoded
+// bytes, in the form key origins of descriptors and PSBTs show them.
+func parseFingerprint(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return 0, fmt.Errorf("invalid fingerprint %q: must be 4 hex "+
+			"encoded bytes", s)
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// writeMnemonicFile writes the mnemonic to a new file at path, which only the
+// user can read.  Existing files are not overwritten.
+func writeMnemonicFile(path, mnemonic string) error {
+	path = cleanAndExpandPath(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(mnemonic + "\n")
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// parseBirthday parses a wallet birthday given either as a unix timestamp or
+// as a YYYY-MM-DD date in UTC.
+func parseBirthday(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid birthday %q: must be a "+
+			"unix timestamp or YYYY-MM-DD date", s)
+	}
+	return t, nil
+}
+
+// readSecret reads a passphrase from the named environment variable or, when
+// fd is not negative, from the open file descriptor.  Surrounding whitespace
+// is trimmed.  A nil slice is returned when neither source is set.
+func readSecret(envVar string, fd int) ([]byte, error) {
+	switch {
+	case envVar != "":
+		v, ok := os.LookupEnv(envVar)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set",
+				envVar)
+		}
+		return bytes.TrimSpace([]byte(v)), nil
+
+	case fd >= 0:
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+		if f == nil {
+			return nil, fmt.Errorf("invalid file descriptor %d", fd)
+		}
+		defer f.Close()
+
+		// Only the first line is used so that the writer is not
+		// required to close its end of the descriptor.
+		line, err := bufio.NewReader(f).ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return bytes.TrimSpace(line), nil
+	}
+
+	return nil, nil
+}
+
+// readSeedFile reads the wallet seed stored in the file at path.  The file may
+// contain either a BIP-0039 mnemonic, which is combined with mnemonicPass to
+// derive the seed, or a hex-encoded seed.
+func readSeedFile(path string, mnemonicPass string) ([]byte, error) {
+	b, err := os.ReadFile(cleanAndExpandPath(path))
+	if err != nil {
+		return nil, err
+	}
+	seedStr := string(bytes.TrimSpace(b))
+
+	if len(strings.Fields(seedStr)) > 1 {
+		seed, err := bip39.NewSeed(seedStr, mnemonicPass)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mnemonic in %s: %w",
+				path, err)
+		}
+		return seed, nil
+	}
+	if mnemonicPass != "" {
+		return nil, fmt.Errorf("a mnemonic passphrase was provided " +
+			"but the seed file does not contain a mnemonic")
+	}
+
+	seed, err := hex.DecodeString(seedStr)
+	if err != nil || len(seed) < hdkeychain.MinSeedBytes ||
+		len(seed) > hdkeychain.MaxSeedBytes {
+
+		return nil, fmt.Errorf("invalid seed in %s: must be a "+
+			"BIP-0039 mnemonic or a hexadecimal value that is at "+
+			"least %d bits and at most %d bits", path, hdkeychain.MinSeedBytes*8,
+			hdkeychain.MaxSeedBytes*8)
+	}
+	return seed, nil
+}
+
 // checkCreateDir checks that the path exists and is a directory.
 // If path does not exist, it is created.
 func checkCreateDir(path string) error {
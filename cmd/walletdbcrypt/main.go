@@ -0,0 +1,202 @@
+// walletdbcrypt converts a wallet database to the encrypted form opened by
+// utxowallet with the --encrypteddb option, or back to plaintext.  The wallet
+// must not be running while its database is converted.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/snacl"
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/bdb"
+	"github.com/bisoncraft/utxowallet/walletdb/encrypted"
+	"github.com/bisoncraft/utxowallet/walletdb/migration"
+	_ "github.com/bisoncraft/utxowallet/walletdb/sqlite"
+	flags "github.com/jessevdk/go-flags"
+)
+
+//nolint:lll
+type options struct {
+	DBPath     string        `long:"db" required:"true" description:"Path to the wallet database, e.g. ~/.utxowallet/btc/mainnet/wallet.db"`
+	Decrypt    bool          `long:"decrypt" description:"Convert an encrypted wallet database back to plaintext"`
+	WalletPass string        `long:"walletpass" default-mask:"-" description:"The public wallet passphrase the database is encrypted with, which may not be empty or the default one when encrypting"`
+	DBTimeout  time.Duration `long:"dbtimeout" description:"The timeout value to use when opening the wallet database"`
+	DBDriver   string        `long:"dbdriver" description:"Database driver of the wallet database {bdb, sqlite}"`
+	KDF        string        `long:"kdf" description:"Key derivation function of the key the database is encrypted with, which should match the --kdfupgrade option of utxowallet {scrypt, argon2id}"`
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	opts := options{
+		WalletPass: wallet.InsecurePubPassphrase,
+		DBTimeout:  wallet.DefaultDBTimeout,
+		DBDriver:   wallet.DefaultDBDriver,
+		KDF:        "scrypt",
+	}
+	if _, err := flags.Parse(&opts); err != nil {
+		var e *flags.Error
+		if errors.As(err, &e) && e.Type == flags.ErrHelp {
+			return nil
+		}
+		return err
+	}
+
+	switch opts.KDF {
+	case "scrypt", "argon2id":
+	default:
+		return fmt.Errorf("unknown key derivation function %q", opts.KDF)
+	}
+
+	// A database encrypted with an empty or the default public passphrase
+	// can be read by anyone, so they are only accepted to decrypt existing
+	// databases.
+	if !opts.Decrypt && (opts.WalletPass == "" ||
+		opts.WalletPass == wallet.InsecurePubPassphrase) {
+
+		return errors.New("the database can't be encrypted with an " +
+			"empty or the default public wallet passphrase, set " +
+			"the wallet's passphrase with --walletpass")
+	}
+
+	dbPath := filepath.Clean(opts.DBPath)
+	pass := []byte(opts.WalletPass)
+	convertedPath := dbPath + ".converting"
+	if _, err := os.Stat(convertedPath); err == nil {
+		return fmt.Errorf("%s exists, remove it if no conversion is "+
+			"running", convertedPath)
+	}
+
+	err := convert(&opts, dbPath, convertedPath, pass)
+	if err == nil {
+		err = verify(&opts, convertedPath, pass)
+	}
+	if err != nil {
+		os.Remove(convertedPath)
+		return err
+	}
+
+	// The original database is kept until the converted one has been
+	// opened successfully.
+	oldPath := fmt.Sprintf("%s.%d.old", dbPath, time.Now().UnixNano())
+	if err := os.Rename(dbPath, oldPath); err != nil {
+		return err
+	}
+	if err := os.Rename(convertedPath, dbPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Converted %s, keeping the original database at %s.  "+
+		"Remove it once the wallet opens the converted database.\n",
+		dbPath, oldPath)
+	return nil
+}
+
+// convert copies the wallet database at dbPath to a new database at
+// convertedPath, encrypting it with the passphrase, or decrypting it if the
+// --decrypt option is set.
+func convert(opts *options, dbPath, convertedPath string, pass []byte) error {
+	decrypt := opts.Decrypt
+	if err := wallet.CheckDBDriver(dbPath, opts.DBDriver); err != nil {
+		return err
+	}
+	src, err := walletdb.Open(opts.DBDriver, dbPath, true, opts.DBTimeout)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Ensure the database is in the form being converted from.
+	encryptedSrc, err := walletdb.Open("encrypted", src, pass)
+	switch {
+	case decrypt && err != nil:
+		return err
+	case decrypt:
+		src = encryptedSrc
+	case err == nil:
+		return errors.New("the wallet database is already encrypted")
+	case err != encrypted.ErrNotEncrypted:
+		return err
+	}
+
+	dst, err := walletdb.Create(
+		opts.DBDriver, convertedPath, true, opts.DBTimeout,
+	)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if !decrypt {
+		dst, err = walletdb.Create(
+			"encrypted", dst, pass, secretKeyGenerator(opts.KDF),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return migration.Restore(dst, src)
+}
+
+// secretKeyGenerator returns the generator of the key the database is
+// encrypted with, which derives it using the default parameters of the key
+// derivation function, like the passphrase keys of the wallet.
+func secretKeyGenerator(kdf string) encrypted.SecretKeyGenerator {
+	options := &waddrmgr.DefaultScryptOptions
+	if kdf == "argon2id" {
+		options = &waddrmgr.DefaultArgon2idOptions
+	}
+	return func(passphrase *[]byte) (*snacl.SecretKey, error) {
+		return waddrmgr.NewSecretKey(passphrase, options)
+	}
+}
+
+// verify opens the converted database at convertedPath in the form it was
+// converted to, and reads all of its keys and values, so that the original
+// database is only replaced by a converted database which can be read.
+func verify(opts *options, convertedPath string, pass []byte) error {
+	db, err := walletdb.Open(
+		opts.DBDriver, convertedPath, true, opts.DBTimeout,
+	)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !opts.Decrypt {
+		db, err = walletdb.Open("encrypted", db, pass)
+		if err != nil {
+			return err
+		}
+	}
+
+	return walletdb.View(db, func(tx walletdb.ReadTx) error {
+		return tx.ForEachBucket(func(key []byte) error {
+			return readBucket(tx.ReadBucket(key))
+		})
+	})
+}
+
+// readBucket reads all keys and values of the bucket and its nested buckets.
+func readBucket(b walletdb.ReadBucket) error {
+	return b.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		nested := b.NestedReadBucket(k)
+		if nested == nil {
+			return fmt.Errorf("missing nested bucket %x", k)
+		}
+		return readBucket(nested)
+	})
+}
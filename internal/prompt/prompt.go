@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/bisoncraft/utxowallet/wallet/bip39"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"golang.org/x/term"
 )
@@ -19,22 +20,53 @@ import (
 // ProvideSeed is used to prompt for the wallet seed which maybe required during
 // upgrades.
 func ProvideSeed() ([]byte, error) {
-	reader := bufio.NewReader(os.Stdin)
+	return existingSeed(bufio.NewReader(os.Stdin))
+}
+
+// existingSeed prompts the user for an existing wallet seed, given either as a
+// hexadecimal value or as a BIP-0039 mnemonic.  When a mnemonic is entered,
+// the user is also asked for its optional passphrase.  All prompts are
+// repeated until the user enters a valid response.
+func existingSeed(reader *bufio.Reader) ([]byte, error) {
 	for {
-		fmt.Print("Enter existing wallet seed: ")
+		fmt.Print("Enter existing wallet seed or mnemonic: ")
 		seedStr, err := reader.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
-		seedStr = strings.TrimSpace(strings.ToLower(seedStr))
+		seedStr = strings.TrimSpace(seedStr)
+
+		if len(strings.Fields(seedStr)) > 1 {
+			if _, err := bip39.DetectWordlist(seedStr); err != nil {
+				fmt.Printf("Invalid mnemonic specified: %v\n", err)
+				continue
+			}
+
+			usePass, err := promptListBool(reader, "Does the "+
+				"mnemonic have a passphrase?", "no")
+			if err != nil {
+				return nil, err
+			}
+			var mnemonicPass []byte
+			if usePass {
+				mnemonicPass, err = promptPass(reader, "Enter "+
+					"the mnemonic passphrase", true)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return bip39.NewSeed(seedStr, string(mnemonicPass))
+		}
 
-		seed, err := hex.DecodeString(seedStr)
+		seed, err := hex.DecodeString(strings.ToLower(seedStr))
 		if err != nil || len(seed) < hdkeychain.MinSeedBytes ||
 			len(seed) > hdkeychain.MaxSeedBytes {
 
 			fmt.Printf("Invalid seed specified.  Must be a "+
-				"hexadecimal value that is at least %d bits and "+
-				"at most %d bits\n", hdkeychain.MinSeedBytes*8,
+				"BIP-0039 mnemonic or a hexadecimal value that "+
+				"is at least %d bits and at most %d bits\n",
+				hdkeychain.MinSeedBytes*8,
 				hdkeychain.MaxSeedBytes*8)
 			continue
 		}
@@ -227,10 +259,11 @@ func PublicPass(reader *bufio.Reader, privPass []byte,
 }
 
 // Seed prompts the user whether they want to use an existing wallet generation
-// seed.  When the user answers no, a seed will be generated and displayed to
-// the user along with prompting them for confirmation.  When the user answers
-// yes, a the user is prompted for it.  All prompts are repeated until the user
-// enters a valid response.
+// seed.  When the user answers no, a BIP-0039 mnemonic will be generated and
+// displayed to the user along with prompting them for confirmation and for an
+// optional mnemonic passphrase, and the seed derived from both is returned.
+// When the user answers yes, the user is prompted for it.  All prompts are
+// repeated until the user enters a valid response.
 func Seed(reader *bufio.Reader) ([]byte, error) {
 	// Ascertain the wallet generation seed.
 	useUserSeed, err := promptListBool(reader, "Do you have an "+
@@ -238,57 +271,57 @@ func Seed(reader *bufio.Reader) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if !useUserSeed {
-		seed, err := hdkeychain.GenerateSeed(hdkeychain.RecommendedSeedLen)
-		if err != nil {
-			return nil, err
-		}
-
-		fmt.Println("Your wallet generation seed is:")
-		fmt.Printf("%x\n", seed)
-		fmt.Println("IMPORTANT: Keep the seed in a safe place as you\n" +
-			"will NOT be able to restore your wallet without it.")
-		fmt.Println("Please keep in mind that anyone who has access\n" +
-			"to the seed can also restore your wallet thereby\n" +
-			"giving them access to all your funds, so it is\n" +
-			"imperative that you keep it in a secure location.")
-
-		for {
-			fmt.Print(`Once you have stored the seed in a safe ` +
-				`and secure location, enter "OK" to continue: `)
-			confirmSeed, err := reader.ReadString('\n')
-			if err != nil {
-				return nil, err
-			}
-			confirmSeed = strings.TrimSpace(confirmSeed)
-			confirmSeed = strings.Trim(confirmSeed, `"`)
-			if confirmSeed == "OK" {
-				break
-			}
-		}
+	if useUserSeed {
+		return existingSeed(reader)
+	}
 
-		return seed, nil
+	entropy, err := bip39.NewEntropy(bip39.RecommendedEntropyBits)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := bip39.NewMnemonic(entropy, bip39.English)
+	if err != nil {
+		return nil, err
 	}
 
+	fmt.Println("Your wallet generation mnemonic is:")
+	fmt.Println(mnemonic)
+	fmt.Println("IMPORTANT: Keep the mnemonic in a safe place as you\n" +
+		"will NOT be able to restore your wallet without it.")
+	fmt.Println("Please keep in mind that anyone who has access\n" +
+		"to the mnemonic can also restore your wallet thereby\n" +
+		"giving them access to all your funds, so it is\n" +
+		"imperative that you keep it in a secure location.")
+
 	for {
-		fmt.Print("Enter existing wallet seed: ")
-		seedStr, err := reader.ReadString('\n')
+		fmt.Print(`Once you have stored the mnemonic in a safe ` +
+			`and secure location, enter "OK" to continue: `)
+		confirmSeed, err := reader.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
-		seedStr = strings.TrimSpace(strings.ToLower(seedStr))
-
-		seed, err := hex.DecodeString(seedStr)
-		if err != nil || len(seed) < hdkeychain.MinSeedBytes ||
-			len(seed) > hdkeychain.MaxSeedBytes {
-
-			fmt.Printf("Invalid seed specified.  Must be a "+
-				"hexadecimal value that is at least %d bits and "+
-				"at most %d bits\n", hdkeychain.MinSeedBytes*8,
-				hdkeychain.MaxSeedBytes*8)
-			continue
+		confirmSeed = strings.TrimSpace(confirmSeed)
+		confirmSeed = strings.Trim(confirmSeed, `"`)
+		if confirmSeed == "OK" {
+			break
 		}
+	}
 
-		return seed, nil
+	usePass, err := promptListBool(reader, "Do you want to protect the "+
+		"mnemonic with a passphrase?", "no")
+	if err != nil {
+		return nil, err
 	}
+	var mnemonicPass []byte
+	if usePass {
+		mnemonicPass, err = promptPass(reader, "Enter the mnemonic "+
+			"passphrase", true)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("IMPORTANT: The mnemonic passphrase is needed along\n" +
+			"with the mnemonic to restore your wallet.")
+	}
+
+	return bip39.NewSeed(mnemonic, string(mnemonicPass))
 }
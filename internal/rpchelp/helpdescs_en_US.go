@@ -76,7 +76,7 @@ var helpDescsEnUS = map[string]string{
 	"infowalletresult-testnet":         "Whether or not server is using testnet",
 	"infowalletresult-relayfee":        "The minimum relay fee for non-free transactions in BTC/KB",
 	"infowalletresult-errors":          "Any current errors",
-	"infowalletresult-paytxfee":        "The increment used each time more fee is required for an authored transaction",
+	"infowalletresult-paytxfee":        "The estimated fee rate in BTC/KB used for authored transactions when none is specified",
 	"infowalletresult-balance":         "The balance of all accounts calculated with one block confirmation",
 	"infowalletresult-walletversion":   "The version of the address manager database",
 	"infowalletresult-unlocked_until":  "Unset",
@@ -238,6 +238,14 @@ var helpDescsEnUS = map[string]string{
 	"listtransactions-from":             "Number of transactions to skip before results are created",
 	"listtransactions-includewatchonly": "Unused",
 
+	// LoadWalletCmd help.
+	"loadwallet--synopsis":  "Opens the existing wallet when the wallet was not loaded at startup.",
+	"loadwallet-walletname": "Name of the wallet, which is returned but otherwise unused since there is a single wallet per network",
+
+	// LoadWalletResult help.
+	"loadwalletresult-name":    "The wallet name",
+	"loadwalletresult-warning": "Unset",
+
 	// ListUnspentCmd help.
 	"listunspent--synopsis": "Returns a JSON array of objects representing unlocked unspent outputs controlled by wallet keys.",
 	"listunspent-minconf":   "Minimum number of block confirmations required before a transaction output is considered",
@@ -26,6 +26,9 @@ type ChainParams struct {
 	DNSSeeds                 []chaincfg.DNSSeed
 	DefaultPort              string
 
+	// RPCServerPort is the default port of the wallet's JSON-RPC server.
+	RPCServerPort string
+
 	// Human-readable part for Bech32 encoded segwit addresses, as defined
 	// in BIP 173.
 	Bech32HRPSegwit string
@@ -49,11 +52,19 @@ type ChainParams struct {
 	BIP0065Height int32
 	BIP0066Height int32
 
+	// CoinbaseMaturity is the number of blocks required before newly mined
+	// coins can be spent.
+	CoinbaseMaturity uint16
+
 	// CheckPoW is a function that will check the proof-of-work validity for a
 	// block header. If CheckPoW is nil, the standard Bitcoin protocol is used.
 	CheckPoW func(*wire.BlockHeader) error
 	// MaxSatoshi varies between assets.
 	MaxSatoshi int64
+	// FilterHeaderCheckpoints maps filter types and heights to known-good
+	// filter headers. They are used to check whether peers are serving the
+	// expected filter headers.
+	FilterHeaderCheckpoints map[wire.FilterType]map[uint32]*chainhash.Hash
 }
 
 func (c *ChainParams) BTCDParams() *chaincfg.Params {
@@ -81,5 +92,6 @@ func (c *ChainParams) BTCDParams() *chaincfg.Params {
 		BIP0034Height:            c.BIP0034Height,
 		BIP0065Height:            c.BIP0065Height,
 		BIP0066Height:            c.BIP0066Height,
+		CoinbaseMaturity:         c.CoinbaseMaturity,
 	}
 }
@@ -10,10 +10,12 @@ import (
 	"crypto/subtle"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"runtime/debug"
 
 	"github.com/bisoncraft/utxowallet/internal/zero"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/nacl/secretbox"
 	"golang.org/x/crypto/scrypt"
 )
@@ -27,6 +29,7 @@ var (
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrMalformed       = errors.New("malformed data")
 	ErrDecryptFailed   = errors.New("unable to decrypt")
+	ErrInvalidParams   = errors.New("invalid key derivation parameters")
 )
 
 // Various constants needed for encryption scheme.
@@ -38,8 +41,46 @@ const (
 	DefaultN  = 16384 // 2^14
 	DefaultR  = 8
 	DefaultP  = 1
+
+	// Defaults for Argon2id, following the second recommended option of
+	// RFC 9106.  The memory is in KiB.
+	DefaultArgon2idTime    = 3
+	DefaultArgon2idMemory  = 64 * 1024 // 64 MiB
+	DefaultArgon2idThreads = 4
+)
+
+// paramsVersion is the version of the marshalled format of Parameters which
+// names the key derivation function.  Parameters for scrypt are marshalled in
+// the original unversioned format instead, so they can still be read by
+// older software.
+const paramsVersion = 1
+
+// KDF identifies the key derivation function used to derive a SecretKey from
+// a passphrase.
+type KDF uint8
+
+// These constants define the supported key derivation functions.
+const (
+	// KDFScrypt derives keys with scrypt using the N, R and P parameters.
+	KDFScrypt KDF = iota
+
+	// KDFArgon2id derives keys with Argon2id using the Time, Memory and
+	// Threads parameters.
+	KDFArgon2id
 )
 
+// String returns the name of the key derivation function.
+func (kdf KDF) String() string {
+	switch kdf {
+	case KDFScrypt:
+		return "scrypt"
+	case KDFArgon2id:
+		return "argon2id"
+	default:
+		return fmt.Sprintf("unknown KDF %d", uint8(kdf))
+	}
+}
+
 // CryptoKey represents a secret key which can be used to encrypt and decrypt
 // data.
 type CryptoKey [KeySize]byte
@@ -97,9 +138,17 @@ func GenerateCryptoKey() (*CryptoKey, error) {
 type Parameters struct {
 	Salt   [KeySize]byte
 	Digest [sha256.Size]byte
-	N      int
-	R      int
-	P      int
+	KDF    KDF
+
+	// N, R and P are the parameters of scrypt.
+	N int
+	R int
+	P int
+
+	// Time, Memory (in KiB) and Threads are the parameters of Argon2id.
+	Time    uint32
+	Memory  uint32
+	Threads uint8
 }
 
 // SecretKey houses a crypto key and the parameters needed to derive it from a
@@ -109,15 +158,31 @@ type SecretKey struct {
 	Parameters Parameters
 }
 
-// deriveKey fills out the Key field.
+// deriveKey fills out the Key field using the key derivation function named
+// by the parameters.
 func (sk *SecretKey) deriveKey(password *[]byte) error {
-	key, err := scrypt.Key(*password, sk.Parameters.Salt[:],
-		sk.Parameters.N,
-		sk.Parameters.R,
-		sk.Parameters.P,
-		len(sk.Key))
-	if err != nil {
-		return err
+	params := &sk.Parameters
+
+	var key []byte
+	switch params.KDF {
+	case KDFScrypt:
+		var err error
+		key, err = scrypt.Key(*password, params.Salt[:], params.N,
+			params.R, params.P, len(sk.Key))
+		if err != nil {
+			return err
+		}
+
+	case KDFArgon2id:
+		// Argon2 panics rather than returning an error for these.
+		if params.Time == 0 || params.Threads == 0 {
+			return ErrInvalidParams
+		}
+		key = argon2.IDKey(*password, params.Salt[:], params.Time,
+			params.Memory, params.Threads, uint32(len(sk.Key)))
+
+	default:
+		return ErrInvalidParams
 	}
 	copy(sk.Key[:], key)
 	zero.Bytes(key)
@@ -127,7 +192,8 @@ func (sk *SecretKey) deriveKey(password *[]byte) error {
 	// between means you end up needing twice the amount of memory.  For
 	// example, if your scrypt parameters are such that you require 1GB and
 	// you call it twice in a row, without this you end up allocating 2GB
-	// since the first GB probably hasn't been released yet.
+	// since the first GB probably hasn't been released yet.  The same goes
+	// for the memory of Argon2id.
 	debug.FreeOSMemory()
 
 	return nil
@@ -138,6 +204,10 @@ func (sk *SecretKey) deriveKey(password *[]byte) error {
 func (sk *SecretKey) Marshal() []byte {
 	params := &sk.Parameters
 
+	if params.KDF != KDFScrypt {
+		return sk.marshalVersioned()
+	}
+
 	// The marshalled format for the the params is as follows:
 	//   <salt><digest><N><R><P>
 	//
@@ -158,8 +228,30 @@ func (sk *SecretKey) Marshal() []byte {
 	return marshalled
 }
 
+// marshalVersioned returns the Parameters field marshalled into the versioned
+// format which names the key derivation function.
+func (sk *SecretKey) marshalVersioned() []byte {
+	params := &sk.Parameters
+
+	// The versioned format for the params is as follows:
+	//   <salt><digest><version><KDF><time><memory><threads>
+	//
+	// KeySize + sha256.Size + version (1 byte) + KDF (1 byte) +
+	// time (4 bytes) + memory (4 bytes) + threads (1 byte)
+	marshalled := make([]byte, 0, KeySize+sha256.Size+11)
+	marshalled = append(marshalled, params.Salt[:]...)
+	marshalled = append(marshalled, params.Digest[:]...)
+	marshalled = append(marshalled, paramsVersion, byte(params.KDF))
+	marshalled = binary.LittleEndian.AppendUint32(marshalled, params.Time)
+	marshalled = binary.LittleEndian.AppendUint32(marshalled, params.Memory)
+	marshalled = append(marshalled, params.Threads)
+
+	return marshalled
+}
+
 // Unmarshal unmarshalls the parameters needed to derive the secret key from a
-// passphrase into sk.
+// passphrase into sk.  Both the original format for scrypt parameters and the
+// versioned format are accepted.
 func (sk *SecretKey) Unmarshal(marshalled []byte) error {
 	if sk.Key == nil {
 		sk.Key = (*CryptoKey)(&[KeySize]byte{})
@@ -169,8 +261,10 @@ func (sk *SecretKey) Unmarshal(marshalled []byte) error {
 	//   <salt><digest><N><R><P>
 	//
 	// KeySize + sha256.Size + N (8 bytes) + R (8 bytes) + P (8 bytes)
+	//
+	// The versioned format is shorter and is told apart by its length.
 	if len(marshalled) != KeySize+sha256.Size+24 {
-		return ErrMalformed
+		return sk.unmarshalVersioned(marshalled)
 	}
 
 	params := &sk.Parameters
@@ -178,6 +272,7 @@ func (sk *SecretKey) Unmarshal(marshalled []byte) error {
 	marshalled = marshalled[KeySize:]
 	copy(params.Digest[:], marshalled[:sha256.Size])
 	marshalled = marshalled[sha256.Size:]
+	params.KDF = KDFScrypt
 	params.N = int(binary.LittleEndian.Uint64(marshalled[:8]))
 	marshalled = marshalled[8:]
 	params.R = int(binary.LittleEndian.Uint64(marshalled[:8]))
@@ -187,6 +282,36 @@ func (sk *SecretKey) Unmarshal(marshalled []byte) error {
 	return nil
 }
 
+// unmarshalVersioned unmarshalls parameters in the versioned format written by
+// marshalVersioned into sk.
+func (sk *SecretKey) unmarshalVersioned(marshalled []byte) error {
+	if len(marshalled) != KeySize+sha256.Size+11 {
+		return ErrMalformed
+	}
+
+	var params Parameters
+	copy(params.Salt[:], marshalled[:KeySize])
+	marshalled = marshalled[KeySize:]
+	copy(params.Digest[:], marshalled[:sha256.Size])
+	marshalled = marshalled[sha256.Size:]
+	if marshalled[0] != paramsVersion {
+		return ErrMalformed
+	}
+	params.KDF = KDF(marshalled[1])
+	if params.KDF != KDFArgon2id {
+		return ErrMalformed
+	}
+	marshalled = marshalled[2:]
+	params.Time = binary.LittleEndian.Uint32(marshalled[:4])
+	marshalled = marshalled[4:]
+	params.Memory = binary.LittleEndian.Uint32(marshalled[:4])
+	marshalled = marshalled[4:]
+	params.Threads = marshalled[0]
+
+	sk.Parameters = params
+	return nil
+}
+
 // Zero zeroes the underlying secret key while leaving the parameters intact.
 // This effectively makes the key unusable until it is derived again via the
 // DeriveKey function.
@@ -223,13 +348,34 @@ func (sk *SecretKey) Decrypt(in []byte) ([]byte, error) {
 
 // NewSecretKey returns a SecretKey structure based on the passed parameters.
 func NewSecretKey(password *[]byte, N, r, p int) (*SecretKey, error) { // nolint:gocritic
+	return newSecretKey(password, Parameters{
+		KDF: KDFScrypt,
+		N:   N,
+		R:   r,
+		P:   p,
+	})
+}
+
+// NewArgon2idSecretKey returns a SecretKey structure derived with Argon2id
+// using the passed parameters.  The memory is in KiB.
+func NewArgon2idSecretKey(password *[]byte, time, memory uint32,
+	threads uint8) (*SecretKey, error) {
+
+	return newSecretKey(password, Parameters{
+		KDF:     KDFArgon2id,
+		Time:    time,
+		Memory:  memory,
+		Threads: threads,
+	})
+}
+
+// newSecretKey returns a SecretKey structure derived using the key derivation
+// function and parameters of params, with a new random salt.
+func newSecretKey(password *[]byte, params Parameters) (*SecretKey, error) {
 	sk := SecretKey{
-		Key: (*CryptoKey)(&[KeySize]byte{}),
+		Key:        (*CryptoKey)(&[KeySize]byte{}),
+		Parameters: params,
 	}
-	// setup parameters
-	sk.Parameters.N = N
-	sk.Parameters.R = r
-	sk.Parameters.P = p
 	_, err := io.ReadFull(prng, sk.Parameters.Salt[:])
 	if err != nil {
 		return nil, err
@@ -114,3 +114,75 @@ func TestDeriveKeyInvalid(t *testing.T) {
 		t.Errorf("unexpected DeriveKey key failure: %v", err)
 	}
 }
+
+func TestArgon2idSecretKey(t *testing.T) {
+	sk, err := NewArgon2idSecretKey(&password, 1, 64, 1)
+	if err != nil {
+		t.Errorf("unexpected NewArgon2idSecretKey error: %v", err)
+		return
+	}
+
+	// Scrypt parameters keep the original format, while Argon2id
+	// parameters use the versioned one.
+	marshalled := sk.Marshal()
+	if len(marshalled) == len(params) {
+		t.Errorf("Argon2id parameters marshalled in the scrypt format")
+		return
+	}
+
+	var sk2 SecretKey
+	if err := sk2.Unmarshal(marshalled); err != nil {
+		t.Errorf("unexpected unmarshal error: %v", err)
+		return
+	}
+	if sk2.Parameters != sk.Parameters {
+		t.Errorf("parameters not equal - got %+v, want %+v",
+			sk2.Parameters, sk.Parameters)
+		return
+	}
+
+	if err := sk2.DeriveKey(&password); err != nil {
+		t.Errorf("unexpected DeriveKey error: %v", err)
+		return
+	}
+	if !bytes.Equal(sk2.Key[:], sk.Key[:]) {
+		t.Errorf("keys not equal")
+		return
+	}
+
+	p := []byte("wrong password")
+	if err := sk2.DeriveKey(&p); err != ErrInvalidPassword {
+		t.Errorf("wrong password didn't fail")
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	sk, err := NewArgon2idSecretKey(&password, 1, 64, 1)
+	if err != nil {
+		t.Errorf("unexpected NewArgon2idSecretKey error: %v", err)
+		return
+	}
+	marshalled := sk.Marshal()
+
+	unknownVersion := append([]byte(nil), marshalled...)
+	unknownVersion[64]++
+	unknownKDF := append([]byte(nil), marshalled...)
+	unknownKDF[65]++
+
+	tests := []struct {
+		name       string
+		marshalled []byte
+	}{
+		{"empty", nil},
+		{"truncated", marshalled[:len(marshalled)-1]},
+		{"unknown version", unknownVersion},
+		{"unknown KDF", unknownKDF},
+	}
+	for _, test := range tests {
+		var sk SecretKey
+		if err := sk.Unmarshal(test.marshalled); err != ErrMalformed {
+			t.Errorf("%s: unexpected unmarshal error: %v", test.name,
+				err)
+		}
+	}
+}
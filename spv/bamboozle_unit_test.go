@@ -618,7 +618,9 @@ func runCheckCFCheckptSanityTestCase(t *testing.T, testCase *cfCheckptTestCase)
 		}
 	}
 
-	heightDiff, err := checkCFCheckptSanity(testCase.checkpoints, cfStore)
+	heightDiff, err := checkCFCheckptSanity(
+		log, testCase.checkpoints, cfStore,
+	)
 	if err != nil {
 		t.Fatalf("Error from checkCFCheckptSanity: %s", err)
 	}
@@ -684,8 +686,8 @@ func TestResolveFilterMismatchFromBlock(t *testing.T) {
 		testCase := testCase
 		t.Run(testCase.name, func(t *testing.T) {
 			badPeers, err := resolveFilterMismatchFromBlock(
-				block, wire.GCSFilterRegular, testCase.peerFilters,
-				testCase.banThreshold,
+				log, block, wire.GCSFilterRegular,
+				testCase.peerFilters, testCase.banThreshold,
 			)
 			if err != nil {
 				switch {
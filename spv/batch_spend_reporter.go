@@ -3,6 +3,7 @@ package spv
 import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 // batchSpendReporter orchestrates the delivery of spend reports to
@@ -26,6 +27,9 @@ type batchSpendReporter struct {
 	// allocations when reconstructing the current filterEntries.
 	outpoints map[wire.OutPoint][]byte
 
+	// log is the logger of the scanner that owns the reporter.
+	log btclog.Logger
+
 	// filterEntries holds the current set of watched outpoint, and is
 	// applied to cfilters to gauge whether we should download the block.
 	//
@@ -34,8 +38,9 @@ type batchSpendReporter struct {
 }
 
 // newBatchSpendReporter instantiates a fresh batchSpendReporter.
-func newBatchSpendReporter() *batchSpendReporter {
+func newBatchSpendReporter(logger btclog.Logger) *batchSpendReporter {
 	return &batchSpendReporter{
+		log:         logger,
 		requests:    make(map[wire.OutPoint][]*GetUtxoRequest),
 		initialTxns: make(map[wire.OutPoint]*SpendReport),
 		outpoints:   make(map[wire.OutPoint][]byte),
@@ -58,7 +63,7 @@ func (b *batchSpendReporter) NotifyProgress(blockHeight uint32) {
 // delivered signaling that no spend was detected. If the original output could
 // not be found, a nil spend report is returned.
 func (b *batchSpendReporter) NotifyUnspentAndUnfound() {
-	log.Debugf("Finished batch, %d unspent outpoints", len(b.requests))
+	b.log.Debugf("Finished batch, %d unspent outpoints", len(b.requests))
 
 	for outpoint, requests := range b.requests {
 		op := outpoint
@@ -66,7 +71,7 @@ func (b *batchSpendReporter) NotifyUnspentAndUnfound() {
 		// A nil SpendReport indicates the output was not found.
 		tx, ok := b.initialTxns[outpoint]
 		if !ok {
-			log.Warnf("Unknown initial txn for getuxo request %v",
+			b.log.Warnf("Unknown initial txn for getuxo request %v",
 				outpoint)
 		}
 
@@ -101,7 +106,7 @@ func (b *batchSpendReporter) notifyRequests(
 	delete(b.outpoints, *outpoint)
 
 	for _, request := range requests {
-		request.deliver(report, err)
+		request.deliver(b.log, report, err)
 	}
 }
 
@@ -144,7 +149,7 @@ func (b *batchSpendReporter) addNewRequests(reqs []*GetUtxoRequest) {
 	for _, req := range reqs {
 		outpoint := req.Input.OutPoint
 
-		log.Debugf("Adding outpoint=%s height=%d to watchlist",
+		b.log.Debugf("Adding outpoint=%s height=%d to watchlist",
 			outpoint, req.BirthHeight)
 
 		b.requests[outpoint] = append(b.requests[outpoint], req)
@@ -203,7 +208,7 @@ func (b *batchSpendReporter) findInitialTransactions(block *wire.MsgBlock,
 			// output on the transaction. If not, we will be unable
 			// to find the initial output.
 			if op.Index >= uint32(len(txOuts)) {
-				log.Errorf("Failed to find outpoint %s -- "+
+				b.log.Errorf("Failed to find outpoint %s -- "+
 					"invalid output index", op)
 				initialTxns[op] = nil
 				continue
@@ -229,11 +234,11 @@ func (b *batchSpendReporter) findInitialTransactions(block *wire.MsgBlock,
 		tx, ok := initialTxns[req.Input.OutPoint]
 		switch {
 		case !ok:
-			log.Debugf("Outpoint %v not found in block %d ",
+			b.log.Debugf("Outpoint %v not found in block %d ",
 				req.Input.OutPoint, height)
 			initialTxns[req.Input.OutPoint] = nil
 		case tx != nil:
-			log.Tracef("Block %d creates output %s",
+			b.log.Tracef("Block %d creates output %s",
 				height, req.Input.OutPoint)
 		default:
 		}
@@ -263,7 +268,7 @@ func (b *batchSpendReporter) notifySpends(block *wire.MsgBlock,
 				continue
 			}
 
-			log.Debugf("UTXO %v spent by txn %v", outpoint,
+			b.log.Debugf("UTXO %v spent by txn %v", outpoint,
 				tx.TxHash())
 
 			spend := &SpendReport{
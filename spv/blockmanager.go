@@ -16,6 +16,7 @@ import (
 	"github.com/bisoncraft/utxowallet/spv/banman"
 	"github.com/bisoncraft/utxowallet/spv/blockntfns"
 	"github.com/bisoncraft/utxowallet/spv/chainsync"
+	"github.com/bisoncraft/utxowallet/spv/filterdb"
 	"github.com/bisoncraft/utxowallet/spv/headerfs"
 	"github.com/bisoncraft/utxowallet/spv/headerlist"
 	"github.com/bisoncraft/utxowallet/spv/query"
@@ -26,6 +27,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 const (
@@ -87,6 +89,11 @@ type blockManagerCfg struct {
 	// compact filters are persistently stored.
 	RegFilterHeaders *headerfs.FilterHeaderStore
 
+	// FilterHeaders are the stores where filter headers for additional
+	// filter types are persistently stored. They are synced behind the
+	// regular filter headers.
+	FilterHeaders map[wire.FilterType]*headerfs.FilterHeaderStore
+
 	// TimeSource is used to access a time estimate based on the clocks of
 	// the connected peers.
 	TimeSource blockchain.MedianTimeSource
@@ -110,6 +117,10 @@ type blockManagerCfg struct {
 		checkResponse func(sp *ServerPeer, resp wire.Message,
 			quit chan<- struct{}, peerQuit chan<- struct{}),
 		options ...QueryOption)
+
+	// Logger is the logger the block manager writes to. The package logger
+	// is used if nil.
+	Logger btclog.Logger
 }
 
 // blockManager provides a concurrency safe block manager for handling all
@@ -119,6 +130,7 @@ type blockManager struct { // nolint:maligned
 	shutdown int32 // To be used atomically.
 
 	cfg *blockManagerCfg
+	log btclog.Logger
 
 	btcdParams *chaincfg.Params
 
@@ -132,9 +144,6 @@ type blockManager struct { // nolint:maligned
 	// headers that we've verified in the past 10 seconds.
 	fltrHeaderProgessLogger *headerProgressLogger
 
-	// genesisHeader is the filter header of the genesis block.
-	genesisHeader chainhash.Hash
-
 	// headerTip will be set to the current block header tip at all times.
 	// Callers MUST hold the lock below each time they read/write from
 	// this field.
@@ -218,16 +227,22 @@ func newBlockManager(cfg *blockManagerCfg) (*blockManager, error) {
 	targetTimePerBlock := int64(cfg.ChainParams.TargetTimePerBlock / time.Second)
 	adjustmentFactor := cfg.ChainParams.RetargetAdjustmentFactor
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log
+	}
+
 	bm := blockManager{
 		cfg:           cfg,
+		log:           logger,
 		btcdParams:    cfg.ChainParams.BTCDParams(),
 		peerChan:      make(chan interface{}, MaxPeers*3),
 		blockNtfnChan: make(chan blockntfns.BlockNtfn),
 		blkHeaderProgressLogger: newBlockProgressLogger(
-			"Processed", "block headers", log,
+			"Processed", "block headers", logger,
 		),
 		fltrHeaderProgessLogger: newBlockProgressLogger(
-			"Verified", "filter header", log,
+			"Verified", "filter header", logger,
 		),
 		headerList: headerlist.NewBoundedMemoryChain(
 			numMaxMemHeaders,
@@ -247,14 +262,6 @@ func newBlockManager(cfg *blockManagerCfg) (*blockManager, error) {
 	bm.newHeadersSignal = sync.NewCond(&bm.newHeadersMtx)
 	bm.newFilterHeadersSignal = sync.NewCond(&bm.newFilterHeadersMtx)
 
-	// We fetch the genesis header to use for verifying the first received
-	// interval.
-	genesisHeader, err := cfg.RegFilterHeaders.FetchHeaderByHeight(0)
-	if err != nil {
-		return nil, err
-	}
-	bm.genesisHeader = *genesisHeader
-
 	// Initialize the next checkpoint based on the current height.
 	header, height, err := cfg.BlockHeaders.ChainTip()
 	if err != nil {
@@ -293,13 +300,13 @@ func (b *blockManager) Start() {
 		return
 	}
 
-	log.Trace("Starting block manager")
+	b.log.Trace("Starting block manager")
 	b.wg.Add(2)
 	go b.blockHandler()
 	go func() {
 		defer b.wg.Done()
 
-		log.Debug("Waiting for peer connection...")
+		b.log.Debug("Waiting for peer connection...")
 
 		// Before starting the cfHandler we want to make sure we are
 		// connected with at least one peer.
@@ -309,16 +316,23 @@ func (b *blockManager) Start() {
 			return
 		}
 
-		log.Debug("Peer connected, starting cfHandler.")
+		b.log.Debug("Peer connected, starting cfHandler.")
 		b.cfHandler()
 	}()
+
+	// The filter headers of additional filter types are synced by their
+	// own handlers, which follow the regular filter headers.
+	for fType, store := range b.cfg.FilterHeaders {
+		b.wg.Add(1)
+		go b.extraCFHandler(fType, store)
+	}
 }
 
 // Stop gracefully shuts down the block manager by stopping all asynchronous
 // handlers and waiting for them to finish.
 func (b *blockManager) Stop() error {
 	if atomic.AddInt32(&b.shutdown, 1) != 1 {
-		log.Warnf("Block manager is already in the process of " +
+		b.log.Warnf("Block manager is already in the process of " +
 			"shutting down")
 		return nil
 	}
@@ -342,7 +356,7 @@ func (b *blockManager) Stop() error {
 		}
 	}()
 
-	log.Infof("Block manager shutting down")
+	b.log.Infof("Block manager shutting down")
 	close(b.quit)
 	b.wg.Wait()
 
@@ -374,7 +388,7 @@ func (b *blockManager) handleNewPeerMsg(peers *list.List, sp *ServerPeer) {
 		return
 	}
 
-	log.Infof("New valid peer %s (%s)", sp, sp.UserAgent())
+	b.log.Infof("New valid peer %s (%s)", sp, sp.UserAgent())
 
 	// Ignore the peer if it's not a sync candidate.
 	if !b.isSyncCandidate(sp) {
@@ -389,14 +403,14 @@ func (b *blockManager) handleNewPeerMsg(peers *list.List, sp *ServerPeer) {
 	// the new peer.
 	_, height, err := b.cfg.BlockHeaders.ChainTip()
 	if err != nil {
-		log.Criticalf("Couldn't retrieve block header chain tip: %s",
+		b.log.Criticalf("Couldn't retrieve block header chain tip: %s",
 			err)
 		return
 	}
 	if height < uint32(sp.StartingHeight()) && b.BlockHeadersSynced() {
 		locator, err := b.cfg.BlockHeaders.LatestBlockLocator()
 		if err != nil {
-			log.Criticalf("Couldn't retrieve latest block "+
+			b.log.Criticalf("Couldn't retrieve latest block "+
 				"locator: %s", err)
 			return
 		}
@@ -435,7 +449,7 @@ func (b *blockManager) handleDonePeerMsg(peers *list.List, sp *ServerPeer) {
 		}
 	}
 
-	log.Infof("Lost peer %s", sp)
+	b.log.Infof("Lost peer %s", sp)
 
 	// Attempt to find a new peer to sync from if the quitting peer is the
 	// sync peer.  Also, reset the header state.
@@ -459,7 +473,7 @@ func (b *blockManager) handleDonePeerMsg(peers *list.List, sp *ServerPeer) {
 // run as a goroutine. It requests and processes cfheaders messages in a
 // separate goroutine from the peer handlers.
 func (b *blockManager) cfHandler() {
-	defer log.Trace("Committed filter header handler done")
+	defer b.log.Trace("Committed filter header handler done")
 
 	var (
 		// allCFCheckpoints is a map from our peers to the list of
@@ -493,7 +507,7 @@ waitForHeaders:
 	// cfheaders. We do this to speed up the sync, as the check pointed
 	// sync is faster, than fetching each header from each peer during the
 	// normal "at tip" syncing.
-	log.Infof("Waiting for more block headers, then will start "+
+	b.log.Infof("Waiting for more block headers, then will start "+
 		"cfheaders sync from height %v...", b.filterHeaderTip)
 
 	b.newHeadersSignal.L.Lock()
@@ -524,13 +538,13 @@ waitForHeaders:
 	// header sync off of that.
 	lastHeader, lastHeight, err := b.cfg.BlockHeaders.ChainTip()
 	if err != nil {
-		log.Critical(err)
+		b.log.Critical(err)
 		return
 	}
 	lastHash := lastHeader.BlockHash()
 
 	b.newFilterHeadersMtx.RLock()
-	log.Infof("Starting cfheaders sync from (block_height=%v, "+
+	b.log.Infof("Starting cfheaders sync from (block_height=%v, "+
 		"block_hash=%v) to (block_height=%v, block_hash=%v)",
 		b.filterHeaderTip, b.filterHeaderTipHash, lastHeight,
 		lastHeader.BlockHash())
@@ -539,7 +553,7 @@ waitForHeaders:
 	fType := wire.GCSFilterRegular
 	store := b.cfg.RegFilterHeaders
 
-	log.Infof("Starting cfheaders sync for filter_type=%v", fType)
+	b.log.Infof("Starting cfheaders sync for filter_type=%v", fType)
 
 	// If we have less than a full checkpoint's worth of blocks, such as on
 	// simnet, we don't really need to request checkpoints as we'll get 0
@@ -571,12 +585,12 @@ waitForHeaders:
 				bestHash = *lastCp.Hash
 			}
 
-			log.Debugf("Getting filter checkpoints up to "+
+			b.log.Debugf("Getting filter checkpoints up to "+
 				"height=%v, hash=%v", bestHeight, bestHash)
 			allCFCheckpoints = b.getCheckpts(&bestHash, fType)
 			if len(allCFCheckpoints) == 0 {
-				log.Warnf("Unable to fetch set of " +
-					"candidate checkpoints, trying again...")
+				b.log.Warnf("Unable to fetch set of candidate " +
+					"checkpoints, trying again...")
 
 				select {
 				case <-time.After(retryTimeout):
@@ -607,8 +621,9 @@ waitForHeaders:
 			checkpoints, store, fType,
 		)
 		if err != nil {
-			log.Warnf("got error attempting to determine correct "+
-				"cfheader checkpoints: %v, trying again", err)
+			b.log.Warnf("got error attempting to determine "+
+				"correct cfheader checkpoints: %v, trying "+
+				"again", err)
 		}
 		if len(goodCheckpoints) == 0 {
 			select {
@@ -644,7 +659,7 @@ waitForHeaders:
 	b.newFilterHeadersMtx.RUnlock()
 	b.newHeadersMtx.RUnlock()
 
-	log.Infof("Fully caught up with cfheaders at height "+
+	b.log.Infof("Fully caught up with cfheaders at height "+
 		"%v, waiting at tip for new blocks", lastHeight)
 
 	// Now that we've been fully caught up to the tip of the current header
@@ -683,7 +698,7 @@ waitForHeaders:
 		if err = b.getUncheckpointedCFHeaders(
 			store, fType,
 		); err != nil {
-			log.Debugf("couldn't get uncheckpointed headers for "+
+			b.log.Debugf("couldn't get uncheckpointed headers for "+
 				"%v: %v", fType, err)
 
 			select {
@@ -702,6 +717,110 @@ waitForHeaders:
 	}
 }
 
+// extraCFHandler is the cfheader download handler for an additional filter
+// type. It must be run as a goroutine. It waits for the regular filter headers
+// to get ahead of the filter headers of its type, then catches up with them
+// using the same checkpointed and un-checkpointed fetching as cfHandler.
+func (b *blockManager) extraCFHandler(fType wire.FilterType,
+	store *headerfs.FilterHeaderStore) {
+
+	defer b.wg.Done()
+	defer b.log.Tracef("Filter header handler for filter_type=%v done",
+		fType)
+
+	select {
+	case <-b.cfg.firstPeerSignal:
+	case <-b.quit:
+		return
+	}
+
+	b.log.Infof("Starting cfheaders sync for filter_type=%v", fType)
+
+	for {
+		_, storeHeight, err := store.ChainTip()
+		if err != nil {
+			b.log.Criticalf("Unable to get filter_type=%v chain "+
+				"tip: %v", fType, err)
+			return
+		}
+
+		// We'll wait until the regular filter headers are ahead of
+		// the filter headers of this type.
+		b.newFilterHeadersSignal.L.Lock()
+		for b.filterHeaderTip <= storeHeight {
+			b.newFilterHeadersSignal.Wait()
+
+			select {
+			case <-b.quit:
+				b.newFilterHeadersSignal.L.Unlock()
+				return
+			default:
+			}
+		}
+		targetHeight := b.filterHeaderTip
+		targetHash := b.filterHeaderTipHash
+		b.newFilterHeadersSignal.L.Unlock()
+
+		err = b.syncExtraCFHeaders(
+			store, fType, storeHeight, targetHeight, &targetHash,
+		)
+		if err != nil {
+			b.log.Debugf("couldn't get cfheaders for %v: %v",
+				fType, err)
+
+			select {
+			case <-time.After(retryTimeout):
+			case <-b.quit:
+				return
+			}
+		}
+
+		// Quit if requested.
+		select {
+		case <-b.quit:
+			return
+		default:
+		}
+	}
+}
+
+// syncExtraCFHeaders catches the filter headers of an additional filter type
+// up with the regular filter header tip at targetHeight. Filter headers that
+// are more than a checkpoint interval behind are fetched using checkpoints.
+func (b *blockManager) syncExtraCFHeaders(store *headerfs.FilterHeaderStore,
+	fType wire.FilterType, storeHeight, targetHeight uint32,
+	targetHash *chainhash.Hash) error {
+
+	if storeHeight+wire.CFCheckptInterval <= targetHeight {
+		checkpoints := b.getCheckpts(targetHash, fType)
+		if len(checkpoints) == 0 {
+			return fmt.Errorf("unable to fetch set of candidate " +
+				"checkpoints")
+		}
+
+		goodCheckpoints, err := b.resolveConflict(
+			checkpoints, store, fType,
+		)
+		if err != nil {
+			return err
+		}
+
+		b.getCheckpointedCFHeaders(goodCheckpoints, store, fType)
+	}
+
+	return b.getUncheckpointedCFHeaders(store, fType)
+}
+
+// filterHeaderStores returns the stores of the regular filter headers and the
+// filter headers of any additional filter types.
+func (b *blockManager) filterHeaderStores() []*headerfs.FilterHeaderStore {
+	stores := []*headerfs.FilterHeaderStore{b.cfg.RegFilterHeaders}
+	for _, store := range b.cfg.FilterHeaders {
+		stores = append(stores, store)
+	}
+	return stores
+}
+
 // getUncheckpointedCFHeaders gets the next batch of cfheaders from the
 // network, if it can, and resolves any conflicts between them. It then writes
 // any verified headers to the store.
@@ -730,11 +849,11 @@ func (b *blockManager) getUncheckpointedCFHeaders(
 	// If the heights match, then we're fully synced, so we don't need to
 	// do anything from there.
 	if blockHeight == filtHeight {
-		log.Tracef("cfheaders already caught up to blocks")
+		b.log.Tracef("cfheaders already caught up to blocks")
 		return nil
 	}
 
-	log.Infof("Attempting to fetch set of un-checkpointed filters "+
+	b.log.Infof("Attempting to fetch set of un-checkpointed filters "+
 		"at height=%v, hash=%v", blockHeight, blockHeader.BlockHash())
 
 	// Query all peers for the responses.
@@ -746,7 +865,8 @@ func (b *blockManager) getUncheckpointedCFHeaders(
 		if msg.PrevFilterHeader != *filterTip {
 			err := b.cfg.BanPeer(peer, banman.InvalidFilterHeader)
 			if err != nil {
-				log.Errorf("Unable to ban peer %v: %v", peer, err)
+				b.log.Errorf("Unable to ban peer %v: %v", peer,
+					err)
 			}
 			delete(headers, peer)
 		}
@@ -770,7 +890,7 @@ func (b *blockManager) getUncheckpointedCFHeaders(
 				return err
 			}
 
-			log.Warnf("Banning %v peers due to invalid filter "+
+			b.log.Warnf("Banning %v peers due to invalid filter "+
 				"headers", len(badPeers))
 
 			for _, peer := range badPeers {
@@ -778,8 +898,8 @@ func (b *blockManager) getUncheckpointedCFHeaders(
 					peer, banman.InvalidFilterHeader,
 				)
 				if err != nil {
-					log.Errorf("Unable to ban peer %v: %v",
-						peer, err)
+					b.log.Errorf("Unable to ban peer %v: "+
+						"%v", peer, err)
 				}
 				delete(headers, peer)
 			}
@@ -811,11 +931,12 @@ func (b *blockManager) getUncheckpointedCFHeaders(
 // checkpointedCFHeadersQuery holds all information necessary to perform and
 // handle a query for checkpointed filter headers.
 type checkpointedCFHeadersQuery struct {
-	blockMgr    *blockManager
-	msgs        []wire.Message
-	checkpoints []*chainhash.Hash
-	stopHashes  map[chainhash.Hash]uint32
-	headerChan  chan *wire.MsgCFHeaders
+	blockMgr      *blockManager
+	msgs          []wire.Message
+	genesisHeader *chainhash.Hash
+	checkpoints   []*chainhash.Hash
+	stopHashes    map[chainhash.Hash]uint32
+	headerChan    chan *wire.MsgCFHeaders
 }
 
 // requests creates the query.Requests for this CF headers query.
@@ -874,7 +995,7 @@ func (c *checkpointedCFHeadersQuery) handleResponse(req, resp wire.Message,
 	// Use either the genesis header or the previous checkpoint index as
 	// the previous checkpoint when verifying that the filter headers in
 	// the response match up.
-	prevCheckpoint := &c.blockMgr.genesisHeader
+	prevCheckpoint := c.genesisHeader
 	if checkPointIndex > 0 {
 		prevCheckpoint = c.checkpoints[checkPointIndex-1]
 	}
@@ -889,8 +1010,8 @@ func (c *checkpointedCFHeadersQuery) handleResponse(req, resp wire.Message,
 
 	// The response doesn't match the checkpoint.
 	if !verifyCheckpoint(prevCheckpoint, nextCheckpoint, r) {
-		log.Warnf("Checkpoints at index %v don't match response!!!",
-			checkPointIndex)
+		c.blockMgr.log.Warnf("Checkpoints at index %v don't match "+
+			"response!!!", checkPointIndex)
 
 		// If the peer gives us a header that doesn't match what we
 		// know to be the best checkpoint, then we'll ban the peer so
@@ -899,7 +1020,8 @@ func (c *checkpointedCFHeadersQuery) handleResponse(req, resp wire.Message,
 			peerAddr, banman.InvalidFilterHeaderCheckpoint,
 		)
 		if err != nil {
-			log.Errorf("Unable to ban peer %v: %v", peerAddr, err)
+			c.blockMgr.log.Errorf("Unable to ban peer %v: %v",
+				peerAddr, err)
 		}
 
 		return query.Progress{
@@ -944,14 +1066,22 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 
 	initialFilterHeader := curHeader
 
-	log.Infof("Fetching set of checkpointed cfheaders filters from "+
+	// We fetch the genesis header to use for verifying the first received
+	// interval.
+	genesisHeader, err := store.FetchHeaderByHeight(0)
+	if err != nil {
+		panic(fmt.Sprintf("failed getting genesis header from filter "+
+			"store: %v", err))
+	}
+
+	b.log.Infof("Fetching set of checkpointed cfheaders filters from "+
 		"height=%v, hash=%v", curHeight, curHeader)
 
 	// The starting interval is the checkpoint index that we'll be starting
 	// from based on our current height in the filter header index.
 	startingInterval := curHeight / wire.CFCheckptInterval
 
-	log.Infof("Starting to query for cfheaders from "+
+	b.log.Infof("Starting to query for cfheaders from "+
 		"checkpoint_interval=%v, checkpoints=%v", startingInterval,
 		len(checkpoints))
 
@@ -992,7 +1122,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 		}
 		endHeightRange := nextInterval * wire.CFCheckptInterval
 
-		log.Tracef("Checkpointed cfheaders request start_range=%v, "+
+		b.log.Tracef("Checkpointed cfheaders request start_range=%v, "+
 			"end_range=%v", startHeightRange, endHeightRange)
 
 		// In order to fetch the range, we'll need the block header for
@@ -1027,7 +1157,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 		return
 	}
 
-	log.Infof("Attempting to query for %v cfheader batches", batchesCount)
+	b.log.Infof("Attempting to query for %v cfheader batches", batchesCount)
 
 	// We'll track the next interval we expect to receive headers for.
 	currentInterval = startingInterval
@@ -1038,11 +1168,12 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 	// dynamically.
 	headerChan := make(chan *wire.MsgCFHeaders, len(queryMsgs))
 	q := checkpointedCFHeadersQuery{
-		blockMgr:    b,
-		msgs:        queryMsgs,
-		checkpoints: checkpoints,
-		stopHashes:  stopHashes,
-		headerChan:  headerChan,
+		blockMgr:      b,
+		msgs:          queryMsgs,
+		genesisHeader: genesisHeader,
+		checkpoints:   checkpoints,
+		stopHashes:    stopHashes,
+		headerChan:    headerChan,
 	}
 
 	// Hand the queries to the work manager, and consume the verified
@@ -1062,8 +1193,9 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 			case err == query.ErrWorkManagerShuttingDown:
 				return
 			case err != nil:
-				log.Errorf("Query finished with error before "+
-					"all responses received: %v", err)
+				b.log.Errorf("Query finished with error "+
+					"before all responses received: %v",
+					err)
 				return
 			}
 
@@ -1083,7 +1215,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 		startHeight := checkPointIndex*wire.CFCheckptInterval + 1
 		lastHeight := startHeight + uint32(len(r.FilterHashes)) - 1
 
-		log.Debugf("Got cfheaders from height=%v to "+
+		b.log.Debugf("Got cfheaders from height=%v to "+
 			"height=%v, prev_hash=%v", startHeight,
 			lastHeight, r.PrevFilterHeader)
 
@@ -1091,7 +1223,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 		// verify that the checkpoints match, and then store
 		// them.
 		if startHeight > curHeight+1 {
-			log.Debugf("Got response for headers at "+
+			b.log.Debugf("Got response for headers at "+
 				"height=%v, only at height=%v, stashing",
 				startHeight, curHeight)
 		}
@@ -1099,7 +1231,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 		// If this is out of order stuff that's already been
 		// written, we can ignore it.
 		if lastHeight <= curHeight {
-			log.Debugf("Received out of order reply "+
+			b.log.Debugf("Received out of order reply "+
 				"end_height=%v, already written", lastHeight)
 			continue
 		}
@@ -1122,7 +1254,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 			// it from the cache and write it.
 			delete(queryResponses, currentInterval)
 
-			log.Debugf("Writing cfheaders at height=%v to "+
+			b.log.Debugf("Writing cfheaders at height=%v to "+
 				"next checkpoint", curHeight)
 
 			// If this is the very first range we've requested, we
@@ -1140,9 +1272,10 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 				offset := curHeight + 1 - startHeight
 				r.FilterHashes = r.FilterHashes[offset:]
 
-				log.Debugf("Using offset %d for initial "+
-					"filter header range (new prev_hash=%v)",
-					offset, r.PrevFilterHeader)
+				b.log.Debugf("Using offset %d for initial "+
+					"filter header range (new "+
+					"prev_hash=%v)", offset,
+					r.PrevFilterHeader)
 			}
 
 			// As we write the set of headers to disk, we
@@ -1164,7 +1297,7 @@ func (b *blockManager) getCheckpointedCFHeaders(checkpoints []*chainhash.Hash,
 		// If the current interval is beyond our checkpoints,
 		// we are done.
 		if currentInterval >= uint32(len(checkpoints)) {
-			log.Infof("Successfully got filter headers "+
+			b.log.Infof("Successfully got filter headers "+
 				"for %d checkpoints", len(checkpoints))
 			break
 		}
@@ -1233,7 +1366,7 @@ func (b *blockManager) writeCFHeadersMsg(msg *wire.MsgCFHeaders,
 	headerBatch[numHeaders-1].HeaderHash = lastHash
 	headerBatch[numHeaders-1].Height = lastHeight
 
-	log.Debugf("Writing filter headers up to height=%v, hash=%v, "+
+	b.log.Debugf("Writing filter headers up to height=%v, hash=%v, "+
 		"new_tip=%v", lastHeight, lastHash, lastHeader)
 
 	// Write the header batch.
@@ -1242,6 +1375,12 @@ func (b *blockManager) writeCFHeadersMsg(msg *wire.MsgCFHeaders,
 		return nil, 0, err
 	}
 
+	// The filter header tip and block notifications follow the regular
+	// filter headers only.
+	if store != b.cfg.RegFilterHeaders {
+		return &lastHeader, lastHeight, nil
+	}
+
 	// We'll also set the new header tip and notify any peers that the tip
 	// has changed as well. Unlike the set of notifications below, this is
 	// for sub-system that only need to know the height has changed rather
@@ -1281,9 +1420,13 @@ func (b *blockManager) rollBackToHeight(height uint32) error {
 		Timestamp: header.Timestamp,
 	}
 
-	_, regHeight, err := b.cfg.RegFilterHeaders.ChainTip()
-	if err != nil {
-		return err
+	filterHeights := make(map[*headerfs.FilterHeaderStore]uint32)
+	for _, store := range b.filterHeaderStores() {
+		_, filterHeight, err := store.ChainTip()
+		if err != nil {
+			return err
+		}
+		filterHeights[store] = filterHeight
 	}
 
 	for uint32(bs.Height) > height {
@@ -1295,12 +1438,15 @@ func (b *blockManager) rollBackToHeight(height uint32) error {
 		newTip := &header.PrevBlock
 
 		// Only roll back filter headers if they've caught up this far.
-		if uint32(bs.Height) <= regHeight {
-			newFilterTip, err := b.cfg.RegFilterHeaders.RollbackLastBlock(newTip)
+		for store, filterHeight := range filterHeights {
+			if uint32(bs.Height) > filterHeight {
+				continue
+			}
+			newFilterTip, err := store.RollbackLastBlock(newTip)
 			if err != nil {
 				return err
 			}
-			regHeight = uint32(newFilterTip.Height)
+			filterHeights[store] = uint32(newFilterTip.Height)
 		}
 
 		bs, err = b.cfg.BlockHeaders.RollbackLastBlock()
@@ -1381,7 +1527,7 @@ func (b *blockManager) resolveConflict(
 				b.cfg.ChainParams, fType, height, header,
 			)
 			if err == chainsync.ErrCheckpointMismatch {
-				log.Warnf("Banning peer=%v since served "+
+				b.log.Warnf("Banning peer=%v since served "+
 					"checkpoints didn't match our "+
 					"checkpoint at height %d", peer, height)
 
@@ -1389,8 +1535,8 @@ func (b *blockManager) resolveConflict(
 					peer, banman.InvalidFilterHeaderCheckpoint,
 				)
 				if err != nil {
-					log.Errorf("Unable to ban peer %v: %v",
-						peer, err)
+					b.log.Errorf("Unable to ban peer %v: "+
+						"%v", peer, err)
 				}
 				delete(checkpoints, peer)
 				break
@@ -1407,7 +1553,7 @@ func (b *blockManager) resolveConflict(
 	}
 
 	// Check if the remaining checkpoints are sane.
-	heightDiff, err := checkCFCheckptSanity(checkpoints, store)
+	heightDiff, err := checkCFCheckptSanity(b.log, checkpoints, store)
 	if err != nil {
 		return nil, err
 	}
@@ -1420,7 +1566,8 @@ func (b *blockManager) resolveConflict(
 		}
 	}
 
-	log.Warnf("Detected mismatch at index=%v for checkpoints!!!", heightDiff)
+	b.log.Warnf("Detected mismatch at index=%v for checkpoints!!!",
+		heightDiff)
 
 	// Delete any responses that have fewer checkpoints than where we see a
 	// mismatch.
@@ -1468,7 +1615,7 @@ func (b *blockManager) resolveConflict(
 				return nil, err
 			}
 
-			log.Warnf("Banning %v peers due to invalid filter "+
+			b.log.Warnf("Banning %v peers due to invalid filter "+
 				"headers", len(badPeers))
 
 			for _, peer := range badPeers {
@@ -1476,8 +1623,8 @@ func (b *blockManager) resolveConflict(
 					peer, banman.InvalidFilterHeader,
 				)
 				if err != nil {
-					log.Errorf("Unable to ban peer %v: %v",
-						peer, err)
+					b.log.Errorf("Unable to ban peer %v: "+
+						"%v", peer, err)
 				}
 				delete(headers, peer)
 				delete(checkpoints, peer)
@@ -1494,7 +1641,7 @@ func (b *blockManager) resolveConflict(
 				peer, banman.InvalidFilterHeaderCheckpoint,
 			)
 			if err != nil {
-				log.Errorf("Unable to ban peer %v: %v", peer,
+				b.log.Errorf("Unable to ban peer %v: %v", peer,
 					err)
 			}
 			delete(checkpoints, peer)
@@ -1504,7 +1651,7 @@ func (b *blockManager) resolveConflict(
 	// Check sanity again. If we're sane, return a matching checkpoint
 	// list. If not, return an error and download checkpoints from
 	// remaining peers.
-	heightDiff, err = checkCFCheckptSanity(checkpoints, store)
+	heightDiff, err = checkCFCheckptSanity(b.log, checkpoints, store)
 	if err != nil {
 		return nil, err
 	}
@@ -1554,7 +1701,7 @@ func (b *blockManager) detectBadPeers(headers map[string]*wire.MsgCFHeaders,
 	targetHeight, filterIndex uint32,
 	fType wire.FilterType) ([]string, error) {
 
-	log.Warnf("Detected cfheader mismatch at height=%v!!!", targetHeight)
+	b.log.Warnf("Detected cfheader mismatch at height=%v!!!", targetHeight)
 
 	// Get the block header for this height.
 	header, err := b.cfg.BlockHeaders.FetchHeaderByHeight(targetHeight)
@@ -1574,7 +1721,7 @@ func (b *blockManager) detectBadPeers(headers map[string]*wire.MsgCFHeaders,
 
 		// If a peer did not respond, ban it immediately.
 		if !ok {
-			log.Warnf("Peer %v did not respond to filter "+
+			b.log.Warnf("Peer %v did not respond to filter "+
 				"request, considering bad", peer)
 			badPeers = append(badPeers, peer)
 			continue
@@ -1587,7 +1734,7 @@ func (b *blockManager) detectBadPeers(headers map[string]*wire.MsgCFHeaders,
 			return nil, err
 		}
 		if hash != *msg.FilterHashes[filterIndex] {
-			log.Warnf("Peer %v serving filters not consistent "+
+			b.log.Warnf("Peer %v serving filters not consistent "+
 				"with filter hashes, considering bad.", peer)
 			badPeers = append(badPeers, peer)
 		}
@@ -1604,11 +1751,11 @@ func (b *blockManager) detectBadPeers(headers map[string]*wire.MsgCFHeaders,
 		return nil, err
 	}
 
-	log.Warnf("Attempting to reconcile cfheader mismatch amongst %v peers",
-		len(headers))
+	b.log.Warnf("Attempting to reconcile cfheader mismatch amongst %v "+
+		"peers", len(headers))
 
 	return resolveFilterMismatchFromBlock(
-		block.MsgBlock(), fType, filtersFromPeers,
+		b.log, block.MsgBlock(), fType, filtersFromPeers,
 
 		// We'll require a strict majority of our peers to agree on
 		// filters.
@@ -1621,7 +1768,7 @@ func (b *blockManager) detectBadPeers(headers map[string]*wire.MsgCFHeaders,
 // and verify from the filter in question. We'll return all the peers that
 // returned what we believe to be an invalid filter. The threshold argument is
 // the minimum number of peers we need to agree on a filter before banning the
-// other peers.
+// other peers. The outcome of each strategy is written to logger.
 //
 // We'll use a few strategies to figure out which peers we believe serve
 // invalid filters:
@@ -1633,18 +1780,20 @@ func (b *blockManager) detectBadPeers(headers map[string]*wire.MsgCFHeaders,
 //  3. If we cannot detect which filters are invalid from the block
 //     contents, we ban peers serving filters different from the majority of
 //     peers.
-func resolveFilterMismatchFromBlock(block *wire.MsgBlock,
-	fType wire.FilterType, filtersFromPeers map[string]*gcs.Filter,
+func resolveFilterMismatchFromBlock(logger btclog.Logger,
+	block *wire.MsgBlock, fType wire.FilterType,
+	filtersFromPeers map[string]*gcs.Filter,
 	threshold int) ([]string, error) {
 
 	badPeers := make(map[string]struct{})
 
-	log.Infof("Attempting to pinpoint mismatch in cfheaders for block=%v",
-		block.Header.BlockHash())
+	logger.Infof("Attempting to pinpoint mismatch in cfheaders for "+
+		"block=%v", block.Header.BlockHash())
 
 	// Based on the type of filter, our verification algorithm will differ.
-	// Only regular filters are currently defined.
-	if fType != wire.GCSFilterRegular {
+	// The contents of regular filters can be verified against the block,
+	// while for other filter types we can only go with the majority.
+	if _, ok := filterdb.LookupFilterType(filterdb.FilterType(fType)); !ok {
 		return nil, fmt.Errorf("unknown filter: %v", fType)
 	}
 
@@ -1655,29 +1804,34 @@ func resolveFilterMismatchFromBlock(block *wire.MsgBlock,
 	// than other peers.
 	opReturnMatches := make(map[string]int)
 
-	// We'll now run through each peer and ensure that each output
-	// script is included in the filter that they responded with to
-	// our query.
-	for peerAddr, filter := range filtersFromPeers {
-		// We'll ensure that all the filters include every output
-		// script within the block. From the scriptSig and witnesses of
-		// the inputs we can also derive most of the scripts of the
-		// outputs being spent (at least for standard scripts).
-		numOpReturns, err := VerifyBasicBlockFilter(
-			filter, btcutil.NewBlock(block),
-		)
-		if err != nil {
-			// Mark peer bad if we cannot verify its filter.
-			log.Warnf("Unable to check filter match for "+
-				"peer %v, marking as bad: %v", peerAddr, err)
+	if fType == wire.GCSFilterRegular {
+		// We'll now run through each peer and ensure that each output
+		// script is included in the filter that they responded with
+		// to our query.
+		for peerAddr, filter := range filtersFromPeers {
+			// We'll ensure that all the filters include every
+			// output script within the block. From the scriptSig
+			// and witnesses of the inputs we can also derive most
+			// of the scripts of the outputs being spent (at least
+			// for standard scripts).
+			numOpReturns, err := VerifyBasicBlockFilter(
+				filter, btcutil.NewBlock(block),
+			)
+			if err != nil {
+				// Mark peer bad if we cannot verify its
+				// filter.
+				logger.Warnf("Unable to check filter match "+
+					"for peer %v, marking as bad: %v",
+					peerAddr, err)
 
-			badPeers[peerAddr] = struct{}{}
-			continue
-		}
-		opReturnMatches[peerAddr] = numOpReturns
+				badPeers[peerAddr] = struct{}{}
+				continue
+			}
+			opReturnMatches[peerAddr] = numOpReturns
 
-		// TODO(roasbeef): eventually just do a comparison against
-		// decompressed filters
+			// TODO(roasbeef): eventually just do a comparison
+			// against decompressed filters
+		}
 	}
 
 	// TODO: We can add an after-the-fact countermeasure here against
@@ -1715,7 +1869,7 @@ func resolveFilterMismatchFromBlock(block *wire.MsgBlock,
 	// If only a few peers had matching OP_RETURNS, we assume they are bad.
 	numRemaining := len(filtersFromPeers) - len(potentialBans)
 	if len(potentialBans) > 0 && numRemaining >= threshold {
-		log.Warnf("Found %d peers serving filters with unexpected "+
+		logger.Warnf("Found %d peers serving filters with unexpected "+
 			"OP_RETURNS. %d peers remaining", len(potentialBans),
 			numRemaining)
 
@@ -1756,7 +1910,7 @@ func resolveFilterMismatchFromBlock(block *wire.MsgBlock,
 		}
 
 		if count[hash] < best {
-			log.Warnf("Peer %v is serving filter with hash(%v) "+
+			logger.Warnf("Peer %v is serving filter with hash(%v) "+
 				"other than majority, marking as bad",
 				peerAddr, hash)
 			badPeers[peerAddr] = struct{}{}
@@ -1842,6 +1996,11 @@ func (b *blockManager) fetchFilterFromAllPeers(
 	// peer.
 	filterResponses := make(map[string]*gcs.Filter)
 
+	fParams, ok := filterdb.LookupFilterType(filterdb.FilterType(filterType))
+	if !ok {
+		return filterResponses
+	}
+
 	// We'll now request the target filter from each peer, using a stop
 	// hash at the target block hash to ensure we only get a single filter.
 	fitlerReqMsg := wire.NewMsgGetCFilters(filterType, height, &blockHash)
@@ -1865,8 +2024,7 @@ func (b *blockManager) fetchFilterFromAllPeers(
 				// we'll decode it into an object the caller
 				// can utilize.
 				gcsFilter, err := gcs.FromNBytes(
-					builder.DefaultP, builder.DefaultM,
-					response.Data,
+					fParams.P, fParams.M, response.Data,
 				)
 				if err != nil {
 					// Malformed filter data. We can ignore
@@ -1918,7 +2076,7 @@ func (b *blockManager) getCheckpts(lastHash *chainhash.Hash,
 // least one of the peers differs. The checkpoints are also checked against the
 // existing store up to the tip of the store. If all of the peers match but
 // the store doesn't, the height at which the mismatch occurs is returned.
-func checkCFCheckptSanity(cp map[string][]*chainhash.Hash,
+func checkCFCheckptSanity(logger btclog.Logger, cp map[string][]*chainhash.Hash,
 	headerStore *headerfs.FilterHeaderStore) (int, error) {
 
 	// Get the known best header to compare against checkpoints.
@@ -1949,7 +2107,7 @@ func checkCFCheckptSanity(cp map[string][]*chainhash.Hash,
 				checkpoint = *checkpoints[i]
 			}
 			if checkpoint != *checkpoints[i] {
-				log.Warnf("mismatch at %v, expected %v got "+
+				logger.Warnf("mismatch at %v, expected %v got "+
 					"%v", i, checkpoint, checkpoints[i])
 				return i, nil
 			}
@@ -1966,8 +2124,9 @@ func checkCFCheckptSanity(cp map[string][]*chainhash.Hash,
 			}
 
 			if *header != checkpoint {
-				log.Warnf("mismatch at height %v, expected %v got "+
-					"%v", ckptHeight, header, checkpoint)
+				logger.Warnf("mismatch at height %v, expected "+
+					"%v got %v", ckptHeight, header,
+					checkpoint)
 				return i, nil
 			}
 		}
@@ -2005,7 +2164,7 @@ out:
 				b.handleDonePeerMsg(candidatePeers, msg.peer)
 
 			default:
-				log.Warnf("Invalid message type in block "+
+				b.log.Warnf("Invalid message type in block "+
 					"handler: %T", msg)
 			}
 
@@ -2014,7 +2173,7 @@ out:
 		}
 	}
 
-	log.Trace("Block handler done")
+	b.log.Trace("Block handler done")
 }
 
 // SyncPeer returns the current sync peer.
@@ -2099,7 +2258,7 @@ func (b *blockManager) startSync(peers *list.List) {
 
 	_, bestHeight, err := b.cfg.BlockHeaders.ChainTip()
 	if err != nil {
-		log.Errorf("Failed to get hash and height for the "+
+		b.log.Errorf("Failed to get hash and height for the "+
 			"latest block: %s", err)
 		return
 	}
@@ -2134,12 +2293,12 @@ func (b *blockManager) startSync(peers *list.List) {
 	if bestPeer != nil {
 		locator, err := b.cfg.BlockHeaders.LatestBlockLocator()
 		if err != nil {
-			log.Errorf("Failed to get block locator for the "+
+			b.log.Errorf("Failed to get block locator for the "+
 				"latest block: %s", err)
 			return
 		}
 
-		log.Infof("Syncing to block height %d from peer %s",
+		b.log.Infof("Syncing to block height %d from peer %s",
 			bestPeer.LastBlock(), bestPeer.Addr())
 
 		// Now that we know we have a new sync peer, we'll lock it in
@@ -2157,13 +2316,13 @@ func (b *blockManager) startSync(peers *list.List) {
 		// we'll use the next checkpoint to guide the set of headers we
 		// fetch, setting our stop hash to the next checkpoint hash.
 		if b.nextCheckpoint != nil && int32(bestHeight) < b.nextCheckpoint.Height {
-			log.Infof("Downloading headers for blocks %d to "+
+			b.log.Infof("Downloading headers for blocks %d to "+
 				"%d from peer %s", bestHeight+1,
 				b.nextCheckpoint.Height, bestPeer.Addr())
 
 			stopHash = b.nextCheckpoint.Hash
 		} else {
-			log.Infof("Fetching set of headers from tip "+
+			b.log.Infof("Fetching set of headers from tip "+
 				"(height=%v) from peer %s", bestHeight,
 				bestPeer.Addr())
 		}
@@ -2172,7 +2331,7 @@ func (b *blockManager) startSync(peers *list.List) {
 		// this peer with an initial GetHeaders message.
 		_ = b.SyncPeer().PushGetHeadersMsg(locator, stopHash)
 	} else {
-		log.Warnf("No sync peer candidates available")
+		b.log.Warnf("No sync peer candidates available")
 	}
 }
 
@@ -2339,8 +2498,9 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 			err = imsg.peer.PushGetHeadersMsg(locator,
 				&invVects[lastBlock].Hash)
 			if err != nil {
-				log.Warnf("Failed to send getheaders message "+
-					"to peer %s: %s", imsg.peer.Addr(), err)
+				b.log.Warnf("Failed to send getheaders "+
+					"message to peer %s: %s",
+					imsg.peer.Addr(), err)
 				return
 			}
 			b.lastRequested = invVects[lastBlock].Hash
@@ -2382,7 +2542,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 	// previous one. This is a quick sanity check to avoid doing the more
 	// expensive checks below if we know the headers are invalid.
 	if !areHeadersConnected(msg.Headers) {
-		log.Warnf("Headers received from peer don't connect")
+		b.log.Warnf("Headers received from peer don't connect")
 		hmsg.peer.Disconnect()
 		return
 	}
@@ -2401,7 +2561,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 		// Ensure there is a previous header to compare against.
 		prevNodeEl := b.headerList.Back()
 		if prevNodeEl == nil {
-			log.Warnf("Header list does not contain a previous" +
+			b.log.Warnf("Header list does not contain a previous" +
 				"element as expected -- disconnecting peer")
 			hmsg.peer.Disconnect()
 			return
@@ -2422,8 +2582,8 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 				&prevNodeHeader,
 			)
 			if err != nil {
-				log.Warnf("Header doesn't pass sanity check: "+
-					"%s -- disconnecting peer", err)
+				b.log.Warnf("Header doesn't pass sanity "+
+					"check: %s -- disconnecting peer", err)
 				hmsg.peer.Disconnect()
 				return
 			}
@@ -2495,9 +2655,9 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 				&blockHeader.PrevBlock,
 			)
 			if err != nil {
-				log.Warnf("Received block header that does not"+
-					" properly connect to the chain from"+
-					" peer %s (%s) -- disconnecting",
+				b.log.Warnf("Received block header that does "+
+					"not properly connect to the chain "+
+					"from peer %s (%s) -- disconnecting",
 					hmsg.peer.Addr(), err)
 				hmsg.peer.Disconnect()
 				return
@@ -2511,10 +2671,11 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 				prevNode.Height,
 			)
 			if backHeight < uint32(prevCheckpoint.Height) {
-				log.Errorf("Attempt at a reorg earlier than a "+
-					"checkpoint past which we've already "+
-					"synchronized -- disconnecting peer "+
-					"%s", hmsg.peer.Addr())
+				b.log.Errorf("Attempt at a reorg earlier than "+
+					"a checkpoint past which we've "+
+					"already synchronized -- "+
+					"disconnecting peer %s",
+					hmsg.peer.Addr())
 				hmsg.peer.Disconnect()
 				return
 			}
@@ -2552,9 +2713,9 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 					int32(prevNodeHeight), prevNodeHeader,
 				)
 				if err != nil {
-					log.Warnf("Header doesn't pass sanity"+
-						" check: %s -- disconnecting "+
-						"peer", err)
+					b.log.Warnf("Header doesn't pass "+
+						"sanity check: %s -- "+
+						"disconnecting peer", err)
 					hmsg.peer.Disconnect()
 					return
 				}
@@ -2565,7 +2726,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 					Height: int32(backHeight+1) + int32(j),
 				})
 			}
-			log.Tracef("Sane reorg attempted. Total work from "+
+			b.log.Tracef("Sane reorg attempted. Total work from "+
 				"reorg chain: %v", totalWork)
 
 			// All the headers pass sanity checks. Now we calculate
@@ -2584,9 +2745,9 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 					knownHead, _, err = b.cfg.BlockHeaders.FetchHeader(
 						&knownHead.PrevBlock)
 					if err != nil {
-						log.Criticalf("Can't get block"+
-							"header for hash %s: "+
-							"%v",
+						b.log.Criticalf("Can't get "+
+							"blockheader for hash "+
+							"%s: %v",
 							knownHead.PrevBlock,
 							err)
 						// Should we panic here?
@@ -2596,7 +2757,8 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 					blockchain.CalcWork(knownHead.Bits))
 			}
 
-			log.Tracef("Total work from known chain: %v", knownWork)
+			b.log.Tracef("Total work from known chain: %v",
+				knownWork)
 
 			// Compare the two work totals and reject the new chain
 			// if it doesn't have more work than the previously
@@ -2604,7 +2766,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 			// the known chain.
 			switch knownWork.Cmp(totalWork) {
 			case 1:
-				log.Warnf("Reorg attempt that has less work "+
+				b.log.Warnf("Reorg attempt that has less work "+
 					"than known chain from peer %s -- "+
 					"disconnecting", hmsg.peer.Addr())
 				hmsg.peer.Disconnect()
@@ -2634,7 +2796,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 			}
 			err = b.cfg.BlockHeaders.WriteHeaders(hdrs)
 			if err != nil {
-				log.Criticalf("Couldn't write block to "+
+				b.log.Criticalf("Couldn't write block to "+
 					"database: %s", err)
 				// Should we panic here?
 			}
@@ -2654,11 +2816,11 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 			nodeHash := node.Header.BlockHash()
 			if nodeHash.IsEqual(b.nextCheckpoint.Hash) {
 				receivedCheckpoint = true
-				log.Infof("Verified downloaded block "+
+				b.log.Infof("Verified downloaded block "+
 					"header against checkpoint at height "+
 					"%d/hash %s", node.Height, nodeHash)
 			} else {
-				log.Warnf("Block header at height %d/hash "+
+				b.log.Warnf("Block header at height %d/hash "+
 					"%s from peer %s does NOT match "+
 					"expected checkpoint hash of %s -- "+
 					"disconnecting", node.Height,
@@ -2669,16 +2831,16 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 					node.Height,
 				)
 
-				log.Infof("Rolling back to previous validated "+
-					"checkpoint at height %d/hash %s",
-					prevCheckpoint.Height,
+				b.log.Infof("Rolling back to previous "+
+					"validated checkpoint at height "+
+					"%d/hash %s", prevCheckpoint.Height,
 					prevCheckpoint.Hash)
 
 				err := b.rollBackToHeight(uint32(
 					prevCheckpoint.Height),
 				)
 				if err != nil {
-					log.Criticalf("Rollback failed: %s",
+					b.log.Criticalf("Rollback failed: %s",
 						err)
 					// Should we panic here?
 				}
@@ -2690,7 +2852,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 		}
 	}
 
-	log.Tracef("Writing header batch of %v block headers",
+	b.log.Tracef("Writing header batch of %v block headers",
 		len(headerWriteBatch))
 
 	if len(headerWriteBatch) > 0 {
@@ -2699,7 +2861,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 		// is atomic.
 		err := b.cfg.BlockHeaders.WriteHeaders(headerWriteBatch...)
 		if err != nil {
-			log.Errorf("Unable to write block headers: %v", err)
+			b.log.Errorf("Unable to write block headers: %v", err)
 			return
 		}
 	}
@@ -2719,7 +2881,7 @@ func (b *blockManager) handleHeadersMsg(hmsg *headersMsg) {
 		}
 		err := hmsg.peer.PushGetHeadersMsg(locator, &nextHash)
 		if err != nil {
-			log.Warnf("Failed to send getheaders message to "+
+			b.log.Warnf("Failed to send getheaders message to "+
 				"peer %s: %s", hmsg.peer.Addr(), err)
 			return
 		}
@@ -72,16 +72,16 @@ func setupBlockManager(t *testing.T) (*blockManager, headerfs.BlockHeaderStore,
 		require.NoError(t, db.Close())
 	})
 
+	params := assets.BTCParams["simnet"]
+
 	hdrStore, err := headerfs.NewBlockHeaderStore(
-		tempDir, db, &chaincfg.SimNetParams.GenesisBlock.Header,
+		tempDir, db, &params.GenesisBlock.Header,
 	)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("error creating block "+
 			"header store: %s", err)
 	}
 
-	params := assets.BTCParams["simnet"]
-
 	cfStore, err := headerfs.NewFilterHeaderStore(
 		tempDir, db, headerfs.RegularFilter, params,
 		nil,
@@ -143,7 +143,7 @@ func generateHeaders(genesisBlockHeader *wire.BlockHeader,
 	// part of the CFHeaders response, so we also keep track of
 	// them.
 	genesisFilter, err := builder.BuildBasicFilter(
-		chaincfg.SimNetParams.GenesisBlock, nil,
+		assets.BTCParams["simnet"].GenesisBlock, nil,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to build genesis filter: %v",
@@ -530,7 +530,9 @@ func TestBlockManagerInvalidInterval(t *testing.T) {
 
 		// Create a mock peer to prevent panics when attempting to ban
 		// a peer that served an invalid filter header.
-		mockPeer := NewServerPeer(&ChainService{}, false)
+		mockPeer := NewServerPeer(&ChainService{
+			chainParams: assets.BTCParams["simnet"],
+		}, false)
 		mockPeer.Peer, err = peer.NewOutboundPeer(
 			NewPeerConfig(mockPeer), "127.0.0.1:8333",
 		)
@@ -860,6 +862,7 @@ func TestBlockManagerDetectBadPeers(t *testing.T) {
 				BlockHeaders:  blockHeaders,
 				queryAllPeers: queryAllPeers,
 			},
+			log: log,
 		}
 
 		// Now trying to detect which peers are bad, we should detect the
@@ -3,8 +3,7 @@ package chainsync
 import (
 	"testing"
 
-	"github.com/bisoncraft/utxowallet/netparams"
-	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/bisoncraft/utxowallet/assets"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 )
@@ -12,20 +11,22 @@ import (
 func TestControlCFHeader(t *testing.T) {
 	t.Parallel()
 
-	// We'll modify our backing list of checkpoints for this test.
+	// We'll use a copy of the mainnet params with our own checkpoints for
+	// this test.
 	height := uint32(999)
 	header := hashFromStr(
 		"4a242283a406a7c089f671bb8df7671e5d5e9ba577cea1047d30a7f4919df193",
 	)
-	filterHeaderCheckpoints = map[wire.BitcoinNet]map[uint32]*chainhash.Hash{
-		chaincfg.MainNetParams.Net: {
+	params := *assets.BTCParams["mainnet"]
+	params.FilterHeaderCheckpoints = map[wire.FilterType]map[uint32]*chainhash.Hash{
+		wire.GCSFilterRegular: {
 			height: header,
 		},
 	}
 
 	// Expect the control at height to succeed.
 	err := ControlCFHeader(
-		netparams.NMainnetParams, wire.GCSFilterRegular, height, header,
+		&params, wire.GCSFilterRegular, height, header,
 	)
 	if err != nil {
 		t.Fatalf("error checking height: %v", err)
@@ -36,18 +37,42 @@ func TestControlCFHeader(t *testing.T) {
 		"000000000006a7c089f671bb8df7671e5d5e9ba577cea1047d30a7f4919df193",
 	)
 	err = ControlCFHeader(
-		netparams.NMainnetParams, wire.GCSFilterRegular, height, header,
+		&params, wire.GCSFilterRegular, height, header,
 	)
 	if err != ErrCheckpointMismatch {
 		t.Fatalf("expected ErrCheckpointMismatch, got %v", err)
 	}
 
-	// Finally, control an unknown height. This should also pass since we
-	// don't have the checkpoint stored.
+	// Control an unknown height. This should also pass since we don't have
+	// the checkpoint stored.
 	err = ControlCFHeader(
-		netparams.NMainnetParams, wire.GCSFilterRegular, 99, header,
+		&params, wire.GCSFilterRegular, 99, header,
 	)
 	if err != nil {
 		t.Fatalf("error checking height: %v", err)
 	}
+
+	// Checkpoints only apply to their own filter type, so the mismatching
+	// header passes for a filter type without checkpoints.
+	err = ControlCFHeader(&params, wire.FilterType(1), height, header)
+	if err != nil {
+		t.Fatalf("error checking other filter type: %v", err)
+	}
+
+	// Finally, add checkpoints for the other filter type and expect the
+	// header to be controlled against them.
+	params.FilterHeaderCheckpoints[wire.FilterType(1)] = map[uint32]*chainhash.Hash{
+		height: header,
+	}
+	err = ControlCFHeader(&params, wire.FilterType(1), height, header)
+	if err != nil {
+		t.Fatalf("error checking other filter type: %v", err)
+	}
+}
+
+// hashFromStr makes a chainhash.Hash from a valid hex string. If the string is
+// invalid, a nil pointer will be returned.
+func hashFromStr(hexStr string) *chainhash.Hash {
+	hash, _ := chainhash.NewHashFromStr(hexStr)
+	return hash
 }
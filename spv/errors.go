@@ -8,4 +8,8 @@ var (
 
 	// ErrShuttingDown signals that neutrino received a shutdown request.
 	ErrShuttingDown = errors.New("neutrino shutting down")
+
+	// ErrNoFeeEstimate signals that not enough data has been gathered to
+	// estimate a fee rate.
+	ErrNoFeeEstimate = errors.New("no fee estimate available")
 )
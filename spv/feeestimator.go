@@ -0,0 +1,426 @@
+package spv
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bisoncraft/utxowallet/spv/blockntfns"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
+)
+
+const (
+	// feeEstimatorBlocks is the number of most recent blocks the fee
+	// estimator learns from.
+	feeEstimatorBlocks = 12
+
+	// feeEstimatorBootstrapBlocks is the number of blocks preceding the
+	// first connected block that are fetched once the chain is current,
+	// so that estimates are available without waiting for several new
+	// blocks.
+	feeEstimatorBootstrapBlocks = 3
+
+	// minBlockFeeSamples is the minimum number of transactions with a
+	// known fee rate a block must contain for it to be used in estimates.
+	minBlockFeeSamples = 5
+
+	// blockInclusionPercentile is the percentile of the known fee rates of
+	// the transactions mined in a block that is considered to be the fee
+	// rate required for inclusion in that block.
+	blockInclusionPercentile = 0.25
+
+	// feeEstimateConfidence is the probability with which a transaction
+	// paying the estimated fee rate should be mined within the requested
+	// number of blocks.
+	feeEstimateConfidence = 0.85
+)
+
+// FeeEstimatorConfig exposes the methods the fee estimator uses to interact
+// with the blockchain and the connected peers.
+type FeeEstimatorConfig struct {
+	// SubscribeBlocks returns a subscription of block connected and
+	// disconnected notifications.
+	SubscribeBlocks func() (*blockntfns.Subscription, error)
+
+	// GetBlockHash returns the block hash at given height in main chain.
+	GetBlockHash func(height int64) (*chainhash.Hash, error)
+
+	// GetBlock fetches a block from the p2p network.
+	GetBlock func(chainhash.Hash, ...QueryOption) (*btcutil.Block, error)
+
+	// IsCurrent returns whether the chain is synced to the network's tip.
+	// Blocks are only fetched once it is.
+	IsCurrent func() bool
+
+	// FeeFilters returns the minimum fee rates, in satoshis per kilobyte,
+	// of the transactions the connected peers relay, as advertised by
+	// their feefilter messages.
+	FeeFilters func() []int64
+
+	// Logger is the logger the fee estimator writes to. The package logger
+	// is used if nil.
+	Logger btclog.Logger
+}
+
+// blockFeeRate records the fee rate required for inclusion in a block, along
+// with the outputs the block added to the prevout cache.
+type blockFeeRate struct {
+	height uint32
+	hash   chainhash.Hash
+
+	// feeRate is the fee rate, in satoshis per kilobyte, required for
+	// inclusion in the block. It is zero if the block did not contain
+	// enough transactions with a known fee.
+	feeRate btcutil.Amount
+
+	outPoints []wire.OutPoint
+}
+
+// FeeEstimator estimates the fee rate required for a transaction to be mined
+// within a number of blocks. As SPV clients have no mempool to learn from, the
+// estimator fetches recently connected blocks and computes the fee rates of
+// the transactions mined in them. Fees are only known for transactions whose
+// inputs all spend outputs of the blocks fetched, so a cache of their outputs
+// is kept. The fee filters advertised by peers are used as a lower bound, and
+// as the estimate when no block data is available.
+type FeeEstimator struct {
+	started uint32
+	stopped uint32
+
+	cfg *FeeEstimatorConfig
+	log btclog.Logger
+
+	mtx      sync.Mutex
+	blocks   []*blockFeeRate
+	prevOuts map[wire.OutPoint]int64
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// NewFeeEstimator creates a new instance of FeeEstimator using the given
+// configuration.
+func NewFeeEstimator(cfg *FeeEstimatorConfig) *FeeEstimator {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log
+	}
+	return &FeeEstimator{
+		cfg:      cfg,
+		log:      logger,
+		prevOuts: make(map[wire.OutPoint]int64),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start begins learning fee rates from connected blocks.
+func (f *FeeEstimator) Start() error {
+	if !atomic.CompareAndSwapUint32(&f.started, 0, 1) {
+		return nil
+	}
+
+	sub, err := f.cfg.SubscribeBlocks()
+	if err != nil {
+		return err
+	}
+
+	f.wg.Add(1)
+	go f.blockHandler(sub)
+
+	return nil
+}
+
+// Stop stops the fee estimator, waiting for any block being fetched.
+func (f *FeeEstimator) Stop() {
+	if !atomic.CompareAndSwapUint32(&f.stopped, 0, 1) {
+		return
+	}
+
+	close(f.quit)
+	f.wg.Wait()
+}
+
+// blockHandler processes the block notifications of the subscription until
+// the fee estimator is stopped.
+//
+// NOTE: This must be run as a goroutine.
+func (f *FeeEstimator) blockHandler(sub *blockntfns.Subscription) {
+	defer f.wg.Done()
+	defer sub.Cancel()
+
+	for {
+		select {
+		case ntfn, ok := <-sub.Notifications:
+			if !ok {
+				return
+			}
+
+			switch ntfn := ntfn.(type) {
+			case *blockntfns.Connected:
+				header := ntfn.Header()
+				f.handleConnectedBlock(
+					header.BlockHash(), ntfn.Height(),
+				)
+
+			case *blockntfns.Disconnected:
+				f.disconnectBlock(ntfn.Height())
+			}
+
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// handleConnectedBlock fetches and processes a newly connected block. The
+// blocks preceding it are fetched first if no blocks have been processed yet.
+func (f *FeeEstimator) handleConnectedBlock(hash chainhash.Hash,
+	height uint32) {
+
+	if !f.cfg.IsCurrent() {
+		return
+	}
+
+	f.mtx.Lock()
+	bootstrap := len(f.blocks) == 0
+	f.mtx.Unlock()
+
+	if bootstrap && height > feeEstimatorBootstrapBlocks {
+		start := height - feeEstimatorBootstrapBlocks
+		for h := start; h < height; h++ {
+			hash, err := f.cfg.GetBlockHash(int64(h))
+			if err != nil {
+				f.log.Debugf("Unable to get block hash at "+
+					"height %d for fee estimation: %v", h,
+					err)
+				continue
+			}
+			f.fetchBlock(*hash, h)
+		}
+	}
+
+	f.fetchBlock(hash, height)
+}
+
+// fetchBlock fetches and processes the block with the given hash and height.
+func (f *FeeEstimator) fetchBlock(hash chainhash.Hash, height uint32) {
+	select {
+	case <-f.quit:
+		return
+	default:
+	}
+
+	block, err := f.cfg.GetBlock(hash)
+	if err != nil {
+		f.log.Debugf("Unable to fetch block %v for fee estimation: %v",
+			hash, err)
+		return
+	}
+
+	f.processBlock(block, height)
+}
+
+// processBlock computes the fee rate required for inclusion in the block and
+// adds the block to the ones estimates are made from, dropping the oldest
+// block if needed.
+func (f *FeeEstimator) processBlock(block *btcutil.Block, height uint32) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	// Blocks disconnected without a notification being received, such as
+	// while the chain was not current, are replaced.
+	f.removeBlocks(height)
+
+	b := &blockFeeRate{
+		height: height,
+		hash:   *block.Hash(),
+	}
+
+	// Outputs of the block are added to the cache first, as transactions
+	// may spend the outputs of transactions preceding them in the block.
+	for _, tx := range block.Transactions() {
+		for i, txOut := range tx.MsgTx().TxOut {
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			f.prevOuts[op] = txOut.Value
+			b.outPoints = append(b.outPoints, op)
+		}
+	}
+
+	var feeRates []btcutil.Amount
+	for i, tx := range block.Transactions() {
+		// The coinbase transaction pays no fee.
+		if i == 0 {
+			continue
+		}
+		msgTx := tx.MsgTx()
+
+		var totalIn int64
+		known := true
+		for _, txIn := range msgTx.TxIn {
+			value, ok := f.prevOuts[txIn.PreviousOutPoint]
+			if !ok {
+				known = false
+				continue
+			}
+			totalIn += value
+			delete(f.prevOuts, txIn.PreviousOutPoint)
+		}
+		if !known {
+			continue
+		}
+
+		var totalOut int64
+		for _, txOut := range msgTx.TxOut {
+			totalOut += txOut.Value
+		}
+
+		weight := blockchain.GetTransactionWeight(tx)
+		vsize := (weight + blockchain.WitnessScaleFactor - 1) /
+			blockchain.WitnessScaleFactor
+		fee := totalIn - totalOut
+		if fee < 0 || vsize == 0 {
+			continue
+		}
+		feeRates = append(feeRates, btcutil.Amount(fee*1000/vsize))
+	}
+
+	if len(feeRates) >= minBlockFeeSamples {
+		sortAmounts(feeRates)
+		b.feeRate = percentile(feeRates, blockInclusionPercentile)
+	}
+
+	f.log.Debugf("Fee estimator processed block %v (height %d): %d "+
+		"transactions with known fees, inclusion fee rate %v/kvB",
+		b.hash, height, len(feeRates), b.feeRate)
+
+	f.blocks = append(f.blocks, b)
+	if len(f.blocks) > feeEstimatorBlocks {
+		f.dropBlock(f.blocks[0])
+		f.blocks = f.blocks[1:]
+	}
+}
+
+// disconnectBlock removes the block at the given height, and any block
+// above it, from the ones estimates are made from.
+func (f *FeeEstimator) disconnectBlock(height uint32) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.removeBlocks(height)
+}
+
+// removeBlocks removes the blocks at or above the given height.
+//
+// NOTE: The mutex must be held.
+func (f *FeeEstimator) removeBlocks(height uint32) {
+	for len(f.blocks) != 0 {
+		last := f.blocks[len(f.blocks)-1]
+		if last.height < height {
+			return
+		}
+		f.dropBlock(last)
+		f.blocks = f.blocks[:len(f.blocks)-1]
+	}
+}
+
+// dropBlock removes the outputs the block added to the prevout cache.
+//
+// NOTE: The mutex must be held.
+func (f *FeeEstimator) dropBlock(b *blockFeeRate) {
+	for _, op := range b.outPoints {
+		delete(f.prevOuts, op)
+	}
+}
+
+// EstimateFee returns the fee rate, in satoshis per kilobyte, a transaction
+// should pay to be mined within confTarget blocks. The estimate is at least
+// the median fee filter of the connected peers. ErrNoFeeEstimate is returned
+// if no recent block had enough transactions with a known fee and no peer
+// advertised a fee filter.
+func (f *FeeEstimator) EstimateFee(confTarget uint32) (btcutil.Amount, error) {
+	if confTarget == 0 {
+		confTarget = 1
+	}
+
+	f.mtx.Lock()
+	var feeRates []btcutil.Amount
+	for _, b := range f.blocks {
+		if b.feeRate > 0 {
+			feeRates = append(feeRates, b.feeRate)
+		}
+	}
+	f.mtx.Unlock()
+
+	var feeFilters []btcutil.Amount
+	for _, feeFilter := range f.cfg.FeeFilters() {
+		if feeFilter > 0 {
+			feeFilters = append(feeFilters, btcutil.Amount(feeFilter))
+		}
+	}
+
+	var minFeeRate btcutil.Amount
+	if len(feeFilters) != 0 {
+		sortAmounts(feeFilters)
+		minFeeRate = percentile(feeFilters, 0.5)
+	}
+
+	if len(feeRates) == 0 {
+		if minFeeRate == 0 {
+			return 0, ErrNoFeeEstimate
+		}
+		return minFeeRate, nil
+	}
+
+	// A fee rate is sufficient for inclusion in a block if it's at least
+	// the block's inclusion fee rate. To be mined within confTarget
+	// blocks with the desired confidence, the fee rate must be sufficient
+	// for a share p of the blocks, where 1-(1-p)^confTarget is the
+	// confidence.
+	p := 1 - math.Pow(1-feeEstimateConfidence, 1/float64(confTarget))
+	sortAmounts(feeRates)
+	feeRate := percentile(feeRates, p)
+	if feeRate < minFeeRate {
+		feeRate = minFeeRate
+	}
+
+	return feeRate, nil
+}
+
+// sortAmounts sorts the amounts in increasing order.
+func sortAmounts(amounts []btcutil.Amount) {
+	sort.Slice(amounts, func(i, j int) bool {
+		return amounts[i] < amounts[j]
+	})
+}
+
+// percentile returns the amount at percentile p, in the range [0, 1], of the
+// amounts, which must be sorted in increasing order.
+func percentile(amounts []btcutil.Amount, p float64) btcutil.Amount {
+	i := int(math.Ceil(p*float64(len(amounts)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return amounts[i]
+}
+
+// EstimateFee returns the fee rate, in satoshis per kilobyte, a transaction
+// should pay to be mined within confTarget blocks, as estimated from recently
+// connected blocks and the fee filters of the connected peers.
+func (s *ChainService) EstimateFee(confTarget uint32) (btcutil.Amount, error) {
+	return s.feeEstimator.EstimateFee(confTarget)
+}
+
+// feeFilters returns the fee filters advertised by the connected peers.
+func (s *ChainService) feeFilters() []int64 {
+	peers := s.Peers()
+	feeFilters := make([]int64, 0, len(peers))
+	for _, sp := range peers {
+		feeFilters = append(feeFilters, atomic.LoadInt64(&sp.feeFilter))
+	}
+	return feeFilters
+}
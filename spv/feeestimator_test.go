@@ -0,0 +1,127 @@
+package spv
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// feeTestBlock returns a block made up of a coinbase transaction paying the
+// outputs provided, followed by the transactions provided.
+func feeTestBlock(height uint32, outputs int,
+	txs ...*wire.MsgTx) *btcutil.Block {
+
+	coinbase := &wire.MsgTx{
+		Version: wire.TxVersion,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+			SignatureScript:  []byte{byte(height), 0},
+		}},
+	}
+	for i := 0; i < outputs; i++ {
+		coinbase.AddTxOut(wire.NewTxOut(100000, []byte{0x51}))
+	}
+
+	msgBlock := &wire.MsgBlock{
+		Header:       wire.BlockHeader{Nonce: height},
+		Transactions: append([]*wire.MsgTx{coinbase}, txs...),
+	}
+	return btcutil.NewBlock(msgBlock)
+}
+
+// feeTestTx returns a transaction spending the outpoint provided, which must
+// have a value of 100000, paying the fee provided. The fee rate paid is
+// returned along with the transaction.
+func feeTestTx(op wire.OutPoint, fee int64) (*wire.MsgTx, btcutil.Amount) {
+	tx := &wire.MsgTx{
+		Version: wire.TxVersion,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: op,
+			SignatureScript:  make([]byte, 100),
+		}},
+		TxOut: []*wire.TxOut{wire.NewTxOut(100000-fee, []byte{0x51})},
+	}
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	vsize := (weight + blockchain.WitnessScaleFactor - 1) /
+		blockchain.WitnessScaleFactor
+	return tx, btcutil.Amount(fee * 1000 / vsize)
+}
+
+// TestFeeEstimator tests that fee rates are estimated from the fees of
+// transactions mined in processed blocks, bounded by the fee filters of peers.
+func TestFeeEstimator(t *testing.T) {
+	t.Parallel()
+
+	var feeFilters []int64
+	f := NewFeeEstimator(&FeeEstimatorConfig{
+		FeeFilters: func() []int64 { return feeFilters },
+	})
+
+	// Without blocks and fee filters, no estimate is available.
+	_, err := f.EstimateFee(1)
+	require.ErrorIs(t, err, ErrNoFeeEstimate)
+
+	// The median fee filter is used without block data.
+	feeFilters = []int64{3000, 1000, 0, 2000}
+	feeRate, err := f.EstimateFee(6)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(2000), feeRate)
+
+	// A block with only a coinbase has no fee rate.
+	const numTxs = 10
+	prevBlock := feeTestBlock(100, numTxs)
+	f.processBlock(prevBlock, 100)
+	feeRate, err = f.EstimateFee(6)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(2000), feeRate)
+
+	// Spend the outputs of the previous block, paying increasing fees,
+	// along with a transaction spending an unknown output.
+	coinbaseHash := prevBlock.Transactions()[0].Hash()
+	var (
+		txs      []*wire.MsgTx
+		feeRates []btcutil.Amount
+	)
+	for i := 0; i < numTxs; i++ {
+		op := wire.OutPoint{Hash: *coinbaseHash, Index: uint32(i)}
+		tx, txFeeRate := feeTestTx(op, int64(i+1)*5000)
+		txs = append(txs, tx)
+		feeRates = append(feeRates, txFeeRate)
+	}
+	unknown, _ := feeTestTx(wire.OutPoint{Hash: chainhash.Hash{1}}, 100)
+	txs = append(txs, unknown)
+	f.processBlock(feeTestBlock(101, 0, txs...), 101)
+
+	// The inclusion fee rate is the 25th percentile of the known fee
+	// rates.
+	inclusionFeeRate := feeRates[2]
+	require.Greater(t, inclusionFeeRate, btcutil.Amount(2000))
+	for _, confTarget := range []uint32{0, 1, 6} {
+		feeRate, err = f.EstimateFee(confTarget)
+		require.NoError(t, err)
+		require.Equal(t, inclusionFeeRate, feeRate)
+	}
+
+	// The fee filters are a lower bound.
+	feeFilters = []int64{1e6}
+	feeRate, err = f.EstimateFee(1)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(1e6), feeRate)
+	feeFilters = nil
+
+	// Disconnecting the block removes its fee rate.
+	f.disconnectBlock(101)
+	_, err = f.EstimateFee(1)
+	require.ErrorIs(t, err, ErrNoFeeEstimate)
+
+	// Only the most recent blocks are kept, along with their outputs.
+	for height := uint32(102); height < 102+2*feeEstimatorBlocks; height++ {
+		f.processBlock(feeTestBlock(height, 1), height)
+	}
+	require.Len(t, f.blocks, feeEstimatorBlocks)
+	require.Len(t, f.prevOuts, feeEstimatorBlocks)
+}
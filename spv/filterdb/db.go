@@ -6,8 +6,8 @@ import (
 	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/bisoncraft/utxowallet/walletdb"
 	"github.com/btcsuite/btcd/btcutil/gcs"
-	"github.com/btcsuite/btcd/btcutil/gcs/builder"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 )
 
 var (
@@ -25,13 +25,15 @@ var (
 )
 
 // FilterType is an enum-like type that represents the various filter types
-// currently defined.
+// currently defined. The values are the BIP-0157 filter types used on the
+// wire, and filter types other than RegularFilter must be registered with
+// RegisterFilterType before they are used.
 type FilterType uint8
 
 const (
 	// RegularFilter is the filter type of regular filters which contain
 	// outputs and pkScript data pushes.
-	RegularFilter FilterType = iota
+	RegularFilter = FilterType(wire.GCSFilterRegular)
 )
 
 // FilterData holds all the info about a filter required to store it.
@@ -78,42 +80,62 @@ type FilterStore struct {
 var _ FilterDatabase = (*FilterStore)(nil)
 
 // New creates a new instance of the FilterStore given an already open
-// database, and the target chain parameters.
+// database, and the target chain parameters. Storage is initialized for every
+// filter type registered at the time of the call, including filter types
+// registered after the database was created.
 func New(db walletdb.DB, params *netparams.ChainParams) (*FilterStore, error) {
 	err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
 		// As part of our initial setup, we'll try to create the top
-		// level filter bucket. If this already exists, then we can
-		// exit early.
-		filters, err := tx.CreateTopLevelBucket(filterBucket)
-		if err != nil {
-			return err
+		// level filter bucket, if it doesn't already exist.
+		filters := tx.ReadWriteBucket(filterBucket)
+		if filters == nil {
+			var err error
+			filters, err = tx.CreateTopLevelBucket(filterBucket)
+			if err != nil {
+				return err
+			}
 		}
 
-		// If the main bucket doesn't already exist, then we'll need to
-		// create the sub-buckets, and also initialize them with the
-		// genesis filters.
-
-		// (*chaincfg.Params)(nil).GenesisHash
-
 		genesisBlock := params.GenesisBlock
 		genesisHash := params.GenesisHash
 
-		// First we'll create the bucket for the regular filters.
-		regFilters, err := filters.CreateBucketIfNotExists(regBucket)
-		if err != nil {
-			return err
-		}
+		for _, fType := range FilterTypes() {
+			fParams, _ := LookupFilterType(fType)
 
-		// With the bucket created, we'll now construct the initial
-		// basic genesis filter and store it within the database.
-		basicFilter, err := builder.BuildBasicFilter(genesisBlock, nil)
-		if err != nil {
-			return err
+			// Filter types which already have a bucket have been
+			// initialized before.
+			bucketName := []byte(fParams.Name)
+			if filters.NestedReadWriteBucket(bucketName) != nil {
+				continue
+			}
+
+			// First we'll create the bucket for the filter type.
+			bucket, err := filters.CreateBucketIfNotExists(
+				bucketName,
+			)
+			if err != nil {
+				return err
+			}
+
+			// With the bucket created, we'll now construct the
+			// initial genesis filter and store it within the
+			// database.
+			genesisFilter, err := fParams.BuildFilter(
+				genesisBlock, nil,
+			)
+			if err != nil {
+				return err
+			}
+
+			err = putFilter(bucket, genesisHash, genesisFilter)
+			if err != nil {
+				return err
+			}
 		}
 
-		return putFilter(regFilters, genesisHash, basicFilter)
+		return nil
 	})
-	if err != nil && err != walletdb.ErrBucketExists {
+	if err != nil {
 		return nil, err
 	}
 
@@ -126,25 +148,22 @@ func New(db walletdb.DB, params *netparams.ChainParams) (*FilterStore, error) {
 //
 // NOTE: This method is a part of the FilterDatabase interface.
 func (f *FilterStore) PurgeFilters(fType FilterType) error {
+	fParams, err := filterParams(fType)
+	if err != nil {
+		return err
+	}
+	bucketName := []byte(fParams.Name)
+
 	return walletdb.Update(f.db, func(tx walletdb.ReadWriteTx) error {
 		filters := tx.ReadWriteBucket(filterBucket)
 
-		switch fType {
-		case RegularFilter:
-			err := filters.DeleteNestedBucket(regBucket)
-			if err != nil {
-				return err
-			}
-
-			_, err = filters.CreateBucket(regBucket)
-			if err != nil {
-				return err
-			}
-		default:
-			return fmt.Errorf("unknown filter type: %v", fType)
+		err := filters.DeleteNestedBucket(bucketName)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		_, err = filters.CreateBucket(bucketName)
+		return err
 	})
 }
 
@@ -173,21 +192,25 @@ func (f *FilterStore) PutFilters(filterList ...*FilterData) error {
 	var updateErr error
 	err := walletdb.Batch(f.db, func(tx walletdb.ReadWriteTx) error {
 		filters := tx.ReadWriteBucket(filterBucket)
-		regularFilterBkt := filters.NestedReadWriteBucket(regBucket)
 
 		for _, filterData := range filterList {
-			var targetBucket walletdb.ReadWriteBucket
-			switch filterData.Type {
-			case RegularFilter:
-				targetBucket = regularFilterBkt
-			default:
-				updateErr = fmt.Errorf("unknown filter "+
+			fParams, err := filterParams(filterData.Type)
+			if err != nil {
+				updateErr = err
+				return nil
+			}
+
+			targetBucket := filters.NestedReadWriteBucket(
+				[]byte(fParams.Name),
+			)
+			if targetBucket == nil {
+				updateErr = fmt.Errorf("no storage for filter "+
 					"type: %v", filterData.Type)
 
 				return nil
 			}
 
-			err := putFilter(
+			err = putFilter(
 				targetBucket, filterData.BlockHash,
 				filterData.Filter,
 			)
@@ -215,17 +238,19 @@ func (f *FilterStore) PutFilters(filterList ...*FilterData) error {
 func (f *FilterStore) FetchFilter(blockHash *chainhash.Hash,
 	filterType FilterType) (*gcs.Filter, error) {
 
+	fParams, err := filterParams(filterType)
+	if err != nil {
+		return nil, err
+	}
+
 	var filter *gcs.Filter
 
-	err := walletdb.View(f.db, func(tx walletdb.ReadTx) error {
+	err = walletdb.View(f.db, func(tx walletdb.ReadTx) error {
 		filters := tx.ReadBucket(filterBucket)
 
-		var targetBucket walletdb.ReadBucket
-		switch filterType {
-		case RegularFilter:
-			targetBucket = filters.NestedReadBucket(regBucket)
-		default:
-			return fmt.Errorf("unknown filter type")
+		targetBucket := filters.NestedReadBucket([]byte(fParams.Name))
+		if targetBucket == nil {
+			return ErrFilterNotFound
 		}
 
 		filterBytes := targetBucket.Get(blockHash[:])
@@ -237,7 +262,7 @@ func (f *FilterStore) FetchFilter(blockHash *chainhash.Hash,
 		}
 
 		dbFilter, err := gcs.FromNBytes(
-			builder.DefaultP, builder.DefaultM, filterBytes,
+			fParams.P, fParams.M, filterBytes,
 		)
 		if err != nil {
 			return err
@@ -5,14 +5,12 @@ import (
 	"testing"
 	"time"
 
-	"github.com/bisoncraft/utxowallet/netparams"
+	"github.com/bisoncraft/utxowallet/assets"
 	"github.com/bisoncraft/utxowallet/walletdb"
 	_ "github.com/bisoncraft/utxowallet/walletdb/bdb"
 	"github.com/btcsuite/btcd/btcutil/gcs"
 	"github.com/btcsuite/btcd/btcutil/gcs/builder"
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/wire"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,10 +25,7 @@ func createTestDatabase(t *testing.T) FilterDatabase {
 		require.NoError(t, db.Close())
 	})
 
-	filterDB, err := New(db, &netparams.ChainParams{
-		GenesisBlock: &wire.MsgBlock{},
-		GenesisHash:  &chainhash.Hash{},
-	})
+	filterDB, err := New(db, assets.BTCParams["simnet"])
 	require.NoError(t, err)
 
 	return filterDB
@@ -40,7 +35,7 @@ func createTestDatabase(t *testing.T) FilterDatabase {
 func TestGenesisFilterCreation(t *testing.T) {
 	var (
 		database    = createTestDatabase(t)
-		genesisHash = chaincfg.SimNetParams.GenesisHash
+		genesisHash = assets.BTCParams["simnet"].GenesisHash
 	)
 
 	// With the database initialized, we should be able to fetch the
@@ -55,6 +50,18 @@ func TestGenesisFilterCreation(t *testing.T) {
 	require.NotNil(t, regGenesisFilter)
 }
 
+// registerTestFilterType registers a filter type for the duration of the test.
+func registerTestFilterType(t *testing.T, fType FilterType,
+	params *FilterParams) {
+
+	require.NoError(t, RegisterFilterType(fType, params))
+	t.Cleanup(func() {
+		filterTypesMtx.Lock()
+		delete(filterTypes, fType)
+		filterTypesMtx.Unlock()
+	})
+}
+
 func genRandFilter(t *testing.T, numElements uint32) *gcs.Filter {
 	elements := make([][]byte, numElements)
 	for i := uint32(0); i < numElements; i++ {
@@ -106,3 +113,130 @@ func TestFilterStorage(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, regFilter, regFilterDB)
 }
+
+// TestFilterTypes tests that filters of registered filter types are stored
+// separately from regular filters, using their own coding parameters.
+func TestFilterTypes(t *testing.T) {
+	const fType = FilterType(0x80)
+
+	// Filters of unregistered types can't be stored or fetched.
+	database := createTestDatabase(t)
+	var randHash chainhash.Hash
+	_, err := rand.Read(randHash[:])
+	require.NoError(t, err)
+	err = database.PutFilters(&FilterData{
+		Filter:    genRandFilter(t, 10),
+		BlockHash: &randHash,
+		Type:      fType,
+	})
+	require.Error(t, err)
+	_, err = database.FetchFilter(&randHash, fType)
+	require.Error(t, err)
+
+	// Register a filter type using a lower false positive rate than the
+	// regular filters.
+	params := &FilterParams{
+		Name:        "test",
+		P:           builder.DefaultP + 1,
+		M:           builder.DefaultM * 2,
+		BuildFilter: builder.BuildBasicFilter,
+	}
+	registerTestFilterType(t, fType, params)
+	require.Error(t, RegisterFilterType(fType, params))
+	require.Error(t, RegisterFilterType(fType+1, params))
+	require.Contains(t, FilterTypes(), fType)
+
+	// A new filter store has the genesis filter of the new type.
+	database = createTestDatabase(t)
+	genesisHash := assets.BTCParams["simnet"].GenesisHash
+	genesisFilter, err := database.FetchFilter(genesisHash, fType)
+	require.NoError(t, err)
+	require.NotNil(t, genesisFilter)
+
+	// Filters of the new type don't overwrite regular filters of the same
+	// block.
+	regFilter := genRandFilter(t, 100)
+	elements := [][]byte{randHash[:]}
+	var key [16]byte
+	filter, err := gcs.BuildGCSFilter(params.P, params.M, key, elements)
+	require.NoError(t, err)
+
+	err = database.PutFilters(&FilterData{
+		Filter:    regFilter,
+		BlockHash: &randHash,
+		Type:      RegularFilter,
+	}, &FilterData{
+		Filter:    filter,
+		BlockHash: &randHash,
+		Type:      fType,
+	})
+	require.NoError(t, err)
+
+	regFilterDB, err := database.FetchFilter(&randHash, RegularFilter)
+	require.NoError(t, err)
+	require.Equal(t, regFilter, regFilterDB)
+	filterDB, err := database.FetchFilter(&randHash, fType)
+	require.NoError(t, err)
+	require.Equal(t, filter, filterDB)
+
+	// Purging the filters of the new type leaves the regular filters.
+	require.NoError(t, database.PurgeFilters(fType))
+	_, err = database.FetchFilter(&randHash, fType)
+	require.ErrorIs(t, err, ErrFilterNotFound)
+	_, err = database.FetchFilter(&randHash, RegularFilter)
+	require.NoError(t, err)
+}
+
+// TestFilterTypeUpgrade tests that opening an existing filter store
+// initializes the storage of filter types registered after it was created.
+func TestFilterTypeUpgrade(t *testing.T) {
+	const fType = FilterType(0x81)
+
+	dbPath := t.TempDir() + "/test.db"
+	db, err := walletdb.Create("bdb", dbPath, true, time.Second*10)
+	require.NoError(t, err)
+	_, err = New(db, assets.BTCParams["simnet"])
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	registerTestFilterType(t, fType, &FilterParams{
+		Name:        "upgrade",
+		P:           builder.DefaultP,
+		M:           builder.DefaultM,
+		BuildFilter: builder.BuildBasicFilter,
+	})
+
+	db, err = walletdb.Open("bdb", dbPath, true, time.Second*10)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+	database, err := New(db, assets.BTCParams["simnet"])
+	require.NoError(t, err)
+
+	genesisHash := assets.BTCParams["simnet"].GenesisHash
+	genesisFilter, err := database.FetchFilter(genesisHash, fType)
+	require.NoError(t, err)
+	require.NotNil(t, genesisFilter)
+
+	var randHash chainhash.Hash
+	_, err = rand.Read(randHash[:])
+	require.NoError(t, err)
+	filter := genRandFilter(t, 10)
+	err = database.PutFilters(&FilterData{
+		Filter:    filter,
+		BlockHash: &randHash,
+		Type:      fType,
+	})
+	require.NoError(t, err)
+	filterDB, err := database.FetchFilter(&randHash, fType)
+	require.NoError(t, err)
+	require.Equal(t, filter, filterDB)
+
+	// Opening the store again keeps the stored filters.
+	_, err = New(db, assets.BTCParams["simnet"])
+	require.NoError(t, err)
+	filterDB, err = database.FetchFilter(&randHash, fType)
+	require.NoError(t, err)
+	require.Equal(t, filter, filterDB)
+}
@@ -0,0 +1,99 @@
+package filterdb
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcutil/gcs"
+	"github.com/btcsuite/btcd/btcutil/gcs/builder"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// FilterParams describes how the filters of a filter type are built and
+// encoded.
+type FilterParams struct {
+	// Name identifies the filter type in persistent storage. It names the
+	// bucket that stores the filters and the chain tip of the filter
+	// headers, so it must never change once filters have been stored.
+	Name string
+
+	// P and M are the Golomb-Rice coding parameters of the filters.
+	P uint8
+	M uint64
+
+	// BuildFilter builds the filter for a block. The prevOutScripts are
+	// the scripts of the outputs spent by the block, and may be nil if
+	// the block spends no outputs, as is the case for the genesis block.
+	BuildFilter func(block *wire.MsgBlock,
+		prevOutScripts [][]byte) (*gcs.Filter, error)
+}
+
+var (
+	filterTypesMtx sync.RWMutex
+	filterTypes    = map[FilterType]*FilterParams{
+		RegularFilter: {
+			Name:        string(regBucket),
+			P:           builder.DefaultP,
+			M:           builder.DefaultM,
+			BuildFilter: builder.BuildBasicFilter,
+		},
+	}
+)
+
+// RegisterFilterType registers a filter type so that its filters and filter
+// headers can be stored and verified. The filter type is the BIP-0157 filter
+// type peers use on the wire. RegisterFilterType is typically called from an
+// init function, and registering a filter type or name that is already
+// registered is an error.
+func RegisterFilterType(fType FilterType, params *FilterParams) error {
+	if params == nil || params.Name == "" || params.BuildFilter == nil {
+		return fmt.Errorf("incomplete parameters for filter type %v",
+			fType)
+	}
+
+	filterTypesMtx.Lock()
+	defer filterTypesMtx.Unlock()
+	for t, p := range filterTypes {
+		if t == fType {
+			return fmt.Errorf("filter type %v is already "+
+				"registered", fType)
+		}
+		if p.Name == params.Name {
+			return fmt.Errorf("filter type name %q is already "+
+				"registered", params.Name)
+		}
+	}
+	filterTypes[fType] = params
+	return nil
+}
+
+// LookupFilterType returns the parameters of a registered filter type.
+func LookupFilterType(fType FilterType) (*FilterParams, bool) {
+	filterTypesMtx.RLock()
+	defer filterTypesMtx.RUnlock()
+	params, found := filterTypes[fType]
+	return params, found
+}
+
+// FilterTypes returns the registered filter types, sorted.
+func FilterTypes() []FilterType {
+	filterTypesMtx.RLock()
+	defer filterTypesMtx.RUnlock()
+	fTypes := make([]FilterType, 0, len(filterTypes))
+	for fType := range filterTypes {
+		fTypes = append(fTypes, fType)
+	}
+	sort.Slice(fTypes, func(i, j int) bool { return fTypes[i] < fTypes[j] })
+	return fTypes
+}
+
+// filterParams returns the parameters of a registered filter type, or an error
+// if the filter type is unknown.
+func filterParams(fType FilterType) (*FilterParams, error) {
+	params, found := LookupFilterType(fType)
+	if !found {
+		return nil, fmt.Errorf("unknown filter type: %v", fType)
+	}
+	return params, nil
+}
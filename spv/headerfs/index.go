@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/bisoncraft/utxowallet/spv/filterdb"
 	"github.com/bisoncraft/utxowallet/walletdb"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 )
 
 var (
@@ -52,6 +54,61 @@ const (
 	RegularFilter
 )
 
+// FilterHeaderType returns the header type of the filter header chain for a
+// filter type. The filter type must be registered with the filterdb package.
+func FilterHeaderType(fType wire.FilterType) HeaderType {
+	return RegularFilter + HeaderType(fType)
+}
+
+// filterParams returns the parameters of the filter type of a filter header
+// type.
+func (h HeaderType) filterParams() (*filterdb.FilterParams, error) {
+	if h < RegularFilter {
+		return nil, fmt.Errorf("not a filter header type: %v", h)
+	}
+	fType := filterdb.FilterType(h - RegularFilter)
+	params, ok := filterdb.LookupFilterType(fType)
+	if !ok {
+		return nil, fmt.Errorf("unknown filter type: %v", fType)
+	}
+	return params, nil
+}
+
+// headerSize returns the size in bytes of the headers of the type.
+func (h HeaderType) headerSize() (int64, error) {
+	if h == Block {
+		return BlockHeaderSize, nil
+	}
+	if _, err := h.filterParams(); err != nil {
+		return 0, err
+	}
+	return RegularFilterHeaderSize, nil
+}
+
+// tipKey returns the key which tracks the tip of the header chain of the type
+// within the index.
+func (h HeaderType) tipKey() ([]byte, error) {
+	switch h {
+	case Block:
+		return bitcoinTip, nil
+	case RegularFilter:
+		return regFilterTip, nil
+	}
+
+	params, err := h.filterParams()
+	if err != nil {
+		return nil, err
+	}
+
+	// The tip key of other filter types is the name of the filter type,
+	// which must not collide with the block header tip.
+	if params.Name == string(bitcoinTip) {
+		return nil, fmt.Errorf("invalid filter type name %q",
+			params.Name)
+	}
+	return []byte(params.Name), nil
+}
+
 const (
 	// BlockHeaderSize is the size in bytes of the Block header type.
 	BlockHeaderSize = 80
@@ -88,6 +145,10 @@ type headerIndex struct {
 // newHeaderIndex creates a new headerIndex given an already open database, and
 // a particular header type.
 func newHeaderIndex(db walletdb.DB, indexType HeaderType) (*headerIndex, error) {
+	if _, err := indexType.tipKey(); err != nil {
+		return nil, err
+	}
+
 	// As an initially step, we'll attempt to create all the buckets
 	// necessary for functioning of the index. If these buckets has already
 	// been created, then we can exit early.
@@ -155,20 +216,14 @@ func (h *headerIndex) addHeaders(batch headerBatch) error {
 	return walletdb.Update(h.db, func(tx walletdb.ReadWriteTx) error {
 		rootBucket := tx.ReadWriteBucket(indexBucket)
 
-		var tipKey []byte
-
 		// Based on the specified index type of this instance of the
 		// index, we'll grab the key that tracks the tip of the chain
 		// so we can update the index once all the header entries have
 		// been updated.
 		// TODO(roasbeef): only need block tip?
-		switch h.indexType {
-		case Block:
-			tipKey = bitcoinTip
-		case RegularFilter:
-			tipKey = regFilterTip
-		default:
-			return fmt.Errorf("unknown index type: %v", h.indexType)
+		tipKey, err := h.indexType.tipKey()
+		if err != nil {
+			return err
 		}
 
 		var (
@@ -248,18 +303,11 @@ func (h *headerIndex) chainTipWithTx(tx walletdb.ReadTx) (*chainhash.Hash,
 
 	rootBucket := tx.ReadBucket(indexBucket)
 
-	var tipKey []byte
-
 	// Based on the specified index type of this instance of the index,
 	// we'll grab the particular key that tracks the chain tip.
-	switch h.indexType {
-	case Block:
-		tipKey = bitcoinTip
-	case RegularFilter:
-		tipKey = regFilterTip
-	default:
-		return nil, 0, fmt.Errorf("unknown chain tip index type: %v",
-			h.indexType)
+	tipKey, err := h.indexType.tipKey()
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Now that we have the particular tip key for this header type, we'll
@@ -289,18 +337,12 @@ func (h *headerIndex) truncateIndex(newTip *chainhash.Hash, remove bool) error {
 	return walletdb.Update(h.db, func(tx walletdb.ReadWriteTx) error {
 		rootBucket := tx.ReadWriteBucket(indexBucket)
 
-		var tipKey []byte
-
 		// Based on the specified index type of this instance of the
 		// index, we'll grab the key that tracks the tip of the chain
 		// we need to update.
-		switch h.indexType {
-		case Block:
-			tipKey = bitcoinTip
-		case RegularFilter:
-			tipKey = regFilterTip
-		default:
-			return fmt.Errorf("unknown index type: %v", h.indexType)
+		tipKey, err := h.indexType.tipKey()
+		if err != nil {
+			return err
 		}
 
 		// If the remove flag is set, then we'll also delete this entry
@@ -114,7 +114,11 @@ func newHeaderStore(db walletdb.DB, filePath string,
 	case RegularFilter:
 		flatFileName = "reg_filter_headers.bin"
 	default:
-		return nil, fmt.Errorf("unrecognized filter type: %v", hType)
+		params, err := hType.filterParams()
+		if err != nil {
+			return nil, err
+		}
+		flatFileName = params.Name + "_filter_headers.bin"
 	}
 
 	flatFileName = filepath.Join(filePath, flatFileName)
@@ -634,26 +638,24 @@ func NewFilterHeaderStore(filePath string, db walletdb.DB,
 	// If the size of the file is zero, then this means that we haven't yet
 	// written the initial genesis header to disk, so we'll do so now.
 	if fileInfo.Size() == 0 {
-		var genesisFilterHash chainhash.Hash
-		switch filterType {
-		case RegularFilter:
-			basicFilter, err := builder.BuildBasicFilter(
-				netParams.GenesisBlock, nil,
-			)
-			if err != nil {
-				return nil, err
-			}
+		params, err := filterType.filterParams()
+		if err != nil {
+			return nil, err
+		}
 
-			genesisFilterHash, err = builder.MakeHeaderForFilter(
-				basicFilter,
-				netParams.GenesisBlock.Header.PrevBlock,
-			)
-			if err != nil {
-				return nil, err
-			}
+		genesisFilter, err := params.BuildFilter(
+			netParams.GenesisBlock, nil,
+		)
+		if err != nil {
+			return nil, err
+		}
 
-		default:
-			return nil, fmt.Errorf("unknown filter type: %v", filterType)
+		genesisFilterHash, err := builder.MakeHeaderForFilter(
+			genesisFilter,
+			netParams.GenesisBlock.Header.PrevBlock,
+		)
+		if err != nil {
+			return nil, err
 		}
 
 		genesisHeader := FilterHeader{
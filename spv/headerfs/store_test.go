@@ -11,8 +11,11 @@ import (
 	"testing"
 	"time"
 
-	"github.com/bisoncraft/utxowallet/netparams"
+	"github.com/bisoncraft/utxowallet/assets"
+	"github.com/bisoncraft/utxowallet/spv/filterdb"
 	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcutil/gcs"
+	"github.com/btcsuite/btcd/btcutil/gcs/builder"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -239,7 +242,7 @@ func createTestFilterHeaderStore() (func(), walletdb.DB, string, *FilterHeaderSt
 	}
 
 	hStore, err := NewFilterHeaderStore(
-		tempDir, db, RegularFilter, netparams.NSimnetParams, nil,
+		tempDir, db, RegularFilter, assets.BTCParams["simnet"], nil,
 	)
 	if err != nil {
 		return nil, nil, "", nil, err
@@ -433,7 +436,7 @@ func TestFilterHeaderStoreRecovery(t *testing.T) {
 	// Next, we'll re-create the block header store in order to trigger the
 	// recovery logic.
 	fhs, err = NewFilterHeaderStore(
-		tempDir, db, RegularFilter, netparams.NSimnetParams, nil,
+		tempDir, db, RegularFilter, assets.BTCParams["simnet"], nil,
 	)
 	if err != nil {
 		t.Fatalf("unable to re-create bhs: %v", err)
@@ -455,6 +458,122 @@ func TestFilterHeaderStoreRecovery(t *testing.T) {
 	}
 }
 
+// TestFilterHeaderStoreFilterTypes tests that the filter headers of an
+// additional filter type are stored separately from the regular filter
+// headers, starting from their own genesis filter header.
+func TestFilterHeaderStoreFilterTypes(t *testing.T) {
+	cleanUp, db, tempDir, regStore, err := createTestFilterHeaderStore()
+	if cleanUp != nil {
+		defer cleanUp()
+	}
+	if err != nil {
+		t.Fatalf("unable to create new filter header store: %v", err)
+	}
+	params := assets.BTCParams["simnet"]
+
+	// A store can't be created for a filter type that isn't registered.
+	const fType = wire.FilterType(0x80)
+	_, err = NewFilterHeaderStore(
+		tempDir, db, FilterHeaderType(fType), params, nil,
+	)
+	if err == nil {
+		t.Fatalf("created store for unregistered filter type")
+	}
+
+	// Register a filter type which only commits to the block hash.
+	err = filterdb.RegisterFilterType(filterdb.FilterType(fType),
+		&filterdb.FilterParams{
+			Name: "blockhash",
+			P:    builder.DefaultP,
+			M:    builder.DefaultM,
+			BuildFilter: func(block *wire.MsgBlock,
+				_ [][]byte) (*gcs.Filter, error) {
+
+				blockHash := block.BlockHash()
+				return gcs.BuildGCSFilter(
+					builder.DefaultP, builder.DefaultM,
+					builder.DeriveKey(&blockHash),
+					[][]byte{blockHash[:]},
+				)
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to register filter type: %v", err)
+	}
+
+	fhs, err := NewFilterHeaderStore(
+		tempDir, db, FilterHeaderType(fType), params, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create filter header store: %v", err)
+	}
+	fileName := filepath.Join(tempDir, "blockhash_filter_headers.bin")
+	if _, err := os.Stat(fileName); err != nil {
+		t.Fatalf("filter header file not created: %v", err)
+	}
+
+	// Both stores start at the genesis block, with different filter
+	// headers.
+	regGenesis, err := regStore.FetchHeaderByHeight(0)
+	if err != nil {
+		t.Fatalf("unable to fetch genesis header: %v", err)
+	}
+	genesis, err := fhs.FetchHeaderByHeight(0)
+	if err != nil {
+		t.Fatalf("unable to fetch genesis header: %v", err)
+	}
+	if *regGenesis == *genesis {
+		t.Fatalf("filter types have the same genesis filter header")
+	}
+
+	// Write headers of the additional filter type, which must not move
+	// the tip of the regular filter headers. The block headers, including
+	// the genesis block, should already be indexed.
+	blockHeaders := createTestFilterHeaderChain(10)
+	if err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		rootBucket := tx.ReadWriteBucket(indexBucket)
+
+		genesisEntry := headerEntry{hash: *params.GenesisHash}
+		if err := putHeaderEntry(rootBucket, genesisEntry); err != nil {
+			return err
+		}
+		for _, header := range blockHeaders {
+			entry := headerEntry{
+				hash:   header.HeaderHash,
+				height: header.Height,
+			}
+			if err := putHeaderEntry(rootBucket, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to pre-load block index: %v", err)
+	}
+	if err := fhs.WriteHeaders(blockHeaders...); err != nil {
+		t.Fatalf("unable to write filter headers: %v", err)
+	}
+
+	_, tipHeight, err := fhs.ChainTip()
+	if err != nil {
+		t.Fatalf("unable to get chain tip: %v", err)
+	}
+	if tipHeight != 10 {
+		t.Fatalf("tip height mismatch: expected %v, got %v", 10,
+			tipHeight)
+	}
+	_, regTipHeight, err := regStore.ChainTip()
+	if err != nil {
+		t.Fatalf("unable to get chain tip: %v", err)
+	}
+	if regTipHeight != 0 {
+		t.Fatalf("regular tip height mismatch: expected %v, got %v",
+			0, regTipHeight)
+	}
+}
+
 // TestBlockHeadersFetchHeaderAncestors tests that we're able to properly fetch
 // the ancestors of a particular block, going from a set distance back to the
 // target block.
@@ -611,7 +730,7 @@ func TestFilterHeaderStateAssertion(t *testing.T) {
 			// We'll then re-initialize the filter header store with
 			// its expected assertion.
 			fhs, err := NewFilterHeaderStore(
-				tempDir, db, RegularFilter, netparams.NSimnetParams,
+				tempDir, db, RegularFilter, assets.BTCParams["simnet"],
 				testCase.headerAssertion,
 			)
 			if err != nil {
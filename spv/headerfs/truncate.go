@@ -3,8 +3,6 @@
 
 package headerfs
 
-import "fmt"
-
 // singleTruncate truncates a single header from the end of the header file.
 // This can be used in the case of a re-org to remove the last header from the
 // end of the main chain.
@@ -22,14 +20,9 @@ func (h *headerStore) singleTruncate() error {
 
 	// Next, we'll determine the number of bytes we need to truncate from
 	// the end of the file.
-	var truncateLength int64
-	switch h.indexType {
-	case Block:
-		truncateLength = 80
-	case RegularFilter:
-		truncateLength = 32
-	default:
-		return fmt.Errorf("unknown index type: %v", h.indexType)
+	truncateLength, err := h.indexType.headerSize()
+	if err != nil {
+		return err
 	}
 
 	// Finally, we'll use both of these values to calculate the new size of
@@ -3,10 +3,7 @@
 
 package headerfs
 
-import (
-	"fmt"
-	"os"
-)
+import "os"
 
 // singleTruncate truncates a single header from the end of the header file.
 // This can be used in the case of a re-org to remove the last header from the
@@ -25,14 +22,9 @@ func (h *headerStore) singleTruncate() error {
 
 	// Next, we'll determine the number of bytes we need to truncate from
 	// the end of the file.
-	var truncateLength int64
-	switch h.indexType {
-	case Block:
-		truncateLength = 80
-	case RegularFilter:
-		truncateLength = 32
-	default:
-		return fmt.Errorf("unknown index type: %v", h.indexType)
+	truncateLength, err := h.indexType.headerSize()
+	if err != nil {
+		return err
 	}
 
 	// Finally, we'll use both of these values to calculate the new size of
@@ -32,6 +32,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/connmgr"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 // These are exported variables so they can be changed by users.
@@ -218,7 +219,8 @@ func (sp *ServerPeer) addKnownAddresses(addresses []*wire.NetAddressV2) {
 			addrmgr.NetAddressKey(na), &cachedAddr{},
 		)
 		if err != nil {
-			log.Debugf("Could not store known addresses: %v", err)
+			sp.server.log.Debugf("Could not store known "+
+				"addresses: %v", err)
 		}
 	}
 }
@@ -247,7 +249,8 @@ func (sp *ServerPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 		peerAddr := sp.Addr()
 		err := sp.server.BanPeer(peerAddr, banman.NoCompactFilters)
 		if err != nil {
-			log.Errorf("Unable to ban peer %v: %v", peerAddr, err)
+			sp.server.log.Errorf("Unable to ban peer %v: %v",
+				peerAddr, err)
 		}
 
 		// Disconnect the peer even though BanPeer attempts to do so
@@ -275,14 +278,15 @@ func (sp *ServerPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *ServerPeer) OnInv(p *peer.Peer, msg *wire.MsgInv) {
-	log.Tracef("Got inv with %d items from %s", len(msg.InvList), p.Addr())
+	sp.server.log.Tracef("Got inv with %d items from %s", len(msg.InvList),
+		p.Addr())
 	newInv := wire.NewMsgInvSizeHint(uint(len(msg.InvList)))
 	for _, invVect := range msg.InvList {
 		if invVect.Type == wire.InvTypeTx {
-			log.Tracef("Ignoring tx %s in inv from %v -- "+
-				"SPV mode", invVect.Hash, sp)
+			sp.server.log.Tracef("Ignoring tx %s in inv from %v "+
+				"-- SPV mode", invVect.Hash, sp)
 			if sp.ProtocolVersion() >= wire.BIP0037Version {
-				log.Infof("Peer %v is announcing "+
+				sp.server.log.Infof("Peer %v is announcing "+
 					"transactions -- disconnecting", sp)
 				sp.Disconnect()
 				return
@@ -291,7 +295,8 @@ func (sp *ServerPeer) OnInv(p *peer.Peer, msg *wire.MsgInv) {
 		}
 		err := newInv.AddInvVect(invVect)
 		if err != nil {
-			log.Errorf("Failed to add inventory vector: %s", err)
+			sp.server.log.Errorf("Failed to add inventory vector: "+
+				"%s", err)
 			break
 		}
 	}
@@ -304,8 +309,8 @@ func (sp *ServerPeer) OnInv(p *peer.Peer, msg *wire.MsgInv) {
 // OnHeaders is invoked when a peer receives a headers bitcoin
 // message.  The message is passed down to the block manager.
 func (sp *ServerPeer) OnHeaders(p *peer.Peer, msg *wire.MsgHeaders) {
-	log.Tracef("Got headers with %d items from %s", len(msg.Headers),
-		p.Addr())
+	sp.server.log.Tracef("Got headers with %d items from %s",
+		len(msg.Headers), p.Addr())
 	sp.server.blockManager.QueueHeaders(msg, sp)
 }
 
@@ -316,8 +321,8 @@ func (sp *ServerPeer) OnHeaders(p *peer.Peer, msg *wire.MsgHeaders) {
 func (sp *ServerPeer) OnFeeFilter(_ *peer.Peer, msg *wire.MsgFeeFilter) {
 	// Check that the passed minimum fee is a valid amount.
 	if msg.MinFee < 0 || msg.MinFee > btcutil.MaxSatoshi {
-		log.Debugf("Peer %v sent an invalid feefilter '%v' -- "+
-			"disconnecting", sp, btcutil.Amount(msg.MinFee))
+		sp.server.log.Debugf("Peer %v sent an invalid feefilter '%v' "+
+			"-- disconnecting", sp, btcutil.Amount(msg.MinFee))
 		sp.Disconnect()
 		return
 	}
@@ -338,7 +343,7 @@ func (sp *ServerPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 	// helps prevent the network from becoming another public test network
 	// since it will not be able to learn about other peers that have not
 	// specifically been provided.
-	if isDevNetwork(sp.server.chainParams2.Net) {
+	if isDevNetwork(sp.server.chainParams.Net) {
 		return
 	}
 
@@ -349,8 +354,8 @@ func (sp *ServerPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 
 	// A message that has no addresses is invalid.
 	if len(msg.AddrList) == 0 {
-		log.Errorf("Command [%s] from %s does not contain any "+
-			"addresses", msg.Command(), sp.Addr())
+		sp.server.log.Errorf("Command [%s] from %s does not contain "+
+			"any addresses", msg.Command(), sp.Addr())
 		sp.Disconnect()
 		return
 	}
@@ -410,8 +415,8 @@ func (sp *ServerPeer) OnAddrV2(_ *peer.Peer, msg *wire.MsgAddrV2) {
 
 	// An empty AddrV2 message is invalid.
 	if len(msg.AddrList) == 0 {
-		log.Errorf("Command [%s] from %s does not contain any "+
-			"addresses", msg.Command(), sp.Addr())
+		sp.server.log.Errorf("Command [%s] from %s does not contain "+
+			"any addresses", msg.Command(), sp.Addr())
 		sp.Disconnect()
 		return
 	}
@@ -615,6 +620,12 @@ type Config struct {
 	// current on disk filter headers to sync them anew.
 	AssertFilterHeader *headerfs.FilterHeader
 
+	// FilterTypes are additional filter types whose filter headers are
+	// synced and verified alongside the regular filter headers. They must
+	// be registered with filterdb.RegisterFilterType, and are only
+	// served by peers that support them.
+	FilterTypes []wire.FilterType
+
 	// BroadcastTimeout is the amount of time we'll wait before giving up on
 	// a transaction broadcast attempt. Broadcasting transactions consists
 	// of three steps:
@@ -624,6 +635,13 @@ type Config struct {
 	//    not, replies with a getdata message.
 	// 3. Neutrino sends the raw transaction.
 	BroadcastTimeout time.Duration
+
+	// Logger is the logger the chain service and its block manager,
+	// rescans, UTXO scanner and fee estimator write to, so that services
+	// for different chains can be told apart in the log. The package
+	// logger is used if nil. Subpackages always log through their package
+	// loggers.
+	Logger btclog.Logger
 }
 
 // peerSubscription holds a peer subscription which we'll notify about any
@@ -646,6 +664,7 @@ type ChainService struct { // nolint:maligned
 	FilterDB         filterdb.FilterDatabase
 	BlockHeaders     headerfs.BlockHeaderStore
 	RegFilterHeaders *headerfs.FilterHeaderStore
+	filterHeaders    map[wire.FilterType]*headerfs.FilterHeaderStore
 	persistToDisk    bool
 
 	FilterCache *lru.Cache[FilterCacheKey, *CacheableFilter]
@@ -653,7 +672,6 @@ type ChainService struct { // nolint:maligned
 
 	chainParams          *netparams.ChainParams
 	btcdParams           *chaincfg.Params
-	chainParams2         *chaincfg.Params
 	addrManager          *addrmgr.AddrManager
 	connManager          *connmgr.ConnManager
 	blockManager         *blockManager
@@ -669,6 +687,7 @@ type ChainService struct { // nolint:maligned
 	services             wire.ServiceFlag
 	utxoScanner          *UtxoScanner
 	broadcaster          *pushtx.Broadcaster
+	feeEstimator         *FeeEstimator
 	banStore             banman.Store
 	workManager          query.WorkManager
 	filterBatchWriter    *chanutils.BatchWriter[*filterdb.FilterData]
@@ -687,6 +706,8 @@ type ChainService struct { // nolint:maligned
 	dialer       func(net.Addr) (net.Conn, error)
 
 	broadcastTimeout time.Duration
+
+	log btclog.Logger
 }
 
 // NewChainService returns a new chain service configured to connect to the
@@ -729,6 +750,11 @@ func NewChainService(cfg Config) (*ChainService, error) {
 	// network.
 	amgr := addrmgr.New(cfg.DataDir, nameResolver)
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log
+	}
+
 	s := ChainService{
 		chain:             cfg.Chain,
 		chainParams:       cfg.ChainParams,
@@ -748,6 +774,7 @@ func NewChainService(cfg Config) (*ChainService, error) {
 		dialer:            dialer,
 		persistToDisk:     cfg.PersistToDisk,
 		broadcastTimeout:  cfg.BroadcastTimeout,
+		log:               logger,
 	}
 	s.workManager = query.NewWorkManager(&query.Config{
 		ConnectedPeers: s.ConnectedPeers,
@@ -810,16 +837,34 @@ func NewChainService(cfg Config) (*ChainService, error) {
 		return nil, err
 	}
 
+	// The filter headers of any additional filter types are stored
+	// alongside the regular ones.
+	s.filterHeaders = make(map[wire.FilterType]*headerfs.FilterHeaderStore)
+	for _, fType := range cfg.FilterTypes {
+		if fType == wire.GCSFilterRegular {
+			continue
+		}
+		s.filterHeaders[fType], err = headerfs.NewFilterHeaderStore(
+			cfg.DataDir, cfg.Database,
+			headerfs.FilterHeaderType(fType), cfg.ChainParams, nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	bm, err := newBlockManager(&blockManagerCfg{
 		ChainParams:      s.chainParams,
 		BlockHeaders:     s.BlockHeaders,
 		RegFilterHeaders: s.RegFilterHeaders,
+		FilterHeaders:    s.filterHeaders,
 		TimeSource:       s.timeSource,
 		QueryDispatcher:  s.workManager,
 		BanPeer:          s.BanPeer,
 		GetBlock:         s.GetBlock,
 		firstPeerSignal:  s.firstPeerConnect,
 		queryAllPeers:    s.queryAllPeers,
+		Logger:           s.log,
 	})
 	if err != nil {
 		return nil, err
@@ -858,7 +903,8 @@ func NewChainService(cfg Config) (*ChainService, error) {
 				// Ignore peers that we've already banned.
 				addrString := addrmgr.NetAddressKey(addr.NetAddress())
 				if s.IsBanned(addrString) {
-					log.Debugf("Ignoring banned peer: %v", addrString)
+					s.log.Debugf("Ignoring banned peer: %v",
+						addrString)
 					continue
 				}
 
@@ -940,6 +986,7 @@ func NewChainService(cfg Config) (*ChainService, error) {
 
 			return matches, err
 		},
+		Logger: s.log,
 	})
 
 	s.broadcaster = pushtx.NewBroadcaster(&pushtx.Config{
@@ -952,6 +999,17 @@ func NewChainService(cfg Config) (*ChainService, error) {
 		RebroadcastInterval: pushtx.DefaultRebroadcastInterval,
 	})
 
+	s.feeEstimator = NewFeeEstimator(&FeeEstimatorConfig{
+		SubscribeBlocks: func() (*blockntfns.Subscription, error) {
+			return s.blockSubscriptionMgr.NewSubscription(0)
+		},
+		GetBlockHash: s.GetBlockHash,
+		GetBlock:     s.GetBlock,
+		IsCurrent:    s.IsCurrent,
+		FeeFilters:   s.feeFilters,
+		Logger:       s.log,
+	})
+
 	s.banStore, err = banman.NewStore(cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize ban store: %v", err)
@@ -978,7 +1036,7 @@ func NewChainService(cfg Config) (*ChainService, error) {
 				var err error
 				tcpAddr, err = s.addrStringToNetAddr(addr)
 				if err != nil {
-					log.Warnf("unable to lookup IP for "+
+					s.log.Warnf("unable to lookup IP for "+
 						"%v: %v", addr, err)
 
 					select {
@@ -1003,6 +1061,23 @@ func NewChainService(cfg Config) (*ChainService, error) {
 	return &s, nil
 }
 
+// FilterHeaders returns the store of the filter headers of a filter type. The
+// filter type must be the regular filter type or one of the additional filter
+// types the ChainService was configured with.
+func (s *ChainService) FilterHeaders(
+	fType wire.FilterType) (*headerfs.FilterHeaderStore, error) {
+
+	if fType == wire.GCSFilterRegular {
+		return s.RegFilterHeaders, nil
+	}
+
+	store, ok := s.filterHeaders[fType]
+	if !ok {
+		return nil, fmt.Errorf("filter type %v is not synced", fType)
+	}
+	return store, nil
+}
+
 // BestBlock retrieves the most recent block's height and hash where we
 // have both the header and filter header ready.
 func (s *ChainService) BestBlock() (*headerfs.BlockStamp, error) {
@@ -1067,8 +1142,8 @@ func (s *ChainService) GetBlockHeight(hash *chainhash.Hash) (int32, error) {
 // BanPeer disconnects and bans a peer due to a specific reason for a duration
 // of BanDuration.
 func (s *ChainService) BanPeer(addr string, reason banman.Reason) error {
-	log.Warnf("Banning peer %v: duration=%v, reason=%v", addr, BanDuration,
-		reason)
+	s.log.Warnf("Banning peer %v: duration=%v, reason=%v", addr,
+		BanDuration, reason)
 
 	// We'll want to disconnect the peer after we return regardless of
 	// whether we ban the peer or not. We do this to prevent a possible race
@@ -1094,7 +1169,7 @@ func (s *ChainService) BanPeer(addr string, reason banman.Reason) error {
 
 // UnbanPeer connects and unbans a previously banned peer.
 func (s *ChainService) UnbanPeer(addr string, parmanent bool) error {
-	log.Infof("UnBanning peer %v", addr)
+	s.log.Infof("UnBanning peer %v", addr)
 
 	ipNet, err := banman.ParseIPNet(addr, nil)
 	if err != nil {
@@ -1114,20 +1189,20 @@ func (s *ChainService) UnbanPeer(addr string, parmanent bool) error {
 func (s *ChainService) IsBanned(addr string) bool {
 	ipNet, err := banman.ParseIPNet(addr, nil)
 	if err != nil {
-		log.Errorf("Unable to parse IP network for peer %v: %v", addr,
+		s.log.Errorf("Unable to parse IP network for peer %v: %v", addr,
 			err)
 		return false
 	}
 	banStatus, err := s.banStore.Status(ipNet)
 	if err != nil {
-		log.Errorf("Unable to determine ban status for peer %v: %v",
+		s.log.Errorf("Unable to determine ban status for peer %v: %v",
 			addr, err)
 		return false
 	}
 
 	// Log how much time left the peer will remain banned for, if any.
 	if time.Now().Before(banStatus.Expiration) {
-		log.Debugf("Peer %v is banned for another %v", addr,
+		s.log.Debugf("Peer %v is banned for another %v", addr,
 			time.Until(banStatus.Expiration))
 	}
 
@@ -1220,7 +1295,7 @@ out:
 		case <-s.quit:
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *ServerPeer) {
-				log.Tracef("Shutdown peer %s", sp)
+				s.log.Tracef("Shutdown peer %s", sp)
 				sp.Disconnect()
 			})
 			break out
@@ -1241,7 +1316,7 @@ cleanup:
 		}
 	}
 	s.wg.Done()
-	log.Tracef("Peer handler done")
+	s.log.Tracef("Peer handler done")
 }
 
 // addrStringToNetAddr takes an address in the form of 'host:port' or 'host'
@@ -1324,7 +1399,7 @@ func (s *ChainService) handleAddPeerMsg(state *peerState, sp *ServerPeer) bool {
 
 	// Ignore new peers if we're shutting down.
 	if atomic.LoadInt32(&s.shutdown) != 0 {
-		log.Infof("New peer %s ignored - server is shutting down", sp)
+		s.log.Infof("New peer %s ignored - server is shutting down", sp)
 		sp.Disconnect()
 		return false
 	}
@@ -1339,7 +1414,7 @@ func (s *ChainService) handleAddPeerMsg(state *peerState, sp *ServerPeer) bool {
 
 	// Limit max number of total peers.
 	if state.Count() >= MaxPeers {
-		log.Infof("Max peers reached [%d] - disconnecting peer %s",
+		s.log.Infof("Max peers reached [%d] - disconnecting peer %s",
 			MaxPeers, sp)
 		sp.Disconnect()
 		// TODO: how to handle permanent peers here?
@@ -1348,7 +1423,7 @@ func (s *ChainService) handleAddPeerMsg(state *peerState, sp *ServerPeer) bool {
 	}
 
 	// Add the new peer and start it.
-	log.Debugf("New peer %s", sp)
+	s.log.Debugf("New peer %s", sp)
 	state.outboundGroups[addrmgr.GroupKey(sp.NA())]++
 	if sp.persistent {
 		state.persistentPeers[sp.ID()] = sp
@@ -1450,7 +1525,7 @@ func (s *ChainService) handleDonePeerMsg(state *peerState, sp *ServerPeer) {
 		state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
 		delete(list, sp.ID())
 
-		log.Debugf("Removed peer %s", sp)
+		s.log.Debugf("Removed peer %s", sp)
 	}
 
 	// Only request a new connection if the peer being disconnected is not
@@ -1577,7 +1652,7 @@ func (s *ChainService) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn)
 	sp := NewServerPeer(s, c.Permanent)
 	p, err := peer.NewOutboundPeer(NewPeerConfig(sp), peerAddr)
 	if err != nil {
-		log.Debugf("Cannot create outbound peer %s: %s", c.Addr, err)
+		s.log.Debugf("Cannot create outbound peer %s: %s", c.Addr, err)
 		disconnect()
 		return
 	}
@@ -1653,6 +1728,10 @@ func (s *ChainService) Start() error {
 			err)
 	}
 
+	if err := s.feeEstimator.Start(); err != nil {
+		return fmt.Errorf("unable to start fee estimator: %v", err)
+	}
+
 	if s.persistToDisk {
 		s.filterBatchWriter.Start()
 	}
@@ -1678,21 +1757,22 @@ func (s *ChainService) Stop() error {
 	var returnErr error
 	s.connManager.Stop()
 	s.broadcaster.Stop()
+	s.feeEstimator.Stop()
 	if err := s.utxoScanner.Stop(); err != nil {
-		log.Errorf("error stopping utxo scanner: %v", err)
+		s.log.Errorf("error stopping utxo scanner: %v", err)
 		returnErr = err
 	}
 	if err := s.workManager.Stop(); err != nil {
-		log.Errorf("error stopping work manager: %v", err)
+		s.log.Errorf("error stopping work manager: %v", err)
 		returnErr = err
 	}
 	s.blockSubscriptionMgr.Stop()
 	if err := s.blockManager.Stop(); err != nil {
-		log.Errorf("error stopping block manager: %v", err)
+		s.log.Errorf("error stopping block manager: %v", err)
 		returnErr = err
 	}
 	if err := s.addrManager.Stop(); err != nil {
-		log.Errorf("error stopping address manager: %v", err)
+		s.log.Errorf("error stopping address manager: %v", err)
 		returnErr = err
 	}
 
@@ -423,6 +423,8 @@ func (s *ChainService) putFilterToCache(blockHash *chainhash.Hash,
 type cfiltersQuery struct {
 	cs            *ChainService
 	filterType    wire.FilterType
+	p             uint8
+	m             uint64
 	startHeight   int64
 	stopHeight    int64
 	stopHash      *chainhash.Hash
@@ -481,9 +483,7 @@ func (q *cfiltersQuery) handleResponse(req, resp wire.Message,
 		return noProgress
 	}
 
-	filter, err := gcs.FromNBytes(
-		builder.DefaultP, builder.DefaultM, response.Data,
-	)
+	filter, err := gcs.FromNBytes(q.p, q.m, response.Data)
 	if err != nil {
 		// Malformed filter data. We can ignore this message.
 		return noProgress
@@ -516,20 +516,20 @@ func (q *cfiltersQuery) handleResponse(req, resp wire.Message,
 	// it.
 	// TODO(halseth): for an LRU we could take care to insert the next
 	//  height filter last.
-	dbFilterType := filterdb.RegularFilter
+	dbFilterType := filterdb.FilterType(q.filterType)
 	evict, err := q.cs.putFilterToCache(
 		&response.BlockHash, dbFilterType, filter,
 	)
 	if err != nil {
-		log.Warnf("Couldn't write filter to cache: %v", err)
+		q.cs.log.Warnf("Couldn't write filter to cache: %v", err)
 	}
 
 	// TODO(halseth): dynamically increase/decrease the batch size to match
 	//  our cache capacity.
 	numFilters := q.stopHeight - q.startHeight + 1
 	if evict && q.cs.FilterCache.Len() < int(numFilters) {
-		log.Debugf("Items evicted from the cache with less than %d "+
-			"elements. Consider increasing the cache size...",
+		q.cs.log.Debugf("Items evicted from the cache with less than "+
+			"%d elements. Consider increasing the cache size...",
 			numFilters)
 	}
 
@@ -567,6 +567,15 @@ func (s *ChainService) prepareCFiltersQuery(blockHash chainhash.Hash,
 	filterType wire.FilterType, batchType optimisticBatchType,
 	maxBatchSize int64) (*cfiltersQuery, error) {
 
+	fParams, ok := filterdb.LookupFilterType(filterdb.FilterType(filterType))
+	if !ok {
+		return nil, fmt.Errorf("unknown filter type: %v", filterType)
+	}
+	filterHeaderStore, err := s.FilterHeaders(filterType)
+	if err != nil {
+		return nil, err
+	}
+
 	_, height, err := s.BlockHeaders.FetchHeader(&blockHash)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get header for start "+
@@ -579,6 +588,20 @@ func (s *ChainService) prepareCFiltersQuery(blockHash chainhash.Hash,
 	}
 	bestHeight := int64(bestBlock.Height)
 
+	// The filter headers of additional filter types may lag behind the
+	// regular filter headers that the best block is based on.
+	_, filterHeight, err := filterHeaderStore.ChainTip()
+	if err != nil {
+		return nil, err
+	}
+	if int64(filterHeight) < bestHeight {
+		bestHeight = int64(filterHeight)
+	}
+	if int64(height) > bestHeight {
+		return nil, fmt.Errorf("filter headers of type %v not synced "+
+			"to block=%v", filterType, blockHash)
+	}
+
 	// If the query specifies an optimistic batch we will attempt to fetch
 	// the maximum number of filters, which is defaulted to
 	// wire.MaxGetCFiltersReqRange, in anticipation of calls for the
@@ -648,7 +671,7 @@ func (s *ChainService) prepareCFiltersQuery(blockHash chainhash.Hash,
 			numFilters+1, len(blockHeaders))
 	}
 
-	filterHeaders, _, err := s.RegFilterHeaders.FetchHeaderAncestors(
+	filterHeaders, _, err := filterHeaderStore.FetchHeaderAncestors(
 		numFilters, stopHash,
 	)
 	if err != nil {
@@ -677,6 +700,8 @@ func (s *ChainService) prepareCFiltersQuery(blockHash chainhash.Hash,
 	return &cfiltersQuery{
 		cs:            s,
 		filterType:    filterType,
+		p:             fParams.P,
+		m:             fParams.M,
 		startHeight:   startHeight,
 		stopHeight:    stopHeight,
 		stopHash:      stopHash,
@@ -692,15 +717,12 @@ func (s *ChainService) GetCFilter(blockHash chainhash.Hash,
 	filterType wire.FilterType, options ...QueryOption) (*gcs.Filter,
 	error) {
 
-	// The only supported filter atm is the regular filter, so we'll reject
-	// all other filters.
-	if filterType != wire.GCSFilterRegular {
-		return nil, fmt.Errorf("unknown filter type: %v", filterType)
+	// We can only fetch filters of the types we sync the filter headers
+	// of, as the filter headers are needed to verify them.
+	if _, err := s.FilterHeaders(filterType); err != nil {
+		return nil, err
 	}
-
-	// Based on if extended is true or not, we'll set up our set of
-	// querying, and db-write functions.
-	dbFilterType := filterdb.RegularFilter
+	dbFilterType := filterdb.FilterType(filterType)
 
 	// First check the cache to see if we already have this filter. If
 	// so, then we can return it an exit early.
@@ -751,7 +773,7 @@ func (s *ChainService) GetCFilter(blockHash chainhash.Hash,
 
 	// With all the necessary items retrieved, we'll launch our concurrent
 	// query to the set of connected peers.
-	log.Debugf("Fetching filters for heights=[%v, %v], stophash=%v",
+	s.log.Debugf("Fetching filters for heights=[%v, %v], stophash=%v",
 		filterQuery.startHeight, filterQuery.stopHeight,
 		filterQuery.stopHash)
 
@@ -781,7 +803,7 @@ func (s *ChainService) GetCFilter(blockHash chainhash.Hash,
 			filterQuery.startHeight + 1
 
 		numRecv := numFilters - int64(len(filterQuery.headerIndex))
-		log.Errorf("Query failed with %d out of %d filters received",
+		s.log.Errorf("Query failed with %d out of %d filters received",
 			numRecv, numFilters)
 	}
 
@@ -872,13 +894,13 @@ func (s *ChainService) GetBlock(blockHash chainhash.Hash,
 			s.chainParams,
 			s.timeSource,
 		); err != nil {
-			log.Warnf("Invalid block for %s received from %s: %v",
+			s.log.Warnf("Invalid block for %s received from %s: %v",
 				blockHash, peer, err)
 
 			// Ban and disconnect the peer.
 			err = s.BanPeer(peer, banman.InvalidBlock)
 			if err != nil {
-				log.Errorf("Unable to ban peer %v: %v", peer,
+				s.log.Errorf("Unable to ban peer %v: %v", peer,
 					err)
 			}
 
@@ -888,12 +910,13 @@ func (s *ChainService) GetBlock(blockHash chainhash.Hash,
 		if err := blockchain.ValidateWitnessCommitment(
 			block,
 		); err != nil {
-			log.Warnf("Invalid block for %s received from %s: %v "+
-				"-- disconnecting peer", blockHash, peer, err)
+			s.log.Warnf("Invalid block for %s received from %s: "+
+				"%v -- disconnecting peer", blockHash, peer,
+				err)
 
 			err = s.BanPeer(peer, banman.InvalidBlock)
 			if err != nil {
-				log.Errorf("Unable to ban peer %v: %v", peer,
+				s.log.Errorf("Unable to ban peer %v: %v", peer,
 					err)
 			}
 
@@ -942,7 +965,7 @@ func (s *ChainService) GetBlock(blockHash chainhash.Hash,
 	// Add block to the cache before returning it.
 	_, err = s.BlockCache.Put(*inv, &CacheableBlock{Block: foundBlock})
 	if err != nil {
-		log.Warnf("couldn't write block to cache: %v", err)
+		s.log.Warnf("couldn't write block to cache: %v", err)
 	}
 
 	return foundBlock, nil
@@ -1048,7 +1071,7 @@ func (s *ChainService) sendTransaction(tx *wire.MsgTx, options ...QueryOption) e
 				rejections[sp.ID()] = broadcastErr
 				rejectCodes[broadcastErr.Code]++
 
-				log.Debugf("Transaction %v rejected by peer "+
+				s.log.Debugf("Transaction %v rejected by peer "+
 					"%v: code = %v, reason = %q", txHash,
 					sp.Addr(), broadcastErr.Code,
 					broadcastErr.Reason)
@@ -1069,8 +1092,8 @@ func (s *ChainService) sendTransaction(tx *wire.MsgTx, options ...QueryOption) e
 	// error as the reliable broadcaster will take care of broadcasting this
 	// transaction upon every block connected/disconnected.
 	if len(replies) == 0 {
-		log.Debugf("No peers replied to inv message for transaction %v",
-			txHash)
+		s.log.Debugf("No peers replied to inv message for transaction "+
+			"%v", txHash)
 		return nil
 	}
 
@@ -1091,10 +1114,10 @@ func (s *ChainService) sendTransaction(tx *wire.MsgTx, options ...QueryOption) e
 	// If all of our peers who replied to our query also rejected our
 	// transaction, we'll deem that there was actually something wrong with
 	// it, so we'll return the most rejected error between all of our peers.
-	log.Debugf("Got replies from %d peers and %d rejections", len(replies),
-		len(rejections))
+	s.log.Debugf("Got replies from %d peers and %d rejections",
+		len(replies), len(rejections))
 	if len(replies) == len(rejections) {
-		log.Warnf("All peers rejected transaction %v checking errors",
+		s.log.Warnf("All peers rejected transaction %v checking errors",
 			txHash)
 
 		// First, find the reject code that was returned most often.
@@ -1125,13 +1148,13 @@ func (s *ChainService) sendTransaction(tx *wire.MsgTx, options ...QueryOption) e
 		numInvalid := float32(rejectCodes[pushtx.Invalid])
 		numPeersResponded := float32(len(replies))
 
-		log.Debugf("Of %d peers that replied, %d think the TX is "+
+		s.log.Debugf("Of %d peers that replied, %d think the TX is "+
 			"invalid", numPeersResponded, numInvalid)
 
 		// 60% or more (by default) of the peers declared this TX as
 		// invalid.
 		if numInvalid/numPeersResponded >= qo.invalidTxThreshold {
-			log.Warnf("Threshold of %d reached (%d out of %d "+
+			s.log.Warnf("Threshold of %d reached (%d out of %d "+
 				"peers), declaring TX %v as invalid",
 				qo.invalidTxThreshold, numInvalid,
 				numPeersResponded, txHash)
@@ -287,7 +287,8 @@ func TestBlockCache(t *testing.T) {
 		),
 		BlockHeaders: headers,
 		chainParams: &netparams.ChainParams{
-			PowLimit: maxPowLimit,
+			PowLimit:   maxPowLimit,
+			MaxSatoshi: btcutil.MaxSatoshi,
 		},
 		timeSource:  blockchain.NewMedianTime(),
 		workManager: &mockDispatcher{},
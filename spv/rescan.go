@@ -21,6 +21,7 @@ import (
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 var (
@@ -301,6 +302,10 @@ type rescanState struct {
 	// opts holds the various rescan configuration options.
 	opts *rescanOptions
 
+	// log is the logger of the chain service the rescan runs against, or
+	// the package logger for other chain sources.
+	log btclog.Logger
+
 	// curHeader is the block header of our current position in the chain.
 	curHeader wire.BlockHeader
 
@@ -330,6 +335,10 @@ func newRescanState(chain ChainSource, options ...RescanOption) (*rescanState,
 	rs := &rescanState{
 		chain: chain,
 		opts:  ro,
+		log:   log,
+	}
+	if chainSource, ok := chain.(*RescanChainSource); ok {
+		rs.log = chainSource.log
 	}
 
 	// If we have something to watch, create a watch list. The watch list
@@ -449,7 +458,7 @@ func (rs *rescanState) rescan() error {
 	// To ensure that we batch as many filter queries as possible, we also
 	// wait for the header chain to either be current or for it to at least
 	// have caught up with the specified end block.
-	log.Debugf("Waiting for the chain source to be current or for the " +
+	rs.log.Debugf("Waiting for the chain source to be current or for the " +
 		"rescan end height to be reached.")
 
 	if err := rs.waitForBlocks(func(hash chainhash.Hash,
@@ -476,7 +485,7 @@ func (rs *rescanState) rescan() error {
 		return err
 	}
 
-	log.Debugf("Starting rescan from known block %d (%s)",
+	rs.log.Debugf("Starting rescan from known block %d (%s)",
 		rs.curStamp.Height, rs.curStamp.Hash)
 
 	// Compare the start time to the start block. If the start time is
@@ -560,8 +569,8 @@ rescanLoop:
 				// current. This is our way of doing a manual
 				// rescan.
 				if rewound {
-					log.Tracef("Rewound to block %d (%s), "+
-						"no longer current",
+					rs.log.Tracef("Rewound to block %d "+
+						"(%s), no longer current",
 						rs.curStamp.Height,
 						rs.curStamp.Hash)
 
@@ -582,7 +591,8 @@ rescanLoop:
 					// defer processing this notification
 					// until later.
 					if blockRetryQueue.peek() != nil {
-						log.Debugf("Stashing %v", ntfn)
+						rs.log.Debugf("Stashing %v",
+							ntfn)
 						blockRetryQueue.push(ntfn)
 						continue rescanLoop
 					}
@@ -594,8 +604,9 @@ rescanLoop:
 					// We'll need to retry the block again
 					// as we couldn't fetch its filter.
 					case errRetryBlock:
-						log.Debugf("Retrying %v after %v",
-							ntfn, blockRetryInterval)
+						rs.log.Debugf("Retrying %v "+
+							"after %v", ntfn,
+							blockRetryInterval)
 						blockRetryQueue.push(ntfn)
 						blockRetrySignal = time.After(
 							blockRetryInterval,
@@ -610,8 +621,9 @@ rescanLoop:
 					// TODO(wilmer): determine if the error
 					// is fatal and return it?
 					default:
-						log.Errorf("Unable to process "+
-							"%v: %v", ntfn, err)
+						rs.log.Errorf("Unable to "+
+							"process %v: %v", ntfn,
+							err)
 						current = false
 					}
 
@@ -626,8 +638,8 @@ rescanLoop:
 					rs.handleBlockDisconnected(ntfn)
 
 				default:
-					log.Warnf("Received unhandled block "+
-						"notification: %T", ntfn)
+					rs.log.Warnf("Received unhandled "+
+						"block notification: %T", ntfn)
 				}
 
 			// Our retry signal has fired, so we'll attempt to
@@ -658,8 +670,8 @@ rescanLoop:
 					// We'll need to retry the block again
 					// as we couldn't fetch its filter.
 					case errRetryBlock:
-						log.Debugf("Retrying %v after "+
-							"%v", retryBlock,
+						rs.log.Debugf("Retrying %v "+
+							"after %v", retryBlock,
 							blockRetryInterval)
 						blockRetrySignal = time.After(
 							blockRetryInterval,
@@ -675,9 +687,9 @@ rescanLoop:
 					// TODO(wilmer): determine if the error
 					// is fatal and return it?
 					default:
-						log.Errorf("Unable to process "+
-							"retry of %v: %v",
-							retryBlock, err)
+						rs.log.Errorf("Unable to "+
+							"process retry of %v: "+
+							"%v", retryBlock, err)
 						current = false
 						continue rescanLoop
 					}
@@ -733,9 +745,10 @@ rescanLoop:
 						"block subscription: %v", err)
 				}
 
-				log.Debugf("Rescan became current at %d (%s), "+
-					"subscribing to block notifications",
-					rs.curStamp.Height, rs.curStamp.Hash)
+				rs.log.Debugf("Rescan became current at %d "+
+					"(%s), subscribing to block "+
+					"notifications", rs.curStamp.Height,
+					rs.curStamp.Hash)
 
 				current = true
 				blockRetryQueue.clear()
@@ -791,7 +804,7 @@ func (rs *rescanState) waitForBlocks(predicate func(hash chainhash.Hash,
 		return nil
 	}
 
-	log.Debugf("Waiting to catch up to the rescan start height=%d "+
+	rs.log.Debugf("Waiting to catch up to the rescan start height=%d "+
 		"from height=%d", rs.curStamp.Height, bestBlock.Height)
 
 	blockSubscription, err := chain.Subscribe(uint32(bestBlock.Height))
@@ -925,7 +938,8 @@ func (rs *rescanState) handleBlockConnected(ntfn *blockntfns.Connected) error {
 		Timestamp: header.Timestamp,
 	}
 
-	log.Tracef("Rescan got block %d (%s)", newStamp.Height, newStamp.Hash)
+	rs.log.Tracef("Rescan got block %d (%s)", newStamp.Height,
+		newStamp.Hash)
 
 	// We're only scanning if the header is beyond the horizon of
 	// our start time.
@@ -964,7 +978,7 @@ func (rs *rescanState) handleBlockConnected(ntfn *blockntfns.Connected) error {
 		// If the query failed, then this either means that we don't
 		// have any peers to fetch this filter from, or the peer(s) that
 		// we're trying to fetch from are in the progress of a re-org.
-		log.Errorf("unable to get filter for hash=%v, retrying: %v",
+		rs.log.Errorf("unable to get filter for hash=%v, retrying: %v",
 			rs.curStamp.Hash, err)
 
 		return errRetryBlock
@@ -1067,7 +1081,7 @@ func (rs *rescanState) handleBlockDisconnected(ntfn *blockntfns.Disconnected) {
 	ro := rs.opts
 
 	blockDisconnected := ntfn.Header()
-	log.Debugf("Rescan got disconnected block %d (%s)", ntfn.Height(),
+	rs.log.Debugf("Rescan got disconnected block %d (%s)", ntfn.Height(),
 		blockDisconnected.BlockHash())
 
 	// Only deal with it if it's the current block we know about. Otherwise,
@@ -1583,7 +1597,7 @@ func (s *ChainService) GetUtxo(options ...RescanOption) (*SpendReport, error) {
 	// is signaled.
 	report, err := req.Result(ro.quit)
 	if err != nil {
-		log.Debugf("Error finding spends for %s: %v",
+		s.log.Debugf("Error finding spends for %s: %v",
 			ro.watchInputs[0].OutPoint.String(), err)
 		return nil, err
 	}
@@ -537,6 +537,7 @@ func testStartRescan(harness *neutrinoHarness, t *testing.T) {
 			},
 			ScriptSize: len(script3),
 		},
+		assets.BTCParams["simnet"],
 	)
 	if err != nil {
 		t.Fatalf("Couldn't create unsigned transaction: %s", err)
@@ -585,6 +586,7 @@ func testStartRescan(harness *neutrinoHarness, t *testing.T) {
 			},
 			ScriptSize: len(script3),
 		},
+		assets.BTCParams["simnet"],
 	)
 	if err != nil {
 		t.Fatalf("Couldn't create unsigned transaction: %s", err)
@@ -9,6 +9,7 @@ import (
 	"github.com/bisoncraft/utxowallet/spv/headerfs"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btclog"
 )
 
 // getUtxoResult is a simple pair type holding a spend report and error.
@@ -47,12 +48,15 @@ type GetUtxoRequest struct {
 }
 
 // deliver tries to deliver the report or error to any subscribers. If
-// resultChan cannot accept a new update, this method will not block.
-func (r *GetUtxoRequest) deliver(report *SpendReport, err error) {
+// resultChan cannot accept a new update, this method will not block, and the
+// duplicate is written to logger.
+func (r *GetUtxoRequest) deliver(logger btclog.Logger, report *SpendReport,
+	err error) {
+
 	select {
 	case r.resultChan <- &getUtxoResult{report, err}:
 	default:
-		log.Warnf("duplicate getutxo result delivered for "+
+		logger.Warnf("duplicate getutxo result delivered for "+
 			"outpoint=%v, spend=%v, err=%v",
 			r.Input.OutPoint, report, err)
 	}
@@ -95,6 +99,10 @@ type UtxoScannerConfig struct {
 
 	// GetBlock fetches a block from the p2p network.
 	GetBlock func(chainhash.Hash, ...QueryOption) (*btcutil.Block, error)
+
+	// Logger is the logger the scanner writes to. The package logger is
+	// used if nil.
+	Logger btclog.Logger
 }
 
 // UtxoScanner batches calls to GetUtxo so that a single scan can search for
@@ -106,6 +114,7 @@ type UtxoScanner struct {
 	stopped uint32
 
 	cfg *UtxoScannerConfig
+	log btclog.Logger
 
 	pq        GetUtxoRequestPQ
 	nextBatch []*GetUtxoRequest
@@ -121,8 +130,13 @@ type UtxoScanner struct {
 // NewUtxoScanner creates a new instance of UtxoScanner using the given chain
 // interface.
 func NewUtxoScanner(cfg *UtxoScannerConfig) *UtxoScanner {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log
+	}
 	scanner := &UtxoScanner{
 		cfg:      cfg,
+		log:      logger,
 		quit:     make(chan struct{}),
 		shutdown: make(chan struct{}),
 	}
@@ -165,7 +179,7 @@ batchShutdown:
 	// batchManager's main goroutine.
 	for !s.pq.IsEmpty() {
 		pendingReq := heap.Pop(&s.pq).(*GetUtxoRequest)
-		pendingReq.deliver(nil, ErrShuttingDown)
+		pendingReq.deliver(s.log, nil, ErrShuttingDown)
 	}
 
 	return nil
@@ -176,7 +190,7 @@ func (s *UtxoScanner) Enqueue(input *InputWithScript,
 	birthHeight uint32,
 	progressHandler ScanProgressHandler) (*GetUtxoRequest, error) {
 
-	log.Debugf("Enqueuing request for %s with birth height %d",
+	s.log.Debugf("Enqueuing request for %s with birth height %d",
 		input.OutPoint.String(), birthHeight)
 
 	req := &GetUtxoRequest{
@@ -248,7 +262,7 @@ func (s *UtxoScanner) batchManager() {
 		// least-height request currently in the queue.
 		err := s.scanFromHeight(req.BirthHeight)
 		if err != nil {
-			log.Errorf("utxo scan failed: %v", err)
+			s.log.Errorf("utxo scan failed: %v", err)
 		}
 	}
 }
@@ -296,7 +310,7 @@ func (s *UtxoScanner) scanFromHeight(initHeight uint32) error {
 		endHeight   = uint32(bestStamp.Height)
 	)
 
-	reporter := newBatchSpendReporter()
+	reporter := newBatchSpendReporter(s.log)
 	options := defaultRescanOptions()
 
 scanToEnd:
@@ -352,7 +366,7 @@ scanToEnd:
 		default:
 		}
 
-		log.Debugf("Fetching block height=%d hash=%s", height, hash)
+		s.log.Debugf("Fetching block height=%d hash=%s", height, hash)
 
 		block, err := s.cfg.GetBlock(*hash)
 		if err != nil {
@@ -366,7 +380,7 @@ scanToEnd:
 		default:
 		}
 
-		log.Debugf("Processing block height=%d hash=%s", height, hash)
+		s.log.Debugf("Processing block height=%d hash=%s", height, hash)
 
 		reporter.ProcessBlock(block.MsgBlock(), newReqs, height)
 		reporter.NotifyProgress(height)
@@ -92,7 +92,7 @@ func TestFindSpends(t *testing.T) {
 
 	// Test that finding spends with an empty outpoints index returns no
 	// spends.
-	r := newBatchSpendReporter()
+	r := newBatchSpendReporter(log)
 	spends := r.notifySpends(&Block100000, height)
 	if len(spends) != 0 {
 		t.Fatalf("unexpected number of spend reports -- "+
@@ -130,7 +130,7 @@ func TestFindInitialTransactions(t *testing.T) {
 	}
 
 	// First, try to find the outpoint within the block.
-	r := newBatchSpendReporter()
+	r := newBatchSpendReporter(log)
 	initialTxns := r.findInitialTransactions(&Block100000, reqs, height)
 	if len(initialTxns) != 1 {
 		t.Fatalf("unexpected number of spend reports -- "+
@@ -147,7 +147,7 @@ func TestFindInitialTransactions(t *testing.T) {
 	outpoint.Index = 1
 
 	// Try to find the invalid outpoint in the same block.
-	r = newBatchSpendReporter()
+	r = newBatchSpendReporter(log)
 	initialTxns = r.findInitialTransactions(&Block100000, reqs, height)
 	if len(initialTxns) != 1 {
 		t.Fatalf("unexpected number of spend reports -- "+
@@ -166,7 +166,7 @@ func TestFindInitialTransactions(t *testing.T) {
 	outpoint.Hash[0] ^= 0x01
 
 	// Try to find the outpoint with an invalid txid in the same block.
-	r = newBatchSpendReporter()
+	r = newBatchSpendReporter(log)
 	initialTxns = r.findInitialTransactions(&Block100000, reqs, height)
 	if len(initialTxns) != 1 {
 		t.Fatalf("unexpected number of spend reports -- "+
@@ -80,6 +80,10 @@ const (
 	// TaprootScript represents a p2tr (pay-to-taproot) address type that
 	// commits to a script and not just a single key.
 	TaprootScript
+
+	// NestedWitnessScript represents a p2wsh (pay-to-witness-script-hash)
+	// address type nested within a p2sh output.
+	NestedWitnessScript
 )
 
 const (
@@ -909,6 +913,10 @@ type witnessScriptAddress struct {
 	// and encrypted with the script encryption key or "public" and
 	// therefore only encrypted with the public encryption key.
 	isSecretScript bool
+
+	// nested denotes whether the witness script hash is nested within a
+	// pay-to-script-hash output.
+	nested bool
 }
 
 // AddrType returns the address type of the managed address. This can be used
@@ -916,11 +924,15 @@ type witnessScriptAddress struct {
 //
 // This is part of the ManagedAddress interface implementation.
 func (a *witnessScriptAddress) AddrType() AddressType {
+	if a.nested {
+		return NestedWitnessScript
+	}
 	return WitnessScript
 }
 
 // Address returns the btcutil.Address which represents the managed address.
-// This will be a pay-to-witness-script-hash address.
+// This will be a pay-to-witness-script-hash address, or a pay-to-script-hash
+// address for nested witness scripts.
 //
 // This is part of the ManagedAddress interface implementation.
 func (a *witnessScriptAddress) Address() btcutil.Address {
@@ -1040,6 +1052,33 @@ func newWitnessScriptAddress(m *ScopedKeyManager, account uint32, scriptIdent,
 	}
 }
 
+// newNestedWitnessScriptAddress initializes and returns a new
+// pay-to-witness-script-hash address nested within a pay-to-script-hash
+// output, where scriptIdent is the hash of the redeem script.
+func newNestedWitnessScriptAddress(m *ScopedKeyManager, account uint32,
+	scriptIdent, scriptEncrypted []byte,
+	isSecretScript bool) (ManagedScriptAddress, error) {
+
+	address, err := btcutil.NewAddressScriptHashFromHash(
+		scriptIdent, m.rootManager.chainParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &witnessScriptAddress{
+		baseScriptAddress: baseScriptAddress{
+			manager:         m,
+			account:         account,
+			scriptEncrypted: scriptEncrypted,
+		},
+		address:        address,
+		witnessVersion: witnessVersionV0,
+		isSecretScript: isSecretScript,
+		nested:         true,
+	}, nil
+}
+
 // taprootScriptAddress represents a pay-to-taproot address that commits to a
 // script.
 type taprootScriptAddress struct {
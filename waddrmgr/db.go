@@ -70,11 +70,12 @@ type addressType uint8
 
 // These constants define the various supported address types.
 const (
-	adtChain         addressType = 0
-	adtImport        addressType = 1 // not iota as they need to be stable for db
-	adtScript        addressType = 2
-	adtWitnessScript addressType = 3
-	adtTaprootScript addressType = 4
+	adtChain               addressType = 0
+	adtImport              addressType = 1 // not iota as they need to be stable for db
+	adtScript              addressType = 2
+	adtWitnessScript       addressType = 3
+	adtTaprootScript       addressType = 4
+	adtNestedWitnessScript addressType = 5
 )
 
 // accountType represents a type of address stored in the database.
@@ -92,6 +93,12 @@ const (
 	// derivation schema of BIP0044-like accounts and does not store private
 	// keys.
 	accountWatchOnly accountType = 1
+
+	// accountMultisig is the account type used for storing multisig
+	// accounts within the database. This is an account deriving the keys
+	// of multiple cosigners, of which the wallet may or may not be one,
+	// and stores the private key of the wallet as a cosigner if it is.
+	accountMultisig accountType = 2
 )
 
 // dbAccountRow houses information stored about an account in the database.
@@ -123,6 +130,28 @@ type dbWatchOnlyAccountRow struct {
 	addrSchema           *ScopeAddrSchema
 }
 
+// dbMultisigCosigner houses information stored about a cosigner of a multisig
+// account in the database.
+type dbMultisigCosigner struct {
+	pubKeyEncrypted      []byte
+	masterKeyFingerprint uint32
+	derivationPath       []uint32
+}
+
+// dbMultisigAccountRow houses additional information stored about a multisig
+// account in the database.
+type dbMultisigAccountRow struct {
+	dbAccountRow
+	threshold         uint8
+	scriptType        MultisigScriptType
+	cosigners         []dbMultisigCosigner
+	ownKeyIndex       uint8
+	privKeyEncrypted  []byte
+	nextExternalIndex uint32
+	nextInternalIndex uint32
+	name              string
+}
+
 // dbAddressRow houses common information stored about an address in the
 // database.
 type dbAddressRow struct {
@@ -971,6 +1000,221 @@ func serializeWatchOnlyAccountRow(encryptedPubKey []byte, masterKeyFingerprint,
 	return buf.Bytes(), nil
 }
 
+// deserializeMultisigAccountRow deserializes the raw data from the passed
+// account row as a multisig account.
+func deserializeMultisigAccountRow(accountID []byte,
+	row *dbAccountRow) (*dbMultisigAccountRow, error) {
+
+	// The serialized multisig account raw data format is:
+	//   <threshold><scripttype><numcosigners><cosigners><ownkeyidx>
+	//   <encprivkeylen><encprivkey><nextextidx><nextintidx><namelen><name>
+	//
+	// 1 byte threshold + 1 byte script type + 1 byte number of cosigners +
+	// cosigners + 1 byte own key index + 4 bytes encrypted privkey len +
+	// encrypted privkey + 4 bytes next external index + 4 bytes next
+	// internal index + 4 bytes name len + name
+	//
+	// Each cosigner is serialized as:
+	//   <encpubkeylen><encpubkey><masterkeyfingerprint><pathlen><path>
+	//
+	// 4 bytes encrypted pubkey len + encrypted pubkey + 4 bytes master key
+	// fingerprint + 1 byte path len + 4 bytes for each step of the path
+
+	// Given the above, the length of the entry must be at a minimum
+	// the constant value sizes.
+	if len(row.rawData) < 20 {
+		str := fmt.Sprintf("malformed serialized multisig account "+
+			"for key %x", accountID)
+		return nil, managerError(ErrDatabase, str, nil)
+	}
+
+	retRow := dbMultisigAccountRow{
+		dbAccountRow: *row,
+	}
+	r := bytes.NewReader(row.rawData)
+
+	err := binary.Read(r, binary.LittleEndian, &retRow.threshold)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(r, binary.LittleEndian, &retRow.scriptType)
+	if err != nil {
+		return nil, err
+	}
+
+	var numCosigners uint8
+	err = binary.Read(r, binary.LittleEndian, &numCosigners)
+	if err != nil {
+		return nil, err
+	}
+	retRow.cosigners = make([]dbMultisigCosigner, numCosigners)
+	for i := range retRow.cosigners {
+		cosigner := &retRow.cosigners[i]
+
+		var pubLen uint32
+		err = binary.Read(r, binary.LittleEndian, &pubLen)
+		if err != nil {
+			return nil, err
+		}
+		cosigner.pubKeyEncrypted = make([]byte, pubLen)
+		err = binary.Read(r, binary.LittleEndian, &cosigner.pubKeyEncrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		err = binary.Read(
+			r, binary.LittleEndian, &cosigner.masterKeyFingerprint,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var pathLen uint8
+		err = binary.Read(r, binary.LittleEndian, &pathLen)
+		if err != nil {
+			return nil, err
+		}
+		cosigner.derivationPath = make([]uint32, pathLen)
+		err = binary.Read(r, binary.LittleEndian, &cosigner.derivationPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = binary.Read(r, binary.LittleEndian, &retRow.ownKeyIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	var privLen uint32
+	err = binary.Read(r, binary.LittleEndian, &privLen)
+	if err != nil {
+		return nil, err
+	}
+	if privLen > 0 {
+		retRow.privKeyEncrypted = make([]byte, privLen)
+		err = binary.Read(r, binary.LittleEndian, &retRow.privKeyEncrypted)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = binary.Read(r, binary.LittleEndian, &retRow.nextExternalIndex)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(r, binary.LittleEndian, &retRow.nextInternalIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	var nameLen uint32
+	err = binary.Read(r, binary.LittleEndian, &nameLen)
+	if err != nil {
+		return nil, err
+	}
+	name := make([]byte, nameLen)
+	err = binary.Read(r, binary.LittleEndian, &name)
+	if err != nil {
+		return nil, err
+	}
+	retRow.name = string(name)
+
+	return &retRow, nil
+}
+
+// serializeMultisigAccountRow returns the serialization of the raw data field
+// for a multisig account.
+func serializeMultisigAccountRow(row *dbMultisigAccountRow) ([]byte, error) {
+	// The serialized multisig account raw data format is described in
+	// deserializeMultisigAccountRow.
+	privLen := uint32(len(row.privKeyEncrypted))
+	nameLen := uint32(len(row.name))
+
+	bufLen := 20 + privLen + nameLen
+	for _, cosigner := range row.cosigners {
+		bufLen += 9 + uint32(len(cosigner.pubKeyEncrypted)) +
+			4*uint32(len(cosigner.derivationPath))
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, bufLen))
+
+	err := binary.Write(buf, binary.LittleEndian, row.threshold)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buf, binary.LittleEndian, row.scriptType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Write(buf, binary.LittleEndian, uint8(len(row.cosigners)))
+	if err != nil {
+		return nil, err
+	}
+	for _, cosigner := range row.cosigners {
+		pubLen := uint32(len(cosigner.pubKeyEncrypted))
+		err = binary.Write(buf, binary.LittleEndian, pubLen)
+		if err != nil {
+			return nil, err
+		}
+		err = binary.Write(buf, binary.LittleEndian, cosigner.pubKeyEncrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		err = binary.Write(
+			buf, binary.LittleEndian, cosigner.masterKeyFingerprint,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		pathLen := uint8(len(cosigner.derivationPath))
+		err = binary.Write(buf, binary.LittleEndian, pathLen)
+		if err != nil {
+			return nil, err
+		}
+		err = binary.Write(buf, binary.LittleEndian, cosigner.derivationPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = binary.Write(buf, binary.LittleEndian, row.ownKeyIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Write(buf, binary.LittleEndian, privLen)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buf, binary.LittleEndian, row.privKeyEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Write(buf, binary.LittleEndian, row.nextExternalIndex)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buf, binary.LittleEndian, row.nextInternalIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Write(buf, binary.LittleEndian, nameLen)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buf, binary.LittleEndian, []byte(row.name))
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 // forEachKeyScope calls the given function for each known manager scope
 // within the set of scopes known by the root manager.
 func forEachKeyScope(ns walletdb.ReadBucket, fn func(KeyScope) error) error {
@@ -1111,6 +1355,8 @@ func fetchAccountInfo(ns walletdb.ReadBucket, scope *KeyScope,
 		return deserializeDefaultAccountRow(accountID, row)
 	case accountWatchOnly:
 		return deserializeWatchOnlyAccountRow(accountID, row)
+	case accountMultisig:
+		return deserializeMultisigAccountRow(accountID, row)
 	}
 
 	str := fmt.Sprintf("unsupported account type '%d'", row.acctType)
@@ -1295,6 +1541,51 @@ func putWatchOnlyAccountInfo(ns walletdb.ReadWriteBucket, scope *KeyScope,
 	return putAccountInfo(ns, scope, account, &acctRow, name)
 }
 
+// putMultisigAccountInfo stores the provided multisig account information to
+// the database.
+func putMultisigAccountInfo(ns walletdb.ReadWriteBucket, scope *KeyScope,
+	account uint32, row *dbMultisigAccountRow) error {
+
+	rawData, err := serializeMultisigAccountRow(row)
+	if err != nil {
+		return err
+	}
+
+	acctRow := dbAccountRow{
+		acctType: accountMultisig,
+		rawData:  rawData,
+	}
+	return putAccountInfo(ns, scope, account, &acctRow, row.name)
+}
+
+// putMultisigNextIndex stores the next index of the branch of a multisig
+// account.
+func putMultisigNextIndex(ns walletdb.ReadWriteBucket, scope *KeyScope,
+	account, branch, index uint32) error {
+
+	row, err := fetchAccountInfo(ns, scope, account)
+	if err != nil {
+		return err
+	}
+	arow, ok := row.(*dbMultisigAccountRow)
+	if !ok {
+		str := fmt.Sprintf("account %d is not a multisig account",
+			account)
+		return managerError(ErrDatabase, str, nil)
+	}
+
+	if branch == InternalBranch {
+		arow.nextInternalIndex = index
+	} else {
+		arow.nextExternalIndex = index
+	}
+	arow.rawData, err = serializeMultisigAccountRow(arow)
+	if err != nil {
+		return err
+	}
+	return putAccountRow(ns, scope, account, &arow.dbAccountRow)
+}
+
 // putAccountInfo stores the provided account information to the database.
 func putAccountInfo(ns walletdb.ReadWriteBucket, scope *KeyScope,
 	account uint32, acctRow *dbAccountRow, name string) error {
@@ -1628,6 +1919,10 @@ func fetchAddressByHash(ns walletdb.ReadBucket, scope *KeyScope,
 		// TLV encodes more stuff in the raw script part. But in the
 		// database we store the same fields.
 		return deserializeWitnessScriptAddress(row)
+	case adtNestedWitnessScript:
+		// A nested witness script address stores the same fields as a
+		// witness script address, with the hash of its redeem script.
+		return deserializeWitnessScriptAddress(row)
 	}
 
 	str := fmt.Sprintf("unsupported address type '%d'", row.addrType)
@@ -1795,6 +2090,10 @@ func putChainedAddress(ns walletdb.ReadWriteBucket, scope *KeyScope,
 		if err != nil {
 			return err
 		}
+
+	// The next indexes of multisig accounts are stored separately, as
+	// their addresses are stored ahead of being issued.
+	case accountMultisig:
 	}
 
 	err = bucket.Put(accountID, serializeAccountRow(row))
@@ -1846,10 +2145,11 @@ func putScriptAddress(ns walletdb.ReadWriteBucket, scope *KeyScope,
 }
 
 // putWitnessScriptAddress stores the provided witness script address
-// information to the database.
+// information to the database.  Nested witness script addresses are stored
+// with the hash of their redeem script.
 func putWitnessScriptAddress(ns walletdb.ReadWriteBucket, scope *KeyScope,
 	addressID []byte, account uint32, status syncStatus,
-	witnessVersion uint8, isSecretScript bool, encryptedHash,
+	witnessVersion uint8, isSecretScript, nested bool, encryptedHash,
 	encryptedScript []byte) error {
 
 	rawData := serializeWitnessScriptAddress(
@@ -1857,11 +2157,15 @@ func putWitnessScriptAddress(ns walletdb.ReadWriteBucket, scope *KeyScope,
 	)
 
 	addrType := adtWitnessScript
-	if witnessVersion == witnessVersionV1 {
+	switch {
+	case witnessVersion == witnessVersionV1:
 		// A taproot script stores a TLV encoded blob of data in the
 		// raw data field. So we only really need to use a different
 		// storage type since all other fields stay the same.
 		addrType = adtTaprootScript
+
+	case nested:
+		addrType = adtNestedWitnessScript
 	}
 
 	addrRow := dbAddressRow{
@@ -2074,6 +2378,25 @@ func deletePrivateKeys(ns walletdb.ReadWriteBucket) error {
 					return managerError(ErrDatabase, str, err)
 				}
 
+			case accountMultisig:
+				arow, err := deserializeMultisigAccountRow(k, row)
+				if err != nil {
+					return err
+				}
+
+				// Reserialize the account without the private key of
+				// the wallet as a cosigner and store it.
+				arow.privKeyEncrypted = nil
+				row.rawData, err = serializeMultisigAccountRow(arow)
+				if err != nil {
+					return err
+				}
+				err = bucket.Put(k, serializeAccountRow(row))
+				if err != nil {
+					str := "failed to delete account private key"
+					return managerError(ErrDatabase, str, err)
+				}
+
 			// Watch-only accounts don't contain any private keys.
 			case accountWatchOnly:
 			}
@@ -2132,7 +2455,7 @@ func deletePrivateKeys(ns walletdb.ReadWriteBucket) error {
 					return managerError(ErrDatabase, str, err)
 				}
 
-			case adtWitnessScript:
+			case adtWitnessScript, adtNestedWitnessScript:
 				srow, err := deserializeWitnessScriptAddress(row)
 				if err != nil {
 					return err
@@ -7,6 +7,7 @@ package waddrmgr
 import (
 	"crypto/rand"
 	"crypto/sha512"
+	"encoding/binary"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -96,10 +97,19 @@ func isReservedAccountNum(acct uint32) bool {
 	return acct == ImportedAddrAccount
 }
 
-// ScryptOptions is used to hold the scrypt parameters needed when deriving new
-// passphrase keys.
+// ScryptOptions is used to hold the key derivation parameters needed when
+// deriving new passphrase keys.  Despite the name, the KDF field may select
+// Argon2id instead of scrypt.
 type ScryptOptions struct {
 	N, R, P int
+
+	// KDF is the key derivation function of new passphrase keys.  The
+	// zero value selects scrypt with N, R and P, while snacl.KDFArgon2id
+	// uses Time, Memory (in KiB) and Threads.
+	KDF     snacl.KDF
+	Time    uint32
+	Memory  uint32
+	Threads uint8
 }
 
 // OpenCallbacks houses caller-provided callbacks that may be called when
@@ -125,6 +135,14 @@ var DefaultScryptOptions = ScryptOptions{
 	P: 1,
 }
 
+// DefaultArgon2idOptions are the default options used with Argon2id.
+var DefaultArgon2idOptions = ScryptOptions{
+	KDF:     snacl.KDFArgon2id,
+	Time:    snacl.DefaultArgon2idTime,
+	Memory:  snacl.DefaultArgon2idMemory,
+	Threads: snacl.DefaultArgon2idThreads,
+}
+
 // FastScryptOptions are the scrypt options that should be used for testing
 // purposes only where speed is more important than security.
 var FastScryptOptions = ScryptOptions{
@@ -176,6 +194,11 @@ type accountInfo struct {
 	// derivation path m/). This may be required by some hardware wallets
 	// for proper identification and signing.
 	masterKeyFingerprint uint32
+
+	// multisig holds the threshold and cosigners of multisig accounts,
+	// and is nil for any other account.  The account keys of multisig
+	// accounts are those of the wallet as one of the cosigners, if it is.
+	multisig *MultisigProperties
 }
 
 // AccountProperties contains properties associated with each account, such as
@@ -221,6 +244,11 @@ type AccountProperties struct {
 	// AddrSchema, if non-nil, specifies an address schema override for
 	// address generation only applicable to the account.
 	AddrSchema *ScopeAddrSchema
+
+	// Multisig, if non-nil, holds the threshold and cosigners of a
+	// multisig account.  AccountPubKey is then the key of the wallet as
+	// one of the cosigners, or nil if it isn't one.
+	Multisig *MultisigProperties
 }
 
 // unlockDeriveInfo houses the information needed to derive a private key for a
@@ -241,9 +269,55 @@ type SecretKeyGenerator func(
 // defaultNewSecretKey returns a new secret key.  See newSecretKey.
 func defaultNewSecretKey(passphrase *[]byte,
 	config *ScryptOptions) (*snacl.SecretKey, error) {
+	if config.KDF == snacl.KDFArgon2id {
+		return snacl.NewArgon2idSecretKey(
+			passphrase, config.Time, config.Memory, config.Threads,
+		)
+	}
 	return snacl.NewSecretKey(passphrase, config.N, config.R, config.P)
 }
 
+// kdfUpgradeNeeded returns whether a passphrase key derived with params should
+// be replaced by one derived using the options.  That is the case when the
+// options switch from scrypt to Argon2id, or when none of their parameters is
+// weaker than those of params and at least one is stronger, so that a key is
+// never re-wrapped with a weaker parameter.  Keys derived using Argon2id are
+// never replaced by scrypt ones.
+func kdfUpgradeNeeded(params *snacl.Parameters, config *ScryptOptions) bool {
+	if params.KDF != config.KDF {
+		return config.KDF == snacl.KDFArgon2id
+	}
+
+	var current, configured [3]uint64
+	switch params.KDF {
+	case snacl.KDFArgon2id:
+		current = [3]uint64{
+			uint64(params.Time), uint64(params.Memory),
+			uint64(params.Threads),
+		}
+		configured = [3]uint64{
+			uint64(config.Time), uint64(config.Memory),
+			uint64(config.Threads),
+		}
+	default:
+		current = [3]uint64{
+			uint64(params.N), uint64(params.R), uint64(params.P),
+		}
+		configured = [3]uint64{
+			uint64(config.N), uint64(config.R), uint64(config.P),
+		}
+	}
+
+	var stronger bool
+	for i := range current {
+		if configured[i] < current[i] {
+			return false
+		}
+		stronger = stronger || configured[i] > current[i]
+	}
+	return stronger
+}
+
 var (
 	// secretKeyGen is the inner method that is executed when calling
 	// newSecretKey.
@@ -274,6 +348,15 @@ func newSecretKey(passphrase *[]byte,
 	return secretKeyGen(passphrase, config)
 }
 
+// NewSecretKey generates a new secret key from the passphrase using the key
+// derivation function and parameters of config.  Like the passphrase keys of
+// the manager, it is generated by the active SecretKeyGenerator.
+func NewSecretKey(passphrase *[]byte,
+	config *ScryptOptions) (*snacl.SecretKey, error) {
+
+	return newSecretKey(passphrase, config)
+}
+
 // EncryptorDecryptor provides an abstraction on top of snacl.CryptoKey so that
 // our tests can use dependency injection to force the behaviour they need.
 type EncryptorDecryptor interface {
@@ -420,6 +503,87 @@ func (m *Manager) IsWatchOnlyAccount(ns walletdb.ReadBucket, keyScope KeyScope,
 	return scopedMgr.IsWatchOnlyAccount(ns, account)
 }
 
+// MasterKeyFingerprint returns the fingerprint of the root key of the manager,
+// from which the accounts of its default key scopes are derived.  Like the
+// fingerprints of PSBT derivations, the fingerprint is the little endian
+// number made up of the first four bytes of the hash160 of the root public
+// key.
+func (m *Manager) MasterKeyFingerprint(ns walletdb.ReadBucket) (uint32, error) {
+	_, masterHDPubEnc := fetchMasterHDKeys(ns)
+	if masterHDPubEnc == nil {
+		str := "master public key not available"
+		return 0, managerError(ErrWatchingOnly, str, nil)
+	}
+
+	m.mtx.RLock()
+	serializedMasterPub, err := m.cryptoKeyPub.Decrypt(masterHDPubEnc)
+	m.mtx.RUnlock()
+	if err != nil {
+		str := "failed to decrypt master public key"
+		return 0, managerError(ErrCrypto, str, err)
+	}
+	masterPub, err := hdkeychain.NewKeyFromString(string(serializedMasterPub))
+	if err != nil {
+		str := "failed to create master extended public key"
+		return 0, managerError(ErrKeyChain, str, err)
+	}
+	return keyFingerprint(masterPub)
+}
+
+// keyFingerprint returns the fingerprint of the extended key, as the little
+// endian number made up of the first four bytes of the hash160 of its public
+// key.
+func keyFingerprint(key *hdkeychain.ExtendedKey) (uint32, error) {
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		str := "failed to convert master public key"
+		return 0, managerError(ErrKeyChain, str, err)
+	}
+
+	hash := btcutil.Hash160(pubKey.SerializeCompressed())
+	return binary.LittleEndian.Uint32(hash[:4]), nil
+}
+
+// masterRootPrivKey returns the decrypted master root private key of the
+// manager.
+//
+// This function MUST be called with the manager unlocked.
+func (m *Manager) masterRootPrivKey(ns walletdb.ReadBucket) (
+	*hdkeychain.ExtendedKey, error) {
+
+	masterRootPrivEnc, _ := fetchMasterHDKeys(ns)
+
+	// If the master root private key isn't found within the database, we
+	// need to bail here as nothing can be derived from it.
+	if masterRootPrivEnc == nil {
+		return nil, managerError(ErrWatchingOnly, "", nil)
+	}
+
+	// Before we can derive any keys using this key, we'll need to fully
+	// decrypt it.
+	serializedMasterRootPriv, err := m.cryptoKeyPriv.Decrypt(
+		masterRootPrivEnc,
+	)
+	if err != nil {
+		str := fmt.Sprintf("failed to decrypt master root " +
+			"serialized private key")
+		return nil, managerError(ErrLocked, str, err)
+	}
+
+	// Now that we know the root priv is within the database, we'll decode
+	// it into a usable object.
+	rootPriv, err := hdkeychain.NewKeyFromString(
+		string(serializedMasterRootPriv),
+	)
+	zero.Bytes(serializedMasterRootPriv)
+	if err != nil {
+		str := fmt.Sprintf("failed to create master extended " +
+			"private key")
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	return rootPriv, nil
+}
+
 // lock performs a best try effort to remove and zero all secret keys associated
 // with the address manager.
 //
@@ -523,35 +687,10 @@ func (m *Manager) NewScopedKeyManager(ns walletdb.ReadWriteBucket,
 		// Note that the path to the coin type is requires hardened
 		// derivation, therefore this can only be done if the wallet's
 		// root key hasn't been neutered.
-		masterRootPrivEnc, _ := fetchMasterHDKeys(ns)
-
-		// If the master root private key isn't found within the
-		// database, but we need to bail here as we can't create the
-		// cointype key without the master root private key.
-		if masterRootPrivEnc == nil {
-			return nil, managerError(ErrWatchingOnly, "", nil)
-		}
-
-		// Before we can derive any new scoped managers using this
-		// key, we'll need to fully decrypt it.
-		serializedMasterRootPriv, err :=
-			m.cryptoKeyPriv.Decrypt(masterRootPrivEnc)
-		if err != nil {
-			str := fmt.Sprintf("failed to decrypt master root " +
-				"serialized private key")
-			return nil, managerError(ErrLocked, str, err)
-		}
-
-		// Now that we know the root priv is within the database,
-		// we'll decode it into a usable object.
-		rootPriv, err = hdkeychain.NewKeyFromString(
-			string(serializedMasterRootPriv),
-		)
-		zero.Bytes(serializedMasterRootPriv)
+		var err error
+		rootPriv, err = m.masterRootPrivKey(ns)
 		if err != nil {
-			str := fmt.Sprintf("failed to create master extended " +
-				"private key")
-			return nil, managerError(ErrKeyChain, str, err)
+			return nil, err
 		}
 	}
 
@@ -886,6 +1025,81 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
+	apply, err := m.changePassphrase(
+		ns, oldPassphrase, newPassphrase, private, config,
+	)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// UpgradeKDF re-wraps the master public and private keys with new passphrase
+// keys derived using the options when the current ones were derived with a
+// different key derivation function, or with weaker parameters.  The
+// passphrases themselves are unchanged.  It returns whether any of the master
+// keys were re-wrapped.
+//
+// This is intended to be called with the private passphrase the manager was
+// just unlocked with, so that existing wallets move to stronger parameters
+// without the user having to change their passphrases.  The private master key
+// of a watching-only address manager is left alone.  The re-wrapped keys
+// replace those in memory once the transaction of ns is committed, so the
+// manager is unchanged if the transaction fails.
+func (m *Manager) UpgradeKDF(ns walletdb.ReadWriteBucket, pubPassphrase,
+	privPassphrase []byte, config *ScryptOptions) (bool, error) {
+
+	watchOnly := m.WatchOnly()
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var applies []func()
+	if kdfUpgradeNeeded(&m.masterKeyPub.Parameters, config) {
+		apply, err := m.changePassphrase(
+			ns, pubPassphrase, pubPassphrase, false, config,
+		)
+		if err != nil {
+			return false, err
+		}
+		applies = append(applies, apply)
+	}
+
+	if !watchOnly && kdfUpgradeNeeded(&m.masterKeyPriv.Parameters, config) {
+		apply, err := m.changePassphrase(
+			ns, privPassphrase, privPassphrase, true, config,
+		)
+		if err != nil {
+			return false, err
+		}
+		applies = append(applies, apply)
+	}
+
+	if len(applies) == 0 {
+		return false, nil
+	}
+	ns.Tx().OnCommit(func() {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		for _, apply := range applies {
+			apply()
+		}
+	})
+	return true, nil
+}
+
+// changePassphrase changes either the public or private passphrase in the
+// database.  See ChangePassphrase.  It returns the function replacing the
+// master key in memory, which must be called with the manager lock held for
+// writes once the database has been updated.
+//
+// This function MUST be called with the manager lock held for writes.
+func (m *Manager) changePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
+	newPassphrase []byte, private bool, config *ScryptOptions) (func(),
+	error) {
+
 	// Ensure the provided old passphrase is correct.  This check is done
 	// using a copy of the appropriate master key depending on the private
 	// flag to ensure the current state is not altered.  The temp key is
@@ -903,11 +1117,11 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 		if err == snacl.ErrInvalidPassword {
 			str := fmt.Sprintf("invalid passphrase for %s master "+
 				"key", keyName)
-			return managerError(ErrWrongPassphrase, str, nil)
+			return nil, managerError(ErrWrongPassphrase, str, nil)
 		}
 
 		str := fmt.Sprintf("failed to derive %s master key", keyName)
-		return managerError(ErrCrypto, str, err)
+		return nil, managerError(ErrCrypto, str, err)
 	}
 	defer secretKey.Zero()
 
@@ -916,10 +1130,11 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 	newMasterKey, err := newSecretKey(&newPassphrase, config)
 	if err != nil {
 		str := "failed to create new master private key"
-		return managerError(ErrCrypto, str, err)
+		return nil, managerError(ErrCrypto, str, err)
 	}
 	newKeyParams := newMasterKey.Marshal()
 
+	var apply func()
 	if private {
 		// Technically, the locked state could be checked here to only
 		// do the decrypts when the address manager is locked as the
@@ -934,7 +1149,7 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 		_, err := rand.Read(passphraseSalt[:])
 		if err != nil {
 			str := "failed to read random source for passhprase salt"
-			return managerError(ErrCrypto, str, err)
+			return nil, managerError(ErrCrypto, str, err)
 		}
 
 		// Re-encrypt the crypto private key using the new master
@@ -942,13 +1157,13 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 		decPriv, err := secretKey.Decrypt(m.cryptoKeyPrivEncrypted)
 		if err != nil {
 			str := "failed to decrypt crypto private key"
-			return managerError(ErrCrypto, str, err)
+			return nil, managerError(ErrCrypto, str, err)
 		}
 		encPriv, err := newMasterKey.Encrypt(decPriv)
 		zero.Bytes(decPriv)
 		if err != nil {
 			str := "failed to encrypt crypto private key"
-			return managerError(ErrCrypto, str, err)
+			return nil, managerError(ErrCrypto, str, err)
 		}
 
 		// Re-encrypt the crypto script key using the new master
@@ -956,13 +1171,13 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 		decScript, err := secretKey.Decrypt(m.cryptoKeyScriptEncrypted)
 		if err != nil {
 			str := "failed to decrypt crypto script key"
-			return managerError(ErrCrypto, str, err)
+			return nil, managerError(ErrCrypto, str, err)
 		}
 		encScript, err := newMasterKey.Encrypt(decScript)
 		zero.Bytes(decScript)
 		if err != nil {
 			str := "failed to encrypt crypto script key"
-			return managerError(ErrCrypto, str, err)
+			return nil, managerError(ErrCrypto, str, err)
 		}
 
 		// When the manager is locked, ensure the new clear text master
@@ -983,50 +1198,54 @@ func (m *Manager) ChangePassphrase(ns walletdb.ReadWriteBucket, oldPassphrase,
 		// transaction.
 		err = putCryptoKeys(ns, nil, encPriv, encScript)
 		if err != nil {
-			return maybeConvertDbError(err)
+			return nil, maybeConvertDbError(err)
 		}
 
 		err = putMasterKeyParams(ns, nil, newKeyParams)
 		if err != nil {
-			return maybeConvertDbError(err)
+			return nil, maybeConvertDbError(err)
 		}
 
-		// Now that the db has been successfully updated, clear the old
-		// key and set the new one.
-		copy(m.cryptoKeyPrivEncrypted, encPriv)
-		copy(m.cryptoKeyScriptEncrypted, encScript)
-		m.masterKeyPriv.Zero() // Clear the old key.
-		m.masterKeyPriv = newMasterKey
-		m.privPassphraseSalt = passphraseSalt
-		m.hashedPrivPassphrase = hashedPassphrase
+		// Now that the db has been successfully updated, the old key
+		// can be cleared and the new one set.
+		apply = func() {
+			copy(m.cryptoKeyPrivEncrypted, encPriv)
+			copy(m.cryptoKeyScriptEncrypted, encScript)
+			m.masterKeyPriv.Zero() // Clear the old key.
+			m.masterKeyPriv = newMasterKey
+			m.privPassphraseSalt = passphraseSalt
+			m.hashedPrivPassphrase = hashedPassphrase
+		}
 	} else {
 		// Re-encrypt the crypto public key using the new master public
 		// key.
 		encryptedPub, err := newMasterKey.Encrypt(m.cryptoKeyPub.Bytes())
 		if err != nil {
 			str := "failed to encrypt crypto public key"
-			return managerError(ErrCrypto, str, err)
+			return nil, managerError(ErrCrypto, str, err)
 		}
 
 		// Save the new keys and params to the the db in a single
 		// transaction.
 		err = putCryptoKeys(ns, encryptedPub, nil, nil)
 		if err != nil {
-			return maybeConvertDbError(err)
+			return nil, maybeConvertDbError(err)
 		}
 
 		err = putMasterKeyParams(ns, newKeyParams, nil)
 		if err != nil {
-			return maybeConvertDbError(err)
+			return nil, maybeConvertDbError(err)
 		}
 
-		// Now that the db has been successfully updated, clear the old
-		// key and set the new one.
-		m.masterKeyPub.Zero()
-		m.masterKeyPub = newMasterKey
+		// Now that the db has been successfully updated, the old key
+		// can be cleared and the new one set.
+		apply = func() {
+			m.masterKeyPub.Zero()
+			m.masterKeyPub = newMasterKey
+		}
 	}
 
-	return nil
+	return apply, nil
 }
 
 // ConvertToWatchingOnly converts the current address manager to a locked
@@ -1202,6 +1421,12 @@ func (m *Manager) Unlock(ns walletdb.ReadBucket, passphrase []byte) error {
 	// extended keys.
 	for _, manager := range m.scopedManagers {
 		for account, acctInfo := range manager.acctInfo {
+			// Accounts without private keys, such as multisig
+			// accounts the wallet isn't a cosigner of, are skipped.
+			if len(acctInfo.acctKeyEncrypted) == 0 {
+				continue
+			}
+
 			decrypted, err := m.cryptoKeyPriv.Decrypt(acctInfo.acctKeyEncrypted)
 			if err != nil {
 				m.lock()
@@ -23,6 +23,7 @@ import (
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/require"
 )
@@ -3292,3 +3293,347 @@ func TestManagedAddressValidation(t *testing.T) {
 	}
 
 }
+
+// TestMasterKeyFingerprint tests that the fingerprint of the root key of the
+// manager is returned.
+func TestMasterKeyFingerprint(t *testing.T) {
+	t.Parallel()
+
+	teardown, db, mgr := setupManager(t)
+	defer teardown()
+
+	rootPubKey, err := rootKey.ECPubKey()
+	require.NoError(t, err)
+	hash := btcutil.Hash160(rootPubKey.SerializeCompressed())
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		fingerprint, err := mgr.MasterKeyFingerprint(ns)
+		require.NoError(t, err)
+		require.Equal(t, hash[:4], []byte{
+			byte(fingerprint), byte(fingerprint >> 8),
+			byte(fingerprint >> 16), byte(fingerprint >> 24),
+		})
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestMultisigAccount tests that the addresses of multisig accounts pay to the
+// sorted multisig script of the keys of their cosigners, that the wallet signs
+// as one of the cosigners, and that the addresses following those issued are
+// stored up to the gap limit.
+func TestMultisigAccount(t *testing.T) {
+	t.Parallel()
+
+	teardown, db, mgr := setupManager(t)
+	defer teardown()
+
+	// The other cosigner derives its key along the same path from another
+	// root key.
+	otherRoot, err := hdkeychain.NewMaster(
+		bytes.Repeat([]byte{2}, hdkeychain.RecommendedSeedLen),
+		&chaincfg.MainNetParams,
+	)
+	require.NoError(t, err)
+	otherFingerprint, err := keyFingerprint(otherRoot)
+	require.NoError(t, err)
+	otherPath := multisigDerivationPath(0, 0, MultisigWitnessScript)
+	otherKey := otherRoot
+	for _, step := range otherPath {
+		otherKey, err = otherKey.Derive(step)
+		require.NoError(t, err)
+	}
+	otherPub, err := otherKey.Neuter()
+	require.NoError(t, err)
+	other := &Cosigner{
+		AccountPubKey:        otherPub,
+		MasterKeyFingerprint: otherFingerprint,
+		DerivationPath:       otherPath,
+	}
+
+	scopedMgr, err := mgr.FetchScopedKeyManager(KeyScopeBIP0084)
+	require.NoError(t, err)
+
+	var (
+		own     *Cosigner
+		account uint32
+	)
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		// The manager must be unlocked to derive its cosigner key.
+		_, err := mgr.MultisigCosigner(ns, 0, MultisigWitnessScript)
+		require.True(t, IsError(err, ErrLocked))
+		require.NoError(t, mgr.Unlock(ns, privPassphrase))
+		own, err = mgr.MultisigCosigner(ns, 0, MultisigWitnessScript)
+		require.NoError(t, err)
+		require.Equal(t, otherPath, own.DerivationPath)
+
+		// Multisig accounts are created in the key scope of their
+		// script type, with a valid threshold.
+		cosigners := []*Cosigner{own, other}
+		_, err = scopedMgr.NewMultisigAccount(
+			ns, "multisig", 3, MultisigWitnessScript, cosigners,
+		)
+		require.True(t, IsError(err, ErrInvalidAccount))
+		_, err = scopedMgr.NewMultisigAccount(
+			ns, "multisig", 2, MultisigNestedWitnessScript,
+			cosigners,
+		)
+		require.True(t, IsError(err, ErrInvalidAccount))
+		_, err = scopedMgr.NewMultisigAccount(
+			ns, "multisig", 2, MultisigWitnessScript,
+			[]*Cosigner{own, own},
+		)
+		require.True(t, IsError(err, ErrInvalidAccount))
+
+		account, err = scopedMgr.NewMultisigAccount(
+			ns, "multisig", 2, MultisigWitnessScript, cosigners,
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	// expectedScript returns the sorted multisig script of the keys of the
+	// cosigners at the index of the branch.
+	expectedScript := func(branch, index uint32) []byte {
+		var keys []*btcutil.AddressPubKey
+		for _, c := range []*Cosigner{own, other} {
+			branchKey, err := c.AccountPubKey.Derive(branch)
+			require.NoError(t, err)
+			key, err := branchKey.Derive(index)
+			require.NoError(t, err)
+			pubKey, err := key.ECPubKey()
+			require.NoError(t, err)
+			addr, err := btcutil.NewAddressPubKey(
+				pubKey.SerializeCompressed(),
+				&chaincfg.MainNetParams,
+			)
+			require.NoError(t, err)
+			keys = append(keys, addr)
+		}
+		if bytes.Compare(keys[0].ScriptAddress(),
+			keys[1].ScriptAddress()) > 0 {
+
+			keys[0], keys[1] = keys[1], keys[0]
+		}
+		script, err := txscript.MultiSigScript(keys, 2)
+		require.NoError(t, err)
+		return script
+	}
+
+	// addressAt returns the address paying to the expected script.
+	addressAt := func(branch, index uint32) btcutil.Address {
+		scriptHash := sha256.Sum256(expectedScript(branch, index))
+		addr, err := btcutil.NewAddressWitnessScriptHash(
+			scriptHash[:], &chaincfg.MainNetParams,
+		)
+		require.NoError(t, err)
+		return addr
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+		// The addresses up to the gap limit are stored.
+		_, err := scopedMgr.Address(
+			ns, addressAt(ExternalBranch, MultisigGapLimit-1),
+		)
+		require.NoError(t, err)
+		_, err = scopedMgr.Address(
+			ns, addressAt(InternalBranch, MultisigGapLimit-1),
+		)
+		require.NoError(t, err)
+		_, err = scopedMgr.Address(
+			ns, addressAt(ExternalBranch, MultisigGapLimit),
+		)
+		require.True(t, IsError(err, ErrAddressNotFound))
+
+		addrs, err := scopedMgr.NextExternalAddresses(ns, account, 1)
+		require.NoError(t, err)
+		require.Len(t, addrs, 1)
+		msAddr, ok := addrs[0].(ManagedMultisigAddress)
+		require.True(t, ok)
+		require.Equal(t, addressAt(ExternalBranch, 0), msAddr.Address())
+		require.Equal(t, WitnessScript, msAddr.AddrType())
+		script, err := msAddr.Script()
+		require.NoError(t, err)
+		require.Equal(t, expectedScript(ExternalBranch, 0), script)
+		require.Equal(t, 2, msAddr.Threshold())
+		require.Nil(t, msAddr.RedeemScript())
+
+		// The private key of the wallet as a cosigner matches one of
+		// the keys of the address.
+		privKey, err := msAddr.PrivKey()
+		require.NoError(t, err)
+		var found bool
+		for _, key := range msAddr.Keys() {
+			found = found || key.PubKey.IsEqual(privKey.PubKey())
+			require.Len(t, key.DerivationPath, 6)
+		}
+		require.True(t, found)
+
+		// Addresses found to be used extend the stored addresses.
+		addrs, err = scopedMgr.ExtendMultisigAddresses(
+			ns, account, ExternalBranch, 30,
+		)
+		require.NoError(t, err)
+		require.Len(t, addrs, 30)
+		_, err = scopedMgr.Address(
+			ns, addressAt(ExternalBranch, 30+MultisigGapLimit),
+		)
+		require.NoError(t, err)
+
+		isWatchOnly, err := scopedMgr.IsWatchOnlyAccount(ns, account)
+		require.NoError(t, err)
+		require.True(t, isWatchOnly)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The account is loaded back from the database.
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		reopened, err := Open(ns, pubPassphrase, &chaincfg.MainNetParams)
+		require.NoError(t, err)
+		defer reopened.Close()
+
+		scopedMgr, err := reopened.FetchScopedKeyManager(KeyScopeBIP0084)
+		require.NoError(t, err)
+		props, err := scopedMgr.AccountProperties(ns, account)
+		require.NoError(t, err)
+		require.Equal(t, "multisig", props.AccountName)
+		require.Equal(t, uint32(31), props.ExternalKeyCount)
+		require.Equal(t, uint32(0), props.InternalKeyCount)
+		require.NotNil(t, props.Multisig)
+		require.Equal(t, 2, props.Multisig.Threshold)
+		require.Len(t, props.Multisig.Cosigners, 2)
+		require.Equal(t, own.AccountPubKey.String(),
+			props.AccountPubKey.String())
+
+		managedAddr, err := scopedMgr.Address(
+			ns, addressAt(InternalBranch, 3),
+		)
+		require.NoError(t, err)
+		require.True(t, managedAddr.Internal())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestUpgradeKDF tests that the master keys are re-wrapped with passphrase keys
+// derived using stronger parameters, and that the manager can still be opened
+// and unlocked with the same passphrases afterwards.
+func TestUpgradeKDF(t *testing.T) {
+	t.Parallel()
+
+	teardown, db, mgr := setupManager(t)
+	defer teardown()
+
+	argon2idOptions := &ScryptOptions{
+		KDF:     snacl.KDFArgon2id,
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+	}
+	upgradeKDF := func(privPass []byte, config *ScryptOptions) (bool,
+		error) {
+
+		var upgraded bool
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+			var err error
+			upgraded, err = mgr.UpgradeKDF(
+				ns, pubPassphrase, privPass, config,
+			)
+			return err
+		})
+		return upgraded, err
+	}
+
+	// The keys already use these parameters.
+	upgraded, err := upgradeKDF(privPassphrase, fastScrypt)
+	require.NoError(t, err)
+	require.False(t, upgraded)
+
+	// Parameters which are stronger in one respect but weaker in another
+	// don't upgrade the keys.
+	mixedScrypt := *fastScrypt
+	mixedScrypt.N *= 2
+	mixedScrypt.R--
+	upgraded, err = upgradeKDF(privPassphrase, &mixedScrypt)
+	require.NoError(t, err)
+	require.False(t, upgraded)
+
+	// The private passphrase must be correct for its key to be re-wrapped,
+	// and the keys in memory are left alone when the upgrade fails.
+	_, err = upgradeKDF([]byte("bogus"), argon2idOptions)
+	require.Error(t, err)
+	require.True(t, IsError(err, ErrWrongPassphrase))
+	require.Equal(t, snacl.KDFScrypt, mgr.masterKeyPub.Parameters.KDF)
+
+	// Nor are they replaced when the transaction of the upgrade fails
+	// afterwards.
+	errRollback := errors.New("rollback")
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		_, err := mgr.UpgradeKDF(
+			ns, pubPassphrase, privPassphrase, argon2idOptions,
+		)
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	require.Equal(t, snacl.KDFScrypt, mgr.masterKeyPub.Parameters.KDF)
+	require.Equal(t, snacl.KDFScrypt, mgr.masterKeyPriv.Parameters.KDF)
+
+	upgraded, err = upgradeKDF(privPassphrase, argon2idOptions)
+	require.NoError(t, err)
+	require.True(t, upgraded)
+
+	upgraded, err = upgradeKDF(privPassphrase, argon2idOptions)
+	require.NoError(t, err)
+	require.False(t, upgraded)
+
+	// Argon2id keys are not replaced by scrypt ones.
+	upgraded, err = upgradeKDF(privPassphrase, fastScrypt)
+	require.NoError(t, err)
+	require.False(t, upgraded)
+
+	mixed := *argon2idOptions
+	mixed.Time++
+	mixed.Memory--
+	upgraded, err = upgradeKDF(privPassphrase, &mixed)
+	require.NoError(t, err)
+	require.False(t, upgraded)
+
+	// Stronger parameters of the same function upgrade the keys again.
+	stronger := *argon2idOptions
+	stronger.Time++
+	upgraded, err = upgradeKDF(privPassphrase, &stronger)
+	require.NoError(t, err)
+	require.True(t, upgraded)
+
+	// The upgraded keys are persisted, and still derived from the same
+	// passphrases.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		reopened, err := Open(ns, pubPassphrase, &chaincfg.MainNetParams)
+		if err != nil {
+			return err
+		}
+		defer reopened.Close()
+
+		for _, params := range []snacl.Parameters{
+			reopened.masterKeyPub.Parameters,
+			reopened.masterKeyPriv.Parameters,
+		} {
+			require.Equal(t, snacl.KDFArgon2id, params.KDF)
+			require.Equal(t, stronger.Time, params.Time)
+		}
+
+		return reopened.Unlock(ns, privPassphrase)
+	})
+	require.NoError(t, err)
+}
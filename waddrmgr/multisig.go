@@ -0,0 +1,869 @@
+package waddrmgr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// MultisigScriptType is the type of script the addresses of a multisig account
+// pay to.
+type MultisigScriptType uint8
+
+const (
+	// MultisigNestedWitnessScript is used for multisig accounts paying to
+	// p2wsh scripts nested within p2sh outputs, as with the BIP-0048
+	// script type 1'.
+	MultisigNestedWitnessScript MultisigScriptType = 1
+
+	// MultisigWitnessScript is used for multisig accounts paying to p2wsh
+	// scripts, as with the BIP-0048 script type 2'.
+	MultisigWitnessScript MultisigScriptType = 2
+)
+
+const (
+	// MaxMultisigKeys is the maximum number of cosigners of a multisig
+	// account.
+	MaxMultisigKeys = txscript.MaxPubKeysPerMultiSig
+
+	// MultisigGapLimit is the number of addresses of each branch of a
+	// multisig account stored ahead of the last issued or used one, so
+	// that funds sent to addresses issued by other cosigners are found.
+	MultisigGapLimit = 20
+
+	// multisigNoOwnKey is the own key index stored for multisig accounts
+	// the wallet isn't a cosigner of.
+	multisigNoOwnKey = 0xff
+)
+
+// KeyScope returns the key scope the addresses of multisig accounts of the
+// script type are found in, which is the default key scope paying to the same
+// kind of outputs.
+func (t MultisigScriptType) KeyScope() (KeyScope, error) {
+	switch t {
+	case MultisigNestedWitnessScript:
+		return KeyScopeBIP0049Plus, nil
+	case MultisigWitnessScript:
+		return KeyScopeBIP0084, nil
+	}
+	str := fmt.Sprintf("unsupported multisig script type %d", t)
+	return KeyScope{}, managerError(ErrInvalidAccount, str, nil)
+}
+
+// Cosigner is a cosigner of a multisig account, identified by the extended
+// public key of its account.
+type Cosigner struct {
+	// AccountPubKey is the extended public key of the account of the
+	// cosigner, from which its keys are derived along the external and
+	// internal branches.
+	AccountPubKey *hdkeychain.ExtendedKey
+
+	// MasterKeyFingerprint is the fingerprint of the root key the account
+	// key is derived from, as found in PSBT derivations.
+	MasterKeyFingerprint uint32
+
+	// DerivationPath is the derivation path of the account key from the
+	// root key, such as m/48'/coin'/account'/script' for BIP-0048.
+	DerivationPath []uint32
+}
+
+// MultisigProperties holds the threshold and cosigners of a multisig account.
+type MultisigProperties struct {
+	// Threshold is the number of signatures required to spend from the
+	// account.
+	Threshold int
+
+	// ScriptType is the type of script the addresses of the account pay
+	// to.
+	ScriptType MultisigScriptType
+
+	// Cosigners are the cosigners of the account.
+	Cosigners []*Cosigner
+}
+
+// MultisigKey is the key of a cosigner of a multisig address.
+type MultisigKey struct {
+	// PubKey is the public key of the cosigner.
+	PubKey *btcec.PublicKey
+
+	// MasterKeyFingerprint is the fingerprint of the root key of the
+	// cosigner.
+	MasterKeyFingerprint uint32
+
+	// DerivationPath is the derivation path of the key from the root key
+	// of the cosigner.
+	DerivationPath []uint32
+}
+
+// ManagedMultisigAddress extends ManagedScriptAddress and represents an
+// address of a multisig account, paying to a sorted multisig witness script.
+// The script returned by Script is the witness script.
+type ManagedMultisigAddress interface {
+	ManagedScriptAddress
+
+	// Threshold returns the number of signatures required to spend from
+	// the address.
+	Threshold() int
+
+	// Keys returns the keys of the cosigners, in the order of the script.
+	Keys() []*MultisigKey
+
+	// RedeemScript returns the p2sh redeem script of nested addresses, or
+	// nil for p2wsh addresses.
+	RedeemScript() []byte
+
+	// DerivationIndex returns the branch and index the keys of the address
+	// are derived at.
+	DerivationIndex() (uint32, uint32)
+
+	// PrivKey returns the private key of the wallet as one of the
+	// cosigners of the address.  It fails if the address manager is
+	// watching-only or locked, or the wallet isn't one of the cosigners.
+	PrivKey() (*btcec.PrivateKey, error)
+}
+
+// multisigAddress represents an address of a multisig account.
+type multisigAddress struct {
+	manager       *ScopedKeyManager
+	account       uint32
+	branch        uint32
+	index         uint32
+	address       btcutil.Address
+	addrType      AddressType
+	threshold     int
+	keys          []*MultisigKey
+	witnessScript []byte
+	redeemScript  []byte
+}
+
+// Enforce multisigAddress satisfies the ManagedMultisigAddress interface.
+var _ ManagedMultisigAddress = (*multisigAddress)(nil)
+
+// InternalAccount returns the internal account number of the address.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) InternalAccount() uint32 {
+	return a.account
+}
+
+// AddrType returns the address type of the managed address.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) AddrType() AddressType {
+	return a.addrType
+}
+
+// Address returns the btcutil.Address which represents the managed address.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Address() btcutil.Address {
+	return a.address
+}
+
+// AddrHash returns the script hash for the address.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) AddrHash() []byte {
+	return a.address.ScriptAddress()
+}
+
+// Imported always returns false since multisig addresses are derived.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Imported() bool {
+	return false
+}
+
+// Internal returns true if the address was created for internal use such as a
+// change output of a transaction.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Internal() bool {
+	return a.branch == InternalBranch
+}
+
+// Compressed returns true since multisig addresses are made of compressed
+// keys.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Compressed() bool {
+	return true
+}
+
+// Used returns true if the address has been used in a transaction.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Used(ns walletdb.ReadBucket) bool {
+	return a.manager.fetchUsed(ns, a.AddrHash())
+}
+
+// Script returns the witness script of the address.
+//
+// This is part of the ManagedScriptAddress interface implementation.
+func (a *multisigAddress) Script() ([]byte, error) {
+	return a.witnessScript, nil
+}
+
+// Threshold returns the number of signatures required to spend from the
+// address.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) Threshold() int {
+	return a.threshold
+}
+
+// Keys returns the keys of the cosigners, in the order of the script.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) Keys() []*MultisigKey {
+	return a.keys
+}
+
+// RedeemScript returns the p2sh redeem script of nested addresses, or nil for
+// p2wsh addresses.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) RedeemScript() []byte {
+	return a.redeemScript
+}
+
+// DerivationIndex returns the branch and index the keys of the address are
+// derived at.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) DerivationIndex() (uint32, uint32) {
+	return a.branch, a.index
+}
+
+// PrivKey returns the private key of the wallet as one of the cosigners of the
+// address.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) PrivKey() (*btcec.PrivateKey, error) {
+	// No private keys are available for a watching-only address manager.
+	if a.manager.rootManager.WatchOnly() {
+		return nil, managerError(ErrWatchingOnly, errWatchingOnly, nil)
+	}
+
+	a.manager.mtx.Lock()
+	defer a.manager.mtx.Unlock()
+
+	// Account manager must be unlocked to derive the private key.
+	if a.manager.rootManager.IsLocked() {
+		return nil, managerError(ErrLocked, errLocked, nil)
+	}
+
+	acctInfo, ok := a.manager.acctInfo[a.account]
+	if !ok || acctInfo.acctKeyPriv == nil {
+		str := fmt.Sprintf("wallet is not a cosigner of multisig "+
+			"account %d", a.account)
+		return nil, managerError(ErrWatchingOnly, str, nil)
+	}
+
+	branchKey, err := acctInfo.acctKeyPriv.Derive(a.branch)
+	if err != nil {
+		str := fmt.Sprintf("failed to derive extended key branch %d",
+			a.branch)
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	key, err := branchKey.Derive(a.index)
+	branchKey.Zero()
+	if err != nil {
+		str := fmt.Sprintf("failed to derive child extended key -- "+
+			"branch %d, child %d", a.branch, a.index)
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	defer key.Zero()
+
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		str := "failed to convert private key"
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	return privKey, nil
+}
+
+// multisigDerivationPath returns the BIP-0048 derivation path of the account
+// key of a multisig account: m/48'/coin'/account'/script'.
+func multisigDerivationPath(coin, account uint32,
+	scriptType MultisigScriptType) []uint32 {
+
+	return []uint32{
+		48 + hdkeychain.HardenedKeyStart,
+		coin + hdkeychain.HardenedKeyStart,
+		account + hdkeychain.HardenedKeyStart,
+		uint32(scriptType) + hdkeychain.HardenedKeyStart,
+	}
+}
+
+// MultisigCosigner returns the wallet as a cosigner of multisig accounts of the
+// script type, with its BIP-0048 account key at the account number provided.
+// The cosigner is shared with the other cosigners to create the account.
+//
+// The manager must be unlocked, as the account key is derived from the root
+// private key.
+func (m *Manager) MultisigCosigner(ns walletdb.ReadBucket, account uint32,
+	scriptType MultisigScriptType) (*Cosigner, error) {
+
+	if _, err := scriptType.KeyScope(); err != nil {
+		return nil, err
+	}
+	if account > MaxAccountNum {
+		err := managerError(ErrAccountNumTooHigh, errAcctTooHigh, nil)
+		return nil, err
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if m.WatchOnly() {
+		return nil, managerError(ErrWatchingOnly, errWatchingOnly, nil)
+	}
+	if m.IsLocked() {
+		return nil, managerError(ErrLocked, errLocked, nil)
+	}
+
+	rootPriv, err := m.masterRootPrivKey(ns)
+	if err != nil {
+		return nil, err
+	}
+	defer rootPriv.Zero()
+	fingerprint, err := keyFingerprint(rootPriv)
+	if err != nil {
+		return nil, err
+	}
+
+	path := multisigDerivationPath(
+		m.chainParams.HDCoinType, account, scriptType,
+	)
+	key := rootPriv
+	for _, step := range path {
+		key, err = key.Derive(step)
+		if err != nil {
+			str := "failed to derive multisig account key"
+			return nil, managerError(ErrKeyChain, str, err)
+		}
+	}
+	acctKeyPub, err := key.Neuter()
+	if err != nil {
+		str := "failed to convert public key for account"
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+
+	return &Cosigner{
+		AccountPubKey:        acctKeyPub,
+		MasterKeyFingerprint: fingerprint,
+		DerivationPath:       path,
+	}, nil
+}
+
+// SameExtendedKey returns whether both extended keys are made of the same
+// public key and chain code, regardless of their versions.
+func SameExtendedKey(a, b *hdkeychain.ExtendedKey) (bool, error) {
+	aPub, err := a.ECPubKey()
+	if err != nil {
+		return false, err
+	}
+	bPub, err := b.ECPubKey()
+	if err != nil {
+		return false, err
+	}
+	return aPub.IsEqual(bPub) && bytes.Equal(a.ChainCode(), b.ChainCode()),
+		nil
+}
+
+// ownMultisigKey returns the index of the cosigner whose account key is
+// derived from the root key of the manager, along with the account private
+// key, or -1 if the wallet isn't one of the cosigners.
+//
+// This function MUST be called with the manager unlocked.
+func (s *ScopedKeyManager) ownMultisigKey(ns walletdb.ReadBucket,
+	cosigners []*Cosigner) (int, *hdkeychain.ExtendedKey, error) {
+
+	rootPriv, err := s.rootManager.masterRootPrivKey(ns)
+	if err != nil {
+		return -1, nil, err
+	}
+	defer rootPriv.Zero()
+	fingerprint, err := keyFingerprint(rootPriv)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	for i, cosigner := range cosigners {
+		if cosigner.MasterKeyFingerprint != fingerprint ||
+			len(cosigner.DerivationPath) == 0 {
+
+			continue
+		}
+
+		key := rootPriv
+		for _, step := range cosigner.DerivationPath {
+			key, err = key.Derive(step)
+			if err != nil {
+				str := "failed to derive multisig account key"
+				return -1, nil, managerError(ErrKeyChain, str, err)
+			}
+		}
+		same, err := SameExtendedKey(key, cosigner.AccountPubKey)
+		if err != nil {
+			str := "failed to convert multisig account key"
+			return -1, nil, managerError(ErrKeyChain, str, err)
+		}
+		if same {
+			return i, key, nil
+		}
+		key.Zero()
+	}
+	return -1, nil, nil
+}
+
+// NewMultisigAccount creates a new multisig account requiring threshold
+// signatures of the cosigners, paying to scripts of the type provided.  The
+// addresses of the account pay to the sorted multisig script of the keys of
+// the cosigners, derived like the sortedmulti descriptor of their account
+// keys.  The scope of the manager must be the key scope of the script type.
+//
+// If one of the cosigners is derived from the root key of the manager, such as
+// those returned by MultisigCosigner, the wallet signs for it, in which case
+// the manager must be unlocked.  The addresses of both branches of the account
+// up to the gap limit are stored, so that funds sent to them are found.
+func (s *ScopedKeyManager) NewMultisigAccount(ns walletdb.ReadWriteBucket,
+	name string, threshold int, scriptType MultisigScriptType,
+	cosigners []*Cosigner) (uint32, error) {
+
+	scope, err := scriptType.KeyScope()
+	if err != nil {
+		return 0, err
+	}
+	if scope != s.scope {
+		str := fmt.Sprintf("multisig accounts of script type %d "+
+			"belong to key scope %v", scriptType, scope)
+		return 0, managerError(ErrInvalidAccount, str, nil)
+	}
+	if len(cosigners) == 0 || len(cosigners) > MaxMultisigKeys {
+		str := fmt.Sprintf("invalid number of cosigners %d",
+			len(cosigners))
+		return 0, managerError(ErrInvalidAccount, str, nil)
+	}
+	if threshold < 1 || threshold > len(cosigners) {
+		str := fmt.Sprintf("invalid threshold %d of %d cosigners",
+			threshold, len(cosigners))
+		return 0, managerError(ErrInvalidAccount, str, nil)
+	}
+	for i, cosigner := range cosigners {
+		if cosigner.AccountPubKey.IsPrivate() {
+			str := fmt.Sprintf("key of cosigner %d is private", i)
+			return 0, managerError(ErrInvalidKeyType, str, nil)
+		}
+		for _, other := range cosigners[:i] {
+			same, err := SameExtendedKey(
+				cosigner.AccountPubKey, other.AccountPubKey,
+			)
+			if err != nil {
+				str := "invalid cosigner key"
+				return 0, managerError(ErrKeyChain, str, err)
+			}
+			if same {
+				str := fmt.Sprintf("duplicate key of cosigner "+
+					"%d", i)
+				return 0, managerError(ErrInvalidAccount, str, nil)
+			}
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// Validate the account name.
+	if err := ValidateAccountName(name); err != nil {
+		return 0, err
+	}
+
+	// Check that account with the same name does not exist
+	_, err = s.lookupAccount(ns, name)
+	if err == nil {
+		str := "account with the same name already exists"
+		return 0, managerError(ErrDuplicateAccount, str, err)
+	}
+
+	row := &dbMultisigAccountRow{
+		threshold:   uint8(threshold),
+		scriptType:  scriptType,
+		cosigners:   make([]dbMultisigCosigner, 0, len(cosigners)),
+		ownKeyIndex: multisigNoOwnKey,
+		name:        name,
+	}
+
+	// Find the key of the wallet among the cosigners, unless it has no
+	// private keys.
+	if !s.rootManager.WatchOnly() {
+		if s.rootManager.IsLocked() {
+			return 0, managerError(ErrLocked, errLocked, nil)
+		}
+
+		ownIndex, acctKeyPriv, err := s.ownMultisigKey(ns, cosigners)
+		if err != nil {
+			return 0, err
+		}
+		if ownIndex >= 0 {
+			row.ownKeyIndex = uint8(ownIndex)
+			row.privKeyEncrypted, err = s.rootManager.cryptoKeyPriv.Encrypt(
+				[]byte(acctKeyPriv.String()),
+			)
+			acctKeyPriv.Zero()
+			if err != nil {
+				str := "failed to encrypt private key for account"
+				return 0, managerError(ErrCrypto, str, err)
+			}
+		}
+	}
+
+	// Encrypt the account keys of the cosigners with the crypto public
+	// key.
+	for _, cosigner := range cosigners {
+		pubKeyEnc, err := s.rootManager.cryptoKeyPub.Encrypt(
+			[]byte(cosigner.AccountPubKey.String()),
+		)
+		if err != nil {
+			str := "failed to encrypt public key for account"
+			return 0, managerError(ErrCrypto, str, err)
+		}
+		row.cosigners = append(row.cosigners, dbMultisigCosigner{
+			pubKeyEncrypted:      pubKeyEnc,
+			masterKeyFingerprint: cosigner.MasterKeyFingerprint,
+			derivationPath:       cosigner.DerivationPath,
+		})
+	}
+
+	account, err := fetchLastAccount(ns, &s.scope)
+	if err != nil {
+		return 0, err
+	}
+	account++
+
+	err = putMultisigAccountInfo(ns, &s.scope, account, row)
+	if err != nil {
+		return 0, err
+	}
+	if err := putLastAccount(ns, &s.scope, account); err != nil {
+		return 0, err
+	}
+
+	// Store the addresses of both branches up to the gap limit.
+	acctInfo := &accountInfo{
+		multisig: &MultisigProperties{
+			Threshold:  threshold,
+			ScriptType: scriptType,
+			Cosigners:  cosigners,
+		},
+	}
+	for _, branch := range []uint32{ExternalBranch, InternalBranch} {
+		_, err := s.storeMultisigAddresses(
+			ns, account, acctInfo, branch, 0, MultisigGapLimit,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return account, nil
+}
+
+// deriveMultisigAddress returns the address of the multisig account at the
+// index of the branch.
+func (s *ScopedKeyManager) deriveMultisigAddress(account uint32,
+	acctInfo *accountInfo, branch, index uint32) (*multisigAddress, error) {
+
+	props := acctInfo.multisig
+	keys := make([]*MultisigKey, 0, len(props.Cosigners))
+	for _, cosigner := range props.Cosigners {
+		branchKey, err := cosigner.AccountPubKey.Derive(branch)
+		if err != nil {
+			str := fmt.Sprintf("failed to derive extended key "+
+				"branch %d", branch)
+			return nil, managerError(ErrKeyChain, str, err)
+		}
+		key, err := branchKey.Derive(index)
+		if err != nil {
+			str := fmt.Sprintf("failed to derive child extended "+
+				"key -- branch %d, child %d", branch, index)
+			return nil, managerError(ErrKeyChain, str, err)
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			str := "failed to convert public key"
+			return nil, managerError(ErrKeyChain, str, err)
+		}
+
+		path := make([]uint32, 0, len(cosigner.DerivationPath)+2)
+		path = append(path, cosigner.DerivationPath...)
+		path = append(path, branch, index)
+		keys = append(keys, &MultisigKey{
+			PubKey:               pubKey,
+			MasterKeyFingerprint: cosigner.MasterKeyFingerprint,
+			DerivationPath:       path,
+		})
+	}
+
+	// The keys are sorted by their serialization as described in
+	// BIP-0067, like the keys of sortedmulti descriptors.
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(
+			keys[i].PubKey.SerializeCompressed(),
+			keys[j].PubKey.SerializeCompressed(),
+		) < 0
+	})
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddInt64(int64(props.Threshold))
+	for _, key := range keys {
+		builder.AddData(key.PubKey.SerializeCompressed())
+	}
+	builder.AddInt64(int64(len(keys)))
+	builder.AddOp(txscript.OP_CHECKMULTISIG)
+	witnessScript, err := builder.Script()
+	if err != nil {
+		str := "failed to build multisig script"
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+
+	scriptHash := sha256.Sum256(witnessScript)
+	address, err := btcutil.NewAddressWitnessScriptHash(
+		scriptHash[:], s.rootManager.chainParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+	addr := &multisigAddress{
+		manager:       s,
+		account:       account,
+		branch:        branch,
+		index:         index,
+		address:       address,
+		addrType:      WitnessScript,
+		threshold:     props.Threshold,
+		keys:          keys,
+		witnessScript: witnessScript,
+	}
+
+	if props.ScriptType == MultisigNestedWitnessScript {
+		addr.redeemScript, err = txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, err
+		}
+		addr.address, err = btcutil.NewAddressScriptHash(
+			addr.redeemScript, s.rootManager.chainParams,
+		)
+		if err != nil {
+			return nil, err
+		}
+		addr.addrType = NestedWitnessScript
+	}
+
+	return addr, nil
+}
+
+// loadLastMultisigAddresses derives the last issued addresses of both branches
+// of the multisig account.
+func (s *ScopedKeyManager) loadLastMultisigAddresses(account uint32,
+	acctInfo *accountInfo) error {
+
+	index := acctInfo.nextExternalIndex
+	if index > 0 {
+		index--
+	}
+	lastExtAddr, err := s.deriveMultisigAddress(
+		account, acctInfo, ExternalBranch, index,
+	)
+	if err != nil {
+		return err
+	}
+	acctInfo.lastExternalAddr = lastExtAddr
+
+	index = acctInfo.nextInternalIndex
+	if index > 0 {
+		index--
+	}
+	lastIntAddr, err := s.deriveMultisigAddress(
+		account, acctInfo, InternalBranch, index,
+	)
+	if err != nil {
+		return err
+	}
+	acctInfo.lastInternalAddr = lastIntAddr
+	return nil
+}
+
+// storeMultisigAddresses stores the addresses of the branch of the multisig
+// account from the start index up to but excluding the end index, skipping
+// those stored already.  The newly stored addresses are returned.
+//
+// This function MUST be called with the manager lock held for writes.
+func (s *ScopedKeyManager) storeMultisigAddresses(ns walletdb.ReadWriteBucket,
+	account uint32, acctInfo *accountInfo, branch, start,
+	end uint32) ([]ManagedAddress, error) {
+
+	var addrs []ManagedAddress
+	for index := start; index < end; index++ {
+		addr, err := s.deriveMultisigAddress(
+			account, acctInfo, branch, index,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if s.existsAddress(ns, addr.AddrHash()) {
+			continue
+		}
+
+		err = putChainedAddress(
+			ns, &s.scope, addr.AddrHash(), account, ssFull, branch,
+			index, adtChain,
+		)
+		if err != nil {
+			return nil, maybeConvertDbError(err)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// extendMultisigAddresses marks the addresses of the branch of the multisig
+// account up to but excluding the next index as issued, and stores the
+// addresses up to the gap limit following them.  The newly stored addresses
+// are returned.
+//
+// This function MUST be called with the manager lock held for writes.
+func (s *ScopedKeyManager) extendMultisigAddresses(ns walletdb.ReadWriteBucket,
+	account uint32, acctInfo *accountInfo, internal bool,
+	nextIndex uint32) ([]ManagedAddress, error) {
+
+	branch, curIndex := ExternalBranch, acctInfo.nextExternalIndex
+	if internal {
+		branch, curIndex = InternalBranch, acctInfo.nextInternalIndex
+	}
+	if nextIndex <= curIndex {
+		return nil, nil
+	}
+	if nextIndex > MaxAddressesPerAccount {
+		str := fmt.Sprintf("next index %d would exceed the maximum "+
+			"allowed number of addresses per account of %d",
+			nextIndex, MaxAddressesPerAccount)
+		return nil, managerError(ErrTooManyAddresses, str, nil)
+	}
+
+	end := nextIndex + MultisigGapLimit
+	if end > MaxAddressesPerAccount {
+		end = MaxAddressesPerAccount
+	}
+	addrs, err := s.storeMultisigAddresses(
+		ns, account, acctInfo, branch, curIndex, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lastAddr, err := s.deriveMultisigAddress(
+		account, acctInfo, branch, nextIndex-1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = putMultisigNextIndex(ns, &s.scope, account, branch, nextIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the in-memory account info once the changes are committed.
+	ns.Tx().OnCommit(func() {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		if internal {
+			acctInfo.nextInternalIndex = nextIndex
+			acctInfo.lastInternalAddr = lastAddr
+		} else {
+			acctInfo.nextExternalIndex = nextIndex
+			acctInfo.lastExternalAddr = lastAddr
+		}
+	})
+
+	return addrs, nil
+}
+
+// nextMultisigAddresses returns the specified number of next addresses of the
+// branch of the multisig account indicated by the internal flag.
+//
+// This function MUST be called with the manager lock held for writes.
+func (s *ScopedKeyManager) nextMultisigAddresses(ns walletdb.ReadWriteBucket,
+	account uint32, acctInfo *accountInfo, numAddresses uint32,
+	internal bool) ([]ManagedAddress, error) {
+
+	branch, nextIndex := ExternalBranch, acctInfo.nextExternalIndex
+	if internal {
+		branch, nextIndex = InternalBranch, acctInfo.nextInternalIndex
+	}
+
+	// Ensure the requested number of addresses doesn't exceed the maximum
+	// allowed for this account.
+	if numAddresses > MaxAddressesPerAccount ||
+		nextIndex+numAddresses > MaxAddressesPerAccount {
+
+		str := fmt.Sprintf("%d new addresses would exceed the maximum "+
+			"allowed number of addresses per account of %d",
+			numAddresses, MaxAddressesPerAccount)
+		return nil, managerError(ErrTooManyAddresses, str, nil)
+	}
+
+	_, err := s.extendMultisigAddresses(
+		ns, account, acctInfo, internal, nextIndex+numAddresses,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]ManagedAddress, 0, numAddresses)
+	for index := nextIndex; index < nextIndex+numAddresses; index++ {
+		addr, err := s.deriveMultisigAddress(
+			account, acctInfo, branch, index,
+		)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// ExtendMultisigAddresses marks the addresses of the branch of the multisig
+// account up to and including the index as issued, as they may have been
+// issued by other cosigners, and stores the addresses up to the gap limit
+// following them.  This is called as addresses of the account are found to be
+// used.  The newly stored addresses are returned so they can be watched for.
+func (s *ScopedKeyManager) ExtendMultisigAddresses(ns walletdb.ReadWriteBucket,
+	account, branch, index uint32) ([]ManagedAddress, error) {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	acctInfo, err := s.loadAccountInfo(ns, account)
+	if err != nil {
+		return nil, err
+	}
+	if acctInfo.multisig == nil {
+		str := fmt.Sprintf("account %d is not a multisig account",
+			account)
+		return nil, managerError(ErrInvalidAccount, str, nil)
+	}
+
+	return s.extendMultisigAddresses(
+		ns, account, acctInfo, branch == InternalBranch, index+1,
+	)
+}
@@ -146,6 +146,21 @@ func WitnessScriptHashIdentity(script []byte) Identity {
 	}
 }
 
+// NestedWitnessScriptHashIdentity returns the identity closure for a p2wsh
+// script nested within a p2sh output, which is the hash of the p2wsh output
+// script used as the redeem script.
+func NestedWitnessScriptHashIdentity(script []byte) Identity {
+	return func() []byte {
+		digest := sha256.Sum256(script)
+		redeemScript := make([]byte, 0, 2+len(digest))
+		redeemScript = append(
+			redeemScript, txscript.OP_0, txscript.OP_DATA_32,
+		)
+		redeemScript = append(redeemScript, digest[:]...)
+		return btcutil.Hash160(redeemScript)
+	}
+}
+
 // TaprootIdentity returns the identity closure for a p2tr script.
 func TaprootIdentity(taprootKey *btcec.PublicKey) Identity {
 	return func() []byte {
@@ -316,7 +331,9 @@ func (s *ScopedKeyManager) AddrSchema() ScopeAddrSchema {
 func (s *ScopedKeyManager) zeroSensitivePublicData() {
 	// Clear all of the account private keys.
 	for _, acctInfo := range s.acctInfo {
-		acctInfo.acctKeyPub.Zero()
+		if acctInfo.acctKeyPub != nil {
+			acctInfo.acctKeyPub.Zero()
+		}
 		acctInfo.acctKeyPub = nil
 	}
 }
@@ -508,6 +525,69 @@ func (s *ScopedKeyManager) loadAccountInfo(ns walletdb.ReadBucket,
 
 		hasPrivateKey = false
 
+	case *dbMultisigAccountRow:
+		acctInfo = &accountInfo{
+			acctName:          row.name,
+			acctType:          row.acctType,
+			acctKeyEncrypted:  row.privKeyEncrypted,
+			nextExternalIndex: row.nextExternalIndex,
+			nextInternalIndex: row.nextInternalIndex,
+			multisig: &MultisigProperties{
+				Threshold:  int(row.threshold),
+				ScriptType: row.scriptType,
+				Cosigners: make(
+					[]*Cosigner, 0, len(row.cosigners),
+				),
+			},
+		}
+
+		// Use the crypto public key to decrypt the account public
+		// extended keys of the cosigners.
+		for _, c := range row.cosigners {
+			pubKey, err := decryptKey(
+				s.rootManager.cryptoKeyPub, c.pubKeyEncrypted,
+			)
+			if err != nil {
+				str := fmt.Sprintf("failed to decrypt cosigner "+
+					"public key for account %d", account)
+				return nil, managerError(ErrCrypto, str, err)
+			}
+			acctInfo.multisig.Cosigners = append(
+				acctInfo.multisig.Cosigners, &Cosigner{
+					AccountPubKey:        pubKey,
+					MasterKeyFingerprint: c.masterKeyFingerprint,
+					DerivationPath:       c.derivationPath,
+				},
+			)
+		}
+
+		// The account keys are those of the wallet as a cosigner, if
+		// it is one.
+		if int(row.ownKeyIndex) < len(acctInfo.multisig.Cosigners) {
+			own := acctInfo.multisig.Cosigners[row.ownKeyIndex]
+			acctInfo.acctKeyPub = own.AccountPubKey
+			acctInfo.masterKeyFingerprint = own.MasterKeyFingerprint
+		}
+		if hasPrivateKey && len(row.privKeyEncrypted) > 0 {
+			acctInfo.acctKeyPriv, err = decryptKey(
+				s.rootManager.cryptoKeyPriv, row.privKeyEncrypted,
+			)
+			if err != nil {
+				str := fmt.Sprintf("failed to decrypt private "+
+					"key for account %d", account)
+				return nil, managerError(ErrCrypto, str, err)
+			}
+		}
+
+		// The last addresses of multisig accounts are derived from the
+		// keys of all of their cosigners.
+		err := s.loadLastMultisigAddresses(account, acctInfo)
+		if err != nil {
+			return nil, err
+		}
+		s.acctInfo[account] = acctInfo
+		return acctInfo, nil
+
 	default:
 		str := fmt.Sprintf("unsupported account type %T", row)
 		return nil, managerError(ErrDatabase, str, nil)
@@ -599,6 +679,7 @@ func (s *ScopedKeyManager) AccountProperties(ns walletdb.ReadBucket,
 		props.IsWatchOnly = s.rootManager.WatchOnly() ||
 			acctInfo.acctKeyPriv == nil
 		props.AddrSchema = acctInfo.addrSchema
+		props.Multisig = acctInfo.multisig
 
 		// Export the account public key with the correct version
 		// corresponding to the manager's key scope for non-watch-only
@@ -751,6 +832,13 @@ func (s *ScopedKeyManager) deriveKeyFromPath(ns walletdb.ReadBucket,
 	if err != nil {
 		return nil, nil, 0, err
 	}
+
+	// The addresses of multisig accounts aren't derived from a single key.
+	if acctInfo.multisig != nil {
+		str := fmt.Sprintf("account %d is a multisig account",
+			internalAccount)
+		return nil, nil, 0, managerError(ErrInvalidAccount, str, nil)
+	}
 	private = private && acctInfo.acctKeyPriv != nil
 
 	addrKey, err := s.deriveKey(acctInfo, branch, index, private)
@@ -773,6 +861,16 @@ func (s *ScopedKeyManager) deriveKeyFromPath(ns walletdb.ReadBucket,
 func (s *ScopedKeyManager) chainAddressRowToManaged(ns walletdb.ReadBucket,
 	row *dbChainAddressRow) (ManagedAddress, error) {
 
+	acctInfo, err := s.loadAccountInfo(ns, row.account)
+	if err != nil {
+		return nil, err
+	}
+	if acctInfo.multisig != nil {
+		return s.deriveMultisigAddress(
+			row.account, acctInfo, row.branch, row.index,
+		)
+	}
+
 	private := !s.rootManager.IsLocked() && !s.rootManager.WatchOnly()
 
 	addressKey, acctKey, masterKeyFingerprint, err := s.deriveKeyFromPath(
@@ -782,10 +880,6 @@ func (s *ScopedKeyManager) chainAddressRowToManaged(ns walletdb.ReadBucket,
 		return nil, err
 	}
 
-	acctInfo, err := s.loadAccountInfo(ns, row.account)
-	if err != nil {
-		return nil, err
-	}
 	return s.keyToManaged(
 		addressKey, DerivationPath{
 			InternalAccount:      row.account,
@@ -856,6 +950,12 @@ func (s *ScopedKeyManager) witnessScriptAddressRowToManaged(
 		return nil, managerError(ErrCrypto, str, err)
 	}
 
+	if row.addrType == adtNestedWitnessScript {
+		return newNestedWitnessScriptAddress(
+			s, row.account, scriptHash, row.encryptedScript,
+			row.isSecretScript,
+		)
+	}
 	return newWitnessScriptAddress(
 		s, row.account, scriptHash, row.encryptedScript,
 		row.witnessVersion, row.isSecretScript,
@@ -1012,6 +1112,11 @@ func (s *ScopedKeyManager) nextAddresses(ns walletdb.ReadWriteBucket,
 	if err != nil {
 		return nil, err
 	}
+	if acctInfo.multisig != nil {
+		return s.nextMultisigAddresses(
+			ns, account, acctInfo, numAddresses, internal,
+		)
+	}
 
 	// Choose the account key to used based on whether the address manager
 	// is locked.
@@ -1235,6 +1340,12 @@ func (s *ScopedKeyManager) extendAddresses(ns walletdb.ReadWriteBucket,
 	if err != nil {
 		return err
 	}
+	if acctInfo.multisig != nil {
+		_, err := s.extendMultisigAddresses(
+			ns, account, acctInfo, internal, lastIndex+1,
+		)
+		return err
+	}
 
 	// Choose the account key to used based on whether the address manager
 	// is locked.
@@ -1872,6 +1983,18 @@ func (s *ScopedKeyManager) RenameAccount(ns walletdb.ReadWriteBucket,
 			return err
 		}
 
+	case *dbMultisigAccountRow:
+		// Remove the old name key from the account name index.
+		if err = deleteAccountNameIndex(ns, &s.scope, row.name); err != nil {
+			return err
+		}
+
+		row.name = name
+		err = putMultisigAccountInfo(ns, &s.scope, account, row)
+		if err != nil {
+			return err
+		}
+
 	default:
 		str := fmt.Sprintf("unsupported account type %T", row)
 		return managerError(ErrDatabase, str, nil)
@@ -2158,6 +2281,22 @@ func (s *ScopedKeyManager) ImportWitnessScript(ns walletdb.ReadWriteBucket,
 	)
 }
 
+// ImportNestedWitnessScript imports a user-provided script into the address
+// manager. The imported script will act as a pay-to-witness-script-hash
+// address nested within a pay-to-script-hash output, like the addresses of
+// multisig accounts using the MultisigNestedWitnessScript script type.
+//
+// The same rules as for ImportWitnessScript apply.
+func (s *ScopedKeyManager) ImportNestedWitnessScript(
+	ns walletdb.ReadWriteBucket, script []byte, bs *BlockStamp,
+	isSecretScript bool) (ManagedScriptAddress, error) {
+
+	return s.importScriptAddress(
+		ns, NestedWitnessScriptHashIdentity(script), script, bs,
+		NestedWitnessScript, witnessVersionV0, isSecretScript,
+	)
+}
+
 // ImportTaprootScript imports a user-provided taproot script into the address
 // manager. The imported script will act as a pay-to-taproot address.
 func (s *ScopedKeyManager) ImportTaprootScript(ns walletdb.ReadWriteBucket,
@@ -2262,10 +2401,11 @@ func (s *ScopedKeyManager) importScriptAddress(ns walletdb.ReadWriteBucket,
 	// Save the new imported address to the db and update start block (if
 	// needed) in a single transaction.
 	switch addrType {
-	case WitnessScript, TaprootScript:
+	case WitnessScript, NestedWitnessScript, TaprootScript:
 		err = putWitnessScriptAddress(
 			ns, &s.scope, scriptIdent, ImportedAddrAccount, ssNone,
-			witnessVersion, isSecretScript, encryptedHash,
+			witnessVersion, isSecretScript,
+			addrType == NestedWitnessScript, encryptedHash,
 			encryptedScript,
 		)
 
@@ -2291,6 +2431,12 @@ func (s *ScopedKeyManager) importScriptAddress(ns walletdb.ReadWriteBucket,
 			witnessVersion, isSecretScript,
 		)
 
+	case NestedWitnessScript:
+		managedAddr, err = newNestedWitnessScriptAddress(
+			s, ImportedAddrAccount, scriptIdent, encryptedScript,
+			isSecretScript,
+		)
+
 	default:
 		managedAddr, err = newScriptAddress(
 			s, ImportedAddrAccount, scriptIdent, encryptedScript,
@@ -2488,7 +2634,8 @@ func (s *ScopedKeyManager) ForEachInternalActiveAddress(ns walletdb.ReadBucket,
 }
 
 // IsWatchOnlyAccount determines if the given account belonging to this scoped
-// manager is set up as watch-only.
+// manager is set up as watch-only.  Multisig accounts are always considered
+// watch-only, as the wallet can't sign for their addresses on its own.
 func (s *ScopedKeyManager) IsWatchOnlyAccount(ns walletdb.ReadBucket,
 	account uint32) (bool, error) {
 
@@ -2500,7 +2647,7 @@ func (s *ScopedKeyManager) IsWatchOnlyAccount(ns walletdb.ReadBucket,
 		return false, err
 	}
 
-	return acctInfo.acctKeyPriv == nil, nil
+	return acctInfo.acctKeyPriv == nil || acctInfo.multisig != nil, nil
 }
 
 // cloneKeyWithVersion clones an extended key to use the version corresponding
@@ -114,3 +114,69 @@ func (t *Tapscript) TaprootKey() (*btcec.PublicKey, error) {
 		return nil, fmt.Errorf("unknown tapscript type %d", t.Type)
 	}
 }
+
+// RevealableLeaves returns the leaves of the tapscript that can be revealed to
+// spend the taproot output through their script path.  Tapscripts that only
+// know the root hash or the final output key have no such leaves.
+func (t *Tapscript) RevealableLeaves() []txscript.TapLeaf {
+	switch t.Type {
+	case TapscriptTypeFullTree:
+		return t.Leaves
+
+	case TapscriptTypePartialReveal:
+		if t.ControlBlock == nil {
+			return nil
+		}
+		return []txscript.TapLeaf{txscript.NewTapLeaf(
+			t.ControlBlock.LeafVersion, t.RevealedScript,
+		)}
+
+	default:
+		return nil
+	}
+}
+
+// LeafControlBlock returns the control block revealing the given leaf of the
+// tapscript, to be included in the witness spending the taproot output through
+// the script path of the leaf.
+func (t *Tapscript) LeafControlBlock(
+	leaf txscript.TapLeaf) (*txscript.ControlBlock, error) {
+
+	if t.ControlBlock == nil || t.ControlBlock.InternalKey == nil {
+		return nil, fmt.Errorf("internal key is missing")
+	}
+
+	switch t.Type {
+	case TapscriptTypeFullTree:
+		tree := txscript.AssembleTaprootScriptTree(t.Leaves...)
+		idx, ok := tree.LeafProofIndex[leaf.TapHash()]
+		if !ok {
+			return nil, fmt.Errorf("leaf not found in tapscript")
+		}
+		ctrlBlock := tree.LeafMerkleProofs[idx].ToControlBlock(
+			t.ControlBlock.InternalKey,
+		)
+		return &ctrlBlock, nil
+
+	case TapscriptTypePartialReveal:
+		revealed := txscript.NewTapLeaf(
+			t.ControlBlock.LeafVersion, t.RevealedScript,
+		)
+		if leaf.TapHash() != revealed.TapHash() {
+			return nil, fmt.Errorf("leaf is not the revealed leaf")
+		}
+		// The parity of the output key is computed rather than trusting
+		// the control block provided on import.
+		ctrlBlock := *t.ControlBlock
+		outputKey := txscript.ComputeTaprootOutputKey(
+			ctrlBlock.InternalKey, ctrlBlock.RootHash(leaf.Script),
+		)
+		ctrlBlock.OutputKeyYIsOdd = outputKey.SerializeCompressed()[0] ==
+			0x03
+		return &ctrlBlock, nil
+
+	default:
+		return nil, fmt.Errorf("tapscript type %d has no script path",
+			t.Type)
+	}
+}
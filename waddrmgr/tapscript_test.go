@@ -107,3 +107,64 @@ func TestTaprootKey(t *testing.T) {
 		})
 	}
 }
+
+// TestLeafControlBlock tests that the control blocks of the leaves of a
+// tapscript commit to its taproot key.
+func TestLeafControlBlock(t *testing.T) {
+	t.Parallel()
+
+	leaf1 := txscript.NewBaseTapLeaf(testScript1)
+	leaf2 := txscript.NewBaseTapLeaf(testScript2)
+	fullTree := &Tapscript{
+		Type:   TapscriptTypeFullTree,
+		Leaves: []txscript.TapLeaf{leaf1, leaf2},
+		ControlBlock: &txscript.ControlBlock{
+			InternalKey: testInternalKey,
+			LeafVersion: txscript.BaseLeafVersion,
+		},
+	}
+	partialReveal := &Tapscript{
+		Type:           TapscriptTypePartialReveal,
+		RevealedScript: testScript2,
+		ControlBlock: &txscript.ControlBlock{
+			InternalKey:    testInternalKey,
+			LeafVersion:    txscript.BaseLeafVersion,
+			InclusionProof: testScript1Proof,
+		},
+	}
+	taprootKey, err := schnorr.ParsePubKey(testTaprootKey)
+	require.NoError(t, err)
+
+	require.Equal(t, fullTree.Leaves, fullTree.RevealableLeaves())
+	for _, leaf := range fullTree.RevealableLeaves() {
+		ctrlBlock, err := fullTree.LeafControlBlock(leaf)
+		require.NoError(t, err)
+		err = txscript.VerifyTaprootLeafCommitment(
+			ctrlBlock, schnorr.SerializePubKey(taprootKey),
+			leaf.Script,
+		)
+		require.NoError(t, err)
+	}
+
+	// Only the revealed leaf of a partial tree has a control block.
+	require.Equal(
+		t, []txscript.TapLeaf{leaf2}, partialReveal.RevealableLeaves(),
+	)
+	ctrlBlock, err := partialReveal.LeafControlBlock(leaf2)
+	require.NoError(t, err)
+	fullCtrlBlock, err := fullTree.LeafControlBlock(leaf2)
+	require.NoError(t, err)
+	require.Equal(t, fullCtrlBlock, ctrlBlock)
+	_, err = partialReveal.LeafControlBlock(leaf1)
+	require.Error(t, err)
+
+	// Tapscripts only knowing the root hash have no script paths.
+	rootHashOnly := &Tapscript{
+		Type:         TaprootKeySpendRootHash,
+		ControlBlock: fullTree.ControlBlock,
+		RootHash:     []byte("I could be a root hash"),
+	}
+	require.Empty(t, rootHashOnly.RevealableLeaves())
+	_, err = rootHashOnly.LeafControlBlock(leaf1)
+	require.Error(t, err)
+}
@@ -0,0 +1,362 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bisoncraft/utxowallet/addrbook"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// AddressBookEntry is a labeled address of the address book.
+type AddressBookEntry struct {
+	addrbook.AddressLabel
+
+	// Mine is true if the address belongs to the wallet.
+	Mine bool
+}
+
+// PutContact adds a contact to the address book, or replaces the note of an
+// existing contact.
+func (w *Wallet) PutContact(name, note string) error {
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+		return addrbook.PutContact(ns, &addrbook.Contact{
+			Name: name,
+			Note: note,
+		})
+	})
+}
+
+// Contact returns the contact with the name provided.
+func (w *Wallet) Contact(name string) (*addrbook.Contact, error) {
+	var c *addrbook.Contact
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(addrbookNamespaceKey)
+		var err error
+		c, err = addrbook.FetchContact(ns, name)
+		return err
+	})
+	return c, err
+}
+
+// Contacts returns all contacts of the address book, sorted by name.
+func (w *Wallet) Contacts() ([]*addrbook.Contact, error) {
+	var contacts []*addrbook.Contact
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(addrbookNamespaceKey)
+		return addrbook.ForEachContact(
+			ns, func(c *addrbook.Contact) error {
+				contacts = append(contacts, c)
+				return nil
+			},
+		)
+	})
+	return contacts, err
+}
+
+// DeleteContact removes a contact from the address book.  The addresses of the
+// contact keep their labels.
+func (w *Wallet) DeleteContact(name string) error {
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+		return addrbook.DeleteContact(ns, name)
+	})
+}
+
+// LabelAddress labels an address, which may belong to the wallet or to a third
+// party, replacing any existing label.  The address is associated with the
+// contact provided, unless it is empty.
+func (w *Wallet) LabelAddress(addr btcutil.Address, label,
+	contact string) error {
+
+	if !addr.IsForNet(w.chainParams) {
+		return fmt.Errorf("address %v is not for %v", addr,
+			w.chainParams.Name)
+	}
+
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+		return addrbook.PutAddressLabel(ns, &addrbook.AddressLabel{
+			Address: addr.EncodeAddress(),
+			Label:   label,
+			Contact: contact,
+		})
+	})
+}
+
+// DeleteAddressLabel removes the label of an address.
+func (w *Wallet) DeleteAddressLabel(addr btcutil.Address) error {
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+		return addrbook.DeleteAddressLabel(ns, addr.EncodeAddress())
+	})
+}
+
+// AddressBook returns the labeled addresses of a contact, or all labeled
+// addresses if contact is empty.
+func (w *Wallet) AddressBook(contact string) ([]AddressBookEntry, error) {
+	var entries []AddressBookEntry
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		addrmgrNs := tx.ReadBucket(waddrmgrNamespaceKey)
+		ns := tx.ReadBucket(addrbookNamespaceKey)
+
+		if contact != "" {
+			if _, err := addrbook.FetchContact(ns, contact); err != nil {
+				return err
+			}
+		}
+
+		forEach := func(a *addrbook.AddressLabel) error {
+			if contact != "" && a.Contact != contact {
+				return nil
+			}
+
+			entry := AddressBookEntry{AddressLabel: *a}
+			addr, err := btcutil.DecodeAddress(
+				a.Address, w.chainParams,
+			)
+			if err == nil {
+				_, err = w.Manager.Address(addrmgrNs, addr)
+				entry.Mine = err == nil
+			}
+			entries = append(entries, entry)
+			return nil
+		}
+		return addrbook.ForEachAddressLabel(ns, forEach)
+	})
+	return entries, err
+}
+
+// LabelXPub labels an extended public key, replacing any existing label.
+func (w *Wallet) LabelXPub(key *hdkeychain.ExtendedKey, label string) error {
+	if key.IsPrivate() {
+		return errors.New("only extended public keys may be labeled")
+	}
+
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+		return addrbook.PutXPubLabel(ns, key.String(), label)
+	})
+}
+
+// LabelOutput labels a transaction output, replacing any existing label.
+func (w *Wallet) LabelOutput(op wire.OutPoint, label string) error {
+	return walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+		return addrbook.PutOutputLabel(ns, &addrbook.OutputLabel{
+			OutPoint: op,
+			Label:    label,
+		})
+	})
+}
+
+// ExportLabels writes the transaction, address, extended public key and output
+// labels of the wallet to out in the BIP-0329 format.
+func (w *Wallet) ExportLabels(out io.Writer) error {
+	var records []*addrbook.Record
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		txmgrNs := tx.ReadBucket(wtxmgrNamespaceKey)
+		ns := tx.ReadBucket(addrbookNamespaceKey)
+
+		err := wtxmgr.ForEachTxLabel(txmgrNs, func(txid chainhash.Hash,
+			label string) error {
+
+			records = append(records, &addrbook.Record{
+				Type:  addrbook.RecordTx,
+				Ref:   txid.String(),
+				Label: label,
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		addrRecord := func(a *addrbook.AddressLabel) error {
+			records = append(records, &addrbook.Record{
+				Type:  addrbook.RecordAddr,
+				Ref:   a.Address,
+				Label: a.Label,
+			})
+			return nil
+		}
+		err = addrbook.ForEachAddressLabel(ns, addrRecord)
+		if err != nil {
+			return err
+		}
+
+		xpubRecord := func(xpub, label string) error {
+			records = append(records, &addrbook.Record{
+				Type:  addrbook.RecordXPub,
+				Ref:   xpub,
+				Label: label,
+			})
+			return nil
+		}
+		err = addrbook.ForEachXPubLabel(ns, xpubRecord)
+		if err != nil {
+			return err
+		}
+
+		outputRecord := func(o *addrbook.OutputLabel) error {
+			records = append(records, &addrbook.Record{
+				Type:      addrbook.RecordOutput,
+				Ref:       o.OutPoint.String(),
+				Label:     o.Label,
+				Spendable: o.Spendable,
+			})
+			return nil
+		}
+		return addrbook.ForEachOutputLabel(ns, outputRecord)
+	})
+	if err != nil {
+		return err
+	}
+
+	return addrbook.WriteRecords(out, records)
+}
+
+// ImportLabels reads BIP-0329 labels from r and stores the transaction,
+// address, extended public key and output labels, replacing existing labels.
+// Labels of transactions unknown to the wallet, records without a label and
+// records of other types are skipped.  Address labels keep their association
+// with a contact.  The number of imported labels is returned.  Either all
+// labels are imported, or none are if an error is returned.
+func (w *Wallet) ImportLabels(r io.Reader) (int, error) {
+	records, err := addrbook.ReadRecords(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var imported int
+	err = walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		txmgrNs := tx.ReadWriteBucket(wtxmgrNamespaceKey)
+		ns := tx.ReadWriteBucket(addrbookNamespaceKey)
+
+		for _, rec := range records {
+			if rec.Label == "" {
+				continue
+			}
+
+			ok, err := w.importLabel(txmgrNs, ns, rec)
+			if err != nil {
+				return fmt.Errorf("unable to import %s label "+
+					"of %s: %w", rec.Type, rec.Ref, err)
+			}
+			if ok {
+				imported++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// importLabel stores the label of a BIP-0329 record.  It returns false if the
+// record was skipped.
+func (w *Wallet) importLabel(txmgrNs, ns walletdb.ReadWriteBucket,
+	rec *addrbook.Record) (bool, error) {
+
+	switch rec.Type {
+	case addrbook.RecordTx:
+		txid, err := chainhash.NewHashFromStr(rec.Ref)
+		if err != nil {
+			return false, err
+		}
+		details, err := w.TxStore.TxDetails(txmgrNs, txid)
+		if err != nil || details == nil {
+			return false, err
+		}
+		return true, w.TxStore.PutTxLabel(txmgrNs, *txid, rec.Label)
+
+	case addrbook.RecordAddr:
+		addr, err := btcutil.DecodeAddress(rec.Ref, w.chainParams)
+		if err != nil {
+			return false, err
+		}
+		if !addr.IsForNet(w.chainParams) {
+			return false, fmt.Errorf("address is not for %v",
+				w.chainParams.Name)
+		}
+
+		a := &addrbook.AddressLabel{
+			Address: addr.EncodeAddress(),
+			Label:   rec.Label,
+		}
+		existing, err := addrbook.FetchAddressLabel(ns, a.Address)
+		switch {
+		case err == nil:
+			a.Contact = existing.Contact
+		case !errors.Is(err, addrbook.ErrNotFound):
+			return false, err
+		}
+		return true, addrbook.PutAddressLabel(ns, a)
+
+	case addrbook.RecordXPub:
+		key, err := hdkeychain.NewKeyFromString(rec.Ref)
+		if err != nil {
+			return false, err
+		}
+		if key.IsPrivate() {
+			return false, errors.New("extended key is private")
+		}
+		return true, addrbook.PutXPubLabel(ns, key.String(), rec.Label)
+
+	case addrbook.RecordOutput:
+		op, err := wire.NewOutPointFromString(rec.Ref)
+		if err != nil {
+			return false, err
+		}
+		return true, addrbook.PutOutputLabel(ns, &addrbook.OutputLabel{
+			OutPoint:  *op,
+			Label:     rec.Label,
+			Spendable: rec.Spendable,
+		})
+
+	default:
+		w.log.Debugf("Skipping unsupported %s label of %s", rec.Type,
+			rec.Ref)
+		return false, nil
+	}
+}
+
+// outputLabel returns the label used to annotate an output in listtransactions
+// results.  The label of the output itself takes precedence over the label of
+// the address it pays to, which takes precedence over the label of the
+// transaction.  Nil is returned if there is no label.
+func outputLabel(ns walletdb.ReadBucket, details *wtxmgr.TxDetails,
+	index uint32, address string) *string {
+
+	// The namespace is missing from databases of wallets that have not
+	// been opened since the address book was added.
+	if ns != nil {
+		op := wire.OutPoint{Hash: details.Hash, Index: index}
+		o, err := addrbook.FetchOutputLabel(ns, op)
+		if err == nil {
+			return &o.Label
+		}
+
+		if address != "" {
+			a, err := addrbook.FetchAddressLabel(ns, address)
+			if err == nil {
+				return &a.Label
+			}
+		}
+	}
+
+	if details.Label != "" {
+		label := details.Label
+		return &label
+	}
+	return nil
+}
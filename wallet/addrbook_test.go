@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/addrbook"
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// insertTestTx inserts the test transaction into the wallet's tx store.
+func insertTestTx(t *testing.T, w *Wallet) {
+	t.Helper()
+
+	rec, err := wtxmgr.NewTxRecord(TstSerializedTx, time.Now())
+	require.NoError(t, err)
+
+	err = walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(wtxmgrNamespaceKey)
+		return w.TxStore.InsertTx(ns, rec, nil)
+	})
+	require.NoError(t, err)
+}
+
+// TestAddressBook tests labeling addresses of the wallet and of contacts, and
+// the precedence of the labels used to annotate listtransactions results.
+func TestAddressBook(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	mine, err := w.NewAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	theirs, err := btcutil.NewAddressPubKeyHash(
+		make([]byte, 20), w.chainParams,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, w.PutContact("alice", "exchange account"))
+	require.NoError(t, w.LabelAddress(theirs, "deposit", "alice"))
+	require.NoError(t, w.LabelAddress(mine, "invoice", ""))
+	require.ErrorIs(
+		t, w.LabelAddress(mine, "invoice", "bob"),
+		addrbook.ErrUnknownContact,
+	)
+
+	entries, err := w.AddressBook("alice")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, theirs.EncodeAddress(), entries[0].Address)
+	require.False(t, entries[0].Mine)
+
+	entries, err = w.AddressBook("")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, entry.Address == mine.EncodeAddress(),
+			entry.Mine)
+	}
+
+	insertTestTx(t, w)
+	require.NoError(t, w.LabelTransaction(*TstTxHash, "payment", false))
+	op := wire.OutPoint{Hash: *TstTxHash, Index: 1}
+	require.NoError(t, w.LabelOutput(op, "change"))
+
+	err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(addrbookNamespaceKey)
+		details, err := w.TxStore.TxDetails(
+			tx.ReadBucket(wtxmgrNamespaceKey), TstTxHash,
+		)
+		require.NoError(t, err)
+
+		label := outputLabel(ns, details, 1, theirs.EncodeAddress())
+		require.Equal(t, "change", *label)
+		label = outputLabel(ns, details, 0, theirs.EncodeAddress())
+		require.Equal(t, "deposit", *label)
+		label = outputLabel(ns, details, 0, "")
+		require.Equal(t, "payment", *label)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestImportExportLabels tests that labels exported in the BIP-0329 format
+// are imported by another wallet.
+func TestImportExportLabels(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	addr, err := btcutil.NewAddressPubKeyHash(
+		make([]byte, 20), w.chainParams,
+	)
+	require.NoError(t, err)
+	require.NoError(t, w.LabelAddress(addr, "donations", ""))
+
+	insertTestTx(t, w)
+	require.NoError(t, w.LabelTransaction(*TstTxHash, "payment", false))
+	op := wire.OutPoint{Hash: *TstTxHash, Index: 0}
+	require.NoError(t, w.LabelOutput(op, "cold storage"))
+
+	var exported bytes.Buffer
+	require.NoError(t, w.ExportLabels(&exported))
+
+	w2, cleanup2 := testWallet(t)
+	defer cleanup2()
+
+	// Labels of transactions unknown to the wallet are skipped.
+	n, err := w2.ImportLabels(bytes.NewReader(exported.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	insertTestTx(t, w2)
+	n, err = w2.ImportLabels(bytes.NewReader(exported.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	var reexported bytes.Buffer
+	require.NoError(t, w2.ExportLabels(&reexported))
+	require.Equal(t, exported.String(), reexported.String())
+}
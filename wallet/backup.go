@@ -0,0 +1,417 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/snacl"
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/walletdb"
+)
+
+const (
+	// BackupFilePrefix is the prefix of the file names of the backups
+	// written by Loader.Backup.  It is followed by the UTC time of the
+	// backup, so the backups of a directory sort by their age.
+	BackupFilePrefix = "wallet-"
+
+	// backupTimeFormat is the format of the time in backup file names.
+	backupTimeFormat = "20060102T150405Z"
+
+	// backupVersion is the version of the encrypted backup format.
+	backupVersion = 1
+
+	// backupChunkSize is the number of bytes of the wallet database which
+	// are encrypted together in an encrypted backup.
+	backupChunkSize = 64 * 1024
+
+	// backupChunkHeaderSize is the size of the index and the last chunk
+	// flag which are encrypted with each chunk of an encrypted backup.
+	backupChunkHeaderSize = 9
+
+	// restoreRecoveryWindow is the recovery window used for the first
+	// synchronization after restoring a backup when the loader doesn't
+	// specify one.
+	restoreRecoveryWindow = 250
+)
+
+var (
+	// backupMagic starts every encrypted backup.  It is followed by the
+	// version of the format, the length and marshalled parameters of the
+	// key derived from the backup passphrase and the encrypted chunks of
+	// the wallet database.
+	backupMagic = []byte("UWBACKUP")
+
+	// ErrBackupPassphrase describes the error condition of restoring an
+	// encrypted backup without its passphrase or with a wrong one.
+	ErrBackupPassphrase = errors.New("invalid backup passphrase")
+
+	// ErrInvalidBackup describes the error condition of restoring a file
+	// that isn't a consistent backup of a wallet database.
+	ErrInvalidBackup = errors.New("invalid wallet backup")
+
+	errRestoreExternalDB = errors.New("backups can only be restored to " +
+		"a local wallet database")
+)
+
+// Backup writes a consistent snapshot of the wallet database to out.  The
+// snapshot is taken in a read transaction, so the wallet keeps running while
+// it is written.  If passphrase is not empty, the snapshot is encrypted with a
+// key derived from it with the key derivation parameters of the wallet's
+// passphrases, and can only be restored with the same passphrase.  It is
+// encrypted in chunks as it is written, so the snapshot is never held in
+// memory.  Otherwise the snapshot is itself a wallet database.
+func (w *Wallet) Backup(out io.Writer, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return w.db.Copy(out)
+	}
+
+	pass := append([]byte(nil), passphrase...)
+	sk, err := waddrmgr.NewSecretKey(&pass, w.kdfOptions())
+	if err != nil {
+		return err
+	}
+	defer sk.Zero()
+
+	params := sk.Marshal()
+	header := append([]byte(nil), backupMagic...)
+	header = append(header, backupVersion, byte(len(params)))
+	header = append(header, params...)
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	encrypter := &backupEncrypter{
+		out: out,
+		key: sk,
+		buf: make([]byte, 0, backupChunkSize),
+	}
+	if err := w.db.Copy(encrypter); err != nil {
+		return err
+	}
+	return encrypter.Close()
+}
+
+// backupEncrypter encrypts the wallet database written to it in chunks of
+// backupChunkSize bytes.  Each chunk is encrypted with its index and whether it
+// is the last chunk, so the chunks of a backup can't be reordered or dropped
+// without failing its restore.
+type backupEncrypter struct {
+	out   io.Writer
+	key   *snacl.SecretKey
+	buf   []byte
+	index uint64
+}
+
+// Write buffers p, writing the chunks it completes.
+func (e *backupEncrypter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(e.buf) == backupChunkSize {
+			if err := e.writeChunk(false); err != nil {
+				return 0, err
+			}
+		}
+		copied := copy(e.buf[len(e.buf):backupChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+copied]
+		p = p[copied:]
+	}
+	return n, nil
+}
+
+// Close writes the buffered data as the last chunk.
+func (e *backupEncrypter) Close() error {
+	return e.writeChunk(true)
+}
+
+// writeChunk encrypts the buffered data and writes it, preceded by the length
+// of the encrypted chunk.
+func (e *backupEncrypter) writeChunk(last bool) error {
+	chunk := make([]byte, backupChunkHeaderSize,
+		backupChunkHeaderSize+len(e.buf))
+	binary.LittleEndian.PutUint64(chunk, e.index)
+	if last {
+		chunk[8] = 1
+	}
+	chunk = append(chunk, e.buf...)
+	encrypted, err := e.key.Encrypt(chunk)
+	if err != nil {
+		return err
+	}
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(encrypted)))
+	if _, err := e.out.Write(length[:]); err != nil {
+		return err
+	}
+	if _, err := e.out.Write(encrypted); err != nil {
+		return err
+	}
+
+	e.index++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// decryptBackup writes the wallet database of the backup read from in to out,
+// one chunk at a time.  Backups which were not encrypted are copied unchanged.
+func decryptBackup(out io.Writer, in io.Reader, passphrase []byte) error {
+	r := bufio.NewReader(in)
+	magic, err := r.Peek(len(backupMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if !bytes.Equal(magic, backupMagic) {
+		_, err := io.Copy(out, r)
+		return err
+	}
+
+	// The magic is followed by the version and the length of the
+	// marshalled parameters of the key.
+	header := make([]byte, len(backupMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return ErrInvalidBackup
+	}
+	version := header[len(backupMagic)]
+	if version != backupVersion {
+		return fmt.Errorf("unsupported backup version %d", version)
+	}
+	if len(passphrase) == 0 {
+		return ErrBackupPassphrase
+	}
+
+	params := make([]byte, header[len(backupMagic)+1])
+	if _, err := io.ReadFull(r, params); err != nil {
+		return ErrInvalidBackup
+	}
+	var sk snacl.SecretKey
+	if err := sk.Unmarshal(params); err != nil {
+		return ErrInvalidBackup
+	}
+	pass := append([]byte(nil), passphrase...)
+	if err := sk.DeriveKey(&pass); err != nil {
+		if err == snacl.ErrInvalidPassword {
+			return ErrBackupPassphrase
+		}
+		return err
+	}
+	defer sk.Zero()
+
+	const maxChunkLen = snacl.NonceSize + snacl.Overhead +
+		backupChunkHeaderSize + backupChunkSize
+	var length [4]byte
+	for index := uint64(0); ; index++ {
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return ErrInvalidBackup
+		}
+		chunkLen := binary.LittleEndian.Uint32(length[:])
+		if chunkLen > maxChunkLen {
+			return ErrInvalidBackup
+		}
+		encrypted := make([]byte, chunkLen)
+		if _, err := io.ReadFull(r, encrypted); err != nil {
+			return ErrInvalidBackup
+		}
+
+		chunk, err := sk.Decrypt(encrypted)
+		if err != nil || len(chunk) < backupChunkHeaderSize ||
+			binary.LittleEndian.Uint64(chunk) != index {
+
+			return ErrInvalidBackup
+		}
+		_, err = out.Write(chunk[backupChunkHeaderSize:])
+		if err != nil {
+			return err
+		}
+
+		// Nothing may follow the last chunk.
+		if chunk[8] == 1 {
+			if _, err := r.ReadByte(); !errors.Is(err, io.EOF) {
+				return ErrInvalidBackup
+			}
+			return nil
+		}
+	}
+}
+
+// Backup writes a snapshot of the loaded wallet to a new file in dir, named
+// with BackupFilePrefix and the current time, and returns its path.  The
+// snapshot is encrypted if passphrase is not empty.  The file is only created
+// once the snapshot is complete, so an interrupted backup never leaves a
+// partial backup behind.
+func (l *Loader) Backup(dir string, passphrase []byte) (string, error) {
+	// The wallet database can't be closed while it is copied.
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	if l.wallet == nil {
+		return "", ErrNotLoaded
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	name := BackupFilePrefix + time.Now().UTC().Format(backupTimeFormat) +
+		".db"
+	if len(passphrase) != 0 {
+		name += ".enc"
+	}
+	path := filepath.Join(dir, name)
+
+	f, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	err = l.wallet.Backup(f, passphrase)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+
+	l.log.Infof("Backed up wallet to %s", path)
+	return path, nil
+}
+
+// RestoreBackup replaces the wallet database of the loader with the backup at
+// path, which is decrypted with passphrase if it was encrypted.  The backup is
+// only restored if it can be opened with pubPassphrase and its sync state is
+// consistent, and the replaced wallet database is kept next to it with a
+// ".<unix time in nanoseconds>.old" suffix.  The block the backup was synced to
+// is returned.
+//
+// The wallet must not be loaded.  When it is next opened, the wallet rescans
+// the chain from the returned block, recovering the addresses used since the
+// backup with the recovery window of the loader, or a default window if the
+// loader has none.
+func (l *Loader) RestoreBackup(path string, passphrase,
+	pubPassphrase []byte) (*waddrmgr.BlockStamp, error) {
+
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	if l.wallet != nil {
+		return nil, ErrLoaded
+	}
+	if !l.localDB {
+		return nil, errRestoreExternalDB
+	}
+
+	backup, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+
+	if err := checkCreateDir(l.netDir); err != nil {
+		return nil, err
+	}
+	dbPath := filepath.Join(l.netDir, WalletDBName)
+	f, err := os.CreateTemp(l.netDir, WalletDBName+".restore*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	err = decryptBackup(f, backup, passphrase)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	syncedTo, err := l.validateBackup(f.Name(), pubPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := fileExists(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		oldPath := fmt.Sprintf("%s.%d.old", dbPath, time.Now().UnixNano())
+		if err := os.Rename(dbPath, oldPath); err != nil {
+			return nil, err
+		}
+		l.log.Infof("Moved replaced wallet database to %s", oldPath)
+	}
+	if err := os.Rename(f.Name(), dbPath); err != nil {
+		return nil, err
+	}
+
+	if l.recoveryWindow == 0 {
+		l.recoveryWindow = restoreRecoveryWindow
+	}
+
+	l.log.Infof("Restored wallet backup synced to block %v (height %d)",
+		syncedTo.Hash, syncedTo.Height)
+	return syncedTo, nil
+}
+
+// validateBackup opens the address manager of the restored wallet database at
+// dbPath and returns the block it is synced to, ensuring that the block is
+// the one recorded at its height.
+func (l *Loader) validateBackup(dbPath string,
+	pubPassphrase []byte) (*waddrmgr.BlockStamp, error) {
+
+	db, err := l.openLocalDB(dbPath, pubPassphrase, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer db.Close()
+
+	var syncedTo waddrmgr.BlockStamp
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		if ns == nil || tx.ReadBucket(wtxmgrNamespaceKey) == nil {
+			return ErrInvalidBackup
+		}
+
+		addrMgr, err := waddrmgr.Open(
+			ns, pubPassphrase, l.chainParams.BTCDParams(),
+		)
+		if err != nil {
+			return err
+		}
+		defer addrMgr.Close()
+
+		syncedTo = addrMgr.SyncedTo()
+		hash, err := addrMgr.BlockHash(ns, syncedTo.Height)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if *hash != syncedTo.Hash {
+			return fmt.Errorf("%w: synced to block %v, but block "+
+				"%v is recorded at height %d", ErrInvalidBackup,
+				syncedTo.Hash, hash, syncedTo.Height)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &syncedTo, nil
+}
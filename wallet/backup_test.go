@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/assets"
+	"github.com/bisoncraft/utxowallet/snacl"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBackupRestore tests that backups of a running wallet can be restored,
+// and that encrypted backups are only restored with their passphrase.
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	netDir := t.TempDir()
+	backupDir := t.TempDir()
+	pubPass := []byte("hello")
+	backupPass := []byte("backup")
+
+	loader := NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+	)
+	_, err := loader.Backup(backupDir, nil)
+	require.ErrorIs(t, err, ErrNotLoaded)
+
+	w, err := loader.CreateNewWallet(
+		pubPass, []byte("world"), nil, time.Now(),
+	)
+	require.NoError(t, err)
+	syncedTo := w.Manager.SyncedTo()
+
+	plainPath, err := loader.Backup(backupDir, nil)
+	require.NoError(t, err)
+	encPath, err := loader.Backup(backupDir, backupPass)
+	require.NoError(t, err)
+
+	_, err = loader.RestoreBackup(plainPath, nil, pubPass)
+	require.ErrorIs(t, err, ErrLoaded)
+	require.NoError(t, loader.UnloadWallet())
+
+	// Encrypted backups require their passphrase, and backups are only
+	// restored if they can be opened with the public passphrase.
+	_, err = loader.RestoreBackup(encPath, nil, pubPass)
+	require.ErrorIs(t, err, ErrBackupPassphrase)
+	_, err = loader.RestoreBackup(encPath, []byte("wrong"), pubPass)
+	require.ErrorIs(t, err, ErrBackupPassphrase)
+	_, err = loader.RestoreBackup(encPath, backupPass, []byte("wrong"))
+	require.Error(t, err)
+	oldPaths, err := filepath.Glob(filepath.Join(netDir, "*.old"))
+	require.NoError(t, err)
+	require.Empty(t, oldPaths)
+
+	stamp, err := loader.RestoreBackup(encPath, backupPass, pubPass)
+	require.NoError(t, err)
+	require.Equal(t, syncedTo, *stamp)
+	require.Equal(t, uint32(restoreRecoveryWindow), loader.recoveryWindow)
+	oldPaths, err = filepath.Glob(filepath.Join(netDir, "*.old"))
+	require.NoError(t, err)
+	require.Len(t, oldPaths, 1)
+
+	_, err = loader.OpenExistingWallet(pubPass, false)
+	require.NoError(t, err)
+	require.NoError(t, loader.UnloadWallet())
+
+	stamp, err = loader.RestoreBackup(plainPath, nil, pubPass)
+	require.NoError(t, err)
+	require.Equal(t, syncedTo, *stamp)
+}
+
+// TestBackupEncryption tests that encrypted backups are decrypted to the
+// data they were written from, and that backups with chunks which were
+// reordered or dropped are rejected.
+func TestBackupEncryption(t *testing.T) {
+	t.Parallel()
+
+	pass := []byte("backup")
+	keyPass := append([]byte(nil), pass...)
+	sk, err := snacl.NewSecretKey(&keyPass, 16, 8, 1)
+	require.NoError(t, err)
+
+	data := make([]byte, 3*backupChunkSize+5)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+
+	// encrypt returns a backup of data, split into chunks at the given
+	// offsets.
+	encrypt := func(offsets ...int) []byte {
+		var b bytes.Buffer
+		params := sk.Marshal()
+		b.Write(backupMagic)
+		b.Write([]byte{backupVersion, byte(len(params))})
+		b.Write(params)
+		encrypter := &backupEncrypter{
+			out: &b,
+			key: sk,
+			buf: make([]byte, 0, backupChunkSize),
+		}
+		offsets = append(offsets, len(data))
+		for i := 1; i < len(offsets); i++ {
+			_, err := encrypter.Write(data[offsets[i-1]:offsets[i]])
+			require.NoError(t, err)
+		}
+		require.NoError(t, encrypter.Close())
+		return b.Bytes()
+	}
+
+	// The chunks don't depend on the sizes of the writes.
+	backup := encrypt(0, 1, backupChunkSize+7, 2*backupChunkSize)
+	require.Len(t, encrypt(0), len(backup))
+	var out bytes.Buffer
+	err = decryptBackup(&out, bytes.NewReader(backup), pass)
+	require.NoError(t, err)
+	require.Equal(t, data, out.Bytes())
+
+	out.Reset()
+	err = decryptBackup(&out, bytes.NewReader(backup), []byte("wrong"))
+	require.ErrorIs(t, err, ErrBackupPassphrase)
+
+	// The chunks of the backup follow its header.
+	headerLen := len(backupMagic) + 2 + len(sk.Marshal())
+	chunkLen := (len(backup) - headerLen - 4 - snacl.NonceSize -
+		snacl.Overhead - backupChunkHeaderSize - 5) / 3
+	chunk := func(i int) []byte {
+		start := headerLen + i*chunkLen
+		return backup[start : start+chunkLen]
+	}
+
+	// Swapping the first two chunks and dropping the last chunk must both
+	// be detected.
+	swapped := append([]byte(nil), backup[:headerLen]...)
+	swapped = append(swapped, chunk(1)...)
+	swapped = append(swapped, chunk(0)...)
+	swapped = append(swapped, backup[headerLen+2*chunkLen:]...)
+	err = decryptBackup(&out, bytes.NewReader(swapped), pass)
+	require.ErrorIs(t, err, ErrInvalidBackup)
+
+	truncated := backup[:headerLen+3*chunkLen]
+	err = decryptBackup(&out, bytes.NewReader(truncated), pass)
+	require.ErrorIs(t, err, ErrInvalidBackup)
+
+	// Unencrypted backups are copied unchanged.
+	out.Reset()
+	err = decryptBackup(&out, bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	require.Equal(t, data, out.Bytes())
+}
@@ -0,0 +1,203 @@
+// Package bip39 implements the BIP-0039 mnemonic code for generating
+// deterministic wallet seeds.
+//
+// A mnemonic encodes 128 to 256 bits of entropy, plus a checksum, as 12 to 24
+// words from a wordlist.  The wallet seed is derived from the mnemonic and an
+// optional passphrase, so the same mnemonic used with different passphrases
+// results in unrelated wallets.  Seeds derived here can be passed directly to
+// hdkeychain.NewMaster to obtain the same master key as any other BIP-0039
+// wallet.
+package bip39
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MinEntropyBits is the minimum entropy size of a mnemonic.
+	MinEntropyBits = 128
+
+	// MaxEntropyBits is the maximum entropy size of a mnemonic.
+	MaxEntropyBits = 256
+
+	// RecommendedEntropyBits is the entropy size of new 24 word mnemonics.
+	RecommendedEntropyBits = 256
+
+	// SeedLen is the length in bytes of the seed derived from a mnemonic.
+	SeedLen = 64
+
+	// seedIterations is the number of PBKDF2 rounds used to derive a seed.
+	seedIterations = 2048
+
+	// bitsPerWord is the number of bits encoded by every mnemonic word.
+	bitsPerWord = 11
+)
+
+var (
+	// ErrInvalidEntropy is returned when the entropy for a mnemonic is not
+	// a multiple of 32 bits between MinEntropyBits and MaxEntropyBits.
+	ErrInvalidEntropy = errors.New("entropy must be a multiple of 32 " +
+		"bits between 128 and 256 bits")
+
+	// ErrInvalidWordCount is returned when a mnemonic does not have 12,
+	// 15, 18, 21 or 24 words.
+	ErrInvalidWordCount = errors.New("mnemonic must have 12, 15, 18, 21 " +
+		"or 24 words")
+
+	// ErrInvalidChecksum is returned when the checksum encoded in a
+	// mnemonic does not match its entropy.
+	ErrInvalidChecksum = errors.New("invalid mnemonic checksum")
+
+	// ErrUnknownWordlist is returned when the words of a mnemonic are not
+	// all from any single registered wordlist.
+	ErrUnknownWordlist = errors.New("mnemonic words are not from a " +
+		"known wordlist")
+)
+
+// validEntropyBits returns whether bits is a valid mnemonic entropy size.
+func validEntropyBits(bits int) bool {
+	return bits >= MinEntropyBits && bits <= MaxEntropyBits && bits%32 == 0
+}
+
+// NewEntropy returns bits of random entropy suitable for NewMnemonic.
+func NewEntropy(bits int) ([]byte, error) {
+	if !validEntropyBits(bits) {
+		return nil, ErrInvalidEntropy
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic encodes entropy as a mnemonic using the words of wl.
+func NewMnemonic(entropy []byte, wl *Wordlist) (string, error) {
+	entropyBits := len(entropy) * 8
+	if !validEntropyBits(entropyBits) {
+		return "", ErrInvalidEntropy
+	}
+
+	// The checksum is the first entropy/32 bits of the entropy's hash,
+	// and is appended to the entropy before it is split into words.
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), checksum[0])
+	numWords := (entropyBits + entropyBits/32) / bitsPerWord
+
+	words := make([]string, numWords)
+	for i := range words {
+		words[i] = wl.words[readBits(data, i*bitsPerWord)]
+	}
+	return strings.Join(words, wl.separator), nil
+}
+
+// EntropyFromMnemonic decodes the entropy of a mnemonic using the words of
+// wl, validating its word count and checksum.
+func EntropyFromMnemonic(mnemonic string, wl *Wordlist) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	numWords := len(words)
+	if numWords < 12 || numWords > 24 || numWords%3 != 0 {
+		return nil, ErrInvalidWordCount
+	}
+
+	entropyBits := numWords * bitsPerWord * 32 / 33
+	checksumBits := entropyBits / 32
+
+	data := make([]byte, entropyBits/8+1)
+	for i, word := range words {
+		idx, ok := wl.Index(word)
+		if !ok {
+			return nil, fmt.Errorf("word %q is not in the %s "+
+				"wordlist", word, wl.name)
+		}
+		writeBits(data, i*bitsPerWord, idx)
+	}
+
+	entropy := data[:entropyBits/8]
+	checksum := sha256.Sum256(entropy)
+	mask := byte(0xff << (8 - checksumBits))
+	if data[len(data)-1]&mask != checksum[0]&mask {
+		return nil, ErrInvalidChecksum
+	}
+
+	return entropy, nil
+}
+
+// DetectWordlist returns the registered wordlist that the mnemonic is valid
+// for.  When the words of the mnemonic appear in more than one wordlist, the
+// first one in name order that also validates the checksum is returned.
+func DetectWordlist(mnemonic string) (*Wordlist, error) {
+	var lastErr error = ErrUnknownWordlist
+	for _, name := range Wordlists() {
+		wl, _ := LookupWordlist(name)
+		_, err := EntropyFromMnemonic(mnemonic, wl)
+		switch {
+		case err == nil:
+			return wl, nil
+
+		// These errors are the same for every wordlist.
+		case errors.Is(err, ErrInvalidWordCount):
+			return nil, err
+
+		case errors.Is(err, ErrInvalidChecksum):
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// NewSeed validates the mnemonic against the registered wordlists and derives
+// the wallet seed from it and the optional passphrase.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := DetectWordlist(mnemonic); err != nil {
+		return nil, err
+	}
+	return seed(mnemonic, passphrase), nil
+}
+
+// seed derives the wallet seed from a mnemonic and passphrase without any
+// validation of the mnemonic.
+func seed(mnemonic, passphrase string) []byte {
+	// The mnemonic is joined using single spaces so that the seed does not
+	// depend on the whitespace used to enter it.  This is the same as the
+	// NFKD normalization of a mnemonic joined by the wordlist separator.
+	words := strings.Fields(mnemonic)
+	for i := range words {
+		words[i] = norm.NFKD.String(words[i])
+	}
+	password := []byte(strings.Join(words, " "))
+	salt := []byte("mnemonic" + norm.NFKD.String(passphrase))
+
+	return pbkdf2.Key(password, salt, seedIterations, SeedLen, sha512.New)
+}
+
+// readBits returns the 11 bits of data starting at bit offset off.
+func readBits(data []byte, off int) int {
+	var v int
+	for i := 0; i < bitsPerWord; i++ {
+		bit := off + i
+		v <<= 1
+		if data[bit/8]&(0x80>>(bit%8)) != 0 {
+			v |= 1
+		}
+	}
+	return v
+}
+
+// writeBits writes the 11 low bits of v to data starting at bit offset off.
+func writeBits(data []byte, off int, v int) {
+	for i := 0; i < bitsPerWord; i++ {
+		if v&(1<<(bitsPerWord-1-i)) != 0 {
+			bit := off + i
+			data[bit/8] |= 0x80 >> (bit % 8)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package bip39
+
+import (
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+// testVectors are a subset of the reference BIP-0039 test vectors, which all
+// use the passphrase "TREZOR".
+var testVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{{
+	entropy:  "00000000000000000000000000000000",
+	mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+}, {
+	entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+	mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+	seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+}, {
+	entropy:  "ffffffffffffffffffffffffffffffff",
+	mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+	seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+}, {
+	entropy:  "808080808080808080808080808080808080808080808080",
+	mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+	seed:     "107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+}, {
+	entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+	mnemonic: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+	seed:     "bc09fca1804f7e69da93c2f2028eb238c227f2e9dda30cd63699232578480a4021b146ad717fbb7e451ce9eb835f43620bf5c514db0f8add49f5d121449d3e87",
+}, {
+	entropy:  "b63a9c59a6e641f288ebc103017f1da9f8290b3da6bdef7b",
+	mnemonic: "renew stay biology evidence goat welcome casual join adapt armor shuffle fault little machine walk stumble urge swap",
+	seed:     "9248d83e06f4cd98debf5b6f010542760df925ce46cf38a1bdb4e4de7d21f5c39366941c69e1bdbf2966e0f6e6dbece898a0e2f0a4c2b3e640953dfe8b7bbdc5",
+}, {
+	entropy:  "3e141609b97933b66a060dcddc71fad1d91677db872031e85f4c015c5e7e8982",
+	mnemonic: "dignity pass list indicate nasty swamp pool script soccer toe leaf photo multiply desk host tomato cradle drill spread actor shine dismiss champion exotic",
+	seed:     "ff7f3184df8696d8bef94b6c03114dbee0ef89ff938712301d27ed8336ca89ef9635da20af07d4175f2bf5f3de130f39c9d9e8dd0472489c19b1a020a940da67",
+}}
+
+// TestVectors tests mnemonic encoding, decoding and seed derivation against
+// the reference test vectors.
+func TestVectors(t *testing.T) {
+	for _, v := range testVectors {
+		entropy, err := hex.DecodeString(v.entropy)
+		require.NoError(t, err)
+
+		mnemonic, err := NewMnemonic(entropy, English)
+		require.NoError(t, err)
+		require.Equal(t, v.mnemonic, mnemonic)
+
+		decoded, err := EntropyFromMnemonic(v.mnemonic, English)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+
+		seed, err := NewSeed(v.mnemonic, "TREZOR")
+		require.NoError(t, err)
+		require.Equal(t, v.seed, hex.EncodeToString(seed))
+	}
+}
+
+// TestMasterKey ensures a seed derived from a mnemonic results in the same
+// master key as other BIP-0039 wallets.
+func TestMasterKey(t *testing.T) {
+	seed, err := NewSeed(testVectors[0].mnemonic, "TREZOR")
+	require.NoError(t, err)
+
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, "xprv9s21ZrQH143K3h3fDYiay8mocZ3afhfULfb5GX8kCBdno"+
+		"77K4HiA15Tg23wpbeF1pLfs1c5SPmYHrEpTuuRhxMwvKDwqdKiGJS9XFKzUsAF",
+		master.String())
+}
+
+// TestInvalidMnemonics tests that mnemonics with the wrong number of words,
+// unknown words or a bad checksum are rejected.
+func TestInvalidMnemonics(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		err      error
+	}{{
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		err:      ErrInvalidWordCount,
+	}, {
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow yellow",
+		err:      ErrInvalidWordCount,
+	}, {
+		mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice caged above",
+		err:      ErrUnknownWordlist,
+	}, {
+		mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo why",
+		err:      ErrUnknownWordlist,
+	}, {
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon letter",
+		err:      ErrInvalidChecksum,
+	}}
+
+	for _, test := range tests {
+		_, err := NewSeed(test.mnemonic, "")
+		require.ErrorIs(t, err, test.err, test.mnemonic)
+	}
+
+	_, err := NewMnemonic(make([]byte, 15), English)
+	require.ErrorIs(t, err, ErrInvalidEntropy)
+	_, err = NewEntropy(100)
+	require.ErrorIs(t, err, ErrInvalidEntropy)
+}
+
+// TestWordlists checks the embedded wordlists against the checksums of the
+// files in the BIP-0039 specification, and that mnemonics in each of them
+// round trip and are detected.
+func TestWordlists(t *testing.T) {
+	checksums := map[string]uint32{
+		"chinese_simplified":  0xe3721bbf,
+		"chinese_traditional": 0x3c20b443,
+		"czech":               0xd1b5fda0,
+		"english":             0xc1dbd296,
+		"french":              0x3e56b216,
+		"italian":             0x2fc7d07e,
+		"japanese":            0x0acc1419,
+		"korean":              0x4ef461eb,
+		"spanish":             0x266e4f3d,
+	}
+
+	for name, checksum := range checksums {
+		b, err := wordlistFiles.ReadFile("wordlists/" + name + ".txt")
+		require.NoError(t, err)
+		require.Equal(t, checksum, crc32.ChecksumIEEE(b), name)
+
+		wl, ok := LookupWordlist(name)
+		require.True(t, ok, name)
+
+		entropy, err := NewEntropy(RecommendedEntropyBits)
+		require.NoError(t, err)
+		mnemonic, err := NewMnemonic(entropy, wl)
+		require.NoError(t, err)
+		require.Len(t, strings.Fields(mnemonic), 24)
+
+		decoded, err := EntropyFromMnemonic(mnemonic, wl)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+
+		// Words shared between wordlists may cause another one to be
+		// detected, but it must decode to the same entropy.
+		detected, err := DetectWordlist(mnemonic)
+		require.NoError(t, err)
+		decoded, err = EntropyFromMnemonic(mnemonic, detected)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+	}
+}
+
+// TestJapaneseSeparator ensures the seed of a mnemonic joined with ideographic
+// spaces matches the seed of the same words joined with regular spaces.
+func TestJapaneseSeparator(t *testing.T) {
+	mnemonic, err := NewMnemonic(make([]byte, 16), Japanese)
+	require.NoError(t, err)
+	require.Contains(t, mnemonic, "\u3000")
+
+	seed1, err := NewSeed(mnemonic, "")
+	require.NoError(t, err)
+	seed2, err := NewSeed(strings.ReplaceAll(mnemonic, "\u3000", " "), "")
+	require.NoError(t, err)
+	require.Equal(t, seed1, seed2)
+}
+
+// TestRegisterWordlist tests that custom wordlists can be registered and are
+// used when detecting the wordlist of a mnemonic.
+func TestRegisterWordlist(t *testing.T) {
+	words := make([]string, WordCount)
+	for i := range words {
+		words[i] = "w" + hex.EncodeToString([]byte{byte(i >> 8), byte(i)})
+	}
+
+	_, err := NewWordlist("short", words[:10], " ")
+	require.Error(t, err)
+
+	wl, err := NewWordlist("test", words, " ")
+	require.NoError(t, err)
+	require.NoError(t, RegisterWordlist(wl))
+	require.Error(t, RegisterWordlist(wl))
+	require.Contains(t, Wordlists(), "test")
+
+	mnemonic, err := NewMnemonic(make([]byte, 16), wl)
+	require.NoError(t, err)
+	detected, err := DetectWordlist(mnemonic)
+	require.NoError(t, err)
+	require.Equal(t, "test", detected.Name())
+}
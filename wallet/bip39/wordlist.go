@@ -0,0 +1,155 @@
+package bip39
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WordCount is the number of words in every BIP-0039 wordlist.
+const WordCount = 2048
+
+//go:embed wordlists/*.txt
+var wordlistFiles embed.FS
+
+// Wordlist is a list of 2048 words that mnemonics are encoded with.
+type Wordlist struct {
+	name      string
+	separator string
+	words     []string
+	index     map[string]int
+}
+
+// NewWordlist creates a wordlist with the given name from exactly 2048 unique
+// words.  The separator is used to join the words of new mnemonics and is
+// usually a single space.  Words are normalized to NFKD form as required by
+// BIP-0039.
+func NewWordlist(name string, words []string, separator string) (*Wordlist, error) {
+	if name == "" {
+		return nil, errors.New("wordlist name must not be empty")
+	}
+	if len(words) != WordCount {
+		return nil, fmt.Errorf("wordlist %s has %d words, expected %d",
+			name, len(words), WordCount)
+	}
+
+	wl := &Wordlist{
+		name:      name,
+		separator: separator,
+		words:     make([]string, len(words)),
+		index:     make(map[string]int, len(words)),
+	}
+	for i, word := range words {
+		word = norm.NFKD.String(strings.TrimSpace(word))
+		if word == "" {
+			return nil, fmt.Errorf("wordlist %s has an empty word "+
+				"at index %d", name, i)
+		}
+		if _, ok := wl.index[word]; ok {
+			return nil, fmt.Errorf("wordlist %s has duplicate "+
+				"word %q", name, word)
+		}
+		wl.words[i] = word
+		wl.index[word] = i
+	}
+
+	return wl, nil
+}
+
+// Name returns the name of the wordlist.
+func (wl *Wordlist) Name() string {
+	return wl.name
+}
+
+// Word returns the word at index i of the wordlist.
+func (wl *Wordlist) Word(i int) string {
+	return wl.words[i]
+}
+
+// Index returns the index of word in the wordlist, and whether it was found.
+func (wl *Wordlist) Index(word string) (int, bool) {
+	i, ok := wl.index[norm.NFKD.String(word)]
+	return i, ok
+}
+
+// The wordlists from the BIP-0039 specification.
+var (
+	English            = mustLoadWordlist("english", " ")
+	Spanish            = mustLoadWordlist("spanish", " ")
+	French             = mustLoadWordlist("french", " ")
+	Italian            = mustLoadWordlist("italian", " ")
+	Czech              = mustLoadWordlist("czech", " ")
+	Japanese           = mustLoadWordlist("japanese", "\u3000")
+	Korean             = mustLoadWordlist("korean", " ")
+	ChineseSimplified  = mustLoadWordlist("chinese_simplified", " ")
+	ChineseTraditional = mustLoadWordlist("chinese_traditional", " ")
+)
+
+// mustLoadWordlist loads one of the embedded wordlists and registers it,
+// panicking on failure.
+func mustLoadWordlist(name, separator string) *Wordlist {
+	b, err := wordlistFiles.ReadFile("wordlists/" + name + ".txt")
+	if err != nil {
+		panic(err)
+	}
+	words := strings.Split(strings.TrimSpace(string(b)), "\n")
+	wl, err := NewWordlist(name, words, separator)
+	if err != nil {
+		panic(err)
+	}
+	if err := RegisterWordlist(wl); err != nil {
+		panic(err)
+	}
+	return wl
+}
+
+var (
+	wordlistsMtx sync.RWMutex
+	wordlists    = make(map[string]*Wordlist)
+)
+
+// RegisterWordlist makes a wordlist available to LookupWordlist and to
+// DetectWordlist, which NewSeed validates mnemonics with.  The built-in
+// wordlists are registered automatically.  It is an error to register two
+// wordlists with the same name.
+func RegisterWordlist(wl *Wordlist) error {
+	if wl == nil {
+		return errors.New("nil wordlist")
+	}
+
+	wordlistsMtx.Lock()
+	defer wordlistsMtx.Unlock()
+
+	if _, ok := wordlists[wl.name]; ok {
+		return fmt.Errorf("wordlist %s already registered", wl.name)
+	}
+	wordlists[wl.name] = wl
+	return nil
+}
+
+// LookupWordlist returns the registered wordlist with the given name.
+func LookupWordlist(name string) (*Wordlist, bool) {
+	wordlistsMtx.RLock()
+	defer wordlistsMtx.RUnlock()
+
+	wl, ok := wordlists[name]
+	return wl, ok
+}
+
+// Wordlists returns the sorted names of all registered wordlists.
+func Wordlists() []string {
+	wordlistsMtx.RLock()
+	defer wordlistsMtx.RUnlock()
+
+	names := make([]string, 0, len(wordlists))
+	for name := range wordlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
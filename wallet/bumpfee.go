@@ -0,0 +1,447 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrTxNotReplaceable is returned by BumpFee when the transaction does
+	// not signal replaceability as described by BIP-0125.
+	ErrTxNotReplaceable = errors.New("transaction does not signal " +
+		"replaceability")
+
+	// ErrTxMined is returned by BumpFee when the transaction has already
+	// been mined.
+	ErrTxMined = errors.New("transaction has already been mined")
+
+	// ErrReplacementFeeTooLow is returned by BumpFee when the fee rate of
+	// the replacement transaction does not exceed the fee rate of the
+	// original transaction.
+	ErrReplacementFeeTooLow = errors.New("replacement fee too low")
+)
+
+// BumpFee replaces the unmined transaction with the hash provided by a
+// transaction paying a higher fee at the fee rate provided, as described by
+// BIP-0125.  The transaction must have been created by the wallet and signal
+// replaceability, which is the case for all transactions created by the
+// wallet.
+//
+// The replacement pays the same outputs and spends the same inputs as the
+// original transaction, with the additional fee taken from the change output.
+// If the change does not cover the additional fee, the change output is
+// removed and confirmed outputs of the same account are added as inputs.  The
+// fee rate is raised when needed for the replacement to pay the fees of the
+// transactions it replaces and its own relay fee, as required by BIP-0125.  The
+// replacement is signed and published, after which the original transaction
+// and all transactions depending on it are removed from the wallet.  The label
+// of the original transaction is carried over to the replacement.
+func (w *Wallet) BumpFee(txid chainhash.Hash,
+	feeSatPerKb btcutil.Amount) (*wire.MsgTx, error) {
+
+	chainClient, err := w.requireChainClient()
+	if err != nil {
+		return nil, err
+	}
+	bs, err := chainClient.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+
+	// A change address may be created for the replacement, so the address
+	// creation is guarded like it is for any other created transaction.
+	w.newAddrMtx.Lock()
+	var (
+		tx    *txauthor.AuthoredTx
+		label string
+	)
+	err = walletdb.Update(w.db, func(dbtx walletdb.ReadWriteTx) error {
+		var err error
+		tx, label, err = w.replacementTx(dbtx, txid, feeSatPerKb, bs)
+		return err
+	})
+	w.newAddrMtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.reliablyPublishTransaction(tx.Tx, label)
+	if err != nil {
+		return nil, err
+	}
+
+	// Now that the replacement has been accepted, the replaced
+	// transactions will no longer be mined, so we remove them to stop
+	// rebroadcasting them.
+	err = walletdb.Update(w.db, func(dbtx walletdb.ReadWriteTx) error {
+		txmgrNs := dbtx.ReadWriteBucket(wtxmgrNamespaceKey)
+		rec, err := wtxmgr.NewTxRecordFromMsgTx(tx.Tx, time.Now())
+		if err != nil {
+			return err
+		}
+		return w.TxStore.ReplaceUnminedTx(txmgrNs, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.log.Infof("Replaced transaction %v with %v", txid, tx.Tx.TxHash())
+
+	return tx.Tx, nil
+}
+
+// replacementTx creates and signs the replacement of an unmined transaction
+// for BumpFee.  The label of the replaced transaction is returned with it.
+func (w *Wallet) replacementTx(dbtx walletdb.ReadWriteTx, txid chainhash.Hash,
+	feeSatPerKb btcutil.Amount, bs *waddrmgr.BlockStamp) (
+	*txauthor.AuthoredTx, string, error) {
+
+	addrmgrNs := dbtx.ReadWriteBucket(waddrmgrNamespaceKey)
+	txmgrNs := dbtx.ReadWriteBucket(wtxmgrNamespaceKey)
+
+	details, err := w.TxStore.TxDetails(txmgrNs, &txid)
+	if err != nil {
+		return nil, "", err
+	}
+	if details == nil {
+		return nil, "", ErrNoTx
+	}
+	if details.Block.Height != -1 {
+		return nil, "", ErrTxMined
+	}
+	if !signalsReplacement(&details.MsgTx) {
+		return nil, "", ErrTxNotReplaceable
+	}
+
+	// We can only re-sign the transaction, and know the fee it pays, if
+	// all of its inputs are ours.
+	if len(details.Debits) != len(details.MsgTx.TxIn) {
+		return nil, "", errors.New("transaction spends outputs not " +
+			"controlled by the wallet")
+	}
+
+	// The replacement must pay a higher fee rate than the original
+	// transaction.
+	origFee := txFee(details)
+	origSize := mempool.GetTxVirtualSize(btcutil.NewTx(&details.MsgTx))
+	origFeeRate := origFee * 1000 / btcutil.Amount(origSize)
+	if feeSatPerKb <= origFeeRate {
+		return nil, "", fmt.Errorf("%w: fee rate of %v/kvB must exceed "+
+			"the fee rate of %v/kvB of the original transaction",
+			ErrReplacementFeeTooLow, feeSatPerKb, origFeeRate)
+	}
+
+	// Look up the outputs spent by the original transaction, which the
+	// replacement spends as well.
+	original := make([]Coin, 0, len(details.MsgTx.TxIn))
+	for _, txIn := range details.MsgTx.TxIn {
+		prevOut, err := w.previousOutput(
+			txmgrNs, &txIn.PreviousOutPoint,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		original = append(original, Coin{
+			TxOut:    *prevOut,
+			OutPoint: txIn.PreviousOutPoint,
+		})
+	}
+
+	// Additional inputs are taken from the account of the original inputs.
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		original[0].PkScript, w.chainParams,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(addrs) != 1 {
+		return nil, "", errors.New("unable to determine the account " +
+			"of the transaction")
+	}
+	scopedMgr, account, err := w.Manager.AddrAccount(addrmgrNs, addrs[0])
+	if err != nil {
+		return nil, "", err
+	}
+	scope := scopedMgr.Scope()
+	watchOnly, err := w.Manager.IsWatchOnlyAccount(
+		addrmgrNs, scope, account,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	if watchOnly {
+		return nil, "", errors.New("cannot bump the fee of a " +
+			"transaction of a watch-only account")
+	}
+
+	// The replacement must also pay for the transactions that depend on
+	// the original transaction, since they are replaced as well.  They
+	// are found in dependency order, so descendants of descendants are
+	// found too.  Only the fees of descendants spending our own outputs
+	// are known.
+	replaced := map[chainhash.Hash]struct{}{txid: {}}
+	unmined, err := w.TxStore.UnminedTxs(txmgrNs)
+	if err != nil {
+		return nil, "", err
+	}
+	var descendantFees btcutil.Amount
+	for _, unminedTx := range unmined {
+		if !spendsAny(unminedTx, replaced) {
+			continue
+		}
+		hash := unminedTx.TxHash()
+		replaced[hash] = struct{}{}
+
+		descendant, err := w.TxStore.TxDetails(txmgrNs, &hash)
+		if err != nil {
+			return nil, "", err
+		}
+		if descendant != nil &&
+			len(descendant.Debits) == len(unminedTx.TxIn) {
+
+			descendantFees += txFee(descendant)
+		}
+	}
+
+	// Additional inputs must be confirmed, as BIP-0125 does not allow a
+	// replacement to add unconfirmed inputs.
+	eligible, err := w.findEligibleOutputs(
+		dbtx, &scope, account, 1, bs, func(utxo wtxmgr.Credit) bool {
+			_, ok := replaced[utxo.Hash]
+			return !ok
+		},
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	additional := make([]Coin, len(eligible))
+	for i := range eligible {
+		additional[i] = Coin{
+			TxOut: wire.TxOut{
+				Value:    int64(eligible[i].Amount),
+				PkScript: eligible[i].PkScript,
+			},
+			OutPoint: eligible[i].OutPoint,
+		}
+	}
+	additional, err = CoinSelectionLargest.ArrangeCoins(
+		additional, CoinSelectionParams{FeeSatPerKb: feeSatPerKb},
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The replacement pays the same outputs, except for the change, which
+	// is paid to the same script if there was any.
+	var (
+		outputs      []*wire.TxOut
+		changeScript []byte
+	)
+	for i, txOut := range details.MsgTx.TxOut {
+		if isChangeOutput(details, uint32(i)) {
+			if changeScript == nil {
+				changeScript = txOut.PkScript
+			}
+			continue
+		}
+		outputs = append(outputs, wire.NewTxOut(
+			txOut.Value, txOut.PkScript,
+		))
+	}
+	var changeSource *txauthor.ChangeSource
+	if changeScript != nil {
+		changeSource = &txauthor.ChangeSource{
+			NewScript: func() ([]byte, error) {
+				return changeScript, nil
+			},
+			ScriptSize: len(changeScript),
+		}
+	} else {
+		_, newChange, err := w.addrMgrWithChangeSource(
+			dbtx, &scope, account,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// The replacement may be authored more than once, but only
+		// one change address is used.
+		changeSource = &txauthor.ChangeSource{
+			NewScript: func() ([]byte, error) {
+				if changeScript != nil {
+					return changeScript, nil
+				}
+				var err error
+				changeScript, err = newChange.NewScript()
+				return changeScript, err
+			},
+			ScriptSize: newChange.ScriptSize,
+		}
+	}
+
+	// The replacement must pay for the transactions it replaces and for
+	// its own relay.  When the fee rate does not cover that minimum fee,
+	// the replacement is authored again at the fee rate paying it, so that
+	// additional inputs are selected to fund the shortfall.
+	feeRate := feeSatPerKb
+	for {
+		tx, err := w.authorReplacement(
+			addrmgrNs, details, outputs, feeRate,
+			replacementInputSource(original, additional),
+			changeSource,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+
+		fee := tx.TotalInput - txauthor.SumOutputValues(tx.Tx.TxOut)
+		size := btcutil.Amount(
+			mempool.GetTxVirtualSize(btcutil.NewTx(tx.Tx)),
+		)
+		relayFee := txrules.FeeForSerializeSize(
+			txrules.DefaultRelayFeePerKb, int(size), w.netParams,
+		)
+		minFee := origFee + descendantFees + relayFee
+		if fee >= minFee {
+			return tx, details.Label, nil
+		}
+
+		// Should more inputs be needed at the raised fee rate, the
+		// replacement grows, and the minimum fee is checked again.
+		raised := (minFee*1000 + size - 1) / size
+		if raised <= feeRate {
+			raised = feeRate + 1
+		}
+		feeRate = raised
+	}
+}
+
+// authorReplacement creates and signs a replacement paying the outputs at the
+// fee rate.  The replacement keeps the lock time, version and input sequence
+// numbers of the original transaction, which may set lock times.
+func (w *Wallet) authorReplacement(addrmgrNs walletdb.ReadBucket,
+	details *wtxmgr.TxDetails, outputs []*wire.TxOut,
+	feeRate btcutil.Amount, inputSource txauthor.InputSource,
+	changeSource *txauthor.ChangeSource) (*txauthor.AuthoredTx, error) {
+
+	tx, err := txauthor.NewUnsignedTransaction(
+		outputs, feeRate, inputSource, changeSource, w.netParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.Tx.LockTime = details.MsgTx.LockTime
+	tx.Tx.Version = details.MsgTx.Version
+	for _, txIn := range tx.Tx.TxIn {
+		for _, origIn := range details.MsgTx.TxIn {
+			if txIn.PreviousOutPoint == origIn.PreviousOutPoint {
+				txIn.Sequence = origIn.Sequence
+			}
+		}
+	}
+
+	if tx.ChangeIndex >= 0 {
+		tx.RandomizeChangePosition()
+	}
+
+	err = tx.AddAllInputScripts(secretSource{w.Manager, addrmgrNs})
+	if err != nil {
+		return nil, err
+	}
+	err = validateMsgTx(tx.Tx, tx.PrevScripts, tx.PrevInputValues)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// previousOutput returns the output spent by a wallet transaction input.
+func (w *Wallet) previousOutput(txmgrNs walletdb.ReadBucket,
+	op *wire.OutPoint) (*wire.TxOut, error) {
+
+	prevTx, err := w.TxStore.TxDetails(txmgrNs, &op.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if prevTx == nil || int(op.Index) >= len(prevTx.MsgTx.TxOut) {
+		return nil, fmt.Errorf("previous output %v not found", op)
+	}
+	return prevTx.MsgTx.TxOut[op.Index], nil
+}
+
+// replacementInputSource returns an input source that spends all inputs of
+// the original transaction, followed by as many additional coins as needed.
+func replacementInputSource(original, additional []Coin) txauthor.InputSource {
+	var originalTotal btcutil.Amount
+	for _, coin := range original {
+		originalTotal += btcutil.Amount(coin.Value)
+	}
+
+	l := len(original)
+	inputSource := makeInputSource(append(original[:l:l], additional...))
+
+	return func(target btcutil.Amount) (btcutil.Amount, []*wire.TxIn,
+		[]btcutil.Amount, [][]byte, error) {
+
+		if target < originalTotal {
+			target = originalTotal
+		}
+		return inputSource(target)
+	}
+}
+
+// signalsReplacement returns whether a transaction signals replaceability as
+// described by BIP-0125.  Replaceability inherited from unconfirmed ancestors
+// is not considered.
+func signalsReplacement(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// spendsAny returns whether the transaction spends an output of any of the
+// transactions provided.
+func spendsAny(tx *wire.MsgTx, hashes map[chainhash.Hash]struct{}) bool {
+	for _, txIn := range tx.TxIn {
+		if _, ok := hashes[txIn.PreviousOutPoint.Hash]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isChangeOutput returns whether the output of a wallet transaction is one of
+// our change outputs.
+func isChangeOutput(details *wtxmgr.TxDetails, index uint32) bool {
+	for _, cred := range details.Credits {
+		if cred.Index == index {
+			return cred.Change
+		}
+	}
+	return false
+}
+
+// txFee returns the fee of a transaction all inputs of which are debits.
+func txFee(details *wtxmgr.TxDetails) btcutil.Amount {
+	var debitTotal btcutil.Amount
+	for _, deb := range details.Debits {
+		debitTotal += deb.Amount
+	}
+	return debitTotal - txauthor.SumOutputValues(details.MsgTx.TxOut)
+}
@@ -0,0 +1,138 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBumpFee tests that a transaction created by the wallet is replaced by a
+// transaction paying a higher fee, first by shrinking its change and then by
+// adding inputs.
+func TestBumpFee(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	// Fund the wallet with two confirmed outputs.
+	addr, err := w.CurrentAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	addUtxo(t, w, &wire.MsgTx{
+		TxIn: []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{
+			wire.NewTxOut(100000, pkScript),
+			wire.NewTxOut(50000, pkScript),
+		},
+	})
+
+	// Pay to an address that is not ours from the largest output.
+	payee, err := btcutil.NewAddressWitnessPubKeyHash(
+		make([]byte, 20), w.chainParams,
+	)
+	require.NoError(t, err)
+	payeeScript, err := txscript.PayToAddrScript(payee)
+	require.NoError(t, err)
+	payment := wire.NewTxOut(60000, payeeScript)
+
+	created, err := w.txToOutputs(
+		[]*wire.TxOut{payment}, nil, nil, 0, 1, 1000,
+		CoinSelectionLargest, false, nil, alwaysAllowUtxo, nil,
+	)
+	require.NoError(t, err)
+	require.Len(t, created.Tx.TxIn, 1)
+	require.Equal(t, txauthor.ReplaceableSequence,
+		created.Tx.TxIn[0].Sequence)
+	require.NoError(t, w.PublishTransaction(created.Tx, "payment"))
+	origHash := created.Tx.TxHash()
+	origChange := created.Tx.TxOut[created.ChangeIndex].Value
+
+	// The fee rate must be increased.
+	_, err = w.BumpFee(origHash, 1000)
+	require.ErrorIs(t, err, ErrReplacementFeeTooLow)
+
+	_, err = w.BumpFee(chainhash.Hash{1}, 5000)
+	require.ErrorIs(t, err, ErrNoTx)
+
+	// A moderate fee increase is taken from the change.
+	bumped, err := w.BumpFee(origHash, 5000)
+	require.NoError(t, err)
+	require.Len(t, bumped.TxIn, 1)
+	require.Equal(t, created.Tx.TxIn[0].PreviousOutPoint,
+		bumped.TxIn[0].PreviousOutPoint)
+	require.Len(t, bumped.TxOut, 2)
+	for _, txOut := range bumped.TxOut {
+		if txOut.Value == payment.Value {
+			require.Equal(t, payeeScript, txOut.PkScript)
+			continue
+		}
+		require.Less(t, txOut.Value, origChange)
+	}
+	assertUnminedTxs(t, w, bumped.TxHash())
+
+	details, err := w.GetTransaction(bumped.TxHash())
+	require.NoError(t, err)
+	require.Equal(t, "payment", details.Summary.Label)
+
+	// A fee rate increase smaller than the incremental relay fee rate
+	// still pays the fee of the replaced transaction and its own relay.
+	bumpedFee := walletTxFee(t, w, bumped)
+	bumped2, err := w.BumpFee(bumped.TxHash(), 5500)
+	require.NoError(t, err)
+	size := mempool.GetTxVirtualSize(btcutil.NewTx(bumped2))
+	require.GreaterOrEqual(t, walletTxFee(t, w, bumped2),
+		bumpedFee+btcutil.Amount(size))
+	assertUnminedTxs(t, w, bumped2.TxHash())
+
+	// A fee increase exceeding the change requires another input.
+	bumped3, err := w.BumpFee(bumped2.TxHash(), 300000)
+	require.NoError(t, err)
+	require.Len(t, bumped3.TxIn, 2)
+	assertUnminedTxs(t, w, bumped3.TxHash())
+}
+
+// walletTxFee returns the fee of a wallet transaction.
+func walletTxFee(t *testing.T, w *Wallet, tx *wire.MsgTx) btcutil.Amount {
+	t.Helper()
+
+	var fee btcutil.Amount
+	err := walletdb.View(w.db, func(dbtx walletdb.ReadTx) error {
+		txmgrNs := dbtx.ReadBucket(wtxmgrNamespaceKey)
+		hash := tx.TxHash()
+		details, err := w.TxStore.TxDetails(txmgrNs, &hash)
+		require.NoError(t, err)
+		require.NotNil(t, details)
+		fee = txFee(details)
+		return nil
+	})
+	require.NoError(t, err)
+	return fee
+}
+
+// assertUnminedTxs asserts that the wallet's unmined transactions are exactly
+// the transactions provided.
+func assertUnminedTxs(t *testing.T, w *Wallet, hashes ...chainhash.Hash) {
+	t.Helper()
+
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		txmgrNs := tx.ReadBucket(wtxmgrNamespaceKey)
+		unmined, err := w.TxStore.UnminedTxHashes(txmgrNs)
+		require.NoError(t, err)
+		require.Len(t, unmined, len(hashes))
+		for i := range hashes {
+			require.Contains(t, unmined, &hashes[i])
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
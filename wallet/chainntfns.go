@@ -15,6 +15,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 const (
@@ -29,7 +30,8 @@ func (w *Wallet) handleChainNotifications() {
 
 	chainClient, err := w.requireChainClient()
 	if err != nil {
-		log.Errorf("handleChainNotifications called without RPC client")
+		w.log.Errorf("handleChainNotifications called without RPC " +
+			"client")
 		return
 	}
 
@@ -45,7 +47,7 @@ func (w *Wallet) handleChainNotifications() {
 		// if it doesn't match the original hash returned by
 		// the notification, to roll back and restart the
 		// rescan.
-		log.Infof("Catching up block hashes to height %d, this"+
+		w.log.Infof("Catching up block hashes to height %d, this"+
 			" might take a while", height)
 		err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
 			ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
@@ -75,11 +77,11 @@ func (w *Wallet) handleChainNotifications() {
 			return nil
 		})
 		if err != nil {
-			log.Errorf("Failed to update address manager "+
+			w.log.Errorf("Failed to update address manager "+
 				"sync state for height %d: %v", height, err)
 		}
 
-		log.Info("Done catching up block hashes")
+		w.log.Info("Done catching up block hashes")
 		return err
 	}
 
@@ -103,7 +105,7 @@ func (w *Wallet) handleChainNotifications() {
 						return ErrWalletShuttingDown
 					}
 
-					log.Errorf("Unable to synchronize "+
+					w.log.Errorf("Unable to synchronize "+
 						"wallet to chain, trying "+
 						"again in %s: %v",
 						w.syncRetryInterval, err)
@@ -139,21 +141,22 @@ func (w *Wallet) handleChainNotifications() {
 					manager: w.Manager,
 				}
 				birthdayBlock, err := birthdaySanityCheck(
-					chainClient, birthdayStore,
+					w.log, chainClient, birthdayStore,
 				)
 				if err != nil && !waddrmgr.IsError(
 					err, waddrmgr.ErrBirthdayBlockNotSet,
 				) {
 
-					log.Errorf("Unable to sanity check "+
+					w.log.Errorf("Unable to sanity check "+
 						"wallet birthday block: %v",
 						err)
 				}
 
 				err = waitForSync(birthdayBlock)
 				if err != nil {
-					log.Infof("Stopped waiting for wallet "+
-						"sync due to error: %v", err)
+					w.log.Infof("Stopped waiting for "+
+						"wallet sync due to error: %v",
+						err)
 
 					return
 				}
@@ -220,14 +223,14 @@ func (w *Wallet) handleChainNotifications() {
 					waddrmgr.IsError(err, waddrmgr.ErrBlockNotFound) &&
 					!w.ChainSynced() {
 
-					log.Debugf("Received block connected "+
-						"notification for height %v "+
-						"while rescanning",
+					w.log.Debugf("Received block "+
+						"connected notification for "+
+						"height %v while rescanning",
 						n.(chain.BlockConnected).Height)
 					continue
 				}
 
-				log.Errorf("Unable to process chain backend "+
+				w.log.Errorf("Unable to process chain backend "+
 					"%v notification: %v", notificationName,
 					err)
 			}
@@ -386,7 +389,20 @@ func (w *Wallet) addRelevantTx(dbtx walletdb.ReadWriteTx, rec *wtxmgr.TxRecord,
 			if err != nil {
 				return err
 			}
-			log.Debugf("Marked address %v used", addr)
+			w.log.Debugf("Marked address %v used", addr)
+
+			// Other cosigners of multisig accounts issue addresses
+			// too, so the addresses following the used one are
+			// watched for as well.
+			msAddr, ok := ma.(waddrmgr.ManagedMultisigAddress)
+			if ok {
+				err := w.extendMultisigAddresses(
+					addrmgrNs, scopedManager, msAddr,
+				)
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -397,7 +413,8 @@ func (w *Wallet) addRelevantTx(dbtx walletdb.ReadWriteTx, rec *wtxmgr.TxRecord,
 	if block == nil {
 		details, err := w.TxStore.UniqueTxDetails(txmgrNs, &rec.Hash, nil)
 		if err != nil {
-			log.Errorf("Cannot query transaction details for notification: %v", err)
+			w.log.Errorf("Cannot query transaction details for "+
+				"notification: %v", err)
 		}
 
 		// It's possible that the transaction was not found within the
@@ -413,7 +430,8 @@ func (w *Wallet) addRelevantTx(dbtx walletdb.ReadWriteTx, rec *wtxmgr.TxRecord,
 	} else {
 		details, err := w.TxStore.UniqueTxDetails(txmgrNs, &rec.Hash, &block.Block)
 		if err != nil {
-			log.Errorf("Cannot query transaction details for notification: %v", err)
+			w.log.Errorf("Cannot query transaction details for "+
+				"notification: %v", err)
 		}
 
 		// We'll only notify the transaction if it was found within the
@@ -518,7 +536,7 @@ func (s *walletBirthdayStore) SetBirthdayBlock(block waddrmgr.BlockStamp) error
 // block to ensure we do not miss any relevant events throughout rescans.
 // waddrmgr.ErrBirthdayBlockNotSet is returned if the birthday block has not
 // been set yet.
-func birthdaySanityCheck(chainConn chainConn,
+func birthdaySanityCheck(logger btclog.Logger, chainConn chainConn,
 	birthdayStore birthdayStore) (*waddrmgr.BlockStamp, error) {
 
 	// We'll start by fetching our wallet's birthday timestamp and block.
@@ -532,7 +550,7 @@ func birthdaySanityCheck(chainConn chainConn,
 	// exit our sanity check to prevent potentially fetching a better
 	// candidate.
 	if birthdayBlockVerified {
-		log.Debugf("Birthday block has already been verified: "+
+		logger.Debugf("Birthday block has already been verified: "+
 			"height=%d, hash=%v", birthdayBlock.Height,
 			birthdayBlock.Hash)
 
@@ -541,7 +559,9 @@ func birthdaySanityCheck(chainConn chainConn,
 
 	// Otherwise, we'll attempt to locate a better one now that we have
 	// access to the chain.
-	newBirthdayBlock, err := locateBirthdayBlock(chainConn, birthdayTimestamp)
+	newBirthdayBlock, err := locateBirthdayBlock(
+		logger, chainConn, birthdayTimestamp,
+	)
 	if err != nil {
 		return nil, err
 	}
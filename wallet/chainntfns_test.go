@@ -150,7 +150,7 @@ func TestBirthdaySanityCheckEmptyBirthdayBlock(t *testing.T) {
 	// set, so we should not attempt a sanity check.
 	birthdayStore := &mockBirthdayStore{}
 
-	birthdayBlock, err := birthdaySanityCheck(chainConn, birthdayStore)
+	birthdayBlock, err := birthdaySanityCheck(log, chainConn, birthdayStore)
 	if !waddrmgr.IsError(err, waddrmgr.ErrBirthdayBlockNotSet) {
 		t.Fatalf("expected ErrBirthdayBlockNotSet, got %v", err)
 	}
@@ -184,7 +184,7 @@ func TestBirthdaySanityCheckVerifiedBirthdayBlock(t *testing.T) {
 
 	// Now, we'll run the sanity check. We should see that the birthday
 	// block hasn't changed.
-	birthdayBlock, err := birthdaySanityCheck(chainConn, birthdayStore)
+	birthdayBlock, err := birthdaySanityCheck(log, chainConn, birthdayStore)
 	if err != nil {
 		t.Fatalf("unable to sanity check birthday block: %v", err)
 	}
@@ -236,7 +236,7 @@ func TestBirthdaySanityCheckLowerEstimate(t *testing.T) {
 
 	// We'll perform the sanity check and determine whether we were able to
 	// find a better birthday block candidate.
-	birthdayBlock, err := birthdaySanityCheck(chainConn, birthdayStore)
+	birthdayBlock, err := birthdaySanityCheck(log, chainConn, birthdayStore)
 	if err != nil {
 		t.Fatalf("unable to sanity check birthday block: %v", err)
 	}
@@ -290,7 +290,7 @@ func TestBirthdaySanityCheckHigherEstimate(t *testing.T) {
 
 	// We'll perform the sanity check and determine whether we were able to
 	// find a better birthday block candidate.
-	birthdayBlock, err := birthdaySanityCheck(chainConn, birthdayStore)
+	birthdayBlock, err := birthdaySanityCheck(log, chainConn, birthdayStore)
 	if err != nil {
 		t.Fatalf("unable to sanity check birthday block: %v", err)
 	}
@@ -0,0 +1,428 @@
+package wallet
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/bisoncraft/utxowallet/wallet/txsizes"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// DefaultLongTermFeeSatPerKb is the fee rate coins are expected to be
+	// spent at in the future, used to weigh the cost of spending coins now
+	// rather than later.
+	DefaultLongTermFeeSatPerKb btcutil.Amount = 10000
+
+	// bnbTotalTries is the maximum number of steps the branch-and-bound
+	// search takes before settling for the best selection found.
+	bnbTotalTries = 100000
+
+	// knapsackIterations is the number of random subsets the knapsack
+	// solver tries.
+	knapsackIterations = 1000
+)
+
+// newCoinSelectionParams returns the coin selection parameters of a
+// transaction paying to the outputs at the fee rate provided, with change
+// scripts of the size provided.
+func newCoinSelectionParams(outputs []*wire.TxOut, feeSatPerKb btcutil.Amount,
+	changeScriptSize int) CoinSelectionParams {
+
+	// The segwit marker and flag added with the first witness input are
+	// accounted for by an additional vbyte.
+	baseSize := txsizes.EstimateVirtualSize(0, 0, 0, 0, outputs, 0) + 1
+	changeSize := txsizes.EstimateVirtualSize(
+		0, 0, 0, 0, outputs, changeScriptSize,
+	) + 1 - baseSize
+
+	// The change script is assumed to be a witness program, which has the
+	// lowest dust threshold, so that excess value up to the change cost is
+	// never returned as change.
+	changeScript := make([]byte, changeScriptSize)
+	if changeScriptSize >= 4 && changeScriptSize <= 42 {
+		changeScript[0] = txscript.OP_0
+		changeScript[1] = byte(changeScriptSize - 2)
+	}
+	dustThreshold := mempool.GetDustThreshold(
+		wire.NewTxOut(0, changeScript),
+	)
+	largestDust := (txrules.DefaultRelayFeePerKb*
+		btcutil.Amount(dustThreshold)+999)/1000 - 1
+
+	return CoinSelectionParams{
+		FeeSatPerKb:         feeSatPerKb,
+		LongTermFeeSatPerKb: DefaultLongTermFeeSatPerKb,
+		Target: txauthor.SumOutputValues(outputs) +
+			feeForSize(feeSatPerKb, baseSize),
+		ChangeCost: feeSatPerKb*btcutil.Amount(changeSize)/1000 +
+			largestDust,
+	}
+}
+
+// feeForSize returns the fee of the virtual size provided at the fee rate
+// provided, rounded up.
+func feeForSize(feeSatPerKb btcutil.Amount, size int) btcutil.Amount {
+	return (feeSatPerKb*btcutil.Amount(size) + 999) / 1000
+}
+
+// selectionCoin is a coin along with the fees paid to spend it.
+type selectionCoin struct {
+	Coin
+
+	// effectiveValue is the value of the coin less the fee paid to spend
+	// it.
+	effectiveValue btcutil.Amount
+
+	// fee and longTermFee are the fees paid to spend the coin at the fee
+	// rate and long-term fee rate.
+	fee         btcutil.Amount
+	longTermFee btcutil.Amount
+}
+
+// selectionCoins returns the coins that yield positively at the fee rate,
+// sorted by decreasing effective value.
+func selectionCoins(eligible []Coin,
+	params CoinSelectionParams) []selectionCoin {
+
+	coins := make([]selectionCoin, 0, len(eligible))
+	for _, coin := range eligible {
+		size := txsizes.GetMinInputVirtualSize(coin.PkScript)
+		fee := feeForSize(params.FeeSatPerKb, size)
+		effectiveValue := btcutil.Amount(coin.Value) - fee
+		if effectiveValue <= 0 {
+			continue
+		}
+		coins = append(coins, selectionCoin{
+			Coin:           coin,
+			effectiveValue: effectiveValue,
+			fee:            fee,
+			longTermFee: feeForSize(
+				params.LongTermFeeSatPerKb, size,
+			),
+		})
+	}
+
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].effectiveValue > coins[j].effectiveValue
+	})
+
+	return coins
+}
+
+// unwrapCoins returns the coins of the selection coins.
+func unwrapCoins(selected []selectionCoin) []Coin {
+	coins := make([]Coin, len(selected))
+	for i := range selected {
+		coins[i] = selected[i].Coin
+	}
+	return coins
+}
+
+// arrangeSelected returns the selected coins followed by the coins that were
+// not selected, so that inputs can still be added from the latter should the
+// selection not cover the fee of the transaction after all.
+func arrangeSelected(selected, coins []selectionCoin) []Coin {
+	isSelected := make(map[wire.OutPoint]struct{}, len(selected))
+	for _, coin := range selected {
+		isSelected[coin.OutPoint] = struct{}{}
+	}
+
+	arranged := unwrapCoins(selected)
+	for _, coin := range coins {
+		if _, ok := isSelected[coin.OutPoint]; !ok {
+			arranged = append(arranged, coin.Coin)
+		}
+	}
+	return arranged
+}
+
+// selectBranchAndBound searches the coins, which must be sorted by decreasing
+// effective value, for the set with effective values summing up to at least
+// the target and at most the target plus the change cost, so that no change
+// output is created. Of the sets found, the one with the least waste is
+// returned. The waste of a set is the excess over the target, which is paid as
+// fee, and the difference between spending the coins at the fee rate rather
+// than the long-term fee rate. Nil is returned if no set is found.
+//
+// This is the branch-and-bound algorithm of Bitcoin Core, exploring the
+// inclusion and then the omission of each coin in a depth-first search.
+func selectBranchAndBound(coins []selectionCoin,
+	target, changeCost btcutil.Amount) []selectionCoin {
+
+	if len(coins) == 0 {
+		return nil
+	}
+
+	var available btcutil.Amount
+	for _, coin := range coins {
+		available += coin.effectiveValue
+	}
+	if available < target {
+		return nil
+	}
+
+	// When the fee rate is higher than the long-term fee rate, the waste
+	// only grows as coins are added, so branches wasting more than the
+	// best set found can be cut.
+	highFeeRate := coins[0].fee > coins[0].longTermFee
+
+	var (
+		value, waste btcutil.Amount
+		selection    []int
+		best         []int
+		bestWaste    = btcutil.Amount(math.MaxInt64)
+	)
+	for try, i := 0, 0; try < bnbTotalTries; try, i = try+1, i+1 {
+		backtrack := false
+		switch {
+		case value+available < target,
+			value > target+changeCost,
+			highFeeRate && waste > bestWaste:
+
+			backtrack = true
+
+		case value >= target:
+			if waste+value-target <= bestWaste {
+				best = append(best[:0], selection...)
+				bestWaste = waste + value - target
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			if len(selection) == 0 {
+				break
+			}
+
+			// Restore the coins omitted after the last selected
+			// coin, then omit it instead.
+			last := selection[len(selection)-1]
+			for i--; i > last; i-- {
+				available += coins[i].effectiveValue
+			}
+			value -= coins[last].effectiveValue
+			waste -= coins[last].fee - coins[last].longTermFee
+			selection = selection[:len(selection)-1]
+			continue
+		}
+
+		coin := &coins[i]
+		available -= coin.effectiveValue
+
+		// Including a coin equivalent to the previous coin, which was
+		// omitted, would only repeat the sets already searched.
+		if len(selection) != 0 && i-1 != selection[len(selection)-1] &&
+			coin.effectiveValue == coins[i-1].effectiveValue &&
+			coin.fee == coins[i-1].fee {
+
+			continue
+		}
+
+		selection = append(selection, i)
+		value += coin.effectiveValue
+		waste += coin.fee - coin.longTermFee
+	}
+
+	if best == nil {
+		return nil
+	}
+	selected := make([]selectionCoin, len(best))
+	for i, idx := range best {
+		selected[i] = coins[idx]
+	}
+	return selected
+}
+
+// selectKnapsack selects coins with effective values summing up to at least
+// the target, preferring an exact match or a sum leaving at least the change
+// cost for change. Random subsets of the coins smaller than the target plus
+// the change cost are tried, and the smallest single coin larger than that is
+// used instead if it is a better match. Nil is returned if the coins do not
+// reach the target.
+//
+// This is the knapsack solver of Bitcoin Core.
+func selectKnapsack(coins []selectionCoin,
+	target, changeCost btcutil.Amount) []selectionCoin {
+
+	shuffled := make([]selectionCoin, len(coins))
+	copy(shuffled, coins)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	var (
+		applicable   []selectionCoin
+		totalLower   btcutil.Amount
+		lowestLarger *selectionCoin
+	)
+	for i := range shuffled {
+		coin := &shuffled[i]
+		switch {
+		case coin.effectiveValue == target:
+			return []selectionCoin{*coin}
+
+		case coin.effectiveValue < target+changeCost:
+			applicable = append(applicable, *coin)
+			totalLower += coin.effectiveValue
+
+		case lowestLarger == nil ||
+			coin.effectiveValue < lowestLarger.effectiveValue:
+
+			lowestLarger = coin
+		}
+	}
+
+	switch {
+	case totalLower == target:
+		return applicable
+
+	case totalLower < target && lowestLarger == nil:
+		return nil
+
+	case totalLower < target:
+		return []selectionCoin{*lowestLarger}
+	}
+
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return applicable[i].effectiveValue > applicable[j].effectiveValue
+	})
+	best, bestValue := approximateBestSubset(applicable, totalLower, target)
+	if bestValue != target && totalLower >= target+changeCost {
+		best, bestValue = approximateBestSubset(
+			applicable, totalLower, target+changeCost,
+		)
+	}
+
+	// The smallest larger coin is used if the subset neither matches the
+	// target nor leaves enough for change, or if it is not larger than the
+	// subset.
+	if lowestLarger != nil &&
+		((bestValue != target && bestValue < target+changeCost) ||
+			lowestLarger.effectiveValue <= bestValue) {
+
+		return []selectionCoin{*lowestLarger}
+	}
+
+	return best
+}
+
+// approximateBestSubset returns the subset of the coins, the effective values
+// of which sum up to total, with the smallest sum of at least the target found
+// in a number of random tries, along with the sum.
+func approximateBestSubset(coins []selectionCoin,
+	total, target btcutil.Amount) ([]selectionCoin, btcutil.Amount) {
+
+	bestIncluded := make([]bool, len(coins))
+	for i := range bestIncluded {
+		bestIncluded[i] = true
+	}
+	bestValue := total
+
+	included := make([]bool, len(coins))
+	for rep := 0; rep < knapsackIterations && bestValue != target; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+
+		// The first pass includes coins at random, and the second pass
+		// includes the remaining coins, until the target is reached.
+		// The coin reaching the target is excluded again to search for
+		// a smaller sum.
+		var value btcutil.Amount
+		reachedTarget := false
+		for pass := 0; pass < 2 && !reachedTarget; pass++ {
+			for i, coin := range coins {
+				if pass == 0 && rand.Intn(2) == 0 ||
+					pass == 1 && included[i] {
+
+					continue
+				}
+
+				value += coin.effectiveValue
+				included[i] = true
+				if value < target {
+					continue
+				}
+
+				reachedTarget = true
+				if value < bestValue {
+					bestValue = value
+					copy(bestIncluded, included)
+				}
+				value -= coin.effectiveValue
+				included[i] = false
+			}
+		}
+	}
+
+	var best []selectionCoin
+	for i, coin := range coins {
+		if bestIncluded[i] {
+			best = append(best, coin)
+		}
+	}
+	return best, bestValue
+}
+
+// BranchAndBoundCoinSelector is an implementation of the CoinSelectionStrategy
+// that searches for a set of coins funding the transaction without change
+// using branch-and-bound, as no change output then needs to be created and
+// later spent. If there is no such set, a knapsack solver selects coins
+// leaving enough value for change. The selected coins are arranged first,
+// followed by the other coins largest first, which are also the arrangement
+// when the coins are not enough to fund the transaction.
+type BranchAndBoundCoinSelector struct{}
+
+// ArrangeCoins takes a list of coins and arranges them according to the
+// specified coin selection strategy and the transaction being funded.
+func (*BranchAndBoundCoinSelector) ArrangeCoins(eligible []Coin,
+	params CoinSelectionParams) ([]Coin, error) {
+
+	coins := selectionCoins(eligible, params)
+
+	selected := selectBranchAndBound(coins, params.Target, params.ChangeCost)
+	if selected != nil {
+		return arrangeSelected(selected, coins), nil
+	}
+
+	selected = selectKnapsack(coins, params.Target, params.ChangeCost)
+	if selected != nil {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].Value > selected[j].Value
+		})
+		return arrangeSelected(selected, coins), nil
+	}
+
+	return unwrapCoins(coins), nil
+}
+
+// ConsolidatingCoinSelector is an implementation of the CoinSelectionStrategy
+// that selects the smallest coins first while the fee rate is at most the
+// long-term fee rate, consolidating many small coins while it is cheaper than
+// it is expected to be in the future. At higher fee rates, coins are selected
+// like by the BranchAndBoundCoinSelector.
+type ConsolidatingCoinSelector struct{}
+
+// ArrangeCoins takes a list of coins and arranges them according to the
+// specified coin selection strategy and the transaction being funded.
+func (*ConsolidatingCoinSelector) ArrangeCoins(eligible []Coin,
+	params CoinSelectionParams) ([]Coin, error) {
+
+	if params.FeeSatPerKb > params.LongTermFeeSatPerKb {
+		return CoinSelectionBranchAndBound.ArrangeCoins(eligible, params)
+	}
+
+	coins := selectionCoins(eligible, params)
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].Value < coins[j].Value
+	})
+
+	return unwrapCoins(coins), nil
+}
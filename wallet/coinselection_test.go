@@ -0,0 +1,258 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txsizes"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// testSelectionCoins returns selection coins with the effective values
+// provided, each paying the fees provided to be spent.
+func testSelectionCoins(fee, longTermFee btcutil.Amount,
+	values ...btcutil.Amount) []selectionCoin {
+
+	coins := make([]selectionCoin, len(values))
+	for i, value := range values {
+		coins[i] = selectionCoin{
+			Coin: Coin{
+				TxOut:    wire.TxOut{Value: int64(value + fee)},
+				OutPoint: wire.OutPoint{Index: uint32(i)},
+			},
+			effectiveValue: value,
+			fee:            fee,
+			longTermFee:    longTermFee,
+		}
+	}
+	return coins
+}
+
+// selectionValues returns the effective values of the selection coins.
+func selectionValues(coins []selectionCoin) []btcutil.Amount {
+	var values []btcutil.Amount
+	for _, coin := range coins {
+		values = append(values, coin.effectiveValue)
+	}
+	return values
+}
+
+// TestSelectBranchAndBound tests that branch-and-bound finds the changeless
+// set of coins with the least waste.
+func TestSelectBranchAndBound(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		fee         btcutil.Amount
+		longTermFee btcutil.Amount
+		values      []btcutil.Amount
+		target      btcutil.Amount
+		changeCost  btcutil.Amount
+		selected    []btcutil.Amount
+	}{{
+		name:     "exact match",
+		values:   []btcutil.Amount{4000, 3000, 1500, 1000},
+		target:   5000,
+		selected: []btcutil.Amount{4000, 1000},
+	}, {
+		name:       "least excess",
+		values:     []btcutil.Amount{4000, 3000, 2200},
+		target:     5000,
+		changeCost: 500,
+		selected:   []btcutil.Amount{3000, 2200},
+	}, {
+		name:        "fewer inputs at high fee rate",
+		fee:         200,
+		longTermFee: 100,
+		values:      []btcutil.Amount{5000, 3000, 2000},
+		target:      5000,
+		selected:    []btcutil.Amount{5000},
+	}, {
+		name:        "more inputs at low fee rate",
+		fee:         100,
+		longTermFee: 200,
+		values:      []btcutil.Amount{5000, 3000, 2000},
+		target:      5000,
+		selected:    []btcutil.Amount{3000, 2000},
+	}, {
+		name:       "no changeless set",
+		values:     []btcutil.Amount{4000, 4000},
+		target:     5000,
+		changeCost: 1000,
+	}, {
+		name:   "insufficient value",
+		values: []btcutil.Amount{4000, 3000},
+		target: 8000,
+	}}
+
+	for _, test := range tests {
+		coins := testSelectionCoins(
+			test.fee, test.longTermFee, test.values...,
+		)
+		selected := selectBranchAndBound(
+			coins, test.target, test.changeCost,
+		)
+		require.Equal(t, test.selected, selectionValues(selected),
+			test.name)
+	}
+}
+
+// TestSelectKnapsack tests that the knapsack solver selects coins reaching
+// the target, leaving enough for change unless the target is matched.
+func TestSelectKnapsack(t *testing.T) {
+	t.Parallel()
+
+	coins := testSelectionCoins(0, 0, 4000, 4000, 1000, 1000)
+
+	// An exact match is found.
+	selected := selectKnapsack(coins, 6000, 500)
+	require.ElementsMatch(t, []btcutil.Amount{4000, 1000, 1000},
+		selectionValues(selected))
+
+	// Otherwise, enough is left for change.
+	selected = selectKnapsack(coins, 5500, 1000)
+	var total btcutil.Amount
+	for _, value := range selectionValues(selected) {
+		total += value
+	}
+	require.GreaterOrEqual(t, total, btcutil.Amount(6500))
+
+	// The smallest larger coin is preferred to a subset not leaving
+	// enough for change.
+	coins = testSelectionCoins(0, 0, 20000, 8000, 3000, 3000)
+	selected = selectKnapsack(coins, 4000, 3000)
+	require.Equal(t, []btcutil.Amount{8000}, selectionValues(selected))
+
+	require.Nil(t, selectKnapsack(coins, 40000, 0))
+}
+
+// TestConsolidatingCoinSelector tests that the smallest coins are arranged
+// first at low fee rates.
+func TestConsolidatingCoinSelector(t *testing.T) {
+	t.Parallel()
+
+	coins := unwrapCoins(testSelectionCoins(0, 0, 3000, 1000, 50000, 2000))
+	params := CoinSelectionParams{
+		FeeSatPerKb:         1000,
+		LongTermFeeSatPerKb: DefaultLongTermFeeSatPerKb,
+		Target:              50000,
+	}
+	arranged, err := CoinSelectionConsolidate.ArrangeCoins(coins, params)
+	require.NoError(t, err)
+	var values []int64
+	for _, coin := range arranged {
+		values = append(values, coin.Value)
+	}
+	require.Equal(t, []int64{1000, 2000, 3000, 50000}, values)
+
+	// At high fee rates, the coin matching the target is selected.
+	params.FeeSatPerKb = 2 * DefaultLongTermFeeSatPerKb
+	params.Target = 50000 - feeForSize(params.FeeSatPerKb,
+		txsizes.GetMinInputVirtualSize(nil))
+	arranged, err = CoinSelectionConsolidate.ArrangeCoins(coins, params)
+	require.NoError(t, err)
+	require.Equal(t, int64(50000), arranged[0].Value)
+}
+
+// TestBranchAndBoundArrangesUnselected tests that the coins that are not
+// selected are arranged largest first after the selected coins.
+func TestBranchAndBoundArrangesUnselected(t *testing.T) {
+	t.Parallel()
+
+	coins := unwrapCoins(testSelectionCoins(
+		0, 0, 3000, 1500, 50000, 2000, 4000,
+	))
+
+	// Spending the coins at the long-term fee rate wastes nothing, so the
+	// only changeless set of 4000 and 1500 is selected.
+	params := CoinSelectionParams{
+		FeeSatPerKb:         DefaultLongTermFeeSatPerKb,
+		LongTermFeeSatPerKb: DefaultLongTermFeeSatPerKb,
+		ChangeCost:          100,
+	}
+	inputFee := feeForSize(params.FeeSatPerKb,
+		txsizes.GetMinInputVirtualSize(nil))
+	tests := []struct {
+		name   string
+		target btcutil.Amount
+		values []int64
+	}{{
+		name:   "branch and bound",
+		target: 5500 - 2*inputFee,
+		values: []int64{4000, 1500, 50000, 3000, 2000},
+	}, {
+		name:   "knapsack",
+		target: 40000,
+		values: []int64{50000, 4000, 3000, 2000, 1500},
+	}, {
+		name:   "insufficient",
+		target: 70000,
+		values: []int64{50000, 4000, 3000, 2000, 1500},
+	}}
+	for _, test := range tests {
+		params.Target = test.target
+		arranged, err := CoinSelectionBranchAndBound.ArrangeCoins(
+			coins, params,
+		)
+		require.NoError(t, err, test.name)
+		var values []int64
+		for _, coin := range arranged {
+			values = append(values, coin.Value)
+		}
+		require.Equal(t, test.values, values, test.name)
+	}
+}
+
+// TestBranchAndBoundTx tests that a transaction funded with branch-and-bound
+// coin selection has no change output when there is a changeless set of
+// coins.
+func TestBranchAndBoundTx(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	addr, err := w.CurrentAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	addUtxo(t, w, &wire.MsgTx{
+		TxIn: []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{
+			wire.NewTxOut(70000, pkScript),
+			wire.NewTxOut(50000, pkScript),
+			wire.NewTxOut(30000, pkScript),
+		},
+	})
+
+	// Pay slightly less than the effective value of the two smaller
+	// outputs.
+	const feeRate = 5000
+	inputFee := feeForSize(feeRate, txsizes.GetMinInputVirtualSize(pkScript))
+	baseFee := newCoinSelectionParams(
+		[]*wire.TxOut{wire.NewTxOut(0, pkScript)}, feeRate,
+		txsizes.P2WPKHPkScriptSize,
+	).Target
+	payment := 80000 - 2*inputFee - baseFee - 100
+	outputs := []*wire.TxOut{wire.NewTxOut(int64(payment), pkScript)}
+
+	tx, err := w.CreateSimpleTx(
+		nil, 0, outputs, 1, feeRate, CoinSelectionBranchAndBound, true,
+	)
+	require.NoError(t, err)
+	require.Equal(t, -1, tx.ChangeIndex)
+	require.Len(t, tx.Tx.TxOut, 1)
+	require.ElementsMatch(t, []btcutil.Amount{50000, 30000},
+		tx.PrevInputValues)
+
+	// Largest first selection creates change instead.
+	tx, err = w.CreateSimpleTx(
+		nil, 0, outputs, 1, feeRate, CoinSelectionLargest, true,
+	)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, tx.ChangeIndex, 0)
+}
@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/bisoncraft/utxowallet/wallet/txsizes"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrCPFPInsufficientValue is returned by CPFP when the output does not have
+// enough value to pay the fee required to reach the target fee rate.
+var ErrCPFPInsufficientValue = errors.New("output value too low to pay " +
+	"the child fee")
+
+// CPFP accelerates the confirmation of an unmined transaction paying to the
+// wallet by spending the output provided in a child transaction, as described
+// by the child-pays-for-parent technique.  The child pays enough fee for the
+// package made up of the child and its unmined ancestors to pay the target fee
+// rate.  The whole output value, less the fee, is paid to a new change address
+// of the account the output belongs to.
+//
+// The fees paid by ancestors are only known if all of their inputs spend
+// wallet outputs.  Other ancestors, such as third party payments, are assumed
+// to pay no fee, so the package pays at least the target fee rate.  Unmined
+// ancestors unknown to the wallet are not accounted for.
+func (w *Wallet) CPFP(op wire.OutPoint,
+	targetPackageFeeRate btcutil.Amount) (*wire.MsgTx, error) {
+
+	if w.LockedOutpoint(op) {
+		return nil, fmt.Errorf("output %v is locked", op)
+	}
+
+	chainClient, err := w.requireChainClient()
+	if err != nil {
+		return nil, err
+	}
+	bs, err := chainClient.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *txauthor.AuthoredTx
+	err = w.sweep(func(dbtx walletdb.ReadWriteTx) error {
+		var err error
+		tx, err = w.childTx(dbtx, op, targetPackageFeeRate, bs.Height)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.reliablyPublishTransaction(tx.Tx, ""); err != nil {
+		return nil, err
+	}
+
+	return tx.Tx, nil
+}
+
+// childTx creates and signs the child transaction for CPFP.  Like any other
+// transaction created by the wallet, the child is locked to the height
+// provided to discourage fee sniping.
+func (w *Wallet) childTx(dbtx walletdb.ReadWriteTx, op wire.OutPoint,
+	feeSatPerKb btcutil.Amount, height int32) (*txauthor.AuthoredTx,
+	error) {
+
+	addrmgrNs := dbtx.ReadWriteBucket(waddrmgrNamespaceKey)
+	txmgrNs := dbtx.ReadWriteBucket(wtxmgrNamespaceKey)
+
+	// The output must be an unmined and unspent credit.
+	unspent, err := w.TxStore.UnspentOutputs(txmgrNs)
+	if err != nil {
+		return nil, err
+	}
+	var credit *wtxmgr.Credit
+	for i := range unspent {
+		if unspent[i].OutPoint == op {
+			credit = &unspent[i]
+			break
+		}
+	}
+	switch {
+	case credit == nil:
+		return nil, fmt.Errorf("output %v is not an unspent wallet "+
+			"output", op)
+	case credit.Height != -1:
+		return nil, fmt.Errorf("output %v is already mined", op)
+	}
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		credit.PkScript, w.chainParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) != 1 {
+		return nil, fmt.Errorf("output %v does not pay to a single "+
+			"address", op)
+	}
+	scopedMgr, account, err := w.Manager.AddrAccount(addrmgrNs, addrs[0])
+	if err != nil {
+		return nil, err
+	}
+	scope := scopedMgr.Scope()
+	watchOnly, err := w.Manager.IsWatchOnlyAccount(
+		addrmgrNs, scope, account,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if watchOnly {
+		return nil, errors.New("cannot spend an output of a " +
+			"watch-only account")
+	}
+
+	// Sum up the size and known fees of the unmined ancestor package.
+	var (
+		packageSize int64
+		packageFees btcutil.Amount
+	)
+	ancestors, err := w.unminedAncestors(txmgrNs, &op.Hash)
+	if err != nil {
+		return nil, err
+	}
+	for _, ancestor := range ancestors {
+		packageSize += mempool.GetTxVirtualSize(
+			btcutil.NewTx(&ancestor.MsgTx),
+		)
+		if len(ancestor.Debits) == len(ancestor.MsgTx.TxIn) {
+			packageFees += txFee(ancestor)
+		}
+	}
+
+	// The child pays the fee of the whole package at the target fee rate,
+	// less the fees already paid by its ancestors, and at least enough to
+	// be relayed itself.
+	var nested, p2wpkh, p2tr, p2pkh int
+	switch {
+	case txscript.IsPayToScriptHash(credit.PkScript):
+		nested++
+	case txscript.IsPayToWitnessPubKeyHash(credit.PkScript):
+		p2wpkh++
+	case txscript.IsPayToTaproot(credit.PkScript):
+		p2tr++
+	default:
+		p2pkh++
+	}
+	childFee := func(tx *wire.MsgTx) btcutil.Amount {
+		childSize := txsizes.EstimateVirtualSize(
+			p2pkh, p2tr, p2wpkh, nested, nil,
+			len(tx.TxOut[0].PkScript),
+		)
+		fee := feeForSize(feeSatPerKb, int(packageSize)+childSize) -
+			packageFees
+		minFee := txrules.FeeForSerializeSize(
+			txrules.DefaultRelayFeePerKb, childSize, w.netParams,
+		)
+		if fee < minFee {
+			fee = minFee
+		}
+		return fee
+	}
+
+	msgTx, err := w.sweepTx(dbtx, &outputSweep{
+		op:                   op,
+		value:                credit.Amount,
+		scope:                scope,
+		account:              account,
+		fee:                  childFee,
+		errInsufficientValue: ErrCPFPInsufficientValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tx := &txauthor.AuthoredTx{
+		Tx:              msgTx,
+		PrevScripts:     [][]byte{credit.PkScript},
+		PrevInputValues: []btcutil.Amount{credit.Amount},
+		TotalInput:      credit.Amount,
+		ChangeIndex:     0,
+	}
+	if err := new(txAuthoringOptions).apply(tx.Tx, height); err != nil {
+		return nil, err
+	}
+
+	err = tx.AddAllInputScripts(secretSource{w.Manager, addrmgrNs})
+	if err != nil {
+		return nil, err
+	}
+	err = validateMsgTx(tx.Tx, tx.PrevScripts, tx.PrevInputValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// unminedAncestors returns the unmined wallet transaction with the hash
+// provided, along with all of its unmined ancestors known to the wallet.
+func (w *Wallet) unminedAncestors(txmgrNs walletdb.ReadBucket,
+	txHash *chainhash.Hash) ([]*wtxmgr.TxDetails, error) {
+
+	var ancestors []*wtxmgr.TxDetails
+	seen := map[chainhash.Hash]struct{}{*txHash: {}}
+	queue := []chainhash.Hash{*txHash}
+	for len(queue) != 0 {
+		hash := queue[0]
+		queue = queue[1:]
+
+		details, err := w.TxStore.TxDetails(txmgrNs, &hash)
+		if err != nil {
+			return nil, err
+		}
+		if details == nil || details.Block.Height != -1 {
+			continue
+		}
+		ancestors = append(ancestors, details)
+
+		for _, txIn := range details.MsgTx.TxIn {
+			prevHash := txIn.PreviousOutPoint.Hash
+			if _, ok := seen[prevHash]; ok {
+				continue
+			}
+			seen[prevHash] = struct{}{}
+			queue = append(queue, prevHash)
+		}
+	}
+	return ancestors, nil
+}
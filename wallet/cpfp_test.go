@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCPFP tests that an unmined payment to the wallet is accelerated by a
+// child paying for the fee of the package.
+func TestCPFP(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	addr, err := w.CurrentAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+
+	// Receive an unmined third party payment, the fee of which is unknown
+	// to the wallet.
+	parent := &wire.MsgTx{
+		Version: wire.TxVersion,
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{1}},
+			Witness:          wire.TxWitness{make([]byte, 72)},
+		}},
+		TxOut: []*wire.TxOut{wire.NewTxOut(100000, pkScript)},
+	}
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(parent, time.Now())
+	require.NoError(t, err)
+	err = walletdb.Update(w.db, func(dbtx walletdb.ReadWriteTx) error {
+		return w.addRelevantTx(dbtx, rec, nil)
+	})
+	require.NoError(t, err)
+
+	// Outputs that are not ours can not be spent.
+	_, err = w.CPFP(wire.OutPoint{Hash: chainhash.Hash{2}}, 20000)
+	require.Error(t, err)
+
+	// Paying more than the output value is not possible.
+	op := wire.OutPoint{Hash: rec.Hash, Index: 0}
+	_, err = w.CPFP(op, 1e6)
+	require.ErrorIs(t, err, ErrCPFPInsufficientValue)
+
+	const feeRate = 20000
+	child, err := w.CPFP(op, feeRate)
+	require.NoError(t, err)
+	require.Len(t, child.TxIn, 1)
+	require.Equal(t, op, child.TxIn[0].PreviousOutPoint)
+	require.Equal(t, txauthor.ReplaceableSequence, child.TxIn[0].Sequence)
+	require.Len(t, child.TxOut, 1)
+	require.Equal(t, uint32(500000), child.LockTime)
+
+	// The child pays for the whole package, as the parent's fee is
+	// unknown.
+	packageSize := mempool.GetTxVirtualSize(btcutil.NewTx(parent)) +
+		mempool.GetTxVirtualSize(btcutil.NewTx(child))
+	fee := btcutil.Amount(100000 - child.TxOut[0].Value)
+	require.GreaterOrEqual(t, fee, btcutil.Amount(feeRate*packageSize/1000))
+
+	// The output is now spent by the child.
+	_, err = w.CPFP(op, feeRate)
+	require.Error(t, err)
+}
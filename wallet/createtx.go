@@ -83,8 +83,52 @@ func constantInputSource(eligible []wtxmgr.Credit) txauthor.InputSource {
 	}
 }
 
+// txAuthoringOptions are the lock time, version and input sequence numbers of
+// a created transaction, and whether the external signer of the wallet is to
+// be skipped when spending from a watch-only account.
+type txAuthoringOptions struct {
+	lockTime           *uint32
+	version            int32
+	sequences          map[wire.OutPoint]uint32
+	skipExternalSigner bool
+}
+
+// apply sets the lock time, version and input sequence numbers of the
+// transaction. Unless another lock time is set, the lock time is set to the
+// height provided, so the transaction can not be mined in a block replacing the
+// current tip, discouraging miners from sniping the fees of recent blocks.
+func (o *txAuthoringOptions) apply(tx *wire.MsgTx, height int32) error {
+	tx.LockTime = uint32(height)
+	if o == nil {
+		return nil
+	}
+
+	if o.lockTime != nil {
+		tx.LockTime = *o.lockTime
+	}
+	if o.version != 0 {
+		tx.Version = o.version
+	}
+	for outpoint, sequence := range o.sequences {
+		spent := false
+		for _, txIn := range tx.TxIn {
+			if txIn.PreviousOutPoint == outpoint {
+				txIn.Sequence = sequence
+				spent = true
+			}
+		}
+		if !spent {
+			return fmt.Errorf("sequence number set for outpoint "+
+				"%v not spent by the transaction", outpoint)
+		}
+	}
+
+	return nil
+}
+
 // secretSource is an implementation of txauthor.SecretSource for the wallet's
-// address manager.
+// address manager.  It is also a txauthor.TapscriptSecretsSource, spending the
+// outputs of imported tapscripts through their script paths.
 type secretSource struct {
 	*waddrmgr.Manager
 	addrmgrNs walletdb.ReadBucket
@@ -124,6 +168,20 @@ func (s secretSource) GetScript(addr btcutil.Address) ([]byte, error) {
 	return msa.Script()
 }
 
+func (s secretSource) GetTapscriptSpend(
+	addr btcutil.Address) (*txauthor.TapscriptSpend, error) {
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	tapscript, err := outputTapscript(s.Manager, s.addrmgrNs, pkScript)
+	if err != nil || tapscript == nil {
+		return nil, err
+	}
+	return tapscriptSpend(s.Manager, s.addrmgrNs, tapscript)
+}
+
 // txToOutputs creates a signed transaction which includes each output from
 // outputs. Previous outputs to redeem are chosen from the passed account's
 // UTXO set and minconf policy. An additional output may be added to return
@@ -141,8 +199,8 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 	account uint32, minconf int32, feeSatPerKb btcutil.Amount,
 	strategy CoinSelectionStrategy, dryRun bool,
 	selectedUtxos []wire.OutPoint,
-	allowUtxo func(utxo wtxmgr.Credit) bool) (
-	*txauthor.AuthoredTx, error) {
+	allowUtxo func(utxo wtxmgr.Credit) bool,
+	authoring *txAuthoringOptions) (*txauthor.AuthoredTx, error) {
 
 	chainClient, err := w.requireChainClient()
 	if err != nil {
@@ -170,9 +228,11 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 	// this issue, we surround the whole address creation process
 	// with a lock.
 	w.newAddrMtx.Lock()
-	defer w.newAddrMtx.Unlock()
 
-	var tx *txauthor.AuthoredTx
+	var (
+		tx        *txauthor.AuthoredTx
+		watchOnly bool
+	)
 	err = walletdb.Update(w.db, func(dbtx walletdb.ReadWriteTx) error {
 		addrmgrNs, changeSource, err := w.addrMgrWithChangeSource(
 			dbtx, changeKeyScope, account,
@@ -232,8 +292,11 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 				}
 			}
 
+			params := newCoinSelectionParams(
+				outputs, feeSatPerKb, changeSource.ScriptSize,
+			)
 			arrangedCoins, err := strategy.ArrangeCoins(
-				wrappedEligible, feeSatPerKb,
+				wrappedEligible, params,
 			)
 			if err != nil {
 				return err
@@ -241,12 +304,16 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 			inputSource = makeInputSource(arrangedCoins)
 		}
 
-		tx, err = txauthor.NewUnsignedTransaction(
+		tx, err = txauthor.NewUnsignedTransactionWithInputSizes(
 			outputs, feeSatPerKb, inputSource, changeSource,
+			w.netParams, w.inputSizeEstimator(addrmgrNs),
 		)
 		if err != nil {
 			return err
 		}
+		if err := authoring.apply(tx.Tx, bs.Height); err != nil {
+			return err
+		}
 
 		// Randomize change position, if change exists, before signing.
 		// This doesn't affect the serialize size, so the change amount
@@ -268,7 +335,6 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 		// Before committing the transaction, we'll sign our inputs. If
 		// the inputs are part of a watch-only account, there's no
 		// private key information stored, so we'll skip signing such.
-		var watchOnly bool
 		if coinSelectKeyScope == nil {
 			// If a key scope wasn't specified, then coin selection
 			// was performed from the default wallet accounts
@@ -305,7 +371,7 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 			changeAmount := btcutil.Amount(
 				tx.Tx.TxOut[tx.ChangeIndex].Value,
 			)
-			log.Warnf("Spend from imported account produced "+
+			w.log.Warnf("Spend from imported account produced "+
 				"change: moving %v from imported account into "+
 				"default account.", changeAmount)
 		}
@@ -328,10 +394,26 @@ func (w *Wallet) txToOutputs(outputs []*wire.TxOut,
 
 		return nil
 	})
+	w.newAddrMtx.Unlock()
 	if err != nil && !errors.Is(err, walletdb.ErrDryRunRollBack) {
 		return nil, err
 	}
 
+	// The inputs of watch-only accounts are signed by the external signer
+	// of the wallet, if it has one, once the change address is committed.
+	// The address lock is released first, as the signer may take a while,
+	// e.g. waiting on the user of a hardware wallet.  Dry runs are never
+	// found to be watch-only, as they return before.
+	signer := w.currentExternalSigner()
+	if authoring != nil && authoring.skipExternalSigner {
+		signer = nil
+	}
+	if watchOnly && signer != nil {
+		if err := w.signTxExternally(signer, tx); err != nil {
+			return nil, err
+		}
+	}
+
 	return tx, nil
 }
 
@@ -402,6 +484,15 @@ func (w *Wallet) findEligibleOutputs(dbtx walletdb.ReadTx,
 		if addrAcct != account {
 			continue
 		}
+
+		// Without a key scope, the outputs of multisig accounts are
+		// not mixed with those of the single key accounts sharing
+		// their account number.
+		if keyScope == nil &&
+			w.multisigOutputAddr(addrmgrNs, output.PkScript) != nil {
+
+			continue
+		}
 		eligible = append(eligible, *output)
 	}
 	return eligible, nil
@@ -421,6 +512,40 @@ func inputYieldsPositively(credit *wire.TxOut,
 	return inputFee < btcutil.Amount(credit.Value)
 }
 
+// inputSizeEstimator returns the estimator of the size of inputs spending the
+// outputs of multisig accounts and imported tapscripts, which are not counted
+// by the default estimate of the transaction size.
+func (w *Wallet) inputSizeEstimator(
+	ns walletdb.ReadBucket) txauthor.InputSizeEstimator {
+
+	multisigInputSize := w.multisigInputSize(ns)
+	return func(pkScript []byte) (txsizes.InputSize, bool) {
+		if size, ok := multisigInputSize(pkScript); ok {
+			return size, true
+		}
+
+		tapscript, err := outputTapscript(w.Manager, ns, pkScript)
+		if err != nil || tapscript == nil {
+			return txsizes.InputSize{}, false
+		}
+		spend, err := tapscriptSpend(w.Manager, ns, tapscript)
+		if err != nil {
+			return txsizes.InputSize{}, false
+		}
+		numSigs := 0
+		for _, key := range spend.Keys {
+			if key != nil {
+				numSigs++
+			}
+		}
+		return txsizes.TapscriptInputSize(
+			len(spend.Keys), numSigs, len(spend.Leaf.Script),
+			txscript.ControlBlockBaseSize+
+				len(spend.ControlBlock.InclusionProof),
+		), true
+	}
+}
+
 // addrMgrWithChangeSource returns the address manager bucket and a change
 // source that returns change addresses from said address manager. The change
 // addresses will come from the specified key scope and account, unless a key
@@ -451,6 +576,12 @@ func (w *Wallet) addrMgrWithChangeSource(dbtx walletdb.ReadWriteTx,
 	if accountInfo.AddrSchema != nil {
 		addrType = accountInfo.AddrSchema.InternalAddrType
 	}
+	if ms := accountInfo.Multisig; ms != nil {
+		addrType = waddrmgr.WitnessScript
+		if ms.ScriptType == waddrmgr.MultisigNestedWitnessScript {
+			addrType = waddrmgr.NestedWitnessScript
+		}
+	}
 
 	// Compute the expected size of the script for the change address type.
 	var scriptSize int
@@ -463,6 +594,12 @@ func (w *Wallet) addrMgrWithChangeSource(dbtx walletdb.ReadWriteTx,
 		scriptSize = txsizes.P2WPKHPkScriptSize
 	case waddrmgr.TaprootPubKey:
 		scriptSize = txsizes.P2TRPkScriptSize
+	case waddrmgr.WitnessScript:
+		scriptSize = txsizes.P2WSHPkScriptSize
+	case waddrmgr.NestedWitnessScript:
+		// Like any P2SH output script, it has the size of a nested
+		// P2WPKH script.
+		scriptSize = txsizes.NestedP2WPKHPkScriptSize
 	default:
 		return nil, nil, fmt.Errorf("unsupported address type: %v",
 			addrType)
@@ -541,9 +678,9 @@ func (s sortByAmount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 type LargestFirstCoinSelector struct{}
 
 // ArrangeCoins takes a list of coins and arranges them according to the
-// specified coin selection strategy and fee rate.
+// specified coin selection strategy and the transaction being funded.
 func (*LargestFirstCoinSelector) ArrangeCoins(eligible []Coin,
-	_ btcutil.Amount) ([]Coin, error) {
+	_ CoinSelectionParams) ([]Coin, error) {
 
 	sort.Sort(sort.Reverse(sortByAmount(eligible)))
 
@@ -556,9 +693,9 @@ func (*LargestFirstCoinSelector) ArrangeCoins(eligible []Coin,
 type RandomCoinSelector struct{}
 
 // ArrangeCoins takes a list of coins and arranges them according to the
-// specified coin selection strategy and fee rate.
+// specified coin selection strategy and the transaction being funded.
 func (*RandomCoinSelector) ArrangeCoins(eligible []Coin,
-	feeSatPerKb btcutil.Amount) ([]Coin, error) {
+	params CoinSelectionParams) ([]Coin, error) {
 
 	// Skip inputs that do not raise the total transaction output
 	// value at the requested fee rate.
@@ -566,7 +703,7 @@ func (*RandomCoinSelector) ArrangeCoins(eligible []Coin,
 	for _, output := range eligible {
 		output := output
 
-		if !inputYieldsPositively(&output.TxOut, feeSatPerKb) {
+		if !inputYieldsPositively(&output.TxOut, params.FeeSatPerKb) {
 			continue
 		}
 
@@ -81,7 +81,7 @@ func TestTxToOutputsDryRun(t *testing.T) {
 	// database us not inflated.
 	dryRunTx, err := w.txToOutputs(
 		txOuts, nil, nil, 0, 1, 1000, CoinSelectionLargest, true,
-		nil, alwaysAllowUtxo,
+		nil, alwaysAllowUtxo, nil,
 	)
 	if err != nil {
 		t.Fatalf("unable to author tx: %v", err)
@@ -99,7 +99,7 @@ func TestTxToOutputsDryRun(t *testing.T) {
 
 	dryRunTx2, err := w.txToOutputs(
 		txOuts, nil, nil, 0, 1, 1000, CoinSelectionLargest, true,
-		nil, alwaysAllowUtxo,
+		nil, alwaysAllowUtxo, nil,
 	)
 	if err != nil {
 		t.Fatalf("unable to author tx: %v", err)
@@ -135,7 +135,7 @@ func TestTxToOutputsDryRun(t *testing.T) {
 	// to the database.
 	tx, err := w.txToOutputs(
 		txOuts, nil, nil, 0, 1, 1000, CoinSelectionLargest, false,
-		nil, alwaysAllowUtxo,
+		nil, alwaysAllowUtxo, nil,
 	)
 	if err != nil {
 		t.Fatalf("unable to author tx: %v", err)
@@ -286,6 +286,7 @@ func TestTxToOutputsRandom(t *testing.T) {
 		tx, err := w.txToOutputs(
 			txOuts, nil, nil, 0, 1, feeSatPerKb,
 			CoinSelectionRandom, true, nil, alwaysAllowUtxo,
+			nil,
 		)
 		require.NoError(t, err)
 		return tx
@@ -357,7 +358,7 @@ func TestCreateSimpleCustomChange(t *testing.T) {
 	}
 	tx1, err := w.txToOutputs(
 		[]*wire.TxOut{targetTxOut}, nil, nil, 0, 1, 1000,
-		CoinSelectionLargest, true, nil, alwaysAllowUtxo,
+		CoinSelectionLargest, true, nil, alwaysAllowUtxo, nil,
 	)
 	require.NoError(t, err)
 
@@ -383,7 +384,7 @@ func TestCreateSimpleCustomChange(t *testing.T) {
 	tx2, err := w.txToOutputs(
 		[]*wire.TxOut{targetTxOut}, &waddrmgr.KeyScopeBIP0086,
 		&waddrmgr.KeyScopeBIP0084, 0, 1, 1000, CoinSelectionLargest,
-		true, nil, alwaysAllowUtxo,
+		true, nil, alwaysAllowUtxo, nil,
 	)
 	require.NoError(t, err)
 
@@ -468,6 +469,7 @@ func TestSelectUtxosTxoToOutpoint(t *testing.T) {
 	tx1, err := w.txToOutputs(
 		[]*wire.TxOut{targetTxOut}, nil, nil, 0, 1, 1000,
 		CoinSelectionLargest, true, selectUtxos, alwaysAllowUtxo,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -488,3 +490,72 @@ func TestSelectUtxosTxoToOutpoint(t *testing.T) {
 	// Expect two outputs, change and the actual payment to the address.
 	require.Len(t, tx1.Tx.TxOut, 2)
 }
+
+// TestTxAuthoringOptions tests that the lock time, version and input sequence
+// numbers of created transactions can be set, and that the lock time defaults
+// to the current height.
+func TestTxAuthoringOptions(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	addr, err := w.CurrentAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	incomingTx := &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{wire.NewTxOut(100000, pkScript)},
+	}
+	addUtxo(t, w, incomingTx)
+	outpoint := wire.OutPoint{Hash: incomingTx.TxHash()}
+	outputs := []*wire.TxOut{wire.NewTxOut(50000, pkScript)}
+
+	tx, err := w.CreateSimpleTx(
+		nil, 0, outputs, 1, 1000, CoinSelectionLargest, true,
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint32(500000), tx.Tx.LockTime)
+	require.Equal(t, int32(wire.TxVersion), tx.Tx.Version)
+	require.Equal(t, txauthor.ReplaceableSequence, tx.Tx.TxIn[0].Sequence)
+
+	tx, err = w.CreateSimpleTx(
+		nil, 0, outputs, 1, 1000, CoinSelectionLargest, false,
+		WithLockTime(400000), WithRelativeLockTime(outpoint, false, 144),
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint32(400000), tx.Tx.LockTime)
+	require.Equal(t, int32(2), tx.Tx.Version)
+	require.Equal(t, uint32(144), tx.Tx.TxIn[0].Sequence)
+
+	// Relative lock times in seconds are rounded up, so the output is not
+	// spendable earlier than requested.
+	tx, err = w.CreateSimpleTx(
+		nil, 0, outputs, 1, 1000, CoinSelectionLargest, true,
+		WithRelativeLockTime(outpoint, true, 1000),
+	)
+	require.NoError(t, err)
+	wantSequence := uint32(wire.SequenceLockTimeIsSeconds | 2)
+	require.Equal(t, wantSequence, tx.Tx.TxIn[0].Sequence)
+
+	// Relative lock times which can't be encoded are rejected rather than
+	// truncated.
+	_, err = w.CreateSimpleTx(
+		nil, 0, outputs, 1, 1000, CoinSelectionLargest, true,
+		WithRelativeLockTime(outpoint, false, 0x10000),
+	)
+	require.ErrorContains(t, err, "exceeds the maximum")
+	_, err = w.CreateSimpleTx(
+		nil, 0, outputs, 1, 1000, CoinSelectionLargest, true,
+		WithRelativeLockTime(outpoint, true, 0xffff*512+1),
+	)
+	require.ErrorContains(t, err, "exceeds the maximum")
+
+	// Sequence numbers can only be set for spent outpoints.
+	_, err = w.CreateSimpleTx(
+		nil, 0, outputs, 1, 1000, CoinSelectionLargest, true,
+		WithInputSequence(wire.OutPoint{Index: 1}, 0),
+	)
+	require.ErrorContains(t, err, "not spent by the transaction")
+}
@@ -0,0 +1,728 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/descriptor"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// MaxDescriptorRangeEnd is the highest end of the range of a descriptor which
+// is imported, as all the addresses of the range are imported in a single
+// database transaction.
+const MaxDescriptorRangeEnd = 10000
+
+// ErrUnsupportedDescriptor is returned when importing a descriptor for outputs
+// the wallet is not able to watch.
+var ErrUnsupportedDescriptor = errors.New("unsupported descriptor")
+
+// AccountDescriptors are the descriptors of the external and internal
+// branches of an account.
+type AccountDescriptors struct {
+	// KeyScope is the key scope of the account.
+	KeyScope waddrmgr.KeyScope
+
+	// AccountNumber is the number of the account within its key scope.
+	AccountNumber uint32
+
+	// AccountName is the name of the account.
+	AccountName string
+
+	// External is the ranged descriptor of the external branch.
+	External *descriptor.Descriptor
+
+	// Internal is the ranged descriptor of the internal branch.
+	Internal *descriptor.Descriptor
+}
+
+// ExportDescriptors returns the descriptors of every account of the wallet,
+// other than the accounts of imported addresses.  The descriptors use the
+// extended public keys of the accounts, along with their key origin when the
+// fingerprint of the master key is known.
+func (w *Wallet) ExportDescriptors() ([]*AccountDescriptors, error) {
+	var descs []*AccountDescriptors
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		addrmgrNs := tx.ReadBucket(waddrmgrNamespaceKey)
+		for _, scopedMgr := range w.Manager.ActiveScopedKeyManagers() {
+			err := scopedMgr.ForEachAccount(
+				addrmgrNs, func(account uint32) error {
+					if account == waddrmgr.ImportedAddrAccount {
+						return nil
+					}
+					d, err := w.accountDescriptors(
+						addrmgrNs, scopedMgr, account,
+					)
+					if err != nil {
+						return err
+					}
+					descs = append(descs, d)
+					return nil
+				},
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return descs, err
+}
+
+// AccountDescriptors returns the descriptors of the account of the key scope
+// provided.
+func (w *Wallet) AccountDescriptors(scope waddrmgr.KeyScope,
+	account uint32) (*AccountDescriptors, error) {
+
+	scopedMgr, err := w.Manager.FetchScopedKeyManager(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var descs *AccountDescriptors
+	err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		addrmgrNs := tx.ReadBucket(waddrmgrNamespaceKey)
+		var err error
+		descs, err = w.accountDescriptors(addrmgrNs, scopedMgr, account)
+		return err
+	})
+	return descs, err
+}
+
+// accountDescriptors returns the descriptors of an account.
+func (w *Wallet) accountDescriptors(ns walletdb.ReadBucket,
+	scopedMgr *waddrmgr.ScopedKeyManager,
+	account uint32) (*AccountDescriptors, error) {
+
+	props, err := scopedMgr.AccountProperties(ns, account)
+	if err != nil {
+		return nil, err
+	}
+	if props.Multisig != nil {
+		return w.multisigAccountDescriptors(props)
+	}
+	if props.AccountPubKey == nil {
+		return nil, fmt.Errorf("account %d has no extended public key",
+			account)
+	}
+
+	// Descriptors only use the standard extended key versions of the
+	// network.
+	acctKey, err := props.AccountPubKey.CloneWithVersion(
+		w.chainParams.HDPublicKeyID[:],
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The master key of accounts derived by the wallet is the root key of
+	// the wallet, while imported accounts carry the fingerprint provided
+	// on import, if any.
+	fingerprint := props.MasterKeyFingerprint
+	if !props.IsWatchOnly {
+		fingerprint, err = w.Manager.MasterKeyFingerprint(ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var origin *descriptor.KeyOrigin
+	if fingerprint != 0 && acctKey.Depth() == accountPubKeyDepth {
+		origin = &descriptor.KeyOrigin{
+			Fingerprint: fingerprint,
+			Path: []uint32{
+				props.KeyScope.Purpose + hdkeychain.HardenedKeyStart,
+				props.KeyScope.Coin + hdkeychain.HardenedKeyStart,
+				acctKey.ChildIndex(),
+			},
+		}
+	}
+
+	schema := scopedMgr.AddrSchema()
+	if props.AddrSchema != nil {
+		schema = *props.AddrSchema
+	}
+	branchDescriptor := func(branch uint32,
+		addrType waddrmgr.AddressType) (*descriptor.Descriptor, error) {
+
+		key := &descriptor.Key{
+			Origin:   origin,
+			ExtKey:   acctKey,
+			Path:     []uint32{branch},
+			Wildcard: descriptor.WildcardUnhardened,
+		}
+		return addrTypeDescriptor(addrType, key)
+	}
+	external, err := branchDescriptor(
+		waddrmgr.ExternalBranch, schema.ExternalAddrType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := branchDescriptor(
+		waddrmgr.InternalBranch, schema.InternalAddrType,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountDescriptors{
+		KeyScope:      props.KeyScope,
+		AccountNumber: props.AccountNumber,
+		AccountName:   props.AccountName,
+		External:      external,
+		Internal:      internal,
+	}, nil
+}
+
+// multisigAccountDescriptors returns the sorted multisig descriptors of a
+// multisig account, with the key origins of every cosigner.
+func (w *Wallet) multisigAccountDescriptors(
+	props *waddrmgr.AccountProperties) (*AccountDescriptors, error) {
+
+	ms := props.Multisig
+	branchDescriptor := func(branch uint32) (*descriptor.Descriptor,
+		error) {
+
+		keys := make([]*descriptor.Key, 0, len(ms.Cosigners))
+		for _, cosigner := range ms.Cosigners {
+			acctKey, err := cosigner.AccountPubKey.CloneWithVersion(
+				w.chainParams.HDPublicKeyID[:],
+			)
+			if err != nil {
+				return nil, err
+			}
+			var origin *descriptor.KeyOrigin
+			if cosigner.MasterKeyFingerprint != 0 {
+				origin = &descriptor.KeyOrigin{
+					Fingerprint: cosigner.MasterKeyFingerprint,
+					Path:        cosigner.DerivationPath,
+				}
+			}
+			keys = append(keys, &descriptor.Key{
+				Origin:   origin,
+				ExtKey:   acctKey,
+				Path:     []uint32{branch},
+				Wildcard: descriptor.WildcardUnhardened,
+			})
+		}
+
+		desc := &descriptor.Descriptor{
+			Type: descriptor.TypeWsh,
+			Sub: &descriptor.Descriptor{
+				Type:      descriptor.TypeSortedMulti,
+				Keys:      keys,
+				Threshold: ms.Threshold,
+			},
+		}
+		if ms.ScriptType == waddrmgr.MultisigNestedWitnessScript {
+			desc = &descriptor.Descriptor{
+				Type: descriptor.TypeSh, Sub: desc,
+			}
+		}
+		return desc, nil
+	}
+	external, err := branchDescriptor(waddrmgr.ExternalBranch)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := branchDescriptor(waddrmgr.InternalBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountDescriptors{
+		KeyScope:      props.KeyScope,
+		AccountNumber: props.AccountNumber,
+		AccountName:   props.AccountName,
+		External:      external,
+		Internal:      internal,
+	}, nil
+}
+
+// addrTypeDescriptor returns the descriptor of the addresses of the address
+// type for the key.
+func addrTypeDescriptor(addrType waddrmgr.AddressType,
+	key *descriptor.Key) (*descriptor.Descriptor, error) {
+
+	keys := []*descriptor.Key{key}
+	switch addrType {
+	case waddrmgr.PubKeyHash:
+		return &descriptor.Descriptor{
+			Type: descriptor.TypePkh, Keys: keys,
+		}, nil
+
+	case waddrmgr.NestedWitnessPubKey:
+		return &descriptor.Descriptor{
+			Type: descriptor.TypeSh,
+			Sub: &descriptor.Descriptor{
+				Type: descriptor.TypeWpkh, Keys: keys,
+			},
+		}, nil
+
+	case waddrmgr.WitnessPubKey:
+		return &descriptor.Descriptor{
+			Type: descriptor.TypeWpkh, Keys: keys,
+		}, nil
+
+	case waddrmgr.TaprootPubKey:
+		key.XOnly = true
+		return &descriptor.Descriptor{
+			Type: descriptor.TypeTr, Keys: keys,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("address type %v has no descriptor",
+			addrType)
+	}
+}
+
+// addrTypeScopes are the key scopes addresses of single key descriptors are
+// imported into, by the address type of the descriptor.
+var addrTypeScopes = map[waddrmgr.AddressType]waddrmgr.KeyScope{
+	waddrmgr.PubKeyHash:          waddrmgr.KeyScopeBIP0044,
+	waddrmgr.NestedWitnessPubKey: waddrmgr.KeyScopeBIP0049Plus,
+	waddrmgr.WitnessPubKey:       waddrmgr.KeyScopeBIP0084,
+	waddrmgr.TaprootPubKey:       waddrmgr.KeyScopeBIP0086,
+}
+
+// descriptorAddrType returns the address type and the key of single key
+// descriptors.  False is returned for other descriptors.
+func descriptorAddrType(desc *descriptor.Descriptor) (waddrmgr.AddressType,
+	*descriptor.Key, bool) {
+
+	switch {
+	case desc.Type == descriptor.TypePkh:
+		return waddrmgr.PubKeyHash, desc.Keys[0], true
+
+	case desc.Type == descriptor.TypeSh &&
+		desc.Sub.Type == descriptor.TypeWpkh:
+
+		return waddrmgr.NestedWitnessPubKey, desc.Sub.Keys[0], true
+
+	case desc.Type == descriptor.TypeWpkh:
+		return waddrmgr.WitnessPubKey, desc.Keys[0], true
+
+	case desc.Type == descriptor.TypeTr && desc.Tree == nil:
+		return waddrmgr.TaprootPubKey, desc.Keys[0], true
+	}
+	return 0, nil, false
+}
+
+// isAccountKey returns whether the key expression is a branch of an account
+// extended public key.
+func isAccountKey(key *descriptor.Key) bool {
+	return key.ExtKey != nil && !key.ExtKey.IsPrivate() &&
+		key.ExtKey.Depth() == accountPubKeyDepth &&
+		key.ExtKey.ChildIndex() >= hdkeychain.HardenedKeyStart &&
+		len(key.Path) == 1 && key.Path[0] <= waddrmgr.InternalBranch &&
+		key.Wildcard == descriptor.WildcardUnhardened
+}
+
+// ImportAccountDescriptors imports the descriptors of the external and
+// internal branches of an account extended public key, such as the ones
+// returned by ExportDescriptors, as a watch-only account with the name
+// provided.  The account is imported into the default key scope using the
+// address types of the branches if there is one, or else into the key scope
+// of the external address type with a custom address schema.  The existing
+// account is returned if the account key was already imported.
+func (w *Wallet) ImportAccountDescriptors(name string, external,
+	internal *descriptor.Descriptor) (*waddrmgr.AccountProperties, error) {
+
+	externalType, externalKey, ok := descriptorAddrType(external)
+	if !ok || !isAccountKey(externalKey) ||
+		externalKey.Path[0] != waddrmgr.ExternalBranch {
+
+		return nil, fmt.Errorf("%w: %v is not an external account "+
+			"branch", ErrUnsupportedDescriptor, external)
+	}
+	internalType, internalKey, ok := descriptorAddrType(internal)
+	if !ok || !isAccountKey(internalKey) ||
+		internalKey.Path[0] != waddrmgr.InternalBranch {
+
+		return nil, fmt.Errorf("%w: %v is not an internal account "+
+			"branch", ErrUnsupportedDescriptor, internal)
+	}
+	same, err := waddrmgr.SameExtendedKey(
+		externalKey.ExtKey, internalKey.ExtKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		return nil, fmt.Errorf("%w: branches of different accounts",
+			ErrUnsupportedDescriptor)
+	}
+
+	var props *waddrmgr.AccountProperties
+	err = walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		var err error
+		props, err = w.importAccountDescriptor(
+			ns, name, externalKey, waddrmgr.ScopeAddrSchema{
+				ExternalAddrType: externalType,
+				InternalAddrType: internalType,
+			},
+		)
+		return err
+	})
+	return props, err
+}
+
+// ImportDescriptor imports the outputs of a descriptor into the wallet.
+//
+// Single key descriptors of a branch of an account extended public key are
+// imported as a watch-only account with the name provided, using the address
+// type of the descriptor for both branches of the account.  See
+// ImportAccountDescriptors for the import of accounts using different address
+// types for each branch.
+//
+// Any other descriptor is expanded at each index from zero to rangeEnd, or
+// once if it is not ranged, and its addresses are imported into the imported
+// account of the key scope of the outputs.  Single key descriptors including
+// private keys can sign for their outputs, while the outputs of script
+// descriptors are watch-only.  Script hash outputs, nested or not, are
+// imported into the BIP0084 key scope, and taproot script trees are imported
+// into the BIP0086 key scope with all their leaves.  The properties of the
+// imported account are returned.
+//
+// Ranged descriptors of extended private keys are not imported, as the wallet
+// can't derive their addresses past rangeEnd, and neither are taproot script
+// trees with a different shape than txscript.AssembleTaprootScriptTree builds
+// from their leaves, as the wallet can't spend through their leaves.  The end
+// of the range is limited to MaxDescriptorRangeEnd.
+//
+// NOTE: If a block stamp is not provided, then the wallet's birthday will be
+// set to the genesis block of the corresponding chain.
+func (w *Wallet) ImportDescriptor(desc *descriptor.Descriptor, name string,
+	rangeEnd uint32, bs *waddrmgr.BlockStamp) (*waddrmgr.AccountProperties,
+	error) {
+
+	addrType, key, singleKey := descriptorAddrType(desc)
+	switch {
+	case singleKey && key.IsPrivate() && key.IsRange():
+		return nil, fmt.Errorf("%w: ranged extended private key",
+			ErrUnsupportedDescriptor)
+
+	case desc.IsRange() && rangeEnd > MaxDescriptorRangeEnd:
+		return nil, fmt.Errorf("range end %d exceeds the maximum of %d",
+			rangeEnd, MaxDescriptorRangeEnd)
+	}
+	if singleKey && isAccountKey(key) {
+		var props *waddrmgr.AccountProperties
+		err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+			ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+			var err error
+			props, err = w.importAccountDescriptor(
+				ns, name, key, waddrmgr.ScopeAddrSchema{
+					ExternalAddrType: addrType,
+					InternalAddrType: addrType,
+				},
+			)
+			return err
+		})
+		return props, err
+	}
+
+	// The starting block for the addresses is the genesis block unless
+	// otherwise specified.
+	if bs == nil {
+		bs = &waddrmgr.BlockStamp{
+			Hash:      *w.chainParams.GenesisHash,
+			Height:    0,
+			Timestamp: w.chainParams.GenesisBlock.Header.Timestamp,
+		}
+	} else if bs.Timestamp.IsZero() {
+		// Only update the new birthday time from default value if we
+		// actually have timestamp info in the header.
+		header, err := w.chainClient.GetBlockHeader(&bs.Hash)
+		if err == nil {
+			bs.Timestamp = header.Timestamp
+		}
+	}
+
+	if !desc.IsRange() {
+		rangeEnd = 0
+	}
+
+	var (
+		addrs []btcutil.Address
+		props *waddrmgr.AccountProperties
+	)
+	err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		var scopedMgr *waddrmgr.ScopedKeyManager
+		for index := uint32(0); ; index++ {
+			addr, mgr, err := w.importDescriptorIndex(
+				ns, desc, index, bs,
+			)
+			if err != nil {
+				return fmt.Errorf("index %d: %w", index, err)
+			}
+			if addr != nil {
+				addrs = append(addrs, addr)
+			}
+			scopedMgr = mgr
+			if index == rangeEnd {
+				break
+			}
+		}
+
+		var err error
+		props, err = scopedMgr.AccountProperties(
+			ns, waddrmgr.ImportedAddrAccount,
+		)
+		if err != nil {
+			return err
+		}
+
+		// We'll only update our birthday with the new one if it is
+		// before our current one, as with imported private keys.  The
+		// birthday block of wallets which have not synced yet is
+		// located from the birthday.
+		birthdayBlock, _, err := w.Manager.BirthdayBlock(ns)
+		switch {
+		case waddrmgr.IsError(err, waddrmgr.ErrBirthdayBlockNotSet):
+			if !bs.Timestamp.Before(w.Manager.Birthday()) {
+				return nil
+			}
+			return w.Manager.SetBirthday(ns, bs.Timestamp)
+
+		case err != nil:
+			return err
+
+		case bs.Height >= birthdayBlock.Height:
+			return nil
+		}
+		err = w.Manager.SetBirthday(ns, bs.Timestamp)
+		if err != nil {
+			return err
+		}
+		return w.Manager.SetBirthdayBlock(ns, *bs, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.log.Infof("Imported %d addresses of descriptor %v", len(addrs),
+		desc.StringWithChecksum())
+
+	// TODO: Perform rescan if requested.
+	if len(addrs) > 0 {
+		err = w.chainClient.NotifyReceived(addrs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to subscribe for address "+
+				"notifications: %w", err)
+		}
+	}
+
+	w.NtfnServer.notifyAccountProperties(props)
+
+	return props, nil
+}
+
+// importAccountDescriptor imports the watch-only account of an account
+// extended public key found in descriptors, using the address schema
+// provided.
+func (w *Wallet) importAccountDescriptor(ns walletdb.ReadWriteBucket,
+	name string, key *descriptor.Key,
+	schema waddrmgr.ScopeAddrSchema) (*waddrmgr.AccountProperties, error) {
+
+	if err := w.validateExtendedPubKey(key.ExtKey, true); err != nil {
+		return nil, err
+	}
+
+	// Only schemas other than the default one of the scope are stored
+	// with the account.
+	scope := addrTypeScopes[schema.ExternalAddrType]
+	addrSchema := &schema
+	for _, defaultScope := range waddrmgr.DefaultKeyScopes {
+		if waddrmgr.ScopeAddrMap[defaultScope] == schema {
+			scope, addrSchema = defaultScope, nil
+			break
+		}
+	}
+
+	// Importing the key of an existing account returns the account.
+	scopedMgr, err := w.Manager.FetchScopedKeyManager(scope)
+	if err == nil {
+		var props *waddrmgr.AccountProperties
+		err := scopedMgr.ForEachAccount(ns, func(account uint32) error {
+			p, err := scopedMgr.AccountProperties(ns, account)
+			if err != nil || p.AccountPubKey == nil {
+				return err
+			}
+			same, err := waddrmgr.SameExtendedKey(
+				p.AccountPubKey, key.ExtKey,
+			)
+			if same {
+				props = p
+			}
+			return err
+		})
+		if err != nil || props != nil {
+			return props, err
+		}
+	}
+
+	var fingerprint uint32
+	if key.Origin != nil {
+		fingerprint = key.Origin.Fingerprint
+	}
+	return w.importAccountScope(
+		ns, name, key.ExtKey, fingerprint, scope, addrSchema,
+	)
+}
+
+// importDescriptorIndex imports the address of the descriptor at the index of
+// its range, returning the address and the scoped manager it was imported
+// into.  A nil address is returned if the address was already imported.
+func (w *Wallet) importDescriptorIndex(ns walletdb.ReadWriteBucket,
+	desc *descriptor.Descriptor, index uint32, bs *waddrmgr.BlockStamp) (
+	btcutil.Address, *waddrmgr.ScopedKeyManager, error) {
+
+	var (
+		scope      = waddrmgr.KeyScopeBIP0084
+		importAddr func(*waddrmgr.ScopedKeyManager) (
+			waddrmgr.ManagedAddress, error)
+	)
+	if addrType, key, ok := descriptorAddrType(desc); ok {
+		scope = addrTypeScopes[addrType]
+		importAddr = func(m *waddrmgr.ScopedKeyManager) (
+			waddrmgr.ManagedAddress, error) {
+
+			if !key.IsPrivate() {
+				pubKey, err := key.PubKeyAt(index)
+				if err != nil {
+					return nil, err
+				}
+				return m.ImportPublicKey(ns, pubKey, bs)
+			}
+			privKey, err := key.PrivKeyAt(index)
+			if err != nil {
+				return nil, err
+			}
+			wif, err := btcutil.NewWIF(privKey, w.chainParams, true)
+			if err != nil {
+				return nil, err
+			}
+			return m.ImportPrivateKey(ns, wif, bs)
+		}
+	} else {
+		switch {
+		case desc.Type == descriptor.TypeSh &&
+			desc.Sub.Type == descriptor.TypeWsh:
+
+			script, err := desc.Sub.Sub.Script(index)
+			if err != nil {
+				return nil, nil, err
+			}
+			importAddr = func(m *waddrmgr.ScopedKeyManager) (
+				waddrmgr.ManagedAddress, error) {
+
+				return m.ImportNestedWitnessScript(
+					ns, script, bs, false,
+				)
+			}
+
+		case desc.Type == descriptor.TypeSh:
+
+			script, err := desc.Sub.Script(index)
+			if err != nil {
+				return nil, nil, err
+			}
+			importAddr = func(m *waddrmgr.ScopedKeyManager) (
+				waddrmgr.ManagedAddress, error) {
+
+				return m.ImportScript(ns, script, bs)
+			}
+
+		case desc.Type == descriptor.TypeWsh:
+			script, err := desc.Sub.Script(index)
+			if err != nil {
+				return nil, nil, err
+			}
+			importAddr = func(m *waddrmgr.ScopedKeyManager) (
+				waddrmgr.ManagedAddress, error) {
+
+				return m.ImportWitnessScript(ns, script, bs, 0, false)
+			}
+
+		case desc.Type == descriptor.TypeTr:
+			scope = waddrmgr.KeyScopeBIP0086
+			tapscript, err := descriptorTapscript(desc, index)
+			if err != nil {
+				return nil, nil, err
+			}
+			importAddr = func(m *waddrmgr.ScopedKeyManager) (
+				waddrmgr.ManagedAddress, error) {
+
+				return m.ImportTaprootScript(
+					ns, tapscript, bs, 1, false,
+				)
+			}
+
+		default:
+			return nil, nil, fmt.Errorf("%w: %v outputs",
+				ErrUnsupportedDescriptor, desc.Type)
+		}
+	}
+
+	scopedMgr, err := w.Manager.FetchScopedKeyManager(scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	addr, err := importAddr(scopedMgr)
+	if waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+		return nil, scopedMgr, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return addr.Address(), scopedMgr, nil
+}
+
+// descriptorTapscript returns the tapscript of a taproot script tree at the
+// index of its range, with all the leaves of the tree.  The tapscript derives
+// its tree from the leaves, so trees of a different shape are not supported.
+func descriptorTapscript(desc *descriptor.Descriptor,
+	index uint32) (*waddrmgr.Tapscript, error) {
+
+	ctrlBlock, _, err := desc.ControlBlock(index, 0)
+	if err != nil {
+		return nil, err
+	}
+	leafDescs := desc.Tree.Leaves()
+	leaves := make([]txscript.TapLeaf, 0, len(leafDescs))
+	for _, leafDesc := range leafDescs {
+		script, err := leafDesc.Script(index)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, txscript.NewTapLeaf(
+			txscript.BaseLeafVersion, script,
+		))
+	}
+	tapscript := &waddrmgr.Tapscript{
+		Type:         waddrmgr.TapscriptTypeFullTree,
+		ControlBlock: ctrlBlock,
+		Leaves:       leaves,
+	}
+
+	outputKey, err := desc.TaprootOutputKey(index)
+	if err != nil {
+		return nil, err
+	}
+	treeKey, err := tapscript.TaprootKey()
+	if err != nil {
+		return nil, err
+	}
+	if !outputKey.IsEqual(treeKey) {
+		return nil, fmt.Errorf("%w: taproot script tree shape",
+			ErrUnsupportedDescriptor)
+	}
+	return tapscript, nil
+}
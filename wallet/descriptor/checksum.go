@@ -0,0 +1,102 @@
+package descriptor
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// inputCharset is the set of characters descriptors are made of, in
+	// the order defined by BIP-0380 for their checksum.
+	inputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+
+	// checksumCharset is the set of characters of checksums.
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	// checksumLen is the number of characters of a checksum.
+	checksumLen = 8
+)
+
+var (
+	// ErrInvalidChecksum is returned when parsing a descriptor with a
+	// checksum not matching the descriptor.
+	ErrInvalidChecksum = errors.New("invalid descriptor checksum")
+
+	// checksumGenerator is the generator of the checksum code.
+	checksumGenerator = [5]uint64{
+		0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a,
+		0x644d626ffd,
+	}
+)
+
+// polymod updates the checksum state with the symbol provided.
+func polymod(c uint64, value int) uint64 {
+	top := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ uint64(value)
+	for i, gen := range checksumGenerator {
+		if (top>>i)&1 != 0 {
+			c ^= gen
+		}
+	}
+	return c
+}
+
+// Checksum returns the BIP-0380 checksum of the descriptor, which must not
+// include a checksum itself.
+func Checksum(desc string) (string, error) {
+	var (
+		c        uint64 = 1
+		cls      int
+		clsCount int
+	)
+	for _, ch := range desc {
+		pos := strings.IndexRune(inputCharset, ch)
+		if pos == -1 {
+			return "", errors.New("invalid descriptor character " +
+				string(ch))
+		}
+
+		// Each character is fed as its position within its group of
+		// 32 characters, with the groups of every three characters
+		// fed as an additional symbol.
+		c = polymod(c, pos&31)
+		cls = cls*3 + pos>>5
+		clsCount++
+		if clsCount == 3 {
+			c = polymod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = polymod(c, cls)
+	}
+	for i := 0; i < checksumLen; i++ {
+		c = polymod(c, 0)
+	}
+	c ^= 1
+
+	checksum := make([]byte, checksumLen)
+	for i := range checksum {
+		checksum[i] = checksumCharset[(c>>(5*(7-i)))&31]
+	}
+	return string(checksum), nil
+}
+
+// splitChecksum splits the descriptor from its checksum, verifying the
+// checksum if there is one.
+func splitChecksum(s string) (string, error) {
+	desc, checksum, ok := strings.Cut(s, "#")
+	if !ok {
+		return s, nil
+	}
+	expected, err := Checksum(desc)
+	if err != nil {
+		return "", err
+	}
+	if checksum != expected {
+		return "", ErrInvalidChecksum
+	}
+	return desc, nil
+}
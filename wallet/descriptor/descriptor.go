@@ -0,0 +1,619 @@
+// Package descriptor implements output script descriptors as specified by
+// BIP-0380 and the BIPs defining their script expressions: pk, pkh, wpkh, sh,
+// wsh, tr, multi, sortedmulti, multi_a and sortedmulti_a.  Miniscript is not
+// supported.
+package descriptor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// Type is the type of a script expression.
+type Type string
+
+// These constants define the supported script expressions.
+const (
+	TypePk           Type = "pk"
+	TypePkh          Type = "pkh"
+	TypeWpkh         Type = "wpkh"
+	TypeSh           Type = "sh"
+	TypeWsh          Type = "wsh"
+	TypeTr           Type = "tr"
+	TypeMulti        Type = "multi"
+	TypeSortedMulti  Type = "sortedmulti"
+	TypeMultiA       Type = "multi_a"
+	TypeSortedMultiA Type = "sortedmulti_a"
+)
+
+const (
+	// maxMultiKeys is the maximum number of keys of multi and
+	// sortedmulti expressions.
+	maxMultiKeys = 20
+
+	// maxMultiAKeys is the maximum number of keys of multi_a and
+	// sortedmulti_a expressions.
+	maxMultiAKeys = 999
+
+	// maxTapTreeDepth is the maximum depth of taproot script trees.
+	maxTapTreeDepth = 128
+)
+
+// context is the context in which a script expression is found, restricting
+// the expressions allowed within it.
+type context uint8
+
+const (
+	contextTop context = iota
+	contextSh
+	contextWsh
+	contextTapLeaf
+)
+
+// Descriptor is an output script descriptor, or a script expression within
+// one.
+type Descriptor struct {
+	// Type is the type of the script expression.
+	Type Type
+
+	// Keys are the key arguments of the expression.  Taproot expressions
+	// have their internal key as the only key.
+	Keys []*Key
+
+	// Threshold is the number of signatures required by multisig
+	// expressions.
+	Threshold int
+
+	// Sub is the script expression nested within sh and wsh expressions.
+	Sub *Descriptor
+
+	// Tree is the script tree of taproot expressions, or nil if they only
+	// have an internal key.
+	Tree *TapTree
+}
+
+// TapTree is a node of a taproot script tree, which is either a leaf with a
+// script expression or a branch with two children.
+type TapTree struct {
+	// Leaf is the script expression of leaf nodes.
+	Leaf *Descriptor
+
+	// Left and Right are the children of branch nodes.
+	Left, Right *TapTree
+}
+
+// Leaves returns the script expressions of the leaves of the tree, in depth
+// first order.
+func (t *TapTree) Leaves() []*Descriptor {
+	if t.Leaf != nil {
+		return []*Descriptor{t.Leaf}
+	}
+	return append(t.Left.Leaves(), t.Right.Leaves()...)
+}
+
+// String returns the descriptor, without its checksum.
+func (d *Descriptor) String() string {
+	var args []string
+	switch d.Type {
+	case TypeSh, TypeWsh:
+		args = []string{d.Sub.String()}
+	case TypeMulti, TypeSortedMulti, TypeMultiA, TypeSortedMultiA:
+		args = []string{strconv.Itoa(d.Threshold)}
+	}
+	for _, key := range d.Keys {
+		args = append(args, key.String())
+	}
+	if d.Tree != nil {
+		args = append(args, d.Tree.String())
+	}
+	return string(d.Type) + "(" + strings.Join(args, ",") + ")"
+}
+
+// String returns the script tree expression.
+func (t *TapTree) String() string {
+	if t.Leaf != nil {
+		return t.Leaf.String()
+	}
+	return "{" + t.Left.String() + "," + t.Right.String() + "}"
+}
+
+// StringWithChecksum returns the descriptor followed by its checksum.
+func (d *Descriptor) StringWithChecksum() string {
+	desc := d.String()
+	checksum, err := Checksum(desc)
+	if err != nil {
+		// Serialized descriptors only use valid characters.
+		panic(err)
+	}
+	return desc + "#" + checksum
+}
+
+// keys returns all the keys of the descriptor, including those of nested
+// expressions.
+func (d *Descriptor) keys() []*Key {
+	keys := append([]*Key{}, d.Keys...)
+	if d.Sub != nil {
+		keys = append(keys, d.Sub.keys()...)
+	}
+	if d.Tree != nil {
+		for _, leaf := range d.Tree.Leaves() {
+			keys = append(keys, leaf.keys()...)
+		}
+	}
+	return keys
+}
+
+// IsRange returns whether the descriptor describes a range of scripts.
+func (d *Descriptor) IsRange() bool {
+	for _, key := range d.keys() {
+		if key.IsRange() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivate returns whether the descriptor includes private keys.
+func (d *Descriptor) IsPrivate() bool {
+	for _, key := range d.keys() {
+		if key.IsPrivate() {
+			return true
+		}
+	}
+	return false
+}
+
+// pubKeysAt returns the public keys of the expression at the index of the
+// range, sorted for sorted multisig expressions.
+func (d *Descriptor) pubKeysAt(index uint32) ([]*btcec.PublicKey, error) {
+	pubKeys := make([]*btcec.PublicKey, len(d.Keys))
+	for i, key := range d.Keys {
+		var err error
+		pubKeys[i], err = key.PubKeyAt(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch d.Type {
+	case TypeSortedMulti:
+		sort.Slice(pubKeys, func(i, j int) bool {
+			return bytes.Compare(
+				pubKeys[i].SerializeCompressed(),
+				pubKeys[j].SerializeCompressed(),
+			) < 0
+		})
+	case TypeSortedMultiA:
+		sort.Slice(pubKeys, func(i, j int) bool {
+			return bytes.Compare(
+				schnorr.SerializePubKey(pubKeys[i]),
+				schnorr.SerializePubKey(pubKeys[j]),
+			) < 0
+		})
+	}
+	return pubKeys, nil
+}
+
+// Script returns the script described at the index of the range.  The index
+// is ignored for descriptors which are not ranged.  This is the output script
+// of top level expressions, the redeem or witness script of expressions
+// nested within sh and wsh expressions, and the leaf script of expressions
+// within taproot trees.
+func (d *Descriptor) Script(index uint32) ([]byte, error) {
+	pubKeys, err := d.pubKeysAt(index)
+	if err != nil {
+		return nil, err
+	}
+
+	b := txscript.NewScriptBuilder()
+	switch d.Type {
+	case TypePk:
+		if d.Keys[0].XOnly {
+			return b.AddData(schnorr.SerializePubKey(pubKeys[0])).
+				AddOp(txscript.OP_CHECKSIG).
+				Script()
+		}
+		return b.AddData(pubKeys[0].SerializeCompressed()).
+			AddOp(txscript.OP_CHECKSIG).
+			Script()
+
+	case TypePkh:
+		return b.AddOp(txscript.OP_DUP).
+			AddOp(txscript.OP_HASH160).
+			AddData(btcutil.Hash160(pubKeys[0].SerializeCompressed())).
+			AddOp(txscript.OP_EQUALVERIFY).
+			AddOp(txscript.OP_CHECKSIG).
+			Script()
+
+	case TypeWpkh:
+		return b.AddOp(txscript.OP_0).
+			AddData(btcutil.Hash160(pubKeys[0].SerializeCompressed())).
+			Script()
+
+	case TypeSh:
+		script, err := d.Sub.Script(index)
+		if err != nil {
+			return nil, err
+		}
+		if len(script) > txscript.MaxScriptElementSize {
+			return nil, fmt.Errorf("redeem script size %d exceeds "+
+				"the maximum of %d", len(script),
+				txscript.MaxScriptElementSize)
+		}
+		return b.AddOp(txscript.OP_HASH160).
+			AddData(btcutil.Hash160(script)).
+			AddOp(txscript.OP_EQUAL).
+			Script()
+
+	case TypeWsh:
+		script, err := d.Sub.Script(index)
+		if err != nil {
+			return nil, err
+		}
+		scriptHash := sha256.Sum256(script)
+		return b.AddOp(txscript.OP_0).
+			AddData(scriptHash[:]).
+			Script()
+
+	case TypeTr:
+		outputKey, err := d.TaprootOutputKey(index)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.PayToTaprootScript(outputKey)
+
+	case TypeMulti, TypeSortedMulti:
+		b.AddInt64(int64(d.Threshold))
+		for _, pubKey := range pubKeys {
+			b.AddData(pubKey.SerializeCompressed())
+		}
+		return b.AddInt64(int64(len(pubKeys))).
+			AddOp(txscript.OP_CHECKMULTISIG).
+			Script()
+
+	case TypeMultiA, TypeSortedMultiA:
+		for i, pubKey := range pubKeys {
+			b.AddData(schnorr.SerializePubKey(pubKey))
+			if i == 0 {
+				b.AddOp(txscript.OP_CHECKSIG)
+			} else {
+				b.AddOp(txscript.OP_CHECKSIGADD)
+			}
+		}
+		return b.AddInt64(int64(d.Threshold)).
+			AddOp(txscript.OP_NUMEQUAL).
+			Script()
+
+	default:
+		return nil, fmt.Errorf("unknown script expression %q", d.Type)
+	}
+}
+
+// Address returns the address of the output script described at the index of
+// the range.
+func (d *Descriptor) Address(index uint32,
+	params *chaincfg.Params) (btcutil.Address, error) {
+
+	script, err := d.Script(index)
+	if err != nil {
+		return nil, err
+	}
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(script, params)
+	if err != nil {
+		return nil, err
+	}
+	switch class {
+	case txscript.PubKeyHashTy, txscript.ScriptHashTy,
+		txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy,
+		txscript.WitnessV1TaprootTy:
+
+		return addrs[0], nil
+	default:
+		return nil, fmt.Errorf("%s descriptor has no address", d.Type)
+	}
+}
+
+// tapNode returns the taproot tree node of the script tree at the index of
+// the range.
+func (t *TapTree) tapNode(index uint32) (txscript.TapNode, error) {
+	if t.Leaf != nil {
+		script, err := t.Leaf.Script(index)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.NewBaseTapLeaf(script), nil
+	}
+	left, err := t.Left.tapNode(index)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.Right.tapNode(index)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.NewTapBranch(left, right), nil
+}
+
+// TaprootOutputKey returns the output key of a taproot descriptor at the
+// index of the range.
+func (d *Descriptor) TaprootOutputKey(index uint32) (*btcec.PublicKey, error) {
+	if d.Type != TypeTr {
+		return nil, fmt.Errorf("%s descriptor has no taproot output key",
+			d.Type)
+	}
+	internalKey, err := d.Keys[0].PubKeyAt(index)
+	if err != nil {
+		return nil, err
+	}
+	if d.Tree == nil {
+		return txscript.ComputeTaprootKeyNoScript(internalKey), nil
+	}
+	root, err := d.Tree.tapNode(index)
+	if err != nil {
+		return nil, err
+	}
+	rootHash := root.TapHash()
+	return txscript.ComputeTaprootOutputKey(internalKey, rootHash[:]), nil
+}
+
+// inclusionProof returns the hashes proving the inclusion of the leaf at the
+// position provided, in depth first order, in the tree.
+func (t *TapTree) inclusionProof(index uint32, leaf int) ([]byte, error) {
+	if t.Leaf != nil {
+		return nil, nil
+	}
+
+	leftLeaves := len(t.Left.Leaves())
+	inner, sibling := t.Left, t.Right
+	if leaf >= leftLeaves {
+		inner, sibling = t.Right, t.Left
+		leaf -= leftLeaves
+	}
+	proof, err := inner.inclusionProof(index, leaf)
+	if err != nil {
+		return nil, err
+	}
+	siblingNode, err := sibling.tapNode(index)
+	if err != nil {
+		return nil, err
+	}
+	siblingHash := siblingNode.TapHash()
+	return append(proof, siblingHash[:]...), nil
+}
+
+// ControlBlock returns the control block spending a taproot descriptor at the
+// index of the range through the leaf at the position provided, in depth first
+// order, in its script tree.  The leaf script is returned along with the
+// control block.
+func (d *Descriptor) ControlBlock(index uint32, leaf int) (
+	*txscript.ControlBlock, []byte, error) {
+
+	if d.Type != TypeTr || d.Tree == nil {
+		return nil, nil, errors.New("descriptor has no script tree")
+	}
+	leaves := d.Tree.Leaves()
+	if leaf < 0 || leaf >= len(leaves) {
+		return nil, nil, fmt.Errorf("script tree has no leaf %d", leaf)
+	}
+	script, err := leaves[leaf].Script(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err := d.Tree.inclusionProof(index, leaf)
+	if err != nil {
+		return nil, nil, err
+	}
+	internalKey, err := d.Keys[0].PubKeyAt(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputKey, err := d.TaprootOutputKey(index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctrlBlock := &txscript.ControlBlock{
+		InternalKey:     internalKey,
+		OutputKeyYIsOdd: outputKey.SerializeCompressed()[0] == 0x03,
+		LeafVersion:     txscript.BaseLeafVersion,
+		InclusionProof:  proof,
+	}
+	return ctrlBlock, script, nil
+}
+
+// Parse parses a descriptor, verifying its checksum if it has one.
+func Parse(s string) (*Descriptor, error) {
+	desc, err := splitChecksum(s)
+	if err != nil {
+		return nil, err
+	}
+	return parseExpr(desc, contextTop)
+}
+
+// splitArgs splits the arguments of an expression at the commas which are
+// not nested within other expressions, key origins or script trees.
+func splitArgs(s string) ([]string, error) {
+	var (
+		args  []string
+		depth int
+		start int
+	)
+	for i, ch := range s {
+		switch ch {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced %q", ch)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("unbalanced brackets")
+	}
+	return append(args, s[start:]), nil
+}
+
+// parseExpr parses a script expression found in the context provided.
+func parseExpr(s string, ctx context) (*Descriptor, error) {
+	open := strings.IndexByte(s, '(')
+	if open == -1 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("invalid script expression %q", s)
+	}
+	d := &Descriptor{Type: Type(s[:open])}
+	args, err := splitArgs(s[open+1 : len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[context][]Type{
+		contextTop: {
+			TypePk, TypePkh, TypeWpkh, TypeSh, TypeWsh, TypeTr,
+			TypeMulti, TypeSortedMulti,
+		},
+		contextSh: {
+			TypePk, TypePkh, TypeWpkh, TypeWsh, TypeMulti,
+			TypeSortedMulti,
+		},
+		contextWsh: {TypePk, TypePkh, TypeMulti, TypeSortedMulti},
+		contextTapLeaf: {
+			TypePk, TypeMultiA, TypeSortedMultiA,
+		},
+	}
+	var ok bool
+	for _, t := range allowed[ctx] {
+		ok = ok || t == d.Type
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s expression not allowed here", d.Type)
+	}
+
+	// Keys are serialized as x-only keys within taproot trees.  Keys
+	// are otherwise compressed, as ParseKey rejects uncompressed keys.
+	xOnly := ctx == contextTapLeaf
+	switch d.Type {
+	case TypePk, TypePkh, TypeWpkh:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expression takes one key",
+				d.Type)
+		}
+		key, err := ParseKey(args[0], xOnly)
+		if err != nil {
+			return nil, err
+		}
+		d.Keys = []*Key{key}
+
+	case TypeSh, TypeWsh:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expression takes one script "+
+				"expression", d.Type)
+		}
+		subCtx := contextSh
+		if d.Type == TypeWsh {
+			subCtx = contextWsh
+		}
+		d.Sub, err = parseExpr(args[0], subCtx)
+		if err != nil {
+			return nil, err
+		}
+
+	case TypeTr:
+		if len(args) != 1 && len(args) != 2 {
+			return nil, errors.New("tr expression takes a key and an " +
+				"optional script tree")
+		}
+		key, err := ParseKey(args[0], true)
+		if err != nil {
+			return nil, err
+		}
+		d.Keys = []*Key{key}
+		if len(args) == 2 {
+			d.Tree, err = parseTree(args[1], 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	case TypeMulti, TypeSortedMulti, TypeMultiA, TypeSortedMultiA:
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%s expression takes a threshold "+
+				"and keys", d.Type)
+		}
+		d.Threshold, err = strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q", args[0])
+		}
+		maxKeys := maxMultiKeys
+		if d.Type == TypeMultiA || d.Type == TypeSortedMultiA {
+			maxKeys = maxMultiAKeys
+		}
+		numKeys := len(args) - 1
+		if d.Threshold < 1 || d.Threshold > numKeys ||
+			numKeys > maxKeys {
+
+			return nil, fmt.Errorf("invalid %d of %d %s expression",
+				d.Threshold, numKeys, d.Type)
+		}
+		for _, arg := range args[1:] {
+			key, err := ParseKey(arg, xOnly)
+			if err != nil {
+				return nil, err
+			}
+			d.Keys = append(d.Keys, key)
+		}
+	}
+
+	return d, nil
+}
+
+// parseTree parses a taproot script tree at the depth provided.
+func parseTree(s string, depth int) (*TapTree, error) {
+	if depth > maxTapTreeDepth {
+		return nil, errors.New("script tree too deep")
+	}
+	if !strings.HasPrefix(s, "{") {
+		leaf, err := parseExpr(s, contextTapLeaf)
+		if err != nil {
+			return nil, err
+		}
+		return &TapTree{Leaf: leaf}, nil
+	}
+
+	if !strings.HasSuffix(s, "}") {
+		return nil, errors.New("script tree branch not closed")
+	}
+	children, err := splitArgs(s[1 : len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(children) != 2 {
+		return nil, errors.New("script tree branch must have two " +
+			"children")
+	}
+	left, err := parseTree(children[0], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	right, err := parseTree(children[1], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return &TapTree{Left: left, Right: right}, nil
+}
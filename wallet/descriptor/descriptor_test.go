@@ -0,0 +1,296 @@
+package descriptor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVectors tests scripts described by descriptors from the BIP test
+// vectors.
+func TestVectors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		script string
+	}{{
+		desc:   "pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)",
+		script: "76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac",
+	}, {
+		desc:   "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)",
+		script: "512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11",
+	}}
+	for _, test := range tests {
+		d, err := Parse(test.desc)
+		require.NoError(t, err)
+		script, err := d.Script(0)
+		require.NoError(t, err)
+		require.Equal(t, test.script, hex.EncodeToString(script))
+	}
+}
+
+// testMasterKey is the master key of the first BIP-0032 test vector.
+const testMasterKey = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jP" +
+	"PqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+
+// testAccountKey returns the extended public key of the account with the path
+// provided below the test master key, along with the master key.
+func testAccountKey(t *testing.T, path ...uint32) (*hdkeychain.ExtendedKey,
+	*hdkeychain.ExtendedKey) {
+
+	master, err := hdkeychain.NewKeyFromString(testMasterKey)
+	require.NoError(t, err)
+	key := master
+	for _, step := range path {
+		key, err = key.Derive(step)
+		require.NoError(t, err)
+	}
+	pub, err := key.Neuter()
+	require.NoError(t, err)
+	return pub, master
+}
+
+// TestParseString tests that parsed descriptors are serialized back in their
+// canonical form.
+func TestParseString(t *testing.T) {
+	t.Parallel()
+
+	const (
+		h      = hdkeychain.HardenedKeyStart
+		pubKey = "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac" +
+			"09b95c709ee5"
+		xOnly = "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b9" +
+			"5c709ee5"
+	)
+	account, _ := testAccountKey(t, 84+h, h, h)
+	xpub := account.String()
+
+	tests := []struct {
+		desc      string
+		canonical string
+	}{{
+		desc:      "wpkh([d34db33f/84'/0'/0']" + xpub + "/0/*)",
+		canonical: "wpkh([d34db33f/84h/0h/0h]" + xpub + "/0/*)",
+	}, {
+		desc: "sh(wpkh(" + pubKey + "))",
+	}, {
+		desc: "pkh([d34db33f]" + xpub + "/1/2)",
+	}, {
+		desc: "sh(wsh(sortedmulti(2," + xpub + "/0/*," + pubKey + ")))",
+	}, {
+		desc: "tr(" + pubKey + ",{pk(" + xpub + "/1/*),{multi_a(1," +
+			xOnly + "," + xpub + "/0/*),pk(" + xOnly + ")}})",
+		canonical: "tr(" + xOnly + ",{pk(" + xpub + "/1/*),{multi_a(1," +
+			xOnly + "," + xpub + "/0/*),pk(" + xOnly + ")}})",
+	}, {
+		desc: "tr(" + testMasterKey + "/86h/0h/0h/0/*h)",
+	}}
+	for _, test := range tests {
+		d, err := Parse(test.desc)
+		require.NoError(t, err, test.desc)
+		canonical := test.canonical
+		if canonical == "" {
+			canonical = test.desc
+		}
+		require.Equal(t, canonical, d.String())
+
+		// Checksums are verified when parsing.
+		withChecksum := d.StringWithChecksum()
+		_, err = Parse(withChecksum)
+		require.NoError(t, err)
+		invalid := withChecksum[:len(withChecksum)-1] + "q"
+		if invalid == withChecksum {
+			invalid = withChecksum[:len(withChecksum)-1] + "p"
+		}
+		_, err = Parse(invalid)
+		require.ErrorIs(t, err, ErrInvalidChecksum)
+	}
+
+	for _, invalid := range []string{
+		"wpkh(" + xpub + "/0h/*)",
+		"wpkh(" + xpub + "/0/*h)",
+		"wsh(wpkh(" + pubKey + "))",
+		"sh(sh(pk(" + pubKey + ")))",
+		"tr(" + pubKey + ",wpkh(" + pubKey + "))",
+		"tr(" + pubKey + ",{pk(" + xOnly + ")})",
+		"wsh(multi(3," + pubKey + "," + pubKey + "))",
+		"wsh(multi(0," + pubKey + "))",
+		"wpkh(" + xOnly + ")",
+		"wpkh([d34db3]" + pubKey + ")",
+		"wpkh(" + pubKey + "," + pubKey + ")",
+		"wpkh(" + pubKey,
+		"multi_a(1," + xOnly + ")",
+	} {
+		_, err := Parse(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+// TestDerivedScripts tests that the scripts described by ranged descriptors
+// pay to the keys derived at each index.
+func TestDerivedScripts(t *testing.T) {
+	t.Parallel()
+
+	const h = hdkeychain.HardenedKeyStart
+	account, master := testAccountKey(t, 84+h, h, h)
+	d, err := Parse("wpkh([d34db33f/84h/0h/0h]" + account.String() + "/1/*)")
+	require.NoError(t, err)
+	require.True(t, d.IsRange())
+	require.False(t, d.IsPrivate())
+
+	params := &chaincfg.MainNetParams
+	for index := uint32(0); index < 3; index++ {
+		_, err := d.Keys[0].PrivKeyAt(index)
+		require.Error(t, err)
+
+		key, _ := testAccountKey(t, 84+h, h, h, 1, index)
+		pubKey, err := key.ECPubKey()
+		require.NoError(t, err)
+		expected, err := btcutil.NewAddressWitnessPubKeyHash(
+			btcutil.Hash160(pubKey.SerializeCompressed()), params,
+		)
+		require.NoError(t, err)
+		addr, err := d.Address(index, params)
+		require.NoError(t, err)
+		require.Equal(t, expected.String(), addr.String())
+
+		origin := d.Keys[0].OriginAt(index)
+		require.Equal(t, []uint32{84 + h, h, h, 1, index}, origin.Path)
+	}
+
+	// Private keys are derived from private descriptors.
+	d, err = Parse("pkh(" + master.String() + "/44h/0h/0h/0/*)")
+	require.NoError(t, err)
+	require.True(t, d.IsPrivate())
+	privKey, err := d.Keys[0].PrivKeyAt(7)
+	require.NoError(t, err)
+	key, _ := testAccountKey(t, 44+h, h, h, 0, 7)
+	pubKey, err := key.ECPubKey()
+	require.NoError(t, err)
+	require.Equal(t, pubKey, privKey.PubKey())
+	expected, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(pubKey.SerializeCompressed()), params,
+	)
+	require.NoError(t, err)
+	addr, err := d.Address(7, params)
+	require.NoError(t, err)
+	require.Equal(t, expected.String(), addr.String())
+}
+
+// TestMultisigScripts tests that multisig descriptors describe the same
+// scripts as the multisig scripts of txscript.
+func TestMultisigScripts(t *testing.T) {
+	t.Parallel()
+
+	const h = hdkeychain.HardenedKeyStart
+	key1, _ := testAccountKey(t, 48+h, h, h, 2+h)
+	key2, _ := testAccountKey(t, 48+h, h, 1+h, 2+h)
+	d, err := Parse("wsh(sortedmulti(2," + key1.String() + "/0/*," +
+		key2.String() + "/0/*))")
+	require.NoError(t, err)
+
+	params := &chaincfg.MainNetParams
+	for index := uint32(0); index < 3; index++ {
+		var addrPubKeys []*btcutil.AddressPubKey
+		for _, path := range [][]uint32{
+			{48 + h, h, h, 2 + h, 0, index},
+			{48 + h, h, 1 + h, 2 + h, 0, index},
+		} {
+			key, _ := testAccountKey(t, path...)
+			pubKey, err := key.ECPubKey()
+			require.NoError(t, err)
+			addrPubKey, err := btcutil.NewAddressPubKey(
+				pubKey.SerializeCompressed(), params,
+			)
+			require.NoError(t, err)
+			addrPubKeys = append(addrPubKeys, addrPubKey)
+		}
+
+		// Sorted multisig scripts do not depend on the order of keys.
+		if string(addrPubKeys[0].ScriptAddress()) >
+			string(addrPubKeys[1].ScriptAddress()) {
+
+			addrPubKeys[0], addrPubKeys[1] = addrPubKeys[1],
+				addrPubKeys[0]
+		}
+		multiSig, err := txscript.MultiSigScript(addrPubKeys, 2)
+		require.NoError(t, err)
+		script, err := d.Sub.Script(index)
+		require.NoError(t, err)
+		require.Equal(t, multiSig, script)
+
+		scriptHash := sha256.Sum256(multiSig)
+		expected, err := btcutil.NewAddressWitnessScriptHash(
+			scriptHash[:], params,
+		)
+		require.NoError(t, err)
+		addr, err := d.Address(index, params)
+		require.NoError(t, err)
+		require.Equal(t, expected.String(), addr.String())
+	}
+
+	// Bare multisig outputs have no address.
+	d, err = Parse("multi(1," + key1.String() + ")")
+	require.NoError(t, err)
+	_, err = d.Address(0, params)
+	require.Error(t, err)
+}
+
+// TestTaprootTree tests that the control blocks of the leaves of taproot
+// script trees commit to the output key.
+func TestTaprootTree(t *testing.T) {
+	t.Parallel()
+
+	const h = hdkeychain.HardenedKeyStart
+	account, _ := testAccountKey(t, 86+h, h, h)
+	xpub := account.String()
+	d, err := Parse("tr(" + xpub + "/0/*,{pk(" + xpub + "/1/*),{{pk(" +
+		xpub + "/2/*),sortedmulti_a(1," + xpub + "/3/*," + xpub +
+		"/4/*)},pk(" + xpub + "/5/*)}})")
+	require.NoError(t, err)
+	require.Len(t, d.Tree.Leaves(), 4)
+
+	for index := uint32(0); index < 2; index++ {
+		outputKey, err := d.TaprootOutputKey(index)
+		require.NoError(t, err)
+		script, err := d.Script(index)
+		require.NoError(t, err)
+		expected, err := txscript.PayToTaprootScript(outputKey)
+		require.NoError(t, err)
+		require.Equal(t, expected, script)
+
+		for leaf := range d.Tree.Leaves() {
+			ctrlBlock, leafScript, err := d.ControlBlock(index, leaf)
+			require.NoError(t, err)
+			require.Len(t, ctrlBlock.InclusionProof,
+				32*[]int{1, 3, 3, 2}[leaf])
+			err = txscript.VerifyTaprootLeafCommitment(
+				ctrlBlock, schnorr.SerializePubKey(outputKey),
+				leafScript,
+			)
+			require.NoError(t, err)
+		}
+		_, _, err = d.ControlBlock(index, 4)
+		require.Error(t, err)
+	}
+
+	// Key path only outputs commit to no script.
+	d, err = Parse("tr(" + xpub + "/0/*)")
+	require.NoError(t, err)
+	_, _, err = d.ControlBlock(0, 0)
+	require.Error(t, err)
+	internalKey, err := d.Keys[0].PubKeyAt(0)
+	require.NoError(t, err)
+	outputKey, err := d.TaprootOutputKey(0)
+	require.NoError(t, err)
+	require.Equal(t, txscript.ComputeTaprootKeyNoScript(internalKey),
+		outputKey)
+}
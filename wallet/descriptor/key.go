@@ -0,0 +1,317 @@
+package descriptor
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+)
+
+// Wildcard is the kind of derivation step ending ranged key expressions.
+type Wildcard uint8
+
+const (
+	// WildcardNone is used for keys which are not ranged.
+	WildcardNone Wildcard = iota
+
+	// WildcardUnhardened is used for keys ending with /*, derived with
+	// unhardened derivation at each index.
+	WildcardUnhardened
+
+	// WildcardHardened is used for keys ending with /*h, derived with
+	// hardened derivation at each index.
+	WildcardHardened
+)
+
+// KeyOrigin is the origin of a key, as found in the key origin information of
+// a key expression.
+type KeyOrigin struct {
+	// Fingerprint is the fingerprint of the master key, interpreted as a
+	// little endian number like the fingerprints of PSBT derivations.
+	Fingerprint uint32
+
+	// Path is the derivation path of the key from the master key.
+	Path []uint32
+}
+
+// Key is a key expression of a descriptor.  Exactly one of PubKey, PrivKey and
+// ExtKey is set.
+type Key struct {
+	// Origin is the origin of the key, or nil if unknown.
+	Origin *KeyOrigin
+
+	// PubKey is a public key.
+	PubKey *btcec.PublicKey
+
+	// PrivKey is a private key.
+	PrivKey *btcutil.WIF
+
+	// ExtKey is an extended public or private key from which the key is
+	// derived along Path.
+	ExtKey *hdkeychain.ExtendedKey
+
+	// Path is the derivation path of the key from ExtKey, not including
+	// the wildcard step.
+	Path []uint32
+
+	// Wildcard is the derivation step ending the path of ranged keys.
+	Wildcard Wildcard
+
+	// XOnly is set for public keys serialized as x-only keys.
+	XOnly bool
+}
+
+// formatPath returns the path with each step prefixed with a slash.
+func formatPath(path []uint32) string {
+	var b strings.Builder
+	for _, step := range path {
+		b.WriteByte('/')
+		if step >= hdkeychain.HardenedKeyStart {
+			b.WriteString(strconv.FormatUint(
+				uint64(step-hdkeychain.HardenedKeyStart), 10,
+			))
+			b.WriteByte('h')
+		} else {
+			b.WriteString(strconv.FormatUint(uint64(step), 10))
+		}
+	}
+	return b.String()
+}
+
+// parsePath parses the steps of a path, each of which is a number followed by
+// an optional hardened marker.
+func parsePath(steps []string) ([]uint32, error) {
+	path := make([]uint32, 0, len(steps))
+	for _, step := range steps {
+		hardened := strings.HasSuffix(step, "h") ||
+			strings.HasSuffix(step, "'")
+		if hardened {
+			step = step[:len(step)-1]
+		}
+		index, err := strconv.ParseUint(step, 10, 31)
+		if err != nil || (len(step) > 1 && step[0] == '0') {
+			return nil, fmt.Errorf("invalid derivation step %q", step)
+		}
+		if hardened {
+			index += hdkeychain.HardenedKeyStart
+		}
+		path = append(path, uint32(index))
+	}
+	return path, nil
+}
+
+// String returns the key expression.
+func (k *Key) String() string {
+	var b strings.Builder
+	if k.Origin != nil {
+		var fingerprint [4]byte
+		binary.LittleEndian.PutUint32(fingerprint[:], k.Origin.Fingerprint)
+		b.WriteByte('[')
+		b.WriteString(hex.EncodeToString(fingerprint[:]))
+		b.WriteString(formatPath(k.Origin.Path))
+		b.WriteByte(']')
+	}
+
+	switch {
+	case k.PubKey != nil && k.XOnly:
+		b.WriteString(hex.EncodeToString(schnorr.SerializePubKey(k.PubKey)))
+	case k.PubKey != nil:
+		b.WriteString(hex.EncodeToString(k.PubKey.SerializeCompressed()))
+	case k.PrivKey != nil:
+		b.WriteString(k.PrivKey.String())
+	case k.ExtKey != nil:
+		b.WriteString(k.ExtKey.String())
+		b.WriteString(formatPath(k.Path))
+		switch k.Wildcard {
+		case WildcardUnhardened:
+			b.WriteString("/*")
+		case WildcardHardened:
+			b.WriteString("/*h")
+		}
+	}
+	return b.String()
+}
+
+// IsRange returns whether the key is derived at each index of a range.
+func (k *Key) IsRange() bool {
+	return k.Wildcard != WildcardNone
+}
+
+// IsPrivate returns whether the key expression includes the private key.
+func (k *Key) IsPrivate() bool {
+	return k.PrivKey != nil || (k.ExtKey != nil && k.ExtKey.IsPrivate())
+}
+
+// derive returns the extended key at the index of the range.
+func (k *Key) derive(index uint32) (*hdkeychain.ExtendedKey, error) {
+	key := k.ExtKey
+	path := k.Path
+	switch k.Wildcard {
+	case WildcardUnhardened:
+		path = append(path[:len(path):len(path)], index)
+	case WildcardHardened:
+		path = append(
+			path[:len(path):len(path)],
+			index+hdkeychain.HardenedKeyStart,
+		)
+	}
+	for _, step := range path {
+		var err error
+		key, err = key.Derive(step)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// PubKeyAt returns the public key at the index of the range.  The index is
+// ignored for keys which are not ranged.
+func (k *Key) PubKeyAt(index uint32) (*btcec.PublicKey, error) {
+	switch {
+	case k.PubKey != nil:
+		return k.PubKey, nil
+	case k.PrivKey != nil:
+		return k.PrivKey.PrivKey.PubKey(), nil
+	}
+	key, err := k.derive(index)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPubKey()
+}
+
+// PrivKeyAt returns the private key at the index of the range.  The index is
+// ignored for keys which are not ranged.  An error is returned if the key
+// expression does not include the private key.
+func (k *Key) PrivKeyAt(index uint32) (*btcec.PrivateKey, error) {
+	if !k.IsPrivate() {
+		return nil, errors.New("key expression has no private key")
+	}
+	if k.PrivKey != nil {
+		return k.PrivKey.PrivKey, nil
+	}
+	key, err := k.derive(index)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPrivKey()
+}
+
+// OriginAt returns the origin of the key at the index of the range, made up
+// of the origin of the key expression followed by its derivation path.  Nil
+// is returned if the origin of the key expression is unknown.
+func (k *Key) OriginAt(index uint32) *KeyOrigin {
+	if k.Origin == nil {
+		return nil
+	}
+	path := append([]uint32{}, k.Origin.Path...)
+	path = append(path, k.Path...)
+	switch k.Wildcard {
+	case WildcardUnhardened:
+		path = append(path, index)
+	case WildcardHardened:
+		path = append(path, index+hdkeychain.HardenedKeyStart)
+	}
+	return &KeyOrigin{Fingerprint: k.Origin.Fingerprint, Path: path}
+}
+
+// ParseKey parses a key expression.  X-only public keys are only accepted if
+// xOnly is set, in which case public keys are serialized as x-only keys.
+func ParseKey(s string, xOnly bool) (*Key, error) {
+	key := new(Key)
+
+	// Parse the key origin information.
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end == -1 {
+			return nil, errors.New("key origin not closed")
+		}
+		steps := strings.Split(s[1:end], "/")
+		fingerprint, err := hex.DecodeString(steps[0])
+		if err != nil || len(fingerprint) != 4 {
+			return nil, fmt.Errorf("invalid fingerprint %q", steps[0])
+		}
+		path, err := parsePath(steps[1:])
+		if err != nil {
+			return nil, err
+		}
+		key.Origin = &KeyOrigin{
+			Fingerprint: binary.LittleEndian.Uint32(fingerprint),
+			Path:        path,
+		}
+		s = s[end+1:]
+	}
+
+	// Hex encoded public keys are either compressed keys, or x-only keys
+	// where allowed.
+	if keyBytes, err := hex.DecodeString(s); err == nil {
+		switch {
+		case len(keyBytes) == btcec.PubKeyBytesLenCompressed:
+			key.PubKey, err = btcec.ParsePubKey(keyBytes)
+		case len(keyBytes) == schnorr.PubKeyBytesLen && xOnly:
+			key.PubKey, err = schnorr.ParsePubKey(keyBytes)
+		default:
+			err = fmt.Errorf("invalid public key length %d",
+				len(keyBytes))
+		}
+		if err != nil {
+			return nil, err
+		}
+		key.XOnly = xOnly
+		return key, nil
+	}
+
+	if wif, err := btcutil.DecodeWIF(s); err == nil {
+		if !wif.CompressPubKey {
+			return nil, errors.New("uncompressed keys are not " +
+				"supported")
+		}
+		key.PrivKey = wif
+		key.XOnly = xOnly
+		return key, nil
+	}
+
+	// Anything else must be an extended key, followed by its derivation
+	// path and wildcard.
+	steps := strings.Split(s, "/")
+	extKey, err := hdkeychain.NewKeyFromString(steps[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %q: %w", steps[0], err)
+	}
+	key.ExtKey = extKey
+	key.XOnly = xOnly
+	steps = steps[1:]
+	if n := len(steps); n > 0 {
+		switch steps[n-1] {
+		case "*":
+			key.Wildcard = WildcardUnhardened
+			steps = steps[:n-1]
+		case "*h", "*'":
+			key.Wildcard = WildcardHardened
+			steps = steps[:n-1]
+		}
+	}
+	key.Path, err = parsePath(steps)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hardened derivation requires the private key.
+	hardened := key.Wildcard == WildcardHardened
+	for _, step := range key.Path {
+		hardened = hardened || step >= hdkeychain.HardenedKeyStart
+	}
+	if hardened && !extKey.IsPrivate() {
+		return nil, errors.New("hardened derivation from an extended " +
+			"public key")
+	}
+	return key, nil
+}
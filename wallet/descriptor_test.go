@@ -0,0 +1,284 @@
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/descriptor"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportDescriptors tests that the descriptors of the accounts of a wallet
+// derive the addresses of the accounts, and that they are imported as
+// watch-only accounts deriving the same addresses.
+func TestExportDescriptors(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	descs, err := w.ExportDescriptors()
+	require.NoError(t, err)
+	require.Len(t, descs, len(waddrmgr.DefaultKeyScopes))
+
+	var fingerprint uint32
+	err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		fingerprint, err = w.Manager.MasterKeyFingerprint(ns)
+		return err
+	})
+	require.NoError(t, err)
+
+	watchWallet, cleanup := testWalletWatchingOnly(t)
+	defer cleanup()
+
+	for _, d := range descs {
+		require.Equal(t, uint32(0), d.AccountNumber)
+
+		// The descriptors derive the addresses of the account.
+		external, err := w.NewAddress(d.AccountNumber, d.KeyScope)
+		require.NoError(t, err)
+		addr, err := d.External.Address(0, w.chainParams)
+		require.NoError(t, err)
+		require.Equal(t, external.String(), addr.String())
+
+		internal, err := w.NewChangeAddress(d.AccountNumber, d.KeyScope)
+		require.NoError(t, err)
+		addr, err = d.Internal.Address(0, w.chainParams)
+		require.NoError(t, err)
+		require.Equal(t, internal.String(), addr.String())
+
+		// The key origin is the derivation of the account from the
+		// root key of the wallet.
+		origin := d.External.String()
+		if d.External.Sub != nil {
+			origin = d.External.Sub.String()
+		}
+		require.Contains(t, origin, fmt.Sprintf("[%s/%dh/%dh/0h]",
+			fingerprintHex(fingerprint), d.KeyScope.Purpose,
+			d.KeyScope.Coin))
+
+		// The descriptors are parsed back from their string form.
+		parsed, err := descriptor.Parse(d.External.StringWithChecksum())
+		require.NoError(t, err)
+		require.Equal(t, d.External.String(), parsed.String())
+
+		// Importing the descriptor of the account into the wallet
+		// returns the account.
+		props, err := w.ImportDescriptor(parsed, "dup", 0, nil)
+		require.NoError(t, err)
+		require.Equal(t, d.AccountNumber, props.AccountNumber)
+		require.Equal(t, d.KeyScope, props.KeyScope)
+
+		// Importing the descriptors into another wallet creates a
+		// watch-only account deriving the same addresses.
+		_, err = watchWallet.ImportAccountDescriptors(
+			"", d.External, d.External,
+		)
+		require.ErrorIs(t, err, ErrUnsupportedDescriptor)
+		props, err = watchWallet.ImportAccountDescriptors(
+			d.KeyScope.String(), d.External, d.Internal,
+		)
+		require.NoError(t, err)
+		require.True(t, props.IsWatchOnly)
+		require.Equal(t, fingerprint, props.MasterKeyFingerprint)
+		addr, err = watchWallet.NewAddress(
+			props.AccountNumber, d.KeyScope,
+		)
+		require.NoError(t, err)
+		require.Equal(t, external.String(), addr.String())
+		addr, err = watchWallet.NewChangeAddress(
+			props.AccountNumber, d.KeyScope,
+		)
+		require.NoError(t, err)
+		require.Equal(t, internal.String(), addr.String())
+	}
+}
+
+// fingerprintHex returns the fingerprint as found in key expressions.
+func fingerprintHex(fingerprint uint32) string {
+	return fmt.Sprintf("%02x%02x%02x%02x", byte(fingerprint),
+		byte(fingerprint>>8), byte(fingerprint>>16), byte(fingerprint>>24))
+}
+
+// TestImportDescriptor tests that the addresses of descriptors other than
+// account descriptors are imported at each index of their range.
+func TestImportDescriptor(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	master, err := hdkeychain.NewMaster(
+		bytes.Repeat([]byte{1}, hdkeychain.RecommendedSeedLen),
+		w.chainParams,
+	)
+	require.NoError(t, err)
+	masterPub, err := master.Neuter()
+	require.NoError(t, err)
+
+	// requireAddresses asserts the addresses of the descriptor are known
+	// to the wallet, and whether they can be signed for.
+	requireAddresses := func(desc *descriptor.Descriptor, n uint32,
+		private bool) {
+
+		err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+			ns := tx.ReadBucket(waddrmgrNamespaceKey)
+			for i := uint32(0); i < n; i++ {
+				addr, err := desc.Address(i, w.chainParams)
+				require.NoError(t, err)
+				managedAddr, err := w.Manager.Address(ns, addr)
+				require.NoError(t, err, addr)
+				if !private {
+					continue
+				}
+				pubKeyAddr, ok :=
+					managedAddr.(waddrmgr.ManagedPubKeyAddress)
+				require.True(t, ok)
+				_, err = pubKeyAddr.PrivKey()
+				require.NoError(t, err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name    string
+		desc    string
+		n       uint32
+		private bool
+	}{{
+		name:    "wpkh private key",
+		desc:    fmt.Sprintf("wpkh(%s/84h/1h/0h/0/0)", master),
+		n:       1,
+		private: true,
+	}, {
+		name: "tr public keys",
+		desc: fmt.Sprintf("tr(%s/1/*)", masterPub),
+		n:    2,
+	}, {
+		name: "single pkh key",
+		desc: fmt.Sprintf("pkh(%s/2)", masterPub),
+		n:    1,
+	}, {
+		name: "sh multi",
+		desc: fmt.Sprintf("sh(multi(1,%s/3/*,%s/4/*))", masterPub,
+			masterPub),
+		n: 2,
+	}, {
+		name: "wsh sortedmulti",
+		desc: fmt.Sprintf("wsh(sortedmulti(2,%s/5/*,%s/6/*))",
+			masterPub, masterPub),
+		n: 3,
+	}, {
+		name: "sh wsh multi",
+		desc: fmt.Sprintf("sh(wsh(multi(1,%s/12/*,%s/13/*)))",
+			masterPub, masterPub),
+		n: 2,
+	}, {
+		name: "tr script tree",
+		desc: fmt.Sprintf("tr(%s/7/*,{pk(%s/8/*),multi_a(1,%s/9/*,"+
+			"%s/10/*)})", masterPub, masterPub, masterPub,
+			masterPub),
+		n: 2,
+	}, {
+		name: "tr deeper script tree",
+		desc: fmt.Sprintf("tr(%s/14/*,{{pk(%s/15/*),pk(%s/16/*)},"+
+			"pk(%s/17/*)})", masterPub, masterPub, masterPub,
+			masterPub),
+		n: 2,
+	}}
+	for _, test := range tests {
+		desc, err := descriptor.Parse(test.desc)
+		require.NoError(t, err, test.name)
+		props, err := w.ImportDescriptor(desc, "", test.n-1, nil)
+		require.NoError(t, err, test.name)
+		require.Equal(
+			t, uint32(waddrmgr.ImportedAddrAccount),
+			props.AccountNumber,
+		)
+		requireAddresses(desc, test.n, test.private)
+
+		// Importing the descriptor again does not fail.
+		_, err = w.ImportDescriptor(desc, "", test.n-1, nil)
+		require.NoError(t, err, test.name)
+	}
+
+	// Every leaf of imported taproot script trees can be spent.
+	desc, err := descriptor.Parse(tests[len(tests)-1].desc)
+	require.NoError(t, err)
+	addr, err := desc.Address(1, w.chainParams)
+	require.NoError(t, err)
+	err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		managedAddr, err := w.Manager.Address(ns, addr)
+		if err != nil {
+			return err
+		}
+		tapscriptAddr, ok :=
+			managedAddr.(waddrmgr.ManagedTaprootScriptAddress)
+		require.True(t, ok)
+		tapscript, err := tapscriptAddr.TaprootScript()
+		require.NoError(t, err)
+		for leaf, leafDesc := range desc.Tree.Leaves() {
+			script, err := leafDesc.Script(1)
+			require.NoError(t, err)
+			want, _, err := desc.ControlBlock(1, leaf)
+			require.NoError(t, err)
+			ctrlBlock, err := tapscript.LeafControlBlock(
+				txscript.NewTapLeaf(txscript.BaseLeafVersion, script),
+			)
+			require.NoError(t, err)
+			wantBytes, err := want.ToBytes()
+			require.NoError(t, err)
+			ctrlBlockBytes, err := ctrlBlock.ToBytes()
+			require.NoError(t, err)
+			require.Equal(t, wantBytes, ctrlBlockBytes)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Script trees the wallet can't rebuild from their leaves, ranged
+	// extended private keys and ranges past the maximum are rejected.
+	for _, d := range []string{
+		fmt.Sprintf("tr(%s/18/*,{pk(%s/19/*),{pk(%s/20/*),pk(%s/21/*)}})",
+			masterPub, masterPub, masterPub, masterPub),
+		fmt.Sprintf("wpkh(%s/84h/1h/0h/0/*)", master),
+	} {
+		desc, err := descriptor.Parse(d)
+		require.NoError(t, err)
+		_, err = w.ImportDescriptor(desc, "", 1, nil)
+		require.ErrorIs(t, err, ErrUnsupportedDescriptor, d)
+	}
+	desc, err = descriptor.Parse(fmt.Sprintf("wpkh(%s/22/*)", masterPub))
+	require.NoError(t, err)
+	_, err = w.ImportDescriptor(desc, "", MaxDescriptorRangeEnd+1, nil)
+	require.ErrorContains(t, err, "exceeds the maximum")
+
+	// Bare scripts are not supported.
+	desc, err = descriptor.Parse(fmt.Sprintf("pk(%s)", masterPub))
+	require.NoError(t, err)
+	_, err = w.ImportDescriptor(desc, "", 0, nil)
+	require.ErrorIs(t, err, ErrUnsupportedDescriptor)
+
+	// Nothing but addresses of the descriptor range are imported.
+	desc, err = descriptor.Parse(fmt.Sprintf("wpkh(%s/11/*)", masterPub))
+	require.NoError(t, err)
+	_, err = w.ImportDescriptor(desc, "", 1, nil)
+	require.NoError(t, err)
+	addr, err = desc.Address(2, w.chainParams)
+	require.NoError(t, err)
+	err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		_, err := w.Manager.Address(ns, addr)
+		return err
+	})
+	require.True(t, waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound))
+}
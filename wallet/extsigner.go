@@ -0,0 +1,102 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+)
+
+// ErrExternalSignerTxMismatch is returned when the packet returned by an
+// external signer holds a different transaction than the one it was to sign.
+var ErrExternalSignerTxMismatch = errors.New("external signer returned a " +
+	"different transaction than the one to sign")
+
+// ExternalSigner signs transactions with keys that are not stored in the
+// wallet, such as the keys of a hardware wallet backing a watch-only account.
+type ExternalSigner interface {
+	// SignPsbt adds the signatures of the keys of the signer to the inputs
+	// of the packet it can sign, as identified by the BIP-0032 derivations
+	// of the inputs, and returns the signed packet.  The unsigned
+	// transaction of the packet must not be modified.
+	SignPsbt(packet *psbt.Packet) (*psbt.Packet, error)
+}
+
+// SetExternalSigner sets the signer of the inputs of watch-only accounts.
+// Transactions created from watch-only accounts are signed with it before
+// they are returned, and FinalizePsbt hands it the inputs of watch-only
+// accounts.  A nil signer removes the signer of the wallet.
+func (w *Wallet) SetExternalSigner(signer ExternalSigner) {
+	w.externalSignerMtx.Lock()
+	w.externalSigner = signer
+	w.externalSignerMtx.Unlock()
+}
+
+// currentExternalSigner returns the external signer of the wallet, or nil if
+// it has none.
+func (w *Wallet) currentExternalSigner() ExternalSigner {
+	w.externalSignerMtx.Lock()
+	defer w.externalSignerMtx.Unlock()
+	return w.externalSigner
+}
+
+// signPsbtExternally has the external signer sign the packet, which is
+// replaced by the signed packet.
+func signPsbtExternally(signer ExternalSigner, packet *psbt.Packet) error {
+	signed, err := signer.SignPsbt(packet)
+	if err != nil {
+		return fmt.Errorf("external signer failed: %w", err)
+	}
+	if signed == nil || signed.UnsignedTx == nil ||
+		signed.UnsignedTx.TxHash() != packet.UnsignedTx.TxHash() ||
+		len(signed.Inputs) != len(packet.Inputs) ||
+		len(signed.Outputs) != len(packet.Outputs) {
+
+		return ErrExternalSignerTxMismatch
+	}
+
+	*packet = *signed
+	return nil
+}
+
+// signTxExternally has the external signer sign all the inputs of the
+// transaction.  The inputs are described to the signer by their derivations,
+// as is the change output, so the signer can tell it apart from the payments.
+func (w *Wallet) signTxExternally(signer ExternalSigner,
+	tx *txauthor.AuthoredTx) error {
+
+	packet, err := psbt.NewFromUnsignedTx(tx.Tx.Copy())
+	if err != nil {
+		return err
+	}
+	if err := w.DecorateInputs(packet, true); err != nil {
+		return err
+	}
+	if tx.ChangeIndex >= 0 {
+		changeOutputInfo, err := w.changeOutputInfo(
+			tx.Tx.TxOut[tx.ChangeIndex],
+		)
+		if err != nil {
+			return err
+		}
+		packet.Outputs[tx.ChangeIndex] = *changeOutputInfo
+	}
+
+	if err := signPsbtExternally(signer, packet); err != nil {
+		return err
+	}
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return fmt.Errorf("error finalizing PSBT: %w", err)
+	}
+	signedTx, err := psbt.Extract(packet)
+	if err != nil {
+		return err
+	}
+
+	for i, txIn := range signedTx.TxIn {
+		tx.Tx.TxIn[i].SignatureScript = txIn.SignatureScript
+		tx.Tx.TxIn[i].Witness = txIn.Witness
+	}
+	return validateMsgTx(tx.Tx, tx.PrevScripts, tx.PrevInputValues)
+}
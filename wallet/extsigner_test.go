@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// testExternalSigner is an external signer holding the master key of a
+// watch-only account.
+type testExternalSigner struct {
+	root        *hdkeychain.ExtendedKey
+	fingerprint uint32
+	calls       int
+}
+
+// SignPsbt signs the P2WPKH inputs of the packet derived from the master key
+// of the signer.  The packet is encoded and decoded, as it would be when handed
+// to another process.
+func (s *testExternalSigner) SignPsbt(packet *psbt.Packet) (*psbt.Packet,
+	error) {
+
+	s.calls++
+
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+	packet, err = psbt.NewFromRawBytes(strings.NewReader(b64), true)
+	if err != nil {
+		return nil, err
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	sigHashes := txscript.NewTxSigHashes(
+		packet.UnsignedTx, PsbtPrevOutputFetcher(packet),
+	)
+	for idx, in := range packet.Inputs {
+		for _, derivation := range in.Bip32Derivation {
+			if derivation.MasterKeyFingerprint != s.fingerprint {
+				continue
+			}
+			key := s.root
+			for _, index := range derivation.Bip32Path {
+				key, err = key.Derive(index)
+				if err != nil {
+					return nil, err
+				}
+			}
+			privKey, err := key.ECPrivKey()
+			if err != nil {
+				return nil, err
+			}
+			sig, err := txscript.RawTxInWitnessSignature(
+				packet.UnsignedTx, sigHashes, idx,
+				in.WitnessUtxo.Value, in.WitnessUtxo.PkScript,
+				txscript.SigHashAll, privKey,
+			)
+			if err != nil {
+				return nil, err
+			}
+			_, err = updater.Sign(
+				idx, sig, derivation.PubKey, nil, nil,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return packet, nil
+}
+
+// TestExternalSigner tests that transactions spending from a watch-only
+// account are signed by the external signer of the wallet.
+func TestExternalSigner(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	seed, err := hdkeychain.GenerateSeed(hdkeychain.MinSeedBytes)
+	require.NoError(t, err)
+	root, err := hdkeychain.NewMaster(seed, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	rootPubKey, err := root.ECPubKey()
+	require.NoError(t, err)
+	hash := btcutil.Hash160(rootPubKey.SerializeCompressed())
+	signer := &testExternalSigner{
+		root:        root,
+		fingerprint: binary.LittleEndian.Uint32(hash[:4]),
+	}
+
+	scope := waddrmgr.KeyScopeBIP0084
+	addrType := waddrmgr.WitnessPubKey
+	acctPubKey := deriveAcctPubKey(t, root, scope, hardenedKey(0))
+	props, err := w.ImportAccount(
+		"hardware", acctPubKey, signer.fingerprint, &addrType,
+	)
+	require.NoError(t, err)
+
+	addr, err := w.NewAddress(props.AccountNumber, scope)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	utxo := wire.NewTxOut(1000000, pkScript)
+	addUtxo(t, w, &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{utxo},
+	})
+	prevScripts := [][]byte{pkScript}
+	prevValues := []btcutil.Amount{btcutil.Amount(utxo.Value)}
+	outputs := []*wire.TxOut{{
+		PkScript: testScriptP2WKH,
+		Value:    50000,
+	}}
+
+	// Without an external signer, the transaction is left unsigned.
+	tx, err := w.CreateSimpleTx(
+		&scope, props.AccountNumber, outputs, 1, 5000,
+		CoinSelectionLargest, false,
+	)
+	require.NoError(t, err)
+	require.Empty(t, tx.Tx.TxIn[0].Witness)
+
+	w.SetExternalSigner(signer)
+	tx, err = w.CreateSimpleTx(
+		&scope, props.AccountNumber, outputs, 1, 5000,
+		CoinSelectionLargest, false,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, signer.calls)
+	require.NoError(t, validateMsgTx(tx.Tx, prevScripts, prevValues))
+
+	// Funding a PSBT doesn't sign it, but finalizing it does.
+	packet := &psbt.Packet{
+		UnsignedTx: &wire.MsgTx{TxOut: outputs},
+		Outputs:    []psbt.POutput{{}},
+	}
+	_, err = w.FundPsbt(
+		packet, &scope, 1, props.AccountNumber, 5000,
+		CoinSelectionLargest,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, signer.calls)
+
+	err = w.FinalizePsbt(&scope, props.AccountNumber, packet)
+	require.NoError(t, err)
+	require.Equal(t, 2, signer.calls)
+	finalTx, err := psbt.Extract(packet)
+	require.NoError(t, err)
+	require.NoError(t, validateMsgTx(finalTx, prevScripts, prevValues))
+}
+
+// TestExternalSignerWatchingOnly tests that a watch-only wallet with an
+// external signer publishes the transactions it sends, instead of returning
+// them unsigned.
+func TestExternalSignerWatchingOnly(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWalletWatchingOnly(t)
+	defer cleanup()
+
+	seed, err := hdkeychain.GenerateSeed(hdkeychain.MinSeedBytes)
+	require.NoError(t, err)
+	root, err := hdkeychain.NewMaster(seed, &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	rootPubKey, err := root.ECPubKey()
+	require.NoError(t, err)
+	hash := btcutil.Hash160(rootPubKey.SerializeCompressed())
+	signer := &testExternalSigner{
+		root:        root,
+		fingerprint: binary.LittleEndian.Uint32(hash[:4]),
+	}
+
+	scope := waddrmgr.KeyScopeBIP0084
+	addrType := waddrmgr.WitnessPubKey
+	acctPubKey := deriveAcctPubKey(t, root, scope, hardenedKey(0))
+	props, err := w.ImportAccount(
+		"hardware", acctPubKey, signer.fingerprint, &addrType,
+	)
+	require.NoError(t, err)
+
+	addr, err := w.NewAddress(props.AccountNumber, scope)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	utxo := wire.NewTxOut(1000000, pkScript)
+	addUtxo(t, w, &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{utxo},
+	})
+	outputs := []*wire.TxOut{{
+		PkScript: testScriptP2WKH,
+		Value:    50000,
+	}}
+
+	// Without an external signer, the transaction is returned unsigned.
+	tx, err := w.SendOutputs(
+		outputs, &scope, props.AccountNumber, 1, 5000,
+		CoinSelectionLargest, "",
+	)
+	require.ErrorIs(t, err, ErrTxUnsigned)
+	require.Empty(t, tx.TxIn[0].Witness)
+
+	w.SetExternalSigner(signer)
+	tx, err = w.SendOutputs(
+		outputs, &scope, props.AccountNumber, 1, 5000,
+		CoinSelectionLargest, "",
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, signer.calls)
+	err = validateMsgTx(
+		tx, [][]byte{pkScript},
+		[]btcutil.Amount{btcutil.Amount(utxo.Value)},
+	)
+	require.NoError(t, err)
+}
@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/bisoncraft/utxowallet/chain"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// DefaultFeeConfTarget is the number of blocks within which transactions
+// created without a fee rate are targeted to be mined.
+const DefaultFeeConfTarget = 6
+
+// ErrNoFeeEstimator is returned when a fee rate is to be estimated, but the
+// chain backend of the wallet is not able to estimate fee rates.
+var ErrNoFeeEstimator = errors.New("chain backend does not estimate fees")
+
+// EstimateFee returns the fee rate, in satoshis per kilobyte, a transaction
+// should pay to be mined within confTarget blocks, as estimated by the chain
+// backend. The estimate is never lower than the default relay fee rate.
+func (w *Wallet) EstimateFee(confTarget uint32) (btcutil.Amount, error) {
+	chainClient, err := w.requireChainClient()
+	if err != nil {
+		return 0, err
+	}
+	estimator, ok := chainClient.(chain.FeeEstimator)
+	if !ok {
+		return 0, ErrNoFeeEstimator
+	}
+
+	feeRate, err := estimator.EstimateFee(confTarget)
+	if err != nil {
+		return 0, err
+	}
+	if feeRate < txrules.DefaultRelayFeePerKb {
+		feeRate = txrules.DefaultRelayFeePerKb
+	}
+	return feeRate, nil
+}
+
+// feeRateOrEstimate returns the fee rate provided, or the estimated fee rate
+// for DefaultFeeConfTarget if it is zero.
+func (w *Wallet) feeRateOrEstimate(feeSatPerKb btcutil.Amount) (btcutil.Amount,
+	error) {
+
+	if feeSatPerKb != 0 {
+		return feeSatPerKb, nil
+	}
+	return w.EstimateFee(DefaultFeeConfTarget)
+}
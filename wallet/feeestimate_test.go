@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// mockFeeEstimatorClient is a mock chain client estimating a constant fee
+// rate.
+type mockFeeEstimatorClient struct {
+	mockChainClient
+	feeRate btcutil.Amount
+}
+
+func (m *mockFeeEstimatorClient) EstimateFee(uint32) (btcutil.Amount, error) {
+	return m.feeRate, nil
+}
+
+// TestEstimateFee tests that transactions created without a fee rate pay the
+// fee rate estimated by the chain backend.
+func TestEstimateFee(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	// The mock chain client does not estimate fees.
+	_, err := w.EstimateFee(DefaultFeeConfTarget)
+	require.ErrorIs(t, err, ErrNoFeeEstimator)
+
+	// Estimates are never lower than the relay fee rate.
+	chainClient := &mockFeeEstimatorClient{feeRate: 100}
+	w.chainClient = chainClient
+	feeRate, err := w.EstimateFee(DefaultFeeConfTarget)
+	require.NoError(t, err)
+	require.Equal(t, txrules.DefaultRelayFeePerKb, feeRate)
+
+	addr, err := w.CurrentAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	addUtxo(t, w, &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{wire.NewTxOut(100000, pkScript)},
+	})
+
+	// A transaction created without a fee rate pays the same fee as one
+	// created with the estimated fee rate.
+	chainClient.feeRate = 20000
+	outputs := []*wire.TxOut{wire.NewTxOut(50000, pkScript)}
+	estimated, err := w.CreateSimpleTx(
+		nil, 0, outputs, 1, 0, CoinSelectionLargest, true,
+	)
+	require.NoError(t, err)
+	explicit, err := w.CreateSimpleTx(
+		nil, 0, outputs, 1, chainClient.feeRate, CoinSelectionLargest,
+		true,
+	)
+	require.NoError(t, err)
+	require.Equal(t, txFeeOf(explicit), txFeeOf(estimated))
+	require.Greater(t, txFeeOf(estimated), txrules.FeeForSerializeSize(
+		txrules.DefaultRelayFeePerKb, estimated.Tx.SerializeSize(),
+		w.netParams,
+	))
+}
+
+// txFeeOf returns the fee paid by an authored transaction.
+func txFeeOf(tx *txauthor.AuthoredTx) btcutil.Amount {
+	fee := tx.TotalInput
+	for _, txOut := range tx.Tx.TxOut {
+		fee -= btcutil.Amount(txOut.Value)
+	}
+	return fee
+}
@@ -0,0 +1,221 @@
+// Package hwi provides an external signer of the wallet signing with hardware
+// wallets through HWI, the Hardware Wallet Interface command line tool.
+//
+// HWI is run as a subprocess for each request, selecting the device by the
+// fingerprint of its master key.  Transactions are handed to it as PSBTs
+// holding the BIP-0032 derivations of the keys of the device, which it signs
+// after the user confirmed them on the device.
+package hwi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// DefaultCommand is the command running HWI when none is specified.
+const DefaultCommand = "hwi"
+
+var (
+	// ErrUnsupportedChain is returned when creating a signer for a chain
+	// HWI does not support.
+	ErrUnsupportedChain = errors.New("chain not supported by HWI")
+
+	// ErrNotSigned is returned when the device did not sign any input of
+	// a transaction.
+	ErrNotSigned = errors.New("transaction not signed by the device")
+)
+
+// Error is an error reported by HWI.
+type Error struct {
+	// Code is the code of the error.
+	Code int
+
+	// Message describes the error.
+	Message string
+}
+
+// Error returns the message of the error along with its code.
+func (e *Error) Error() string {
+	return fmt.Sprintf("hwi: %s (code %d)", e.Message, e.Code)
+}
+
+// Device is a hardware wallet found by HWI.
+type Device struct {
+	// Type is the type of the device, such as "trezor" or "ledger".
+	Type string `json:"type"`
+
+	// Model is the model of the device.
+	Model string `json:"model"`
+
+	// Path is the path of the device on the system.
+	Path string `json:"path"`
+
+	// Fingerprint is the hex encoded fingerprint of the master key of the
+	// device, as found in key expressions of descriptors.
+	Fingerprint string `json:"fingerprint"`
+
+	// NeedsPinSent is whether the device is to be unlocked with its PIN
+	// before use.
+	NeedsPinSent bool `json:"needs_pin_sent"`
+
+	// NeedsPassphraseSent is whether a passphrase is to be sent to the
+	// device before use.
+	NeedsPassphraseSent bool `json:"needs_passphrase_sent"`
+
+	// Error is an error reported by HWI for the device, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Enumerate returns the hardware wallets connected to the system, as found by
+// HWI run by the command provided.
+func Enumerate(command string) ([]Device, error) {
+	out, err := run(command, "enumerate")
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	if err := json.Unmarshal(out, &devices); err != nil {
+		return nil, fmt.Errorf("hwi: unable to decode devices: %w", err)
+	}
+	return devices, nil
+}
+
+// Signer signs PSBTs with the keys of a hardware wallet through HWI.  It
+// implements the ExternalSigner interface of the wallet package.
+type Signer struct {
+	command     string
+	fingerprint uint32
+	chain       string
+}
+
+// NewSigner returns a signer with the hardware wallet having the master key of
+// the fingerprint provided, as reported by the DerivationInfo of the keys of
+// the wallet.  HWI is run by the command provided, or by DefaultCommand if it
+// is empty.
+func NewSigner(command string, fingerprint uint32,
+	params *chaincfg.Params) (*Signer, error) {
+
+	var chain string
+	switch params.Net {
+	case wire.MainNet:
+		chain = "main"
+	case wire.TestNet3:
+		chain = "test"
+	case wire.TestNet:
+		chain = "regtest"
+	case chaincfg.SigNetParams.Net:
+		chain = "signet"
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedChain,
+			params.Name)
+	}
+
+	if command == "" {
+		command = DefaultCommand
+	}
+	return &Signer{
+		command:     command,
+		fingerprint: fingerprint,
+		chain:       chain,
+	}, nil
+}
+
+// Fingerprint returns the hex encoded fingerprint of the master key of the
+// hardware wallet, as found in key expressions of descriptors.
+func (s *Signer) Fingerprint() string {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], s.fingerprint)
+	return hex.EncodeToString(b[:])
+}
+
+// SignPsbt has the hardware wallet sign the inputs of the packet it holds the
+// keys of, and returns the signed packet.
+func (s *Signer) SignPsbt(packet *psbt.Packet) (*psbt.Packet, error) {
+	b64, err := packet.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.run("signtx", b64)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Psbt   string `json:"psbt"`
+		Signed bool   `json:"signed"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("hwi: unable to decode signed "+
+			"transaction: %w", err)
+	}
+	if !result.Signed {
+		return nil, ErrNotSigned
+	}
+
+	return psbt.NewFromRawBytes(strings.NewReader(result.Psbt), true)
+}
+
+// AccountPubKey returns the extended public key of the hardware wallet at the
+// derivation path provided, such as "m/84h/0h/0h", for importing the account
+// of the key into the wallet.
+func (s *Signer) AccountPubKey(path string) (*hdkeychain.ExtendedKey, error) {
+	out, err := s.run("getxpub", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Xpub string `json:"xpub"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("hwi: unable to decode extended public "+
+			"key: %w", err)
+	}
+	return hdkeychain.NewKeyFromString(result.Xpub)
+}
+
+// run runs an HWI command with the hardware wallet of the signer.
+func (s *Signer) run(args ...string) ([]byte, error) {
+	args = append([]string{
+		"--fingerprint", s.Fingerprint(), "--chain", s.chain,
+	}, args...)
+	return run(s.command, args...)
+}
+
+// run runs HWI with the arguments provided and returns its output.  Errors are
+// reported by HWI in its output as a JSON object with an error message and
+// code, which is returned as an *Error.
+func run(command string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	out := stdout.Bytes()
+	var result struct {
+		Error *string `json:"error"`
+		Code  int     `json:"code"`
+	}
+	if json.Unmarshal(out, &result) == nil && result.Error != nil {
+		return nil, &Error{Code: result.Code, Message: *result.Error}
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("hwi: %w: %s", runErr,
+			strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package hwi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHWI writes a script standing in for HWI, which records its arguments and
+// prints the output provided.  The path of the script and of the file of its
+// arguments are returned.
+func fakeHWI(t *testing.T, output string) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake HWI is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	command := filepath.Join(dir, "hwi")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat <<'EOF'\n" +
+		output + "\nEOF\n"
+	require.NoError(t, os.WriteFile(command, []byte(script), 0700))
+	return command, argsFile
+}
+
+func TestSignPsbt(t *testing.T) {
+	t.Parallel()
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil,
+		nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	require.NoError(t, err)
+	b64, err := packet.B64Encode()
+	require.NoError(t, err)
+
+	command, argsFile := fakeHWI(
+		t, `{"psbt": "`+b64+`", "signed": true}`,
+	)
+	signer, err := NewSigner(
+		command, 0x04030201, &chaincfg.TestNet3Params,
+	)
+	require.NoError(t, err)
+	require.Equal(t, "01020304", signer.Fingerprint())
+
+	signed, err := signer.SignPsbt(packet)
+	require.NoError(t, err)
+	require.Equal(t, tx.TxHash(), signed.UnsignedTx.TxHash())
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	require.Equal(t, "--fingerprint 01020304 --chain test signtx "+b64,
+		strings.TrimSpace(string(args)))
+
+	// Errors reported by HWI are returned.
+	command, _ = fakeHWI(
+		t, `{"error": "Could not find device", "code": -3}`,
+	)
+	signer, err = NewSigner(command, 1, &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	_, err = signer.SignPsbt(packet)
+	var hwiErr *Error
+	require.True(t, errors.As(err, &hwiErr))
+	require.Equal(t, -3, hwiErr.Code)
+
+	command, _ = fakeHWI(t, `{"psbt": "`+b64+`", "signed": false}`)
+	signer, err = NewSigner(command, 1, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	_, err = signer.SignPsbt(packet)
+	require.ErrorIs(t, err, ErrNotSigned)
+
+	_, err = NewSigner(command, 1, &chaincfg.SimNetParams)
+	require.ErrorIs(t, err, ErrUnsupportedChain)
+}
+
+func TestEnumerate(t *testing.T) {
+	t.Parallel()
+
+	command, _ := fakeHWI(t, `[{"type": "trezor", "model": "trezor_t", `+
+		`"path": "webusb:001:1", "fingerprint": "01020304", `+
+		`"needs_pin_sent": false, "needs_passphrase_sent": true}]`)
+	devices, err := Enumerate(command)
+	require.NoError(t, err)
+	require.Equal(t, []Device{{
+		Type:                "trezor",
+		Model:               "trezor_t",
+		Path:                "webusb:001:1",
+		Fingerprint:         "01020304",
+		NeedsPassphraseSent: true,
+	}}, devices)
+}
@@ -277,8 +277,19 @@ func (w *Wallet) importAccountScope(ns walletdb.ReadWriteBucket, name string,
 
 	scopedMgr, err := w.Manager.FetchScopedKeyManager(keyScope)
 	if err != nil {
+		// Watching-only wallets start without any scoped managers, so
+		// fall back to the scope's default schema when the caller
+		// didn't require a custom one.
+		scopeSchema, ok := waddrmgr.ScopeAddrMap[keyScope]
+		if addrSchema != nil {
+			scopeSchema, ok = *addrSchema, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("no address schema for key "+
+				"scope %v", keyScope)
+		}
 		scopedMgr, err = w.Manager.NewScopedKeyManager(
-			ns, keyScope, *addrSchema,
+			ns, keyScope, scopeSchema,
 		)
 		if err != nil {
 			return nil, err
@@ -424,7 +435,7 @@ func (w *Wallet) ImportPublicKey(pubKey *btcec.PublicKey,
 		return err
 	}
 
-	log.Infof("Imported address %v", addr.Address())
+	w.log.Infof("Imported address %v", addr.Address())
 
 	err = w.chainClient.NotifyReceived([]btcutil.Address{addr.Address()})
 	if err != nil {
@@ -477,7 +488,7 @@ func (w *Wallet) ImportTaprootScript(scope waddrmgr.KeyScope,
 		return nil, err
 	}
 
-	log.Infof("Imported address %v", addr.Address())
+	w.log.Infof("Imported address %v", addr.Address())
 
 	err = w.chainClient.NotifyReceived([]btcutil.Address{addr.Address()})
 	if err != nil {
@@ -584,7 +595,7 @@ func (w *Wallet) ImportPrivateKey(scope waddrmgr.KeyScope, wif *btcutil.WIF,
 	}
 
 	addrStr := addr.EncodeAddress()
-	log.Infof("Imported payment address %s", addrStr)
+	w.log.Infof("Imported payment address %s", addrStr)
 
 	w.NtfnServer.notifyAccountProperties(props)
 
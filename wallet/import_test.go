@@ -4,7 +4,9 @@ import (
 	"encoding/binary"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bisoncraft/utxowallet/assets"
 	"github.com/bisoncraft/utxowallet/waddrmgr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
@@ -291,3 +293,39 @@ func testImportAccount(t *testing.T, w *Wallet, tc *testCase, watchOnly bool,
 	require.NoError(t, err)
 	require.Equal(t, true, addrManaged.Imported())
 }
+
+// TestImportAccountWithoutScopes tests that an account can be imported into a
+// watching-only wallet that doesn't have a scoped key manager for the account's
+// key scope yet.
+func TestImportAccountWithoutScopes(t *testing.T) {
+	t.Parallel()
+
+	loader := NewLoader(
+		assets.BTCParams["testnet"], t.TempDir(), true,
+		defaultDBTimeout, 250,
+	)
+	w, err := loader.CreateNewWatchingOnlyWallet(
+		[]byte("hello"), time.Now(),
+	)
+	require.NoError(t, err)
+	defer w.Manager.Close()
+	w.chainClient = &mockChainClient{}
+
+	tc := testCases[1]
+	root, err := hdkeychain.NewKeyFromString(tc.masterPriv)
+	require.NoError(t, err)
+	acctPub := deriveAcctPubKey(
+		t, root, tc.expectedScope, hardenedKey(tc.accountIndex),
+	)
+
+	acct, err := w.ImportAccount(
+		"default", acctPub, root.ParentFingerprint(), &tc.addrType,
+	)
+	require.NoError(t, err)
+	require.Equal(t, tc.expectedScope, acct.KeyScope)
+	require.Equal(t, uint32(0), acct.AccountNumber)
+
+	extAddr, err := w.NewAddress(acct.AccountNumber, tc.expectedScope)
+	require.NoError(t, err)
+	require.Equal(t, tc.expectedAddr, extAddr.String())
+}
@@ -5,24 +5,35 @@
 package wallet
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/bisoncraft/utxowallet/internal/prompt"
+	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/bip39"
 	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/encrypted"
+	"github.com/bisoncraft/utxowallet/walletdb/migration"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
-	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btclog"
 )
 
 const (
 	// WalletDBName specified the database filename for the wallet.
 	WalletDBName = "wallet.db"
 
+	// DefaultDBDriver is the walletdb driver the wallet database is opened
+	// with by default.
+	DefaultDBDriver = "bdb"
+
 	// DefaultDBTimeout is the default timeout value when opening the wallet
 	// database.
 	DefaultDBTimeout = 60 * time.Second
@@ -40,17 +51,26 @@ var (
 	// ErrExists describes the error condition of attempting to create a new
 	// wallet when one exists already.
 	ErrExists = errors.New("wallet already exists")
+
+	// ErrDBDriverMismatch describes the error condition of opening a
+	// database with a different driver than the one it was created with.
+	ErrDBDriverMismatch = errors.New("database driver mismatch")
 )
 
 // loaderConfig contains the configuration options for the loader.
 type loaderConfig struct {
 	walletSyncRetryInterval time.Duration
+	dbDriver                string
+	encryptDB               bool
+	kdfUpgrade              *waddrmgr.ScryptOptions
+	logger                  btclog.Logger
 }
 
 // defaultLoaderConfig returns the default configuration options for the loader.
 func defaultLoaderConfig() *loaderConfig {
 	return &loaderConfig{
 		walletSyncRetryInterval: defaultSyncRetryInterval,
+		dbDriver:                DefaultDBDriver,
 	}
 }
 
@@ -65,6 +85,56 @@ func WithWalletSyncRetryInterval(interval time.Duration) LoaderOption {
 	}
 }
 
+// WithDBDriver specifies the walletdb driver the local wallet database is
+// opened with, "bdb" by default.  The driver must be registered, and must take
+// the database path, the no-freelist-sync option and the timeout as its
+// arguments, as the bdb and sqlite drivers do.
+func WithDBDriver(driver string) LoaderOption {
+	return func(c *loaderConfig) {
+		c.dbDriver = driver
+	}
+}
+
+// WithEncryptedDB specifies that the keys and values of the local wallet
+// database are encrypted with a key derived from the public passphrase, so the
+// transaction history and addresses of the wallet can't be read from the
+// database file without it.  The key is derived with the parameters of the KDF
+// upgrade of the loader, if any.  Existing wallet databases must be converted
+// to the encrypted form before they are opened with this option.
+//
+// As the encrypted keys aren't stored in order, the first cursor a database
+// transaction opens on a bucket decrypts and sorts all the keys of the bucket,
+// which takes tens of milliseconds for a bucket of 10,000 keys (see
+// BenchmarkCursor in walletdb/encrypted).  Later cursors of the transaction
+// reuse the sorted keys until keys are added to or deleted from the bucket
+// (see BenchmarkTxDetails).  Wallets with a long transaction history are
+// slower to iterate over.
+func WithEncryptedDB() LoaderOption {
+	return func(c *loaderConfig) {
+		c.encryptDB = true
+	}
+}
+
+// WithKDFUpgrade specifies that the master keys of loaded wallets are re-wrapped
+// with passphrase keys derived using the given parameters the next time the
+// wallets are unlocked, when those parameters name a different key derivation
+// function or are stronger than the current ones.  The passphrases of the
+// wallets are unchanged.
+func WithKDFUpgrade(config *waddrmgr.ScryptOptions) LoaderOption {
+	return func(c *loaderConfig) {
+		c.kdfUpgrade = config
+	}
+}
+
+// WithLogger specifies the logger the loader and the wallets it opens write to,
+// so that wallets for different chains can be told apart in the log.  The
+// package logger is used by default.
+func WithLogger(logger btclog.Logger) LoaderOption {
+	return func(c *loaderConfig) {
+		c.logger = logger
+	}
+}
+
 // Loader implements the creating of new and opening of existing wallets, while
 // providing a callback system for other subsystems to handle the loading of a
 // wallet.  This is primarily intended for use by the RPC servers, to enable
@@ -75,7 +145,7 @@ func WithWalletSyncRetryInterval(interval time.Duration) LoaderOption {
 type Loader struct {
 	cfg            *loaderConfig
 	callbacks      []func(*Wallet)
-	chainParams    *chaincfg.Params
+	chainParams    *netparams.ChainParams
 	netDir         string
 	noFreelistSync bool
 	timeout        time.Duration
@@ -85,13 +155,14 @@ type Loader struct {
 	walletExists   func() (bool, error)
 	walletCreated  func(db walletdb.ReadWriteTx) error
 	db             walletdb.DB
+	log            btclog.Logger
 	mu             sync.Mutex
 }
 
 // NewLoader constructs a Loader with an optional recovery window. If the
 // recovery window is non-zero, the wallet will attempt to recovery addresses
 // starting from the last SyncedTo height.
-func NewLoader(chainParams *chaincfg.Params, netDir string,
+func NewLoader(chainParams *netparams.ChainParams, netDir string,
 	noFreelistSync bool, timeout time.Duration, recoveryWindow uint32,
 	opts ...LoaderOption) *Loader {
 
@@ -99,6 +170,9 @@ func NewLoader(chainParams *chaincfg.Params, netDir string,
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	if cfg.logger == nil {
+		cfg.logger = log
+	}
 
 	return &Loader{
 		cfg:            cfg,
@@ -108,6 +182,7 @@ func NewLoader(chainParams *chaincfg.Params, netDir string,
 		timeout:        timeout,
 		recoveryWindow: recoveryWindow,
 		localDB:        true,
+		log:            cfg.logger,
 	}
 }
 
@@ -115,7 +190,7 @@ func NewLoader(chainParams *chaincfg.Params, netDir string,
 // users are free to use their own walletdb implementation (eg. leveldb, etcd)
 // to store the wallet. Given that the external DB may be shared an additional
 // function is also passed which will override Loader.WalletExists().
-func NewLoaderWithDB(chainParams *chaincfg.Params, recoveryWindow uint32,
+func NewLoaderWithDB(chainParams *netparams.ChainParams, recoveryWindow uint32,
 	db walletdb.DB, walletExists func() (bool, error),
 	opts ...LoaderOption) (*Loader, error) {
 
@@ -131,6 +206,9 @@ func NewLoaderWithDB(chainParams *chaincfg.Params, recoveryWindow uint32,
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	if cfg.logger == nil {
+		cfg.logger = log
+	}
 
 	return &Loader{
 		cfg:            cfg,
@@ -139,6 +217,7 @@ func NewLoaderWithDB(chainParams *chaincfg.Params, recoveryWindow uint32,
 		localDB:        false,
 		walletExists:   walletExists,
 		db:             db,
+		log:            cfg.logger,
 	}, nil
 }
 
@@ -198,7 +277,7 @@ func (l *Loader) CreateNewWallet(pubPassphrase, privPassphrase, seed []byte,
 		}
 
 		// Derive the master extended key from the seed.
-		rootKey, err = hdkeychain.NewMaster(seed, l.chainParams)
+		rootKey, err = hdkeychain.NewMaster(seed, l.chainParams.BTCDParams())
 		if err != nil {
 			return nil, fmt.Errorf("failed to derive master " +
 				"extended key")
@@ -210,6 +289,21 @@ func (l *Loader) CreateNewWallet(pubPassphrase, privPassphrase, seed []byte,
 	)
 }
 
+// CreateNewWalletFromMnemonic creates a new wallet using the provided public
+// and private passphrases from a BIP-0039 mnemonic and its optional
+// passphrase.  The mnemonic may be from any registered bip39 wordlist, and the
+// wallet's master key is the same as other BIP-0039 wallets derive from it.
+func (l *Loader) CreateNewWalletFromMnemonic(pubPassphrase, privPassphrase []byte,
+	mnemonic, mnemonicPassphrase string, bday time.Time) (*Wallet, error) {
+
+	seed, err := bip39.NewSeed(mnemonic, mnemonicPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.CreateNewWallet(pubPassphrase, privPassphrase, seed, bday)
+}
+
 // CreateNewWalletExtendedKey creates a new wallet from an extended master root
 // key using the provided public and private passphrases.  The root key is
 // optional.  If non-nil, addresses are derived from this root key.  If nil, a
@@ -260,9 +354,7 @@ func (l *Loader) createNewWallet(pubPassphrase, privPassphrase []byte,
 		if err != nil {
 			return nil, err
 		}
-		l.db, err = walletdb.Create(
-			"bdb", dbPath, l.noFreelistSync, l.timeout,
-		)
+		l.db, err = l.openLocalDB(dbPath, pubPassphrase, true)
 		if err != nil {
 			return nil, err
 		}
@@ -288,13 +380,14 @@ func (l *Loader) createNewWallet(pubPassphrase, privPassphrase []byte,
 	}
 
 	// Open the newly-created wallet.
-	w, err := OpenWithRetry(
+	w, err := openWallet(
 		l.db, pubPassphrase, nil, l.chainParams, l.recoveryWindow,
-		l.cfg.walletSyncRetryInterval,
+		l.cfg.walletSyncRetryInterval, l.log,
 	)
 	if err != nil {
 		return nil, err
 	}
+	w.kdfUpgrade = l.cfg.kdfUpgrade
 	w.Start()
 
 	l.onLoaded(w)
@@ -330,11 +423,18 @@ func (l *Loader) OpenExistingWallet(pubPassphrase []byte,
 
 		// Open the database using the boltdb backend.
 		dbPath := filepath.Join(l.netDir, WalletDBName)
-		l.db, err = walletdb.Open(
-			"bdb", dbPath, l.noFreelistSync, l.timeout,
-		)
+		l.db, err = l.openLocalDB(dbPath, pubPassphrase, false)
 		if err != nil {
-			log.Errorf("Failed to open database: %v", err)
+			l.log.Errorf("Failed to open database: %v", err)
+			return nil, err
+		}
+
+		// Apply any database upgrades before opening the wallet, so
+		// the database can be restored if they fail.
+		if err := l.upgradeDB(dbPath, pubPassphrase); err != nil {
+			if e := l.db.Close(); e != nil {
+				l.log.Warnf("Error closing database: %v", e)
+			}
 			return nil, err
 		}
 	}
@@ -351,9 +451,9 @@ func (l *Loader) OpenExistingWallet(pubPassphrase []byte,
 			ObtainPrivatePass: noConsole,
 		}
 	}
-	w, err := OpenWithRetry(
+	w, err := openWallet(
 		l.db, pubPassphrase, cbs, l.chainParams, l.recoveryWindow,
-		l.cfg.walletSyncRetryInterval,
+		l.cfg.walletSyncRetryInterval, l.log,
 	)
 	if err != nil {
 		// If opening the wallet fails (e.g. because of wrong
@@ -362,18 +462,153 @@ func (l *Loader) OpenExistingWallet(pubPassphrase []byte,
 		if l.localDB {
 			e := l.db.Close()
 			if e != nil {
-				log.Warnf("Error closing database: %v", e)
+				l.log.Warnf("Error closing database: %v", e)
 			}
 		}
 
 		return nil, err
 	}
+	w.kdfUpgrade = l.cfg.kdfUpgrade
 	w.Start()
 
 	l.onLoaded(w)
 	return w, nil
 }
 
+// snapshotFile is a database snapshot which is synced to disk when closed.
+type snapshotFile struct {
+	*os.File
+	written bool
+}
+
+// Close syncs and closes the snapshot file.
+func (f *snapshotFile) Close() error {
+	err := f.Sync()
+	if e := f.File.Close(); err == nil {
+		err = e
+	}
+	f.written = err == nil
+	return err
+}
+
+// upgradeDB applies the migrations the local wallet database at dbPath needs,
+// after copying it to a snapshot next to it with a ".<unix time in
+// nanoseconds>.pre-upgrade" suffix.  The database is restored from the
+// snapshot if any of the migrations fail.  Otherwise the snapshot is kept, so
+// it can be restored with RestoreBackup if the upgraded wallet turns out to be
+// broken.
+func (l *Loader) upgradeDB(dbPath string, pubPassphrase []byte) error {
+	snapshotPath := fmt.Sprintf("%s.%d.pre-upgrade", dbPath,
+		time.Now().UnixNano())
+	var snapshot *snapshotFile
+	openSnapshot := func() (io.WriteCloser, error) {
+		f, err := os.OpenFile(
+			snapshotPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600,
+		)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = &snapshotFile{File: f}
+		return snapshot, nil
+	}
+
+	err := migration.UpgradeDB(l.db, openSnapshot, migrationManagers)
+	switch {
+	case snapshot == nil:
+		return err
+
+	case !snapshot.written:
+		// The database was not changed, as the snapshot couldn't be
+		// written.
+		if e := os.Remove(snapshotPath); e != nil {
+			l.log.Warnf("Unable to remove snapshot: %v", e)
+		}
+		return err
+
+	case err == nil:
+		l.log.Infof("Upgraded wallet database, keeping the previous "+
+			"version at %s", snapshotPath)
+		return nil
+	}
+
+	l.log.Errorf("Unable to upgrade wallet database, restoring it from "+
+		"%s: %v", snapshotPath, err)
+	snapshotDB, e := l.openLocalDB(snapshotPath, pubPassphrase, false)
+	if e == nil {
+		e = migration.Restore(l.db, snapshotDB)
+		snapshotDB.Close()
+	}
+	if e != nil {
+		l.log.Errorf("Unable to restore wallet database: %v", e)
+	}
+	return err
+}
+
+// DryRunUpgrade reports the changes the database upgrades applied when the
+// wallet is next opened would make to its database, without changing it.  The
+// wallet must not be loaded.
+func (l *Loader) DryRunUpgrade(pubPassphrase []byte) ([]migration.Change,
+	error) {
+
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	if l.wallet != nil {
+		return nil, ErrLoaded
+	}
+	if !l.localDB {
+		return DryRunUpgrade(l.db)
+	}
+
+	dbPath := filepath.Join(l.netDir, WalletDBName)
+	db, err := l.openLocalDB(dbPath, pubPassphrase, false)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return DryRunUpgrade(db)
+}
+
+// openLocalDB opens, or creates if create is set, the local database at dbPath
+// with the database driver of the loader.  The database is opened through the
+// encrypting database driver if the loader encrypts its database, with a key
+// derived from the public passphrase, which is derived using the parameters of
+// the KDF upgrade of the loader when the database is created.
+func (l *Loader) openLocalDB(dbPath string, pubPassphrase []byte,
+	create bool) (walletdb.DB, error) {
+
+	if err := CheckDBDriver(dbPath, l.cfg.dbDriver); err != nil {
+		return nil, err
+	}
+
+	open := walletdb.Open
+	if create {
+		open = walletdb.Create
+	}
+	db, err := open(l.cfg.dbDriver, dbPath, l.noFreelistSync, l.timeout)
+	if err != nil || !l.cfg.encryptDB {
+		return db, err
+	}
+
+	args := []interface{}{db, pubPassphrase}
+	if create {
+		kdf := l.cfg.kdfUpgrade
+		if kdf == nil {
+			kdf = &waddrmgr.DefaultScryptOptions
+		}
+		args = append(args, dbSecretKeyGenerator(kdf))
+	}
+	encryptedDB, err := open("encrypted", args...)
+	if err != nil {
+		if e := db.Close(); e != nil {
+			l.log.Warnf("Error closing database: %v", e)
+		}
+		return nil, err
+	}
+	return encryptedDB, nil
+}
+
 // WalletExists returns whether a file exists at the loader's database path.
 // This may return an error for unexpected I/O failures.
 func (l *Loader) WalletExists() (bool, error) {
@@ -421,6 +656,52 @@ func (l *Loader) UnloadWallet() error {
 	return nil
 }
 
+// CheckDBDriver returns an error wrapping ErrDBDriverMismatch if the database
+// file at dbPath was created by one of the bdb and sqlite drivers, and the
+// driver provided is the other one.  Missing and empty files, and databases of
+// other drivers are not checked.
+func CheckDBDriver(dbPath, driver string) error {
+	if driver != "bdb" && driver != "sqlite" {
+		return nil
+	}
+
+	f, err := os.Open(dbPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// SQLite databases start with their magic string, while bbolt
+	// databases start with a meta page, which has the bbolt magic number
+	// after the 16 byte page header.
+	var header [20]byte
+	n, err := io.ReadFull(f, header[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	var fileDriver string
+	switch {
+	case bytes.HasPrefix(header[:n], []byte("SQLite format 3\x00")):
+		fileDriver = "sqlite"
+	case n == len(header) &&
+		binary.LittleEndian.Uint32(header[16:]) == 0xed0cdaed:
+
+		fileDriver = "bdb"
+	default:
+		return nil
+	}
+
+	if fileDriver != driver {
+		return fmt.Errorf("%w: %s was created with the %s driver, not "+
+			"the %s driver", ErrDBDriverMismatch, dbPath,
+			fileDriver, driver)
+	}
+	return nil
+}
+
 func fileExists(filePath string) (bool, error) {
 	_, err := os.Stat(filePath)
 	if err != nil {
@@ -0,0 +1,325 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/assets"
+	"github.com/bisoncraft/utxowallet/snacl"
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/walletdb/encrypted"
+	_ "github.com/bisoncraft/utxowallet/walletdb/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateNewWalletFromMnemonic ensures a wallet created from a BIP-0039
+// mnemonic derives the same addresses as other BIP-0039 wallets, using the
+// reference vectors from BIP-0084.
+func TestCreateNewWalletFromMnemonic(t *testing.T) {
+	t.Parallel()
+
+	loader := NewLoader(
+		assets.BTCParams["mainnet"], t.TempDir(), true,
+		defaultDBTimeout, 250,
+	)
+
+	const mnemonic = "abandon abandon abandon abandon abandon abandon " +
+		"abandon abandon abandon abandon abandon about"
+	w, err := loader.CreateNewWalletFromMnemonic(
+		[]byte("hello"), []byte("world"), mnemonic, "", time.Now(),
+	)
+	require.NoError(t, err)
+	defer w.Manager.Close()
+	w.chainClient = &mockChainClient{}
+
+	addr, err := w.NewAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	require.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+		addr.String())
+
+	// An invalid mnemonic must not create a wallet.
+	loader = NewLoader(
+		assets.BTCParams["mainnet"], t.TempDir(), true,
+		defaultDBTimeout, 250,
+	)
+	_, err = loader.CreateNewWalletFromMnemonic(
+		[]byte("hello"), []byte("world"), mnemonic+" about", "",
+		time.Now(),
+	)
+	require.Error(t, err)
+}
+
+// TestEncryptedDB ensures that wallets can be created in and reopened from an
+// encrypted wallet database, which doesn't hold the plaintext namespaces.
+func TestEncryptedDB(t *testing.T) {
+	t.Parallel()
+
+	netDir := t.TempDir()
+	loader := NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+		WithEncryptedDB(),
+	)
+	pubPass := []byte("hello")
+	w, err := loader.CreateNewWallet(
+		pubPass, []byte("world"), nil, time.Now(),
+	)
+	require.NoError(t, err)
+	w.chainClient = &mockChainClient{}
+	addr, err := w.NewAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	require.NoError(t, loader.UnloadWallet())
+
+	raw, err := os.ReadFile(filepath.Join(netDir, WalletDBName))
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), string(waddrmgrNamespaceKey))
+	require.NotContains(t, string(raw), string(wtxmgrNamespaceKey))
+
+	_, err = loader.OpenExistingWallet([]byte("wrong"), false)
+	require.Error(t, err)
+
+	w, err = loader.OpenExistingWallet(pubPass, false)
+	require.NoError(t, err)
+	have, err := w.HaveAddress(addr)
+	require.NoError(t, err)
+	require.True(t, have)
+
+	// Changing the public passphrase changes the passphrase the database
+	// is encrypted with.
+	newPubPass := []byte("hello again")
+	require.NoError(t, w.ChangePublicPassphrase(pubPass, newPubPass))
+	require.NoError(t, loader.UnloadWallet())
+
+	_, err = loader.OpenExistingWallet(pubPass, false)
+	require.Error(t, err)
+
+	w, err = loader.OpenExistingWallet(newPubPass, false)
+	require.NoError(t, err)
+	defer loader.UnloadWallet()
+	have, err = w.HaveAddress(addr)
+	require.NoError(t, err)
+	require.True(t, have)
+
+	// When the database can't be re-keyed, the passphrase of the address
+	// manager is left unchanged.
+	changeDBPassphrase := func(old, new []byte) {
+		err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+			return encrypted.ChangePassphrase(
+				tx, old, new,
+				dbSecretKeyGenerator(w.kdfOptions()),
+			)
+		})
+		require.NoError(t, err)
+	}
+	otherPass := []byte("other")
+	changeDBPassphrase(newPubPass, otherPass)
+	err = w.ChangePublicPassphrase(newPubPass, []byte("third"))
+	require.True(t, waddrmgr.IsError(err, waddrmgr.ErrWrongPassphrase))
+	changeDBPassphrase(otherPass, newPubPass)
+	require.NoError(t, w.ChangePublicPassphrase(newPubPass, []byte("third")))
+}
+
+// TestEncryptedDBKDF ensures that the key of an encrypted wallet database is
+// derived using the KDF upgrade of the loader, when the database is created
+// and when the public passphrase is changed.
+func TestEncryptedDBKDF(t *testing.T) {
+	t.Parallel()
+
+	netDir := t.TempDir()
+	loader := NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+		WithEncryptedDB(),
+		WithKDFUpgrade(&waddrmgr.ScryptOptions{
+			KDF:     snacl.KDFArgon2id,
+			Time:    1,
+			Memory:  64,
+			Threads: 1,
+		}),
+	)
+	assertKDF := func() {
+		t.Helper()
+
+		dbPath := filepath.Join(netDir, WalletDBName)
+		db, err := walletdb.Open("bdb", dbPath, true, defaultDBTimeout)
+		require.NoError(t, err)
+		defer db.Close()
+		var sk snacl.SecretKey
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			meta := tx.ReadBucket([]byte("encrypted-walletdb"))
+			return sk.Unmarshal(meta.Get([]byte("params")))
+		})
+		require.NoError(t, err)
+		require.Equal(t, snacl.KDFArgon2id, sk.Parameters.KDF)
+		require.Equal(t, uint32(1), sk.Parameters.Time)
+	}
+
+	pubPass := []byte("hello")
+	_, err := loader.CreateNewWallet(
+		pubPass, []byte("world"), nil, time.Now(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, loader.UnloadWallet())
+	assertKDF()
+
+	w, err := loader.OpenExistingWallet(pubPass, false)
+	require.NoError(t, err)
+	newPubPass := []byte("hello again")
+	require.NoError(t, w.ChangePublicPassphrase(pubPass, newPubPass))
+	require.NoError(t, loader.UnloadWallet())
+	assertKDF()
+}
+
+// TestDBDriver ensures that wallets can be created in and reopened from a
+// database of the driver given with the WithDBDriver option.
+func TestDBDriver(t *testing.T) {
+	t.Parallel()
+
+	netDir := t.TempDir()
+	loader := NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+		WithDBDriver("sqlite"),
+	)
+	pubPass := []byte("hello")
+	w, err := loader.CreateNewWallet(
+		pubPass, []byte("world"), nil, time.Now(),
+	)
+	require.NoError(t, err)
+	w.chainClient = &mockChainClient{}
+	addr, err := w.NewAddress(0, waddrmgr.KeyScopeBIP0084)
+	require.NoError(t, err)
+	require.NoError(t, loader.UnloadWallet())
+
+	raw, err := os.ReadFile(filepath.Join(netDir, WalletDBName))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(raw), "SQLite format 3"))
+
+	// The wallet database is not opened with the other driver.
+	bdbLoader := NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+	)
+	_, err = bdbLoader.OpenExistingWallet(pubPass, false)
+	require.ErrorIs(t, err, ErrDBDriverMismatch)
+
+	w, err = loader.OpenExistingWallet(pubPass, false)
+	require.NoError(t, err)
+	defer loader.UnloadWallet()
+	have, err := w.HaveAddress(addr)
+	require.NoError(t, err)
+	require.True(t, have)
+}
+
+// TestCheckDBDriver ensures that the driver a database file was created with
+// is detected.
+func TestCheckDBDriver(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, driver := range []string{"bdb", "sqlite"} {
+		dbPath := filepath.Join(dir, driver+".db")
+		require.NoError(t, CheckDBDriver(dbPath, driver))
+		db, err := walletdb.Create(
+			driver, dbPath, true, defaultDBTimeout,
+		)
+		require.NoError(t, err)
+		require.NoError(t, walletdb.Update(db,
+			func(tx walletdb.ReadWriteTx) error {
+				_, err := tx.CreateTopLevelBucket([]byte("b"))
+				return err
+			},
+		))
+		require.NoError(t, db.Close())
+
+		require.NoError(t, CheckDBDriver(dbPath, driver))
+		other := "bdb"
+		if driver == "bdb" {
+			other = "sqlite"
+		}
+		err = CheckDBDriver(dbPath, other)
+		require.ErrorIs(t, err, ErrDBDriverMismatch)
+	}
+}
+
+// TestLoaderDryRunUpgrade ensures that the database upgrades of a wallet which
+// is not loaded can be dry run, and that an up to date wallet needs none.
+func TestLoaderDryRunUpgrade(t *testing.T) {
+	t.Parallel()
+
+	loader := NewLoader(
+		assets.BTCParams["testnet"], t.TempDir(), true,
+		defaultDBTimeout, 0,
+	)
+	pubPass := []byte("hello")
+	_, err := loader.DryRunUpgrade(pubPass)
+	require.Error(t, err)
+
+	_, err = loader.CreateNewWallet(
+		pubPass, []byte("world"), nil, time.Now(),
+	)
+	require.NoError(t, err)
+	_, err = loader.DryRunUpgrade(pubPass)
+	require.ErrorIs(t, err, ErrLoaded)
+	require.NoError(t, loader.UnloadWallet())
+
+	changes, err := loader.DryRunUpgrade(pubPass)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = loader.OpenExistingWallet(pubPass, false)
+	require.NoError(t, err)
+	require.NoError(t, loader.UnloadWallet())
+}
+
+// TestKDFUpgrade tests that the master keys of a wallet loaded with the
+// WithKDFUpgrade option are re-wrapped when it is unlocked, and that the wallet
+// is still unlocked with the same passphrase afterwards.
+func TestKDFUpgrade(t *testing.T) {
+	t.Parallel()
+
+	netDir := t.TempDir()
+	argon2idOptions := &waddrmgr.ScryptOptions{
+		KDF:     snacl.KDFArgon2id,
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+	}
+	loader := NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+		WithKDFUpgrade(argon2idOptions),
+	)
+	pubPass, privPass := []byte("hello"), []byte("world")
+	w, err := loader.CreateNewWallet(pubPass, privPass, nil, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, w.kdfUpgrade)
+	require.False(t, w.kdfUpgraded)
+
+	require.NoError(t, w.Unlock(privPass, nil))
+	require.True(t, w.kdfUpgraded)
+	require.NotNil(t, w.kdfUpgrade)
+
+	// Passphrase changes after the upgrade keep using its parameters.
+	newPrivPass := []byte("world2")
+	require.NoError(t, w.ChangePrivatePassphrase(privPass, newPrivPass))
+	privPass = newPrivPass
+	err = walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		upgraded, err := w.Manager.UpgradeKDF(
+			addrmgrNs, pubPass, privPass, argon2idOptions,
+		)
+		require.False(t, upgraded)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, loader.UnloadWallet())
+
+	loader = NewLoader(
+		assets.BTCParams["testnet"], netDir, true, defaultDBTimeout, 0,
+	)
+	w, err = loader.OpenExistingWallet(pubPass, false)
+	require.NoError(t, err)
+	defer loader.UnloadWallet()
+	require.Error(t, w.Unlock([]byte("wrong"), nil))
+	require.NoError(t, w.Unlock(privPass, nil))
+}
@@ -6,12 +6,18 @@
 package wallet
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 
 	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/wallet/txsizes"
 	"github.com/bisoncraft/utxowallet/walletdb"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 )
 
 // MakeMultiSigScript creates a multi-signature script that can be redeemed with
@@ -112,3 +118,302 @@ func (w *Wallet) ImportP2SHRedeemScript(script []byte) (*btcutil.AddressScriptHa
 	})
 	return p2shAddr, err
 }
+
+// ErrMultisigIncomplete is returned by FinalizePsbt when inputs of multisig
+// accounts are still missing signatures of other cosigners after the wallet
+// signed them.  The packet then holds the partial signatures of the wallet.
+var ErrMultisigIncomplete = errors.New("multisig inputs require signatures " +
+	"of other cosigners")
+
+// MultisigCosigner returns the wallet as a cosigner of multisig accounts of the
+// script type, with its BIP-0048 account key at the account number provided.
+// The cosigner is shared with the other cosigners to create the account with
+// NewMultisigAccount.  The wallet must be unlocked.
+func (w *Wallet) MultisigCosigner(account uint32,
+	scriptType waddrmgr.MultisigScriptType) (*waddrmgr.Cosigner, error) {
+
+	var cosigner *waddrmgr.Cosigner
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		var err error
+		cosigner, err = w.Manager.MultisigCosigner(ns, account, scriptType)
+		return err
+	})
+	return cosigner, err
+}
+
+// NewMultisigAccount creates a multisig account requiring threshold signatures
+// of the cosigners, paying to sorted multisig scripts of the type provided.
+// The account is created in the default key scope paying to the same kind of
+// outputs, so its outputs are tracked like those of any other account.  If the
+// wallet is one of the cosigners, it signs the inputs of the account in
+// FinalizePsbt, in which case the wallet must be unlocked.
+func (w *Wallet) NewMultisigAccount(name string, threshold int,
+	scriptType waddrmgr.MultisigScriptType,
+	cosigners []*waddrmgr.Cosigner) (*waddrmgr.AccountProperties, error) {
+
+	keyScope, err := scriptType.KeyScope()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		props *waddrmgr.AccountProperties
+		addrs []btcutil.Address
+	)
+	err = walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		ns := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		scopedMgr, err := w.Manager.FetchScopedKeyManager(keyScope)
+		if err != nil {
+			// Watching-only wallets start without any scoped
+			// managers.
+			scopedMgr, err = w.Manager.NewScopedKeyManager(
+				ns, keyScope, waddrmgr.ScopeAddrMap[keyScope],
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		account, err := scopedMgr.NewMultisigAccount(
+			ns, name, threshold, scriptType, cosigners,
+		)
+		if err != nil {
+			return err
+		}
+		err = scopedMgr.ForEachAccountAddress(
+			ns, account, func(addr waddrmgr.ManagedAddress) error {
+				addrs = append(addrs, addr.Address())
+				return nil
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		props, err = scopedMgr.AccountProperties(ns, account)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.watchAddresses(addrs); err != nil {
+		return nil, err
+	}
+	w.NtfnServer.notifyAccountProperties(props)
+
+	return props, nil
+}
+
+// watchAddresses requests the chain backend, if there is one, to notify the
+// wallet of transactions paying to the addresses.
+func (w *Wallet) watchAddresses(addrs []btcutil.Address) error {
+	chainClient := w.ChainClient()
+	if chainClient == nil || len(addrs) == 0 {
+		return nil
+	}
+	err := chainClient.NotifyReceived(addrs)
+	if err != nil {
+		return fmt.Errorf("unable to subscribe for address "+
+			"notifications: %w", err)
+	}
+	return nil
+}
+
+// extendMultisigAddresses stores and watches for the addresses of a multisig
+// account following the address found to be used, which may have been issued
+// by another cosigner.
+func (w *Wallet) extendMultisigAddresses(ns walletdb.ReadWriteBucket,
+	scopedMgr *waddrmgr.ScopedKeyManager,
+	addr waddrmgr.ManagedMultisigAddress) error {
+
+	branch, index := addr.DerivationIndex()
+	newAddrs, err := scopedMgr.ExtendMultisigAddresses(
+		ns, addr.InternalAccount(), branch, index,
+	)
+	if err != nil {
+		return err
+	}
+
+	addrs := make([]btcutil.Address, 0, len(newAddrs))
+	for _, newAddr := range newAddrs {
+		addrs = append(addrs, newAddr.Address())
+	}
+	return w.watchAddresses(addrs)
+}
+
+// multisigOutputAddr returns the address of a multisig account the output
+// script pays to, or nil if it doesn't pay to one.
+func (w *Wallet) multisigOutputAddr(ns walletdb.ReadBucket,
+	pkScript []byte) waddrmgr.ManagedMultisigAddress {
+
+	if !txscript.IsPayToScriptHash(pkScript) &&
+		!txscript.IsPayToWitnessScriptHash(pkScript) {
+
+		return nil
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		pkScript, w.chainParams,
+	)
+	if err != nil || len(addrs) != 1 {
+		return nil
+	}
+	addr, err := w.Manager.Address(ns, addrs[0])
+	if err != nil {
+		return nil
+	}
+	msAddr, _ := addr.(waddrmgr.ManagedMultisigAddress)
+	return msAddr
+}
+
+// fetchMultisigOutputAddr is like multisigOutputAddr, within its own database
+// transaction.
+func (w *Wallet) fetchMultisigOutputAddr(
+	pkScript []byte) waddrmgr.ManagedMultisigAddress {
+
+	var addr waddrmgr.ManagedMultisigAddress
+	_ = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		addr = w.multisigOutputAddr(ns, pkScript)
+		return nil
+	})
+	return addr
+}
+
+// multisigInputSize returns the estimator of the size of inputs spending the
+// outputs of multisig accounts.
+func (w *Wallet) multisigInputSize(
+	ns walletdb.ReadBucket) txauthor.InputSizeEstimator {
+
+	return func(pkScript []byte) (txsizes.InputSize, bool) {
+		addr := w.multisigOutputAddr(ns, pkScript)
+		if addr == nil {
+			return txsizes.InputSize{}, false
+		}
+		return txsizes.MultisigInputSize(
+			addr.Threshold(), len(addr.Keys()),
+			addr.RedeemScript() != nil,
+		), true
+	}
+}
+
+// multisigDerivations returns the BIP32 derivations of the keys of the
+// cosigners of the address.
+func multisigDerivations(
+	addr waddrmgr.ManagedMultisigAddress) []*psbt.Bip32Derivation {
+
+	keys := addr.Keys()
+	derivations := make([]*psbt.Bip32Derivation, 0, len(keys))
+	for _, key := range keys {
+		derivations = append(derivations, &psbt.Bip32Derivation{
+			PubKey:               key.PubKey.SerializeCompressed(),
+			MasterKeyFingerprint: key.MasterKeyFingerprint,
+			Bip32Path:            key.DerivationPath,
+		})
+	}
+	return derivations
+}
+
+// addInputInfoMultisig adds the UTXO, scripts and BIP32 derivation info of
+// every cosigner for an input spending an output of a multisig account.
+func addInputInfoMultisig(in *psbt.PInput, prevTx *wire.MsgTx,
+	utxo *wire.TxOut, addr waddrmgr.ManagedMultisigAddress) error {
+
+	witnessScript, err := addr.Script()
+	if err != nil {
+		return err
+	}
+
+	// As a fix for CVE-2020-14199 we have to always include the full
+	// non-witness UTXO in the PSBT for segwit v0.
+	in.NonWitnessUtxo = prevTx
+	in.WitnessUtxo = &wire.TxOut{
+		Value:    utxo.Value,
+		PkScript: utxo.PkScript,
+	}
+	in.SighashType = txscript.SigHashAll
+	in.WitnessScript = witnessScript
+	in.RedeemScript = addr.RedeemScript()
+	in.Bip32Derivation = multisigDerivations(addr)
+	return nil
+}
+
+// createMultisigOutputInfo creates the scripts and BIP32 derivation info of
+// every cosigner for an output paying to a multisig account of the wallet.
+func createMultisigOutputInfo(
+	addr waddrmgr.ManagedMultisigAddress) (*psbt.POutput, error) {
+
+	witnessScript, err := addr.Script()
+	if err != nil {
+		return nil, err
+	}
+	return &psbt.POutput{
+		RedeemScript:    addr.RedeemScript(),
+		WitnessScript:   witnessScript,
+		Bip32Derivation: multisigDerivations(addr),
+	}, nil
+}
+
+// signMultisigInput adds the signature of the wallet as a cosigner to an input
+// spending an output of a multisig account, unless the input already has
+// enough signatures, and finalizes the input once it does.  Whether the input
+// is still missing signatures of other cosigners is returned.
+func signMultisigInput(packet *psbt.Packet, idx int, utxo *wire.TxOut,
+	sigHashes *txscript.TxSigHashes,
+	addr waddrmgr.ManagedMultisigAddress) (bool, error) {
+
+	in := &packet.Inputs[idx]
+	witnessScript, err := addr.Script()
+	if err != nil {
+		return false, err
+	}
+	in.WitnessScript = witnessScript
+	in.RedeemScript = addr.RedeemScript()
+
+	if len(in.PartialSigs) < addr.Threshold() {
+		privKey, err := addr.PrivKey()
+		switch {
+		case err == nil:
+			pubKey := privKey.PubKey().SerializeCompressed()
+			signed := false
+			for _, sig := range in.PartialSigs {
+				signed = signed || bytes.Equal(sig.PubKey, pubKey)
+			}
+			if signed {
+				break
+			}
+
+			hashType := in.SighashType
+			if hashType == 0 {
+				hashType = txscript.SigHashAll
+			}
+			sig, err := txscript.RawTxInWitnessSignature(
+				packet.UnsignedTx, sigHashes, idx, utxo.Value,
+				witnessScript, hashType, privKey,
+			)
+			if err != nil {
+				return false, err
+			}
+			in.PartialSigs = append(in.PartialSigs, &psbt.PartialSig{
+				PubKey:    pubKey,
+				Signature: sig,
+			})
+
+		// The wallet isn't one of the cosigners, or can't sign for
+		// them, so the signatures must come from other cosigners.
+		case waddrmgr.IsError(err, waddrmgr.ErrWatchingOnly):
+
+		default:
+			return false, err
+		}
+	}
+
+	if len(in.PartialSigs) < addr.Threshold() {
+		return true, nil
+	}
+
+	// Exactly the threshold of signatures is used in the final witness.
+	in.PartialSigs = in.PartialSigs[:addr.Threshold()]
+	return false, psbt.Finalize(packet, idx)
+}
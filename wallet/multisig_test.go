@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/descriptor"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMultisigAccount tests that the wallets of the cosigners of a multisig
+// account derive the same addresses, and that each of them signs the inputs of
+// the account until enough of them did.
+func TestMultisigAccount(t *testing.T) {
+	t.Parallel()
+
+	w1, cleanup := testWallet(t)
+	defer cleanup()
+	w2, cleanup := testWallet(t)
+	defer cleanup()
+
+	scriptType := waddrmgr.MultisigWitnessScript
+	scope := waddrmgr.KeyScopeBIP0084
+	cosigner1, err := w1.MultisigCosigner(0, scriptType)
+	require.NoError(t, err)
+	cosigner2, err := w2.MultisigCosigner(0, scriptType)
+	require.NoError(t, err)
+	cosigners := []*waddrmgr.Cosigner{cosigner1, cosigner2}
+
+	props1, err := w1.NewMultisigAccount("multisig", 2, scriptType, cosigners)
+	require.NoError(t, err)
+	require.Equal(t, scope, props1.KeyScope)
+	require.Equal(t, 2, props1.Multisig.Threshold)
+	props2, err := w2.NewMultisigAccount("multisig", 2, scriptType, cosigners)
+	require.NoError(t, err)
+
+	// Both cosigners derive the same addresses.
+	addr, err := w1.NewAddress(props1.AccountNumber, scope)
+	require.NoError(t, err)
+	addr2, err := w2.NewAddress(props2.AccountNumber, scope)
+	require.NoError(t, err)
+	require.Equal(t, addr.String(), addr2.String())
+	_, ok := addr.(*btcutil.AddressWitnessScriptHash)
+	require.True(t, ok)
+
+	// The account is exported as a sorted multisig descriptor.
+	descs, err := w1.AccountDescriptors(scope, props1.AccountNumber)
+	require.NoError(t, err)
+	require.Equal(t, descriptor.TypeWsh, descs.External.Type)
+	descAddr, err := descs.External.Address(0, w1.chainParams)
+	require.NoError(t, err)
+	require.Equal(t, addr.String(), descAddr.String())
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	utxo := wire.NewTxOut(1000000, pkScript)
+	incomingTx := &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{utxo},
+	}
+	addUtxo(t, w1, incomingTx)
+	addUtxo(t, w2, incomingTx)
+
+	// Funding from the account adds the scripts and the derivations of
+	// both cosigners to the input and change output.
+	packet := &psbt.Packet{
+		UnsignedTx: &wire.MsgTx{
+			TxOut: []*wire.TxOut{{
+				PkScript: testScriptP2WKH,
+				Value:    50000,
+			}},
+		},
+		Outputs: []psbt.POutput{{}},
+	}
+	changeIndex, err := w1.FundPsbt(
+		packet, &scope, 1, props1.AccountNumber, 5000,
+		CoinSelectionLargest,
+	)
+	require.NoError(t, err)
+	require.Len(t, packet.Inputs, 1)
+	require.NotNil(t, packet.Inputs[0].WitnessScript)
+	require.Len(t, packet.Inputs[0].Bip32Derivation, 2)
+	require.GreaterOrEqual(t, changeIndex, int32(0))
+	require.NotNil(t, packet.Outputs[changeIndex].WitnessScript)
+	require.Len(t, packet.Outputs[changeIndex].Bip32Derivation, 2)
+
+	// The first cosigner adds its signature only.
+	err = w1.FinalizePsbt(&scope, props1.AccountNumber, packet)
+	require.ErrorIs(t, err, ErrMultisigIncomplete)
+	require.Len(t, packet.Inputs[0].PartialSigs, 1)
+
+	// Signing again doesn't add another signature of the same key.
+	err = w1.FinalizePsbt(&scope, props1.AccountNumber, packet)
+	require.ErrorIs(t, err, ErrMultisigIncomplete)
+	require.Len(t, packet.Inputs[0].PartialSigs, 1)
+
+	// The second cosigner completes the transaction.
+	err = w2.FinalizePsbt(&scope, props2.AccountNumber, packet)
+	require.NoError(t, err)
+	finalTx, err := psbt.Extract(packet)
+	require.NoError(t, err)
+	err = validateMsgTx(
+		finalTx, [][]byte{pkScript},
+		[]btcutil.Amount{btcutil.Amount(utxo.Value)},
+	)
+	require.NoError(t, err)
+}
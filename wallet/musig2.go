@@ -0,0 +1,272 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+)
+
+var (
+	// ErrMuSig2SessionNotFound is returned when a MuSig2 session is not
+	// known to the wallet, either because it was never created or because
+	// it was already completed or cleaned up.
+	ErrMuSig2SessionNotFound = errors.New("MuSig2 session not found")
+
+	// ErrMuSig2InvalidTweaks is returned when creating a MuSig2 session
+	// with more than one kind of tweak of the combined key.
+	ErrMuSig2InvalidTweaks = errors.New("MuSig2 key may only be tweaked " +
+		"by either generic tweaks, a BIP-0086 tweak or a taproot tweak")
+)
+
+// MuSig2SessionID identifies a MuSig2 signing session of the wallet.  It is the
+// hash of the public nonce of the wallet in the session.
+type MuSig2SessionID [sha256.Size]byte
+
+// MuSig2Tweaks are the tweaks applied to the combined key of the signers of a
+// MuSig2 session.  At most one kind of tweak is applied.
+type MuSig2Tweaks struct {
+	// GenericTweaks are the tweaks applied to the combined key in order.
+	GenericTweaks []musig2.KeyTweakDesc
+
+	// TaprootBIP0086Tweak is whether the combined key is the internal key
+	// of a BIP-0086 taproot output key committing to no script.
+	TaprootBIP0086Tweak bool
+
+	// TaprootTweak is the root hash of the script tree of a taproot output
+	// key, with the combined key as its internal key.
+	TaprootTweak []byte
+}
+
+// contextOptions returns the options of the MuSig2 context applying the
+// tweaks.
+func (t *MuSig2Tweaks) contextOptions() ([]musig2.ContextOption, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	var opts []musig2.ContextOption
+	if len(t.GenericTweaks) > 0 {
+		opts = append(opts, musig2.WithTweakedContext(t.GenericTweaks...))
+	}
+	if t.TaprootBIP0086Tweak {
+		opts = append(opts, musig2.WithBip86TweakCtx())
+	}
+	if len(t.TaprootTweak) > 0 {
+		opts = append(opts, musig2.WithTaprootTweakCtx(t.TaprootTweak))
+	}
+	if len(opts) > 1 {
+		return nil, ErrMuSig2InvalidTweaks
+	}
+	return opts, nil
+}
+
+// MuSig2SessionInfo describes a MuSig2 signing session of the wallet.
+type MuSig2SessionInfo struct {
+	// SessionID identifies the session.
+	SessionID MuSig2SessionID
+
+	// PublicNonce is the public nonce of the wallet, to be shared with the
+	// other signers.
+	PublicNonce [musig2.PubNonceSize]byte
+
+	// CombinedKey is the combined key of the signers, with the tweaks of
+	// the session applied.  Signatures of the session are valid for this
+	// key.
+	CombinedKey *btcec.PublicKey
+
+	// TaprootInternalKey is the combined key of the signers before the
+	// taproot tweak was applied, if the session has a taproot tweak.
+	TaprootInternalKey *btcec.PublicKey
+
+	// HaveAllNonces is whether the nonces of all the signers are known,
+	// so the wallet is ready to sign.
+	HaveAllNonces bool
+}
+
+// muSig2Session is a MuSig2 signing session of the wallet.
+type muSig2Session struct {
+	info    MuSig2SessionInfo
+	session *musig2.Session
+}
+
+// MuSig2CreateSession starts a MuSig2 signing session, as specified by
+// BIP-0327, with the private key of the wallet for the signing key provided.
+// The keys of all signers, including the signing key, are sorted to compute
+// their combined key, to which the tweaks, if any, are applied.  The public
+// nonces of the other signers known so far are registered with the session.
+//
+// The signing key must be the key of a P2PKH, P2WPKH or BIP-0086 P2TR address
+// of the wallet, and the wallet must be unlocked.  The session is kept in
+// memory only, so it does not survive a restart of the wallet.
+func (w *Wallet) MuSig2CreateSession(signingKey *btcec.PublicKey,
+	signers []*btcec.PublicKey, tweaks *MuSig2Tweaks,
+	otherNonces [][musig2.PubNonceSize]byte) (*MuSig2SessionInfo, error) {
+
+	ctxOpts, err := tweaks.contextOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var privKey *btcec.PrivateKey
+	err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		var err error
+		privKey, err = xOnlyPrivKey(
+			w.Manager, ns, schnorr.SerializePubKey(signingKey),
+			w.chainParams,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if privKey == nil || !privKey.PubKey().IsEqual(signingKey) {
+		return nil, fmt.Errorf("signing key %x is not a wallet key",
+			signingKey.SerializeCompressed())
+	}
+
+	ctxOpts = append(ctxOpts, musig2.WithKnownSigners(signers))
+	ctx, err := musig2.NewContext(privKey, true, ctxOpts...)
+	if err != nil {
+		return nil, err
+	}
+	session, err := ctx.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	var haveAllNonces bool
+	for _, nonce := range otherNonces {
+		haveAllNonces, err = session.RegisterPubNonce(nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	combinedKey, err := ctx.CombinedKey()
+	if err != nil {
+		return nil, err
+	}
+	var internalKey *btcec.PublicKey
+	if tweaks != nil && (tweaks.TaprootBIP0086Tweak ||
+		len(tweaks.TaprootTweak) > 0) {
+
+		internalKey, err = ctx.TaprootInternalKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pubNonce := session.PublicNonce()
+	s := &muSig2Session{
+		info: MuSig2SessionInfo{
+			SessionID:          sha256.Sum256(pubNonce[:]),
+			PublicNonce:        pubNonce,
+			CombinedKey:        combinedKey,
+			TaprootInternalKey: internalKey,
+			HaveAllNonces:      haveAllNonces,
+		},
+		session: session,
+	}
+
+	w.muSig2SessionsMtx.Lock()
+	w.muSig2Sessions[s.info.SessionID] = s
+	w.muSig2SessionsMtx.Unlock()
+
+	info := s.info
+	return &info, nil
+}
+
+// MuSig2RegisterNonces registers the public nonces of other signers with a
+// MuSig2 session.  Whether the nonces of all signers are known is returned.
+func (w *Wallet) MuSig2RegisterNonces(id MuSig2SessionID,
+	nonces [][musig2.PubNonceSize]byte) (bool, error) {
+
+	w.muSig2SessionsMtx.Lock()
+	defer w.muSig2SessionsMtx.Unlock()
+
+	s, ok := w.muSig2Sessions[id]
+	if !ok {
+		return false, ErrMuSig2SessionNotFound
+	}
+	for _, nonce := range nonces {
+		haveAllNonces, err := s.session.RegisterPubNonce(nonce)
+		if err != nil {
+			return false, err
+		}
+		s.info.HaveAllNonces = haveAllNonces
+	}
+	return s.info.HaveAllNonces, nil
+}
+
+// MuSig2Sign returns the partial signature of the wallet of the message in a
+// MuSig2 session, once the nonces of all signers are known.  A session only
+// signs once, as its nonce must never be reused.  If cleanUp is true, the
+// session is removed after signing, for when the signatures are combined by
+// another signer.
+func (w *Wallet) MuSig2Sign(id MuSig2SessionID, msg [sha256.Size]byte,
+	cleanUp bool) (*musig2.PartialSignature, error) {
+
+	w.muSig2SessionsMtx.Lock()
+	defer w.muSig2SessionsMtx.Unlock()
+
+	s, ok := w.muSig2Sessions[id]
+	if !ok {
+		return nil, ErrMuSig2SessionNotFound
+	}
+	sig, err := s.session.Sign(msg, musig2.WithSortedKeys())
+	if err != nil {
+		return nil, err
+	}
+	if cleanUp {
+		delete(w.muSig2Sessions, id)
+	}
+	return sig, nil
+}
+
+// MuSig2CombineSig combines the partial signatures of the other signers with
+// the partial signature of the wallet in a MuSig2 session, which must have
+// signed first.  Once the signatures of all signers are combined, the final
+// schnorr signature is returned along with true, and the session is removed.
+func (w *Wallet) MuSig2CombineSig(id MuSig2SessionID,
+	sigs []*musig2.PartialSignature) (*schnorr.Signature, bool, error) {
+
+	w.muSig2SessionsMtx.Lock()
+	defer w.muSig2SessionsMtx.Unlock()
+
+	s, ok := w.muSig2Sessions[id]
+	if !ok {
+		return nil, false, ErrMuSig2SessionNotFound
+	}
+	var haveAllSigs bool
+	for _, sig := range sigs {
+		var err error
+		haveAllSigs, err = s.session.CombineSig(sig)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if !haveAllSigs {
+		return nil, false, nil
+	}
+
+	delete(w.muSig2Sessions, id)
+	return s.session.FinalSig(), true, nil
+}
+
+// MuSig2Cleanup removes a MuSig2 session that is not going to be completed.
+func (w *Wallet) MuSig2Cleanup(id MuSig2SessionID) error {
+	w.muSig2SessionsMtx.Lock()
+	defer w.muSig2SessionsMtx.Unlock()
+
+	if _, ok := w.muSig2Sessions[id]; !ok {
+		return ErrMuSig2SessionNotFound
+	}
+	delete(w.muSig2Sessions, id)
+	return nil
+}
@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMuSig2Session tests that two wallets sign a message for their combined
+// taproot key in MuSig2 sessions.
+func TestMuSig2Session(t *testing.T) {
+	t.Parallel()
+
+	w1, cleanup := testWallet(t)
+	defer cleanup()
+	w2, cleanup := testWallet(t)
+	defer cleanup()
+
+	// walletKey returns the key of a new address of the wallet.
+	walletKey := func(w *Wallet,
+		scope waddrmgr.KeyScope) *btcec.PublicKey {
+
+		addr, err := w.NewAddress(0, scope)
+		require.NoError(t, err)
+		var pubKey *btcec.PublicKey
+		err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+			ns := tx.ReadBucket(waddrmgrNamespaceKey)
+			managedAddr, err := w.Manager.Address(ns, addr)
+			if err != nil {
+				return err
+			}
+			pubKey = managedAddr.(waddrmgr.ManagedPubKeyAddress).
+				PubKey()
+			return nil
+		})
+		require.NoError(t, err)
+		return pubKey
+	}
+	key1 := walletKey(w1, waddrmgr.KeyScopeBIP0086)
+	key2 := walletKey(w2, waddrmgr.KeyScopeBIP0084)
+	signers := []*btcec.PublicKey{key1, key2}
+	tweaks := &MuSig2Tweaks{TaprootBIP0086Tweak: true}
+
+	// Sessions are only created for wallet keys, with a single kind of
+	// tweak.
+	_, err := w1.MuSig2CreateSession(key2, signers, tweaks, nil)
+	require.Error(t, err)
+	_, err = w1.MuSig2CreateSession(key1, signers, &MuSig2Tweaks{
+		TaprootBIP0086Tweak: true,
+		TaprootTweak:        make([]byte, 32),
+	}, nil)
+	require.ErrorIs(t, err, ErrMuSig2InvalidTweaks)
+
+	session1, err := w1.MuSig2CreateSession(key1, signers, tweaks, nil)
+	require.NoError(t, err)
+	require.False(t, session1.HaveAllNonces)
+	require.NotNil(t, session1.TaprootInternalKey)
+	session2, err := w2.MuSig2CreateSession(
+		key2, signers, tweaks,
+		[][musig2.PubNonceSize]byte{session1.PublicNonce},
+	)
+	require.NoError(t, err)
+	require.True(t, session2.HaveAllNonces)
+	require.True(t, session1.CombinedKey.IsEqual(session2.CombinedKey))
+
+	haveAllNonces, err := w1.MuSig2RegisterNonces(
+		session1.SessionID,
+		[][musig2.PubNonceSize]byte{session2.PublicNonce},
+	)
+	require.NoError(t, err)
+	require.True(t, haveAllNonces)
+
+	// The second signer hands its partial signature to the first, which
+	// combines them.
+	msg := sha256.Sum256([]byte("message"))
+	sig1, err := w1.MuSig2Sign(session1.SessionID, msg, false)
+	require.NoError(t, err)
+	sig2, err := w2.MuSig2Sign(session2.SessionID, msg, true)
+	require.NoError(t, err)
+	require.ErrorIs(
+		t, w2.MuSig2Cleanup(session2.SessionID),
+		ErrMuSig2SessionNotFound,
+	)
+
+	// Sessions sign only once.
+	_, err = w1.MuSig2Sign(session1.SessionID, msg, false)
+	require.Error(t, err)
+
+	require.NotNil(t, sig1)
+	finalSig, done, err := w1.MuSig2CombineSig(
+		session1.SessionID, []*musig2.PartialSignature{sig2},
+	)
+	require.NoError(t, err)
+	require.True(t, done)
+	require.True(t, finalSig.Verify(msg[:], session1.CombinedKey))
+
+	_, _, err = w1.MuSig2CombineSig(session1.SessionID, nil)
+	require.ErrorIs(t, err, ErrMuSig2SessionNotFound)
+}
@@ -55,11 +55,12 @@ func lookupInputAccount(dbtx walletdb.ReadTx, w *Wallet, details *wtxmgr.TxDetai
 	prevOP := &details.MsgTx.TxIn[deb.Index].PreviousOutPoint
 	prev, err := w.TxStore.TxDetails(txmgrNs, &prevOP.Hash)
 	if err != nil {
-		log.Errorf("Cannot query previous transaction details for %v: %v", prevOP.Hash, err)
+		w.log.Errorf("Cannot query previous transaction details for "+
+			"%v: %v", prevOP.Hash, err)
 		return 0
 	}
 	if prev == nil {
-		log.Errorf("Missing previous transaction %v", prevOP.Hash)
+		w.log.Errorf("Missing previous transaction %v", prevOP.Hash)
 		return 0
 	}
 	prevOut := prev.MsgTx.TxOut[prevOP.Index]
@@ -69,7 +70,8 @@ func lookupInputAccount(dbtx walletdb.ReadTx, w *Wallet, details *wtxmgr.TxDetai
 		_, inputAcct, err = w.Manager.AddrAccount(addrmgrNs, addrs[0])
 	}
 	if err != nil {
-		log.Errorf("Cannot fetch account for previous output %v: %v", prevOP, err)
+		w.log.Errorf("Cannot fetch account for previous output %v: %v",
+			prevOP, err)
 		inputAcct = 0
 	}
 	return inputAcct
@@ -87,7 +89,7 @@ func lookupOutputChain(dbtx walletdb.ReadTx, w *Wallet, details *wtxmgr.TxDetail
 		ma, err = w.Manager.Address(addrmgrNs, addrs[0])
 	}
 	if err != nil {
-		log.Errorf("Cannot fetch account for wallet output: %v", err)
+		w.log.Errorf("Cannot fetch account for wallet output: %v", err)
 	} else {
 		account = ma.InternalAccount()
 		internal = ma.Internal()
@@ -101,7 +103,7 @@ func makeTxSummary(dbtx walletdb.ReadTx, w *Wallet, details *wtxmgr.TxDetails) T
 		var buf bytes.Buffer
 		err := details.MsgTx.Serialize(&buf)
 		if err != nil {
-			log.Errorf("Transaction serialization: %v", err)
+			w.log.Errorf("Transaction serialization: %v", err)
 		}
 		serializedTx = buf.Bytes()
 	}
@@ -198,8 +200,8 @@ func (s *NotificationServer) notifyUnminedTransaction(dbtx walletdb.ReadTx, deta
 	// Sanity check: should not be currently coalescing a notification for
 	// mined transactions at the same time that an unmined tx is notified.
 	if s.currentTxNtfn != nil {
-		log.Errorf("Notifying unmined tx notification (%s) while creating notification for blocks",
-			details.Hash)
+		s.wallet.log.Errorf("Notifying unmined tx notification (%s) "+
+			"while creating notification for blocks", details.Hash)
 	}
 
 	defer s.mu.Unlock()
@@ -212,14 +214,16 @@ func (s *NotificationServer) notifyUnminedTransaction(dbtx walletdb.ReadTx, deta
 	unminedTxs := []TransactionSummary{makeTxSummary(dbtx, s.wallet, details)}
 	unminedHashes, err := s.wallet.TxStore.UnminedTxHashes(dbtx.ReadBucket(wtxmgrNamespaceKey))
 	if err != nil {
-		log.Errorf("Cannot fetch unmined transaction hashes: %v", err)
+		s.wallet.log.Errorf("Cannot fetch unmined transaction hashes: "+
+			"%v", err)
 		return
 	}
 	bals := make(map[uint32]btcutil.Amount)
 	relevantAccounts(s.wallet, bals, unminedTxs)
 	err = totalBalances(dbtx, s.wallet, bals)
 	if err != nil {
-		log.Errorf("Cannot determine balances for relevant accounts: %v", err)
+		s.wallet.log.Errorf("Cannot determine balances for relevant "+
+			"accounts: %v", err)
 		return
 	}
 	n := &TransactionNotifications{
@@ -299,7 +303,8 @@ func (s *NotificationServer) notifyAttachedBlock(dbtx walletdb.ReadTx, block *wt
 	txmgrNs := dbtx.ReadBucket(wtxmgrNamespaceKey)
 	unminedHashes, err := s.wallet.TxStore.UnminedTxHashes(txmgrNs)
 	if err != nil {
-		log.Errorf("Cannot fetch unmined transaction hashes: %v", err)
+		s.wallet.log.Errorf("Cannot fetch unmined transaction hashes: "+
+			"%v", err)
 		return
 	}
 	s.currentTxNtfn.UnminedTransactionHashes = unminedHashes
@@ -310,7 +315,8 @@ func (s *NotificationServer) notifyAttachedBlock(dbtx walletdb.ReadTx, block *wt
 	}
 	err = totalBalances(dbtx, s.wallet, bals)
 	if err != nil {
-		log.Errorf("Cannot determine balances for relevant accounts: %v", err)
+		s.wallet.log.Errorf("Cannot determine balances for relevant "+
+			"accounts: %v", err)
 		return
 	}
 	s.currentTxNtfn.NewBalances = flattenBalanceMap(bals)
@@ -14,6 +14,7 @@ import (
 	"github.com/bisoncraft/utxowallet/wallet/txrules"
 	"github.com/bisoncraft/utxowallet/walletdb"
 	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/btcutil/psbt"
@@ -27,7 +28,8 @@ import (
 // the index of the change output is returned. If no custom change scope is
 // specified, we will use the coin selection scope (if not nil) or the BIP0086
 // scope by default. Otherwise, no additional output is created and the
-// index -1 is returned.
+// index -1 is returned. If feeSatPerKB is zero, the fee rate is estimated by
+// the chain backend.
 //
 // NOTE: If the packet doesn't contain any inputs, coin selection is performed
 // automatically, only selecting inputs from the account based on the given key
@@ -71,12 +73,19 @@ func (w *Wallet) FundPsbt(packet *psbt.Packet, keyScope *waddrmgr.KeyScope,
 		// effective fee rate to ensure accuracy. Otherwise, we may
 		// mistakenly mark small-ish, but not quite dust output as
 		// dust.
-		err := txrules.CheckOutput(output, txrules.DefaultRelayFeePerKb)
+		err := txrules.CheckOutput(
+			output, txrules.DefaultRelayFeePerKb, w.netParams,
+		)
 		if err != nil {
 			return 0, err
 		}
 	}
 
+	feeSatPerKB, err = w.feeRateOrEstimate(feeSatPerKB)
+	if err != nil {
+		return 0, err
+	}
+
 	// Let's find out the amount to fund first.
 	amt := int64(0)
 	for _, output := range txOut {
@@ -90,10 +99,15 @@ func (w *Wallet) FundPsbt(packet *psbt.Packet, keyScope *waddrmgr.KeyScope,
 		// We ask the underlying wallet to fund a TX for us. This
 		// includes everything we need, specifically fee estimation and
 		// change address creation.
+		// The funded PSBT is signed later on, so the inputs of
+		// watch-only accounts aren't handed to the external signer.
+		createOpts := append(
+			[]TxCreateOption{withoutExternalSigner()}, optFuncs...,
+		)
 		tx, err = w.CreateSimpleTx(
 			keyScope, account, packet.UnsignedTx.TxOut, minConfs,
 			feeSatPerKB, coinSelectionStrategy, false,
-			optFuncs...,
+			createOpts...,
 		)
 		if err != nil {
 			return 0, fmt.Errorf("error creating funding TX: %w",
@@ -162,6 +176,9 @@ func (w *Wallet) FundPsbt(packet *psbt.Packet, keyScope *waddrmgr.KeyScope,
 		for _, optFunc := range optFuncs {
 			optFunc(opts)
 		}
+		if opts.err != nil {
+			return 0, opts.err
+		}
 
 		if opts.changeKeyScope == nil {
 			opts.changeKeyScope = keyScope
@@ -181,7 +198,7 @@ func (w *Wallet) FundPsbt(packet *psbt.Packet, keyScope *waddrmgr.KeyScope,
 		// We also need a change source which needs to be able to insert
 		// a new change address into the database.
 		err = walletdb.Update(w.db, func(dbtx walletdb.ReadWriteTx) error {
-			_, changeSource, err := w.addrMgrWithChangeSource(
+			addrmgrNs, changeSource, err := w.addrMgrWithChangeSource(
 				dbtx, opts.changeKeyScope, account,
 			)
 			if err != nil {
@@ -191,8 +208,9 @@ func (w *Wallet) FundPsbt(packet *psbt.Packet, keyScope *waddrmgr.KeyScope,
 			// Ask the txauthor to create a transaction with our
 			// selected coins. This will perform fee estimation and
 			// add a change output if necessary.
-			tx, err = txauthor.NewUnsignedTransaction(
+			tx, err = txauthor.NewUnsignedTransactionWithInputSizes(
 				txOut, feeSatPerKB, inputSource, changeSource,
+				w.netParams, w.inputSizeEstimator(addrmgrNs),
 			)
 			if err != nil {
 				return fmt.Errorf("fee estimation not "+
@@ -217,16 +235,9 @@ func (w *Wallet) FundPsbt(packet *psbt.Packet, keyScope *waddrmgr.KeyScope,
 			packet.UnsignedTx.TxOut, changeTxOut,
 		)
 
-		addr, _, _, err := w.ScriptForOutput(changeTxOut)
-		if err != nil {
-			return 0, fmt.Errorf("error querying wallet for "+
-				"change addr: %w", err)
-		}
-
-		changeOutputInfo, err := createOutputInfo(changeTxOut, addr)
+		changeOutputInfo, err := w.changeOutputInfo(changeTxOut)
 		if err != nil {
-			return 0, fmt.Errorf("error adding output info to "+
-				"change output: %w", err)
+			return 0, err
 		}
 
 		packet.Outputs = append(packet.Outputs, *changeOutputInfo)
@@ -263,9 +274,47 @@ func (w *Wallet) DecorateInputs(packet *psbt.Packet, failOnUnknown bool) error {
 	for idx := range packet.Inputs {
 		txIn := packet.UnsignedTx.TxIn[idx]
 
-		tx, utxo, derivationPath, _, err := w.FetchInputInfo(
-			&txIn.PreviousOutPoint,
-		)
+		tx, utxo, _, err := w.FetchOutpointInfo(&txIn.PreviousOutPoint)
+
+		// The outputs of multisig accounts are spent with the keys of
+		// all cosigners, and the outputs of imported tapscripts
+		// through the script paths of their leaves.
+		if err == nil {
+			msAddr := w.fetchMultisigOutputAddr(utxo.PkScript)
+			if msAddr != nil {
+				err := addInputInfoMultisig(
+					&packet.Inputs[idx], tx, utxo, msAddr,
+				)
+				if err != nil {
+					return fmt.Errorf("error adding "+
+						"multisig input info: %w", err)
+				}
+				continue
+			}
+
+			tapscript, err := w.fetchTapscript(utxo.PkScript)
+			if err != nil {
+				return fmt.Errorf("error fetching tapscript: %w",
+					err)
+			}
+			if tapscript != nil {
+				err := addInputInfoTapscript(
+					&packet.Inputs[idx], utxo, tapscript,
+				)
+				if err != nil {
+					return fmt.Errorf("error adding "+
+						"tapscript input info: %w", err)
+				}
+				continue
+			}
+		}
+
+		var derivationPath *psbt.Bip32Derivation
+		if err == nil {
+			derivationPath, err = w.FetchDerivationInfo(
+				utxo.PkScript,
+			)
+		}
 
 		switch {
 		// If the error just means it's not an input our wallet controls
@@ -357,6 +406,70 @@ func addInputInfoSegWitV1(in *psbt.PInput, utxo *wire.TxOut,
 	}}
 }
 
+// addInputInfoTapscript adds the UTXO and the leaves revealable by the script
+// paths of an imported tapscript for a SegWit v1 PSBT input (p2tr).
+func addInputInfoTapscript(in *psbt.PInput, utxo *wire.TxOut,
+	tapscript *waddrmgr.Tapscript) error {
+
+	in.WitnessUtxo = &wire.TxOut{
+		Value:    utxo.Value,
+		PkScript: utxo.PkScript,
+	}
+	in.SighashType = txscript.SigHashDefault
+
+	in.TaprootLeafScript = nil
+	for _, leaf := range tapscript.RevealableLeaves() {
+		ctrlBlock, err := tapscript.LeafControlBlock(leaf)
+		if err != nil {
+			return err
+		}
+		ctrlBlockBytes, err := ctrlBlock.ToBytes()
+		if err != nil {
+			return err
+		}
+		in.TaprootLeafScript = append(
+			in.TaprootLeafScript, &psbt.TaprootTapLeafScript{
+				ControlBlock: ctrlBlockBytes,
+				Script:       leaf.Script,
+				LeafVersion:  leaf.LeafVersion,
+			},
+		)
+		in.TaprootInternalKey = schnorr.SerializePubKey(
+			ctrlBlock.InternalKey,
+		)
+	}
+	return nil
+}
+
+// changeOutputInfo creates the BIP32 derivation info for a change output of
+// the wallet.
+func (w *Wallet) changeOutputInfo(changeTxOut *wire.TxOut) (*psbt.POutput,
+	error) {
+
+	var (
+		changeOutputInfo *psbt.POutput
+		err              error
+	)
+	msAddr := w.fetchMultisigOutputAddr(changeTxOut.PkScript)
+	if msAddr != nil {
+		changeOutputInfo, err = createMultisigOutputInfo(msAddr)
+	} else {
+		var addr waddrmgr.ManagedPubKeyAddress
+		addr, _, _, err = w.ScriptForOutput(changeTxOut)
+		if err != nil {
+			return nil, fmt.Errorf("error querying wallet for "+
+				"change addr: %w", err)
+		}
+
+		changeOutputInfo, err = createOutputInfo(changeTxOut, addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error adding output info to change "+
+			"output: %w", err)
+	}
+	return changeOutputInfo, nil
+}
+
 // createOutputInfo creates the BIP32 derivation info for an output from our
 // internal wallet.
 func createOutputInfo(txOut *wire.TxOut,
@@ -412,6 +525,15 @@ func createOutputInfo(txOut *wire.TxOut,
 // will fail. If no error is returned, the PSBT is ready to be extracted and the
 // final TX within to be broadcast.
 //
+// Inputs of multisig accounts are signed by the wallet as a cosigner and are
+// finalized once they hold the signatures of enough cosigners. If any of them
+// still lacks signatures of other cosigners, ErrMultisigIncomplete is returned
+// and the packet, holding the signatures of the wallet, is to be passed on to
+// the other cosigners.
+//
+// Inputs of watch-only accounts are signed by the external signer set with
+// SetExternalSigner, if any.
+//
 // NOTE: This method does NOT publish the transaction after it's been finalized
 // successfully.
 func (w *Wallet) FinalizePsbt(keyScope *waddrmgr.KeyScope, account uint32,
@@ -431,6 +553,8 @@ func (w *Wallet) FinalizePsbt(keyScope *waddrmgr.KeyScope, account uint32,
 	// cannot sign because it's not our UTXO, this will be a hard failure.
 	tx := packet.UnsignedTx
 	sigHashes := txscript.NewTxSigHashes(tx, PsbtPrevOutputFetcher(packet))
+	multisigIncomplete := false
+	watchOnlyInputs := false
 	for idx, txIn := range tx.TxIn {
 		in := packet.Inputs[idx]
 
@@ -449,12 +573,24 @@ func (w *Wallet) FinalizePsbt(keyScope *waddrmgr.KeyScope, account uint32,
 		// We can only sign this input if it's ours, so we try to map it
 		// to a coin we own. If we can't, then we'll continue as it
 		// isn't our input.
-		fullTx, txOut, _, _, err := w.FetchInputInfo(
+		fullTx, txOut, _, err := w.FetchOutpointInfo(
 			&txIn.PreviousOutPoint,
 		)
 		if err != nil {
 			continue
 		}
+		msAddr := w.fetchMultisigOutputAddr(txOut.PkScript)
+		if msAddr == nil {
+			_, err = w.FetchDerivationInfo(txOut.PkScript)
+		}
+		if err != nil {
+			// Outputs of imported tapscripts have no derivation
+			// info, but are signed through their script paths.
+			tapscript, tsErr := w.fetchTapscript(txOut.PkScript)
+			if tsErr != nil || tapscript == nil {
+				continue
+			}
+		}
 
 		// Find out what UTXO we are signing. Wallets _should_ always
 		// provide the full non-witness UTXO for segwit v0.
@@ -488,6 +624,21 @@ func (w *Wallet) FinalizePsbt(keyScope *waddrmgr.KeyScope, account uint32,
 			}
 		}
 
+		// Inputs of multisig accounts are signed with the key of the
+		// wallet as a cosigner, and finalized once enough cosigners
+		// signed them.
+		if msAddr != nil {
+			incomplete, err := signMultisigInput(
+				packet, idx, signOutput, sigHashes, msAddr,
+			)
+			if err != nil {
+				return fmt.Errorf("error signing multisig "+
+					"input %d: %w", idx, err)
+			}
+			multisigIncomplete = multisigIncomplete || incomplete
+			continue
+		}
+
 		// Finally, if the input doesn't belong to a watch-only account,
 		// then we'll sign it as is, and populate the input with the
 		// witness and sigScript (if needed).
@@ -516,6 +667,7 @@ func (w *Wallet) FinalizePsbt(keyScope *waddrmgr.KeyScope, account uint32,
 				"watch-only: %w", err)
 		}
 		if watchOnly {
+			watchOnlyInputs = true
 			continue
 		}
 
@@ -538,6 +690,18 @@ func (w *Wallet) FinalizePsbt(keyScope *waddrmgr.KeyScope, account uint32,
 		packet.Inputs[idx].FinalScriptSig = sigScript
 	}
 
+	// Inputs of watch-only accounts are signed by the external signer of
+	// the wallet, if it has one.
+	if signer := w.currentExternalSigner(); watchOnlyInputs && signer != nil {
+		if err := signPsbtExternally(signer, packet); err != nil {
+			return err
+		}
+	}
+
+	if multisigIncomplete {
+		return ErrMultisigIncomplete
+	}
+
 	// Make sure the PSBT itself thinks it's finalized and ready to be
 	// broadcast.
 	err = psbt.MaybeFinalizeAll(packet)
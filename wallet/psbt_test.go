@@ -272,7 +272,7 @@ func TestFundPsbt(t *testing.T) {
 			packet.UnsignedTx.TxOut, 0,
 		)
 		return txrules.FeeForSerializeSize(
-			feeRateSatPerKB, estimatedSize,
+			feeRateSatPerKB, estimatedSize, w.netParams,
 		)
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 )
 
 // RecoveryManager maintains the state required to recover previously used
@@ -35,6 +36,9 @@ type RecoveryManager struct {
 	// chainParams are the parameters that describe the chain we're trying
 	// to recover funds on.
 	chainParams *chaincfg.Params
+
+	// log is the logger the start of the recovery is reported to.
+	log btclog.Logger
 }
 
 // NewRecoveryManager initializes a new RecoveryManager with a derivation
@@ -48,6 +52,7 @@ func NewRecoveryManager(recoveryWindow, batchSize uint32,
 		blockBatch:     make([]wtxmgr.BlockMeta, 0, batchSize),
 		chainParams:    chainParams,
 		state:          NewRecoveryState(recoveryWindow),
+		log:            log,
 	}
 }
 
@@ -149,7 +154,7 @@ func (rm *RecoveryManager) AddToBlockBatch(hash *chainhash.Hash, height int32,
 	timestamp time.Time) {
 
 	if !rm.started {
-		log.Infof("Seed birthday surpassed, starting recovery "+
+		rm.log.Infof("Seed birthday surpassed, starting recovery "+
 			"of wallet from height=%d hash=%v with "+
 			"recovery-window=%d", height, *hash, rm.recoveryWindow)
 		rm.started = true
@@ -139,7 +139,7 @@ func (w *Wallet) rescanBatchHandler() {
 			switch n := n.(type) {
 			case *chain.RescanProgress:
 				if curBatch == nil {
-					log.Warnf("Received rescan progress " +
+					w.log.Warnf("Received rescan progress " +
 						"notification but no rescan " +
 						"currently running")
 					continue
@@ -158,7 +158,7 @@ func (w *Wallet) rescanBatchHandler() {
 
 			case *chain.RescanFinished:
 				if curBatch == nil {
-					log.Warnf("Received rescan finished " +
+					w.log.Warnf("Received rescan finished " +
 						"notification but no rescan " +
 						"currently running")
 					continue
@@ -211,16 +211,16 @@ out:
 		select {
 		case msg := <-w.rescanProgress:
 			n := msg.Notification
-			log.Infof("Rescanned through block %v (height %d)",
+			w.log.Infof("Rescanned through block %v (height %d)",
 				n.Hash, n.Height)
 
 		case msg := <-w.rescanFinished:
 			n := msg.Notification
 			addrs := msg.Addresses
 			noun := pickNoun(len(addrs), "address", "addresses")
-			log.Infof("Finished rescan for %d %s (synced to block "+
-				"%s, height %d)", len(addrs), noun, n.Hash,
-				n.Height)
+			w.log.Infof("Finished rescan for %d %s (synced to "+
+				"block %s, height %d)", len(addrs), noun,
+				n.Hash, n.Height)
 
 			go w.resendUnminedTxs()
 
@@ -237,7 +237,7 @@ out:
 func (w *Wallet) rescanRPCHandler() {
 	chainClient, err := w.requireChainClient()
 	if err != nil {
-		log.Errorf("rescanRPCHandler called without an RPC client")
+		w.log.Errorf("rescanRPCHandler called without an RPC client")
 		w.wg.Done()
 		return
 	}
@@ -251,14 +251,15 @@ out:
 			// Log the newly-started rescan.
 			numAddrs := len(batch.addrs)
 			noun := pickNoun(numAddrs, "address", "addresses")
-			log.Infof("Started rescan from block %v (height %d) for %d %s",
-				batch.bs.Hash, batch.bs.Height, numAddrs, noun)
+			w.log.Infof("Started rescan from block %v (height %d) "+
+				"for %d %s", batch.bs.Hash, batch.bs.Height,
+				numAddrs, noun)
 
 			err := chainClient.Rescan(&batch.bs.Hash, batch.addrs,
 				batch.outpoints)
 			if err != nil {
-				log.Errorf("Rescan for %d %s failed: %v", numAddrs,
-					noun, err)
+				w.log.Errorf("Rescan for %d %s failed: %v",
+					numAddrs, noun, err)
 			}
 			batch.done(err)
 		case <-quit:
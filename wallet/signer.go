@@ -5,15 +5,27 @@
 package wallet
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/walletdb"
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 )
 
+// ErrNoTapscriptSpend is returned when an output of an imported tapscript is
+// to be signed, but none of the leaves of its script tree are satisfied by the
+// signatures of wallet keys alone.
+var ErrNoTapscriptSpend = errors.New("no leaf of the tapscript can be " +
+	"signed by the wallet")
+
 // ScriptForOutput returns the address, witness program and redeem script for a
 // given UTXO. An error is returned if the UTXO does not belong to our wallet or
 // it is not a managed pubKey address.
@@ -87,11 +99,27 @@ type PrivKeyTweaker func(*btcec.PrivateKey) (*btcec.PrivateKey, error)
 // transaction with the signature as defined within the passed SignDescriptor.
 // This method is capable of generating the proper input script for both
 // regular p2wkh output and p2wkh outputs nested within a regular p2sh output.
+// Outputs of imported tapscripts are spent through the script path of a leaf
+// signed by wallet keys, in which case the tweaker is not used.
 func (w *Wallet) ComputeInputScript(tx *wire.MsgTx, output *wire.TxOut,
 	inputIndex int, sigHashes *txscript.TxSigHashes,
 	hashType txscript.SigHashType, tweaker PrivKeyTweaker) (wire.TxWitness,
 	[]byte, error) {
 
+	if txscript.IsPayToTaproot(output.PkScript) {
+		spend, err := w.fetchTapscriptSpend(output.PkScript)
+		if err != nil {
+			return nil, nil, err
+		}
+		if spend != nil {
+			witness, err := spend.Witness(
+				tx, sigHashes, inputIndex, output.Value,
+				output.PkScript, hashType,
+			)
+			return witness, nil, err
+		}
+	}
+
 	walletAddr, witnessProgram, sigScript, err := w.ScriptForOutput(output)
 	if err != nil {
 		return nil, nil, err
@@ -136,3 +164,240 @@ func (w *Wallet) ComputeInputScript(tx *wire.MsgTx, output *wire.TxOut,
 
 	return witnessScript, sigScript, nil
 }
+
+// fetchTapscript returns the tapscript the taproot output script commits to if
+// it pays to an imported tapscript, or nil otherwise.
+func (w *Wallet) fetchTapscript(pkScript []byte) (*waddrmgr.Tapscript, error) {
+	var tapscript *waddrmgr.Tapscript
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		var err error
+		tapscript, err = outputTapscript(w.Manager, ns, pkScript)
+		return err
+	})
+	return tapscript, err
+}
+
+// fetchTapscriptSpend returns the script path spend of the taproot output
+// script if it pays to an imported tapscript, or nil otherwise.
+func (w *Wallet) fetchTapscriptSpend(
+	pkScript []byte) (*txauthor.TapscriptSpend, error) {
+
+	var spend *txauthor.TapscriptSpend
+	err := walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(waddrmgrNamespaceKey)
+		tapscript, err := outputTapscript(w.Manager, ns, pkScript)
+		if err != nil || tapscript == nil {
+			return err
+		}
+		spend, err = tapscriptSpend(w.Manager, ns, tapscript)
+		return err
+	})
+	return spend, err
+}
+
+// outputTapscript returns the tapscript the taproot output script commits to
+// if it pays to an imported tapscript, or nil otherwise.
+func outputTapscript(mgr *waddrmgr.Manager, ns walletdb.ReadBucket,
+	pkScript []byte) (*waddrmgr.Tapscript, error) {
+
+	if !txscript.IsPayToTaproot(pkScript) {
+		return nil, nil
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		pkScript, mgr.ChainParams(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	managedAddr, err := mgr.Address(ns, addrs[0])
+	switch {
+	case waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound):
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+	}
+	tapscriptAddr, ok := managedAddr.(waddrmgr.ManagedTaprootScriptAddress)
+	if !ok {
+		return nil, nil
+	}
+	return tapscriptAddr.TaprootScript()
+}
+
+// tapscriptSpend returns the script path spend of the taproot output of the
+// tapscript through the first of its revealable leaves that the wallet holds
+// enough keys to sign.
+func tapscriptSpend(mgr *waddrmgr.Manager, ns walletdb.ReadBucket,
+	tapscript *waddrmgr.Tapscript) (*txauthor.TapscriptSpend, error) {
+
+	for _, leaf := range tapscript.RevealableLeaves() {
+		keys, threshold, ok := tapscriptLeafKeys(leaf.Script)
+		if !ok {
+			continue
+		}
+
+		// Exactly the threshold of keys sign, the others leave their
+		// signature empty.
+		privKeys := make([]*btcec.PrivateKey, len(keys))
+		numSigs := 0
+		for i := 0; i < len(keys) && numSigs < threshold; i++ {
+			privKey, err := xOnlyPrivKey(
+				mgr, ns, keys[i], mgr.ChainParams(),
+			)
+			if err != nil {
+				return nil, err
+			}
+			if privKey != nil {
+				privKeys[i] = privKey
+				numSigs++
+			}
+		}
+		if numSigs < threshold {
+			continue
+		}
+
+		ctrlBlock, err := tapscript.LeafControlBlock(leaf)
+		if err != nil {
+			return nil, err
+		}
+		return &txauthor.TapscriptSpend{
+			Leaf:         leaf,
+			ControlBlock: ctrlBlock,
+			Keys:         privKeys,
+		}, nil
+	}
+
+	return nil, ErrNoTapscriptSpend
+}
+
+// tapscriptLeafKeys returns the x-only keys checked by a leaf script that is
+// satisfied by signatures alone, along with the number of signatures required.
+// Such scripts either check a single key with OP_CHECKSIG, or count the valid
+// signatures of several keys with OP_CHECKSIGADD:
+//
+//	<key> OP_CHECKSIG [<key> OP_CHECKSIGADD ...] [<threshold> OP_NUMEQUAL]
+//
+// False is returned for other scripts.
+func tapscriptLeafKeys(script []byte) ([][]byte, int, bool) {
+	type scriptToken struct {
+		op   byte
+		data []byte
+	}
+	var tokens []scriptToken
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	for tokenizer.Next() {
+		tokens = append(tokens, scriptToken{
+			op:   tokenizer.Opcode(),
+			data: tokenizer.Data(),
+		})
+	}
+	if tokenizer.Err() != nil || len(tokens) < 2 || len(tokens)%2 != 0 {
+		return nil, 0, false
+	}
+
+	// A single key may be checked without a threshold.
+	numKeys := len(tokens)/2 - 1
+	if len(tokens) == 2 {
+		numKeys = 1
+	}
+
+	keys := make([][]byte, 0, numKeys)
+	for i := 0; i < numKeys; i++ {
+		key, checkSig := tokens[2*i], tokens[2*i+1]
+		wantOp := byte(txscript.OP_CHECKSIGADD)
+		if i == 0 {
+			wantOp = txscript.OP_CHECKSIG
+		}
+		if len(key.data) != schnorr.PubKeyBytesLen ||
+			checkSig.op != wantOp {
+
+			return nil, 0, false
+		}
+		keys = append(keys, key.data)
+	}
+	if len(tokens) == 2 {
+		return keys, 1, true
+	}
+
+	thresholdPush, numEqual := tokens[2*numKeys], tokens[2*numKeys+1]
+	threshold, ok := scriptSmallInt(thresholdPush.op, thresholdPush.data)
+	if !ok || threshold < 1 || threshold > numKeys ||
+		numEqual.op != txscript.OP_NUMEQUAL {
+
+		return nil, 0, false
+	}
+	return keys, threshold, true
+}
+
+// scriptSmallInt returns the positive number pushed by a small integer opcode
+// or minimally encoded data push of at most two bytes.
+func scriptSmallInt(op byte, data []byte) (int, bool) {
+	switch {
+	case op >= txscript.OP_1 && op <= txscript.OP_16:
+		return int(op-txscript.OP_1) + 1, true
+
+	case len(data) > 0 && len(data) <= 2 && data[len(data)-1]&0x80 == 0:
+		n := 0
+		for i := len(data) - 1; i >= 0; i-- {
+			n = n<<8 | int(data[i])
+		}
+		return n, true
+
+	default:
+		return 0, false
+	}
+}
+
+// xOnlyPrivKey returns the private key of the wallet for an x-only key, such as
+// the keys of leaf scripts, or nil if it is not the key of a wallet address.  Keys of
+// P2PKH and P2WPKH addresses are found through the hash of either of their
+// compressed serializations, and keys of P2TR addresses through their BIP-0086
+// output key.
+func xOnlyPrivKey(mgr *waddrmgr.Manager, ns walletdb.ReadBucket,
+	xOnlyKey []byte, params *chaincfg.Params) (*btcec.PrivateKey, error) {
+
+	pubKey, err := schnorr.ParsePubKey(xOnlyKey)
+	if err != nil {
+		return nil, nil
+	}
+
+	var addrs []btcutil.Address
+	for _, format := range []byte{0x02, 0x03} {
+		serialized := append([]byte{format}, xOnlyKey...)
+		addr, err := btcutil.NewAddressPubKeyHash(
+			btcutil.Hash160(serialized), params,
+		)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	outputKey := txscript.ComputeTaprootKeyNoScript(pubKey)
+	addr, err := btcutil.NewAddressTaproot(
+		schnorr.SerializePubKey(outputKey), params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	addrs = append(addrs, addr)
+
+	for _, addr := range addrs {
+		managedAddr, err := mgr.Address(ns, addr)
+		if err != nil {
+			continue
+		}
+		pubKeyAddr, ok := managedAddr.(waddrmgr.ManagedPubKeyAddress)
+		if !ok {
+			continue
+		}
+		if !bytes.Equal(
+			schnorr.SerializePubKey(pubKeyAddr.PubKey()), xOnlyKey,
+		) {
+
+			continue
+		}
+		return pubKeyAddr.PrivKey()
+	}
+	return nil, nil
+}
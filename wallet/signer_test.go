@@ -8,9 +8,14 @@ import (
 	"testing"
 
 	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
 )
 
 // TestComputeInputScript checks that the wallet can create the full
@@ -107,3 +112,120 @@ func runTestCase(t *testing.T, w *Wallet, scope waddrmgr.KeyScope,
 		t.Fatalf("error validating tx: %v", err)
 	}
 }
+
+// TestTapscriptSpend tests that outputs of imported tapscripts are spent
+// through the script path of a leaf signed by wallet keys.
+func TestTapscriptSpend(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	// walletKey returns the key of a new address of the key scope.
+	walletKey := func(scope waddrmgr.KeyScope) []byte {
+		addr, err := w.NewAddress(0, scope)
+		require.NoError(t, err)
+		var pubKey *btcec.PublicKey
+		err = walletdb.View(w.db, func(tx walletdb.ReadTx) error {
+			ns := tx.ReadBucket(waddrmgrNamespaceKey)
+			managedAddr, err := w.Manager.Address(ns, addr)
+			if err != nil {
+				return err
+			}
+			pubKey = managedAddr.(waddrmgr.ManagedPubKeyAddress).
+				PubKey()
+			return nil
+		})
+		require.NoError(t, err)
+		return schnorr.SerializePubKey(pubKey)
+	}
+	foreignKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	foreignPubKey := schnorr.SerializePubKey(foreignKey.PubKey())
+
+	// The first leaf is signed by a foreign key only, the second by two
+	// out of a foreign key and two wallet keys.
+	foreignLeaf, err := txscript.NewScriptBuilder().
+		AddData(foreignPubKey).AddOp(txscript.OP_CHECKSIG).Script()
+	require.NoError(t, err)
+	multiLeaf, err := txscript.NewScriptBuilder().
+		AddData(walletKey(waddrmgr.KeyScopeBIP0084)).
+		AddOp(txscript.OP_CHECKSIG).
+		AddData(foreignPubKey).AddOp(txscript.OP_CHECKSIGADD).
+		AddData(walletKey(waddrmgr.KeyScopeBIP0086)).
+		AddOp(txscript.OP_CHECKSIGADD).
+		AddInt64(2).AddOp(txscript.OP_NUMEQUAL).Script()
+	require.NoError(t, err)
+
+	keys, threshold, ok := tapscriptLeafKeys(multiLeaf)
+	require.True(t, ok)
+	require.Len(t, keys, 3)
+	require.Equal(t, 2, threshold)
+	_, _, ok = tapscriptLeafKeys(multiLeaf[:len(multiLeaf)-1])
+	require.False(t, ok)
+
+	tapscript := &waddrmgr.Tapscript{
+		Type: waddrmgr.TapscriptTypeFullTree,
+		ControlBlock: &txscript.ControlBlock{
+			InternalKey: foreignKey.PubKey(),
+			LeafVersion: txscript.BaseLeafVersion,
+		},
+		Leaves: []txscript.TapLeaf{
+			txscript.NewBaseTapLeaf(foreignLeaf),
+			txscript.NewBaseTapLeaf(multiLeaf),
+		},
+	}
+	addr, err := w.ImportTaprootScript(
+		waddrmgr.KeyScopeBIP0086, tapscript, nil, 1, false,
+	)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr.Address())
+	require.NoError(t, err)
+
+	utxo := wire.NewTxOut(100000, pkScript)
+	incomingTx := &wire.MsgTx{
+		TxIn:  []*wire.TxIn{{}},
+		TxOut: []*wire.TxOut{utxo},
+	}
+	addUtxo(t, w, incomingTx)
+
+	// The input script is computed for the output.
+	tx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: wire.OutPoint{
+				Hash: incomingTx.TxHash(),
+			},
+		}},
+		TxOut: []*wire.TxOut{wire.NewTxOut(90000, pkScript)},
+	}
+	fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, utxo.Value)
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	witness, sigScript, err := w.ComputeInputScript(
+		tx, utxo, 0, sigHashes, txscript.SigHashDefault, nil,
+	)
+	require.NoError(t, err)
+	require.Nil(t, sigScript)
+	require.Equal(t, multiLeaf, witness[len(witness)-2])
+	tx.TxIn[0].Witness = witness
+	err = validateMsgTx(
+		tx, [][]byte{pkScript}, []btcutil.Amount{100000},
+	)
+	require.NoError(t, err)
+
+	// Input scripts added by txauthor spend through the script path as
+	// well.
+	tx.TxIn[0].Witness = nil
+	err = walletdb.View(w.db, func(dbtx walletdb.ReadTx) error {
+		ns := dbtx.ReadBucket(waddrmgrNamespaceKey)
+		return txauthor.AddAllInputScripts(
+			tx, [][]byte{pkScript}, []btcutil.Amount{100000},
+			secretSource{w.Manager, ns},
+		)
+	})
+	require.NoError(t, err)
+	require.Len(t, tx.TxIn[0].Witness, 5)
+	err = validateMsgTx(
+		tx, [][]byte{pkScript}, []btcutil.Amount{100000},
+	)
+	require.NoError(t, err)
+}
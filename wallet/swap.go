@@ -0,0 +1,305 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/chain"
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/swap"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrSwapInsufficientValue is returned when the value of a swap
+	// contract is too low to pay the fee of the transaction spending it.
+	ErrSwapInsufficientValue = errors.New("contract value too low to pay " +
+		"the fee")
+
+	// ErrNoSpendFinder is returned when the spend of a swap contract is
+	// to be found, but the chain backend of the wallet is not able to scan
+	// the chain for spends.
+	ErrNoSpendFinder = errors.New("chain backend does not find spends")
+)
+
+// SwapKey returns the public key of a new external address of the BIP0084
+// account provided, to be used as the recipient or refund key of a swap
+// contract.  The wallet can only sign the spends of contracts using the keys
+// of its BIP0084 accounts.
+func (w *Wallet) SwapKey(account uint32) (*btcec.PublicKey, error) {
+	addr, err := w.NewAddress(account, waddrmgr.KeyScopeBIP0084)
+	if err != nil {
+		return nil, err
+	}
+
+	var pubKey *btcec.PublicKey
+	err = walletdb.View(w.db, func(dbtx walletdb.ReadTx) error {
+		addrmgrNs := dbtx.ReadBucket(waddrmgrNamespaceKey)
+		managedAddr, err := w.Manager.Address(addrmgrNs, addr)
+		if err != nil {
+			return err
+		}
+		pubKey = managedAddr.(waddrmgr.ManagedPubKeyAddress).PubKey()
+		return nil
+	})
+	return pubKey, err
+}
+
+// FundSwapContract creates, signs and publishes a transaction paying the value
+// provided to the swap contract, funded by outputs of the account with at
+// least minconf confirmations.  The transaction is returned along with the
+// outpoint of the contract output.
+func (w *Wallet) FundSwapContract(c *swap.Contract, value btcutil.Amount,
+	account uint32, minconf int32, feeSatPerKb btcutil.Amount,
+	label string) (*wire.MsgTx, wire.OutPoint, error) {
+
+	pkScript, err := c.PkScript()
+	if err != nil {
+		return nil, wire.OutPoint{}, err
+	}
+	tx, err := w.SendOutputs(
+		[]*wire.TxOut{wire.NewTxOut(int64(value), pkScript)}, nil,
+		account, minconf, feeSatPerKb, CoinSelectionLargest, label,
+	)
+	if err != nil {
+		return nil, wire.OutPoint{}, err
+	}
+
+	for i, txOut := range tx.TxOut {
+		if bytes.Equal(txOut.PkScript, pkScript) {
+			op := wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)}
+			return tx, op, nil
+		}
+	}
+	return nil, wire.OutPoint{}, errors.New("contract output not found")
+}
+
+// RedeemSwap creates, signs and publishes a transaction redeeming the swap
+// contract output with the secret.  The recipient key of the contract must be
+// a wallet key, and the value of the contract, less the fee, is paid to a new
+// change address of its account.  If the fee rate is zero, it is estimated by
+// the chain backend.
+func (w *Wallet) RedeemSwap(c *swap.Contract, op wire.OutPoint,
+	value btcutil.Amount, secret []byte, feeSatPerKb btcutil.Amount,
+	label string) (*wire.MsgTx, error) {
+
+	if secret == nil {
+		return nil, swap.ErrInvalidSecret
+	}
+	return w.spendSwap(c, op, value, secret, feeSatPerKb, label)
+}
+
+// RefundSwap creates, signs and publishes a transaction refunding the swap
+// contract output.  The refund key of the contract must be a wallet key, and
+// the value of the contract, less the fee, is paid to a new change address of
+// its account.  The transaction can only be mined once the lock time of the
+// contract has passed.  If the fee rate is zero, it is estimated by the chain
+// backend.
+func (w *Wallet) RefundSwap(c *swap.Contract, op wire.OutPoint,
+	value btcutil.Amount, feeSatPerKb btcutil.Amount,
+	label string) (*wire.MsgTx, error) {
+
+	return w.spendSwap(c, op, value, nil, feeSatPerKb, label)
+}
+
+// spendSwap publishes a transaction redeeming the swap contract output with
+// the secret or, if the secret is nil, refunding it.
+func (w *Wallet) spendSwap(c *swap.Contract, op wire.OutPoint,
+	value btcutil.Amount, secret []byte, feeSatPerKb btcutil.Amount,
+	label string) (*wire.MsgTx, error) {
+
+	feeSatPerKb, err := w.feeRateOrEstimate(feeSatPerKb)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *wire.MsgTx
+	err = w.sweep(func(dbtx walletdb.ReadWriteTx) error {
+		var err error
+		tx, err = w.swapSpendTx(
+			dbtx, c, op, value, secret, feeSatPerKb,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.reliablyPublishTransaction(tx, label); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// swapSpendTx creates and signs the transaction spending a swap contract for
+// spendSwap.
+func (w *Wallet) swapSpendTx(dbtx walletdb.ReadWriteTx, c *swap.Contract,
+	op wire.OutPoint, value btcutil.Amount, secret []byte,
+	feeSatPerKb btcutil.Amount) (*wire.MsgTx, error) {
+
+	addrmgrNs := dbtx.ReadWriteBucket(waddrmgrNamespaceKey)
+
+	// The key signing the spend is looked up through its P2WPKH address.
+	key := c.RefundKey
+	if secret != nil {
+		key = c.RecipientKey
+	}
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(key.SerializeCompressed()), w.chainParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+	managedAddr, err := w.Manager.Address(addrmgrNs, addr)
+	if err != nil {
+		return nil, fmt.Errorf("contract key is not a wallet key: %w",
+			err)
+	}
+	pubKeyAddr, ok := managedAddr.(waddrmgr.ManagedPubKeyAddress)
+	if !ok {
+		return nil, fmt.Errorf("contract key address %v is not a "+
+			"pubkey address", addr)
+	}
+	privKey, err := pubKeyAddr.PrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	scopedMgr, account, err := w.Manager.AddrAccount(addrmgrNs, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	witnessSize, err := c.RefundWitnessSize()
+	if secret != nil {
+		witnessSize, err = c.RedeemWitnessSize()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The witness, along with the segwit marker and flag, is discounted
+	// from the virtual size.
+	spendFee := func(tx *wire.MsgTx) btcutil.Amount {
+		weight := tx.SerializeSizeStripped()*
+			blockchain.WitnessScaleFactor + 2 + witnessSize
+		vsize := (weight + blockchain.WitnessScaleFactor - 1) /
+			blockchain.WitnessScaleFactor
+		return txrules.FeeForSerializeSize(
+			feeSatPerKb, vsize, w.netParams,
+		)
+	}
+
+	tx, err := w.sweepTx(dbtx, &outputSweep{
+		op:                   op,
+		value:                value,
+		scope:                scopedMgr.Scope(),
+		account:              account,
+		fee:                  spendFee,
+		errInsufficientValue: ErrSwapInsufficientValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Refunds are only valid once the lock time of the contract has
+	// passed.
+	if secret == nil {
+		tx.LockTime = c.LockTime
+	}
+
+	pkScript, err := c.PkScript()
+	if err != nil {
+		return nil, err
+	}
+	prevOuts := txscript.NewCannedPrevOutputFetcher(pkScript, int64(value))
+	if secret != nil {
+		tx.TxIn[0].Witness, err = c.SignRedeem(
+			tx, 0, prevOuts, secret, privKey,
+		)
+	} else {
+		tx.TxIn[0].Witness, err = c.SignRefund(tx, 0, prevOuts, privKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateMsgTx(
+		tx, [][]byte{pkScript}, []btcutil.Amount{value},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// SwapSpend is a transaction spending a swap contract.
+type SwapSpend struct {
+	// Tx is the transaction spending the contract.
+	Tx *wire.MsgTx
+
+	// InputIndex is the index of the input spending the contract.
+	InputIndex uint32
+
+	// Height is the height of the block mining the transaction.
+	Height uint32
+
+	// Secret is the secret revealed by the transaction, or nil if the
+	// contract was refunded.
+	Secret []byte
+}
+
+// FindSwapSpend scans the chain, starting at the height hint, for the
+// transaction spending the swap contract output.  The height hint should be
+// no later than the height of the block mining the contract.  Whether the
+// contract was redeemed, and if so the secret revealed by the recipient, is
+// returned along with the transaction.  Nil is returned if the contract is
+// not spent.
+func (w *Wallet) FindSwapSpend(c *swap.Contract, op wire.OutPoint,
+	heightHint int32) (*SwapSpend, error) {
+
+	chainClient, err := w.requireChainClient()
+	if err != nil {
+		return nil, err
+	}
+	finder, ok := chainClient.(chain.SpendFinder)
+	if !ok {
+		return nil, ErrNoSpendFinder
+	}
+
+	pkScript, err := c.PkScript()
+	if err != nil {
+		return nil, err
+	}
+	report, err := finder.FindSpend(op, pkScript, heightHint)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil || report.SpendingTx == nil {
+		return nil, nil
+	}
+
+	spend := &SwapSpend{
+		Tx:         report.SpendingTx,
+		InputIndex: report.SpendingInputIndex,
+		Height:     report.SpendingTxHeight,
+	}
+	if int(spend.InputIndex) >= len(spend.Tx.TxIn) {
+		return nil, fmt.Errorf("spending transaction %v has no input %d",
+			spend.Tx.TxHash(), spend.InputIndex)
+	}
+	witness := spend.Tx.TxIn[spend.InputIndex].Witness
+	secret, err := swap.ExtractSecret(witness, c.SecretHash)
+	if err == nil {
+		spend.Secret = secret
+	}
+	return spend, nil
+}
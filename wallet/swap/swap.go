@@ -0,0 +1,553 @@
+// Package swap provides the scripts and witnesses of the hash time locked
+// contracts used in atomic swaps.
+//
+// A contract pays to a recipient able to reveal the preimage of a secret hash
+// or, once its lock time has passed, back to the party which funded it.  The
+// contract is either a P2WSH output, or a taproot output with a key path that
+// can not be spent and one script leaf for each of the two branches.
+package swap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// SecretSize is the size of the secret revealed when redeeming a contract.
+const SecretSize = 32
+
+// ContractType is the type of output paying to a contract.
+type ContractType uint8
+
+const (
+	// ContractP2WSH is a contract paid to the P2WSH output of its script.
+	ContractP2WSH ContractType = iota
+
+	// ContractTaproot is a contract paid to a taproot output committing to
+	// a redeem leaf and a refund leaf.
+	ContractTaproot
+)
+
+// String returns the name of the contract type.
+func (t ContractType) String() string {
+	switch t {
+	case ContractP2WSH:
+		return "p2wsh"
+	case ContractTaproot:
+		return "taproot"
+	default:
+		return fmt.Sprintf("unknown contract type %d", t)
+	}
+}
+
+var (
+	// ErrInvalidSecret is returned when a secret does not hash to the
+	// secret hash of a contract.
+	ErrInvalidSecret = errors.New("secret does not match the secret hash")
+
+	// ErrNotContract is returned when parsing a script which is not a
+	// swap contract.
+	ErrNotContract = errors.New("script is not a swap contract")
+
+	// ErrNoSecret is returned when a witness does not reveal the secret
+	// of a contract.
+	ErrNoSecret = errors.New("witness does not reveal the secret")
+)
+
+// numsKeyHex is the x coordinate of the point H suggested by BIP-0341,
+// for which no private key is known.  It is the internal key of taproot
+// contracts, so they can only be spent through one of their script leaves.
+const numsKeyHex = "50929b74c1a04954b78b4b6035e97a5e" +
+	"078a5a0f28ec96d547bfee9ace803ac0"
+
+// NUMSKey returns the internal key of taproot contracts, for which no private
+// key is known.
+func NUMSKey() *btcec.PublicKey {
+	keyBytes, _ := hex.DecodeString(numsKeyHex)
+	key, err := schnorr.ParsePubKey(keyBytes)
+	if err != nil {
+		panic(fmt.Sprintf("invalid NUMS key: %v", err))
+	}
+	return key
+}
+
+// NewSecret returns a new random secret and its hash.
+func NewSecret() (secret []byte, secretHash [32]byte, err error) {
+	secret = make([]byte, SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, secretHash, err
+	}
+	return secret, sha256.Sum256(secret), nil
+}
+
+// Contract is a hash time locked contract.
+type Contract struct {
+	// Type is the type of output paying to the contract.
+	Type ContractType
+
+	// SecretHash is the SHA256 hash of the secret revealed by the
+	// recipient to redeem the contract.
+	SecretHash [32]byte
+
+	// RecipientKey is the key of the recipient, which can redeem the
+	// contract with the secret.
+	RecipientKey *btcec.PublicKey
+
+	// RefundKey is the key of the funder of the contract, which can
+	// refund it once the lock time has passed.
+	RefundKey *btcec.PublicKey
+
+	// LockTime is the block height or time, as interpreted for the lock
+	// time of transactions, after which the contract can be refunded.
+	LockTime uint32
+}
+
+// Script returns the witness script of a P2WSH contract:
+//
+//	OP_IF
+//	    OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <secret hash> OP_EQUALVERIFY
+//	    <recipient key>
+//	OP_ELSE
+//	    <lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP
+//	    <refund key>
+//	OP_ENDIF
+//	OP_CHECKSIG
+func (c *Contract) Script() ([]byte, error) {
+	if c.Type != ContractP2WSH {
+		return nil, fmt.Errorf("%v contract has no witness script",
+			c.Type)
+	}
+
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_IF).
+		AddOp(txscript.OP_SIZE).
+		AddInt64(SecretSize).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_SHA256).
+		AddData(c.SecretHash[:]).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddData(c.RecipientKey.SerializeCompressed()).
+		AddOp(txscript.OP_ELSE).
+		AddInt64(int64(c.LockTime)).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP).
+		AddData(c.RefundKey.SerializeCompressed()).
+		AddOp(txscript.OP_ENDIF).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// RedeemLeaf returns the leaf of a taproot contract spent by the recipient:
+//
+//	OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <secret hash> OP_EQUALVERIFY
+//	<recipient key> OP_CHECKSIG
+func (c *Contract) RedeemLeaf() (txscript.TapLeaf, error) {
+	script, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_SIZE).
+		AddInt64(SecretSize).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_SHA256).
+		AddData(c.SecretHash[:]).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddData(schnorr.SerializePubKey(c.RecipientKey)).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		return txscript.TapLeaf{}, err
+	}
+	return txscript.NewBaseTapLeaf(script), nil
+}
+
+// RefundLeaf returns the leaf of a taproot contract spent by the funder:
+//
+//	<lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP <refund key> OP_CHECKSIG
+func (c *Contract) RefundLeaf() (txscript.TapLeaf, error) {
+	script, err := txscript.NewScriptBuilder().
+		AddInt64(int64(c.LockTime)).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP).
+		AddData(schnorr.SerializePubKey(c.RefundKey)).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		return txscript.TapLeaf{}, err
+	}
+	return txscript.NewBaseTapLeaf(script), nil
+}
+
+// tapTree returns the script tree of a taproot contract, along with its redeem
+// and refund leaves.
+func (c *Contract) tapTree() (*txscript.IndexedTapScriptTree, txscript.TapLeaf,
+	txscript.TapLeaf, error) {
+
+	if c.Type != ContractTaproot {
+		return nil, txscript.TapLeaf{}, txscript.TapLeaf{},
+			fmt.Errorf("%v contract has no script tree", c.Type)
+	}
+	redeemLeaf, err := c.RedeemLeaf()
+	if err != nil {
+		return nil, txscript.TapLeaf{}, txscript.TapLeaf{}, err
+	}
+	refundLeaf, err := c.RefundLeaf()
+	if err != nil {
+		return nil, txscript.TapLeaf{}, txscript.TapLeaf{}, err
+	}
+	tree := txscript.AssembleTaprootScriptTree(redeemLeaf, refundLeaf)
+	return tree, redeemLeaf, refundLeaf, nil
+}
+
+// controlBlock returns the serialized control block proving the inclusion of
+// the leaf in the script tree of a taproot contract.
+func controlBlock(tree *txscript.IndexedTapScriptTree,
+	leaf txscript.TapLeaf) ([]byte, error) {
+
+	proof := tree.LeafMerkleProofs[tree.LeafProofIndex[leaf.TapHash()]]
+	ctrlBlock := proof.ToControlBlock(NUMSKey())
+	return ctrlBlock.ToBytes()
+}
+
+// PkScript returns the output script paying to the contract.
+func (c *Contract) PkScript() ([]byte, error) {
+	switch c.Type {
+	case ContractP2WSH:
+		script, err := c.Script()
+		if err != nil {
+			return nil, err
+		}
+		scriptHash := sha256.Sum256(script)
+		return txscript.NewScriptBuilder().
+			AddOp(txscript.OP_0).
+			AddData(scriptHash[:]).
+			Script()
+
+	case ContractTaproot:
+		tree, _, _, err := c.tapTree()
+		if err != nil {
+			return nil, err
+		}
+		rootHash := tree.RootNode.TapHash()
+		outputKey := txscript.ComputeTaprootOutputKey(
+			NUMSKey(), rootHash[:],
+		)
+		return txscript.PayToTaprootScript(outputKey)
+
+	default:
+		return nil, fmt.Errorf("unknown contract type %d", c.Type)
+	}
+}
+
+// Address returns the address paying to the contract.
+func (c *Contract) Address(params *chaincfg.Params) (btcutil.Address, error) {
+	pkScript, err := c.PkScript()
+	if err != nil {
+		return nil, err
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, params)
+	if err != nil {
+		return nil, err
+	}
+	return addrs[0], nil
+}
+
+// RedeemWitness returns the witness redeeming the contract with the secret
+// and the signature of the recipient provided.
+func (c *Contract) RedeemWitness(sig, secret []byte) (wire.TxWitness, error) {
+	if sha256.Sum256(secret) != c.SecretHash {
+		return nil, ErrInvalidSecret
+	}
+
+	switch c.Type {
+	case ContractP2WSH:
+		script, err := c.Script()
+		if err != nil {
+			return nil, err
+		}
+		return wire.TxWitness{sig, secret, {1}, script}, nil
+
+	case ContractTaproot:
+		tree, redeemLeaf, _, err := c.tapTree()
+		if err != nil {
+			return nil, err
+		}
+		ctrlBlock, err := controlBlock(tree, redeemLeaf)
+		if err != nil {
+			return nil, err
+		}
+		return wire.TxWitness{
+			sig, secret, redeemLeaf.Script, ctrlBlock,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown contract type %d", c.Type)
+	}
+}
+
+// RefundWitness returns the witness refunding the contract with the signature
+// of the funder provided.
+func (c *Contract) RefundWitness(sig []byte) (wire.TxWitness, error) {
+	switch c.Type {
+	case ContractP2WSH:
+		script, err := c.Script()
+		if err != nil {
+			return nil, err
+		}
+		return wire.TxWitness{sig, nil, script}, nil
+
+	case ContractTaproot:
+		tree, _, refundLeaf, err := c.tapTree()
+		if err != nil {
+			return nil, err
+		}
+		ctrlBlock, err := controlBlock(tree, refundLeaf)
+		if err != nil {
+			return nil, err
+		}
+		return wire.TxWitness{sig, refundLeaf.Script, ctrlBlock}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown contract type %d", c.Type)
+	}
+}
+
+// sigSize returns the maximum size of the signatures spending the contract.
+func (c *Contract) sigSize() int {
+	if c.Type == ContractTaproot {
+		return schnorr.SignatureSize
+	}
+	return 73
+}
+
+// RedeemWitnessSize returns the maximum serialized size of the witness
+// redeeming the contract.
+func (c *Contract) RedeemWitnessSize() (int, error) {
+	secret := make([]byte, SecretSize)
+	contract := *c
+	contract.SecretHash = sha256.Sum256(secret)
+	witness, err := contract.RedeemWitness(make([]byte, c.sigSize()), secret)
+	if err != nil {
+		return 0, err
+	}
+	return witness.SerializeSize(), nil
+}
+
+// RefundWitnessSize returns the maximum serialized size of the witness
+// refunding the contract.
+func (c *Contract) RefundWitnessSize() (int, error) {
+	witness, err := c.RefundWitness(make([]byte, c.sigSize()))
+	if err != nil {
+		return 0, err
+	}
+	return witness.SerializeSize(), nil
+}
+
+// sign returns the signature of the input at index idx of the transaction,
+// spending the contract through the redeem or refund branch.
+func (c *Contract) sign(tx *wire.MsgTx, idx int,
+	prevOuts txscript.PrevOutputFetcher, redeem bool,
+	privKey *btcec.PrivateKey) ([]byte, error) {
+
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("transaction has no input %d", idx)
+	}
+	prevOut := prevOuts.FetchPrevOutput(tx.TxIn[idx].PreviousOutPoint)
+	if prevOut == nil {
+		return nil, fmt.Errorf("unknown output spent by input %d", idx)
+	}
+	pkScript, err := c.PkScript()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prevOut.PkScript, pkScript) {
+		return nil, fmt.Errorf("input %d does not spend the contract",
+			idx)
+	}
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+
+	if c.Type == ContractTaproot {
+		_, redeemLeaf, refundLeaf, err := c.tapTree()
+		if err != nil {
+			return nil, err
+		}
+		leaf := refundLeaf
+		if redeem {
+			leaf = redeemLeaf
+		}
+		return txscript.RawTxInTapscriptSignature(
+			tx, sigHashes, idx, prevOut.Value, prevOut.PkScript,
+			leaf, txscript.SigHashDefault, privKey,
+		)
+	}
+
+	script, err := c.Script()
+	if err != nil {
+		return nil, err
+	}
+	return txscript.RawTxInWitnessSignature(
+		tx, sigHashes, idx, prevOut.Value, script, txscript.SigHashAll,
+		privKey,
+	)
+}
+
+// SignRedeem returns the witness of the input at index idx of the transaction
+// redeeming the contract with the secret, signed with the private key of the
+// recipient.  The outputs spent by the transaction are looked up with the
+// fetcher provided.
+func (c *Contract) SignRedeem(tx *wire.MsgTx, idx int,
+	prevOuts txscript.PrevOutputFetcher, secret []byte,
+	privKey *btcec.PrivateKey) (wire.TxWitness, error) {
+
+	if sha256.Sum256(secret) != c.SecretHash {
+		return nil, ErrInvalidSecret
+	}
+	sig, err := c.sign(tx, idx, prevOuts, true, privKey)
+	if err != nil {
+		return nil, err
+	}
+	return c.RedeemWitness(sig, secret)
+}
+
+// SignRefund returns the witness of the input at index idx of the transaction
+// refunding the contract, signed with the private key of the funder.  The
+// transaction must have a lock time of at least the lock time of the contract
+// and the input must not have a final sequence number.
+func (c *Contract) SignRefund(tx *wire.MsgTx, idx int,
+	prevOuts txscript.PrevOutputFetcher,
+	privKey *btcec.PrivateKey) (wire.TxWitness, error) {
+
+	sig, err := c.sign(tx, idx, prevOuts, false, privKey)
+	if err != nil {
+		return nil, err
+	}
+	return c.RefundWitness(sig)
+}
+
+// ParseContract parses the witness script of a P2WSH contract, as returned by
+// Script.
+func ParseContract(script []byte) (*Contract, error) {
+	const (
+		secretHashIdx   = 5
+		recipientKeyIdx = 7
+		lockTimeIdx     = 9
+		refundKeyIdx    = 12
+	)
+	template := []byte{
+		txscript.OP_IF,
+		txscript.OP_SIZE,
+		txscript.OP_DATA_1,
+		txscript.OP_EQUALVERIFY,
+		txscript.OP_SHA256,
+		txscript.OP_DATA_32,
+		txscript.OP_EQUALVERIFY,
+		txscript.OP_DATA_33,
+		txscript.OP_ELSE,
+		0, // The lock time can be pushed with any opcode.
+		txscript.OP_CHECKLOCKTIMEVERIFY,
+		txscript.OP_DROP,
+		txscript.OP_DATA_33,
+		txscript.OP_ENDIF,
+		txscript.OP_CHECKSIG,
+	}
+
+	var (
+		contract  = &Contract{Type: ContractP2WSH}
+		tokenizer = txscript.MakeScriptTokenizer(0, script)
+		i         int
+	)
+	for ; tokenizer.Next(); i++ {
+		if i >= len(template) {
+			return nil, ErrNotContract
+		}
+		op, data := tokenizer.Opcode(), tokenizer.Data()
+		if i != lockTimeIdx && op != template[i] {
+			return nil, ErrNotContract
+		}
+
+		switch i {
+		case 2:
+			if len(data) != 1 || data[0] != SecretSize {
+				return nil, ErrNotContract
+			}
+
+		case secretHashIdx:
+			copy(contract.SecretHash[:], data)
+
+		case recipientKeyIdx, refundKeyIdx:
+			key, err := btcec.ParsePubKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrNotContract,
+					err)
+			}
+			if i == recipientKeyIdx {
+				contract.RecipientKey = key
+			} else {
+				contract.RefundKey = key
+			}
+
+		case lockTimeIdx:
+			lockTime, err := lockTimeFromOp(op, data)
+			if err != nil {
+				return nil, err
+			}
+			contract.LockTime = lockTime
+		}
+	}
+	if tokenizer.Err() != nil || i != len(template) {
+		return nil, ErrNotContract
+	}
+
+	// Scripts pushing data with non-minimal opcodes are not standard, so
+	// only scripts created like Script does are accepted.
+	canonical, err := contract.Script()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, script) {
+		return nil, ErrNotContract
+	}
+	return contract, nil
+}
+
+// lockTimeFromOp returns the lock time pushed by the opcode and data provided.
+func lockTimeFromOp(op byte, data []byte) (uint32, error) {
+	switch {
+	case op == txscript.OP_0:
+		return 0, nil
+	case op >= txscript.OP_1 && op <= txscript.OP_16:
+		return uint32(txscript.AsSmallInt(op)), nil
+	case op >= txscript.OP_DATA_1 && op <= txscript.OP_DATA_5:
+		// Lock times are checked as 5 byte numbers by
+		// OP_CHECKLOCKTIMEVERIFY.
+		num, err := txscript.MakeScriptNum(data, true, 5)
+		if err != nil || num < 0 || int64(num) > int64(^uint32(0)) {
+			return 0, ErrNotContract
+		}
+		return uint32(num), nil
+	default:
+		return 0, ErrNotContract
+	}
+}
+
+// ExtractSecret returns the secret revealed by the witness of an input
+// redeeming a contract with the secret hash provided.  ErrNoSecret is returned
+// if the witness does not reveal the secret, such as when the contract was
+// refunded.
+func ExtractSecret(witness wire.TxWitness, secretHash [32]byte) ([]byte,
+	error) {
+
+	for _, item := range witness {
+		if len(item) == SecretSize && sha256.Sum256(item) == secretHash {
+			return item, nil
+		}
+	}
+	return nil, ErrNoSecret
+}
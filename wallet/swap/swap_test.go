@@ -0,0 +1,171 @@
+package swap
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// testContract returns a contract of the type provided along with its secret
+// and the private keys of the recipient and the funder.
+func testContract(t *testing.T, contractType ContractType) (*Contract, []byte,
+	*btcec.PrivateKey, *btcec.PrivateKey) {
+
+	secret, secretHash, err := NewSecret()
+	require.NoError(t, err)
+	recipientKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	refundKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	contract := &Contract{
+		Type:         contractType,
+		SecretHash:   secretHash,
+		RecipientKey: recipientKey.PubKey(),
+		RefundKey:    refundKey.PubKey(),
+		LockTime:     800000,
+	}
+	return contract, secret, recipientKey, refundKey
+}
+
+// spendTx returns a transaction spending the contract output provided, along
+// with a fetcher of the output.
+func spendTx(t *testing.T, c *Contract, value int64) (*wire.MsgTx,
+	txscript.PrevOutputFetcher) {
+
+	pkScript, err := c.PkScript()
+	require.NoError(t, err)
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(value-1000, []byte{txscript.OP_TRUE}))
+	return tx, txscript.NewCannedPrevOutputFetcher(pkScript, value)
+}
+
+// executeSpend runs the scripts of the spending transaction's input.
+func executeSpend(tx *wire.MsgTx, prevOuts txscript.PrevOutputFetcher) error {
+	prevOut := prevOuts.FetchPrevOutput(tx.TxIn[0].PreviousOutPoint)
+	vm, err := txscript.NewEngine(
+		prevOut.PkScript, tx, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(tx, prevOuts), prevOut.Value, prevOuts,
+	)
+	if err != nil {
+		return err
+	}
+	return vm.Execute()
+}
+
+// TestContractSpends tests that contracts of all types can be redeemed with
+// the secret and refunded after their lock time.
+func TestContractSpends(t *testing.T) {
+	t.Parallel()
+
+	for _, contractType := range []ContractType{
+		ContractP2WSH, ContractTaproot,
+	} {
+		contract, secret, recipientKey, refundKey := testContract(
+			t, contractType,
+		)
+		addr, err := contract.Address(&chaincfg.MainNetParams)
+		require.NoError(t, err)
+		if contractType == ContractTaproot {
+			require.IsType(t, &btcutil.AddressTaproot{}, addr)
+		} else {
+			require.IsType(
+				t, &btcutil.AddressWitnessScriptHash{}, addr,
+			)
+		}
+
+		// The recipient redeems the contract with the secret, which is
+		// then extracted from the witness.
+		tx, prevOuts := spendTx(t, contract, 100000)
+		witness, err := contract.SignRedeem(
+			tx, 0, prevOuts, secret, recipientKey,
+		)
+		require.NoError(t, err)
+		tx.TxIn[0].Witness = witness
+		require.NoError(t, executeSpend(tx, prevOuts), contractType)
+		size, err := contract.RedeemWitnessSize()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, size, witness.SerializeSize())
+
+		extracted, err := ExtractSecret(witness, contract.SecretHash)
+		require.NoError(t, err)
+		require.Equal(t, secret, extracted)
+
+		// The funder can not redeem the contract.
+		witness, err = contract.SignRedeem(
+			tx, 0, prevOuts, secret, refundKey,
+		)
+		require.NoError(t, err)
+		tx.TxIn[0].Witness = witness
+		require.Error(t, executeSpend(tx, prevOuts), contractType)
+
+		// The wrong secret is not accepted.
+		_, err = contract.SignRedeem(
+			tx, 0, prevOuts, make([]byte, SecretSize), recipientKey,
+		)
+		require.ErrorIs(t, err, ErrInvalidSecret)
+
+		// The funder refunds the contract once the lock time has
+		// passed.
+		tx, prevOuts = spendTx(t, contract, 100000)
+		tx.LockTime = contract.LockTime
+		tx.TxIn[0].Sequence = wire.MaxTxInSequenceNum - 1
+		witness, err = contract.SignRefund(tx, 0, prevOuts, refundKey)
+		require.NoError(t, err)
+		tx.TxIn[0].Witness = witness
+		require.NoError(t, executeSpend(tx, prevOuts), contractType)
+		size, err = contract.RefundWitnessSize()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, size, witness.SerializeSize())
+
+		_, err = ExtractSecret(witness, contract.SecretHash)
+		require.ErrorIs(t, err, ErrNoSecret)
+
+		// The contract can not be refunded before the lock time.
+		tx.LockTime = contract.LockTime - 1
+		witness, err = contract.SignRefund(tx, 0, prevOuts, refundKey)
+		require.NoError(t, err)
+		tx.TxIn[0].Witness = witness
+		require.Error(t, executeSpend(tx, prevOuts), contractType)
+	}
+}
+
+// TestParseContract tests that the parameters of P2WSH contracts are parsed
+// from their scripts.
+func TestParseContract(t *testing.T) {
+	t.Parallel()
+
+	contract, _, _, _ := testContract(t, ContractP2WSH)
+	for _, lockTime := range []uint32{0, 16, 800000, 1700000000, 1<<32 - 1} {
+		contract.LockTime = lockTime
+		script, err := contract.Script()
+		require.NoError(t, err)
+		parsed, err := ParseContract(script)
+		require.NoError(t, err)
+		require.Equal(t, contract, parsed)
+	}
+
+	script, err := contract.Script()
+	require.NoError(t, err)
+	for _, invalid := range [][]byte{
+		nil,
+		script[:len(script)-1],
+		append(script, txscript.OP_CHECKSIG),
+		append([]byte{txscript.OP_NOP}, script[1:]...),
+	} {
+		_, err := ParseContract(invalid)
+		require.ErrorIs(t, err, ErrNotContract)
+	}
+
+	// Taproot contracts have no witness script.
+	contract.Type = ContractTaproot
+	_, err = contract.Script()
+	require.Error(t, err)
+}
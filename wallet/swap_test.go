@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/spv"
+	"github.com/bisoncraft/utxowallet/wallet/swap"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSpendFinderClient is a mock chain client finding the spend provided.
+type mockSpendFinderClient struct {
+	mockChainClient
+	report *spv.SpendReport
+}
+
+func (m *mockSpendFinderClient) FindSpend(wire.OutPoint, []byte,
+	int32) (*spv.SpendReport, error) {
+
+	return m.report, nil
+}
+
+// TestSwapSpends tests that swap contracts using wallet keys are redeemed and
+// refunded to the wallet, and that the secret is found in redeeming spends.
+func TestSwapSpends(t *testing.T) {
+	t.Parallel()
+
+	w, cleanup := testWallet(t)
+	defer cleanup()
+
+	recipientKey, err := w.SwapKey(0)
+	require.NoError(t, err)
+	refundKey, err := w.SwapKey(0)
+	require.NoError(t, err)
+	secret, secretHash, err := swap.NewSecret()
+	require.NoError(t, err)
+
+	// The spend of a contract can not be found with the mock chain client.
+	contract := &swap.Contract{
+		SecretHash:   secretHash,
+		RecipientKey: recipientKey,
+		RefundKey:    refundKey,
+		LockTime:     1000,
+	}
+	op := wire.OutPoint{Hash: chainhash.Hash{1}}
+	_, err = w.FindSwapSpend(contract, op, 0)
+	require.ErrorIs(t, err, ErrNoSpendFinder)
+
+	// isWalletOutput returns whether the output pays to a wallet address.
+	isWalletOutput := func(txOut *wire.TxOut) bool {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			txOut.PkScript, w.chainParams,
+		)
+		require.NoError(t, err)
+		return walletdb.View(w.db, func(dbtx walletdb.ReadTx) error {
+			addrmgrNs := dbtx.ReadBucket(waddrmgrNamespaceKey)
+			_, err := w.Manager.Address(addrmgrNs, addrs[0])
+			return err
+		}) == nil
+	}
+
+	const (
+		value   = 100000
+		feeRate = 5000
+	)
+	for i, contractType := range []swap.ContractType{
+		swap.ContractP2WSH, swap.ContractTaproot,
+	} {
+		contract.Type = contractType
+		op.Index = uint32(i)
+
+		// The redeeming transaction pays the contract value, less
+		// the fee, to the wallet.
+		redeem, err := w.RedeemSwap(
+			contract, op, value, secret, feeRate, "",
+		)
+		require.NoError(t, err)
+		require.Len(t, redeem.TxOut, 1)
+		require.True(t, isWalletOutput(redeem.TxOut[0]))
+		fee := btcutil.Amount(value - redeem.TxOut[0].Value)
+		vsize := mempool.GetTxVirtualSize(btcutil.NewTx(redeem))
+		require.GreaterOrEqual(
+			t, fee, feeRate*btcutil.Amount(vsize)/1000,
+		)
+
+		// The secret is found in the redeeming transaction.
+		finder := &mockSpendFinderClient{
+			report: &spv.SpendReport{
+				SpendingTx:       redeem,
+				SpendingTxHeight: 100,
+			},
+		}
+		w.chainClient = finder
+		spend, err := w.FindSwapSpend(contract, op, 0)
+		require.NoError(t, err)
+		require.Equal(t, redeem, spend.Tx)
+		require.Equal(t, uint32(100), spend.Height)
+		require.Equal(t, secret, spend.Secret)
+
+		// The refunding transaction is locked until the lock time of
+		// the contract and reveals no secret.
+		refund, err := w.RefundSwap(contract, op, value, feeRate, "")
+		require.NoError(t, err)
+		require.Equal(t, contract.LockTime, refund.LockTime)
+		require.Less(t, refund.TxIn[0].Sequence, wire.MaxTxInSequenceNum)
+		require.True(t, isWalletOutput(refund.TxOut[0]))
+
+		finder.report.SpendingTx = refund
+		spend, err = w.FindSwapSpend(contract, op, 0)
+		require.NoError(t, err)
+		require.Nil(t, spend.Secret)
+
+		// Nothing is returned for unspent contracts.
+		finder.report = &spv.SpendReport{Output: redeem.TxOut[0]}
+		spend, err = w.FindSwapSpend(contract, op, 0)
+		require.NoError(t, err)
+		require.Nil(t, spend)
+		w.chainClient = &mockChainClient{}
+	}
+
+	// The contract value must pay for the fee.
+	_, err = w.RedeemSwap(contract, op, 500, secret, feeRate, "")
+	require.ErrorIs(t, err, ErrSwapInsufficientValue)
+
+	// The secret must match the secret hash.
+	_, err = w.RedeemSwap(contract, op, value, secretHash[:], feeRate, "")
+	require.ErrorIs(t, err, swap.ErrInvalidSecret)
+
+	// Only the wallet keys of contracts can be used.
+	otherKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	contract.RecipientKey = otherKey.PubKey()
+	_, err = w.RedeemSwap(contract, op, value, secret, feeRate, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/waddrmgr"
+	"github.com/bisoncraft/utxowallet/wallet/txauthor"
+	"github.com/bisoncraft/utxowallet/wallet/txrules"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// outputSweep is a single known output to be swept to a new change address of
+// an account.
+type outputSweep struct {
+	op      wire.OutPoint
+	value   btcutil.Amount
+	scope   waddrmgr.KeyScope
+	account uint32
+
+	// fee returns the fee of the unsigned sweeping transaction, which has
+	// its single input and change output.
+	fee func(tx *wire.MsgTx) btcutil.Amount
+
+	// errInsufficientValue is wrapped by the error returned when the
+	// value of the output is too low to pay the fee.
+	errInsufficientValue error
+}
+
+// sweep runs create in a database transaction.  The sweeping transactions
+// pay to a new change address, so the address creation is guarded like it is
+// for any other created transaction.
+func (w *Wallet) sweep(create func(walletdb.ReadWriteTx) error) error {
+	w.newAddrMtx.Lock()
+	defer w.newAddrMtx.Unlock()
+
+	return walletdb.Update(w.db, create)
+}
+
+// sweepTx creates the unsigned transaction paying the value of the output,
+// less the fee, to a new change address of the account of the sweep.  The
+// input signals replaceability, which also makes it non-final so that the
+// transaction can be locked.
+func (w *Wallet) sweepTx(dbtx walletdb.ReadWriteTx,
+	s *outputSweep) (*wire.MsgTx, error) {
+
+	_, changeSource, err := w.addrMgrWithChangeSource(
+		dbtx, &s.scope, s.account,
+	)
+	if err != nil {
+		return nil, err
+	}
+	changeScript, err := changeSource.NewScript()
+	if err != nil {
+		return nil, err
+	}
+
+	input := wire.NewTxIn(&s.op, nil, nil)
+	input.Sequence = txauthor.ReplaceableSequence
+	output := wire.NewTxOut(0, changeScript)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(input)
+	tx.AddTxOut(output)
+
+	fee := s.fee(tx)
+	output.Value = int64(s.value - fee)
+	if s.value <= fee ||
+		txrules.IsDustOutput(output, txrules.DefaultRelayFeePerKb) {
+
+		return nil, fmt.Errorf("%w: fee of %v for output value %v",
+			s.errInsufficientValue, fee, s.value)
+	}
+
+	return tx, nil
+}
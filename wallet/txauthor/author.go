@@ -8,14 +8,22 @@ package txauthor
 import (
 	"errors"
 
+	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/bisoncraft/utxowallet/wallet/txrules"
 	"github.com/bisoncraft/utxowallet/wallet/txsizes"
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 )
 
+// ReplaceableSequence is the sequence number of the inputs of transactions
+// created by NewUnsignedTransaction.  It signals that the transaction may be
+// replaced by a transaction paying a higher fee, as described by BIP-0125,
+// while leaving relative lock-times of BIP-0068 disabled.
+const ReplaceableSequence = wire.MaxTxInSequenceNum - 2
+
 // SumOutputValues sums up the list of TxOuts and returns an Amount.
 func SumOutputValues(outputs []*wire.TxOut) (totalOutput btcutil.Amount) {
 	for _, txOut := range outputs {
@@ -79,10 +87,15 @@ type ChangeSource struct {
 //
 // If any remaining output value can be returned to the wallet via a change
 // output without violating mempool dust rules, a P2WPKH change output is
-// appended to the transaction outputs.  Since the change output may not be
-// necessary, fetchChange is called zero or one times to generate this script.
-// This function must return a P2WPKH script or smaller, otherwise fee estimation
-// will be incorrect.
+// appended to the transaction outputs.  If the inputs do not pay for the fee of
+// a change output, but do pay for the fee of the transaction without one, no
+// further inputs are fetched and the remaining value is paid as fee.  Since the
+// change output may not be necessary, fetchChange is called zero or one times
+// to generate this script.  This function must return a P2WPKH script or
+// smaller, otherwise fee estimation will be incorrect.
+//
+// Every input is given the ReplaceableSequence sequence number, so created
+// transactions signal BIP-0125 replaceability.
 //
 // If successful, the transaction, total input value spent, and all previous
 // output scripts are returned.  If the input source was unable to provide
@@ -91,27 +104,53 @@ type ChangeSource struct {
 //
 // BUGS: Fee estimation may be off when redeeming non-compressed P2PKH outputs.
 func NewUnsignedTransaction(outputs []*wire.TxOut, feeRatePerKb btcutil.Amount,
-	fetchInputs InputSource, changeSource *ChangeSource) (*AuthoredTx, error) {
+	fetchInputs InputSource, changeSource *ChangeSource,
+	params *netparams.ChainParams) (*AuthoredTx, error) {
+
+	return NewUnsignedTransactionWithInputSizes(
+		outputs, feeRatePerKb, fetchInputs, changeSource, params, nil,
+	)
+}
+
+// InputSizeEstimator returns the worst case size of an input spending an
+// output with the script provided, or false if the size of the input is
+// estimated from the type of the script alone.
+type InputSizeEstimator func(pkScript []byte) (txsizes.InputSize, bool)
+
+// NewUnsignedTransactionWithInputSizes is like NewUnsignedTransaction, but
+// estimates the size of the inputs the estimator knows of, such as inputs
+// spending multisig outputs, with the estimator.  The estimator may be nil.
+func NewUnsignedTransactionWithInputSizes(outputs []*wire.TxOut,
+	feeRatePerKb btcutil.Amount, fetchInputs InputSource,
+	changeSource *ChangeSource, params *netparams.ChainParams,
+	inputSize InputSizeEstimator) (*AuthoredTx, error) {
 
 	targetAmount := SumOutputValues(outputs)
 	estimatedSize := txsizes.EstimateVirtualSize(
 		0, 0, 1, 0, outputs, changeSource.ScriptSize,
 	)
-	targetFee := txrules.FeeForSerializeSize(feeRatePerKb, estimatedSize)
+	targetFee := txrules.FeeForSerializeSize(
+		feeRatePerKb, estimatedSize, params,
+	)
 
 	for {
 		inputAmount, inputs, inputValues, scripts, err := fetchInputs(targetAmount + targetFee)
 		if err != nil {
 			return nil, err
 		}
-		if inputAmount < targetAmount+targetFee {
-			return nil, insufficientFundsError{}
-		}
 
 		// We count the types of inputs, which we'll use to estimate
 		// the vsize of the transaction.
 		var nested, p2wpkh, p2tr, p2pkh int
+		var others []txsizes.InputSize
 		for _, pkScript := range scripts {
+			if inputSize != nil {
+				if size, ok := inputSize(pkScript); ok {
+					others = append(others, size)
+					continue
+				}
+			}
+
 			switch {
 			// If this is a p2sh output, we assume this is a
 			// nested P2WKH.
@@ -126,16 +165,40 @@ func NewUnsignedTransaction(outputs []*wire.TxOut, feeRatePerKb btcutil.Amount,
 			}
 		}
 
-		maxSignedSize := txsizes.EstimateVirtualSize(
-			p2pkh, p2tr, p2wpkh, nested, outputs, changeSource.ScriptSize,
+		maxSignedSize := txsizes.EstimateVirtualSizeWithInputs(
+			p2pkh, p2tr, p2wpkh, nested, others, outputs,
+			changeSource.ScriptSize,
+		)
+		maxRequiredFee := txrules.FeeForSerializeSize(
+			feeRatePerKb, maxSignedSize, params,
 		)
-		maxRequiredFee := txrules.FeeForSerializeSize(feeRatePerKb, maxSignedSize)
 		remainingAmount := inputAmount - targetAmount
-		if remainingAmount < maxRequiredFee {
+
+		// The inputs may not pay for a change output, but still pay for
+		// a transaction without one.  Any value remaining is paid as fee
+		// rather than adding inputs only to create change.
+		noChangeSize := txsizes.EstimateVirtualSizeWithInputs(
+			p2pkh, p2tr, p2wpkh, nested, others, outputs, 0,
+		)
+		noChangeFee := txrules.FeeForSerializeSize(
+			feeRatePerKb, noChangeSize, params,
+		)
+		changeless := remainingAmount < maxRequiredFee &&
+			remainingAmount >= noChangeFee
+
+		switch {
+		case changeless:
+		case inputAmount < targetAmount+targetFee:
+			return nil, insufficientFundsError{}
+		case remainingAmount < maxRequiredFee:
 			targetFee = maxRequiredFee
 			continue
 		}
 
+		for _, input := range inputs {
+			input.Sequence = ReplaceableSequence
+		}
+
 		unsignedTransaction := &wire.MsgTx{
 			Version:  wire.TxVersion,
 			TxIn:     inputs,
@@ -144,18 +207,22 @@ func NewUnsignedTransaction(outputs []*wire.TxOut, feeRatePerKb btcutil.Amount,
 		}
 
 		changeIndex := -1
-		changeAmount := inputAmount - targetAmount - maxRequiredFee
-		changeScript, err := changeSource.NewScript()
-		if err != nil {
-			return nil, err
-		}
-		change := wire.NewTxOut(int64(changeAmount), changeScript)
-		if changeAmount != 0 && !txrules.IsDustOutput(change,
-			txrules.DefaultRelayFeePerKb) {
-
-			l := len(outputs)
-			unsignedTransaction.TxOut = append(outputs[:l:l], change)
-			changeIndex = l
+		if !changeless {
+			changeAmount := inputAmount - targetAmount - maxRequiredFee
+			changeScript, err := changeSource.NewScript()
+			if err != nil {
+				return nil, err
+			}
+			change := wire.NewTxOut(int64(changeAmount), changeScript)
+			if changeAmount != 0 && !txrules.IsDustOutput(change,
+				txrules.DefaultRelayFeePerKb) {
+
+				l := len(outputs)
+				unsignedTransaction.TxOut = append(
+					outputs[:l:l], change,
+				)
+				changeIndex = l
+			}
 		}
 
 		return &AuthoredTx{
@@ -200,11 +267,73 @@ type SecretsSource interface {
 	ChainParams() *chaincfg.Params
 }
 
+// TapscriptSpend is the spend of a taproot output through the script path of a
+// leaf whose script is satisfied by signatures alone, such as a single key
+// checked with OP_CHECKSIG or keys counted with OP_CHECKSIGADD.
+type TapscriptSpend struct {
+	// Leaf is the leaf revealed by the spend.
+	Leaf txscript.TapLeaf
+
+	// ControlBlock proves that the leaf is committed to by the taproot
+	// output key.
+	ControlBlock *txscript.ControlBlock
+
+	// Keys are the private keys signing for the keys of the leaf script,
+	// in the order the keys appear in the script.  Keys not signing are
+	// nil, and their signatures are left empty in the witness.
+	Keys []*btcec.PrivateKey
+}
+
+// Witness returns the witness of the input spending the taproot output through
+// the script path of the leaf.
+func (s *TapscriptSpend) Witness(tx *wire.MsgTx,
+	hashCache *txscript.TxSigHashes, idx int, inputValue int64,
+	pkScript []byte, hashType txscript.SigHashType) (wire.TxWitness, error) {
+
+	ctrlBlock, err := s.ControlBlock.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature of the first key of the script is on top of the
+	// stack, so signatures are pushed in the reverse order of the keys.
+	witness := make(wire.TxWitness, 0, len(s.Keys)+2)
+	for i := len(s.Keys) - 1; i >= 0; i-- {
+		if s.Keys[i] == nil {
+			witness = append(witness, nil)
+			continue
+		}
+		sig, err := txscript.RawTxInTapscriptSignature(
+			tx, hashCache, idx, inputValue, pkScript, s.Leaf,
+			hashType, s.Keys[i],
+		)
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, sig)
+	}
+
+	return append(witness, s.Leaf.Script, ctrlBlock), nil
+}
+
+// TapscriptSecretsSource is a SecretsSource that also provides the script path
+// spends of taproot outputs committing to script trees.
+type TapscriptSecretsSource interface {
+	SecretsSource
+
+	// GetTapscriptSpend returns the script path spend of the taproot
+	// output of the address, or nil if the output is to be spent through
+	// its key path.
+	GetTapscriptSpend(addr btcutil.Address) (*TapscriptSpend, error)
+}
+
 // AddAllInputScripts modifies transaction a transaction by adding inputs
 // scripts for each input.  Previous output scripts being redeemed by each input
 // are passed in prevPkScripts and the slice length must match the number of
 // inputs.  Private keys and redeem scripts are looked up using a SecretsSource
-// based on the previous output script.
+// based on the previous output script.  Taproot outputs are spent through a
+// script path when the SecretsSource is a TapscriptSecretsSource providing
+// one.
 func AddAllInputScripts(tx *wire.MsgTx, prevPkScripts [][]byte,
 	inputValues []btcutil.Amount, secrets SecretsSource) error {
 
@@ -249,7 +378,18 @@ func AddAllInputScripts(tx *wire.MsgTx, prevPkScripts [][]byte,
 			}
 
 		case txscript.IsPayToTaproot(pkScript):
-			err := spendTaprootKey(
+			spent, err := spendTaprootScript(
+				inputs[i], pkScript, int64(inputValues[i]),
+				chainParams, secrets, tx, hashCache, i,
+			)
+			if err != nil {
+				return err
+			}
+			if spent {
+				break
+			}
+
+			err = spendTaprootKey(
 				inputs[i], pkScript, int64(inputValues[i]),
 				chainParams, secrets, tx, hashCache, i,
 			)
@@ -361,6 +501,40 @@ func spendTaprootKey(txIn *wire.TxIn, pkScript []byte,
 	return nil
 }
 
+// spendTaprootScript generates, and sets a valid witness for spending the
+// passed pkScript through a script path, if the secrets source provides one.
+// Whether the witness was set is returned.
+func spendTaprootScript(txIn *wire.TxIn, pkScript []byte,
+	inputValue int64, chainParams *chaincfg.Params, secrets SecretsSource,
+	tx *wire.MsgTx, hashCache *txscript.TxSigHashes, idx int) (bool, error) {
+
+	tapscripts, ok := secrets.(TapscriptSecretsSource)
+	if !ok {
+		return false, nil
+	}
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, chainParams)
+	if err != nil {
+		return false, err
+	}
+	spend, err := tapscripts.GetTapscriptSpend(addrs[0])
+	if err != nil || spend == nil {
+		return false, err
+	}
+
+	witness, err := spend.Witness(
+		tx, hashCache, idx, inputValue, pkScript,
+		txscript.SigHashDefault,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	txIn.Witness = witness
+
+	return true, nil
+}
+
 // spendNestedWitnessPubKey generates both a sigScript, and valid witness for
 // spending the passed pkScript with the specified input amount. The generated
 // sigScript is the version 0 p2wkh witness program corresponding to the queried
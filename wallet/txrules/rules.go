@@ -9,6 +9,7 @@ package txrules
 import (
 	"errors"
 
+	"github.com/bisoncraft/utxowallet/netparams"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/mempool"
 	"github.com/btcsuite/btcd/txscript"
@@ -38,12 +39,14 @@ var (
 )
 
 // CheckOutput performs simple consensus and policy tests on a transaction
-// output.
-func CheckOutput(output *wire.TxOut, relayFeePerKb btcutil.Amount) error {
+// output. The output value is checked against the chain's maximum amount.
+func CheckOutput(output *wire.TxOut, relayFeePerKb btcutil.Amount,
+	params *netparams.ChainParams) error {
+
 	if output.Value < 0 {
 		return ErrAmountNegative
 	}
-	if output.Value > btcutil.MaxSatoshi {
+	if output.Value > params.MaxSatoshi {
 		return ErrAmountExceedsMax
 	}
 	if IsDustOutput(output, relayFeePerKb) {
@@ -53,16 +56,20 @@ func CheckOutput(output *wire.TxOut, relayFeePerKb btcutil.Amount) error {
 }
 
 // FeeForSerializeSize calculates the required fee for a transaction of some
-// arbitrary size given a mempool's relay fee policy.
-func FeeForSerializeSize(relayFeePerKb btcutil.Amount, txSerializeSize int) btcutil.Amount {
+// arbitrary size given a mempool's relay fee policy.  The fee is capped at the
+// chain's maximum amount.
+func FeeForSerializeSize(relayFeePerKb btcutil.Amount, txSerializeSize int,
+	params *netparams.ChainParams) btcutil.Amount {
+
 	fee := relayFeePerKb * btcutil.Amount(txSerializeSize) / 1000
 
 	if fee == 0 && relayFeePerKb > 0 {
 		fee = relayFeePerKb
 	}
 
-	if fee < 0 || fee > btcutil.MaxSatoshi {
-		fee = btcutil.MaxSatoshi
+	maxFee := btcutil.Amount(params.MaxSatoshi)
+	if fee < 0 || fee > maxFee {
+		fee = maxFee
 	}
 
 	return fee
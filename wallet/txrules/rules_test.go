@@ -0,0 +1,85 @@
+package txrules
+
+import (
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/assets"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TestCheckOutput ensures output amounts are checked against the maximum amount
+// of the chain they are created for.
+func TestCheckOutput(t *testing.T) {
+	t.Parallel()
+
+	btcParams := assets.BTCParams["mainnet"]
+	ltcParams := assets.LTCParams["mainnet"]
+	pkScript := make([]byte, 22)
+
+	tests := []struct {
+		name  string
+		value int64
+		ltc   bool
+		err   error
+	}{
+		{"negative", -1, false, ErrAmountNegative},
+		{"dust", 1, false, ErrOutputIsDust},
+		{"btc max", btcutil.MaxSatoshi, false, nil},
+		{"above btc max", btcutil.MaxSatoshi + 1, false,
+			ErrAmountExceedsMax},
+		{"above btc max on ltc", btcutil.MaxSatoshi + 1, true, nil},
+		{"above ltc max", ltcParams.MaxSatoshi + 1, true,
+			ErrAmountExceedsMax},
+	}
+	for _, test := range tests {
+		params := btcParams
+		if test.ltc {
+			params = ltcParams
+		}
+		output := wire.NewTxOut(test.value, pkScript)
+		err := CheckOutput(output, DefaultRelayFeePerKb, params)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+}
+
+// TestFeeForSerializeSize ensures fees are calculated from the fee rate, and
+// capped at the maximum amount of the chain.
+func TestFeeForSerializeSize(t *testing.T) {
+	t.Parallel()
+
+	btcParams := assets.BTCParams["mainnet"]
+	ltcParams := assets.LTCParams["mainnet"]
+
+	tests := []struct {
+		name string
+		rate btcutil.Amount
+		size int
+		ltc  bool
+		fee  btcutil.Amount
+	}{
+		{"rate", 2e3, 250, false, 500},
+		{"minimum", 1e3, 0, false, 1e3},
+		{"zero rate", 0, 250, false, 0},
+		{"capped", btcutil.MaxSatoshi, 2000, false,
+			btcutil.MaxSatoshi},
+		{"uncapped on ltc", btcutil.MaxSatoshi, 1001, true,
+			btcutil.MaxSatoshi * 1001 / 1000},
+		{"capped on ltc", btcutil.Amount(ltcParams.MaxSatoshi), 2000,
+			true, btcutil.Amount(ltcParams.MaxSatoshi)},
+	}
+	for _, test := range tests {
+		params := btcParams
+		if test.ltc {
+			params = ltcParams
+		}
+		fee := FeeForSerializeSize(test.rate, test.size, params)
+		if fee != test.fee {
+			t.Errorf("%s: got fee %v, want %v", test.name, fee,
+				test.fee)
+		}
+	}
+}
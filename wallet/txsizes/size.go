@@ -157,6 +157,14 @@ const (
 	//   - 33 wu serialized compressed pubkey
 	RedeemP2WPKHInputWitnessWeight = 1 + 1 + 73 + 1 + 33
 
+	// P2WSHPkScriptSize is the size of a transaction output script that
+	// pays to a witness script hash. It is calculated as:
+	//
+	//   - OP_0
+	//   - OP_DATA_32
+	//   - 32 bytes script hash
+	P2WSHPkScriptSize = 1 + 1 + 32
+
 	// RedeemP2TRInputWitnessWeight is the worst case weight of
 	// a witness for spending P2TR outputs. It
 	// is calculated as:
@@ -195,6 +203,87 @@ func EstimateSerializeSize(inputCount int, txOuts []*wire.TxOut, addChangeOutput
 		changeSize
 }
 
+// InputSize is the worst case size of a transaction input spending an output
+// of a kind not counted by EstimateVirtualSize, such as a multisig output.
+type InputSize struct {
+	// BaseSize is the serialize size of the input, excluding its witness.
+	BaseSize int
+
+	// WitnessWeight is the weight of the witness of the input, which is
+	// zero for inputs without a witness.
+	WitnessWeight int
+}
+
+// smallIntSize returns the size of the script push of a small integer, which
+// is a single opcode up to 16.
+func smallIntSize(n int) int {
+	if n <= 16 {
+		return 1
+	}
+	return 2
+}
+
+// MultisigInputSize returns the worst case size of a transaction input
+// spending a P2WSH output, or a P2SH-P2WSH output if nested, paying to a
+// threshold of numKeys compressed keys multisig script.  The witness is made
+// of:
+//
+//   - 1 wu compact int encoding the number of items
+//   - 1 wu empty item consumed by OP_CHECKMULTISIG
+//   - threshold times 1 wu compact int encoding value 73 and 73 wu DER
+//     signature with sighash
+//   - the compact int encoding the size of the witness script
+//   - the witness script made of the threshold, numKeys times OP_DATA_33 and
+//     the 33 bytes serialized compressed pubkey, the number of keys and
+//     OP_CHECKMULTISIG
+func MultisigInputSize(threshold, numKeys int, nested bool) InputSize {
+	scriptSize := smallIntSize(threshold) + numKeys*(1+33) +
+		smallIntSize(numKeys) + 1
+	witnessWeight := wire.VarIntSerializeSize(uint64(threshold+2)) + 1 +
+		threshold*(1+73) +
+		wire.VarIntSerializeSize(uint64(scriptSize)) + scriptSize
+
+	// The signature script of nested inputs pushes the 34 bytes witness
+	// program: OP_DATA_34, OP_0, OP_DATA_32 and the 32 bytes script hash.
+	sigScriptSize := 0
+	if nested {
+		sigScriptSize = 1 + P2WSHPkScriptSize
+	}
+
+	return InputSize{
+		BaseSize: 32 + 4 +
+			wire.VarIntSerializeSize(uint64(sigScriptSize)) +
+			sigScriptSize + 4,
+		WitnessWeight: witnessWeight,
+	}
+}
+
+// TapscriptInputSize returns the worst case size of a transaction input
+// spending a P2TR output through the script path of a leaf script of
+// scriptSize bytes, revealed by a control block of ctrlBlockSize bytes, which
+// checks numKeys keys of which numSigs sign.  The witness is made of:
+//
+//   - the compact int encoding the number of items
+//   - numSigs times 1 wu compact int encoding value 65 and 65 wu schnorr
+//     signature with sighash
+//   - 1 wu empty item for each of the other keys
+//   - the compact int encoding the size of the leaf script and the script
+//   - the compact int encoding the size of the control block and the
+//     control block
+func TapscriptInputSize(numKeys, numSigs, scriptSize,
+	ctrlBlockSize int) InputSize {
+
+	witnessWeight := wire.VarIntSerializeSize(uint64(numKeys+2)) +
+		numSigs*(1+65) + (numKeys - numSigs) +
+		wire.VarIntSerializeSize(uint64(scriptSize)) + scriptSize +
+		wire.VarIntSerializeSize(uint64(ctrlBlockSize)) + ctrlBlockSize
+
+	return InputSize{
+		BaseSize:      RedeemP2TRInputSize,
+		WitnessWeight: witnessWeight,
+	}
+}
+
 // EstimateVirtualSize returns a worst case virtual size estimate for a
 // signed transaction that spends the given number of P2PKH, P2TR, P2WPKH and
 // (nested) P2SH-P2WPKH outputs, and contains each transaction output
@@ -202,6 +291,19 @@ func EstimateSerializeSize(inputCount int, txOuts []*wire.TxOut, addChangeOutput
 // change output if addChangeOutput is true.
 func EstimateVirtualSize(numP2PKHIns, numP2TRIns, numP2WPKHIns, numNestedP2WPKHIns int,
 	txOuts []*wire.TxOut, changeScriptSize int) int {
+
+	return EstimateVirtualSizeWithInputs(
+		numP2PKHIns, numP2TRIns, numP2WPKHIns, numNestedP2WPKHIns, nil,
+		txOuts, changeScriptSize,
+	)
+}
+
+// EstimateVirtualSizeWithInputs is like EstimateVirtualSize, but additionally
+// counts the inputs of the sizes provided.
+func EstimateVirtualSizeWithInputs(numP2PKHIns, numP2TRIns, numP2WPKHIns,
+	numNestedP2WPKHIns int, otherIns []InputSize, txOuts []*wire.TxOut,
+	changeScriptSize int) int {
+
 	outputCount := len(txOuts)
 
 	changeOutputSize := 0
@@ -212,31 +314,44 @@ func EstimateVirtualSize(numP2PKHIns, numP2TRIns, numP2WPKHIns, numNestedP2WPKHI
 		outputCount++
 	}
 
+	var otherBaseSize, otherWitnessWeight, numOtherWitnessIns int
+	for _, in := range otherIns {
+		otherBaseSize += in.BaseSize
+		otherWitnessWeight += in.WitnessWeight
+		if in.WitnessWeight > 0 {
+			numOtherWitnessIns++
+		}
+	}
+
 	// Version 4 bytes + LockTime 4 bytes + Serialized var int size for the
 	// number of transaction inputs and outputs + size of redeem scripts +
 	// the size out the serialized outputs and change.
 	baseSize := 8 +
 		wire.VarIntSerializeSize(
-			uint64(numP2PKHIns+numP2TRIns+numP2WPKHIns+numNestedP2WPKHIns)) +
+			uint64(numP2PKHIns+numP2TRIns+numP2WPKHIns+
+				numNestedP2WPKHIns+len(otherIns))) +
 		wire.VarIntSerializeSize(uint64(len(txOuts))) +
 		numP2PKHIns*RedeemP2PKHInputSize +
 		numP2WPKHIns*RedeemP2WPKHInputSize +
 		numP2TRIns*RedeemP2TRInputSize +
 		numNestedP2WPKHIns*RedeemNestedP2WPKHInputSize +
+		otherBaseSize +
 		SumOutputSerializeSizes(txOuts) +
 		changeOutputSize
 
 	// If this transaction has any witness inputs, we must count the
 	// witness data.
 	witnessWeight := 0
-	if numP2WPKHIns+numNestedP2WPKHIns+numP2TRIns > 0 {
+	numWitnessIns := numP2WPKHIns + numNestedP2WPKHIns + numP2TRIns +
+		numOtherWitnessIns
+	if numWitnessIns > 0 {
 		// Additional 2 weight units for segwit marker + flag.
 		witnessWeight = 2 +
-			wire.VarIntSerializeSize(
-				uint64(numP2WPKHIns+numNestedP2WPKHIns+numP2TRIns)) +
+			wire.VarIntSerializeSize(uint64(numWitnessIns)) +
 			numP2WPKHIns*RedeemP2WPKHInputWitnessWeight +
 			numP2TRIns*RedeemP2TRInputWitnessWeight +
-			numNestedP2WPKHIns*RedeemP2WPKHInputWitnessWeight
+			numNestedP2WPKHIns*RedeemP2WPKHInputWitnessWeight +
+			otherWitnessWeight
 	}
 
 	// We add 3 to the witness weight to make sure the result is
@@ -181,3 +181,87 @@ func TestEstimateVirtualSize(t *testing.T) {
 		}
 	}
 }
+
+// TestMultisigInputSize tests that the size of multisig inputs is the size of
+// inputs with worst case witnesses.
+func TestMultisigInputSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		threshold, numKeys int
+		nested             bool
+	}{
+		{1, 1, false},
+		{2, 3, false},
+		{2, 3, true},
+		{15, 20, true},
+	}
+	for _, test := range tests {
+		scriptSize := 3 + test.numKeys*34
+		if test.numKeys > 16 {
+			scriptSize++
+		}
+		witness := wire.TxWitness{nil}
+		for i := 0; i < test.threshold; i++ {
+			witness = append(witness, make([]byte, 73))
+		}
+		witness = append(witness, make([]byte, scriptSize))
+
+		txIn := wire.NewTxIn(&wire.OutPoint{}, nil, witness)
+		if test.nested {
+			txIn.SignatureScript = make([]byte, 35)
+		}
+
+		size := MultisigInputSize(test.threshold, test.numKeys, test.nested)
+		if size.BaseSize != txIn.SerializeSize() {
+			t.Errorf("%d-of-%d: base size %d, expected %d",
+				test.threshold, test.numKeys, size.BaseSize,
+				txIn.SerializeSize())
+		}
+		if size.WitnessWeight != witness.SerializeSize() {
+			t.Errorf("%d-of-%d: witness weight %d, expected %d",
+				test.threshold, test.numKeys, size.WitnessWeight,
+				witness.SerializeSize())
+		}
+	}
+}
+
+func TestTapscriptInputSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		numKeys, numSigs, scriptSize, ctrlBlockSize int
+	}{
+		{1, 1, 34, 33},
+		{3, 2, 104, 65},
+		{20, 15, 700, 33 + 32*7},
+	}
+	for _, test := range tests {
+		var witness wire.TxWitness
+		for i := 0; i < test.numKeys; i++ {
+			if i < test.numSigs {
+				witness = append(witness, make([]byte, 65))
+				continue
+			}
+			witness = append(witness, nil)
+		}
+		witness = append(witness, make([]byte, test.scriptSize),
+			make([]byte, test.ctrlBlockSize))
+
+		txIn := wire.NewTxIn(&wire.OutPoint{}, nil, witness)
+		size := TapscriptInputSize(
+			test.numKeys, test.numSigs, test.scriptSize,
+			test.ctrlBlockSize,
+		)
+		if size.BaseSize != txIn.SerializeSize() {
+			t.Errorf("%d-of-%d: base size %d, expected %d",
+				test.numSigs, test.numKeys, size.BaseSize,
+				txIn.SerializeSize())
+		}
+		if size.WitnessWeight != witness.SerializeSize() {
+			t.Errorf("%d-of-%d: witness weight %d, expected %d",
+				test.numSigs, test.numKeys, size.WitnessWeight,
+				witness.SerializeSize())
+		}
+	}
+}
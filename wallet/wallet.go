@@ -15,11 +15,15 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/bisoncraft/utxowallet/addrbook"
 	"github.com/bisoncraft/utxowallet/chain"
+	"github.com/bisoncraft/utxowallet/netparams"
+	"github.com/bisoncraft/utxowallet/snacl"
 	"github.com/bisoncraft/utxowallet/waddrmgr"
 	"github.com/bisoncraft/utxowallet/wallet/txauthor"
 	"github.com/bisoncraft/utxowallet/wallet/txrules"
 	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/walletdb/encrypted"
 	"github.com/bisoncraft/utxowallet/walletdb/migration"
 	"github.com/bisoncraft/utxowallet/wtxmgr"
 	"github.com/btcsuite/btcd/blockchain"
@@ -31,6 +35,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -86,6 +91,7 @@ var (
 	// Namespace bucket keys.
 	waddrmgrNamespaceKey = []byte("waddrmgr")
 	wtxmgrNamespaceKey   = []byte("wtxmgr")
+	addrbookNamespaceKey = []byte("addrbook")
 )
 
 // Coin represents a spendable UTXO which is available for coin selection.
@@ -95,14 +101,40 @@ type Coin struct {
 	wire.OutPoint
 }
 
+// CoinSelectionParams describes the transaction being funded to a coin
+// selection strategy.
+type CoinSelectionParams struct {
+	// FeeSatPerKb is the fee rate of the transaction.
+	FeeSatPerKb btcutil.Amount
+
+	// LongTermFeeSatPerKb is the fee rate coins are expected to be spent
+	// at in the future. Spending coins at a lower fee rate consolidates
+	// them cheaply, while spending them at a higher fee rate wastes fees.
+	LongTermFeeSatPerKb btcutil.Amount
+
+	// Target is the amount the effective values of the selected coins,
+	// their values less the fees paid to spend them, must at least sum up
+	// to. It is made up of the value of the outputs and the fee of the
+	// transaction without inputs and change.
+	Target btcutil.Amount
+
+	// ChangeCost is the amount by which the effective values of the
+	// selected coins may exceed the target without a change output being
+	// created. It is made up of the fee of a change output and the largest
+	// change value that is dust. Any excess up to the change cost is paid
+	// as fee.
+	ChangeCost btcutil.Amount
+}
+
 // CoinSelectionStrategy is an interface that represents a coin selection
 // strategy. A coin selection strategy is responsible for ordering, shuffling or
 // filtering a list of coins before they are passed to the coin selection
-// algorithm.
+// algorithm. Coins are added to the transaction in the order they are arranged
+// in until the transaction is funded.
 type CoinSelectionStrategy interface {
 	// ArrangeCoins takes a list of coins and arranges them according to the
-	// specified coin selection strategy and fee rate.
-	ArrangeCoins(eligible []Coin, feeSatPerKb btcutil.Amount) ([]Coin,
+	// specified coin selection strategy and the transaction being funded.
+	ArrangeCoins(eligible []Coin, params CoinSelectionParams) ([]Coin,
 		error)
 }
 
@@ -115,6 +147,16 @@ var (
 	// transaction. This strategy prevents the creation of ever smaller
 	// utxos over time.
 	CoinSelectionRandom CoinSelectionStrategy = &RandomCoinSelector{}
+
+	// CoinSelectionBranchAndBound searches for a set of utxos funding the
+	// transaction without change, falling back to a knapsack solver and
+	// then to the largest utxos if there is none.
+	CoinSelectionBranchAndBound CoinSelectionStrategy = &BranchAndBoundCoinSelector{}
+
+	// CoinSelectionConsolidate picks the smallest utxos first when the fee
+	// rate is at most the long-term fee rate, consolidating them while it
+	// is cheap, and selects like CoinSelectionBranchAndBound otherwise.
+	CoinSelectionConsolidate CoinSelectionStrategy = &ConsolidatingCoinSelector{}
 )
 
 // Wallet is a structure containing all the components for a
@@ -138,6 +180,12 @@ type Wallet struct {
 	lockedOutpoints    map[wire.OutPoint]struct{}
 	lockedOutpointsMtx sync.Mutex
 
+	muSig2Sessions    map[MuSig2SessionID]*muSig2Session
+	muSig2SessionsMtx sync.Mutex
+
+	externalSigner    ExternalSigner
+	externalSignerMtx sync.Mutex
+
 	recovering     atomic.Value
 	recoveryWindow uint32
 
@@ -163,6 +211,7 @@ type Wallet struct {
 
 	NtfnServer *NotificationServer
 
+	netParams   *netparams.ChainParams
 	chainParams *chaincfg.Params
 	wg          sync.WaitGroup
 
@@ -173,6 +222,20 @@ type Wallet struct {
 	// syncRetryInterval is the amount of time to wait between re-tries on
 	// errors during initial sync.
 	syncRetryInterval time.Duration
+
+	// kdfUpgrade holds the key derivation parameters the master keys of
+	// the address manager are re-wrapped with, if weaker, when the wallet
+	// is next unlocked, and which new passphrase keys are derived with.
+	// It is not modified after the wallet is loaded.
+	kdfUpgrade *waddrmgr.ScryptOptions
+
+	// kdfUpgraded is set once the master keys have been checked against
+	// kdfUpgrade, and upgraded if needed.  It is only accessed by the
+	// walletLocker.
+	kdfUpgraded bool
+
+	// log is the logger the wallet writes to.
+	log btclog.Logger
 }
 
 // Start starts the goroutines necessary to manage a wallet.
@@ -404,18 +467,18 @@ func (w *Wallet) syncWithChain(birthdayStamp *waddrmgr.BlockStamp) error {
 	// Neutrino, where the cfheader server tells us what it believes the
 	// chain tip is.
 	if !w.isDevEnv() || neutrinoRecovery {
-		log.Debug("Waiting for chain backend to sync to tip")
+		w.log.Debug("Waiting for chain backend to sync to tip")
 		if err := w.waitUntilBackendSynced(chainClient); err != nil {
 			return err
 		}
-		log.Debug("Chain backend synced to tip!")
+		w.log.Debug("Chain backend synced to tip!")
 	}
 
 	// If we've yet to find our birthday block, we'll do so now.
 	if birthdayStamp == nil {
 		var err error
 		birthdayStamp, err = locateBirthdayBlock(
-			chainClient, w.Manager.Birthday(),
+			w.log, chainClient, w.Manager.Birthday(),
 		)
 		if err != nil {
 			return fmt.Errorf("unable to locate birthday block: %w",
@@ -600,7 +663,7 @@ func (w *Wallet) waitUntilBackendSynced(chainClient chain.Interface) error {
 // locateBirthdayBlock returns a block that meets the given birthday timestamp
 // by a margin of +/-2 hours. This is safe to do as the timestamp is already 2
 // days in the past of the actual timestamp.
-func locateBirthdayBlock(chainClient chainConn,
+func locateBirthdayBlock(logger btclog.Logger, chainClient chainConn,
 	birthday time.Time) (*waddrmgr.BlockStamp, error) {
 
 	// Retrieve the lookup range for our block.
@@ -610,7 +673,7 @@ func locateBirthdayBlock(chainClient chainConn,
 		return nil, err
 	}
 
-	log.Debugf("Locating suitable block for birthday %v between blocks "+
+	logger.Debugf("Locating suitable block for birthday %v between blocks "+
 		"%v-%v", birthday, startHeight, bestHeight)
 
 	var (
@@ -633,7 +696,7 @@ func locateBirthdayBlock(chainClient chainConn,
 			return nil, err
 		}
 
-		log.Debugf("Checking candidate block: height=%v, hash=%v, "+
+		logger.Debugf("Checking candidate block: height=%v, hash=%v, "+
 			"timestamp=%v", mid, hash, header.Timestamp)
 
 		// If the search happened to reach either of our range extremes,
@@ -669,7 +732,7 @@ func locateBirthdayBlock(chainClient chainConn,
 		break
 	}
 
-	log.Debugf("Found birthday block: height=%d, hash=%v, timestamp=%v",
+	logger.Debugf("Found birthday block: height=%d, hash=%v, timestamp=%v",
 		birthdayBlock.Height, birthdayBlock.Hash,
 		birthdayBlock.Timestamp)
 
@@ -691,7 +754,7 @@ type recoverySyncer struct {
 func (w *Wallet) recovery(chainClient chain.Interface,
 	birthdayBlock *waddrmgr.BlockStamp) error {
 
-	log.Infof("RECOVERY MODE ENABLED -- rescanning for used addresses "+
+	w.log.Infof("RECOVERY MODE ENABLED -- rescanning for used addresses "+
 		"with recovery_window=%d", w.recoveryWindow)
 
 	// Wallet locking must synchronize with the end of recovery, since use of
@@ -706,6 +769,7 @@ func (w *Wallet) recovery(chainClient chain.Interface,
 	recoveryMgr := NewRecoveryManager(
 		w.recoveryWindow, recoveryBatchSize, w.chainParams,
 	)
+	recoveryMgr.log = w.log
 
 	// In the event that this recovery is being resumed, we will need to
 	// repopulate all found addresses from the database. Ideally, for basic
@@ -807,7 +871,7 @@ func (w *Wallet) recovery(chainClient chain.Interface,
 			}
 
 			if len(recoveryBatch) > 0 {
-				log.Infof("Recovered addresses from blocks "+
+				w.log.Infof("Recovered addresses from blocks "+
 					"%d-%d", recoveryBatch[0].Height,
 					recoveryBatch[len(recoveryBatch)-1].Height)
 			}
@@ -848,7 +912,7 @@ func (w *Wallet) recoverScopedAddresses(
 		return nil
 	}
 
-	log.Infof("Scanning %d blocks for recoverable addresses", len(batch))
+	w.log.Infof("Scanning %d blocks for recoverable addresses", len(batch))
 
 expandHorizons:
 	for scope, scopedMgr := range scopedMgrs {
@@ -885,7 +949,7 @@ expandHorizons:
 	block := batch[filterResp.BatchIndex]
 
 	// Log any non-trivial findings of addresses or outpoints.
-	logFilterBlocksResp(block, filterResp)
+	logFilterBlocksResp(w.log, block, filterResp)
 
 	// Report any external or internal addresses found as a result of the
 	// appropriate branch recovery state. Adding indexes above the
@@ -1161,7 +1225,7 @@ func extendFoundAddresses(ns walletdb.ReadWriteBucket,
 
 // logFilterBlocksResp provides useful logging information when filtering
 // succeeded in finding relevant transactions.
-func logFilterBlocksResp(block wtxmgr.BlockMeta,
+func logFilterBlocksResp(logger btclog.Logger, block wtxmgr.BlockMeta,
 	resp *chain.FilterBlocksResponse) {
 
 	// Log the number of external addresses found in this block.
@@ -1170,7 +1234,7 @@ func logFilterBlocksResp(block wtxmgr.BlockMeta,
 		nFoundExternal += len(indexes)
 	}
 	if nFoundExternal > 0 {
-		log.Infof("Recovered %d external addrs at height=%d hash=%v",
+		logger.Infof("Recovered %d external addrs at height=%d hash=%v",
 			nFoundExternal, block.Height, block.Hash)
 	}
 
@@ -1180,14 +1244,14 @@ func logFilterBlocksResp(block wtxmgr.BlockMeta,
 		nFoundInternal += len(indexes)
 	}
 	if nFoundInternal > 0 {
-		log.Infof("Recovered %d internal addrs at height=%d hash=%v",
+		logger.Infof("Recovered %d internal addrs at height=%d hash=%v",
 			nFoundInternal, block.Height, block.Hash)
 	}
 
 	// Log the number of outpoints found in this block.
 	nFoundOutPoints := len(resp.FoundOutPoints)
 	if nFoundOutPoints > 0 {
-		log.Infof("Found %d spends from watched outpoints at "+
+		logger.Infof("Found %d spends from watched outpoints at "+
 			"height=%d hash=%v",
 			nFoundOutPoints, block.Height, block.Hash)
 	}
@@ -1206,6 +1270,7 @@ type (
 		resp                  chan createTxResponse
 		selectUtxos           []wire.OutPoint
 		allowUtxo             func(wtxmgr.Credit) bool
+		authoring             *txAuthoringOptions
 	}
 	createTxResponse struct {
 		tx  *txauthor.AuthoredTx
@@ -1248,6 +1313,7 @@ out:
 				txr.changeKeyScope, txr.account, txr.minconf,
 				txr.feeSatPerKB, txr.coinSelectionStrategy,
 				txr.dryRun, txr.selectUtxos, txr.allowUtxo,
+				txr.authoring,
 			)
 
 			release()
@@ -1266,6 +1332,11 @@ type txCreateOptions struct {
 	changeKeyScope *waddrmgr.KeyScope
 	selectUtxos    []wire.OutPoint
 	allowUtxo      func(wtxmgr.Credit) bool
+	authoring      txAuthoringOptions
+
+	// err is the first error of an option which could not be applied, which
+	// fails the creation of the transaction.
+	err error
 }
 
 // TxCreateOption is a set of optional arguments to modify the tx creation
@@ -1305,6 +1376,97 @@ func WithUtxoFilter(allowUtxo func(utxo wtxmgr.Credit) bool) TxCreateOption {
 	}
 }
 
+// WithLockTime sets the absolute lock time of the transaction. The transaction
+// can not be mined in a block of a lower height or, for lock times of at least
+// txscript.LockTimeThreshold, a lower median time past. If unspecified, the
+// lock time is set to the current height to discourage fee sniping.
+func WithLockTime(lockTime uint32) TxCreateOption {
+	return func(opts *txCreateOptions) {
+		opts.authoring.lockTime = &lockTime
+	}
+}
+
+// WithTxVersion sets the version of the transaction. Relative lock times, as
+// described by BIP-0068, require a version of at least 2.
+func WithTxVersion(version int32) TxCreateOption {
+	return func(opts *txCreateOptions) {
+		opts.authoring.version = version
+	}
+}
+
+// WithInputSequence sets the sequence number of the input spending the given
+// outpoint, which must be spent by the transaction.
+func WithInputSequence(outpoint wire.OutPoint,
+	sequence uint32) TxCreateOption {
+
+	return func(opts *txCreateOptions) {
+		if opts.authoring.sequences == nil {
+			opts.authoring.sequences = make(map[wire.OutPoint]uint32)
+		}
+		opts.authoring.sequences[outpoint] = sequence
+	}
+}
+
+// WithRelativeLockTime sets the relative lock time of the input spending the
+// given outpoint, as described by BIP-0068. The lock time is a number of
+// blocks or, if isSeconds is true, a number of seconds, which is rounded up to
+// a multiple of 512 seconds. Lock times above 0xffff blocks or 0xffff*512
+// seconds fail the creation of the transaction. The transaction version is
+// raised to 2 if it is lower.
+func WithRelativeLockTime(outpoint wire.OutPoint, isSeconds bool,
+	lockTime uint32) TxCreateOption {
+
+	return func(opts *txCreateOptions) {
+		sequence, err := relativeLockTimeSequence(isSeconds, lockTime)
+		if err != nil {
+			if opts.err == nil {
+				opts.err = err
+			}
+			return
+		}
+		WithInputSequence(outpoint, sequence)(opts)
+		if opts.authoring.version < 2 {
+			opts.authoring.version = 2
+		}
+	}
+}
+
+// relativeLockTimeSequence returns the sequence number of an input with the
+// relative lock time, in blocks or seconds. Seconds are rounded up to the
+// granularity of BIP-0068, so the input is never spendable earlier than
+// requested.
+func relativeLockTimeSequence(isSeconds bool, lockTime uint32) (uint32,
+	error) {
+
+	if !isSeconds {
+		if lockTime > wire.SequenceLockTimeMask {
+			return 0, fmt.Errorf("relative lock time of %d blocks "+
+				"exceeds the maximum of %d", lockTime,
+				wire.SequenceLockTimeMask)
+		}
+		return lockTime, nil
+	}
+
+	const granularity = 1 << wire.SequenceLockTimeGranularity
+	units := (uint64(lockTime) + granularity - 1) >>
+		wire.SequenceLockTimeGranularity
+	if units > wire.SequenceLockTimeMask {
+		return 0, fmt.Errorf("relative lock time of %d seconds "+
+			"exceeds the maximum of %d", lockTime,
+			wire.SequenceLockTimeMask*granularity)
+	}
+	return wire.SequenceLockTimeIsSeconds | uint32(units), nil
+}
+
+// withoutExternalSigner leaves the inputs of watch-only accounts unsigned
+// rather than having them signed by the external signer of the wallet, for
+// transactions that are signed later on.
+func withoutExternalSigner() TxCreateOption {
+	return func(opts *txCreateOptions) {
+		opts.authoring.skipExternalSigner = true
+	}
+}
+
 // CreateSimpleTx creates a new signed transaction spending unspent outputs with
 // at least minconf confirmations spending to any number of address/amount
 // pairs. Only unspent outputs belonging to the given key scope and account will
@@ -1320,6 +1482,13 @@ func WithUtxoFilter(allowUtxo func(utxo wtxmgr.Credit) bool) TxCreateOption {
 // tx creation process such as using a custom change scope, which otherwise
 // defaults to the same as the specified coin selection scope.
 //
+// If satPerKb is zero, the fee rate is estimated by the chain backend for
+// DefaultFeeConfTarget.
+//
+// Transactions spending from watch-only accounts are returned unsigned, unless
+// the wallet has an external signer set with SetExternalSigner, in which case
+// they are signed by it.
+//
 // NOTE: The dryRun argument can be set true to create a tx that doesn't alter
 // the database. A tx created with this set to true SHOULD NOT be broadcast.
 func (w *Wallet) CreateSimpleTx(coinSelectKeyScope *waddrmgr.KeyScope,
@@ -1331,6 +1500,9 @@ func (w *Wallet) CreateSimpleTx(coinSelectKeyScope *waddrmgr.KeyScope,
 	for _, optFunc := range optFuncs {
 		optFunc(opts)
 	}
+	if opts.err != nil {
+		return nil, opts.err
+	}
 
 	// If the change scope isn't set, then it should be the same as the
 	// coin selection scope in order to match existing behavior.
@@ -1338,6 +1510,11 @@ func (w *Wallet) CreateSimpleTx(coinSelectKeyScope *waddrmgr.KeyScope,
 		opts.changeKeyScope = coinSelectKeyScope
 	}
 
+	satPerKb, err := w.feeRateOrEstimate(satPerKb)
+	if err != nil {
+		return nil, err
+	}
+
 	req := createTxRequest{
 		coinSelectKeyScope:    coinSelectKeyScope,
 		changeKeyScope:        opts.changeKeyScope,
@@ -1350,6 +1527,7 @@ func (w *Wallet) CreateSimpleTx(coinSelectKeyScope *waddrmgr.KeyScope,
 		resp:                  make(chan createTxResponse),
 		selectUtxos:           opts.selectUtxos,
 		allowUtxo:             opts.allowUtxo,
+		authoring:             &opts.authoring,
 	}
 	w.createTxRequests <- req
 	resp := <-req.resp
@@ -1417,42 +1595,58 @@ out:
 				req.err <- err
 				continue
 			}
+			if w.kdfUpgrade != nil && !w.kdfUpgraded {
+				w.upgradeKDF(req.passphrase)
+			}
 			timeout = req.lockAfter
 			if timeout == nil {
-				log.Info("The wallet has been unlocked without a time limit")
+				w.log.Info("The wallet has been unlocked " +
+					"without a time limit")
 			} else {
-				log.Info("The wallet has been temporarily unlocked")
+				w.log.Info("The wallet has been temporarily " +
+					"unlocked")
 			}
 			req.err <- nil
 			continue
 
 		case req := <-w.changePassphrase:
 			err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+				if !req.private {
+					return w.changePublicPassphrase(
+						tx, req.old, req.new,
+					)
+				}
+
 				addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
 				return w.Manager.ChangePassphrase(
 					addrmgrNs, req.old, req.new, req.private,
-					&waddrmgr.DefaultScryptOptions,
+					w.kdfOptions(),
 				)
 			})
+			if err == nil && !req.private {
+				w.publicPassphrase = req.new
+			}
 			req.err <- err
 			continue
 
 		case req := <-w.changePassphrases:
 			err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
-				addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
-				err := w.Manager.ChangePassphrase(
-					addrmgrNs, req.publicOld, req.publicNew,
-					false, &waddrmgr.DefaultScryptOptions,
+				err := w.changePublicPassphrase(
+					tx, req.publicOld, req.publicNew,
 				)
 				if err != nil {
 					return err
 				}
 
+				addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
 				return w.Manager.ChangePassphrase(
 					addrmgrNs, req.privateOld, req.privateNew,
-					true, &waddrmgr.DefaultScryptOptions,
+					true, w.kdfOptions(),
 				)
 			})
+			if err == nil {
+				w.publicPassphrase = req.publicNew
+			}
 			req.err <- err
 			continue
 
@@ -1497,14 +1691,108 @@ out:
 		timeout = nil
 		err := w.Manager.Lock()
 		if err != nil && !waddrmgr.IsError(err, waddrmgr.ErrLocked) {
-			log.Errorf("Could not lock wallet: %v", err)
+			w.log.Errorf("Could not lock wallet: %v", err)
 		} else {
-			log.Info("The wallet has been locked")
+			w.log.Info("The wallet has been locked")
 		}
 	}
 	w.wg.Done()
 }
 
+// changePublicPassphrase changes the public passphrase of the address manager
+// in the transaction.  If the wallet database is encrypted with the public
+// passphrase, its key is re-encrypted with the new passphrase in the same
+// transaction, so the database still opens with the passphrase of the wallet.
+//
+// The database key is re-encrypted first, as the address manager replaces its
+// public master key in memory when its passphrase is changed, which a failure
+// rolling back the transaction afterwards would not undo.
+func (w *Wallet) changePublicPassphrase(tx walletdb.ReadWriteTx, old,
+	new []byte) error {
+
+	err := encrypted.ChangePassphrase(
+		tx, old, new, dbSecretKeyGenerator(w.kdfOptions()),
+	)
+	switch {
+	case errors.Is(err, encrypted.ErrInvalidPassphrase):
+		return waddrmgr.ManagerError{
+			ErrorCode:   waddrmgr.ErrWrongPassphrase,
+			Description: "invalid passphrase for master public key",
+			Err:         err,
+		}
+	case err != nil && !errors.Is(err, encrypted.ErrNotEncrypted):
+		return err
+	}
+
+	addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+	return w.Manager.ChangePassphrase(
+		addrmgrNs, old, new, false, w.kdfOptions(),
+	)
+}
+
+// dbSecretKeyGenerator returns the generator of the keys the master key of an
+// encrypted wallet database is encrypted with, which derives them like the
+// passphrase keys of the address manager, using the options provided.
+func dbSecretKeyGenerator(
+	options *waddrmgr.ScryptOptions) encrypted.SecretKeyGenerator {
+
+	return func(passphrase *[]byte) (*snacl.SecretKey, error) {
+		return waddrmgr.NewSecretKey(passphrase, options)
+	}
+}
+
+// kdfOptions returns the key derivation parameters of new passphrase keys of
+// the wallet, which are those of the KDF upgrade the wallet was loaded with, if
+// any, or the default scrypt parameters.
+func (w *Wallet) kdfOptions() *waddrmgr.ScryptOptions {
+	if w.kdfUpgrade != nil {
+		return w.kdfUpgrade
+	}
+	return &waddrmgr.DefaultScryptOptions
+}
+
+// upgradeKDF re-wraps the master keys of the address manager with passphrase
+// keys derived using the kdfUpgrade parameters when they switch to Argon2id or
+// are stronger than the current ones.  The key of an encrypted wallet database
+// is re-wrapped along with them, in the same transaction, and the address
+// manager only replaces its keys in memory once the transaction is committed.
+// Failing to upgrade is logged rather than failing the unlock, and the upgrade
+// is tried again on the next unlock.
+func (w *Wallet) upgradeKDF(privPassphrase []byte) {
+	var upgraded bool
+	err := walletdb.Update(w.db, func(tx walletdb.ReadWriteTx) error {
+		addrmgrNs := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		var err error
+		upgraded, err = w.Manager.UpgradeKDF(
+			addrmgrNs, w.publicPassphrase, privPassphrase,
+			w.kdfUpgrade,
+		)
+		if err != nil || !upgraded {
+			return err
+		}
+
+		err = encrypted.ChangePassphrase(
+			tx, w.publicPassphrase, w.publicPassphrase,
+			dbSecretKeyGenerator(w.kdfUpgrade),
+		)
+		if errors.Is(err, encrypted.ErrNotEncrypted) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		w.log.Errorf("Unable to upgrade the key derivation parameters "+
+			"of the wallet: %v", err)
+		return
+	}
+
+	if upgraded {
+		w.log.Infof("Upgraded the key derivation of the wallet "+
+			"passphrases to %v", w.kdfUpgrade.KDF)
+	}
+	w.kdfUpgraded = true
+}
+
 // Unlock unlocks the wallet's address manager and relocks it after timeout has
 // expired.  If the wallet is already unlocked and the new passphrase is
 // correct, the current timeout is replaced with the new one.  The wallet will
@@ -2034,8 +2322,8 @@ func (w *Wallet) NextAccount(scope waddrmgr.KeyScope, name string) (uint32, erro
 		return err
 	})
 	if err != nil {
-		log.Errorf("Cannot fetch new account properties for notification "+
-			"after account creation: %v", err)
+		w.log.Errorf("Cannot fetch new account properties for "+
+			"notification after account creation: %v", err)
 	} else {
 		w.NtfnServer.notifyAccountProperties(props)
 	}
@@ -2097,6 +2385,7 @@ func listTransactions(tx walletdb.ReadTx, details *wtxmgr.TxDetails, addrMgr *wa
 	syncHeight int32, net *chaincfg.Params) []btcjson.ListTransactionsResult {
 
 	addrmgrNs := tx.ReadBucket(waddrmgrNamespaceKey)
+	addrbookNs := tx.ReadBucket(addrbookNamespaceKey)
 
 	var (
 		blockHashStr  string
@@ -2189,6 +2478,9 @@ outputs:
 			WalletConflicts: []string{},
 			Time:            received,
 			TimeReceived:    received,
+			Label: outputLabel(
+				addrbookNs, details, uint32(i), address,
+			),
 		}
 
 		// Add a received/generated/immature result if this is a credit.
@@ -2909,7 +3201,7 @@ func (w *Wallet) ListLeasedOutputs() ([]*ListLeasedOutputResult, error) {
 			}
 
 			if details == nil {
-				log.Infof("unable to find tx details for "+
+				w.log.Infof("unable to find tx details for "+
 					"%v:%v", output.Outpoint.Hash,
 					output.Outpoint.Index)
 				continue
@@ -3098,7 +3390,7 @@ func (w *Wallet) resendUnminedTxs() {
 		return err
 	})
 	if err != nil {
-		log.Errorf("Unable to retrieve unconfirmed transactions to "+
+		w.log.Errorf("Unable to retrieve unconfirmed transactions to "+
 			"resend: %v", err)
 		return
 	}
@@ -3106,13 +3398,13 @@ func (w *Wallet) resendUnminedTxs() {
 	for _, tx := range txs {
 		txHash, err := w.publishTransaction(tx)
 		if err != nil {
-			log.Debugf("Unable to rebroadcast transaction %v: %v",
+			w.log.Debugf("Unable to rebroadcast transaction %v: %v",
 				tx.TxHash(), err)
 			continue
 		}
 
-		log.Debugf("Successfully rebroadcast unconfirmed transaction %v",
-			txHash)
+		w.log.Debugf("Successfully rebroadcast unconfirmed "+
+			"transaction %v", txHash)
 	}
 }
 
@@ -3194,8 +3486,9 @@ func (w *Wallet) newAddress(addrmgrNs walletdb.ReadWriteBucket, account uint32,
 
 	props, err := manager.AccountProperties(addrmgrNs, account)
 	if err != nil {
-		log.Errorf("Cannot fetch account properties for notification "+
-			"after deriving next external address: %v", err)
+		w.log.Errorf("Cannot fetch account properties for "+
+			"notification after deriving next external address: %v",
+			err)
 		return nil, nil, err
 	}
 
@@ -3411,15 +3704,17 @@ func (w *Wallet) TotalReceivedForAddr(addr btcutil.Address, minConf int32) (btcu
 // accounts matching the account number provided across all key scopes may be
 // selected. This is done to handle the default account case, where a user wants
 // to fund a PSBT with inputs regardless of their type (NP2WKH, P2WKH, etc.). It
-// returns the transaction upon success.
+// returns the transaction upon success. If satPerKb is zero, the fee rate is
+// estimated by the chain backend. Options such as the lock time of the
+// transaction can be passed in.
 func (w *Wallet) SendOutputs(outputs []*wire.TxOut, keyScope *waddrmgr.KeyScope,
 	account uint32, minconf int32, satPerKb btcutil.Amount,
-	coinSelectionStrategy CoinSelectionStrategy, label string) (*wire.MsgTx,
-	error) {
+	coinSelectionStrategy CoinSelectionStrategy, label string,
+	optFuncs ...TxCreateOption) (*wire.MsgTx, error) {
 
 	return w.sendOutputs(
 		outputs, keyScope, account, minconf, satPerKb,
-		coinSelectionStrategy, label,
+		coinSelectionStrategy, label, nil, optFuncs...,
 	)
 }
 
@@ -3429,10 +3724,11 @@ func (w *Wallet) SendOutputsWithInput(outputs []*wire.TxOut,
 	keyScope *waddrmgr.KeyScope,
 	account uint32, minconf int32, satPerKb btcutil.Amount,
 	coinSelectionStrategy CoinSelectionStrategy, label string,
-	selectedUtxos []wire.OutPoint) (*wire.MsgTx, error) {
+	selectedUtxos []wire.OutPoint,
+	optFuncs ...TxCreateOption) (*wire.MsgTx, error) {
 
 	return w.sendOutputs(outputs, keyScope, account, minconf, satPerKb,
-		coinSelectionStrategy, label, selectedUtxos...)
+		coinSelectionStrategy, label, selectedUtxos, optFuncs...)
 }
 
 // sendOutputs creates and sends payment transactions. It returns the
@@ -3440,13 +3736,14 @@ func (w *Wallet) SendOutputsWithInput(outputs []*wire.TxOut,
 func (w *Wallet) sendOutputs(outputs []*wire.TxOut, keyScope *waddrmgr.KeyScope,
 	account uint32, minconf int32, satPerKb btcutil.Amount,
 	coinSelectionStrategy CoinSelectionStrategy, label string,
-	selectedUtxos ...wire.OutPoint) (*wire.MsgTx, error) {
+	selectedUtxos []wire.OutPoint,
+	optFuncs ...TxCreateOption) (*wire.MsgTx, error) {
 
 	// Ensure the outputs to be created adhere to the network's consensus
 	// rules.
 	for _, output := range outputs {
 		err := txrules.CheckOutput(
-			output, txrules.DefaultRelayFeePerKb,
+			output, txrules.DefaultRelayFeePerKb, w.netParams,
 		)
 		if err != nil {
 			return nil, err
@@ -3457,20 +3754,22 @@ func (w *Wallet) sendOutputs(outputs []*wire.TxOut, keyScope *waddrmgr.KeyScope,
 	// transaction will be added to the database in order to ensure that we
 	// continue to re-broadcast the transaction upon restarts until it has
 	// been confirmed.
+	optFuncs = append([]TxCreateOption{
+		WithCustomSelectUtxos(selectedUtxos),
+	}, optFuncs...)
 	createdTx, err := w.CreateSimpleTx(
 		keyScope, account, outputs, minconf, satPerKb,
-		coinSelectionStrategy, false, WithCustomSelectUtxos(
-			selectedUtxos,
-		),
+		coinSelectionStrategy, false, optFuncs...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// If our wallet is read-only, we'll get a transaction with coins
-	// selected but no witness data. In such a case we need to inform our
-	// caller that they'll actually need to go ahead and sign the TX.
-	if w.Manager.WatchOnly() {
+	// If our wallet is read-only and has no external signer, we'll get a
+	// transaction with coins selected but no witness data. In such a case
+	// we need to inform our caller that they'll actually need to go ahead
+	// and sign the TX.
+	if !inputsSigned(createdTx.Tx) {
 		return createdTx.Tx, ErrTxUnsigned
 	}
 
@@ -3487,6 +3786,17 @@ func (w *Wallet) sendOutputs(outputs []*wire.TxOut, keyScope *waddrmgr.KeyScope,
 	return createdTx.Tx, nil
 }
 
+// inputsSigned returns whether all the inputs of the transaction have a
+// signature script or a witness.
+func inputsSigned(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) == 0 && len(txIn.Witness) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // SignatureError records the underlying error when validating a transaction
 // input signature.
 type SignatureError struct {
@@ -3808,7 +4118,7 @@ func (w *Wallet) publishTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
 
 	switch {
 	case errors.Is(rpcErr, chain.ErrTxAlreadyInMempool):
-		log.Infof("%v: tx already in mempool", txid)
+		w.log.Infof("%v: tx already in mempool", txid)
 		return &txid, nil
 
 	case errors.Is(rpcErr, chain.ErrTxAlreadyKnown),
@@ -3823,18 +4133,19 @@ func (w *Wallet) publishTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
 			return w.TxStore.RemoveUnminedTx(txmgrNs, txRec)
 		})
 		if dbErr != nil {
-			log.Warnf("Unable to remove confirmed transaction %v "+
-				"from unconfirmed store: %v", tx.TxHash(), dbErr)
+			w.log.Warnf("Unable to remove confirmed transaction "+
+				"%v from unconfirmed store: %v", tx.TxHash(),
+				dbErr)
 		}
 
-		log.Infof("%v: tx already confirmed", txid)
+		w.log.Infof("%v: tx already confirmed", txid)
 
 		return &txid, nil
 
 	}
 
 	// Log the causing error, even if we know how to handle it.
-	log.Infof("%v: broadcast failed because of: %v", txid, rpcErr)
+	w.log.Infof("%v: broadcast failed because of: %v", txid, rpcErr)
 
 	// If the transaction was rejected for whatever other reason, then
 	// we'll remove it from the transaction store, as otherwise, we'll
@@ -3849,10 +4160,10 @@ func (w *Wallet) publishTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
 		return w.TxStore.RemoveUnminedTx(txmgrNs, txRec)
 	})
 	if dbErr != nil {
-		log.Warnf("Unable to remove invalid transaction %v: %v",
+		w.log.Warnf("Unable to remove invalid transaction %v: %v",
 			tx.TxHash(), dbErr)
 	} else {
-		log.Infof("Removed invalid transaction: %v", tx.TxHash())
+		w.log.Infof("Removed invalid transaction: %v", tx.TxHash())
 
 		// The serialized transaction is for logging only, don't fail
 		// on the error.
@@ -3861,12 +4172,13 @@ func (w *Wallet) publishTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
 
 		// Optionally log the tx in debug when the size is manageable.
 		if txRaw.Len() < 1_000_000 {
-			log.Debugf("Removed invalid transaction: %v \n hex=%x",
-				newLogClosure(func() string {
-					return spew.Sdump(tx)
-				}), txRaw.Bytes())
+			txDump := newLogClosure(func() string {
+				return spew.Sdump(tx)
+			})
+			w.log.Debugf("Removed invalid transaction: %v \n hex=%x",
+				txDump, txRaw.Bytes())
 		} else {
-			log.Debug("Removed invalid transaction due to size " +
+			w.log.Debug("Removed invalid transaction due to size " +
 				"too large")
 		}
 	}
@@ -3880,6 +4192,13 @@ func (w *Wallet) ChainParams() *chaincfg.Params {
 	return w.chainParams
 }
 
+// NetParams returns the asset's network parameters, which include chain
+// specific rules such as the maximum amount that are not represented in
+// ChainParams.
+func (w *Wallet) NetParams() *netparams.ChainParams {
+	return w.netParams
+}
+
 // Database returns the underlying walletdb database. This method is provided
 // in order to allow applications wrapping btcwallet to store app-specific data
 // with the wallet's database.
@@ -3890,7 +4209,7 @@ func (w *Wallet) Database() walletdb.DB {
 // CreateWithCallback is the same as Create with an added callback that will be
 // called in the same transaction the wallet structure is initialized.
 func CreateWithCallback(db walletdb.DB, pubPass, privPass []byte,
-	rootKey *hdkeychain.ExtendedKey, params *chaincfg.Params,
+	rootKey *hdkeychain.ExtendedKey, params *netparams.ChainParams,
 	birthday time.Time, cb func(walletdb.ReadWriteTx) error) error {
 
 	return create(
@@ -3902,7 +4221,7 @@ func CreateWithCallback(db walletdb.DB, pubPass, privPass []byte,
 // added callback that will be called in the same transaction the wallet
 // structure is initialized.
 func CreateWatchingOnlyWithCallback(db walletdb.DB, pubPass []byte,
-	params *chaincfg.Params, birthday time.Time,
+	params *netparams.ChainParams, birthday time.Time,
 	cb func(walletdb.ReadWriteTx) error) error {
 
 	return create(
@@ -3914,7 +4233,7 @@ func CreateWatchingOnlyWithCallback(db walletdb.DB, pubPass []byte,
 // root key is non-nil, it is used.  Otherwise, a secure random seed of the
 // recommended length is generated.
 func Create(db walletdb.DB, pubPass, privPass []byte,
-	rootKey *hdkeychain.ExtendedKey, params *chaincfg.Params,
+	rootKey *hdkeychain.ExtendedKey, params *netparams.ChainParams,
 	birthday time.Time) error {
 
 	return create(
@@ -3927,7 +4246,7 @@ func Create(db walletdb.DB, pubPass, privPass []byte,
 // watching only.  Likewise no private passphrase may be provided
 // either.
 func CreateWatchingOnly(db walletdb.DB, pubPass []byte,
-	params *chaincfg.Params, birthday time.Time) error {
+	params *netparams.ChainParams, birthday time.Time) error {
 
 	return create(
 		db, pubPass, nil, nil, params, birthday, true, nil,
@@ -3935,10 +4254,12 @@ func CreateWatchingOnly(db walletdb.DB, pubPass []byte,
 }
 
 func create(db walletdb.DB, pubPass, privPass []byte,
-	rootKey *hdkeychain.ExtendedKey, params *chaincfg.Params,
+	rootKey *hdkeychain.ExtendedKey, netParams *netparams.ChainParams,
 	birthday time.Time, isWatchingOnly bool,
 	cb func(walletdb.ReadWriteTx) error) error {
 
+	params := netParams.BTCDParams()
+
 	// If no root key was provided, we create one now from a random seed.
 	// But only if this is not a watching-only wallet where the accounts are
 	// created individually from their xpubs.
@@ -3973,6 +4294,10 @@ func create(db walletdb.DB, pubPass, privPass []byte,
 		if err != nil {
 			return err
 		}
+		addrbookNs, err := tx.CreateTopLevelBucket(addrbookNamespaceKey)
+		if err != nil {
+			return err
+		}
 
 		err = waddrmgr.Create(
 			addrmgrNs, rootKey, pubPass, privPass, params, nil,
@@ -3987,6 +4312,11 @@ func create(db walletdb.DB, pubPass, privPass []byte,
 			return err
 		}
 
+		err = addrbook.Create(addrbookNs)
+		if err != nil {
+			return err
+		}
+
 		if cb != nil {
 			return cb(tx)
 		}
@@ -3995,9 +4325,33 @@ func create(db walletdb.DB, pubPass, privPass []byte,
 	})
 }
 
+// migrationManagers returns the migration managers of the transaction and
+// address managers of a wallet database, in the order they are upgraded.
+func migrationManagers(tx walletdb.ReadWriteTx) ([]migration.Manager, error) {
+	addrMgrBucket := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+	if addrMgrBucket == nil {
+		return nil, errors.New("missing address manager namespace")
+	}
+	txMgrBucket := tx.ReadWriteBucket(wtxmgrNamespaceKey)
+	if txMgrBucket == nil {
+		return nil, errors.New("missing transaction manager namespace")
+	}
+
+	return []migration.Manager{
+		wtxmgr.NewMigrationManager(txMgrBucket),
+		waddrmgr.NewMigrationManager(addrMgrBucket),
+	}, nil
+}
+
+// DryRunUpgrade reports the changes the database upgrades applied when the
+// wallet is opened would make to the wallet database, without changing it.
+func DryRunUpgrade(db walletdb.DB) ([]migration.Change, error) {
+	return migration.DryRun(db, migrationManagers)
+}
+
 // Open loads an already-created wallet from the passed database and namespaces.
 func Open(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
-	params *chaincfg.Params, recoveryWindow uint32) (*Wallet, error) {
+	params *netparams.ChainParams, recoveryWindow uint32) (*Wallet, error) {
 
 	return OpenWithRetry(
 		db, pubPass, cbs, params, recoveryWindow,
@@ -4008,9 +4362,23 @@ func Open(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
 // OpenWithRetry loads an already-created wallet from the passed database and
 // namespaces and re-tries on errors during initial sync.
 func OpenWithRetry(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
-	params *chaincfg.Params, recoveryWindow uint32,
+	netParams *netparams.ChainParams, recoveryWindow uint32,
 	syncRetryInterval time.Duration) (*Wallet, error) {
 
+	return openWallet(
+		db, pubPass, cbs, netParams, recoveryWindow, syncRetryInterval,
+		log,
+	)
+}
+
+// openWallet loads an already-created wallet that writes to logger.
+func openWallet(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
+	netParams *netparams.ChainParams, recoveryWindow uint32,
+	syncRetryInterval time.Duration,
+	logger btclog.Logger) (*Wallet, error) {
+
+	params := netParams.BTCDParams()
+
 	var (
 		addrMgr *waddrmgr.Manager
 		txMgr   *wtxmgr.Store
@@ -4021,22 +4389,16 @@ func OpenWithRetry(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
 	// to the address and transaction managers, as they are backed by the
 	// database.
 	err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
-		addrMgrBucket := tx.ReadWriteBucket(waddrmgrNamespaceKey)
-		if addrMgrBucket == nil {
-			return errors.New("missing address manager namespace")
-		}
-		txMgrBucket := tx.ReadWriteBucket(wtxmgrNamespaceKey)
-		if txMgrBucket == nil {
-			return errors.New("missing transaction manager namespace")
-		}
-
-		addrMgrUpgrader := waddrmgr.NewMigrationManager(addrMgrBucket)
-		txMgrUpgrader := wtxmgr.NewMigrationManager(txMgrBucket)
-		err := migration.Upgrade(txMgrUpgrader, addrMgrUpgrader)
+		upgraders, err := migrationManagers(tx)
 		if err != nil {
 			return err
 		}
+		if err := migration.Upgrade(upgraders...); err != nil {
+			return err
+		}
 
+		addrMgrBucket := tx.ReadWriteBucket(waddrmgrNamespaceKey)
+		txMgrBucket := tx.ReadWriteBucket(wtxmgrNamespaceKey)
 		addrMgr, err = waddrmgr.Open(addrMgrBucket, pubPass, params)
 		if err != nil {
 			return err
@@ -4046,13 +4408,25 @@ func OpenWithRetry(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
 			return err
 		}
 
+		// Wallets created before the address book was added do not
+		// have its namespace yet.
+		if tx.ReadWriteBucket(addrbookNamespaceKey) == nil {
+			addrbookNs, err := tx.CreateTopLevelBucket(
+				addrbookNamespaceKey,
+			)
+			if err != nil {
+				return err
+			}
+			return addrbook.Create(addrbookNs)
+		}
+
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("Opened wallet") // TODO: log balance? last sync height?
+	logger.Infof("Opened wallet") // TODO: log balance? last sync height?
 
 	w := &Wallet{
 		publicPassphrase:    pubPass,
@@ -4060,6 +4434,7 @@ func OpenWithRetry(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
 		Manager:             addrMgr,
 		TxStore:             txMgr,
 		lockedOutpoints:     map[wire.OutPoint]struct{}{},
+		muSig2Sessions:      map[MuSig2SessionID]*muSig2Session{},
 		recoveryWindow:      recoveryWindow,
 		rescanAddJob:        make(chan *RescanJob),
 		rescanBatch:         make(chan *rescanBatch),
@@ -4073,9 +4448,11 @@ func OpenWithRetry(db walletdb.DB, pubPass []byte, cbs *waddrmgr.OpenCallbacks,
 		lockState:           make(chan bool),
 		changePassphrase:    make(chan changePassphraseRequest),
 		changePassphrases:   make(chan changePassphrasesRequest),
+		netParams:           netParams,
 		chainParams:         params,
 		quit:                make(chan struct{}),
 		syncRetryInterval:   syncRetryInterval,
+		log:                 logger,
 	}
 
 	w.NtfnServer = newNotificationServer(w)
@@ -97,7 +97,7 @@ func TestLocateBirthdayBlock(t *testing.T) {
 				chainParams.GenesisBlock, numBlocks, blockInterval,
 			)
 			birthdayBlock, err := locateBirthdayBlock(
-				chainConn, testCase.birthday,
+				log, chainConn, testCase.birthday,
 			)
 			if err != nil {
 				t.Fatalf("unable to locate birthday block: %v",
@@ -0,0 +1,150 @@
+package encrypted
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/bisoncraft/utxowallet/snacl"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var (
+	// metaBucketKey is the key of the plaintext top level bucket of the
+	// underlying database holding the parameters of the encryption.
+	metaBucketKey = []byte("encrypted-walletdb")
+
+	// paramsKey is the key of the marshalled parameters of the key derived
+	// from the passphrase.
+	paramsKey = []byte("params")
+
+	// masterKeyKey is the key of the master key, encrypted with the key
+	// derived from the passphrase.
+	masterKeyKey = []byte("masterkey")
+
+	// errDecrypt is returned when a key or value can't be decrypted.
+	errDecrypt = errors.New("unable to decrypt")
+)
+
+// cipherKeys holds the keys used to encrypt the keys and values of the
+// database, all derived from its master key.
+type cipherKeys struct {
+	keyMAC []byte
+	key    cipher.AEAD
+	value  cipher.AEAD
+}
+
+// deriveKey derives the subkey for the purpose from the master key.
+func deriveKey(masterKey *snacl.CryptoKey, purpose string) []byte {
+	mac := hmac.New(sha256.New, masterKey[:])
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// newCipherKeys derives the keys used to encrypt keys and values from the
+// master key.
+func newCipherKeys(masterKey *snacl.CryptoKey) (*cipherKeys, error) {
+	keyAEAD, err := chacha20poly1305.NewX(deriveKey(masterKey, "key"))
+	if err != nil {
+		return nil, err
+	}
+	valueAEAD, err := chacha20poly1305.NewX(deriveKey(masterKey, "value"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cipherKeys{
+		keyMAC: deriveKey(masterKey, "key nonce"),
+		key:    keyAEAD,
+		value:  valueAEAD,
+	}, nil
+}
+
+// keyNonce returns the nonce a key is encrypted with.  It is derived from the
+// key, so that every key is always encrypted to the same ciphertext and can be
+// looked up in the underlying database.
+func (ck *cipherKeys) keyNonce(key []byte) []byte {
+	mac := hmac.New(sha256.New, ck.keyMAC)
+	mac.Write(key)
+	return mac.Sum(nil)[:chacha20poly1305.NonceSizeX]
+}
+
+// encryptKey encrypts a key deterministically.
+func (ck *cipherKeys) encryptKey(key []byte) []byte {
+	nonce := ck.keyNonce(key)
+	return ck.key.Seal(nonce, nonce, key, nil)
+}
+
+// decryptKey decrypts a key encrypted by encryptKey.
+func (ck *cipherKeys) decryptKey(encKey []byte) ([]byte, error) {
+	nonceSize := chacha20poly1305.NonceSizeX
+	if len(encKey) < nonceSize {
+		return nil, errDecrypt
+	}
+	nonce := encKey[:nonceSize]
+	key, err := ck.key.Open(nil, nonce, encKey[nonceSize:], nil)
+	if err != nil || !hmac.Equal(nonce, ck.keyNonce(key)) {
+		return nil, errDecrypt
+	}
+	if key == nil {
+		key = []byte{}
+	}
+	return key, nil
+}
+
+// bucketID returns the identifier of the bucket with the encrypted key nested
+// in the bucket identified by parent, or at the top level if parent is nil.  It
+// is a hash of the encrypted keys of the path to the bucket.
+func bucketID(parent, encKey []byte) []byte {
+	h := sha256.New()
+	h.Write(parent)
+	h.Write(encKey)
+	return h.Sum(nil)
+}
+
+// valueAD returns the additional data a value is authenticated with, which is
+// made of the identifier of its bucket and its encrypted key, so the value
+// can't be moved to another key or bucket.
+func valueAD(bucketID, encKey []byte) []byte {
+	ad := make([]byte, 0, len(bucketID)+len(encKey))
+	ad = append(ad, bucketID...)
+	return append(ad, encKey...)
+}
+
+// encryptValue encrypts the value of the encrypted key of the bucket with a
+// random nonce.
+func (ck *cipherKeys) encryptValue(bucketID, encKey, value []byte) ([]byte,
+	error) {
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX,
+		chacha20poly1305.NonceSizeX+len(value)+ck.value.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ad := valueAD(bucketID, encKey)
+	return ck.value.Seal(nonce, nonce, value, ad), nil
+}
+
+// decryptValue decrypts the value of the encrypted key of the bucket.  Values
+// are never nil, as nil values denote nested buckets.
+func (ck *cipherKeys) decryptValue(bucketID, encKey, encValue []byte) ([]byte,
+	error) {
+
+	nonceSize := chacha20poly1305.NonceSizeX
+	if len(encValue) < nonceSize {
+		return nil, errDecrypt
+	}
+	value, err := ck.value.Open(
+		nil, encValue[:nonceSize], encValue[nonceSize:],
+		valueAD(bucketID, encKey),
+	)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
@@ -0,0 +1,887 @@
+package encrypted
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/bisoncraft/utxowallet/snacl"
+	"github.com/bisoncraft/utxowallet/walletdb"
+)
+
+var (
+	// ErrNotEncrypted is returned when opening an underlying database which
+	// is not encrypted.
+	ErrNotEncrypted = errors.New("database is not encrypted")
+
+	// ErrNotEmpty is returned when creating an encrypted database in an
+	// underlying database which already holds plaintext buckets.
+	ErrNotEmpty = errors.New("database is not empty")
+
+	// ErrInvalidPassphrase is returned when opening an encrypted database
+	// with the wrong passphrase.
+	ErrInvalidPassphrase = errors.New("invalid passphrase")
+)
+
+// SecretKeyGenerator returns a new secret key derived from the passphrase,
+// which the master key of an encrypted database is encrypted with.
+type SecretKeyGenerator func(passphrase *[]byte) (*snacl.SecretKey, error)
+
+// defaultSecretKey derives a secret key from the passphrase using scrypt with
+// the default parameters of snacl.
+func defaultSecretKey(passphrase *[]byte) (*snacl.SecretKey, error) {
+	return snacl.NewSecretKey(
+		passphrase, snacl.DefaultN, snacl.DefaultR, snacl.DefaultP,
+	)
+}
+
+// initMeta initializes the encryption of an empty underlying database, and
+// returns its new master key.
+func initMeta(tx walletdb.ReadWriteTx, passphrase []byte,
+	newSecretKey SecretKeyGenerator) (*snacl.CryptoKey, error) {
+
+	empty := true
+	err := tx.ForEachBucket(func([]byte) error {
+		empty = false
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		return nil, ErrNotEmpty
+	}
+
+	masterKey, err := snacl.GenerateCryptoKey()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := tx.CreateTopLevelBucket(metaBucketKey)
+	if err != nil {
+		return nil, err
+	}
+	err = putMasterKey(meta, masterKey, passphrase, newSecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return masterKey, nil
+}
+
+// putMasterKey stores the master key in the meta bucket, encrypted with a new
+// key derived from the passphrase by newSecretKey, or using scrypt with the
+// default parameters if it is nil.
+func putMasterKey(meta walletdb.ReadWriteBucket, masterKey *snacl.CryptoKey,
+	passphrase []byte, newSecretKey SecretKeyGenerator) error {
+
+	if newSecretKey == nil {
+		newSecretKey = defaultSecretKey
+	}
+	pass := append([]byte(nil), passphrase...)
+	sk, err := newSecretKey(&pass)
+	if err != nil {
+		return err
+	}
+	defer sk.Zero()
+
+	encMasterKey, err := sk.Encrypt(masterKey[:])
+	if err != nil {
+		return err
+	}
+	if err := meta.Put(paramsKey, sk.Marshal()); err != nil {
+		return err
+	}
+	return meta.Put(masterKeyKey, encMasterKey)
+}
+
+// unlockMeta returns the master key of an encrypted underlying database.
+func unlockMeta(meta walletdb.ReadBucket, passphrase []byte) (*snacl.CryptoKey,
+	error) {
+
+	var sk snacl.SecretKey
+	if err := sk.Unmarshal(meta.Get(paramsKey)); err != nil {
+		return nil, err
+	}
+	pass := append([]byte(nil), passphrase...)
+	if err := sk.DeriveKey(&pass); err != nil {
+		if err == snacl.ErrInvalidPassword {
+			return nil, ErrInvalidPassphrase
+		}
+		return nil, err
+	}
+	defer sk.Zero()
+
+	decrypted, err := sk.Decrypt(meta.Get(masterKeyKey))
+	if err != nil {
+		return nil, err
+	}
+	if len(decrypted) != snacl.KeySize {
+		return nil, snacl.ErrMalformed
+	}
+
+	var masterKey snacl.CryptoKey
+	copy(masterKey[:], decrypted)
+	return &masterKey, nil
+}
+
+// ChangePassphrase re-encrypts the master key of the encrypted database of the
+// read-write transaction tx with a key derived from newPassphrase by
+// newSecretKey, once it is decrypted with oldPassphrase.  The key is derived
+// using scrypt with the default parameters if newSecretKey is nil.  The keys
+// and values of the database are not changed.  The new passphrase is committed
+// with tx, so it can be changed together with other data encrypted with the
+// same passphrase.  ErrNotEncrypted is returned if tx is not a transaction of
+// an encrypted database.
+func ChangePassphrase(tx walletdb.ReadWriteTx, oldPassphrase,
+	newPassphrase []byte, newSecretKey SecretKeyGenerator) error {
+
+	t, ok := tx.(*transaction)
+	if !ok {
+		return ErrNotEncrypted
+	}
+	if t.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	meta := t.innerRW.ReadWriteBucket(metaBucketKey)
+	if meta == nil {
+		return ErrNotEncrypted
+	}
+
+	masterKey, err := unlockMeta(meta, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	defer masterKey.Zero()
+
+	return putMasterKey(meta, masterKey, newPassphrase, newSecretKey)
+}
+
+// db wraps an underlying database, encrypting all of its keys and values.
+type db struct {
+	inner walletdb.DB
+	keys  *cipherKeys
+}
+
+// Enforce db implements the walletdb.DB and walletdb.BatchDB interfaces.
+var _ walletdb.BatchDB = (*db)(nil)
+
+// openDB opens the encrypted database stored in the underlying database,
+// initializing it first if create is set and the underlying database is empty.
+// The master key of a new database is encrypted with a key derived by
+// newSecretKey.
+func openDB(inner walletdb.DB, passphrase []byte, create bool,
+	newSecretKey SecretKeyGenerator) (walletdb.DB, error) {
+
+	var masterKey *snacl.CryptoKey
+	err := walletdb.Update(inner, func(tx walletdb.ReadWriteTx) error {
+		var err error
+		meta := tx.ReadBucket(metaBucketKey)
+		switch {
+		case meta != nil:
+			masterKey, err = unlockMeta(meta, passphrase)
+		case create:
+			masterKey, err = initMeta(tx, passphrase, newSecretKey)
+		default:
+			err = ErrNotEncrypted
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer masterKey.Zero()
+
+	keys, err := newCipherKeys(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &db{inner: inner, keys: keys}, nil
+}
+
+// BeginReadTx opens a database read transaction.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) BeginReadTx() (walletdb.ReadTx, error) {
+	tx, err := d.inner.BeginReadTx()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{db: d, inner: tx}, nil
+}
+
+// BeginReadWriteTx opens a database read+write transaction.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) BeginReadWriteTx() (walletdb.ReadWriteTx, error) {
+	tx, err := d.inner.BeginReadWriteTx()
+	if err != nil {
+		return nil, err
+	}
+	return d.readWriteTx(tx), nil
+}
+
+// readWriteTx wraps a read-write transaction of the underlying database.
+func (d *db) readWriteTx(tx walletdb.ReadWriteTx) *transaction {
+	return &transaction{db: d, inner: tx, innerRW: tx}
+}
+
+// Copy writes a copy of the underlying database, which remains encrypted, to
+// the provided writer.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) Copy(w io.Writer) error {
+	return d.inner.Copy(w)
+}
+
+// Close closes the underlying database.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) Close() error {
+	return d.inner.Close()
+}
+
+// PrintStats returns the stats of the underlying database.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) PrintStats() string {
+	return d.inner.PrintStats()
+}
+
+// View opens a database read transaction and executes the function f with the
+// transaction passed as a parameter.  The error of any value f read which
+// couldn't be decrypted is returned if f doesn't return an error.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) View(f func(tx walletdb.ReadTx) error, reset func()) error {
+	return d.inner.View(func(tx walletdb.ReadTx) error {
+		t := &transaction{db: d, inner: tx}
+		if err := f(t); err != nil {
+			return err
+		}
+		return t.err
+	}, reset)
+}
+
+// Update opens a database read/write transaction and executes the function f
+// with the transaction passed as a parameter.  The transaction is rolled back,
+// and the error returned, if any value f read couldn't be decrypted.
+//
+// This function is part of the walletdb.DB interface implementation.
+func (d *db) Update(f func(tx walletdb.ReadWriteTx) error,
+	reset func()) error {
+
+	return d.inner.Update(func(tx walletdb.ReadWriteTx) error {
+		t := d.readWriteTx(tx)
+		if err := f(t); err != nil {
+			return err
+		}
+		return t.err
+	}, reset)
+}
+
+// Batch combines the transactions of concurrent callers if the underlying
+// database supports it, and is otherwise the same as Update.
+//
+// This function is part of the walletdb.BatchDB interface implementation.
+func (d *db) Batch(f func(tx walletdb.ReadWriteTx) error) error {
+	batchDB, ok := d.inner.(walletdb.BatchDB)
+	if !ok {
+		return walletdb.Update(d, f)
+	}
+
+	return batchDB.Batch(func(tx walletdb.ReadWriteTx) error {
+		t := d.readWriteTx(tx)
+		if err := f(t); err != nil {
+			return err
+		}
+		return t.err
+	})
+}
+
+// transaction wraps a transaction of the underlying database.
+type transaction struct {
+	db      *db
+	inner   walletdb.ReadTx
+	innerRW walletdb.ReadWriteTx
+
+	// err is the first error decrypting a value read with Get or a
+	// cursor, which can't return it.  It fails the transaction instead.
+	err error
+
+	// index holds the decrypted and sorted keys of the buckets cursors
+	// have been opened on, by bucket identifier, until keys are added to
+	// or deleted from the bucket.
+	index map[string][]cursorEntry
+}
+
+// Enforce transaction implements the walletdb.ReadWriteTx interface.
+var _ walletdb.ReadWriteTx = (*transaction)(nil)
+
+// encryptKey encrypts a key.  Empty keys are passed to the underlying database
+// as they are, so that it rejects them.
+func (tx *transaction) encryptKey(key []byte) []byte {
+	if len(key) == 0 {
+		return key
+	}
+	return tx.db.keys.encryptKey(key)
+}
+
+// fail records the error of a read which couldn't return it, so that the
+// transaction fails.
+func (tx *transaction) fail(err error) {
+	if tx.err == nil {
+		tx.err = err
+	}
+}
+
+// ReadBucket opens the root bucket for read only access.
+//
+// This function is part of the walletdb.ReadTx interface implementation.
+func (tx *transaction) ReadBucket(key []byte) walletdb.ReadBucket {
+	encKey := tx.encryptKey(key)
+	b := tx.inner.ReadBucket(encKey)
+	if b == nil {
+		return nil
+	}
+	return &bucket{tx: tx, inner: b, id: bucketID(nil, encKey)}
+}
+
+// ForEachBucket invokes the passed function with the key of every top level
+// bucket, in the order of the keys.
+//
+// This function is part of the walletdb.ReadTx interface implementation.
+func (tx *transaction) ForEachBucket(fn func(key []byte) error) error {
+	var keys [][]byte
+	err := tx.inner.ForEachBucket(func(encKey []byte) error {
+		if bytes.Equal(encKey, metaBucketKey) {
+			return nil
+		}
+		key, err := tx.db.keys.decryptKey(encKey)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback closes the transaction, discarding changes (if any) if the
+// database was modified by a write transaction.
+//
+// This function is part of the walletdb.ReadTx interface implementation.
+func (tx *transaction) Rollback() error {
+	return tx.inner.Rollback()
+}
+
+// ReadWriteBucket opens the root bucket for read/write access.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) ReadWriteBucket(key []byte) walletdb.ReadWriteBucket {
+	if tx.innerRW == nil {
+		return nil
+	}
+	encKey := tx.encryptKey(key)
+	b := tx.innerRW.ReadWriteBucket(encKey)
+	if b == nil {
+		return nil
+	}
+	return &bucket{
+		tx: tx, inner: b, innerRW: b, id: bucketID(nil, encKey),
+	}
+}
+
+// CreateTopLevelBucket creates the top level bucket for a key if it does not
+// exist.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) CreateTopLevelBucket(
+	key []byte) (walletdb.ReadWriteBucket, error) {
+
+	if tx.innerRW == nil {
+		return nil, walletdb.ErrTxNotWritable
+	}
+	encKey := tx.encryptKey(key)
+	b, err := tx.innerRW.CreateTopLevelBucket(encKey)
+	if err != nil {
+		return nil, err
+	}
+	return &bucket{
+		tx: tx, inner: b, innerRW: b, id: bucketID(nil, encKey),
+	}, nil
+}
+
+// DeleteTopLevelBucket deletes the top level bucket for a key.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) DeleteTopLevelBucket(key []byte) error {
+	if tx.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	encKey := tx.encryptKey(key)
+	delete(tx.index, string(bucketID(nil, encKey)))
+	return tx.innerRW.DeleteTopLevelBucket(encKey)
+}
+
+// Commit commits all changes that have been made through the root bucket and
+// all of its sub-buckets to persistent storage.  The transaction is rolled back
+// instead if any value read in it couldn't be decrypted.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) Commit() error {
+	if tx.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	if tx.err != nil {
+		_ = tx.innerRW.Rollback()
+		return tx.err
+	}
+	return tx.innerRW.Commit()
+}
+
+// OnCommit takes a function closure that will be executed when the transaction
+// successfully gets committed.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) OnCommit(f func()) {
+	if tx.innerRW != nil {
+		tx.innerRW.OnCommit(f)
+	}
+}
+
+// bucket wraps a bucket of the underlying database.
+type bucket struct {
+	tx      *transaction
+	inner   walletdb.ReadBucket
+	innerRW walletdb.ReadWriteBucket
+
+	// id identifies the bucket by its path, and authenticates its values.
+	id []byte
+}
+
+// nested returns the nested bucket of the underlying database with the
+// encrypted key.
+func (b *bucket) nested(encKey []byte, inner walletdb.ReadBucket,
+	innerRW walletdb.ReadWriteBucket) *bucket {
+
+	return &bucket{
+		tx:      b.tx,
+		inner:   inner,
+		innerRW: innerRW,
+		id:      bucketID(b.id, encKey),
+	}
+}
+
+// Enforce bucket implements the walletdb.ReadWriteBucket interface.
+var _ walletdb.ReadWriteBucket = (*bucket)(nil)
+
+// keys returns the keys of the bucket in the order of their plaintext.  They
+// are decrypted and sorted when the first cursor of the transaction is opened
+// on the bucket, and again only once keys have been added or deleted.  Keys
+// which can't be decrypted are left out, and fail the transaction.
+func (b *bucket) keys() []cursorEntry {
+	if entries, ok := b.tx.index[string(b.id)]; ok {
+		return entries
+	}
+
+	var entries []cursorEntry
+	_ = b.inner.ForEach(func(encKey, _ []byte) error {
+		key, err := b.tx.db.keys.decryptKey(encKey)
+		if err != nil {
+			b.tx.fail(err)
+			return nil
+		}
+		entries = append(entries, cursorEntry{
+			key:    key,
+			encKey: append([]byte(nil), encKey...),
+		})
+		return nil
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	if b.tx.index == nil {
+		b.tx.index = make(map[string][]cursorEntry)
+	}
+	b.tx.index[string(b.id)] = entries
+	return entries
+}
+
+// keysChanged discards the sorted keys of the bucket, as keys are added to or
+// deleted from it.  Cursors which are already open keep iterating over the
+// keys they were opened with.
+func (b *bucket) keysChanged() {
+	delete(b.tx.index, string(b.id))
+}
+
+// keyAdded discards the sorted keys of the bucket if the encrypted key is not
+// in the bucket yet.  It is called before the key is written.
+func (b *bucket) keyAdded(encKey []byte) {
+	if _, ok := b.tx.index[string(b.id)]; !ok {
+		return
+	}
+	if b.inner.Get(encKey) == nil &&
+		b.inner.NestedReadBucket(encKey) == nil {
+
+		b.keysChanged()
+	}
+}
+
+// NestedReadBucket retrieves a nested bucket with the given key.
+//
+// This function is part of the walletdb.ReadBucket interface implementation.
+func (b *bucket) NestedReadBucket(key []byte) walletdb.ReadBucket {
+	encKey := b.tx.encryptKey(key)
+	nested := b.inner.NestedReadBucket(encKey)
+	if nested == nil {
+		return nil
+	}
+	return b.nested(encKey, nested, nil)
+}
+
+// ForEach invokes the passed function with every key/value pair in the bucket,
+// in the order of the keys.  Nested buckets are included with nil values.
+//
+// This function is part of the walletdb.ReadBucket interface implementation.
+func (b *bucket) ForEach(fn func(k, v []byte) error) error {
+	type pair struct {
+		key, value []byte
+	}
+	var pairs []pair
+	keys := b.tx.db.keys
+	err := b.inner.ForEach(func(encKey, encValue []byte) error {
+		key, err := keys.decryptKey(encKey)
+		if err != nil {
+			return err
+		}
+		var value []byte
+		if encValue != nil {
+			value, err = keys.decryptValue(b.id, encKey, encValue)
+			if err != nil {
+				return err
+			}
+		}
+		pairs = append(pairs, pair{key, value})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return bytes.Compare(pairs[i].key, pairs[j].key) < 0
+	})
+	for _, p := range pairs {
+		if err := fn(p.key, p.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the value for the given key.  Returns nil if the key does not
+// exist in this bucket, or if its value can't be decrypted, in which case the
+// transaction fails with the error.
+//
+// This function is part of the walletdb.ReadBucket interface implementation.
+func (b *bucket) Get(key []byte) []byte {
+	encKey := b.tx.encryptKey(key)
+	encValue := b.inner.Get(encKey)
+	if encValue == nil {
+		return nil
+	}
+	value, err := b.tx.db.keys.decryptValue(b.id, encKey, encValue)
+	if err != nil {
+		b.tx.fail(err)
+		return nil
+	}
+	return value
+}
+
+// ReadCursor returns a new cursor, allowing for iteration over the bucket's
+// key/value pairs and nested buckets in forward or backward order.
+//
+// This function is part of the walletdb.ReadBucket interface implementation.
+func (b *bucket) ReadCursor() walletdb.ReadCursor {
+	return newCursor(b)
+}
+
+// NestedReadWriteBucket retrieves a nested bucket with the given key.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) NestedReadWriteBucket(key []byte) walletdb.ReadWriteBucket {
+	if b.innerRW == nil {
+		return nil
+	}
+	encKey := b.tx.encryptKey(key)
+	nested := b.innerRW.NestedReadWriteBucket(encKey)
+	if nested == nil {
+		return nil
+	}
+	return b.nested(encKey, nested, nested)
+}
+
+// CreateBucket creates and returns a new nested bucket with the given key.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) CreateBucket(key []byte) (walletdb.ReadWriteBucket, error) {
+	if b.innerRW == nil {
+		return nil, walletdb.ErrTxNotWritable
+	}
+	encKey := b.tx.encryptKey(key)
+	nested, err := b.innerRW.CreateBucket(encKey)
+	if err != nil {
+		return nil, err
+	}
+	b.keysChanged()
+	return b.nested(encKey, nested, nested), nil
+}
+
+// CreateBucketIfNotExists creates and returns a new nested bucket with the
+// given key if it does not already exist.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) CreateBucketIfNotExists(
+	key []byte) (walletdb.ReadWriteBucket, error) {
+
+	if b.innerRW == nil {
+		return nil, walletdb.ErrTxNotWritable
+	}
+	encKey := b.tx.encryptKey(key)
+	b.keyAdded(encKey)
+	nested, err := b.innerRW.CreateBucketIfNotExists(encKey)
+	if err != nil {
+		return nil, err
+	}
+	return b.nested(encKey, nested, nested), nil
+}
+
+// DeleteNestedBucket removes a nested bucket with the given key.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) DeleteNestedBucket(key []byte) error {
+	if b.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	b.keysChanged()
+	return b.innerRW.DeleteNestedBucket(b.tx.encryptKey(key))
+}
+
+// Put saves the specified key/value pair to the bucket.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) Put(key, value []byte) error {
+	if b.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	encKey := b.tx.encryptKey(key)
+	encValue, err := b.tx.db.keys.encryptValue(b.id, encKey, value)
+	if err != nil {
+		return err
+	}
+	b.keyAdded(encKey)
+	return b.innerRW.Put(encKey, encValue)
+}
+
+// Delete removes the specified key from the bucket.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) Delete(key []byte) error {
+	if b.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	b.keysChanged()
+	return b.innerRW.Delete(b.tx.encryptKey(key))
+}
+
+// ReadWriteCursor returns a new cursor, allowing for iteration over the
+// bucket's key/value pairs and nested buckets in forward or backward order.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) ReadWriteCursor() walletdb.ReadWriteCursor {
+	return newCursor(b)
+}
+
+// Tx returns the bucket's transaction.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) Tx() walletdb.ReadWriteTx {
+	return b.tx
+}
+
+// NextSequence returns an autoincrementing integer for the bucket.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) NextSequence() (uint64, error) {
+	if b.innerRW == nil {
+		return 0, walletdb.ErrTxNotWritable
+	}
+	return b.innerRW.NextSequence()
+}
+
+// SetSequence updates the sequence number for the bucket.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) SetSequence(v uint64) error {
+	if b.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	return b.innerRW.SetSequence(v)
+}
+
+// Sequence returns the current integer for the bucket without incrementing it.
+//
+// This function is part of the walletdb.ReadWriteBucket interface
+// implementation.
+func (b *bucket) Sequence() uint64 {
+	if b.innerRW == nil {
+		return 0
+	}
+	return b.innerRW.Sequence()
+}
+
+// cursorEntry is a key of the bucket of a cursor.
+type cursorEntry struct {
+	key    []byte
+	encKey []byte
+}
+
+// cursor iterates over the keys of a bucket in the order of their plaintext.
+// As the encrypted keys of the underlying bucket are in a different order, the
+// cursor iterates over the keys of the bucket decrypted and sorted by the
+// transaction.  The values are read from the underlying bucket as the cursor
+// moves, and keys deleted since the cursor was created are skipped.
+type cursor struct {
+	bucket  *bucket
+	entries []cursorEntry
+	pos     int
+}
+
+// Enforce cursor implements the walletdb.ReadWriteCursor interface.
+var _ walletdb.ReadWriteCursor = (*cursor)(nil)
+
+// newCursor returns a cursor over the keys of the bucket.
+func newCursor(b *bucket) *cursor {
+	return &cursor{bucket: b, entries: b.keys(), pos: -1}
+}
+
+// pair returns the key/value pair of the entry, and whether it still exists.
+// Values which can't be decrypted are skipped, and fail the transaction.
+func (c *cursor) pair(i int) ([]byte, []byte, bool) {
+	e := c.entries[i]
+	b := c.bucket
+	encValue := b.inner.Get(e.encKey)
+	if encValue == nil {
+		nested := b.inner.NestedReadBucket(e.encKey)
+		return e.key, nil, nested != nil
+	}
+	value, err := b.tx.db.keys.decryptValue(b.id, e.encKey, encValue)
+	if err != nil {
+		b.tx.fail(err)
+		return nil, nil, false
+	}
+	return e.key, value, true
+}
+
+// move positions the cursor at the first existing entry from i in the
+// direction of step, and returns its pair.
+func (c *cursor) move(i, step int) ([]byte, []byte) {
+	for ; i >= 0 && i < len(c.entries); i += step {
+		if k, v, ok := c.pair(i); ok {
+			c.pos = i
+			return k, v
+		}
+	}
+	c.pos = i
+	return nil, nil
+}
+
+// First positions the cursor at the first key/value pair and returns the pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) First() ([]byte, []byte) {
+	return c.move(0, 1)
+}
+
+// Last positions the cursor at the last key/value pair and returns the pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Last() ([]byte, []byte) {
+	return c.move(len(c.entries)-1, -1)
+}
+
+// Next moves the cursor one key/value pair forward and returns the new pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Next() ([]byte, []byte) {
+	if c.pos >= len(c.entries) {
+		return nil, nil
+	}
+	return c.move(c.pos+1, 1)
+}
+
+// Prev moves the cursor one key/value pair backward and returns the new pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Prev() ([]byte, []byte) {
+	if c.pos < 0 {
+		return nil, nil
+	}
+	return c.move(c.pos-1, -1)
+}
+
+// Seek positions the cursor at the passed seek key.  If the key does not
+// exist, the cursor is moved to the next key after seek.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Seek(seek []byte) ([]byte, []byte) {
+	i := sort.Search(len(c.entries), func(i int) bool {
+		return bytes.Compare(c.entries[i].key, seek) >= 0
+	})
+	return c.move(i, 1)
+}
+
+// Delete removes the current key/value pair the cursor is at without
+// invalidating the cursor.
+//
+// This function is part of the walletdb.ReadWriteCursor interface
+// implementation.
+func (c *cursor) Delete() error {
+	if c.bucket.innerRW == nil {
+		return walletdb.ErrTxNotWritable
+	}
+	if c.pos < 0 || c.pos >= len(c.entries) {
+		return nil
+	}
+	encKey := c.entries[c.pos].encKey
+	if c.bucket.innerRW.Get(encKey) == nil {
+		return walletdb.ErrIncompatibleValue
+	}
+	c.bucket.keysChanged()
+	return c.bucket.innerRW.Delete(encKey)
+}
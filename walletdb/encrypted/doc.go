@@ -0,0 +1,44 @@
+/*
+Package encrypted implements an instance of walletdb that encrypts all keys and
+values stored in another walletdb database, so that neither the transaction
+history nor the addresses of a wallet can be read from a stolen database file.
+
+Keys are encrypted deterministically with XChaCha20-Poly1305, using a nonce
+derived from the key, so they can still be looked up in the underlying
+database.  Values are encrypted with random nonces, authenticated together with
+their encrypted keys and the path of their bucket.  The encryption keys are
+derived from a random master key, which is stored in a plaintext top level
+bucket of the underlying database, encrypted with a key derived from a
+passphrase using scrypt, or the key derivation function of the
+SecretKeyGenerator the database is created with.
+
+The underlying database orders the encrypted keys, so the keys of a bucket are
+decrypted and sorted when a transaction opens its first cursor on the bucket,
+and again once keys have been added to or deleted from the bucket.  The size of
+the database and the number of keys in each bucket are not hidden.
+
+The passphrase is changed with ChangePassphrase, in a transaction of the
+encrypted database, which re-encrypts the master key only, with a key derived
+by the SecretKeyGenerator provided.
+
+# Usage
+
+This package is only a driver to the walletdb package and provides the database
+type of "encrypted".  The parameters the Open and Create functions take are the
+underlying database as a walletdb.DB and the passphrase as a []byte, which
+Create may follow with a SecretKeyGenerator.  Create initializes the encryption
+of an empty underlying database, or opens it if it is already encrypted.
+Closing the encrypted database closes the underlying database:
+
+	inner, err := walletdb.Create("bdb", "path/to/database.db", true,
+		60*time.Second)
+	if err != nil {
+		// Handle error
+	}
+
+	db, err := walletdb.Create("encrypted", inner, passphrase)
+	if err != nil {
+		// Handle error
+	}
+*/
+package encrypted
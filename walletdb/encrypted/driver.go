@@ -0,0 +1,86 @@
+package encrypted
+
+import (
+	"fmt"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+)
+
+const (
+	dbType = "encrypted"
+)
+
+// parseArgs parses the arguments from the walletdb Open/Create methods.
+func parseArgs(funcName string,
+	args ...interface{}) (walletdb.DB, []byte, error) {
+
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("invalid arguments to %s.%s -- "+
+			"expected underlying database and passphrase", dbType,
+			funcName)
+	}
+
+	inner, ok := args[0].(walletdb.DB)
+	if !ok {
+		return nil, nil, fmt.Errorf("first argument to %s.%s is "+
+			"invalid -- expected underlying walletdb.DB", dbType,
+			funcName)
+	}
+
+	passphrase, ok := args[1].([]byte)
+	if !ok {
+		return nil, nil, fmt.Errorf("second argument to %s.%s is "+
+			"invalid -- expected passphrase []byte", dbType,
+			funcName)
+	}
+
+	return inner, passphrase, nil
+}
+
+// openDBDriver is the callback provided during driver registration that opens
+// an existing database for use.
+func openDBDriver(args ...interface{}) (walletdb.DB, error) {
+	inner, passphrase, err := parseArgs("Open", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(inner, passphrase, false, nil)
+}
+
+// createDBDriver is the callback provided during driver registration that
+// creates, initializes, and opens a database for use.  An optional
+// SecretKeyGenerator may follow the passphrase.
+func createDBDriver(args ...interface{}) (walletdb.DB, error) {
+	var newSecretKey SecretKeyGenerator
+	if len(args) == 3 {
+		var ok bool
+		newSecretKey, ok = args[2].(SecretKeyGenerator)
+		if !ok {
+			return nil, fmt.Errorf("third argument to %s.Create "+
+				"is invalid -- expected SecretKeyGenerator",
+				dbType)
+		}
+		args = args[:2]
+	}
+
+	inner, passphrase, err := parseArgs("Create", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(inner, passphrase, true, newSecretKey)
+}
+
+func init() {
+	// Register the driver.
+	driver := walletdb.Driver{
+		DbType: dbType,
+		Create: createDBDriver,
+		Open:   openDBDriver,
+	}
+	if err := walletdb.RegisterDriver(driver); err != nil {
+		panic(fmt.Sprintf("Failed to register database driver '%s': %v",
+			dbType, err))
+	}
+}
@@ -0,0 +1,709 @@
+package encrypted_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/snacl"
+	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/bdb"
+	"github.com/bisoncraft/utxowallet/walletdb/encrypted"
+	"github.com/bisoncraft/utxowallet/wtxmgr"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// dbType is the database type name for this driver.
+	dbType = "encrypted"
+
+	// defaultDBTimeout is the value of db timeout for testing.
+	defaultDBTimeout = 10 * time.Second
+)
+
+// passphrase is the passphrase of the test databases.
+var passphrase = []byte("passphrase")
+
+// createInner creates an underlying bdb database at dbPath.
+func createInner(t testing.TB, dbPath string) walletdb.DB {
+	inner, err := walletdb.Create("bdb", dbPath, true, defaultDBTimeout)
+	if err != nil {
+		t.Fatalf("Failed to create underlying database: %v", err)
+	}
+	return inner
+}
+
+// TestCreateOpenFail ensures that errors related to creating and opening a
+// database are handled properly.
+func TestCreateOpenFail(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Ensure that attempting to open a database with the wrong number of
+	// parameters returns the expected error.
+	wantErr := fmt.Errorf("invalid arguments to %s.Open -- expected "+
+		"underlying database and passphrase", dbType)
+	if _, err := walletdb.Open(
+		dbType, 1, 2, 3,
+	); err.Error() != wantErr.Error() {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	// Ensure that attempting to open a database with an invalid type for
+	// the first parameter returns the expected error.
+	wantErr = fmt.Errorf("first argument to %s.Open is invalid -- "+
+		"expected underlying walletdb.DB", dbType)
+	if _, err := walletdb.Open(
+		dbType, 1, passphrase,
+	); err.Error() != wantErr.Error() {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	inner := createInner(t, filepath.Join(tempDir, "db"))
+	defer inner.Close()
+
+	// Ensure that attempting to create a database with an invalid type for
+	// the second parameter returns the expected error.
+	wantErr = fmt.Errorf("second argument to %s.Create is invalid -- "+
+		"expected passphrase []byte", dbType)
+	if _, err := walletdb.Create(
+		dbType, inner, "passphrase",
+	); err.Error() != wantErr.Error() {
+
+		t.Errorf("Create: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	// Ensure that attempting to open an underlying database which is not
+	// encrypted returns the expected error.
+	if _, err := walletdb.Open(
+		dbType, inner, passphrase,
+	); err != encrypted.ErrNotEncrypted {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, encrypted.ErrNotEncrypted)
+		return
+	}
+
+	// Ensure that an encrypted database can't be created in an underlying
+	// database holding plaintext buckets.
+	plain := createInner(t, filepath.Join(tempDir, "plain"))
+	defer plain.Close()
+	err := walletdb.Update(plain, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("ns"))
+		return err
+	})
+	if err != nil {
+		t.Errorf("Update: unexpected error: %v", err)
+		return
+	}
+	if _, err := walletdb.Create(
+		dbType, plain, passphrase,
+	); err != encrypted.ErrNotEmpty {
+
+		t.Errorf("Create: did not receive expected error - got %v, "+
+			"want %v", err, encrypted.ErrNotEmpty)
+		return
+	}
+
+	// Ensure that opening an encrypted database with the wrong passphrase
+	// returns the expected error.
+	if _, err := walletdb.Create(dbType, inner, passphrase); err != nil {
+		t.Errorf("Create: unexpected error: %v", err)
+		return
+	}
+	if _, err := walletdb.Open(
+		dbType, inner, []byte("wrong"),
+	); err != encrypted.ErrInvalidPassphrase {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, encrypted.ErrInvalidPassphrase)
+		return
+	}
+}
+
+// TestPersistence ensures that values stored are still valid after closing and
+// reopening the database, and that neither keys nor values are stored in
+// plaintext.
+func TestPersistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+	db, err := walletdb.Create(
+		dbType, createInner(t, dbPath), passphrase,
+	)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+
+	storeValues := map[string]string{
+		"ns1key1": "foo1",
+		"ns1key2": "foo2",
+		"ns1key3": "",
+	}
+	ns1Key := []byte("ns1")
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns1, err := tx.CreateTopLevelBucket(ns1Key)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range storeValues {
+			if err := ns1.Put([]byte(k), []byte(v)); err != nil {
+				return fmt.Errorf("Put: unexpected error: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ns1 Update: unexpected error: %v", err)
+		return
+	}
+	db.Close()
+
+	raw, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Errorf("unable to read database: %v", err)
+		return
+	}
+	for _, s := range []string{"ns1", "ns1key1", "foo1"} {
+		if bytes.Contains(raw, []byte(s)) {
+			t.Errorf("database holds plaintext %q", s)
+			return
+		}
+	}
+
+	inner, err := walletdb.Open("bdb", dbPath, true, defaultDBTimeout)
+	if err != nil {
+		t.Errorf("Failed to open underlying database: %v", err)
+		return
+	}
+	db, err = walletdb.Open(dbType, inner, passphrase)
+	if err != nil {
+		t.Errorf("Failed to open test database (%s) %v", dbType, err)
+		return
+	}
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		ns1 := tx.ReadBucket(ns1Key)
+		if ns1 == nil {
+			return fmt.Errorf("ReadTx.ReadBucket: unexpected nil " +
+				"root bucket")
+		}
+
+		for k, v := range storeValues {
+			gotVal := ns1.Get([]byte(k))
+			if !reflect.DeepEqual(gotVal, []byte(v)) {
+				return fmt.Errorf("Get: key '%s' does not match "+
+					"expected value - got %s, want %s", k,
+					gotVal, v)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ns1 View: unexpected error: %v", err)
+	}
+}
+
+// TestCursor ensures that cursors iterate over the key/value pairs and nested
+// buckets of a bucket in the order of their plaintext keys.
+func TestCursor(t *testing.T) {
+	db, err := walletdb.Create(
+		dbType, createInner(t, filepath.Join(t.TempDir(), "db")),
+		passphrase,
+	)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns, err := tx.CreateTopLevelBucket([]byte("ns"))
+		if err != nil {
+			return err
+		}
+		for _, k := range [][]byte{{2}, {1, 0}, {1}, {0xff}} {
+			if err := ns.Put(k, k); err != nil {
+				return err
+			}
+		}
+		if _, err := ns.CreateBucket([]byte{3}); err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		c := ns.ReadWriteCursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if bytes.Equal(k, []byte{3}) != (v == nil) {
+				return fmt.Errorf("unexpected value %x of key "+
+					"%x", v, k)
+			}
+			keys = append(keys, k)
+		}
+		wantKeys := [][]byte{{1}, {1, 0}, {2}, {3}, {0xff}}
+		if !reflect.DeepEqual(keys, wantKeys) {
+			return fmt.Errorf("unexpected keys %x, want %x", keys,
+				wantKeys)
+		}
+
+		if k, _ := c.Seek([]byte{1, 1}); !bytes.Equal(k, []byte{2}) {
+			return fmt.Errorf("Seek: unexpected key %x", k)
+		}
+		if err := c.Delete(); err != nil {
+			return err
+		}
+		if k, _ := c.Next(); !bytes.Equal(k, []byte{3}) {
+			return fmt.Errorf("Next: unexpected key %x", k)
+		}
+		if err := c.Delete(); err != walletdb.ErrIncompatibleValue {
+			return fmt.Errorf("Delete: unexpected error %v", err)
+		}
+
+		// Keys deleted since the cursor was created are skipped.
+		if k, _ := c.Prev(); !bytes.Equal(k, []byte{1, 0}) {
+			return fmt.Errorf("Prev: unexpected key %x", k)
+		}
+		if ns.Get([]byte{2}) != nil {
+			return fmt.Errorf("Get: deleted key found")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Update: unexpected error: %v", err)
+	}
+}
+
+// TestMovedValue ensures that values moved to the same key of another bucket
+// in the underlying database fail the transactions reading them.
+func TestMovedValue(t *testing.T) {
+	inner := createInner(t, filepath.Join(t.TempDir(), "db"))
+	db, err := walletdb.Create(dbType, inner, passphrase)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+
+	key := []byte("key")
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, ns := range []string{"ns1", "ns2"} {
+			b, err := tx.CreateTopLevelBucket([]byte(ns))
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, []byte(ns)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	// Swap the encrypted values of the two buckets.
+	err = walletdb.Update(inner, func(tx walletdb.ReadWriteTx) error {
+		var buckets []walletdb.ReadWriteBucket
+		var values [][]byte
+		err := tx.ForEachBucket(func(k []byte) error {
+			if string(k) == "encrypted-walletdb" {
+				return nil
+			}
+			b := tx.ReadWriteBucket(k)
+			return b.ForEach(func(_, v []byte) error {
+				buckets = append(buckets, b)
+				values = append(values, append([]byte(nil), v...))
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if len(buckets) != 2 {
+			return fmt.Errorf("found %d encrypted values", len(buckets))
+		}
+		for i, b := range buckets {
+			err := b.ForEach(func(k, _ []byte) error {
+				return b.Put(k, values[1-i])
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to swap values: %v", err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if v := tx.ReadBucket([]byte("ns1")).Get(key); v != nil {
+			return fmt.Errorf("Get: unexpected value %q", v)
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("View: moved value was read")
+	}
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		tx.ReadWriteBucket([]byte("ns2")).Get(key)
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("Update: moved value was read")
+	}
+}
+
+// TestChangePassphrase ensures that the passphrase of an encrypted database is
+// changed with the transaction it is changed in.
+func TestChangePassphrase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+	inner := createInner(t, dbPath)
+	db, err := walletdb.Create(dbType, inner, passphrase)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+
+	err = walletdb.Update(inner, func(tx walletdb.ReadWriteTx) error {
+		return encrypted.ChangePassphrase(
+			tx, passphrase, passphrase, nil,
+		)
+	})
+	if err != encrypted.ErrNotEncrypted {
+		t.Fatalf("ChangePassphrase: unexpected error %v, want %v", err,
+			encrypted.ErrNotEncrypted)
+	}
+
+	newPassphrase := []byte("new passphrase")
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		b, err := tx.CreateTopLevelBucket([]byte("ns"))
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte("key"), []byte("value")); err != nil {
+			return err
+		}
+		err = encrypted.ChangePassphrase(tx, []byte("wrong"),
+			newPassphrase, nil)
+		if err != encrypted.ErrInvalidPassphrase {
+			return fmt.Errorf("ChangePassphrase: unexpected "+
+				"error %v, want %v", err,
+				encrypted.ErrInvalidPassphrase)
+		}
+		return encrypted.ChangePassphrase(
+			tx, passphrase, newPassphrase, nil,
+		)
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	db.Close()
+
+	inner, err = walletdb.Open("bdb", dbPath, true, defaultDBTimeout)
+	if err != nil {
+		t.Fatalf("Failed to open underlying database: %v", err)
+	}
+	_, err = walletdb.Open(dbType, inner, passphrase)
+	if err != encrypted.ErrInvalidPassphrase {
+		t.Fatalf("Open: unexpected error %v, want %v", err,
+			encrypted.ErrInvalidPassphrase)
+	}
+	db, err = walletdb.Open(dbType, inner, newPassphrase)
+	if err != nil {
+		t.Fatalf("Failed to open test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		v := tx.ReadBucket([]byte("ns")).Get([]byte("key"))
+		if string(v) != "value" {
+			return fmt.Errorf("Get: unexpected value %q", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+}
+
+// storedKeyParams returns the parameters of the key the master key of the
+// encrypted database in the underlying database is encrypted with.
+func storedKeyParams(t *testing.T, inner walletdb.DB) snacl.Parameters {
+	var sk snacl.SecretKey
+	err := walletdb.View(inner, func(tx walletdb.ReadTx) error {
+		meta := tx.ReadBucket([]byte("encrypted-walletdb"))
+		return sk.Unmarshal(meta.Get([]byte("params")))
+	})
+	if err != nil {
+		t.Fatalf("unable to read the key parameters: %v", err)
+	}
+	return sk.Parameters
+}
+
+// TestSecretKeyGenerator ensures that the master key is encrypted with keys
+// derived by the SecretKeyGenerator the database is created with, or its
+// passphrase changed with.
+func TestSecretKeyGenerator(t *testing.T) {
+	inner := createInner(t, filepath.Join(t.TempDir(), "db"))
+
+	_, err := walletdb.Create(dbType, inner, passphrase, 1)
+	wantErr := fmt.Errorf("third argument to %s.Create is invalid -- "+
+		"expected SecretKeyGenerator", dbType)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Fatalf("Create: unexpected error %v, want %v", err, wantErr)
+	}
+
+	argon2id := encrypted.SecretKeyGenerator(
+		func(passphrase *[]byte) (*snacl.SecretKey, error) {
+			return snacl.NewArgon2idSecretKey(passphrase, 1, 64, 1)
+		},
+	)
+	db, err := walletdb.Create(dbType, inner, passphrase, argon2id)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+	params := storedKeyParams(t, inner)
+	if params.KDF != snacl.KDFArgon2id || params.Time != 1 ||
+		params.Memory != 64 || params.Threads != 1 {
+
+		t.Fatalf("unexpected key parameters %+v", params)
+	}
+
+	scrypt := encrypted.SecretKeyGenerator(
+		func(passphrase *[]byte) (*snacl.SecretKey, error) {
+			return snacl.NewSecretKey(passphrase, 16, 8, 1)
+		},
+	)
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return encrypted.ChangePassphrase(
+			tx, passphrase, passphrase, scrypt,
+		)
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	params = storedKeyParams(t, inner)
+	if params.KDF != snacl.KDFScrypt || params.N != 16 {
+		t.Fatalf("unexpected key parameters %+v", params)
+	}
+}
+
+// TestCursorKeys ensures that cursors opened in a transaction see the keys
+// added to and deleted from their bucket by the transaction.
+func TestCursorKeys(t *testing.T) {
+	db, err := walletdb.Create(
+		dbType, createInner(t, filepath.Join(t.TempDir(), "db")),
+		passphrase,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+
+	keys := func(c walletdb.ReadCursor) string {
+		var s string
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			s += string(k)
+		}
+		return s
+	}
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		b, err := tx.CreateTopLevelBucket([]byte("ns"))
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"a", "c"} {
+			if err := b.Put([]byte(k), []byte(k)); err != nil {
+				return err
+			}
+		}
+		c := b.ReadCursor()
+		if s := keys(c); s != "ac" {
+			return fmt.Errorf("got keys %q, want %q", s, "ac")
+		}
+
+		// Updating a key keeps the sorted keys.
+		if err := b.Put([]byte("a"), []byte("A")); err != nil {
+			return err
+		}
+		if _, v := b.ReadCursor().First(); string(v) != "A" {
+			return fmt.Errorf("got value %q, want %q", v, "A")
+		}
+
+		if err := b.Put([]byte("b"), []byte("b")); err != nil {
+			return err
+		}
+		if _, err := b.CreateBucket([]byte("d")); err != nil {
+			return err
+		}
+		if s := keys(b.ReadCursor()); s != "abcd" {
+			return fmt.Errorf("got keys %q, want %q", s, "abcd")
+		}
+		if err := b.Delete([]byte("a")); err != nil {
+			return err
+		}
+		if err := b.DeleteNestedBucket([]byte("d")); err != nil {
+			return err
+		}
+		if s := keys(b.ReadCursor()); s != "bc" {
+			return fmt.Errorf("got keys %q, want %q", s, "bc")
+		}
+
+		// The cursor opened first skips the deleted key, but doesn't
+		// see the added ones.
+		if s := keys(c); s != "c" {
+			return fmt.Errorf("got keys %q, want %q", s, "c")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+}
+
+// BenchmarkCursor measures creating a cursor over a bucket of 10,000 keys,
+// which decrypts and sorts all of them, and iterating over it.
+func BenchmarkCursor(b *testing.B) {
+	db, err := walletdb.Create(
+		dbType, createInner(b, filepath.Join(b.TempDir(), "db")),
+		passphrase,
+	)
+	if err != nil {
+		b.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+
+	ns := []byte("ns")
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket(ns)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10000; i++ {
+			k := []byte(fmt.Sprintf("key%05d", i))
+			if err := bucket.Put(k, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+			c := tx.ReadBucket(ns).ReadCursor()
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatalf("View: unexpected error: %v", err)
+		}
+	}
+}
+
+// BenchmarkTxDetails measures the lookup of the 100 most recent of 2,000
+// wallet transactions, each paying an output to the wallet, in the transaction
+// store, which opens cursors on the credits and debits buckets of the store for
+// every transaction, as when listing the recent transactions of a wallet.
+func BenchmarkTxDetails(b *testing.B) {
+	db, err := walletdb.Create(
+		dbType, createInner(b, filepath.Join(b.TempDir(), "db")),
+		passphrase,
+	)
+	if err != nil {
+		b.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+
+	const numTxs = 2000
+	ns := []byte("wtxmgr")
+	hashes := make([]chainhash.Hash, 0, numTxs)
+	var store *wtxmgr.Store
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bucket, err := tx.CreateTopLevelBucket(ns)
+		if err != nil {
+			return err
+		}
+		if err := wtxmgr.Create(bucket); err != nil {
+			return err
+		}
+		store, err = wtxmgr.Open(bucket, &chaincfg.TestNet3Params)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < numTxs; i++ {
+			msgTx := wire.NewMsgTx(wire.TxVersion)
+			msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+				Hash: chainhash.Hash{byte(i), byte(i >> 8)},
+			}, nil, nil))
+			msgTx.AddTxOut(wire.NewTxOut(1e6, make([]byte, 22)))
+			rec, err := wtxmgr.NewTxRecordFromMsgTx(
+				msgTx, time.Now(),
+			)
+			if err != nil {
+				return err
+			}
+			block := &wtxmgr.BlockMeta{
+				Block: wtxmgr.Block{
+					Hash:   chainhash.Hash{byte(i), byte(i >> 8)},
+					Height: int32(i + 1),
+				},
+				Time: time.Now(),
+			}
+			if err := store.InsertTx(bucket, rec, block); err != nil {
+				return err
+			}
+			err = store.AddCredit(bucket, rec, block, 0, false)
+			if err != nil {
+				return err
+			}
+			hashes = append(hashes, rec.Hash)
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+			bucket := tx.ReadBucket(ns)
+			for j := numTxs - 100; j < numTxs; j++ {
+				details, err := store.TxDetails(bucket, &hashes[j])
+				if err != nil {
+					return err
+				}
+				if len(details.Credits) != 1 {
+					return fmt.Errorf("got %d credits, want 1",
+						len(details.Credits))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatalf("View: unexpected error: %v", err)
+		}
+	}
+}
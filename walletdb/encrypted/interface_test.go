@@ -0,0 +1,22 @@
+package encrypted_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	"github.com/bisoncraft/utxowallet/walletdb/walletdbtest"
+)
+
+// TestInterface performs all interfaces tests for this database driver.
+func TestInterface(t *testing.T) {
+	inner, err := walletdb.Create(
+		"bdb", filepath.Join(t.TempDir(), "db"), true, defaultDBTimeout,
+	)
+	if err != nil {
+		t.Errorf("Failed to create underlying database: %v", err)
+		return
+	}
+
+	walletdbtest.TestInterface(t, dbType, inner, passphrase)
+}
@@ -0,0 +1,252 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+)
+
+// ManagersFunc returns the managers of the services stored in a database,
+// bound to their namespaces in tx.
+type ManagersFunc func(tx walletdb.ReadWriteTx) ([]Manager, error)
+
+// Change describes the migrations which would be applied to a service, and the
+// changes they would make to its namespace.
+type Change struct {
+	// Name is the name of the service.
+	Name string
+
+	// FromVersion is the current version of the service's database.
+	FromVersion uint32
+
+	// ToVersion is the version the service's database would be upgraded
+	// to.
+	ToVersion uint32
+
+	// Added, Modified and Removed are the number of keys in the service's
+	// namespace, or in any of its nested buckets, that the migrations
+	// would add, modify and remove.
+	Added, Modified, Removed int
+}
+
+// needsUpgrade returns whether the service has any versions to apply.
+func needsUpgrade(mgr Manager) (bool, error) {
+	currentVersion, err := mgr.CurrentVersion(mgr.Namespace())
+	if err != nil {
+		return false, err
+	}
+	latestVersion := GetLatestVersion(mgr.Versions())
+	if currentVersion > latestVersion {
+		return false, ErrReversion
+	}
+
+	return currentVersion < latestVersion, nil
+}
+
+// UpgradeDB upgrades the services of the database returned by mgrs within a
+// single database transaction.  If any of the services need to be upgraded,
+// the database is first copied to the writer returned by snapshot, which is
+// closed once the copy is complete.  The snapshot can be restored with Restore
+// if the upgrade fails, or if the upgraded database turns out to be broken.
+func UpgradeDB(db walletdb.DB, snapshot func() (io.WriteCloser, error),
+	mgrs ManagersFunc) error {
+
+	// The services are only bound to a read-write transaction, so the
+	// transaction used to check their versions is rolled back.
+	var needed bool
+	err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		services, err := mgrs(tx)
+		if err != nil {
+			return err
+		}
+		for _, mgr := range services {
+			needed, err = needsUpgrade(mgr)
+			if err != nil || needed {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return walletdb.ErrDryRunRollBack
+	})
+	if err != walletdb.ErrDryRunRollBack {
+		return err
+	}
+	if !needed {
+		return nil
+	}
+
+	w, err := snapshot()
+	if err != nil {
+		return err
+	}
+	err = db.Copy(w)
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		services, err := mgrs(tx)
+		if err != nil {
+			return err
+		}
+		return Upgrade(services...)
+	})
+}
+
+// DryRun applies the migrations of the services of the database returned by
+// mgrs in a transaction which is rolled back, leaving the database unchanged,
+// and reports the changes the migrations would make to each service that needs
+// to be upgraded.
+func DryRun(db walletdb.DB, mgrs ManagersFunc) ([]Change, error) {
+	var changes []Change
+	err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		services, err := mgrs(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, mgr := range services {
+			ns := mgr.Namespace()
+			needed, err := needsUpgrade(mgr)
+			if err != nil {
+				return err
+			}
+			if !needed {
+				continue
+			}
+
+			currentVersion, err := mgr.CurrentVersion(ns)
+			if err != nil {
+				return err
+			}
+			change := Change{
+				Name:        mgr.Name(),
+				FromVersion: currentVersion,
+				ToVersion:   GetLatestVersion(mgr.Versions()),
+			}
+
+			before := make(map[string][]byte)
+			if err := flattenBucket(ns, nil, before); err != nil {
+				return err
+			}
+			if err := upgrade(mgr); err != nil {
+				return err
+			}
+			after := make(map[string][]byte)
+			if err := flattenBucket(ns, nil, after); err != nil {
+				return err
+			}
+
+			for k, v := range after {
+				oldV, ok := before[k]
+				switch {
+				case !ok:
+					change.Added++
+				case !bytes.Equal(oldV, v):
+					change.Modified++
+				}
+			}
+			for k := range before {
+				if _, ok := after[k]; !ok {
+					change.Removed++
+				}
+			}
+
+			changes = append(changes, change)
+		}
+
+		return walletdb.ErrDryRunRollBack
+	})
+	if err != walletdb.ErrDryRunRollBack {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// flattenBucket adds all key/value pairs of the bucket and its nested buckets
+// to pairs, keyed by the length-prefixed keys of their path from the bucket.
+func flattenBucket(b walletdb.ReadBucket, path []byte,
+	pairs map[string][]byte) error {
+
+	return b.ForEach(func(k, v []byte) error {
+		kPath := binary.AppendUvarint(path[:len(path):len(path)],
+			uint64(len(k)))
+		kPath = append(kPath, k...)
+
+		if v == nil {
+			if nested := b.NestedReadBucket(k); nested != nil {
+				return flattenBucket(nested, kPath, pairs)
+			}
+		}
+		pairs[string(kPath)] = append([]byte(nil), v...)
+		return nil
+	})
+}
+
+// Restore replaces the contents of the database with those of the snapshot in
+// a single transaction.  The snapshot must be a writable database, as the
+// sequences of its buckets can only be read through a read-write transaction,
+// but it is left unchanged.
+func Restore(db, snapshot walletdb.DB) error {
+	src, err := snapshot.BeginReadWriteTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Rollback()
+	}()
+
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		var keys [][]byte
+		err := tx.ForEachBucket(func(k []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := tx.DeleteTopLevelBucket(k); err != nil {
+				return err
+			}
+		}
+
+		return src.ForEachBucket(func(k []byte) error {
+			dst, err := tx.CreateTopLevelBucket(k)
+			if err != nil {
+				return err
+			}
+			return copyBucket(dst, src.ReadWriteBucket(k))
+		})
+	})
+}
+
+// copyBucket copies the key/value pairs, nested buckets and sequence of src to
+// dst.
+func copyBucket(dst, src walletdb.ReadWriteBucket) error {
+	err := src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			if nested := src.NestedReadWriteBucket(k); nested != nil {
+				dstNested, err := dst.CreateBucket(k)
+				if err != nil {
+					return err
+				}
+				return copyBucket(dstNested, nested)
+			}
+		}
+		return dst.Put(k, v)
+	})
+	if err != nil {
+		return err
+	}
+
+	return dst.SetSequence(src.Sequence())
+}
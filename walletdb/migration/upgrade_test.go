@@ -0,0 +1,254 @@
+package migration_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/bdb"
+	"github.com/bisoncraft/utxowallet/walletdb/migration"
+)
+
+var (
+	nsKey      = []byte("ns")
+	versionKey = []byte("version")
+)
+
+// bucketMigrationManager is a migration manager of a service storing its
+// version in its namespace.
+type bucketMigrationManager struct {
+	ns       walletdb.ReadWriteBucket
+	versions []migration.Version
+}
+
+var _ migration.Manager = (*bucketMigrationManager)(nil)
+
+func (m *bucketMigrationManager) Name() string {
+	return "bucket"
+}
+
+func (m *bucketMigrationManager) Namespace() walletdb.ReadWriteBucket {
+	return m.ns
+}
+
+func (m *bucketMigrationManager) CurrentVersion(ns walletdb.ReadBucket) (uint32, error) {
+	return binary.BigEndian.Uint32(ns.Get(versionKey)), nil
+}
+
+func (m *bucketMigrationManager) SetVersion(ns walletdb.ReadWriteBucket, version uint32) error {
+	return ns.Put(versionKey, binary.BigEndian.AppendUint32(nil, version))
+}
+
+func (m *bucketMigrationManager) Versions() []migration.Version {
+	return m.versions
+}
+
+// testVersions are the versions of the test service.  The migration to version
+// 1 adds key c, modifies key a and removes key b.
+var testVersions = []migration.Version{
+	{
+		Number:    0,
+		Migration: nil,
+	},
+	{
+		Number: 1,
+		Migration: func(ns walletdb.ReadWriteBucket) error {
+			if err := ns.Put([]byte("c"), []byte("3")); err != nil {
+				return err
+			}
+			if err := ns.Put([]byte("a"), []byte("4")); err != nil {
+				return err
+			}
+			return ns.Delete([]byte("b"))
+		},
+	},
+}
+
+// testManagers returns a ManagersFunc of the test service with the versions.
+func testManagers(versions []migration.Version) migration.ManagersFunc {
+	return func(tx walletdb.ReadWriteTx) ([]migration.Manager, error) {
+		return []migration.Manager{&bucketMigrationManager{
+			ns:       tx.ReadWriteBucket(nsKey),
+			versions: versions,
+		}}, nil
+	}
+}
+
+// createTestDB creates a database holding version 0 of the test service.
+func createTestDB(t *testing.T, dbPath string) walletdb.DB {
+	db, err := walletdb.Create("bdb", dbPath, true, 10*time.Second)
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns, err := tx.CreateTopLevelBucket(nsKey)
+		if err != nil {
+			return err
+		}
+		nested, err := ns.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		if err := nested.SetSequence(5); err != nil {
+			return err
+		}
+		pairs := map[string]uint32{"version": 0, "a": 1, "b": 2}
+		for k, v := range pairs {
+			err := ns.Put([]byte(k), binary.BigEndian.AppendUint32(
+				nil, v,
+			))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to initialize db: %v", err)
+	}
+
+	return db
+}
+
+// checkVersion ensures that the test service of the database is at the version
+// and holds the key b only before the upgrade.
+func checkVersion(t *testing.T, db walletdb.DB, version uint32) {
+	t.Helper()
+
+	err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+		ns := tx.ReadBucket(nsKey)
+		v := binary.BigEndian.Uint32(ns.Get(versionKey))
+		if v != version {
+			return errors.New("unexpected version")
+		}
+		if (ns.Get([]byte("b")) != nil) != (version == 0) {
+			return errors.New("unexpected key b")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("version %d: %v", version, err)
+	}
+}
+
+// snapshotTo returns a snapshot function writing the snapshot to path.
+func snapshotTo(path string) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) {
+		return os.Create(path)
+	}
+}
+
+// TestDryRun ensures that a dry run reports the changes of the migrations
+// without applying them.
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	db := createTestDB(t, filepath.Join(t.TempDir(), "db"))
+
+	changes, err := migration.DryRun(db, testManagers(testVersions))
+	if err != nil {
+		t.Fatalf("unable to dry run: %v", err)
+	}
+	expected := []migration.Change{{
+		Name:        "bucket",
+		FromVersion: 0,
+		ToVersion:   1,
+		Added:       1,
+		Modified:    2,
+		Removed:     1,
+	}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+	checkVersion(t, db, 0)
+}
+
+// TestUpgradeDB ensures that databases are copied before they are upgraded,
+// and can be restored from their snapshots.
+func TestUpgradeDB(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	db := createTestDB(t, filepath.Join(dir, "db"))
+
+	snapshotPath := filepath.Join(dir, "snapshot")
+	err := migration.UpgradeDB(
+		db, snapshotTo(snapshotPath), testManagers(testVersions),
+	)
+	if err != nil {
+		t.Fatalf("unable to upgrade: %v", err)
+	}
+	checkVersion(t, db, 1)
+
+	// Databases at the latest version are not copied again.
+	noSnapshot := func() (io.WriteCloser, error) {
+		t.Fatalf("unexpected snapshot")
+		return nil, nil
+	}
+	err = migration.UpgradeDB(db, noSnapshot, testManagers(testVersions))
+	if err != nil {
+		t.Fatalf("unable to upgrade: %v", err)
+	}
+
+	snapshot, err := walletdb.Open("bdb", snapshotPath, true, time.Second)
+	if err != nil {
+		t.Fatalf("unable to open snapshot: %v", err)
+	}
+	defer snapshot.Close()
+	checkVersion(t, snapshot, 0)
+
+	if err := migration.Restore(db, snapshot); err != nil {
+		t.Fatalf("unable to restore: %v", err)
+	}
+	checkVersion(t, db, 0)
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		// The sequences of buckets are restored too.
+		nested := tx.ReadWriteBucket(nsKey).NestedReadWriteBucket(
+			[]byte("nested"),
+		)
+		if nested == nil || nested.Sequence() != 5 {
+			return errors.New("nested bucket not restored")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected restored db: %v", err)
+	}
+}
+
+// TestUpgradeDBFailure ensures that a failed upgrade leaves the database
+// unchanged.
+func TestUpgradeDBFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	db := createTestDB(t, filepath.Join(dir, "db"))
+
+	errMigration := errors.New("migration failed")
+	versions := append(testVersions[:2:2], migration.Version{
+		Number: 2,
+		Migration: func(walletdb.ReadWriteBucket) error {
+			return errMigration
+		},
+	})
+	snapshotPath := filepath.Join(dir, "snapshot")
+	err := migration.UpgradeDB(
+		db, snapshotTo(snapshotPath), testManagers(versions),
+	)
+	if err != errMigration {
+		t.Fatalf("expected migration error, got %v", err)
+	}
+	checkVersion(t, db, 0)
+
+	if _, err := os.Stat(snapshotPath); err != nil {
+		t.Fatalf("snapshot not written: %v", err)
+	}
+}
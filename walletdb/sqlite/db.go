@@ -0,0 +1,770 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "modernc.org/sqlite" // Registers the sqlite database/sql driver.
+)
+
+// schema creates the table of the buckets and key/value pairs of the database.
+// Each row references the row of the bucket it is in as its parent, or 0 for
+// top level buckets.  The rows of buckets have a NULL value.
+const schema = `
+CREATE TABLE IF NOT EXISTS entries (
+	id       INTEGER PRIMARY KEY,
+	parent   INTEGER NOT NULL,
+	key      BLOB NOT NULL,
+	value    BLOB,
+	sequence INTEGER,
+	UNIQUE (parent, key)
+);`
+
+// rootID is the parent of the top level buckets.
+const rootID = 0
+
+// convertErr converts some database/sql errors to the equivalent walletdb
+// error.
+func convertErr(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrTxDone):
+		return walletdb.ErrTxClosed
+	case errors.Is(err, sql.ErrConnDone):
+		return walletdb.ErrDbNotOpen
+	}
+
+	// Return the original error if none of the above applies.
+	return err
+}
+
+// transaction represents a database transaction.  It can either by read-only or
+// read-write and implements the walletdb Tx interfaces.  The transaction
+// provides a root bucket against which all read and writes occur.
+//
+// Errors of the methods of buckets and cursors that have no error result are
+// kept and returned when the transaction is committed or rolled back.
+type transaction struct {
+	db       *db
+	sqlTx    *sql.Tx
+	writable bool
+	closed   bool
+	err      error
+	onCommit []func()
+}
+
+// Enforce transaction implements the walletdb transaction interfaces.
+var _ walletdb.ReadWriteTx = (*transaction)(nil)
+
+// setErr keeps the first error of the transaction.
+func (tx *transaction) setErr(err error) {
+	if tx.err == nil {
+		tx.err = convertErr(err)
+	}
+}
+
+// root returns the bucket holding the top level buckets.
+func (tx *transaction) root() *bucket {
+	return &bucket{tx: tx, id: rootID}
+}
+
+func (tx *transaction) ReadBucket(key []byte) walletdb.ReadBucket {
+	return tx.ReadWriteBucket(key)
+}
+
+// ForEachBucket will iterate through all top level buckets.
+func (tx *transaction) ForEachBucket(fn func(key []byte) error) error {
+	return tx.root().ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return fn(k)
+	})
+}
+
+func (tx *transaction) ReadWriteBucket(key []byte) walletdb.ReadWriteBucket {
+	return tx.root().NestedReadWriteBucket(key)
+}
+
+func (tx *transaction) CreateTopLevelBucket(key []byte) (walletdb.ReadWriteBucket, error) {
+	return tx.root().CreateBucketIfNotExists(key)
+}
+
+func (tx *transaction) DeleteTopLevelBucket(key []byte) error {
+	return tx.root().DeleteNestedBucket(key)
+}
+
+// close marks the transaction closed, allowing another write transaction to
+// begin if it was writable.
+func (tx *transaction) close() {
+	tx.closed = true
+	if tx.writable {
+		tx.db.writeMtx.Unlock()
+	}
+}
+
+// Commit commits all changes that have been made through the root bucket and
+// all of its sub-buckets to persistent storage.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) Commit() error {
+	if tx.closed {
+		return walletdb.ErrTxClosed
+	}
+	if !tx.writable || tx.err != nil {
+		_ = tx.sqlTx.Rollback()
+		tx.close()
+		if tx.err != nil {
+			return tx.err
+		}
+		return walletdb.ErrTxNotWritable
+	}
+
+	err := tx.sqlTx.Commit()
+	tx.close()
+	if err != nil {
+		return convertErr(err)
+	}
+
+	for _, f := range tx.onCommit {
+		f()
+	}
+	return nil
+}
+
+// Rollback undoes all changes that have been made to the root bucket and all of
+// its sub-buckets.
+//
+// This function is part of the walletdb.ReadTx interface implementation.
+func (tx *transaction) Rollback() error {
+	if tx.closed {
+		return walletdb.ErrTxClosed
+	}
+
+	err := tx.sqlTx.Rollback()
+	tx.close()
+	if tx.err != nil {
+		return tx.err
+	}
+	return convertErr(err)
+}
+
+// OnCommit takes a function closure that will be executed when the transaction
+// successfully gets committed.
+//
+// This function is part of the walletdb.ReadWriteTx interface implementation.
+func (tx *transaction) OnCommit(f func()) {
+	tx.onCommit = append(tx.onCommit, f)
+}
+
+// entry looks up the key in the bucket of the id provided.  The id of the
+// entry is returned along with its value, which is nil if the entry is a
+// bucket.  The found result is false if the key does not exist.
+func (tx *transaction) entry(parent int64, key []byte) (id int64,
+	value []byte, isBucket, found bool, err error) {
+
+	row := tx.sqlTx.QueryRow(`SELECT id, value, value IS NULL FROM entries
+		WHERE parent = ? AND key = ?`, parent, key)
+	err = row.Scan(&id, &value, &isBucket)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil, false, false, nil
+	case err != nil:
+		return 0, nil, false, false, convertErr(err)
+	}
+	if !isBucket && value == nil {
+		value = []byte{}
+	}
+	return id, value, isBucket, true, nil
+}
+
+// bucket is an internal type used to represent a collection of key/value pairs
+// and implements the walletdb Bucket interfaces.
+type bucket struct {
+	tx *transaction
+	id int64
+}
+
+// Enforce bucket implements the walletdb Bucket interfaces.
+var _ walletdb.ReadWriteBucket = (*bucket)(nil)
+
+// NestedReadWriteBucket retrieves a nested bucket with the given key.  Returns
+// nil if the bucket does not exist.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) NestedReadWriteBucket(key []byte) walletdb.ReadWriteBucket {
+	id, _, isBucket, found, err := b.tx.entry(b.id, key)
+	if err != nil {
+		b.tx.setErr(err)
+		return nil
+	}
+	// Don't return a non-nil interface to a nil pointer.
+	if !found || !isBucket {
+		return nil
+	}
+	return &bucket{tx: b.tx, id: id}
+}
+
+func (b *bucket) NestedReadBucket(key []byte) walletdb.ReadBucket {
+	return b.NestedReadWriteBucket(key)
+}
+
+// createBucket inserts the row of a new nested bucket with the given key.
+func (b *bucket) createBucket(key []byte) (*bucket, error) {
+	res, err := b.tx.sqlTx.Exec(`INSERT INTO entries (parent, key,
+		sequence) VALUES (?, ?, 0)`, b.id, key)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &bucket{tx: b.tx, id: id}, nil
+}
+
+// CreateBucket creates and returns a new nested bucket with the given key.
+// Returns ErrBucketExists if the bucket already exists, ErrBucketNameRequired
+// if the key is empty, or ErrIncompatibleValue if the key value is otherwise
+// invalid.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) CreateBucket(key []byte) (walletdb.ReadWriteBucket, error) {
+	if !b.tx.writable {
+		return nil, walletdb.ErrTxNotWritable
+	}
+	if len(key) == 0 {
+		return nil, walletdb.ErrBucketNameRequired
+	}
+
+	_, _, isBucket, found, err := b.tx.entry(b.id, key)
+	switch {
+	case err != nil:
+		return nil, err
+	case found && isBucket:
+		return nil, walletdb.ErrBucketExists
+	case found:
+		return nil, walletdb.ErrIncompatibleValue
+	}
+	return b.createBucket(key)
+}
+
+// CreateBucketIfNotExists creates and returns a new nested bucket with the
+// given key if it does not already exist.  Returns ErrBucketNameRequired if the
+// key is empty or ErrIncompatibleValue if the key value is otherwise invalid.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) CreateBucketIfNotExists(key []byte) (walletdb.ReadWriteBucket, error) {
+	if !b.tx.writable {
+		return nil, walletdb.ErrTxNotWritable
+	}
+	if len(key) == 0 {
+		return nil, walletdb.ErrBucketNameRequired
+	}
+
+	id, _, isBucket, found, err := b.tx.entry(b.id, key)
+	switch {
+	case err != nil:
+		return nil, err
+	case found && isBucket:
+		return &bucket{tx: b.tx, id: id}, nil
+	case found:
+		return nil, walletdb.ErrIncompatibleValue
+	}
+	return b.createBucket(key)
+}
+
+// DeleteNestedBucket removes a nested bucket with the given key.  Returns
+// ErrTxNotWritable if attempted against a read-only transaction and
+// ErrBucketNotFound if the specified bucket does not exist.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) DeleteNestedBucket(key []byte) error {
+	if !b.tx.writable {
+		return walletdb.ErrTxNotWritable
+	}
+	if len(key) == 0 {
+		return walletdb.ErrIncompatibleValue
+	}
+
+	id, _, isBucket, found, err := b.tx.entry(b.id, key)
+	switch {
+	case err != nil:
+		return err
+	case !found:
+		return walletdb.ErrBucketNotFound
+	case !isBucket:
+		return walletdb.ErrIncompatibleValue
+	}
+
+	// Delete the bucket along with all the buckets nested in it and their
+	// key/value pairs.
+	_, err = b.tx.sqlTx.Exec(`WITH RECURSIVE tree(id) AS (
+			SELECT ?
+			UNION ALL
+			SELECT entries.id FROM entries JOIN tree
+			ON entries.parent = tree.id WHERE entries.value IS NULL
+		)
+		DELETE FROM entries WHERE id IN tree OR parent IN tree`, id)
+	return convertErr(err)
+}
+
+// ForEach invokes the passed function with every key/value pair in the bucket.
+// This includes nested buckets, in which case the value is nil, but it does not
+// include the key/value pairs within those nested buckets.
+//
+// This function is part of the walletdb.ReadBucket interface implementation.
+func (b *bucket) ForEach(fn func(k, v []byte) error) error {
+	c := &cursor{bucket: b}
+	k, v, err := c.move(`ORDER BY key LIMIT 1`)
+	for err == nil && k != nil {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+		k, v, err = c.move(`AND key > ? ORDER BY key LIMIT 1`, k)
+	}
+	return err
+}
+
+// Put saves the specified key/value pair to the bucket.  Keys that do not
+// already exist are added and keys that already exist are overwritten.  Returns
+// ErrTxNotWritable if attempted against a read-only transaction.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) Put(key, value []byte) error {
+	if !b.tx.writable {
+		return walletdb.ErrTxNotWritable
+	}
+	if len(key) == 0 {
+		return walletdb.ErrKeyRequired
+	}
+
+	_, _, isBucket, found, err := b.tx.entry(b.id, key)
+	if err != nil {
+		return err
+	}
+	if found && isBucket {
+		return walletdb.ErrIncompatibleValue
+	}
+
+	// Empty values are stored as empty blobs, as NULL values are buckets.
+	if value == nil {
+		value = []byte{}
+	}
+	_, err = b.tx.sqlTx.Exec(`INSERT INTO entries (parent, key, value)
+		VALUES (?, ?, ?) ON CONFLICT (parent, key) DO UPDATE
+		SET value = excluded.value`, b.id, key, value)
+	return convertErr(err)
+}
+
+// Get returns the value for the given key.  Returns nil if the key does
+// not exist in this bucket (or nested buckets).
+//
+// This function is part of the walletdb.ReadBucket interface implementation.
+func (b *bucket) Get(key []byte) []byte {
+	_, value, _, _, err := b.tx.entry(b.id, key)
+	if err != nil {
+		b.tx.setErr(err)
+		return nil
+	}
+	return value
+}
+
+// Delete removes the specified key from the bucket.  Deleting a key that does
+// not exist does not return an error.  Returns ErrTxNotWritable if attempted
+// against a read-only transaction.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) Delete(key []byte) error {
+	if !b.tx.writable {
+		return walletdb.ErrTxNotWritable
+	}
+
+	_, _, isBucket, found, err := b.tx.entry(b.id, key)
+	switch {
+	case err != nil:
+		return err
+	case !found:
+		return nil
+	case isBucket:
+		return walletdb.ErrIncompatibleValue
+	}
+
+	_, err = b.tx.sqlTx.Exec(`DELETE FROM entries WHERE parent = ? AND
+		key = ?`, b.id, key)
+	return convertErr(err)
+}
+
+func (b *bucket) ReadCursor() walletdb.ReadCursor {
+	return b.ReadWriteCursor()
+}
+
+// ReadWriteCursor returns a new cursor, allowing for iteration over the bucket's
+// key/value pairs and nested buckets in forward or backward order.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) ReadWriteCursor() walletdb.ReadWriteCursor {
+	return &cursor{bucket: b}
+}
+
+// Tx returns the bucket's transaction.
+//
+// This function is part of the walletdb.ReadWriteBucket interface implementation.
+func (b *bucket) Tx() walletdb.ReadWriteTx {
+	return b.tx
+}
+
+// NextSequence returns an autoincrementing integer for the bucket.
+func (b *bucket) NextSequence() (uint64, error) {
+	if !b.tx.writable {
+		return 0, walletdb.ErrTxNotWritable
+	}
+
+	var sequence int64
+	err := b.tx.sqlTx.QueryRow(`UPDATE entries SET sequence = sequence + 1
+		WHERE id = ? RETURNING sequence`, b.id).Scan(&sequence)
+	if err != nil {
+		return 0, convertErr(err)
+	}
+	return uint64(sequence), nil
+}
+
+// SetSequence updates the sequence number for the bucket.
+func (b *bucket) SetSequence(v uint64) error {
+	if !b.tx.writable {
+		return walletdb.ErrTxNotWritable
+	}
+
+	_, err := b.tx.sqlTx.Exec(`UPDATE entries SET sequence = ? WHERE id = ?`,
+		int64(v), b.id)
+	return convertErr(err)
+}
+
+// Sequence returns the current integer for the bucket without incrementing it.
+func (b *bucket) Sequence() uint64 {
+	var sequence int64
+	err := b.tx.sqlTx.QueryRow(`SELECT sequence FROM entries WHERE id = ?`,
+		b.id).Scan(&sequence)
+	if err != nil {
+		b.tx.setErr(err)
+		return 0
+	}
+	return uint64(sequence)
+}
+
+// cursor represents a cursor over key/value pairs and nested buckets of a
+// bucket.  Each move of the cursor queries the entry next to the key it is
+// positioned at, so the cursor remains valid when the bucket is modified.
+type cursor struct {
+	bucket *bucket
+	key    []byte
+}
+
+// move positions the cursor at the first entry of the bucket matching the
+// clause provided, appended to the query of the entries of the bucket, and
+// returns the entry.  The cursor keeps its position if there is no such
+// entry.
+func (c *cursor) move(clause string, args ...interface{}) (key,
+	value []byte, err error) {
+
+	var isBucket bool
+	args = append([]interface{}{c.bucket.id}, args...)
+	err = c.bucket.tx.sqlTx.QueryRow(`SELECT key, value, value IS NULL
+		FROM entries WHERE parent = ? `+clause, args...).Scan(
+		&key, &value, &isBucket,
+	)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil, nil
+	case err != nil:
+		return nil, nil, convertErr(err)
+	}
+	if !isBucket && value == nil {
+		value = []byte{}
+	}
+	c.key = key
+	return key, value, nil
+}
+
+// moveOrSetErr moves the cursor, keeping any error in the transaction.
+func (c *cursor) moveOrSetErr(clause string, args ...interface{}) (key,
+	value []byte) {
+
+	key, value, err := c.move(clause, args...)
+	if err != nil {
+		c.bucket.tx.setErr(err)
+	}
+	return key, value
+}
+
+// Delete removes the current key/value pair the cursor is at without
+// invalidating the cursor. Returns ErrTxNotWritable if attempted on a read-only
+// transaction, or ErrIncompatibleValue if attempted when the cursor points to a
+// nested bucket.
+//
+// This function is part of the walletdb.ReadWriteCursor interface implementation.
+func (c *cursor) Delete() error {
+	if c.key == nil {
+		return nil
+	}
+	return c.bucket.Delete(c.key)
+}
+
+// First positions the cursor at the first key/value pair and returns the pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) First() (key, value []byte) {
+	return c.moveOrSetErr(`ORDER BY key LIMIT 1`)
+}
+
+// Last positions the cursor at the last key/value pair and returns the pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Last() (key, value []byte) {
+	return c.moveOrSetErr(`ORDER BY key DESC LIMIT 1`)
+}
+
+// Next moves the cursor one key/value pair forward and returns the new pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Next() (key, value []byte) {
+	if c.key == nil {
+		return nil, nil
+	}
+	return c.moveOrSetErr(`AND key > ? ORDER BY key LIMIT 1`, c.key)
+}
+
+// Prev moves the cursor one key/value pair backward and returns the new pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Prev() (key, value []byte) {
+	if c.key == nil {
+		return nil, nil
+	}
+	return c.moveOrSetErr(`AND key < ? ORDER BY key DESC LIMIT 1`, c.key)
+}
+
+// Seek positions the cursor at the passed seek key. If the key does not exist,
+// the cursor is moved to the next key after seek. Returns the new pair.
+//
+// This function is part of the walletdb.ReadCursor interface implementation.
+func (c *cursor) Seek(seek []byte) (key, value []byte) {
+	if seek == nil {
+		seek = []byte{}
+	}
+	return c.moveOrSetErr(`AND key >= ? ORDER BY key LIMIT 1`, seek)
+}
+
+// db represents a collection of namespaces which are persisted and implements
+// the walletdb.Db interface.  All database access is performed through
+// transactions which are obtained through the specific Namespace.
+type db struct {
+	sqlDB  *sql.DB
+	closed atomic.Bool
+
+	// writeMtx is held by the write transaction of the database, so
+	// there's at most one at a time.
+	writeMtx sync.Mutex
+}
+
+// Enforce db implements the walletdb.Db interface.
+var _ walletdb.DB = (*db)(nil)
+
+func (db *db) beginTx(writable bool) (*transaction, error) {
+	if db.closed.Load() {
+		return nil, walletdb.ErrDbNotOpen
+	}
+	if writable {
+		db.writeMtx.Lock()
+	}
+
+	sqlTx, err := db.sqlDB.BeginTx(
+		context.Background(), &sql.TxOptions{ReadOnly: !writable},
+	)
+	if err != nil {
+		if writable {
+			db.writeMtx.Unlock()
+		}
+		return nil, convertErr(err)
+	}
+	return &transaction{db: db, sqlTx: sqlTx, writable: writable}, nil
+}
+
+func (db *db) BeginReadTx() (walletdb.ReadTx, error) {
+	return db.beginTx(false)
+}
+
+func (db *db) BeginReadWriteTx() (walletdb.ReadWriteTx, error) {
+	return db.beginTx(true)
+}
+
+// Copy writes a copy of the database to the provided writer.  The copy is a
+// consistent snapshot of the database, as a SQLite database file.
+//
+// This function is part of the walletdb.Db interface implementation.
+func (db *db) Copy(w io.Writer) error {
+	f, err := os.CreateTemp("", "walletdb-copy-*.db")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if _, err := db.sqlDB.Exec(`VACUUM INTO ?`, path); err != nil {
+		return convertErr(err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Close cleanly shuts down the database and syncs all data.
+//
+// This function is part of the walletdb.Db interface implementation.
+func (db *db) Close() error {
+	db.closed.Store(true)
+	return convertErr(db.sqlDB.Close())
+}
+
+// Batch executes the function f in a write transaction.  Unlike bdb, SQLite
+// does not combine the transactions of concurrent calls, so this is the same as
+// Update.
+//
+// This function is part of the walletdb.BatchDB interface implementation.
+func (db *db) Batch(f func(tx walletdb.ReadWriteTx) error) error {
+	return db.Update(f, func() {})
+}
+
+// View opens a database read transaction and executes the function f with the
+// transaction passed as a parameter. After f exits, the transaction is rolled
+// back. If f errors, its error is returned, not a rollback error (if any
+// occur). The passed reset function is called before the start of the
+// transaction and can be used to reset intermediate state. As callers may
+// expect retries of the f closure (depending on the database backend used), the
+// reset function will be called before each retry respectively.
+func (db *db) View(f func(tx walletdb.ReadTx) error, reset func()) error {
+	// We don't do any retries with SQLite so we just initially call the
+	// reset function once.
+	reset()
+
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the transaction rolls back in the event of a panic.
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = f(tx)
+	rollbackErr := tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	if rollbackErr != nil {
+		return rollbackErr
+	}
+	return nil
+}
+
+// Update opens a database read/write transaction and executes the function f
+// with the transaction passed as a parameter. After f exits, if f did not
+// error, the transaction is committed. Otherwise, if f did error, the
+// transaction is rolled back. If the rollback fails, the original error
+// returned by f is still returned. If the commit fails, the commit error is
+// returned. As callers may expect retries of the f closure (depending on the
+// database backend used), the reset function will be called before each retry
+// respectively.
+func (db *db) Update(f func(tx walletdb.ReadWriteTx) error, reset func()) error {
+	// We don't do any retries with SQLite so we just initially call the
+	// reset function once.
+	reset()
+
+	tx, err := db.BeginReadWriteTx()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the transaction rolls back in the event of a panic.
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = f(tx)
+	if err != nil {
+		// Want to return the original error, not a rollback error if
+		// any occur.
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PrintStats returns all collected stats pretty printed into a string.
+func (db *db) PrintStats() string {
+	return "<no stats are collected by sqlite backend>"
+}
+
+// fileExists reports whether the named file or directory exists.
+func fileExists(name string) bool {
+	if _, err := os.Stat(name); err != nil {
+		if os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// openDB opens the database at the provided path.  walletdb.ErrDbDoesNotExist
+// is returned if the database doesn't exist and the create flag is not set.
+// The timeout is how long to wait on the database when it is locked by another
+// process.
+func openDB(dbPath string, create bool,
+	timeout time.Duration) (walletdb.DB, error) {
+
+	if !create && !fileExists(dbPath) {
+		return nil, walletdb.ErrDbDoesNotExist
+	}
+
+	// Write transactions take the write lock of the database as they
+	// begin, so they don't fail on another process writing to it first.
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)",
+		timeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(FULL)")
+	params.Set("_txlock", "immediate")
+	sqlDB, err := sql.Open("sqlite", dbPath+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := sqlDB.Exec(schema); err != nil {
+		_ = sqlDB.Close()
+		return nil, convertErr(err)
+	}
+	return &db{sqlDB: sqlDB}, nil
+}
@@ -0,0 +1,51 @@
+/*
+Package sqlite implements an instance of walletdb that uses SQLite for the
+backing datastore, through a pure Go implementation of SQLite which requires no
+cgo.
+
+Buckets and their key/value pairs are all stored in a single table, with each
+row referencing the row of the bucket it belongs to, so the database can be
+inspected with ordinary SQLite tools:
+
+	CREATE TABLE entries (
+		id       INTEGER PRIMARY KEY,
+		parent   INTEGER NOT NULL,
+		key      BLOB NOT NULL,
+		value    BLOB,
+		sequence INTEGER,
+		UNIQUE (parent, key)
+	);
+
+Top level buckets have a parent of 0.  The rows of buckets have a NULL value
+and hold the sequence of the bucket, while the rows of key/value pairs have a
+non-NULL value.  The database is opened in WAL mode, so read transactions are
+not blocked by the single write transaction.
+
+# Usage
+
+This package is only a driver to the walletdb package and provides the database
+type of "sqlite". The only parameters the Open and Create functions take are
+the database path as a string and a timeout value for waiting on the database
+when it is locked by another process as a time.Duration:
+
+	db, err := walletdb.Open("sqlite", "path/to/database.db", 60*time.Second)
+	if err != nil {
+		// Handle error
+	}
+
+The no-freelist-sync bool of the bdb driver is also accepted between the path
+and the timeout, and ignored, so that either driver can be opened with the same
+arguments:
+
+	db, err := walletdb.Open("sqlite", "path/to/database.db", true,
+		60*time.Second)
+	if err != nil {
+		// Handle error
+	}
+
+	db, err := walletdb.Create("sqlite", "path/to/database.db", 60*time.Second)
+	if err != nil {
+		// Handle error
+	}
+*/
+package sqlite
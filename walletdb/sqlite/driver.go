@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+)
+
+const (
+	dbType = "sqlite"
+)
+
+// parseArgs parses the arguments from the walletdb Open/Create methods.  The
+// no-freelist-sync option of the bdb driver may be passed between the path and
+// the timeout, so both drivers can be opened with the same arguments, and is
+// ignored.
+func parseArgs(funcName string,
+	args ...interface{}) (string, time.Duration, error) {
+
+	if len(args) == 3 {
+		if _, ok := args[1].(bool); !ok {
+			return "", 0, fmt.Errorf("second argument to %s.%s is "+
+				"invalid -- expected no-freelist-sync bool",
+				dbType, funcName)
+		}
+		args = []interface{}{args[0], args[2]}
+	}
+	if len(args) != 2 {
+		return "", 0, fmt.Errorf("invalid arguments to %s.%s -- "+
+			"expected database path, optional no-freelist-sync "+
+			"and timeout option", dbType, funcName)
+	}
+
+	dbPath, ok := args[0].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("first argument to %s.%s is invalid "+
+			"-- expected database path string", dbType, funcName)
+	}
+
+	timeout, ok := args[1].(time.Duration)
+	if !ok {
+		return "", 0, fmt.Errorf("second argument to %s.%s is invalid "+
+			"-- expected timeout time.Duration", dbType, funcName)
+	}
+
+	return dbPath, timeout, nil
+}
+
+// openDBDriver is the callback provided during driver registration that opens
+// an existing database for use.
+func openDBDriver(args ...interface{}) (walletdb.DB, error) {
+	dbPath, timeout, err := parseArgs("Open", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(dbPath, false, timeout)
+}
+
+// createDBDriver is the callback provided during driver registration that
+// creates, initializes, and opens a database for use.
+func createDBDriver(args ...interface{}) (walletdb.DB, error) {
+	dbPath, timeout, err := parseArgs("Create", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(dbPath, true, timeout)
+}
+
+func init() {
+	// Register the driver.
+	driver := walletdb.Driver{
+		DbType: dbType,
+		Create: createDBDriver,
+		Open:   openDBDriver,
+	}
+	if err := walletdb.RegisterDriver(driver); err != nil {
+		panic(fmt.Sprintf("Failed to register database driver '%s': %v",
+			dbType, err))
+	}
+}
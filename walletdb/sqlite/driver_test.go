@@ -0,0 +1,305 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bisoncraft/utxowallet/walletdb"
+	_ "github.com/bisoncraft/utxowallet/walletdb/sqlite"
+)
+
+const (
+	// dbType is the database type name for this driver.
+	dbType = "sqlite"
+
+	// defaultDBTimeout is the value of db timeout for testing.
+	defaultDBTimeout = 10 * time.Second
+)
+
+// TestCreateOpenFail ensures that errors related to creating and opening a
+// database are handled properly.
+func TestCreateOpenFail(t *testing.T) {
+	// Ensure that attempting to open a database that doesn't exist returns
+	// the expected error.
+	wantErr := walletdb.ErrDbDoesNotExist
+	if _, err := walletdb.Open(
+		dbType, "noexist.db", defaultDBTimeout,
+	); err != wantErr {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	// Ensure that attempting to open a database with the wrong number of
+	// parameters returns the expected error.
+	wantErr = fmt.Errorf("invalid arguments to %s.Open -- expected "+
+		"database path, optional no-freelist-sync and timeout option",
+		dbType)
+	if _, err := walletdb.Open(
+		dbType, 1, 2, 3, 4,
+	); err.Error() != wantErr.Error() {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	// Ensure that attempting to open a database with an invalid type for
+	// the first parameter returns the expected error.
+	wantErr = fmt.Errorf("first argument to %s.Open is invalid -- "+
+		"expected database path string", dbType)
+	if _, err := walletdb.Open(
+		dbType, 1, defaultDBTimeout,
+	); err.Error() != wantErr.Error() {
+
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	// Ensure that attempting to create a database with an invalid type for
+	// the second parameter returns the expected error.
+	wantErr = fmt.Errorf("second argument to %s.Create is invalid -- "+
+		"expected timeout time.Duration", dbType)
+	if _, err := walletdb.Create(
+		dbType, "noexist.db", 1,
+	); err.Error() != wantErr.Error() {
+
+		t.Errorf("Create: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
+	// Ensure operations against a closed database return the expected
+	// error.
+	tempDir, err := os.MkdirTemp("", "createfail")
+	if err != nil {
+		t.Errorf("unable to create temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	dbPath := filepath.Join(tempDir, "db")
+	db, err := walletdb.Create(dbType, dbPath, defaultDBTimeout)
+	if err != nil {
+		t.Errorf("Create: unexpected error: %v", err)
+		return
+	}
+	db.Close()
+
+	wantErr = walletdb.ErrDbNotOpen
+	if _, err := db.BeginReadTx(); err != wantErr {
+		t.Errorf("Namespace: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+}
+
+// TestPersistence ensures that values stored are still valid after closing and
+// reopening the database, and in a copy of the database.
+func TestPersistence(t *testing.T) {
+	// Create a new database to run tests against.
+	tempDir, err := os.MkdirTemp("", "persistencetest")
+	if err != nil {
+		t.Errorf("unable to create temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	dbPath := filepath.Join(tempDir, "db")
+	db, err := walletdb.Create(dbType, dbPath, defaultDBTimeout)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+	defer db.Close()
+
+	// Create a namespace and put some values into it so they can be tested
+	// for existence on re-open.
+	storeValues := map[string]string{
+		"ns1key1": "foo1",
+		"ns1key2": "foo2",
+		"ns1key3": "",
+	}
+	ns1Key := []byte("ns1")
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns1, err := tx.CreateTopLevelBucket(ns1Key)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range storeValues {
+			if err := ns1.Put([]byte(k), []byte(v)); err != nil {
+				return fmt.Errorf("Put: unexpected error: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ns1 Update: unexpected error: %v", err)
+		return
+	}
+
+	// checkValues ensures the values previously stored in the namespace
+	// still exist and are correct.
+	checkValues := func(db walletdb.DB) error {
+		return walletdb.View(db, func(tx walletdb.ReadTx) error {
+			ns1 := tx.ReadBucket(ns1Key)
+			if ns1 == nil {
+				return fmt.Errorf("ReadTx.ReadBucket: " +
+					"unexpected nil root bucket")
+			}
+
+			for k, v := range storeValues {
+				gotVal := ns1.Get([]byte(k))
+				if !reflect.DeepEqual(gotVal, []byte(v)) {
+					return fmt.Errorf("Get: key '%s' does "+
+						"not match expected value - "+
+						"got %s, want %s", k, gotVal, v)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	// Copy the database.
+	var b bytes.Buffer
+	if err := db.Copy(&b); err != nil {
+		t.Errorf("Copy: unexpected error: %v", err)
+		return
+	}
+	copyPath := filepath.Join(tempDir, "copy")
+	if err := os.WriteFile(copyPath, b.Bytes(), 0600); err != nil {
+		t.Errorf("unable to write copy: %v", err)
+		return
+	}
+	copyDB, err := walletdb.Open(dbType, copyPath, defaultDBTimeout)
+	if err != nil {
+		t.Errorf("Failed to open copy (%s) %v", dbType, err)
+		return
+	}
+	defer copyDB.Close()
+	if err := checkValues(copyDB); err != nil {
+		t.Errorf("copy View: unexpected error: %v", err)
+		return
+	}
+
+	// Close and reopen the database to ensure the values persist.  It is
+	// reopened with the arguments of the bdb driver, which are accepted too.
+	db.Close()
+	db, err = walletdb.Open(dbType, dbPath, true, defaultDBTimeout)
+	if err != nil {
+		t.Errorf("Failed to open test database (%s) %v", dbType, err)
+		return
+	}
+	defer db.Close()
+
+	if err := checkValues(db); err != nil {
+		t.Errorf("ns1 View: unexpected error: %v", err)
+		return
+	}
+}
+
+// TestCursor ensures that cursors iterate over the key/value pairs and nested
+// buckets of a bucket in the order of their keys.
+func TestCursor(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "cursortest")
+	if err != nil {
+		t.Errorf("unable to create temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := walletdb.Create(
+		dbType, filepath.Join(tempDir, "db"), defaultDBTimeout,
+	)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ns, err := tx.CreateTopLevelBucket([]byte("ns"))
+		if err != nil {
+			return err
+		}
+		for _, k := range [][]byte{{2}, {1, 0}, {1}, {0xff}} {
+			if err := ns.Put(k, k); err != nil {
+				return err
+			}
+		}
+		nested, err := ns.CreateBucket([]byte{3})
+		if err != nil {
+			return err
+		}
+		if err := nested.Put([]byte{1}, []byte{1}); err != nil {
+			return err
+		}
+
+		// The keys are ordered bytewise, with nested buckets having
+		// nil values.
+		var keys [][]byte
+		c := ns.ReadWriteCursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if bytes.Equal(k, []byte{3}) != (v == nil) {
+				return fmt.Errorf("unexpected value %x of key "+
+					"%x", v, k)
+			}
+			keys = append(keys, k)
+		}
+		wantKeys := [][]byte{{1}, {1, 0}, {2}, {3}, {0xff}}
+		if !reflect.DeepEqual(keys, wantKeys) {
+			return fmt.Errorf("unexpected keys %x, want %x", keys,
+				wantKeys)
+		}
+
+		if k, _ := c.Seek([]byte{1, 1}); !bytes.Equal(k, []byte{2}) {
+			return fmt.Errorf("Seek: unexpected key %x", k)
+		}
+		if err := c.Delete(); err != nil {
+			return err
+		}
+		if k, _ := c.Prev(); !bytes.Equal(k, []byte{1, 0}) {
+			return fmt.Errorf("Prev: unexpected key %x", k)
+		}
+		if k, _ := c.Last(); !bytes.Equal(k, []byte{0xff}) {
+			return fmt.Errorf("Last: unexpected key %x", k)
+		}
+		if k, _ := c.Prev(); !bytes.Equal(k, []byte{3}) {
+			return fmt.Errorf("Prev: unexpected key %x", k)
+		}
+		if err := c.Delete(); err != walletdb.ErrIncompatibleValue {
+			return fmt.Errorf("Delete: unexpected error %v", err)
+		}
+		if ns.Get([]byte{2}) != nil {
+			return fmt.Errorf("Get: deleted key found")
+		}
+
+		// Deleting a bucket deletes the buckets nested in it.
+		if _, err := nested.CreateBucket([]byte{4}); err != nil {
+			return err
+		}
+		if err := ns.DeleteNestedBucket([]byte{3}); err != nil {
+			return err
+		}
+		if _, err := ns.CreateBucket([]byte{3}); err != nil {
+			return err
+		}
+		if !walletdb.BucketIsEmpty(ns.NestedReadBucket([]byte{3})) {
+			return fmt.Errorf("recreated bucket not empty")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Update: unexpected error: %v", err)
+	}
+}
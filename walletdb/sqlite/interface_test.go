@@ -0,0 +1,22 @@
+package sqlite_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bisoncraft/utxowallet/walletdb/walletdbtest"
+)
+
+// TestInterface performs all interfaces tests for this database driver.
+func TestInterface(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "interfacetest")
+	if err != nil {
+		t.Errorf("unable to create temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	dbPath := filepath.Join(tempDir, "db")
+	walletdbtest.TestInterface(t, dbType, dbPath, defaultDBTimeout)
+}
@@ -398,6 +398,22 @@ func (s *Store) RemoveUnminedTx(ns walletdb.ReadWriteBucket, rec *TxRecord) erro
 	return s.removeConflict(ns, rec)
 }
 
+// ReplaceUnminedTx removes the unmined transactions that the unmined
+// transaction rec double spends, along with all transactions that depend on
+// them.  It is to be used once rec has replaced them in the mempool, as
+// described by BIP-0125, so that the replaced transactions are no longer
+// rebroadcast and the outputs they spent can be tracked as spent by rec.  The
+// replacement must already have been inserted with InsertTx.
+func (s *Store) ReplaceUnminedTx(ns walletdb.ReadWriteBucket, rec *TxRecord) error {
+	if existsRawUnmined(ns, rec.Hash[:]) == nil {
+		str := fmt.Sprintf("replacement transaction %v is not an "+
+			"unmined transaction", rec.Hash)
+		return storeError(ErrInput, str, nil)
+	}
+
+	return s.removeDoubleSpends(ns, rec)
+}
+
 // insertMinedTx inserts a new transaction record for a mined transaction into
 // the database under the confirmed bucket. It guarantees that, if the
 // tranasction was previously unconfirmed, then it will take care of cleaning up
@@ -1142,6 +1158,30 @@ func FetchTxLabel(ns walletdb.ReadBucket, txid chainhash.Hash) (string, error) {
 	return DeserializeLabel(v)
 }
 
+// ForEachTxLabel calls f for each labeled transaction in the tx labels bucket.
+// If no labels have been written yet, f is never called.
+func ForEachTxLabel(ns walletdb.ReadBucket,
+	f func(txid chainhash.Hash, label string) error) error {
+
+	labelBucket := ns.NestedReadBucket(bucketTxLabels)
+	if labelBucket == nil {
+		return nil
+	}
+
+	return labelBucket.ForEach(func(k, v []byte) error {
+		var txid chainhash.Hash
+		if err := txid.SetBytes(k); err != nil {
+			return err
+		}
+
+		label, err := DeserializeLabel(v)
+		if err != nil {
+			return err
+		}
+		return f(txid, label)
+	})
+}
+
 // DeserializeLabel reads a deserializes a length-value encoded label from the
 // byte array provided.
 func DeserializeLabel(v []byte) (string, error) {
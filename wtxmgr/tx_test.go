@@ -1483,6 +1483,114 @@ func TestRemoveUnminedTx(t *testing.T) {
 	checkBalance(btcutil.Amount(initialBalance), true)
 }
 
+// TestReplaceUnminedTx ensures that an unmined transaction replacing another
+// unmined transaction removes the replaced transaction and its descendants.
+func TestReplaceUnminedTx(t *testing.T) {
+	t.Parallel()
+
+	store, db, teardown, err := testStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown()
+
+	// Create a confirmed coinbase output to be spent by the original and
+	// the replacement transactions.
+	b100 := &BlockMeta{
+		Block: Block{Height: 100},
+		Time:  time.Now(),
+	}
+	cb := newCoinBase(1e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The original transaction spends the coinbase, and its change output
+	// is spent by a child transaction.
+	spendTx := spendOutput(&cbRec.Hash, 0, 5e7, 4e7)
+	spendTxRec, err := NewTxRecordFromMsgTx(spendTx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	childTx := spendOutput(&spendTxRec.Hash, 1, 3e7)
+	childTxRec, err := NewTxRecordFromMsgTx(childTx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The replacement pays the same output with less change.
+	replacementTx := spendOutput(&cbRec.Hash, 0, 5e7, 3e7)
+	replacementRec, err := NewTxRecordFromMsgTx(replacementTx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitDBTx(t, store, db, func(ns walletdb.ReadWriteBucket) {
+		if err := store.InsertTx(ns, cbRec, b100); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddCredit(ns, cbRec, b100, 0, false); err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range []*TxRecord{spendTxRec, childTxRec} {
+			if err := store.InsertTx(ns, rec, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := store.AddCredit(ns, spendTxRec, nil, 1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.AddCredit(ns, childTxRec, nil, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The replacement must be inserted before it replaces the
+		// original transaction.
+		err = store.ReplaceUnminedTx(ns, replacementRec)
+		if serr, ok := err.(Error); !ok || serr.Code != ErrInput {
+			t.Fatalf("expected ErrInput, got %v", err)
+		}
+
+		if err := store.InsertTx(ns, replacementRec, nil); err != nil {
+			t.Fatal(err)
+		}
+		err = store.AddCredit(ns, replacementRec, nil, 1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ReplaceUnminedTx(ns, replacementRec); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// Only the replacement should remain unmined, and only its change
+	// output should count towards the unconfirmed balance.
+	commitDBTx(t, store, db, func(ns walletdb.ReadWriteBucket) {
+		unminedTxs, err := store.UnminedTxs(ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unminedTxs) != 1 ||
+			unminedTxs[0].TxHash() != replacementRec.Hash {
+
+			t.Fatalf("expected only the replacement to be "+
+				"unmined, got %v", unminedTxs)
+		}
+
+		bal, err := store.Balance(ns, 0, b100.Height+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bal != 3e7 {
+			t.Fatalf("expected balance of %v, got %v",
+				btcutil.Amount(3e7), bal)
+		}
+	})
+}
+
 // TestInsertMempoolTxAlreadyConfirmed ensures that transactions that already
 // exist within the store as confirmed cannot be added as unconfirmed.
 func TestInsertMempoolTxAlreadyConfirmed(t *testing.T) {